@@ -1,8 +1,12 @@
 package eab
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -20,10 +24,25 @@ func addCommand() cli.Command {
 		Action: cli.ActionFunc(addAction),
 		Usage:  "add ACME External Account Binding Key",
 		UsageText: `**step ca acme eab add** <provisioner> [<eab-key-reference>]
+[**--from-file**=<file>] [**--expires-in**=<duration>] [**--format**=<format>]
 [**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
 [**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
 [**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
 		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "from-file",
+				Usage: `Add a key for each reference in a CSV <file>. The references are read from
+the "reference" column if the first row is a header, or from the first column
+otherwise. Empty lines and lines starting with # are ignored.`,
+			},
+			cli.DurationFlag{
+				Name: "expires-in",
+				Usage: `The <duration> a key can be used to bind an ACME account, only printed as
+the expiration time of the new keys. The CA does not store it and never expires
+keys: nothing expires unless **step ca acme eab prune** runs with the same
+duration.`,
+			},
+			formatFlag,
 			flags.AdminCert,
 			flags.AdminKey,
 			flags.AdminSubject,
@@ -53,21 +72,54 @@ $ step ca acme eab add my_acme_provisioner
 Add an ACME External Account Binding Key with reference:
 '''
 $ step ca acme eab add my_acme_provisioner my_first_eab_key
+'''
+
+Add an ACME External Account Binding Key that must be used in 72 hours, and print it in JSON:
+'''
+$ step ca acme eab add my_acme_provisioner my_first_eab_key --expires-in 72h --format json
+'''
+
+Add a key for each team in a CSV file and save the key ids and HMAC keys:
+'''
+$ cat teams.csv
+reference,owner
+team-a,alice@example.com
+team-b,bob@example.com
+$ step ca acme eab add my_acme_provisioner --from-file teams.csv --format csv > keys.csv
 '''`,
 	}
 }
 
 func addAction(ctx *cli.Context) (err error) {
-	if err := errs.MinMaxNumberOfArguments(ctx, 1, 2); err != nil {
+	filename := ctx.String("from-file")
+	if filename != "" {
+		if err := errs.NumberOfArguments(ctx, 1); err != nil {
+			return err
+		}
+	} else if err := errs.MinMaxNumberOfArguments(ctx, 1, 2); err != nil {
 		return err
 	}
 
+	format, err := validateFormat(ctx)
+	if err != nil {
+		return err
+	}
+	expiresIn := ctx.Duration("expires-in")
+	if expiresIn < 0 {
+		return errs.InvalidFlagValue(ctx, "expires-in", expiresIn.String(), "")
+	}
+
 	args := ctx.Args()
 	provisioner := args.Get(0)
 
-	reference := ""
-	if ctx.NArg() == 2 {
-		reference = args.Get(1)
+	references := []string{""}
+	switch {
+	case filename != "":
+		if references, err = readReferences(filename); err != nil {
+			return err
+		}
+	case ctx.NArg() == 2:
+		references = []string{args.Get(1)}
 	}
 
 	client, err := cautils.NewAdminClient(ctx)
@@ -75,21 +127,97 @@ func addAction(ctx *cli.Context) (err error) {
 		return errors.Wrap(err, "error creating admin client")
 	}
 
-	eak, err := client.CreateExternalAccountKey(provisioner, &adminAPI.CreateExternalAccountKeyRequest{
-		Reference: reference,
-	})
-	if err != nil {
-		return errors.Wrap(notImplemented(err), "error creating ACME EAB key")
+	// Keys already created are printed on errors, so their HMAC keys are not
+	// lost.
+	var keys []*eakOutput
+	for _, reference := range references {
+		eak, err := client.CreateExternalAccountKey(provisioner, &adminAPI.CreateExternalAccountKeyRequest{
+			Reference: reference,
+		})
+		if err != nil {
+			if len(keys) > 0 {
+				printAddedKeys(os.Stdout, format, keys, expiresIn)
+			}
+			if reference != "" {
+				return errors.Wrapf(notImplemented(err), "error creating ACME EAB key with reference %q", reference)
+			}
+			return errors.Wrap(notImplemented(err), "error creating ACME EAB key")
+		}
+		keys = append(keys, toOutput(eak, true, expiresIn))
 	}
 
-	cliEAK := toCLI(ctx, client, eak)
+	return printAddedKeys(os.Stdout, format, keys, expiresIn)
+}
 
-	// TODO(hs): JSON output, so that executing this command can be more easily automated?
+// printAddedKeys writes the new keys, including their HMAC keys.
+func printAddedKeys(out io.Writer, format string, keys []*eakOutput, expiresIn time.Duration) error {
+	if format != "table" {
+		return writeKeys(out, format, keys)
+	}
 
-	out := os.Stdout
-	format := "%-36s%-28s%-48s%s\n"
-	fmt.Fprintf(out, format, "Key ID", "Provisioner", "Key (base64, raw url encoded)", "Reference")
-	fmt.Fprintf(out, format, cliEAK.id, cliEAK.provisioner, cliEAK.key, cliEAK.reference)
+	if expiresIn > 0 {
+		format := "%-36s%-28s%-48s%-30s%s\n"
+		fmt.Fprintf(out, format, "Key ID", "Provisioner", "Key (base64, raw url encoded)", "Expires At", "Reference")
+		for _, k := range keys {
+			fmt.Fprintf(out, format, k.ID, k.Provisioner, k.HmacKey, k.ExpiresAt, k.Reference)
+		}
+		return nil
+	}
 
+	format = "%-36s%-28s%-48s%s\n"
+	fmt.Fprintf(out, format, "Key ID", "Provisioner", "Key (base64, raw url encoded)", "Reference")
+	for _, k := range keys {
+		fmt.Fprintf(out, format, k.ID, k.Provisioner, k.HmacKey, k.Reference)
+	}
 	return nil
 }
+
+// readReferences reads the references of the keys from a CSV file.
+func readReferences(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", filename)
+	}
+
+	column := 0
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(name), "reference") {
+				column = i
+				records = records[1:]
+				break
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(records))
+	references := make([]string, 0, len(records))
+	for _, record := range records {
+		if len(record) <= column {
+			continue
+		}
+		reference := strings.TrimSpace(record[column])
+		switch {
+		case reference == "":
+			continue
+		case seen[reference]:
+			return nil, errors.Errorf("error parsing %s: reference %q is defined more than once", filename, reference)
+		}
+		seen[reference] = true
+		references = append(references, reference)
+	}
+	if len(references) == 0 {
+		return nil, errors.Errorf("error parsing %s: no references found", filename)
+	}
+	return references, nil
+}
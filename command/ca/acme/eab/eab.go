@@ -2,13 +2,18 @@ package eab
 
 import (
 	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"html"
+	"io"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 
+	"github.com/smallstep/cli-utils/errs"
 	"github.com/smallstep/linkedca"
 
 	"github.com/smallstep/certificates/authority/admin"
@@ -51,6 +56,8 @@ func Command() cli.Command {
 			listCommand(),
 			addCommand(),
 			removeCommand(),
+			rotateCommand(),
+			pruneCommand(),
 		},
 		Description: `**step ca acme eab** command group provides facilities for managing ACME
 		External Account Binding Keys.
@@ -71,6 +78,21 @@ Remove an ACME External Account Binding Key:
 '''
 $ step ca acme eab remove my_provisioner my_key_id
 '''
+
+Add an ACME External Account Binding Key for each reference in a CSV file:
+'''
+$ step ca acme eab add my_provisioner --from-file teams.csv --format json
+'''
+
+Rotate the ACME External Account Binding Key with a reference:
+'''
+$ step ca acme eab rotate my_provisioner my_reference
+'''
+
+Remove the keys that have not been bound to an account in 72 hours:
+'''
+$ step ca acme eab prune my_provisioner --expires-in 72h
+'''
 `,
 	}
 }
@@ -87,3 +109,113 @@ func notImplemented(err error) error {
 	}
 	return err
 }
+
+var formatFlag = cli.StringFlag{
+	Name: "format",
+	Usage: `The output <format>. The default is table.
+
+: <format> is a case-sensitive string and must be one of:
+
+    **table**
+    :  Print a table for humans.
+
+    **json**
+    :  Print a JSON array with a JSON object for each key.
+
+    **csv**
+    :  Print CSV with a header row and a row for each key.`,
+	Value: "table",
+}
+
+func validateFormat(ctx *cli.Context) (string, error) {
+	format := ctx.String("format")
+	switch format {
+	case "table", "json", "csv":
+		return format, nil
+	default:
+		return "", errs.InvalidFlagValue(ctx, "format", format, "table, json, csv")
+	}
+}
+
+// eakOutput is the machine-readable representation of an EAB key. The HMAC
+// key is only set for new keys.
+type eakOutput struct {
+	ID          string `json:"keyId"`
+	Provisioner string `json:"provisioner"`
+	Reference   string `json:"reference,omitempty"`
+	HmacKey     string `json:"hmacKey,omitempty"`
+	CreatedAt   string `json:"createdAt,omitempty"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+	BoundAt     string `json:"boundAt,omitempty"`
+	Account     string `json:"account,omitempty"`
+}
+
+func toOutput(eak *linkedca.EABKey, withKey bool, expiresIn time.Duration) *eakOutput {
+	out := &eakOutput{
+		ID:          eak.Id,
+		Provisioner: eak.Provisioner,
+		Reference:   eak.Reference,
+		Account:     eak.Account,
+	}
+	if withKey {
+		out.HmacKey = base64.RawURLEncoding.Strict().EncodeToString(eak.HmacKey)
+	}
+	if eak.CreatedAt != nil {
+		createdAt := eak.CreatedAt.AsTime()
+		out.CreatedAt = createdAt.Format(time.RFC3339)
+		if expiresIn > 0 {
+			out.ExpiresAt = createdAt.Add(expiresIn).Format(time.RFC3339)
+		}
+	}
+	if isBound(eak) {
+		out.BoundAt = eak.BoundAt.AsTime().Format(time.RFC3339)
+	}
+	return out
+}
+
+// writeKeys writes the keys to w in JSON or CSV.
+func writeKeys(w io.Writer, format string, keys []*eakOutput) error {
+	if format == "json" {
+		if keys == nil {
+			keys = []*eakOutput{}
+		}
+		b, err := json.MarshalIndent(keys, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling ACME EAB keys")
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"keyId", "provisioner", "reference", "hmacKey", "createdAt", "expiresAt", "boundAt", "account"})
+	for _, k := range keys {
+		cw.Write([]string{k.ID, k.Provisioner, k.Reference, k.HmacKey, k.CreatedAt, k.ExpiresAt, k.BoundAt, k.Account})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isBound(eak *linkedca.EABKey) bool {
+	return eak.BoundAt != nil && !eak.BoundAt.AsTime().IsZero() && eak.BoundAt.AsTime().Unix() != 0
+}
+
+// getKeys returns all the EAB keys of a provisioner, or the keys with the
+// given reference.
+func getKeys(client *ca.AdminClient, provisioner, reference string) ([]*linkedca.EABKey, error) {
+	var (
+		keys   []*linkedca.EABKey
+		cursor string
+	)
+	for {
+		resp, err := client.GetExternalAccountKeysPaginate(provisioner, reference, ca.WithAdminCursor(cursor))
+		if err != nil {
+			return nil, errors.Wrap(notImplemented(err), "error retrieving ACME EAB keys")
+		}
+		keys = append(keys, resp.EAKs...)
+		if resp.NextCursor == "" {
+			return keys, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
@@ -0,0 +1,126 @@
+package eab
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/smallstep/linkedca"
+)
+
+func Test_readReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        []string
+		errContains string
+	}{
+		{"ok/header", "owner,reference\nalice@example.com,team-a\nbob@example.com, team-b\n", []string{"team-a", "team-b"}, ""},
+		{"ok/header-case", "Reference\nteam-a\n", []string{"team-a"}, ""},
+		{"ok/no-header", "team-a,alice@example.com\nteam-b\n", []string{"team-a", "team-b"}, ""},
+		{"ok/comments-and-empty", "# teams\nteam-a\n\n,\nteam-b\n", []string{"team-a", "team-b"}, ""},
+		{"ok/short-rows", "owner,reference\nalice@example.com\nbob@example.com,team-b\n", []string{"team-b"}, ""},
+		{"fail/duplicate", "team-a\nteam-b\nteam-a\n", nil, `reference "team-a" is defined more than once`},
+		{"fail/empty", "reference\n# no teams\n", nil, "no references found"},
+		{"fail/csv", "\"team-a\nteam-b\n", nil, "error parsing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "references.csv")
+			require.NoError(t, os.WriteFile(filename, []byte(tt.content), 0o600))
+			got, err := readReferences(filename)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := readReferences(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
+
+func Test_expiredKeys(t *testing.T) {
+	now := time.Now()
+	newKey := func(id string, createdAt, boundAt time.Time) *linkedca.EABKey {
+		eak := &linkedca.EABKey{Id: id, CreatedAt: timestamppb.New(createdAt)}
+		if !boundAt.IsZero() {
+			eak.BoundAt = timestamppb.New(boundAt)
+			eak.Account = "account-" + id
+		}
+		return eak
+	}
+	expired := newKey("expired", now.Add(-73*time.Hour), time.Time{})
+	eaks := []*linkedca.EABKey{
+		expired,
+		newKey("recent", now.Add(-time.Hour), time.Time{}),
+		newKey("bound", now.Add(-100*time.Hour), now.Add(-99*time.Hour)),
+		{Id: "no-created-at"},
+	}
+
+	assert.Equal(t, []*linkedca.EABKey{expired}, expiredKeys(eaks, 72*time.Hour, now))
+	assert.Empty(t, expiredKeys(eaks, 100*time.Hour, now))
+	assert.Len(t, expiredKeys(eaks, time.Minute, now), 2)
+	assert.Empty(t, expiredKeys(nil, time.Hour, now))
+}
+
+func Test_printAddedKeys(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	eak := &linkedca.EABKey{
+		Id:          "key-id",
+		Provisioner: "acme",
+		Reference:   "team-a",
+		HmacKey:     []byte{1, 2, 3, 4},
+		CreatedAt:   timestamppb.New(createdAt),
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		keys := []*eakOutput{toOutput(eak, true, 72*time.Hour)}
+		require.NoError(t, printAddedKeys(&buf, "json", keys, 72*time.Hour))
+		var got []map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, []map[string]string{{
+			"keyId":       "key-id",
+			"provisioner": "acme",
+			"reference":   "team-a",
+			"hmacKey":     "AQIDBA",
+			"createdAt":   "2026-01-02T15:04:05Z",
+			"expiresAt":   "2026-01-05T15:04:05Z",
+		}}, got)
+
+		buf.Reset()
+		require.NoError(t, printAddedKeys(&buf, "json", nil, 0))
+		assert.Equal(t, "[]\n", buf.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		keys := []*eakOutput{toOutput(eak, true, 0)}
+		require.NoError(t, printAddedKeys(&buf, "csv", keys, 0))
+		assert.Equal(t, "keyId,provisioner,reference,hmacKey,createdAt,expiresAt,boundAt,account\n"+
+			"key-id,acme,team-a,AQIDBA,2026-01-02T15:04:05Z,,,\n", buf.String())
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		keys := []*eakOutput{toOutput(eak, true, 72*time.Hour)}
+		require.NoError(t, printAddedKeys(&buf, "table", keys, 72*time.Hour))
+		assert.Contains(t, buf.String(), "Expires At")
+		assert.Contains(t, buf.String(), "2026-01-05T15:04:05Z")
+
+		buf.Reset()
+		require.NoError(t, printAddedKeys(&buf, "table", keys, 0))
+		assert.NotContains(t, buf.String(), "Expires At")
+		assert.Contains(t, buf.String(), "AQIDBA")
+	})
+}
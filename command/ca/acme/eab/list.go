@@ -11,6 +11,7 @@ import (
 
 	"github.com/smallstep/certificates/ca"
 	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
 
 	"github.com/smallstep/cli/flags"
 	"github.com/smallstep/cli/internal/cast"
@@ -23,13 +24,22 @@ func listCommand() cli.Command {
 		Action: cli.ActionFunc(listAction),
 		Usage:  "list all ACME External Account Binding Keys",
 		UsageText: `**step ca acme eab list** <provisioner> [<eab-key-reference>]
-[**--limit**=<number>]
+[**--limit**=<number>] [**--bound**] [**--unbound**] [**--format**=<format>]
 [**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
 [**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
 [**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
 		Flags: []cli.Flag{
 			flags.Limit,
 			flags.NoPager,
+			cli.BoolFlag{
+				Name:  "bound",
+				Usage: `List only the keys bound to an ACME account.`,
+			},
+			cli.BoolFlag{
+				Name:  "unbound",
+				Usage: `List only the keys not bound to an ACME account.`,
+			},
+			formatFlag,
 			flags.AdminCert,
 			flags.AdminKey,
 			flags.AdminSubject,
@@ -43,6 +53,9 @@ func listCommand() cli.Command {
 
 Output will go to stdout by default. If many EAB keys are stored in the ACME provisioner, output will be sent to $PAGER (when set). 
 
+The output includes the ACME account each key is bound to, and when it was
+bound. The JSON and CSV formats are not paged and never include the HMAC keys.
+
 ## POSITIONAL ARGUMENTS
 
 <provisioner>
@@ -63,6 +76,16 @@ Show ACME External Account Binding Key with specific reference:
 '''
 $ step ca acme eab list my_acme_provisioner my_reference
 '''
+
+Report the ACME accounts that consumed each key, in CSV:
+'''
+$ step ca acme eab list my_acme_provisioner --bound --format csv
+'''
+
+List the keys that have not been used yet, in JSON:
+'''
+$ step ca acme eab list my_acme_provisioner --unbound --format json
+'''
 `,
 	}
 }
@@ -80,11 +103,43 @@ func listAction(ctx *cli.Context) (err error) {
 		reference = args.Get(1)
 	}
 
+	outputFormat, err := validateFormat(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool("bound") && ctx.Bool("unbound") {
+		return errs.IncompatibleFlagWithFlag(ctx, "bound", "unbound")
+	}
+	include := func(eak *linkedca.EABKey) bool {
+		switch {
+		case ctx.Bool("bound"):
+			return isBound(eak)
+		case ctx.Bool("unbound"):
+			return !isBound(eak)
+		default:
+			return true
+		}
+	}
+
 	client, err := cautils.NewAdminClient(ctx)
 	if err != nil {
 		return errors.Wrap(err, "error creating admin client")
 	}
 
+	if outputFormat != "table" {
+		eaks, err := getKeys(client, provisioner, reference)
+		if err != nil {
+			return err
+		}
+		var keys []*eakOutput
+		for _, eak := range eaks {
+			if include(eak) {
+				keys = append(keys, toOutput(eak, false, 0))
+			}
+		}
+		return writeKeys(os.Stdout, outputFormat, keys)
+	}
+
 	var out io.WriteCloser
 	var cmd *exec.Cmd
 
@@ -144,6 +199,9 @@ func listAction(ctx *cli.Context) (err error) {
 			firstIteration = false
 		}
 		for _, k := range eaksResponse.EAKs {
+			if !include(k) {
+				continue
+			}
 			cliEAK := toCLI(ctx, client, k)
 			_, err = fmt.Fprintf(out, format, cliEAK.id, cliEAK.provisioner, "*****", cliEAK.createdAt, cliEAK.boundAt, cliEAK.account, cliEAK.reference)
 			if err != nil {
@@ -0,0 +1,148 @@
+package eab
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func pruneCommand() cli.Command {
+	return cli.Command{
+		Name:   "prune",
+		Action: cli.ActionFunc(pruneAction),
+		Usage:  "remove the expired ACME EAB Keys",
+		UsageText: `**step ca acme eab prune** <provisioner> **--expires-in**=<duration>
+[**--dry-run**] [**--force**]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.DurationFlag{
+				Name:  "expires-in",
+				Usage: `The <duration> after which a key that is not bound to an ACME account expires.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the expired keys without removing them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Remove the expired keys without asking for confirmation.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca acme eab prune** removes the ACME EAB Keys that were not bound to an
+ACME account within a duration after their creation. Keys bound to an account
+are never removed.
+
+The CA does not store an expiration time and does not expire ACME EAB Keys.
+This command removes every unbound key created more than **--expires-in** ago,
+regardless of the **--expires-in** printed when the key was added, so use the
+same duration for all the keys of a provisioner. It can run periodically.
+
+## POSITIONAL ARGUMENTS
+
+<provisioner>
+: Name of the provisioner of the ACME EAB keys
+
+## EXAMPLES
+
+Show the keys that have not been bound in 72 hours:
+'''
+$ step ca acme eab prune my_acme_provisioner --expires-in 72h --dry-run
+'''
+
+Remove the keys that have not been bound in 72 hours:
+'''
+$ step ca acme eab prune my_acme_provisioner --expires-in 72h --force
+'''`,
+	}
+}
+
+func pruneAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	expiresIn := ctx.Duration("expires-in")
+	switch {
+	case !ctx.IsSet("expires-in"):
+		return errs.RequiredFlag(ctx, "expires-in")
+	case expiresIn <= 0:
+		return errs.InvalidFlagValue(ctx, "expires-in", expiresIn.String(), "")
+	}
+
+	provisioner := ctx.Args().Get(0)
+
+	client, err := cautils.NewAdminClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error creating admin client")
+	}
+
+	eaks, err := getKeys(client, provisioner, "")
+	if err != nil {
+		return err
+	}
+
+	expired := expiredKeys(eaks, expiresIn, time.Now())
+	if len(expired) == 0 {
+		fmt.Println("No expired ACME EAB keys.")
+		return nil
+	}
+
+	format := "%-36s%-30s%-30s%s\n"
+	fmt.Fprintf(os.Stdout, format, "Key ID", "Created At", "Expired At", "Reference")
+	for _, eak := range expired {
+		k := toOutput(eak, false, expiresIn)
+		fmt.Fprintf(os.Stdout, format, k.ID, k.CreatedAt, k.ExpiresAt, k.Reference)
+	}
+	if ctx.Bool("dry-run") {
+		return nil
+	}
+
+	if !ctx.Bool("force") {
+		ok, err := ui.PromptYesNo(fmt.Sprintf("Do you want to remove %d expired keys? [y/n]", len(expired)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	for _, eak := range expired {
+		if err := client.RemoveExternalAccountKey(provisioner, eak.Id); err != nil {
+			return errors.Wrapf(notImplemented(err), "error removing ACME EAB key %s", eak.Id)
+		}
+	}
+	fmt.Printf("Removed %d expired ACME EAB keys.\n", len(expired))
+	return nil
+}
+
+// expiredKeys returns the keys not bound to an ACME account that were created
+// more than expiresIn before now.
+func expiredKeys(eaks []*linkedca.EABKey, expiresIn time.Duration, now time.Time) []*linkedca.EABKey {
+	var expired []*linkedca.EABKey
+	for _, eak := range eaks {
+		if !isBound(eak) && eak.CreatedAt != nil && now.After(eak.CreatedAt.AsTime().Add(expiresIn)) {
+			expired = append(expired, eak)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,140 @@
+package eab
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	adminAPI "github.com/smallstep/certificates/authority/admin/api"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func rotateCommand() cli.Command {
+	return cli.Command{
+		Name:   "rotate",
+		Action: cli.ActionFunc(rotateAction),
+		Usage:  "replace the ACME EAB Key with a reference by a new one",
+		UsageText: `**step ca acme eab rotate** <provisioner> <eab-key-reference>
+[**--expires-in**=<duration>] [**--format**=<format>] [**--force**]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.DurationFlag{
+				Name: "expires-in",
+				Usage: `The <duration> the new key can be used to bind an ACME account, only
+printed as the expiration time of the key. The CA does not store it; see
+**step ca acme eab prune**.`,
+			},
+			formatFlag,
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Rotate a key bound to an ACME account without asking for confirmation.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca acme eab rotate** removes the ACME EAB Key with a reference and
+adds a new key with the same reference, for example, when the HMAC key was
+leaked before it was used. The new key is printed like in
+**step ca acme eab add**.
+
+ACME accounts already bound to the old key are not affected. Rotating a bound
+key asks for confirmation unless **--force** is used.
+
+## POSITIONAL ARGUMENTS
+
+<provisioner>
+: Name of the provisioner of the ACME EAB key
+
+<eab-key-reference>
+: The reference of the key to rotate
+
+## EXAMPLES
+
+Rotate the key with the reference my_reference:
+'''
+$ step ca acme eab rotate my_acme_provisioner my_reference
+'''
+
+Rotate a key and print the new key in JSON:
+'''
+$ step ca acme eab rotate my_acme_provisioner my_reference --format json
+'''`,
+	}
+}
+
+func rotateAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 2); err != nil {
+		return err
+	}
+
+	format, err := validateFormat(ctx)
+	if err != nil {
+		return err
+	}
+	expiresIn := ctx.Duration("expires-in")
+	if expiresIn < 0 {
+		return errs.InvalidFlagValue(ctx, "expires-in", expiresIn.String(), "")
+	}
+
+	args := ctx.Args()
+	provisioner := args.Get(0)
+	reference := args.Get(1)
+	if reference == "" {
+		return errs.InvalidFlagValueMsg(ctx, "eab-key-reference", reference, "the reference cannot be empty")
+	}
+
+	client, err := cautils.NewAdminClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error creating admin client")
+	}
+
+	eaks, err := getKeys(client, provisioner, reference)
+	if err != nil {
+		return err
+	}
+	if len(eaks) == 0 {
+		return errors.Errorf("ACME EAB key with reference %q not found", reference)
+	}
+
+	for _, eak := range eaks {
+		if isBound(eak) && !ctx.Bool("force") {
+			ok, err := ui.PromptYesNo(fmt.Sprintf("Key %s is bound to ACME account %s. Do you want to rotate it? [y/n]", eak.Id, eak.Account))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+	}
+
+	// References are unique, so the old key is removed first.
+	for _, eak := range eaks {
+		if err := client.RemoveExternalAccountKey(provisioner, eak.Id); err != nil {
+			return errors.Wrapf(notImplemented(err), "error removing ACME EAB key %s", eak.Id)
+		}
+	}
+
+	eak, err := client.CreateExternalAccountKey(provisioner, &adminAPI.CreateExternalAccountKeyRequest{
+		Reference: reference,
+	})
+	if err != nil {
+		return errors.Wrapf(notImplemented(err), "error creating ACME EAB key with reference %q; the old key was removed", reference)
+	}
+
+	return printAddedKeys(os.Stdout, format, []*eakOutput{toOutput(eak, true, expiresIn)}, expiresIn)
+}
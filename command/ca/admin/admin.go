@@ -23,6 +23,9 @@ func Command() cli.Command {
 			addCommand(),
 			removeCommand(),
 			updateCommand(),
+			applyCommand(),
+			exportCommand(),
+			importCommand(),
 		},
 		Description: `**step ca admin** command group provides facilities for managing the
 certificate authority admins.
@@ -51,6 +54,16 @@ $ step ca admin update max@smallstep.com --super=false
 Remove an admin:
 '''
 $ step ca admin remove max@smallstep.com
+'''
+
+Apply the admins in a configuration file:
+'''
+$ step ca admin apply -f admins.yaml
+'''
+
+Export the admins to a configuration file:
+'''
+$ step ca admin export > admins.yaml
 '''`,
 	}
 }
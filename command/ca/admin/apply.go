@@ -0,0 +1,288 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	adminAPI "github.com/smallstep/certificates/authority/admin/api"
+	"github.com/smallstep/certificates/ca"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/applyutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func applyCommand() cli.Command {
+	return cli.Command{
+		Name:   "apply",
+		Action: cli.ActionFunc(applyAction),
+		Usage:  "apply a declarative admin configuration to the CA",
+		UsageText: `**step ca admin apply** **--file**=<file> [**--dry-run**] [**--force**]
+[**--prune-current-admin**] [**--admin-cert**=<file>] [**--admin-key**=<file>]
+[**--admin-subject**=<subject>] [**--admin-provisioner**=<name>]
+[**--admin-password-file**=<file>] [**--ca-url**=<uri>] [**--root**=<file>]
+[**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file,f",
+				Usage: `The YAML or JSON <file> with the desired admins.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the changes required to apply the configuration without applying them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Apply the changes without asking for confirmation.`,
+			},
+			cli.BoolFlag{
+				Name: "prune-current-admin",
+				Usage: `Allow the deletion of the admin running the command, which will not be
+able to use the admin API after it.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca admin apply** makes the admins of the CA match the ones in a
+configuration file, so the configuration can be kept and reviewed in version
+control.
+
+An admin is identified by its subject and provisioner. The command prints a plan
+with the admins that will be created, updated, or deleted and, after a
+confirmation, applies the changes. New admins are created first, and admins in
+the CA that are not in the file are deleted at the end. The admin running the
+command is not deleted unless the **--prune-current-admin** flag is used.
+
+The file contains a list of admins, as the top-level value or under the
+"admins" key, with the following properties:
+
+* **subject**: the subject name that must appear in the identifying credential
+  of the admin.
+* **provisioner**: the name of the provisioner.
+* **type** (optional): ADMIN or SUPER_ADMIN, defaults to ADMIN.
+
+## EXAMPLES
+
+Show the changes required to apply a configuration:
+'''
+$ cat admins.yaml
+admins:
+- subject: max@smallstep.com
+  provisioner: admin-jwk
+  type: SUPER_ADMIN
+- subject: mariano@smallstep.com
+  provisioner: google
+$ step ca admin apply -f admins.yaml --dry-run
+'''
+
+Apply a configuration without asking for confirmation:
+'''
+$ step ca admin apply -f admins.yaml --force
+'''`,
+	}
+}
+
+// adminConfig is an admin in a configuration file.
+type adminConfig struct {
+	Subject     string `json:"subject"`
+	Provisioner string `json:"provisioner"`
+	Type        string `json:"type,omitempty"`
+}
+
+func newAdminConfig(adm *cliAdmin) *adminConfig {
+	return &adminConfig{
+		Subject:     adm.Subject,
+		Provisioner: adm.ProvisionerName,
+		Type:        adm.Type.String(),
+	}
+}
+
+func (a *adminConfig) key() string {
+	return a.Subject + " (" + a.Provisioner + ")"
+}
+
+func (a *adminConfig) state() string {
+	b, _ := json.Marshal(a)
+	s, _ := applyutil.Canonical(b)
+	return s
+}
+
+func applyAction(ctx *cli.Context) error {
+	return applyAdmins(ctx, true)
+}
+
+// applyAdmins plans and applies the changes required to make the admins of
+// the CA match the ones in the file set by the --file flag. If prune is false,
+// the admins that are not in the file are kept, as in the import command.
+func applyAdmins(ctx *cli.Context, prune bool) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	filename := ctx.String("file")
+	if filename == "" {
+		return errs.RequiredFlag(ctx, "file")
+	}
+	desired, err := readAdmins(filename)
+	if err != nil {
+		return err
+	}
+
+	client, adminCert, err := cautils.NewAdminClientWithCertificate(ctx)
+	if err != nil {
+		return err
+	}
+	admins, err := client.GetAdmins()
+	if err != nil {
+		return err
+	}
+	current, err := listToCLI(ctx, client, admins)
+	if err != nil {
+		return err
+	}
+
+	var adminSubjects []string
+	var adminProvisioner string
+	if !ctx.Bool("prune-current-admin") {
+		adminSubjects, adminProvisioner = cautils.AdminIdentity(adminCert)
+	}
+	plan, err := planAdmins(client, current, desired, prune, adminSubjects, adminProvisioner)
+	if err != nil {
+		return err
+	}
+	verb := "apply"
+	if !prune {
+		verb = "import"
+	}
+	return plan.Run(os.Stdout, verb, ctx.Bool("dry-run"), ctx.Bool("force"))
+}
+
+// readAdmins reads the desired admins from a YAML or JSON file.
+func readAdmins(filename string) ([]*adminConfig, error) {
+	list, err := applyutil.ReadList(filename, "admins")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(list))
+	admins := make([]*adminConfig, len(list))
+	for i, b := range list {
+		adm := new(adminConfig)
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(adm); err != nil {
+			return nil, errors.Wrapf(err, "error parsing %s: invalid admin at index %d", filename, i)
+		}
+		if adm.Type == "" {
+			adm.Type = linkedca.Admin_ADMIN.String()
+		}
+		adm.Type = strings.ToUpper(adm.Type)
+		switch {
+		case adm.Subject == "":
+			return nil, errors.Errorf("error parsing %s: admin at index %d is missing the subject", filename, i)
+		case adm.Provisioner == "":
+			return nil, errors.Errorf("error parsing %s: admin at index %d is missing the provisioner", filename, i)
+		case adm.Type != linkedca.Admin_ADMIN.String() && adm.Type != linkedca.Admin_SUPER_ADMIN.String():
+			return nil, errors.Errorf("error parsing %s: admin %s has an invalid type %q", filename, adm.key(), adm.Type)
+		case keys[adm.key()]:
+			return nil, errors.Errorf("error parsing %s: admin %s is defined more than once", filename, adm.key())
+		}
+		keys[adm.key()] = true
+		admins[i] = adm
+	}
+	return admins, nil
+}
+
+// planAdmins returns the changes required to turn the current admins into the
+// desired ones. Admins are created and updated before deleting any, so the
+// CA is not left without the admins used to manage it. If prune is false,
+// admins that are not in the desired configuration are not deleted.
+//
+// The adminSubjects and adminProvisioner identify the admin running the
+// command. Deleting it locks the admin out of the CA, so it returns an error if
+// it is not in the desired configuration. Empty values allow any deletion.
+func planAdmins(client *ca.AdminClient, current []*cliAdmin, desired []*adminConfig, prune bool, adminSubjects []string, adminProvisioner string) (applyutil.Plan, error) {
+	byKey := make(map[string]*cliAdmin, len(current))
+	for _, adm := range current {
+		byKey[adm.Subject+" ("+adm.ProvisionerName+")"] = adm
+	}
+
+	var plan applyutil.Plan
+	for _, want := range desired {
+		typ := linkedca.Admin_Type(linkedca.Admin_Type_value[want.Type])
+		have, ok := byKey[want.key()]
+		delete(byKey, want.key())
+		if !ok {
+			plan = append(plan, &applyutil.Change{
+				Action: applyutil.Create,
+				Kind:   "admin",
+				Name:   want.key(),
+				Diff:   applyutil.Diff("", want.state()),
+				Apply: func() error {
+					_, err := client.CreateAdmin(&adminAPI.CreateAdminRequest{
+						Subject:     want.Subject,
+						Provisioner: want.Provisioner,
+						Type:        typ,
+					})
+					return err
+				},
+			})
+			continue
+		}
+
+		if diff := applyutil.Diff(newAdminConfig(have).state(), want.state()); diff != "" {
+			plan = append(plan, &applyutil.Change{
+				Action: applyutil.Update,
+				Kind:   "admin",
+				Name:   want.key(),
+				Diff:   diff,
+				Apply: func() error {
+					_, err := client.UpdateAdmin(have.Id, &adminAPI.UpdateAdminRequest{
+						Type: typ,
+					})
+					return err
+				},
+			})
+		}
+	}
+
+	if !prune {
+		return plan, nil
+	}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		adm := byKey[k]
+		if adm.ProvisionerName == adminProvisioner && slices.Contains(adminSubjects, adm.Subject) {
+			return nil, errors.Errorf("admin %s is the current admin and cannot be deleted: add it to the file or use '--prune-current-admin'", k)
+		}
+		id := adm.Id
+		plan = append(plan, &applyutil.Change{
+			Action: applyutil.Delete,
+			Kind:   "admin",
+			Name:   k,
+			Apply: func() error {
+				return client.RemoveAdmin(id)
+			},
+		})
+	}
+	return plan, nil
+}
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smallstep/linkedca"
+)
+
+func TestPlanAdmins_currentAdmin(t *testing.T) {
+	current := []*cliAdmin{
+		{Admin: &linkedca.Admin{Id: "1", Subject: "max@smallstep.com", Type: linkedca.Admin_SUPER_ADMIN}, ProvisionerName: "admin-jwk"},
+		{Admin: &linkedca.Admin{Id: "2", Subject: "mariano@smallstep.com", Type: linkedca.Admin_ADMIN}, ProvisionerName: "google"},
+	}
+	desired := []*adminConfig{
+		{Subject: "mariano@smallstep.com", Provisioner: "google", Type: "ADMIN"},
+	}
+	subjects := []string{"max", "max@smallstep.com"}
+
+	_, err := planAdmins(nil, current, desired, true, subjects, "admin-jwk")
+	assert.EqualError(t, err, "admin max@smallstep.com (admin-jwk) is the current admin and cannot be deleted: add it to the file or use '--prune-current-admin'")
+
+	// The same subject in another provisioner is a different admin.
+	plan, err := planAdmins(nil, current, desired, true, subjects, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 0 to create, 0 to update, 1 to delete.", plan.Summary())
+
+	// The admin is kept without prune, and deleted if allowed.
+	plan, err = planAdmins(nil, current, desired, false, subjects, "admin-jwk")
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+	plan, err = planAdmins(nil, current, desired, true, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 0 to create, 0 to update, 1 to delete.", plan.Summary())
+}
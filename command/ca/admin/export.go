@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"os"
+	"sort"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/applyutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func exportCommand() cli.Command {
+	return cli.Command{
+		Name:   "export",
+		Action: cli.ActionFunc(exportAction),
+		Usage:  "export the admins of the CA",
+		UsageText: `**step ca admin export** [**--format**=<format>]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "format",
+				Usage: `The output <format>. The default is yaml.
+
+: <format> is a case-sensitive string and must be one of:
+
+    **yaml**
+    :  Print the admins in YAML.
+
+    **json**
+    :  Print the admins in JSON.`,
+				Value: "yaml",
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca admin export** prints the admins of the CA, sorted by subject and
+provisioner, in the format used by **step ca admin import** and
+**step ca admin apply**.
+
+## EXAMPLES
+
+Export the admins to a file:
+'''
+$ step ca admin export > admins.yaml
+'''
+
+Export the admins in JSON:
+'''
+$ step ca admin export --format json
+'''`,
+	}
+}
+
+func exportAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	format := ctx.String("format")
+	if format != "yaml" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "yaml, json")
+	}
+
+	client, err := cautils.NewAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	admins, err := client.GetAdmins()
+	if err != nil {
+		return err
+	}
+	cliAdmins, err := listToCLI(ctx, client, admins)
+	if err != nil {
+		return err
+	}
+
+	list := make([]*adminConfig, len(cliAdmins))
+	for i, adm := range cliAdmins {
+		list[i] = newAdminConfig(adm)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].key() < list[j].key()
+	})
+
+	b, err := applyutil.Marshal(map[string]any{"admins": list}, format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+)
+
+func importCommand() cli.Command {
+	return cli.Command{
+		Name:   "import",
+		Action: cli.ActionFunc(importAction),
+		Usage:  "import admins to the CA",
+		UsageText: `**step ca admin import** **--file**=<file> [**--dry-run**] [**--force**]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file,f",
+				Usage: `The YAML or JSON <file> with the admins to import.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the changes required to import the admins without applying them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Import the admins without asking for confirmation.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca admin import** creates or updates the admins in a file created by
+**step ca admin export**.
+
+The file uses the same format as **step ca admin apply**, but unlike apply,
+the admins in the CA that are not in the file are kept. The provisioners of the
+admins must exist in the CA; import the provisioners first.
+
+## EXAMPLES
+
+Copy the admins of a staging CA to a production CA:
+'''
+$ step ca admin export --context staging > admins.yaml
+$ step ca admin import -f admins.yaml --context production
+'''`,
+	}
+}
+
+func importAction(ctx *cli.Context) error {
+	return applyAdmins(ctx, false)
+}
@@ -0,0 +1,280 @@
+package ca
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/pki"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/command/ca/certificates"
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/certinventory"
+	"github.com/smallstep/cli/internal/revokeutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+// bulkFlags are the flags that select the certificates to revoke.
+var bulkFlags = []string{"from-file", "provisioner", "issued-after", "issued-before"}
+
+// isBulkRevocation returns true if the flags select the certificates to
+// revoke.
+func isBulkRevocation(ctx *cli.Context) bool {
+	return bulkFlag(ctx) != ""
+}
+
+// bulkFlag returns the first flag that selects the certificates to revoke.
+func bulkFlag(ctx *cli.Context) string {
+	for _, name := range bulkFlags {
+		if ctx.IsSet(name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func bulkRevokeAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+	for _, name := range []string{"cert", "key", "token"} {
+		if ctx.IsSet(name) {
+			return errs.IncompatibleFlagWithFlag(ctx, name, bulkFlag(ctx))
+		}
+	}
+	if n := ctx.Int("concurrency"); n < 1 {
+		return errs.InvalidFlagValue(ctx, "concurrency", strconv.Itoa(n), "")
+	}
+	if rate := ctx.Float64("rate"); rate < 0 {
+		return errs.InvalidFlagValue(ctx, "rate", fmt.Sprint(rate), "")
+	}
+	defaultCode, err := ReasonCodeToNum(ctx.String("reasonCode"))
+	if err != nil {
+		return err
+	}
+
+	var entries []revokeutil.Entry
+	if filename := ctx.String("from-file"); filename != "" {
+		if entries, err = revokeutil.ReadFile(filename); err != nil {
+			return err
+		}
+	}
+	selected, err := selectRevocations(ctx)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, selected...)
+
+	// Resolve the serial numbers and reasons before revoking any certificate.
+	revocations := make([]*revocation, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i := range entries {
+		r, err := newRevocation(&entries[i], ctx.String("reason"), defaultCode)
+		if err != nil {
+			return err
+		}
+		if seen[r.serial] {
+			continue
+		}
+		seen[r.serial] = true
+		revocations = append(revocations, r)
+	}
+	if len(revocations) == 0 {
+		fmt.Println("No certificates to revoke.")
+		return nil
+	}
+
+	format := "%-40s%-12s%-8s%s\n"
+	fmt.Printf(format, "Serial", "Reason code", "Mode", "Reason")
+	for _, r := range revocations {
+		fmt.Printf(format, r.serial, strconv.Itoa(r.reasonCode), r.mode(), r.reason)
+	}
+	if ctx.Bool("dry-run") {
+		fmt.Printf("%d certificates would be revoked.\n", len(revocations))
+		return nil
+	}
+	if !ctx.Bool("force") {
+		ok, err := ui.PromptYesNo(fmt.Sprintf("Do you want to revoke %d certificates? [y/n]", len(revocations)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	flow, err := newRevokeFlow(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	client, err := flow.getClient(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	var tokenGen *cautils.TokenGenerator
+	for _, r := range revocations {
+		if r.certFile == "" {
+			if tokenGen, err = flow.revokeTokenGenerator(ctx); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	results := revokeutil.Run(revocations, ctx.Int("concurrency"), ctx.Float64("rate"), func(r *revocation) error {
+		if r.certFile != "" {
+			if flow.offline {
+				if err := flow.offlineCA.VerifyClientCert(r.certFile, r.keyFile); err != nil {
+					return err
+				}
+			}
+		} else {
+			tok, err := tokenGen.RevokeToken(r.serial)
+			if err != nil {
+				return errors.Wrap(err, "error generating token")
+			}
+			r.token = tok
+		}
+		return flow.revoke(ctx, client, r)
+	})
+
+	var failed int
+	fmt.Println()
+	format = "%-40s%-12s%s\n"
+	fmt.Printf(format, "Serial", "Reason code", "Result")
+	for i, err := range results {
+		r := revocations[i]
+		result := "revoked"
+		if err != nil {
+			failed++
+			result = "error: " + err.Error()
+		}
+		fmt.Printf(format, r.serial, strconv.Itoa(r.reasonCode), result)
+	}
+	fmt.Printf("Revoked %d of %d certificates.\n", len(results)-failed, len(results))
+	if failed > 0 {
+		return errors.Errorf("%d of %d revocations failed", failed, len(results))
+	}
+	return nil
+}
+
+// selectRevocations returns the valid certificates in the database of the CA
+// selected by the provisioner and issuance flags.
+func selectRevocations(ctx *cli.Context) ([]revokeutil.Entry, error) {
+	if !ctx.IsSet("provisioner") && !ctx.IsSet("issued-after") && !ctx.IsSet("issued-before") {
+		return nil, nil
+	}
+	if !ctx.Bool("offline") {
+		return nil, errs.RequiredWithFlag(ctx, bulkFlag(ctx), "offline")
+	}
+
+	filter := &certinventory.Filter{
+		Provisioner: ctx.String("provisioner"),
+		Status:      certinventory.StatusValid,
+	}
+	for name, t := range map[string]*time.Time{
+		"issued-after":  &filter.IssuedAfter,
+		"issued-before": &filter.IssuedBefore,
+	} {
+		v, ok := flags.ParseTimeOrDuration(ctx.String(name))
+		if !ok {
+			return nil, errs.InvalidFlagValue(ctx, name, ctx.String(name), "")
+		}
+		*t = v
+	}
+
+	// The offline CA opens the same database, so it must be closed before
+	// revoking the certificates.
+	nosqlDB, closeDB, err := certificates.OpenDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+	records, err := certificates.ListRecords(nosqlDB)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []revokeutil.Entry
+	for _, r := range records {
+		if filter.Match(r, now) {
+			entries = append(entries, revokeutil.Entry{Serial: r.Certificate.SerialNumber.String()})
+		}
+	}
+	return entries, nil
+}
+
+// newRevocation returns the revocation of an entry, using the serial number
+// in the certificate of mTLS entries, and the default reasons if the entry
+// does not have them.
+func newRevocation(e *revokeutil.Entry, defaultReason string, defaultCode int) (*revocation, error) {
+	r := &revocation{
+		certFile:   e.Cert,
+		keyFile:    e.Key,
+		reason:     defaultReason,
+		reasonCode: defaultCode,
+	}
+	if e.Reason != "" {
+		r.reason = e.Reason
+	}
+	if e.ReasonCode != "" {
+		code, err := ReasonCodeToNum(e.ReasonCode)
+		if err != nil {
+			return nil, err
+		}
+		r.reasonCode = code
+	}
+
+	if e.IsMTLS() {
+		certs, err := pemutil.ReadCertificateBundle(e.Cert)
+		if err != nil {
+			return nil, err
+		}
+		r.serial = certs[0].SerialNumber.String()
+		return r, nil
+	}
+
+	var err error
+	if r.serial, err = parseSerialNumber(e.Serial); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *revocation) mode() string {
+	if r.certFile != "" {
+		return "mTLS"
+	}
+	return "token"
+}
+
+// revokeTokenGenerator returns the generator of the tokens used to revoke
+// certificates by serial number.
+func (f *revokeFlow) revokeTokenGenerator(ctx *cli.Context) (*cautils.TokenGenerator, error) {
+	if f.offline {
+		return f.offlineCA.RevokeTokenGenerator(ctx)
+	}
+
+	caURL, err := flags.ParseCaURLIfExists(ctx)
+	if err != nil {
+		return nil, err
+	} else if caURL == "" {
+		return nil, errs.RequiredFlag(ctx, "ca-url")
+	}
+	root := ctx.String("root")
+	if root == "" {
+		root = pki.GetRootCAPath()
+		if _, err := os.Stat(root); err != nil {
+			return nil, errs.RequiredFlag(ctx, "root")
+		}
+	}
+	return cautils.NewRevokeTokenGenerator(ctx, caURL, root)
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/smallstep/cli/command/ca/acme"
 	"github.com/smallstep/cli/command/ca/admin"
+	"github.com/smallstep/cli/command/ca/certificates"
 	"github.com/smallstep/cli/command/ca/policy"
 	"github.com/smallstep/cli/command/ca/provisioner"
+	"github.com/smallstep/cli/command/ca/rotate"
 )
 
 // init creates and registers the ca command
@@ -82,6 +84,8 @@ $ step ca renew internal.crt internal.key \
 			acme.Command(),
 			policy.Command(),
 			admin.Command(),
+			certificates.Command(),
+			rotate.Command(),
 		},
 	}
 
@@ -1,20 +1,26 @@
 package ca
 
 import (
+	"crypto"
+	"crypto/x509"
 	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 
+	"github.com/smallstep/certificates/api"
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/errs"
 	"github.com/smallstep/cli-utils/step"
 	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/keyutil"
 	"go.step.sm/crypto/pemutil"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cryptoutil"
 	"github.com/smallstep/cli/token"
+	"github.com/smallstep/cli/utils"
 	"github.com/smallstep/cli/utils/cautils"
 )
 
@@ -30,6 +36,7 @@ func certificateCommand() cli.Command {
 [**--acme**=<file>] [**--standalone**] [**--webroot**=<file>]
 [**--contact**=<email>] [**--http-listen**=<address>]
 [**--kty**=<type>] [**--curve**=<curve>] [**--size**=<size>] [**--console**]
+[**--csr-template**=<file>] [**--csr-file**=<file>] [**--kms**=<uri>]
 [**--x5c-cert**=<file>] [**--x5c-key**=<file>] [**--k8ssa-token-path**=<file>]
 [**--offline**] [**--password-file**] [**--ca-url**=<uri>] [**--root**=<file>]
 [**--context**=<name>]`,
@@ -46,7 +53,8 @@ are configured (via the --san flag) then the <subject> will be set as the only S
 :  File to write the certificate (PEM format)
 
 <key-file>
-:  File to write the private key (PEM format)
+:  File to write the private key (PEM format). With **--csr-template** and
+**--kms**, the name of the key to create in the KMS.
 
 ## EXAMPLES
 
@@ -125,6 +133,36 @@ $ cat path/to/data.json
 $ step ca certificate foo.internal foo.crt foo.key --set-file path/to/data.json
 '''
 
+**CSR Templates** - Render the certificate request from a template, using the
+subject, the SANs, and the **--set** variables, and write the request to a
+file for auditing:
+'''
+$ cat csr.tpl
+{
+	"subject": {
+		"commonName": {{ toJson .Subject.CommonName }},
+		"organizationalUnit": {{ toJson .Insecure.User.ou }}
+	},
+	"sans": {{ toJson .SANs }},
+	"extKeyUsage": ["serverAuth", "clientAuth"]
+}
+$ step ca certificate foo.internal foo.crt foo.key \
+	--csr-template csr.tpl --set ou=Engineering --csr-file foo.csr
+'''
+
+Generate the key in a PKCS #11 module, a TPM, or a YubiKey using
+step-kms-plugin, so the private key never leaves the device:
+'''
+$ step ca certificate foo.internal foo.crt 'pkcs11:id=7331;object=foo' \
+	--csr-template csr.tpl --kms 'pkcs11:module-path=/usr/local/lib/softhsm/libsofthsm2.so;token=smallstep?pin-value=password'
+
+$ step ca certificate foo.internal foo.crt 'tpmkms:name=foo' \
+	--csr-template csr.tpl --kms tpmkms:
+
+$ step ca certificate foo.internal foo.crt 'yubikey:slot-id=9a' \
+	--csr-template csr.tpl --kms 'yubikey:pin-value=123456'
+'''
+
 **step CA ACME** - In order to use the step CA ACME protocol you must add a
 ACME provisioner to the step CA config. See **step ca provisioner add -h**.
 
@@ -175,6 +213,21 @@ multiple SANs. The '--san' flag and the '--token' flag are mutually exclusive.`,
 				Usage: "The directory where TPM keys and certificates will be stored",
 				Value: filepath.Join(step.Path(), "tpm"),
 			},
+			cli.StringFlag{
+				Name: "csr-template",
+				Usage: `The certificate request template <file>, a JSON representation of the
+certificate request to create. The template data has the subject and SANs of
+the request, and the **--set** and **--set-file** variables in .Insecure.User.
+
+With **--kms**, the key is created in the KMS with the name in <key-file>. The
+same **--kms** is used to read the **--x5c-key**, so an X5C provisioner with
+a key in a file cannot be used with a key created in a KMS; use a token
+created with **step ca token** instead.`,
+			},
+			cli.StringFlag{
+				Name:  "csr-file",
+				Usage: `The <file> to write the certificate request sent to the CA (PEM format).`,
+			},
 			flags.TemplateSet,
 			flags.TemplateSetFile,
 			flags.CaConfig,
@@ -238,6 +291,10 @@ func certificateAction(ctx *cli.Context) error {
 		// expects all necessary parameters in the attestation-uri, and having
 		// both can be confusing.
 		return errs.IncompatibleFlagWithFlag(ctx, "attestation-uri", "kms")
+	case ctx.String("csr-template") != "" && ctx.String("attestation-uri") != "":
+		return errs.IncompatibleFlagWithFlag(ctx, "csr-template", "attestation-uri")
+	case ctx.String("csr-template") != "" && ctx.IsSet("acme"):
+		return errs.IncompatibleFlagWithFlag(ctx, "csr-template", "acme")
 	}
 
 	// certificate flow unifies online and offline flows on a single api
@@ -254,16 +311,32 @@ func certificateAction(ctx *cli.Context) error {
 		if tok, err = flow.GenerateToken(ctx, subject, sans); err != nil {
 			var acmeTokenErr *cautils.ACMETokenError
 			if errors.As(err, &acmeTokenErr) {
+				if ctx.String("csr-template") != "" {
+					return errors.Errorf("flag '--csr-template' is not supported with the ACME provisioner '%s'", acmeTokenErr.Name)
+				}
 				return cautils.ACMECreateCertFlow(ctx, acmeTokenErr.Name)
 			}
 			return err
 		}
 	}
 
-	req, pk, err := flow.CreateSignRequest(ctx, tok, subject, sans)
+	var (
+		req *api.SignRequest
+		pk  crypto.PrivateKey
+	)
+	if templateFile := ctx.String("csr-template"); templateFile != "" {
+		req, pk, err = createTemplateSignRequest(ctx, flow, tok, subject, sans, templateFile, keyFile)
+	} else {
+		req, pk, err = flow.CreateSignRequest(ctx, tok, subject, sans)
+	}
 	if err != nil {
 		return err
 	}
+	if csrFile := ctx.String("csr-file"); csrFile != "" {
+		if err := writeCertificateRequest(csrFile, req.CsrPEM.CertificateRequest); err != nil {
+			return err
+		}
+	}
 
 	jwt, err := token.ParseInsecure(tok)
 	if err != nil {
@@ -289,12 +362,67 @@ func certificateAction(ctx *cli.Context) error {
 		return err
 	}
 
-	_, err = pemutil.Serialize(pk, pemutil.ToFile(keyFile, 0600))
+	ui.PrintSelected("Certificate", crtFile)
+	// Keys created in a KMS are not written to disk.
+	if pk == nil {
+		ui.PrintSelected("Key", keyFile)
+	} else {
+		_, err = pemutil.Serialize(pk, pemutil.ToFile(keyFile, 0600))
+		if err != nil {
+			return err
+		}
+		ui.PrintSelected("Private Key", keyFile)
+	}
+	if csrFile := ctx.String("csr-file"); csrFile != "" {
+		ui.PrintSelected("Certificate Request", csrFile)
+	}
+	return nil
+}
+
+// createTemplateSignRequest creates a sign request with a certificate request
+// rendered from the template file. The key is created in the KMS with the
+// given name if the kms flag is set, and the returned private key is nil;
+// otherwise a new private key is returned.
+func createTemplateSignRequest(ctx *cli.Context, flow *cautils.CertificateFlow, tok, subject string, sans []string, templateFile, keyName string) (*api.SignRequest, crypto.PrivateKey, error) {
+	b, err := utils.ReadFile(templateFile)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	kty, crv, size, err := utils.GetKeyDetailsFromCLI(ctx, false, "kty", "curve", "size")
+	if err != nil {
+		return nil, nil, err
 	}
 
-	ui.PrintSelected("Certificate", crtFile)
-	ui.PrintSelected("Private Key", keyFile)
-	return nil
+	var (
+		signer crypto.Signer
+		pk     crypto.PrivateKey
+	)
+	if kmsURI := ctx.String("kms"); kmsURI != "" {
+		if signer, err = cryptoutil.GenerateSigner(kmsURI, keyName, kty, crv, size); err != nil {
+			return nil, nil, err
+		}
+		// The key already exists in the KMS, report it so it can be deleted.
+		if !cryptoutil.IsX509Signer(signer) {
+			return nil, nil, errors.Errorf("the key %q created in the KMS %q cannot be used to sign a certificate request; "+
+				"delete it with: step kms delete --kms %q %q", keyName, kmsURI, kmsURI, keyName)
+		}
+	} else {
+		if signer, err = keyutil.GenerateSigner(kty, crv, size); err != nil {
+			return nil, nil, err
+		}
+		pk = signer
+	}
+
+	req, err := flow.CreateTemplateSignRequest(ctx, tok, subject, sans, string(b), signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, pk, nil
+}
+
+// writeCertificateRequest writes the certificate request to a file in PEM
+// format.
+func writeCertificateRequest(filename string, cr *x509.CertificateRequest) error {
+	_, err := pemutil.Serialize(cr, pemutil.ToFile(filename, 0o644))
+	return err
 }
@@ -0,0 +1,101 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/token"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func newCertificateContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	fs := flag.NewFlagSet(t.Name(), 0)
+	for _, f := range certificateCommand().Flags {
+		f.Apply(fs)
+	}
+	require.NoError(t, fs.Parse(args))
+	return cli.NewContext(cli.NewApp(), fs, nil)
+}
+
+func Test_createTemplateSignRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	now := time.Now()
+	claims, err := token.NewClaims(
+		token.WithSubject("foo.internal"),
+		token.WithSANS([]string{"foo.internal", "bar.internal"}),
+		token.WithValidity(now, now.Add(time.Minute)),
+	)
+	require.NoError(t, err)
+	tok, err := claims.Sign(jose.ES256, key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	templateFile := filepath.Join(dir, "csr.tpl")
+	require.NoError(t, os.WriteFile(templateFile, []byte(`{
+	"subject": {
+		"commonName": {{ toJson .Subject.CommonName }},
+		"organization": {{ toJson .Insecure.User.org }}
+	},
+	"sans": {{ toJson .SANs }}
+}`), 0o600))
+
+	t.Run("ok/ec", func(t *testing.T) {
+		ctx := newCertificateContext(t, "--set", "org=Smallstep")
+		req, pk, err := createTemplateSignRequest(ctx, &cautils.CertificateFlow{}, tok, "foo.internal", nil, templateFile, "foo.key")
+		require.NoError(t, err)
+		require.NotNil(t, pk)
+
+		cr := req.CsrPEM.CertificateRequest
+		assert.Equal(t, pk.(crypto.Signer).Public(), cr.PublicKey)
+		assert.IsType(t, &ecdsa.PublicKey{}, cr.PublicKey)
+		assert.Equal(t, "foo.internal", cr.Subject.CommonName)
+		assert.Equal(t, []string{"Smallstep"}, cr.Subject.Organization)
+		assert.Equal(t, []string{"foo.internal", "bar.internal"}, cr.DNSNames)
+
+		// The --csr-file output is the request sent to the CA.
+		csrFile := filepath.Join(dir, "foo.csr")
+		require.NoError(t, writeCertificateRequest(csrFile, cr))
+		got, err := pemutil.ReadCertificateRequest(csrFile)
+		require.NoError(t, err)
+		assert.Equal(t, cr.Raw, got.Raw)
+	})
+
+	t.Run("ok/rsa", func(t *testing.T) {
+		ctx := newCertificateContext(t, "--kty", "RSA", "--size", "2048")
+		req, pk, err := createTemplateSignRequest(ctx, &cautils.CertificateFlow{}, tok, "foo.internal", []string{"baz.internal"}, templateFile, "foo.key")
+		require.NoError(t, err)
+		require.IsType(t, &rsa.PrivateKey{}, pk)
+		cr := req.CsrPEM.CertificateRequest
+		assert.Equal(t, pk.(crypto.Signer).Public(), cr.PublicKey)
+		assert.Equal(t, []string{"baz.internal"}, cr.DNSNames)
+		assert.Empty(t, cr.Subject.Organization)
+	})
+
+	t.Run("fail/template-file", func(t *testing.T) {
+		ctx := newCertificateContext(t)
+		_, _, err := createTemplateSignRequest(ctx, &cautils.CertificateFlow{}, tok, "foo.internal", nil, filepath.Join(dir, "missing.tpl"), "foo.key")
+		assert.Error(t, err)
+	})
+
+	t.Run("fail/kty", func(t *testing.T) {
+		ctx := newCertificateContext(t, "--kty", "oct")
+		_, _, err := createTemplateSignRequest(ctx, &cautils.CertificateFlow{}, tok, "foo.internal", nil, templateFile, "foo.key")
+		assert.Error(t, err)
+	})
+}
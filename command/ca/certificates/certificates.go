@@ -0,0 +1,354 @@
+package certificates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/authority/config"
+	"github.com/smallstep/certificates/db"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/nosql"
+	"github.com/smallstep/nosql/database"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/certinventory"
+	"github.com/smallstep/cli/utils"
+)
+
+// Command returns the certificates subcommand.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "certificates",
+		Usage:     "list and search the certificates issued by the CA",
+		UsageText: "**step ca certificates** <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Subcommands: cli.Commands{
+			listCommand(),
+			searchCommand(),
+			getCommand(),
+		},
+		Description: `**step ca certificates** command group provides facilities for auditing the
+X.509 certificates issued by the certificate authority.
+
+The admin API of the CA does not provide the issued certificates, so these
+commands only work in offline mode, with the **--offline** flag: they read the
+certificates from the database of the CA configured in ca.json, and require
+access to it. Databases like BadgerDB can only be opened by one process; stop the
+CA or use a copy of the database if it is in use.
+
+## EXAMPLES
+
+List the certificates issued by a provisioner:
+'''
+$ step ca certificates list --offline --provisioner acme
+'''
+
+Search the certificates with a name:
+'''
+$ step ca certificates search --offline example.com
+'''
+
+Get a certificate by serial number:
+'''
+$ step ca certificates get --offline 0x1b2c3d
+'''`,
+	}
+}
+
+var filterFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "subject",
+		Usage: `Select the certificates with a <subject> containing the given text, e.g. CN=foo.`,
+	},
+	cli.StringFlag{
+		Name: "san",
+		Usage: `Select the certificates with a subject alternative <name>: a DNS name, IP
+address, email address, or URI. A name starting with * matches the names ending
+with the rest, e.g. *.example.com.`,
+	},
+	cli.StringFlag{
+		Name:  "provisioner",
+		Usage: `Select the certificates authorized by the provisioner with the given <name> or id.`,
+	},
+	cli.StringFlag{
+		Name:  "serial",
+		Usage: `Select the certificate with a serial <number> in decimal, or hexadecimal with a 0x prefix.`,
+	},
+	cli.StringFlag{
+		Name: "issued-after",
+		Usage: `Select the certificates issued after a <time|duration>. The <time|duration> is
+an RFC 3339 time, like 2026-01-02T15:04:05Z, or a duration from now, like -24h.`,
+	},
+	cli.StringFlag{
+		Name:  "issued-before",
+		Usage: `Select the certificates issued before a <time|duration>.`,
+	},
+	cli.StringFlag{
+		Name:  "expires-after",
+		Usage: `Select the certificates that expire after a <time|duration>.`,
+	},
+	cli.StringFlag{
+		Name: "expires-before",
+		Usage: `Select the certificates that expire before a <time|duration>, like 720h for the
+certificates expiring in the next 30 days.`,
+	},
+	cli.StringFlag{
+		Name: "status",
+		Usage: `Select the certificates with a <status>.
+
+: <status> is a case-sensitive string and must be one of:
+
+    **valid**
+    :  Not expired and not revoked.
+
+    **expired**
+    :  Expired but not revoked.
+
+    **revoked**
+    :  Revoked.`,
+	},
+}
+
+var offlineFlag = cli.BoolFlag{
+	Name: "offline",
+	Usage: `Read the certificates from the database configured in the CA configuration file
+instead of the CA. It is required, the admin API does not provide the issued
+certificates.`,
+}
+
+var listFormatFlag = cli.StringFlag{
+	Name: "format",
+	Usage: `The output <format>. The default is table.
+
+: <format> is a case-sensitive string and must be one of:
+
+    **table**
+    :  Print a table for humans.
+
+    **json**
+    :  Print a JSON array.
+
+    **csv**
+    :  Print CSV with a header row.`,
+	Value: "table",
+}
+
+// commonFlags returns the flags used to list certificates.
+func commonFlags() []cli.Flag {
+	fs := append([]cli.Flag{}, filterFlags...)
+	return append(fs,
+		listFormatFlag,
+		flags.Limit,
+		flags.NoPager,
+		offlineFlag,
+		flags.CaConfig,
+	)
+}
+
+// parseFilter returns the filter set by the flags.
+func parseFilter(ctx *cli.Context) (*certinventory.Filter, error) {
+	f := &certinventory.Filter{
+		Subject:     ctx.String("subject"),
+		SAN:         ctx.String("san"),
+		Provisioner: ctx.String("provisioner"),
+		Status:      ctx.String("status"),
+	}
+	if s := ctx.String("serial"); s != "" {
+		serial, err := certinventory.ParseSerial(s)
+		if err != nil {
+			return nil, errs.InvalidFlagValueMsg(ctx, "serial", s, err.Error())
+		}
+		f.Serial = serial
+	}
+	for name, t := range map[string]*time.Time{
+		"issued-after":   &f.IssuedAfter,
+		"issued-before":  &f.IssuedBefore,
+		"expires-after":  &f.ExpiresAfter,
+		"expires-before": &f.ExpiresBefore,
+	} {
+		v, ok := flags.ParseTimeOrDuration(ctx.String(name))
+		if !ok {
+			return nil, errs.InvalidFlagValue(ctx, name, ctx.String(name), "")
+		}
+		*t = v
+	}
+	switch f.Status {
+	case "", certinventory.StatusValid, certinventory.StatusExpired, certinventory.StatusRevoked:
+	default:
+		return nil, errs.InvalidFlagValue(ctx, "status", f.Status, "valid, expired, revoked")
+	}
+	return f, nil
+}
+
+// OpenDB opens the database configured in the CA configuration file set by
+// the --ca-config flag. It requires the --offline flag. The returned function
+// closes it.
+func OpenDB(ctx *cli.Context) (nosql.DB, func(), error) {
+	if !ctx.Bool("offline") {
+		return nil, nil, errors.New("the admin API of the CA does not provide the issued certificates: use '--offline' to read them from the database of the CA")
+	}
+	configFile := ctx.String("ca-config")
+	b, err := utils.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg config.Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, nil, errors.Wrapf(err, "error reading %s", configFile)
+	}
+	if cfg.DB == nil {
+		return nil, nil, errors.Errorf("error reading %s: the CA does not have a database", configFile)
+	}
+
+	authDB, err := db.New(cfg.DB)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error opening the database of the CA")
+	}
+	closeDB := func() {
+		authDB.Shutdown()
+	}
+	nosqlDB, ok := authDB.(nosql.DB)
+	if !ok {
+		closeDB()
+		return nil, nil, errors.Errorf("error opening the database of the CA: database type %q is not supported", cfg.DB.Type)
+	}
+	return nosqlDB, closeDB, nil
+}
+
+// ListRecords returns the certificates in the database, sorted by issuance
+// time.
+func ListRecords(nosqlDB nosql.DB) ([]*certinventory.Record, error) {
+	certs, err := listTable(nosqlDB, certinventory.CertsTable)
+	if err != nil {
+		return nil, err
+	}
+	data, err := listTable(nosqlDB, certinventory.CertsDataTable)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := listTable(nosqlDB, certinventory.RevokedCertsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*certinventory.Record, 0, len(certs))
+	for serial, der := range certs {
+		r, err := certinventory.ParseRecord(der, data[serial], revoked[serial])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping certificate %s: %v\n", serial, err)
+			continue
+		}
+		records = append(records, r)
+	}
+	certinventory.Sort(records)
+	return records, nil
+}
+
+// listTable returns the values of a table by key. A missing table is empty.
+func listTable(nosqlDB nosql.DB, table []byte) (map[string][]byte, error) {
+	entries, err := nosqlDB.List(table)
+	if err != nil {
+		if database.IsErrNotFound(err) {
+			return map[string][]byte{}, nil
+		}
+		return nil, errors.Wrapf(err, "error reading table %s", table)
+	}
+	m := make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		m[string(e.Key)] = e.Value
+	}
+	return m, nil
+}
+
+// getRecord returns the certificate with the given serial number in decimal.
+func getRecord(nosqlDB nosql.DB, serial string) (*certinventory.Record, error) {
+	get := func(table []byte) ([]byte, error) {
+		b, err := nosqlDB.Get(table, []byte(serial))
+		if err != nil && !database.IsErrNotFound(err) {
+			return nil, errors.Wrapf(err, "error reading table %s", table)
+		}
+		return b, nil
+	}
+	der, err := get(certinventory.CertsTable)
+	if err != nil {
+		return nil, err
+	}
+	if der == nil {
+		return nil, errors.Errorf("certificate with serial number %s not found", serial)
+	}
+	data, err := get(certinventory.CertsDataTable)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := get(certinventory.RevokedCertsTable)
+	if err != nil {
+		return nil, err
+	}
+	return certinventory.ParseRecord(der, data, revoked)
+}
+
+// listAndPrint prints the certificates in the database that match the
+// filter.
+func listAndPrint(ctx *cli.Context, filter *certinventory.Filter) error {
+	nosqlDB, closeDB, err := OpenDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	records, err := ListRecords(nosqlDB)
+	if err != nil {
+		return err
+	}
+	return printRecords(ctx, records, filter)
+}
+
+// printRecords prints the records that match the filter, up to the limit, and
+// uses $PAGER for tables if it is set.
+func printRecords(ctx *cli.Context, records []*certinventory.Record, filter *certinventory.Filter) error {
+	format := ctx.String("format")
+	switch format {
+	case "table", "json", "csv":
+	default:
+		return errs.InvalidFlagValue(ctx, "format", format, "table, json, csv")
+	}
+
+	now := time.Now()
+	limit := int(ctx.Uint("limit"))
+	var selected []*certinventory.Record
+	for _, r := range records {
+		if limit > 0 && len(selected) == limit {
+			break
+		}
+		if filter.Match(r, now) {
+			selected = append(selected, r)
+		}
+	}
+	if len(selected) == 0 && format == "table" {
+		fmt.Println("No certificates found.")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := certinventory.Write(&buf, format, selected, now); err != nil {
+		return err
+	}
+	if pager := os.Getenv("PAGER"); pager != "" && format == "table" && !ctx.Bool("no-pager") {
+		cmd := exec.Command(pager)
+		cmd.Stdin = &buf
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrap(err, "error running $PAGER")
+		}
+		return nil
+	}
+	_, err := os.Stdout.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,136 @@
+package certificates
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/certinventory"
+)
+
+func getCommand() cli.Command {
+	return cli.Command{
+		Name:   "get",
+		Action: cli.ActionFunc(getAction),
+		Usage:  "get a certificate issued by the CA",
+		UsageText: `**step ca certificates get** <serial-number> **--offline** [**--ca-config**=<file>]
+[**--format**=<format>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "format",
+				Usage: `The output <format>. The default is text.
+
+: <format> is a case-sensitive string and must be one of:
+
+    **text**
+    :  Print the properties of the certificate and the certificate in PEM.
+
+    **pem**
+    :  Print the certificate in PEM.
+
+    **json**
+    :  Print the properties of the certificate and the certificate in PEM in JSON.`,
+				Value: "text",
+			},
+			offlineFlag,
+			flags.CaConfig,
+		},
+		Description: `**step ca certificates get** prints an X.509 certificate issued by the CA, with
+the provisioner that authorized it and its revocation status.
+
+## POSITIONAL ARGUMENTS
+
+<serial-number>
+: The serial number of the certificate in decimal, or in hexadecimal with a 0x
+prefix or with colons.
+
+## EXAMPLES
+
+Get a certificate:
+'''
+$ step ca certificates get --offline 180553451935342346337359640113546433026
+'''
+
+Save a certificate in PEM using its hexadecimal serial number:
+'''
+$ step ca certificates get --offline 0x87d4f5a30c1e2b9f7a6d5c4b3a291807 --format pem > cert.crt
+'''`,
+	}
+}
+
+func getAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	format := ctx.String("format")
+	switch format {
+	case "text", "pem", "json":
+	default:
+		return errs.InvalidFlagValue(ctx, "format", format, "text, pem, json")
+	}
+	serial, err := certinventory.ParseSerial(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	nosqlDB, closeDB, err := OpenDB(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	r, err := getRecord(nosqlDB, serial)
+	if err != nil {
+		return err
+	}
+
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: r.Certificate.Raw})
+	out := certinventory.NewOutput(r, time.Now())
+	switch format {
+	case "pem":
+		_, err = os.Stdout.Write(block)
+		return err
+	case "json":
+		b, err := json.MarshalIndent(struct {
+			*certinventory.Output
+			Certificate string `json:"certificate"`
+		}{out, string(block)}, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling certificate")
+		}
+		fmt.Println(string(b))
+		return nil
+	default:
+		fmt.Printf("Serial Number: %s (0x%x)\n", out.Serial, r.Certificate.SerialNumber)
+		fmt.Printf("Subject:       %s\n", out.Subject)
+		if len(out.SANs) > 0 {
+			fmt.Printf("SANs:          %s\n", strings.Join(out.SANs, ", "))
+		}
+		fmt.Printf("Issuer:        %s\n", out.Issuer)
+		fmt.Printf("Not Before:    %s\n", out.NotBefore.Format(time.RFC3339))
+		fmt.Printf("Not After:     %s\n", out.NotAfter.Format(time.RFC3339))
+		if r.Provisioner != nil {
+			fmt.Printf("Provisioner:   %s (%s)\n", r.Provisioner.Name, r.Provisioner.Type)
+		}
+		fmt.Printf("Status:        %s\n", out.Status)
+		if r.Revocation != nil {
+			fmt.Printf("Revoked At:    %s\n", out.RevokedAt)
+			if out.Reason != "" {
+				fmt.Printf("Reason:        %s\n", out.Reason)
+			}
+		}
+		fmt.Println()
+		_, err = os.Stdout.Write(block)
+		return err
+	}
+}
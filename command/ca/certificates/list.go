@@ -0,0 +1,59 @@
+package certificates
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+func listCommand() cli.Command {
+	return cli.Command{
+		Name:   "list",
+		Action: cli.ActionFunc(listAction),
+		Usage:  "list the certificates issued by the CA",
+		UsageText: `**step ca certificates list** **--offline** [**--ca-config**=<file>]
+[**--subject**=<subject>] [**--san**=<name>] [**--provisioner**=<name>] [**--serial**=<number>]
+[**--issued-after**=<time|duration>] [**--issued-before**=<time|duration>]
+[**--expires-after**=<time|duration>] [**--expires-before**=<time|duration>]
+[**--status**=<status>] [**--format**=<format>] [**--limit**=<number>] [**--no-pager**]`,
+		Flags: commonFlags(),
+		Description: `**step ca certificates list** lists the X.509 certificates issued by the CA,
+oldest first, that match all the filters.
+
+Output will go to stdout by default. Tables are sent to $PAGER when set, unless
+**--no-pager** is used. Use **--limit** to print only the first certificates.
+
+## EXAMPLES
+
+List all the certificates:
+'''
+$ step ca certificates list --offline
+'''
+
+List everything issued by a compromised provisioner in the last week, in CSV:
+'''
+$ step ca certificates list --offline --provisioner acme --issued-after -168h --format csv
+'''
+
+List the valid certificates that expire in the next 30 days:
+'''
+$ step ca certificates list --offline --status valid --expires-before 720h
+'''
+
+List the revoked certificates using a different CA configuration:
+'''
+$ step ca certificates list --offline --ca-config /etc/step-ca/config/ca.json --status revoked
+'''`,
+	}
+}
+
+func listAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		return err
+	}
+	return listAndPrint(ctx, filter)
+}
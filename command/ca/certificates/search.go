@@ -0,0 +1,54 @@
+package certificates
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+func searchCommand() cli.Command {
+	return cli.Command{
+		Name:   "search",
+		Action: cli.ActionFunc(searchAction),
+		Usage:  "search the certificates issued by the CA",
+		UsageText: `**step ca certificates search** <query> **--offline** [**--ca-config**=<file>]
+[**--subject**=<subject>] [**--san**=<name>] [**--provisioner**=<name>] [**--serial**=<number>]
+[**--issued-after**=<time|duration>] [**--issued-before**=<time|duration>]
+[**--expires-after**=<time|duration>] [**--expires-before**=<time|duration>]
+[**--status**=<status>] [**--format**=<format>] [**--limit**=<number>] [**--no-pager**]`,
+		Flags: commonFlags(),
+		Description: `**step ca certificates search** lists the X.509 certificates issued by the CA
+with a subject, subject alternative name, or provisioner name containing the
+query, or with the query as serial number. The search is case insensitive and
+can be combined with the filters of **step ca certificates list**.
+
+## POSITIONAL ARGUMENTS
+
+<query>
+: The text to search.
+
+## EXAMPLES
+
+Search the certificates for a domain:
+'''
+$ step ca certificates search --offline example.com
+'''
+
+Search the valid certificates of a user in JSON:
+'''
+$ step ca certificates search --offline jane@example.com --status valid --format json
+'''`,
+	}
+}
+
+func searchAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		return err
+	}
+	filter.Query = ctx.Args().First()
+	return listAndPrint(ctx, filter)
+}
@@ -0,0 +1,429 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/pemutil"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/smallstep/certificates/ca"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/policyutil"
+	"github.com/smallstep/cli/utils"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func evaluateCommand() cli.Command {
+	return cli.Command{
+		Name:   "evaluate",
+		Action: cli.ActionFunc(evaluateAction),
+		Usage:  "evaluate the names of a certificate against the issuance policies",
+		UsageText: `**step ca policy evaluate** [**--cert**=<file>] [**--csr**=<file>]
+[**--san**=<name>] [**--cn**=<name>] [**--cert-type**=<type>] [**--file**=<file>]
+[**--provisioner**=<name>] [**--eab-key-id**=<eab-key-id>] [**--eab-key-reference**=<eab-key-reference>]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "cert",
+				Usage: `The X.509 or SSH certificate <file> with the names to evaluate. The type of
+an SSH certificate sets the **--cert-type**.`,
+			},
+			cli.StringFlag{
+				Name:  "csr",
+				Usage: `The certificate signing request <file> with the names to evaluate.`,
+			},
+			cli.StringSliceFlag{
+				Name: "san",
+				Usage: `The subject alternative <name> to evaluate. The type of the name is
+detected: IP addresses, URIs, email addresses, or DNS names. In SSH certificates,
+all the names are principals. Use the flag multiple times to evaluate multiple
+names.`,
+			},
+			cli.StringFlag{
+				Name:  "cn",
+				Usage: `The common <name> to evaluate.`,
+			},
+			cli.StringFlag{
+				Name: "cert-type",
+				Usage: `The <type> of certificate to evaluate. The default is x509.
+
+: <type> is a case-sensitive string and must be one of:
+
+    **x509**
+    :  Evaluate the X.509 policies.
+
+    **ssh-user**
+    :  Evaluate the SSH user certificate policies.
+
+    **ssh-host**
+    :  Evaluate the SSH host certificate policies.`,
+			},
+			cli.StringFlag{
+				Name: "file,f",
+				Usage: `Read the policies from a YAML or JSON <file> created by
+**step ca policy export** instead of the CA.`,
+			},
+			flags.Provisioner,
+			flags.EABKeyID,
+			flags.EABReference,
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca policy evaluate** checks if the names of a certificate would be
+allowed by the certificate issuance policies, and explains the rule that allowed
+or denied each name, so policies can be changed without breaking issuance.
+
+The names are evaluated against the authority policy, the policy of the
+provisioner if **--provisioner** is set, and the policy of an ACME account if
+**--eab-key-id** or **--eab-key-reference** is set. A certificate is only
+allowed if all the levels allow all its names.
+
+Names are evaluated with the policy engine of step-ca, one at a time, and the
+command shows the rule that matched each one. A name is denied if it matches a
+deny rule of its type. If a policy has allow rules of its type, the name must
+also match one of them; if the policy has allow rules, but no rules of its type,
+the name is not allowed. Common names not allowed by the common name rules are
+evaluated as the DNS name, IP address, email address, or URI they look like. SSH
+host principals are evaluated as IP addresses, email addresses, or DNS names,
+and SSH user principals with an @ as email addresses. Regular expression and URI
+constraint rules are not supported by the policy engine: they are listed and
+ignored.
+
+The command exits with a non-zero status if the certificate would be denied.
+
+## EXAMPLES
+
+Evaluate the names of a CSR against the authority and provisioner policies:
+'''
+$ step ca policy evaluate --csr server.csr --provisioner acme
+'''
+
+Evaluate a list of names:
+'''
+$ step ca policy evaluate --san www.example.com --san 10.0.0.1 --cn www.example.com
+'''
+
+Evaluate the principals of an SSH host certificate:
+'''
+$ step ca policy evaluate --cert ssh_host_ecdsa_key-cert.pub --provisioner sshpop
+'''
+
+Evaluate the names of a certificate against the policies of an ACME account:
+'''
+$ step ca policy evaluate --cert server.crt --provisioner acme --eab-key-reference my_reference
+'''
+
+Evaluate the names of a certificate against the policies in a file before
+importing it:
+'''
+$ step ca policy evaluate --cert server.crt --provisioner acme --file policies.yaml
+'''`,
+	}
+}
+
+// policyLevel is a policy that applies to a certificate.
+type policyLevel struct {
+	name   string
+	policy *linkedca.Policy
+}
+
+func evaluateAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	certType := ctx.String("cert-type")
+	switch certType {
+	case "", "x509", "ssh-user", "ssh-host":
+	default:
+		return errs.InvalidFlagValue(ctx, "cert-type", certType, "x509, ssh-user, ssh-host")
+	}
+
+	var names []policyutil.Name
+	switch {
+	case ctx.IsSet("cert") && ctx.IsSet("csr"):
+		return errs.IncompatibleFlagWithFlag(ctx, "cert", "csr")
+	case ctx.IsSet("cert"):
+		var err error
+		if names, certType, err = readCertificateNames(ctx.String("cert"), certType); err != nil {
+			return err
+		}
+	case ctx.IsSet("csr"):
+		if certType != "" && certType != "x509" {
+			return errs.IncompatibleFlagValue(ctx, "csr", "cert-type", certType)
+		}
+		csr, err := pemutil.ReadCertificateRequest(ctx.String("csr"))
+		if err != nil {
+			return err
+		}
+		names = x509Names(csr.Subject.CommonName, csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs)
+	}
+	if certType == "" {
+		certType = "x509"
+	}
+	isSSH := strings.HasPrefix(certType, "ssh-")
+	if cn := ctx.String("cn"); cn != "" {
+		if isSSH {
+			return errs.IncompatibleFlagValue(ctx, "cn", "cert-type", certType)
+		}
+		names = append(names, policyutil.Name{Kind: policyutil.CommonName, Value: cn})
+	}
+	for _, san := range ctx.StringSlice("san") {
+		kind := policyutil.Detect(san)
+		if isSSH {
+			kind = policyutil.Principal
+		}
+		names = append(names, policyutil.Name{Kind: kind, Value: san})
+	}
+	if len(names) == 0 {
+		return errs.RequiredOrFlag(ctx, "cert", "csr", "san", "cn")
+	}
+
+	levels, err := getPolicyLevels(ctx)
+	if err != nil {
+		return err
+	}
+
+	allowed := true
+	for _, level := range levels {
+		rules := policyRules(level.policy, certType)
+		fmt.Printf("%s:\n", level.name)
+		for _, rule := range rules.Unsupported() {
+			fmt.Printf("  %s: not supported by the policy engine, ignored\n", rule)
+		}
+		if rules.Empty() {
+			fmt.Printf("  no %s policy, all names are allowed\n", certType)
+			continue
+		}
+		results, err := rules.Evaluate(names, certType == "ssh-host")
+		if err != nil {
+			return fmt.Errorf("error evaluating %s: %w", level.name, err)
+		}
+		for _, res := range results {
+			fmt.Printf("  %s: %s\n", res.Name, res.Reason)
+			allowed = allowed && res.Allowed
+		}
+	}
+
+	fmt.Println()
+	if !allowed {
+		return errors.New("the certificate would be denied by the policies")
+	}
+	fmt.Println("The certificate would be allowed by the policies.")
+	return nil
+}
+
+// readCertificateNames returns the names of an X.509 or SSH certificate and
+// the type of certificate.
+func readCertificateNames(filename, certType string) ([]policyutil.Name, string, error) {
+	b, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if pub, _, _, _, err := ssh.ParseAuthorizedKey(b); err == nil {
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok {
+			return nil, "", fmt.Errorf("error parsing %s: file is not an SSH certificate", filename)
+		}
+		typ := "ssh-user"
+		if cert.CertType == ssh.HostCert {
+			typ = "ssh-host"
+		}
+		if certType != "" && certType != typ {
+			return nil, "", fmt.Errorf("error parsing %s: file is an %s certificate", filename, typ)
+		}
+		names := make([]policyutil.Name, len(cert.ValidPrincipals))
+		for i, p := range cert.ValidPrincipals {
+			names[i] = policyutil.Name{Kind: policyutil.Principal, Value: p}
+		}
+		return names, typ, nil
+	}
+
+	if certType != "" && certType != "x509" {
+		return nil, "", fmt.Errorf("error parsing %s: file is not an SSH certificate", filename)
+	}
+	crt, err := pemutil.ReadCertificate(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	return x509Names(crt.Subject.CommonName, crt.DNSNames, crt.IPAddresses, crt.EmailAddresses, crt.URIs), "x509", nil
+}
+
+func x509Names(cn string, dnsNames []string, ips []net.IP, emails []string, uris []*url.URL) []policyutil.Name {
+	var names []policyutil.Name
+	if cn != "" {
+		names = append(names, policyutil.Name{Kind: policyutil.CommonName, Value: cn})
+	}
+	for _, s := range dnsNames {
+		names = append(names, policyutil.Name{Kind: policyutil.DNS, Value: s})
+	}
+	for _, ip := range ips {
+		names = append(names, policyutil.Name{Kind: policyutil.IP, Value: ip.String()})
+	}
+	for _, s := range emails {
+		names = append(names, policyutil.Name{Kind: policyutil.Email, Value: s})
+	}
+	for _, u := range uris {
+		names = append(names, policyutil.Name{Kind: policyutil.URI, Value: u.String()})
+	}
+	return names
+}
+
+// getPolicyLevels returns the authority, provisioner, and ACME account
+// policies selected by the flags, from the CA or from a file.
+func getPolicyLevels(ctx *cli.Context) ([]policyLevel, error) {
+	var (
+		provisioner = ctx.String("provisioner")
+		reference   = ctx.String("eab-key-reference")
+		keyID       = ctx.String("eab-key-id")
+		filename    = ctx.String("file")
+	)
+	if provisioner == "" && (reference != "" || keyID != "") {
+		return nil, errs.RequiredWithFlag(ctx, "eab-key-id", "provisioner")
+	}
+
+	var (
+		authority    *linkedca.Policy
+		provisioners map[string]*linkedca.Policy
+		client       *ca.AdminClient
+		err          error
+	)
+	if filename != "" {
+		if reference != "" || keyID != "" {
+			return nil, errs.IncompatibleFlagWithFlag(ctx, "file", "eab-key-id")
+		}
+		if authority, provisioners, err = readPolicies(filename); err != nil {
+			return nil, err
+		}
+	} else {
+		if client, err = cautils.NewAdminClient(ctx); err != nil {
+			return nil, fmt.Errorf("error creating admin client: %w", err)
+		}
+		if authority, provisioners, err = getPolicies(client); err != nil {
+			return nil, err
+		}
+	}
+
+	levels := []policyLevel{{name: "authority policy", policy: authority}}
+	if provisioner == "" {
+		return levels, nil
+	}
+	policy, ok := provisioners[provisioner]
+	if !ok && filename == "" {
+		return nil, fmt.Errorf("provisioner %q not found", provisioner)
+	}
+	levels = append(levels, policyLevel{name: fmt.Sprintf("provisioner %q policy", provisioner), policy: policy})
+
+	if reference == "" && keyID == "" {
+		return levels, nil
+	}
+	policy, err = client.GetACMEPolicy(provisioner, reference, keyID)
+	if err != nil {
+		var ae *ca.AdminClientError
+		if !errors.As(err, &ae) || ae.Type != "notFound" {
+			return nil, fmt.Errorf("error retrieving ACME policy: %w", err)
+		}
+		policy = nil
+	}
+	name := "ACME account policy"
+	if reference != "" {
+		name = fmt.Sprintf("ACME account %q policy", reference)
+	}
+	return append(levels, policyLevel{name: name, policy: policy}), nil
+}
+
+// policyRules returns the rules of a policy for the given type of certificate.
+func policyRules(p *linkedca.Policy, certType string) *policyutil.Rules {
+	switch certType {
+	case "ssh-host":
+		host := p.GetSsh().GetHost()
+		if host == nil {
+			return nil
+		}
+		return &policyutil.Rules{
+			Allow:              sshHostNames(host.GetAllow()),
+			Deny:               sshHostNames(host.GetDeny()),
+			AllowWildcardNames: true,
+		}
+	case "ssh-user":
+		user := p.GetSsh().GetUser()
+		if user == nil {
+			return nil
+		}
+		return &policyutil.Rules{
+			Allow:              sshUserNames(user.GetAllow()),
+			Deny:               sshUserNames(user.GetDeny()),
+			AllowWildcardNames: true,
+		}
+	default:
+		x := p.GetX509()
+		if x == nil {
+			return nil
+		}
+		return &policyutil.Rules{
+			Allow:              x509PolicyNames(x.GetAllow()),
+			Deny:               x509PolicyNames(x.GetDeny()),
+			AllowWildcardNames: x.GetAllowWildcardNames(),
+		}
+	}
+}
+
+func x509PolicyNames(n *linkedca.X509Names) policyutil.Names {
+	if n == nil {
+		return policyutil.Names{}
+	}
+	return policyutil.Names{
+		DNS:             n.Dns,
+		DNSRegex:        n.DnsRegex,
+		IPs:             n.Ips,
+		Emails:          n.Emails,
+		EmailRegex:      n.EmailRegex,
+		URIs:            n.Uris,
+		URIConstraints:  n.UriConstraints,
+		URIRegex:        n.UriRegex,
+		CommonNames:     n.CommonNames,
+		CommonNameRegex: n.CommonNameRegex,
+	}
+}
+
+func sshHostNames(n *linkedca.SSHHostNames) policyutil.Names {
+	if n == nil {
+		return policyutil.Names{}
+	}
+	return policyutil.Names{
+		DNS:            n.Dns,
+		DNSRegex:       n.DnsRegex,
+		IPs:            n.Ips,
+		Principals:     n.Principals,
+		PrincipalRegex: n.PrincipalRegex,
+	}
+}
+
+func sshUserNames(n *linkedca.SSHUserNames) policyutil.Names {
+	if n == nil {
+		return policyutil.Names{}
+	}
+	return policyutil.Names{
+		Emails:         n.Emails,
+		EmailRegex:     n.EmailRegex,
+		Principals:     n.Principals,
+		PrincipalRegex: n.PrincipalRegex,
+	}
+}
@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/smallstep/certificates/ca"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/applyutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func exportCommand() cli.Command {
+	return cli.Command{
+		Name:   "export",
+		Action: cli.ActionFunc(exportAction),
+		Usage:  "export the certificate issuance policies of the CA",
+		UsageText: `**step ca policy export** [**--format**=<format>]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "format",
+				Usage: `The output <format>. The default is yaml.
+
+: <format> is a case-sensitive string and must be one of:
+
+    **yaml**
+    :  Print the policies in YAML.
+
+    **json**
+    :  Print the policies in JSON.`,
+				Value: "yaml",
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca policy export** prints the authority and provisioner certificate
+issuance policies of the CA in a stable YAML or JSON representation that can be
+used with **step ca policy import**.
+
+The output has an "authority" property with the authority policy, and a
+"provisioners" property with the policy of each provisioner by name. ACME EAB
+policies are not exported.
+
+## EXAMPLES
+
+Export the policies to a file:
+'''
+$ step ca policy export > policies.yaml
+'''
+
+Export the policies in JSON:
+'''
+$ step ca policy export --format json
+'''`,
+	}
+}
+
+func exportAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	format := ctx.String("format")
+	if format != "yaml" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "yaml, json")
+	}
+
+	client, err := cautils.NewAdminClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating admin client: %w", err)
+	}
+	authority, provisioners, err := getPolicies(client)
+	if err != nil {
+		return err
+	}
+
+	var v policiesConfig
+	if v.Authority, err = marshalPolicy(authority); err != nil {
+		return err
+	}
+	v.Provisioners = make(map[string]json.RawMessage, len(provisioners))
+	for name, policy := range provisioners {
+		if v.Provisioners[name], err = marshalPolicy(policy); err != nil {
+			return err
+		}
+	}
+
+	b, err := applyutil.Marshal(v, format)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+// policiesConfig is the file with the policies of the CA.
+type policiesConfig struct {
+	Authority    json.RawMessage            `json:"authority,omitempty"`
+	Provisioners map[string]json.RawMessage `json:"provisioners,omitempty"`
+}
+
+// getPolicies returns the authority policy and the policies of the
+// provisioners by name. Policies that are not set are nil.
+func getPolicies(client *ca.AdminClient) (*linkedca.Policy, map[string]*linkedca.Policy, error) {
+	authority, err := client.GetAuthorityPolicy()
+	if err != nil {
+		var ae *ca.AdminClientError
+		if !errors.As(err, &ae) || ae.Type != "notFound" {
+			return nil, nil, fmt.Errorf("error retrieving authority policy: %w", err)
+		}
+		authority = nil
+	}
+
+	list, err := client.GetProvisioners()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving provisioners: %w", err)
+	}
+	provisioners := make(map[string]*linkedca.Policy, len(list))
+	for _, p := range list {
+		prov, err := client.GetProvisioner(ca.WithProvisionerName(p.GetName()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error retrieving provisioner %q: %w", p.GetName(), err)
+		}
+		provisioners[prov.Name] = prov.Policy
+	}
+	return authority, provisioners, nil
+}
+
+// marshalPolicy returns the JSON encoding of a policy, or nil if the policy is
+// not set.
+func marshalPolicy(policy *linkedca.Policy) (json.RawMessage, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	b, err := protojson.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling policy: %w", err)
+	}
+	return b, nil
+}
+
+// sortedNames returns the keys of the map in order.
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
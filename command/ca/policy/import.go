@@ -0,0 +1,212 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/applyutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func importCommand() cli.Command {
+	return cli.Command{
+		Name:   "import",
+		Action: cli.ActionFunc(importAction),
+		Usage:  "import certificate issuance policies to the CA",
+		UsageText: `**step ca policy import** **--file**=<file> [**--dry-run**] [**--force**]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file,f",
+				Usage: `The YAML or JSON <file> with the policies to import.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the changes required to import the policies without applying them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Import the policies without asking for confirmation.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca policy import** creates or updates the certificate issuance policies
+in a file created by **step ca policy export**.
+
+The command prints a plan with the policies that will be created or updated,
+and the differences between their current and imported configurations, and
+after a confirmation, applies the changes. Policies in the CA that are not in
+the file are kept. The provisioners in the file must exist in the CA.
+
+## EXAMPLES
+
+Show the changes required to import a file:
+'''
+$ step ca policy import -f policies.yaml --dry-run
+'''
+
+Copy the policies of a staging CA to a production CA:
+'''
+$ step ca policy export --context staging > policies.yaml
+$ step ca policy import -f policies.yaml --context production
+'''`,
+	}
+}
+
+func importAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	filename := ctx.String("file")
+	if filename == "" {
+		return errs.RequiredFlag(ctx, "file")
+	}
+	authority, provisioners, err := readPolicies(filename)
+	if err != nil {
+		return err
+	}
+
+	client, err := cautils.NewAdminClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating admin client: %w", err)
+	}
+	currentAuthority, currentProvisioners, err := getPolicies(client)
+	if err != nil {
+		return err
+	}
+
+	var plan applyutil.Plan
+	if authority != nil {
+		change, err := planPolicy("authority", currentAuthority, authority,
+			func() error {
+				_, err := client.CreateAuthorityPolicy(authority)
+				return err
+			},
+			func() error {
+				_, err := client.UpdateAuthorityPolicy(authority)
+				return err
+			})
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			plan = append(plan, change)
+		}
+	}
+	for _, name := range sortedNames(provisioners) {
+		current, ok := currentProvisioners[name]
+		if !ok {
+			return fmt.Errorf("error importing %s: provisioner %q not found", filename, name)
+		}
+		policy := provisioners[name]
+		change, err := planPolicy(name, current, policy,
+			func() error {
+				_, err := client.CreateProvisionerPolicy(name, policy)
+				return err
+			},
+			func() error {
+				_, err := client.UpdateProvisionerPolicy(name, policy)
+				return err
+			})
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			plan = append(plan, change)
+		}
+	}
+
+	return plan.Run(os.Stdout, "import", ctx.Bool("dry-run"), ctx.Bool("force"))
+}
+
+// readPolicies reads the authority and provisioner policies from a YAML or
+// JSON file.
+func readPolicies(filename string) (*linkedca.Policy, map[string]*linkedca.Policy, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, errs.FileError(err, filename)
+	}
+	var v any
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	if b, err = json.Marshal(v); err != nil {
+		return nil, nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+
+	var cfg policiesConfig
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+
+	var authority *linkedca.Policy
+	if len(cfg.Authority) > 0 && string(cfg.Authority) != "null" {
+		authority = new(linkedca.Policy)
+		if err := protojson.Unmarshal(cfg.Authority, authority); err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s: invalid authority policy: %w", filename, err)
+		}
+	}
+	provisioners := make(map[string]*linkedca.Policy, len(cfg.Provisioners))
+	for name, b := range cfg.Provisioners {
+		policy := new(linkedca.Policy)
+		if err := protojson.Unmarshal(b, policy); err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s: invalid policy for provisioner %q: %w", filename, name, err)
+		}
+		provisioners[name] = policy
+	}
+	return authority, provisioners, nil
+}
+
+// planPolicy returns the change required to turn the current policy into the
+// desired one, or nil if they are equal.
+func planPolicy(name string, current, desired *linkedca.Policy, create, update func() error) (*applyutil.Change, error) {
+	haveState, err := policyState(current)
+	if err != nil {
+		return nil, err
+	}
+	wantState, err := policyState(desired)
+	if err != nil {
+		return nil, err
+	}
+	diff := applyutil.Diff(haveState, wantState)
+	switch {
+	case diff == "", wantState == "":
+		return nil, nil
+	case current == nil:
+		return &applyutil.Change{Action: applyutil.Create, Kind: "policy", Name: name, Diff: diff, Apply: create}, nil
+	default:
+		return &applyutil.Change{Action: applyutil.Update, Kind: "policy", Name: name, Diff: diff, Apply: update}, nil
+	}
+}
+
+// policyState returns the canonical representation of a policy, or an empty
+// string if the policy is not set.
+func policyState(policy *linkedca.Policy) (string, error) {
+	b, err := marshalPolicy(policy)
+	if err != nil || b == nil {
+		return "", err
+	}
+	return applyutil.Canonical(b)
+}
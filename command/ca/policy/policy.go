@@ -22,6 +22,9 @@ func Command() cli.Command {
 			authority.Command(ctx),
 			provisioner.Command(ctx),
 			acme.Command(ctx),
+			evaluateCommand(),
+			exportCommand(),
+			importCommand(),
 		},
 	}
 }
@@ -0,0 +1,576 @@
+package provisioner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smallstep/certificates/ca"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/applyutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func applyCommand() cli.Command {
+	return cli.Command{
+		Name:   "apply",
+		Action: cli.ActionFunc(applyAction),
+		Usage:  "apply a declarative provisioner configuration to the CA",
+		UsageText: `**step ca provisioner apply** **--file**=<file> [**--dry-run**] [**--force**]
+[**--prune-current-admin**] [**--admin-cert**=<file>] [**--admin-key**=<file>]
+[**--admin-subject**=<subject>] [**--admin-provisioner**=<name>]
+[**--admin-password-file**=<file>] [**--ca-url**=<uri>] [**--root**=<file>]
+[**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file,f",
+				Usage: `The YAML or JSON <file> with the desired provisioners.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the changes required to apply the configuration without applying them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Apply the changes without asking for confirmation.`,
+			},
+			cli.BoolFlag{
+				Name: "prune-current-admin",
+				Usage: `Allow the deletion of the provisioner of the admin running the command,
+which will not be able to use the admin API after it.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca provisioner apply** makes the provisioners of the CA match the ones
+in a configuration file, so the configuration can be kept and reviewed in
+version control. This command requires the admin API.
+
+The command compares the provisioners in the file with the ones in the CA, by
+name, and prints a plan with the provisioners, webhooks, and policies that will
+be created, updated, or deleted, and the differences between their current and
+desired configurations. After a confirmation, the changes are applied in order.
+Provisioners in the CA that are not in the file are deleted, except the
+provisioner of the admin running the command, unless the
+**--prune-current-admin** flag is used.
+
+The file contains a list of provisioners, as the top-level value or under the
+"provisioners" key. Each provisioner uses the JSON representation of the
+provisioner objects of the admin API, written in YAML or JSON, and can include
+its "webhooks" and "policy". Properties set by the CA, like the ids, creation
+dates, and webhook secrets, are ignored. The secrets of new webhooks are printed
+when they are created. Secrets with the value "REDACTED", like the ones written
+by **step ca provisioner export --redact**, keep their current values. Other
+values are applied as they are: a webhook without authentication in the file has
+its authentication removed.
+
+## EXAMPLES
+
+Show the changes required to apply a configuration:
+'''
+$ step ca provisioner apply -f provisioners.yaml --dry-run
+'''
+
+Apply a configuration without asking for confirmation:
+'''
+$ step ca provisioner apply -f provisioners.yaml --force
+'''
+
+A configuration with an ACME provisioner with a policy and an OIDC provisioner
+with a webhook:
+'''
+$ cat provisioners.yaml
+provisioners:
+- name: acme
+  type: ACME
+  details:
+    ACME:
+      challenges: [HTTP_01, DNS_01]
+  claims:
+    x509:
+      enabled: true
+      durations:
+        default: 24h
+        max: 72h
+  policy:
+    x509:
+      allow:
+        dns: ["*.internal.example.com"]
+- name: google
+  type: OIDC
+  details:
+    OIDC:
+      clientId: 1087160488420-8qt7bavg3qesdhs6it824mhnfgcfe8il.apps.googleusercontent.com
+      clientSecret: udTrOT3gzrO7W9fDPgZQLfYJ
+      configurationEndpoint: https://accounts.google.com/.well-known/openid-configuration
+      domains: [example.com]
+  webhooks:
+  - name: people
+    url: https://people.example.com/enrich
+    kind: ENRICHING
+    certType: X509
+'''`,
+	}
+}
+
+func applyAction(ctx *cli.Context) error {
+	return applyProvisioners(ctx, true)
+}
+
+// applyProvisioners plans and applies the changes required to make the
+// provisioners of the CA match the ones in the file set by the --file flag.
+// If prune is false, the provisioners, webhooks, and policies that are not in
+// the file are kept, as in the import command.
+func applyProvisioners(ctx *cli.Context, prune bool) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	filename := ctx.String("file")
+	if filename == "" {
+		return errs.RequiredFlag(ctx, "file")
+	}
+	desired, err := readProvisioners(filename)
+	if err != nil {
+		return err
+	}
+
+	client, adminCert, err := cautils.NewAdminClientWithCertificate(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := getProvisioners(client)
+	if err != nil {
+		return err
+	}
+
+	var adminProvisioner string
+	if !ctx.Bool("prune-current-admin") {
+		_, adminProvisioner = cautils.AdminIdentity(adminCert)
+	}
+	plan, err := planProvisioners(client, current, desired, prune, adminProvisioner)
+	if err != nil {
+		return err
+	}
+	verb := "apply"
+	if !prune {
+		verb = "import"
+	}
+	return plan.Run(os.Stdout, verb, ctx.Bool("dry-run"), ctx.Bool("force"))
+}
+
+// readProvisioners reads the desired provisioners from a YAML or JSON file.
+func readProvisioners(filename string) ([]*linkedca.Provisioner, error) {
+	list, err := applyutil.ReadList(filename, "provisioners")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(list))
+	provs := make([]*linkedca.Provisioner, len(list))
+	for i, b := range list {
+		p := new(linkedca.Provisioner)
+		if err := protojson.Unmarshal(b, p); err != nil {
+			return nil, errors.Wrapf(err, "error parsing %s: invalid provisioner at index %d", filename, i)
+		}
+		switch {
+		case p.Name == "":
+			return nil, errors.Errorf("error parsing %s: provisioner at index %d is missing the name", filename, i)
+		case names[p.Name]:
+			return nil, errors.Errorf("error parsing %s: provisioner %q is defined more than once", filename, p.Name)
+		case p.Type == linkedca.Provisioner_NOOP:
+			return nil, errors.Errorf("error parsing %s: provisioner %q is missing the type", filename, p.Name)
+		}
+		names[p.Name] = true
+
+		webhooks := make(map[string]bool, len(p.Webhooks))
+		for _, wh := range p.Webhooks {
+			switch {
+			case wh.Name == "":
+				return nil, errors.Errorf("error parsing %s: provisioner %q has a webhook without name", filename, p.Name)
+			case webhooks[wh.Name]:
+				return nil, errors.Errorf("error parsing %s: provisioner %q webhook %q is defined more than once", filename, p.Name, wh.Name)
+			case wh.Kind == linkedca.Webhook_NO_KIND:
+				wh.Kind = linkedca.Webhook_ENRICHING
+			}
+			webhooks[wh.Name] = true
+		}
+		provs[i] = p
+	}
+	return provs, nil
+}
+
+// getProvisioners returns the provisioners in the CA, sorted by name.
+func getProvisioners(client *ca.AdminClient) ([]*linkedca.Provisioner, error) {
+	list, err := client.GetProvisioners()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting the provisioners")
+	}
+	provs := make([]*linkedca.Provisioner, 0, len(list))
+	for _, p := range list {
+		prov, err := client.GetProvisioner(ca.WithProvisionerName(p.GetName()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting provisioner %q", p.GetName())
+		}
+		provs = append(provs, prov)
+	}
+	sort.Slice(provs, func(i, j int) bool {
+		return provs[i].Name < provs[j].Name
+	})
+	return provs, nil
+}
+
+// planProvisioners returns the changes required to turn the current
+// provisioners into the desired ones. New provisioners are created before
+// their webhooks and policies, and deleted provisioners are deleted at the end.
+// If prune is false, provisioners, webhooks, and policies that are not in the
+// desired configuration are not deleted.
+//
+// The redacted secrets in the desired provisioners, like the ones written by
+// the export command with --redact, keep their current values.
+//
+// The adminProvisioner is the provisioner of the admin running the command.
+// Deleting it locks the admin out of the CA, so it returns an error if it is
+// not in the desired configuration. An empty name allows any deletion.
+func planProvisioners(client *ca.AdminClient, current, desired []*linkedca.Provisioner, prune bool, adminProvisioner string) (applyutil.Plan, error) {
+	byName := make(map[string]*linkedca.Provisioner, len(current))
+	for _, p := range current {
+		byName[p.Name] = p
+	}
+
+	var plan applyutil.Plan
+	for _, want := range desired {
+		have, ok := byName[want.Name]
+		delete(byName, want.Name)
+		if err := restoreSecrets(want, have); err != nil {
+			return nil, err
+		}
+
+		wantState, err := provisionerState(want)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			plan = append(plan, &applyutil.Change{
+				Action: applyutil.Create,
+				Kind:   "provisioner",
+				Name:   want.Name,
+				Diff:   applyutil.Diff("", wantState),
+				Apply: func() error {
+					p := proto.Clone(want).(*linkedca.Provisioner)
+					p.Webhooks, p.Policy = nil, nil
+					_, err := client.CreateProvisioner(p)
+					return err
+				},
+			})
+			have = &linkedca.Provisioner{Name: want.Name}
+		} else {
+			haveState, err := provisionerState(have)
+			if err != nil {
+				return nil, err
+			}
+			if diff := applyutil.Diff(haveState, wantState); diff != "" {
+				plan = append(plan, &applyutil.Change{
+					Action: applyutil.Update,
+					Kind:   "provisioner",
+					Name:   want.Name,
+					Diff:   diff,
+					Apply: func() error {
+						// Webhooks and policies are updated separately.
+						p := proto.Clone(want).(*linkedca.Provisioner)
+						p.Id, p.AuthorityId, p.CreatedAt = have.Id, have.AuthorityId, have.CreatedAt
+						p.Webhooks, p.Policy = have.Webhooks, have.Policy
+						return client.UpdateProvisioner(want.Name, p)
+					},
+				})
+			}
+		}
+
+		changes, err := planWebhooks(client, want.Name, have.Webhooks, want.Webhooks, prune)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, changes...)
+
+		change, err := planPolicy(client, want.Name, have.Policy, want.Policy, prune)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			plan = append(plan, change)
+		}
+	}
+
+	for _, p := range current {
+		if _, ok := byName[p.Name]; !ok || !prune {
+			continue
+		}
+		if p.Name == adminProvisioner {
+			return nil, errors.Errorf("provisioner %q is used by the current admin and cannot be deleted: add it to the file or use '--prune-current-admin'", p.Name)
+		}
+		name := p.Name
+		plan = append(plan, &applyutil.Change{
+			Action: applyutil.Delete,
+			Kind:   "provisioner",
+			Name:   name,
+			Apply: func() error {
+				return client.RemoveProvisioner(ca.WithProvisionerName(name))
+			},
+		})
+	}
+	return plan, nil
+}
+
+// redacted is the value of the secrets removed from an export with --redact.
+// Redacted secrets keep their current values in apply and import.
+const redacted = "REDACTED"
+
+// redactedBytes is the value of the binary secrets removed from an export. It
+// is encoded in JSON and YAML as the base64 string "REDACTED".
+var redactedBytes, _ = base64.StdEncoding.DecodeString(redacted)
+
+func isRedacted(s string) bool {
+	return s == redacted
+}
+
+func isRedactedBytes(b []byte) bool {
+	return bytes.Equal(b, redactedBytes)
+}
+
+// restoreSecrets sets the redacted secrets in the desired provisioner to the
+// values in the current one, which is nil if the provisioner does not exist.
+// Other secrets, including empty ones and webhooks without authentication,
+// are applied as they are. It returns an error if a redacted secret does not
+// have a current value.
+func restoreSecrets(want, have *linkedca.Provisioner) error {
+	missing := func(secret string) error {
+		return errors.Errorf("provisioner %q has a redacted %s that is not set in the CA", want.Name, secret)
+	}
+
+	if jwk := want.GetDetails().GetJWK(); jwk != nil && isRedactedBytes(jwk.EncryptedPrivateKey) {
+		cur := have.GetDetails().GetJWK()
+		if len(cur.GetEncryptedPrivateKey()) == 0 {
+			return missing("encryptedPrivateKey")
+		}
+		jwk.EncryptedPrivateKey = cur.EncryptedPrivateKey
+	}
+	if oidc := want.GetDetails().GetOIDC(); oidc != nil && isRedacted(oidc.ClientSecret) {
+		cur := have.GetDetails().GetOIDC()
+		if cur.GetClientSecret() == "" {
+			return missing("clientSecret")
+		}
+		oidc.ClientSecret = cur.ClientSecret
+	}
+	if scep := want.GetDetails().GetSCEP(); scep != nil {
+		cur := have.GetDetails().GetSCEP()
+		if isRedacted(scep.Challenge) {
+			if cur.GetChallenge() == "" {
+				return missing("challenge")
+			}
+			scep.Challenge = cur.Challenge
+		}
+		if d := scep.GetDecrypter(); d != nil {
+			if isRedactedBytes(d.Key) {
+				if len(cur.GetDecrypter().GetKey()) == 0 {
+					return missing("decrypter key")
+				}
+				d.Key = cur.GetDecrypter().GetKey()
+			}
+			if isRedactedBytes(d.KeyPassword) {
+				if len(cur.GetDecrypter().GetKeyPassword()) == 0 {
+					return missing("decrypter keyPassword")
+				}
+				d.KeyPassword = cur.GetDecrypter().GetKeyPassword()
+			}
+		}
+	}
+
+	webhooks := make(map[string]*linkedca.Webhook, len(have.GetWebhooks()))
+	for _, wh := range have.GetWebhooks() {
+		webhooks[wh.Name] = wh
+	}
+	for _, wh := range want.Webhooks {
+		cur := webhooks[wh.Name]
+		if t := wh.GetBearerToken(); t != nil && isRedacted(t.BearerToken) {
+			if cur.GetBearerToken().GetBearerToken() == "" {
+				return missing("bearer token in webhook " + wh.Name)
+			}
+			t.BearerToken = cur.GetBearerToken().GetBearerToken()
+		}
+		if a := wh.GetBasicAuth(); a != nil && isRedacted(a.Password) {
+			if cur.GetBasicAuth().GetPassword() == "" {
+				return missing("password in webhook " + wh.Name)
+			}
+			a.Password = cur.GetBasicAuth().GetPassword()
+		}
+	}
+	return nil
+}
+
+// planWebhooks returns the changes required to turn the current webhooks of a
+// provisioner into the desired ones.
+func planWebhooks(client *ca.AdminClient, provName string, current, desired []*linkedca.Webhook, prune bool) (applyutil.Plan, error) {
+	byName := make(map[string]*linkedca.Webhook, len(current))
+	for _, wh := range current {
+		byName[wh.Name] = wh
+	}
+
+	var plan applyutil.Plan
+	for _, want := range desired {
+		name := provName + "/" + want.Name
+		have, ok := byName[want.Name]
+		delete(byName, want.Name)
+
+		wantState, err := webhookState(want)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			plan = append(plan, &applyutil.Change{
+				Action: applyutil.Create,
+				Kind:   "webhook",
+				Name:   name,
+				Diff:   applyutil.Diff("", wantState),
+				Apply: func() error {
+					wh, err := client.CreateProvisionerWebhook(provName, want)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Webhook %s\nWebhook ID: %s\nSecret: %s\n", name, wh.Id, wh.Secret)
+					return nil
+				},
+			})
+			continue
+		}
+
+		haveState, err := webhookState(have)
+		if err != nil {
+			return nil, err
+		}
+		if diff := applyutil.Diff(haveState, wantState); diff != "" {
+			plan = append(plan, &applyutil.Change{
+				Action: applyutil.Update,
+				Kind:   "webhook",
+				Name:   name,
+				Diff:   diff,
+				Apply: func() error {
+					wh := proto.Clone(want).(*linkedca.Webhook)
+					wh.Id, wh.Secret = have.Id, have.Secret
+					_, err := client.UpdateProvisionerWebhook(provName, wh)
+					return err
+				},
+			})
+		}
+	}
+
+	for _, wh := range current {
+		if _, ok := byName[wh.Name]; !ok || !prune {
+			continue
+		}
+		whName := wh.Name
+		plan = append(plan, &applyutil.Change{
+			Action: applyutil.Delete,
+			Kind:   "webhook",
+			Name:   provName + "/" + whName,
+			Apply: func() error {
+				return client.DeleteProvisionerWebhook(provName, whName)
+			},
+		})
+	}
+	return plan, nil
+}
+
+// planPolicy returns the change required to turn the current policy of a
+// provisioner into the desired one, or nil if they are equal.
+func planPolicy(client *ca.AdminClient, provName string, current, desired *linkedca.Policy, prune bool) (*applyutil.Change, error) {
+	haveState, err := messageState(current)
+	if err != nil {
+		return nil, err
+	}
+	wantState, err := messageState(desired)
+	if err != nil {
+		return nil, err
+	}
+	diff := applyutil.Diff(haveState, wantState)
+	switch {
+	case diff == "", wantState == "" && !prune:
+		return nil, nil
+	case haveState == "":
+		return &applyutil.Change{
+			Action: applyutil.Create,
+			Kind:   "policy",
+			Name:   provName,
+			Diff:   diff,
+			Apply: func() error {
+				_, err := client.CreateProvisionerPolicy(provName, desired)
+				return err
+			},
+		}, nil
+	case wantState == "":
+		return &applyutil.Change{
+			Action: applyutil.Delete,
+			Kind:   "policy",
+			Name:   provName,
+			Apply: func() error {
+				return client.RemoveProvisionerPolicy(provName)
+			},
+		}, nil
+	default:
+		return &applyutil.Change{
+			Action: applyutil.Update,
+			Kind:   "policy",
+			Name:   provName,
+			Diff:   diff,
+			Apply: func() error {
+				_, err := client.UpdateProvisionerPolicy(provName, desired)
+				return err
+			},
+		}, nil
+	}
+}
+
+// provisionerState returns the canonical representation of the properties of
+// a provisioner managed with UpdateProvisioner.
+func provisionerState(p *linkedca.Provisioner) (string, error) {
+	p = proto.Clone(p).(*linkedca.Provisioner)
+	p.Id, p.AuthorityId, p.CreatedAt, p.DeletedAt = "", "", nil, nil
+	p.Webhooks, p.Policy = nil, nil
+	return messageState(p)
+}
+
+// webhookState returns the canonical representation of a webhook without the
+// properties generated by the CA.
+func webhookState(wh *linkedca.Webhook) (string, error) {
+	wh = proto.Clone(wh).(*linkedca.Webhook)
+	wh.Id, wh.Secret = "", ""
+	return messageState(wh)
+}
+
+// messageState returns the canonical representation of a message, or an empty
+// string if the message is nil or empty.
+func messageState(m proto.Message) (string, error) {
+	if !m.ProtoReflect().IsValid() {
+		return "", nil
+	}
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshaling configuration")
+	}
+	return applyutil.Canonical(b)
+}
@@ -0,0 +1,122 @@
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smallstep/linkedca"
+)
+
+func newSecretProvisioners() (jwk, scep *linkedca.Provisioner) {
+	jwk = &linkedca.Provisioner{
+		Name: "jwk",
+		Type: linkedca.Provisioner_JWK,
+		Details: &linkedca.ProvisionerDetails{Data: &linkedca.ProvisionerDetails_JWK{JWK: &linkedca.JWKProvisioner{
+			PublicKey:           []byte(`{"kty":"EC"}`),
+			EncryptedPrivateKey: []byte("encrypted-key"),
+		}}},
+		Webhooks: []*linkedca.Webhook{
+			{Name: "bearer", Auth: &linkedca.Webhook_BearerToken{BearerToken: &linkedca.BearerToken{BearerToken: "token"}}},
+			{Name: "basic", Auth: &linkedca.Webhook_BasicAuth{BasicAuth: &linkedca.BasicAuth{Username: "user", Password: "password"}}},
+		},
+	}
+	scep = &linkedca.Provisioner{
+		Name: "scep",
+		Type: linkedca.Provisioner_SCEP,
+		Details: &linkedca.ProvisionerDetails{Data: &linkedca.ProvisionerDetails_SCEP{SCEP: &linkedca.SCEPProvisioner{
+			Challenge: "challenge",
+			Decrypter: &linkedca.SCEPDecrypter{Key: []byte("key"), KeyPassword: []byte("key-password")},
+		}}},
+	}
+	return jwk, scep
+}
+
+func TestRedactAndRestoreSecrets(t *testing.T) {
+	jwk, scep := newSecretProvisioners()
+	for _, have := range []*linkedca.Provisioner{jwk, scep} {
+		want := exportedProvisioner(have)
+		redactSecrets(want)
+
+		// The redacted provisioner goes through the export file.
+		b, err := protojson.Marshal(want)
+		require.NoError(t, err)
+		want = new(linkedca.Provisioner)
+		require.NoError(t, protojson.Unmarshal(b, want))
+		assert.False(t, proto.Equal(exportedProvisioner(have), want))
+
+		require.NoError(t, restoreSecrets(want, have))
+		assert.True(t, proto.Equal(exportedProvisioner(have), want), "%s: %v", have.Name, want)
+	}
+
+	b, err := protojson.Marshal(func() *linkedca.Provisioner {
+		p := exportedProvisioner(jwk)
+		redactSecrets(p)
+		return p
+	}())
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"encryptedPrivateKey":"REDACTED"`)
+	assert.Contains(t, string(b), `"bearerToken":"REDACTED"`)
+	assert.Contains(t, string(b), `"password":"REDACTED"`)
+}
+
+func TestRestoreSecrets(t *testing.T) {
+	jwk, _ := newSecretProvisioners()
+
+	t.Run("ok/removed-webhook-auth", func(t *testing.T) {
+		want := exportedProvisioner(jwk)
+		want.Webhooks[0].Auth = nil
+		require.NoError(t, restoreSecrets(want, jwk))
+		assert.Nil(t, want.Webhooks[0].Auth)
+	})
+
+	t.Run("ok/new-values", func(t *testing.T) {
+		want := exportedProvisioner(jwk)
+		want.GetDetails().GetJWK().EncryptedPrivateKey = []byte("new-key")
+		want.Webhooks[0].GetBearerToken().BearerToken = "new-token"
+		want.Webhooks[1].GetBasicAuth().Password = ""
+		require.NoError(t, restoreSecrets(want, jwk))
+		assert.Equal(t, []byte("new-key"), want.GetDetails().GetJWK().EncryptedPrivateKey)
+		assert.Equal(t, "new-token", want.Webhooks[0].GetBearerToken().BearerToken)
+		assert.Empty(t, want.Webhooks[1].GetBasicAuth().Password)
+	})
+
+	t.Run("fail/new-provisioner", func(t *testing.T) {
+		want := exportedProvisioner(jwk)
+		redactSecrets(want)
+		assert.EqualError(t, restoreSecrets(want, nil),
+			`provisioner "jwk" has a redacted encryptedPrivateKey that is not set in the CA`)
+	})
+
+	t.Run("fail/new-webhook", func(t *testing.T) {
+		want := exportedProvisioner(jwk)
+		redactSecrets(want)
+		want.Webhooks[0].Name = "other"
+		assert.EqualError(t, restoreSecrets(want, jwk),
+			`provisioner "jwk" has a redacted bearer token in webhook other that is not set in the CA`)
+	})
+}
+
+func TestPlanProvisioners_currentAdmin(t *testing.T) {
+	current := []*linkedca.Provisioner{
+		{Name: "admin", Type: linkedca.Provisioner_JWK},
+		{Name: "other", Type: linkedca.Provisioner_ACME},
+	}
+	desired := []*linkedca.Provisioner{
+		{Name: "acme", Type: linkedca.Provisioner_ACME},
+	}
+
+	_, err := planProvisioners(nil, current, desired, true, "admin")
+	assert.EqualError(t, err, `provisioner "admin" is used by the current admin and cannot be deleted: add it to the file or use '--prune-current-admin'`)
+
+	// The provisioner is kept without prune, and deleted if allowed.
+	plan, err := planProvisioners(nil, current, desired, false, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 1 to create, 0 to update, 0 to delete.", plan.Summary())
+	plan, err = planProvisioners(nil, current, desired, true, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Plan: 1 to create, 0 to update, 2 to delete.", plan.Summary())
+}
@@ -0,0 +1,221 @@
+package provisioner
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smallstep/certificates/authority"
+	"github.com/smallstep/certificates/authority/config"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/linkedca"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/applyutil"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func exportCommand() cli.Command {
+	return cli.Command{
+		Name:   "export",
+		Action: cli.ActionFunc(exportAction),
+		Usage:  "export the provisioners of the CA",
+		UsageText: `**step ca provisioner export** [**--format**=<format>] [**--redact**]
+[**--ca-config**=<file>] [**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			exportFormatFlag,
+			cli.BoolFlag{
+				Name: "redact",
+				Usage: `Replace the secrets in the export with the value "REDACTED": the encrypted
+keys of JWK provisioners, the client secrets of OIDC provisioners, the challenges
+and decrypter keys of SCEP provisioners, and the passwords and bearer tokens of
+webhooks. Binary secrets are written as the base64 string "REDACTED". Redacted
+secrets keep their current values in **step ca provisioner apply** and
+**step ca provisioner import**.`,
+			},
+			cli.StringFlag{
+				Name: "ca-config",
+				Usage: `Export the provisioners in the "authority.provisioners" property of the
+certificate authority configuration <file> (ca.json) instead of using the admin
+API.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca provisioner export** prints the provisioners of the CA, including their
+webhooks and policies, in a stable YAML or JSON representation that can be used
+with **step ca provisioner import** and **step ca provisioner apply**. This
+command requires the admin API, unless the **--ca-config** flag is used to read
+the provisioners from the configuration file of a CA. With the latter, the
+provisioners in a ca.json can be moved to the database of a CA with the admin
+API enabled.
+
+Properties set by the CA, like the ids, creation dates, and webhook secrets, are
+not exported.
+
+## EXAMPLES
+
+Export the provisioners to a file:
+'''
+$ step ca provisioner export > provisioners.yaml
+'''
+
+Export the provisioners without secrets, in JSON:
+'''
+$ step ca provisioner export --redact --format json > provisioners.json
+'''
+
+Copy the provisioners of a staging CA to a production CA:
+'''
+$ step ca provisioner export --context staging > provisioners.yaml
+$ step ca provisioner import -f provisioners.yaml --context production
+'''
+
+Move the provisioners in a ca.json to a CA with the admin API enabled:
+'''
+$ step ca provisioner export --ca-config $(step path)/config/ca.json > provisioners.yaml
+$ step ca provisioner import -f provisioners.yaml
+'''`,
+	}
+}
+
+var exportFormatFlag = cli.StringFlag{
+	Name: "format",
+	Usage: `The output <format>. The default is yaml.
+
+: <format> is a case-sensitive string and must be one of:
+
+    **yaml**
+    :  Print the configuration in YAML.
+
+    **json**
+    :  Print the configuration in JSON.`,
+	Value: "yaml",
+}
+
+func exportAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	format := ctx.String("format")
+	if format != "yaml" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "yaml, json")
+	}
+
+	var provs []*linkedca.Provisioner
+	if cfgFile := ctx.String("ca-config"); cfgFile != "" {
+		var err error
+		if provs, err = readConfigProvisioners(cfgFile); err != nil {
+			return err
+		}
+	} else {
+		client, err := cautils.NewAdminClient(ctx)
+		if err != nil {
+			return err
+		}
+		if provs, err = getProvisioners(client); err != nil {
+			return err
+		}
+	}
+
+	b, err := marshalProvisioners(provs, format, ctx.Bool("redact"))
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+// readConfigProvisioners returns the provisioners in the authority
+// configuration of a ca.json file, sorted by name.
+func readConfigProvisioners(filename string) ([]*linkedca.Provisioner, error) {
+	cfg, err := config.LoadConfiguration(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading configuration")
+	}
+	provs := make([]*linkedca.Provisioner, len(cfg.AuthorityConfig.Provisioners))
+	for i, p := range cfg.AuthorityConfig.Provisioners {
+		if provs[i], err = authority.ProvisionerToLinkedca(p); err != nil {
+			return nil, errors.Wrapf(err, "error converting provisioner %q", p.GetName())
+		}
+	}
+	sort.Slice(provs, func(i, j int) bool {
+		return provs[i].Name < provs[j].Name
+	})
+	return provs, nil
+}
+
+// marshalProvisioners returns the configuration file with the given
+// provisioners, without the properties set by the CA.
+func marshalProvisioners(provs []*linkedca.Provisioner, format string, redact bool) ([]byte, error) {
+	list := make([]json.RawMessage, len(provs))
+	for i, p := range provs {
+		p = exportedProvisioner(p)
+		if redact {
+			redactSecrets(p)
+		}
+		b, err := protojson.Marshal(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error marshaling provisioner %q", p.Name)
+		}
+		list[i] = b
+	}
+	return applyutil.Marshal(map[string]any{"provisioners": list}, format)
+}
+
+// exportedProvisioner returns a copy of the provisioner without the
+// properties set by the CA.
+func exportedProvisioner(p *linkedca.Provisioner) *linkedca.Provisioner {
+	p = proto.Clone(p).(*linkedca.Provisioner)
+	p.Id, p.AuthorityId, p.CreatedAt, p.DeletedAt = "", "", nil, nil
+	for _, wh := range p.Webhooks {
+		wh.Id, wh.Secret = "", ""
+	}
+	return p
+}
+
+// redactSecrets replaces the secrets of a provisioner and its webhooks with
+// the redacted marker.
+func redactSecrets(p *linkedca.Provisioner) {
+	if jwk := p.GetDetails().GetJWK(); jwk != nil && len(jwk.EncryptedPrivateKey) > 0 {
+		jwk.EncryptedPrivateKey = redactedBytes
+	}
+	if oidc := p.GetDetails().GetOIDC(); oidc != nil && oidc.ClientSecret != "" {
+		oidc.ClientSecret = redacted
+	}
+	if scep := p.GetDetails().GetSCEP(); scep != nil {
+		if scep.Challenge != "" {
+			scep.Challenge = redacted
+		}
+		if d := scep.GetDecrypter(); d != nil {
+			if len(d.Key) > 0 {
+				d.Key = redactedBytes
+			}
+			if len(d.KeyPassword) > 0 {
+				d.KeyPassword = redactedBytes
+			}
+		}
+	}
+	for _, wh := range p.Webhooks {
+		if t := wh.GetBearerToken(); t != nil && t.BearerToken != "" {
+			t.BearerToken = redacted
+		}
+		if a := wh.GetBasicAuth(); a != nil && a.Password != "" {
+			a.Password = redacted
+		}
+	}
+}
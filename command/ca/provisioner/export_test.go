@@ -0,0 +1,65 @@
+package provisioner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smallstep/linkedca"
+)
+
+func TestReadConfigProvisioners(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "ca.json")
+	require.NoError(t, os.WriteFile(cfgFile, []byte(`{
+	"root": "root_ca.crt",
+	"authority": {
+		"provisioners": [{
+			"type": "OIDC",
+			"name": "google",
+			"clientID": "client-id",
+			"clientSecret": "client-secret",
+			"configurationEndpoint": "https://accounts.google.com/.well-known/openid-configuration",
+			"domains": ["example.com"]
+		}, {
+			"type": "ACME",
+			"name": "acme",
+			"challenges": ["http-01", "dns-01"],
+			"claims": {"maxTLSCertDuration": "72h"}
+		}]
+	}
+}`), 0o600))
+
+	provs, err := readConfigProvisioners(cfgFile)
+	require.NoError(t, err)
+	require.Len(t, provs, 2)
+	assert.Equal(t, "acme", provs[0].Name)
+	assert.Equal(t, linkedca.Provisioner_ACME, provs[0].Type)
+	assert.Equal(t, []linkedca.ACMEProvisioner_ChallengeType{
+		linkedca.ACMEProvisioner_HTTP_01, linkedca.ACMEProvisioner_DNS_01,
+	}, provs[0].GetDetails().GetACME().Challenges)
+	assert.Equal(t, "72h0m0s", provs[0].GetClaims().GetX509().GetDurations().GetMax())
+	assert.Equal(t, "google", provs[1].Name)
+	assert.Equal(t, "client-secret", provs[1].GetDetails().GetOIDC().ClientSecret)
+
+	// The export can be read by the import and apply commands.
+	for _, format := range []string{"yaml", "json"} {
+		b, err := marshalProvisioners(provs, format, false)
+		require.NoError(t, err)
+		filename := filepath.Join(dir, "provisioners."+format)
+		require.NoError(t, os.WriteFile(filename, b, 0o600))
+		got, err := readProvisioners(filename)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, "acme", got[0].Name)
+		assert.Equal(t, provs[0].GetDetails().GetACME().Challenges, got[0].GetDetails().GetACME().Challenges)
+		assert.Equal(t, "google", got[1].Name)
+		assert.Equal(t, "client-secret", got[1].GetDetails().GetOIDC().ClientSecret)
+	}
+
+	_, err = readConfigProvisioners(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "error loading configuration")
+}
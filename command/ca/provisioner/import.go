@@ -0,0 +1,66 @@
+package provisioner
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+)
+
+func importCommand() cli.Command {
+	return cli.Command{
+		Name:   "import",
+		Action: cli.ActionFunc(importAction),
+		Usage:  "import provisioners to the CA",
+		UsageText: `**step ca provisioner import** **--file**=<file> [**--dry-run**] [**--force**]
+[**--admin-cert**=<file>] [**--admin-key**=<file>] [**--admin-subject**=<subject>]
+[**--admin-provisioner**=<name>] [**--admin-password-file**=<file>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "file,f",
+				Usage: `The YAML or JSON <file> with the provisioners to import.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the changes required to import the provisioners without applying them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Import the provisioners without asking for confirmation.`,
+			},
+			flags.AdminCert,
+			flags.AdminKey,
+			flags.AdminSubject,
+			flags.AdminProvisioner,
+			flags.AdminPasswordFile,
+			flags.CaURL,
+			flags.Root,
+			flags.Context,
+		},
+		Description: `**step ca provisioner import** creates or updates the provisioners in a file
+created by **step ca provisioner export**. This command requires the admin API.
+
+The file uses the same format as **step ca provisioner apply**, but unlike
+apply, the provisioners, webhooks, and policies in the CA that are not in the
+file are kept. Secrets with the value "REDACTED", like the ones written by
+**step ca provisioner export --redact**, keep their current values; they cannot
+be used in new provisioners.
+
+## EXAMPLES
+
+Show the changes required to import a file:
+'''
+$ step ca provisioner import -f provisioners.yaml --dry-run
+'''
+
+Import the provisioners of another CA:
+'''
+$ step ca provisioner export --context staging > provisioners.yaml
+$ step ca provisioner import -f provisioners.yaml --context production
+'''`,
+	}
+}
+
+func importAction(ctx *cli.Context) error {
+	return applyProvisioners(ctx, false)
+}
@@ -34,6 +34,9 @@ func Command() cli.Command {
 			addCommand(),
 			updateCommand(),
 			removeCommand(),
+			applyCommand(),
+			exportCommand(),
+			importCommand(),
 			webhook.Command(),
 		},
 		Description: `**step ca provisioner** command group provides facilities for managing the
@@ -91,6 +94,16 @@ $ step ca provisioner add max@smallstep.com max-laptop.jwk --ca-config ca.json
 Remove the provisioner matching a given issuer and kid:
 '''
 $ step ca provisioner remove max@smallstep.com --kid 1234 --ca-config ca.json
+'''
+
+Apply the provisioners in a configuration file:
+'''
+$ step ca provisioner apply -f provisioners.yaml
+'''
+
+Export the provisioners without their secrets:
+'''
+$ step ca provisioner export --redact > provisioners.yaml
 '''`,
 	}
 }
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/internal/webhookutil"
+)
+
+func serveCommand() cli.Command {
+	return cli.Command{
+		Name:   "serve",
+		Action: cli.ActionFunc(serveAction),
+		Usage:  "run a local webhook server for development and testing",
+		UsageText: `**step ca provisioner webhook serve**
+[**--secret**=<secret>] [**--secret-file**=<file>] [**--id**=<id>]
+[**--address**=<address>] [**--response**=<file>] [**--exec**=<command>]
+[**--cert**=<file>] [**--key**=<file>]`,
+		Flags: []cli.Flag{
+			secretFlag,
+			secretFileFlag,
+			idFlag,
+			cli.StringFlag{
+				Name:  "address",
+				Usage: `The TCP <address> to listen on (e.g. ":8443").`,
+				Value: ":8080",
+			},
+			cli.StringFlag{
+				Name: "response",
+				Usage: `The <file> containing the JSON body sent in response to every valid request.
+Defaults to '{"allow": true}'.`,
+			},
+			cli.StringFlag{
+				Name: "exec",
+				Usage: `The <command> to run for every valid request. The command reads the request
+body from its standard input and must write the JSON response body to its standard output.`,
+			},
+			cli.StringFlag{
+				Name:  "cert",
+				Usage: `The <file> containing the TLS certificate of the server.`,
+			},
+			cli.StringFlag{
+				Name:  "key",
+				Usage: `The <file> containing the key corresponding to the certificate.`,
+			},
+		},
+		Description: `**step ca provisioner webhook serve** runs a webhook server that can be used to
+develop and test the configuration of provisioner webhooks.
+
+The server verifies the X-Smallstep-Signature header of every request with the
+webhook secret, prints the webhook ID, the result of the verification and the
+request body, and replies with a canned or scripted response. Requests with an
+invalid signature, or with a webhook ID other than **--id**, are rejected with
+the status 401.
+
+The same request body is sent to ENRICHING and AUTHORIZING webhooks. A
+response must be a JSON object with the boolean property "allow", ENRICHING
+webhooks can also set the property "data" with the values added to the
+template context.
+
+When using **--exec**, the webhook ID and the path of the request are available
+to the command in the STEP_WEBHOOK_ID and STEP_WEBHOOK_PATH environment
+variables. A command that fails or writes an invalid response results in a
+response with the status 500.
+
+## EXAMPLES
+
+Run a server that allows all requests:
+'''
+$ step ca provisioner webhook serve --secret-file secret.txt
+'''
+
+Run an HTTPS server that replies with the contents of a file:
+'''
+$ cat response.json
+{"allow": true, "data": {"role": "eng"}}
+$ step ca provisioner webhook serve --secret-file secret.txt --id 7d2d2cd8-4f4b-4dd1-a94e-4fe0f3a7ec3a \
+  --address :8443 --cert localhost.crt --key localhost.key --response response.json
+'''
+
+Run a server that uses a script to build the responses:
+'''
+$ cat enrich.sh
+#!/bin/sh
+jq '{allow: true, data: {cn: .x509CertificateRequest.subject.commonName}}'
+$ step ca provisioner webhook serve --secret-file secret.txt --exec ./enrich.sh
+'''`,
+	}
+}
+
+func serveAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	secret, err := readSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := webhookutil.Sign(secret, nil); err != nil {
+		return err
+	}
+
+	address := ctx.String("address")
+	responseFile := ctx.String("response")
+	execCmd := strings.TrimSpace(ctx.String("exec"))
+	cert := ctx.String("cert")
+	key := ctx.String("key")
+
+	switch {
+	case address == "":
+		return errs.RequiredFlag(ctx, "address")
+	case responseFile != "" && execCmd != "":
+		return errs.IncompatibleFlagWithFlag(ctx, "response", "exec")
+	case cert != "" && key == "":
+		return errs.RequiredWithFlag(ctx, "cert", "key")
+	case key != "" && cert == "":
+		return errs.RequiredWithFlag(ctx, "key", "cert")
+	}
+
+	var responder webhookutil.Responder
+	switch {
+	case responseFile != "":
+		b, err := os.ReadFile(responseFile)
+		if err != nil {
+			return errs.FileError(err, responseFile)
+		}
+		if _, err := webhookutil.ParseResponse(b); err != nil {
+			return fmt.Errorf("error reading %s: %w", responseFile, err)
+		}
+		responder = webhookutil.StaticResponder(b)
+	case execCmd != "":
+		parts := strings.Fields(execCmd)
+		responder = webhookutil.CommandResponder(parts[0], parts[1:]...)
+	default:
+		responder = webhookutil.StaticResponder([]byte(`{"allow": true}`))
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("error listening at %s: %w", address, err)
+	}
+
+	srv := &http.Server{
+		Handler: &webhookutil.Handler{
+			Secret:    secret,
+			ID:        ctx.String("id"),
+			Responder: responder,
+			Out:       os.Stdout,
+		},
+		ReadHeaderTimeout: 15 * time.Second,
+	}
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if cert != "" {
+		fmt.Fprintf(os.Stderr, "Serving webhook requests at https://%s\n", l.Addr())
+		err = srv.ServeTLS(l, cert, key)
+	} else {
+		fmt.Fprintf(os.Stderr, "Serving webhook requests at http://%s\n", l.Addr())
+		err = srv.Serve(l)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server failed: %w", err)
+	}
+	return nil
+}
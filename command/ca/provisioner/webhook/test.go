@@ -0,0 +1,234 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/internal/webhookutil"
+	"github.com/smallstep/cli/utils"
+)
+
+func testCommand() cli.Command {
+	return cli.Command{
+		Name:   "test",
+		Action: cli.ActionFunc(testAction),
+		Usage:  "send a sample signed request to a webhook server",
+		UsageText: `**step ca provisioner webhook test** <url>
+[**--secret**=<secret>] [**--secret-file**=<file>] [**--id**=<id>]
+[**--kind**=<kind>] [**--cert-type**=<cert-type>] [**--subject**=<subject>]
+[**--provisioner**=<name>]
+[**--payload**=<file>] [**--bearer-token-file**=<filename>]
+[**--basic-auth-username**=<username>] [**--basic-auth-password-file**=<filename>]
+[**--expect**=<result>] [**--roots**=<file>] [**--insecure**] [**--timeout**=<duration>]`,
+		Flags: []cli.Flag{
+			secretFlag,
+			secretFileFlag,
+			idFlag,
+			cli.StringFlag{
+				Name: "kind",
+				Usage: `The <kind> of webhook the sample request is sent to. Options are ENRICHING
+or AUTHORIZING. Defaults to ENRICHING.`,
+				Value: "ENRICHING",
+			},
+			cli.StringFlag{
+				Name: "cert-type",
+				Usage: `The type of certificate in the sample request. Options are X509 or SSH.
+Defaults to X509.`,
+				Value: "X509",
+			},
+			cli.StringFlag{
+				Name:  "subject",
+				Usage: `The <subject> of the certificate in the sample request.`,
+				Value: "test.example.com",
+			},
+			cli.StringFlag{
+				Name:  "provisioner",
+				Usage: `The provisioner <name> in the sample request.`,
+			},
+			cli.StringFlag{
+				Name: "payload",
+				Usage: `The <file> containing the JSON request body to send instead of the sample
+request.`,
+			},
+			bearerTokenFileFlag,
+			basicAuthUsernameFlag,
+			basicAuthPasswordFileFlag,
+			cli.StringFlag{
+				Name: "expect",
+				Usage: `The expected <result> of the request. The command fails if the webhook
+server does not return it.
+
+: <result> is a case-sensitive string and must be one of:
+
+    **allow**
+    :  The response must allow the request.
+
+    **deny**
+    :  The response must deny the request.`,
+			},
+			cli.StringFlag{
+				Name:  "roots",
+				Usage: `The <file> containing the root certificates used to verify the webhook server.`,
+			},
+			cli.BoolFlag{
+				Name:  "insecure",
+				Usage: `Do not verify the TLS certificate of the webhook server.`,
+			},
+			cli.DurationFlag{
+				Name:  "timeout",
+				Usage: `The <duration> to wait for the response of the webhook server.`,
+				Value: 10 * time.Second,
+			},
+		},
+		Description: `**step ca provisioner webhook test** sends a request like the ones sent by
+step-ca to a webhook server, signed with the webhook secret, and prints the
+response.
+
+By default, the request body contains a new certificate request, like the
+requests sent to ENRICHING webhooks before signing an X.509 or SSH certificate.
+With **--kind AUTHORIZING**, the request body contains the certificate that is
+going to be signed, like the requests sent to AUTHORIZING webhooks. The
+command fails if the server does not respond with the status 200 or a valid
+response body, so it can be used to test webhook servers in CI pipelines.
+
+## POSITIONAL ARGUMENTS
+
+<url>
+: The url of the webhook server.
+
+## EXAMPLES
+
+Send a sample request to a webhook server:
+'''
+$ step ca provisioner webhook test https://example.com/enrich \
+  --id 7d2d2cd8-4f4b-4dd1-a94e-4fe0f3a7ec3a --secret-file secret.txt
+'''
+
+Send a sample SSH request and fail if the server does not deny it:
+'''
+$ step ca provisioner webhook test https://example.com/authorize --secret-file secret.txt \
+  --kind AUTHORIZING --cert-type SSH --subject root --expect deny
+'''
+
+Send a custom request to a local server using a bearer token:
+'''
+$ step ca provisioner webhook test http://localhost:8080 --secret-file secret.txt \
+  --payload request.json --bearer-token-file token.txt
+'''`,
+	}
+}
+
+func testAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	url := ctx.Args().First()
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return fmt.Errorf("invalid url %q: the scheme must be http or https", url)
+	}
+	secret, err := readSecret(ctx)
+	if err != nil {
+		return err
+	}
+
+	expect := ctx.String("expect")
+	switch expect {
+	case "", "allow", "deny":
+	default:
+		return errs.InvalidFlagValue(ctx, "expect", expect, "allow, deny")
+	}
+
+	var body []byte
+	if payload := ctx.String("payload"); payload != "" {
+		if body, err = os.ReadFile(payload); err != nil {
+			return errs.FileError(err, payload)
+		}
+	} else {
+		kind := strings.ToUpper(ctx.String("kind"))
+		if kind != webhookutil.KindEnriching && kind != webhookutil.KindAuthorizing {
+			return errs.InvalidFlagValue(ctx, "kind", ctx.String("kind"), "ENRICHING, AUTHORIZING")
+		}
+		certType := strings.ToUpper(ctx.String("cert-type"))
+		if certType != "X509" && certType != "SSH" {
+			return errs.InvalidFlagValue(ctx, "cert-type", ctx.String("cert-type"), "X509, SSH")
+		}
+		if body, err = webhookutil.SampleRequest(kind, certType, ctx.String("provisioner"), ctx.String("subject")); err != nil {
+			return err
+		}
+	}
+
+	header := http.Header{}
+	if ctx.IsSet("bearer-token-file") {
+		bearerTkn, err := utils.ReadStringPasswordFromFile(ctx.String("bearer-token-file"))
+		if err != nil {
+			return err
+		}
+		header.Set("Authorization", "Bearer "+bearerTkn)
+	} else if ctx.IsSet("basic-auth-username") || ctx.IsSet("basic-auth-password-file") {
+		var password string
+		if ctx.IsSet("basic-auth-password-file") {
+			password, err = utils.ReadStringPasswordFromFile(ctx.String("basic-auth-password-file"))
+			if err != nil {
+				return err
+			}
+		}
+		auth := ctx.String("basic-auth-username") + ":" + password
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: ctx.Bool("insecure"), //nolint:gosec // on purpose with --insecure
+	}
+	if roots := ctx.String("roots"); roots != "" {
+		if tlsConfig.RootCAs, err = x509util.ReadCertPool(roots); err != nil {
+			return err
+		}
+	}
+	client := &http.Client{
+		Timeout: ctx.Duration("timeout"),
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	fmt.Println("Request:")
+	webhookutil.WriteJSON(os.Stdout, body)
+	status, resp, err := webhookutil.Send(context.Background(), client, url, ctx.String("id"), secret, header, body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Response: %d %s\n", status, http.StatusText(status))
+	webhookutil.WriteJSON(os.Stdout, resp)
+
+	if status != http.StatusOK {
+		return fmt.Errorf("webhook server responded with status %d", status)
+	}
+	rb, err := webhookutil.ParseResponse(resp)
+	if err != nil {
+		return err
+	}
+	switch {
+	case expect == "allow" && !rb.Allow && rb.Error != nil:
+		return fmt.Errorf("webhook server denied the request: %w", rb.Error)
+	case expect == "allow" && !rb.Allow:
+		return errors.New("webhook server denied the request")
+	case expect == "deny" && rb.Allow:
+		return errors.New("webhook server allowed the request")
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/smallstep/cli-utils/ui"
 	"github.com/smallstep/linkedca"
 
+	"github.com/smallstep/cli/utils"
 	"github.com/smallstep/cli/utils/cautils"
 )
 
@@ -26,6 +27,8 @@ func Command() cli.Command {
 			addCommand(),
 			updateCommand(),
 			removeCommand(),
+			serveCommand(),
+			testCommand(),
 		},
 		Description: `**step ca provisioner webhook** command group provides facilities for managing the webhooks attached to a provisioner
 
@@ -50,6 +53,16 @@ step ca provisioner webhook update my_provisioner my_webhook --url https://examp
 Remove a webhook:
 '''
 step ca provisioner webhook remove my_provisioner my_webhook
+'''
+
+Run a local webhook server that verifies the requests of step-ca:
+'''
+step ca provisioner webhook serve --secret-file secret.txt --response response.json
+'''
+
+Send a sample request to a webhook server:
+'''
+step ca provisioner webhook test https://example.com --secret-file secret.txt
 '''
 		`,
 	}
@@ -80,12 +93,40 @@ var (
 		Name:  "disable-tls-client-auth",
 		Usage: `The CA will not send a client certificate when requested by the webhook server.`,
 	}
+	secretFlag = cli.StringFlag{
+		Name:   "secret",
+		Usage:  `The base64 encoded webhook <secret> printed when the webhook was created.`,
+		EnvVar: "STEP_WEBHOOK_SECRET",
+	}
+	secretFileFlag = cli.StringFlag{
+		Name:  "secret-file",
+		Usage: `The <file> containing the base64 encoded webhook secret.`,
+	}
+	idFlag = cli.StringFlag{
+		Name:  "id",
+		Usage: `The webhook <id> printed when the webhook was created.`,
+	}
 	certTypeFlag = cli.StringFlag{
 		Name:  "cert-type",
 		Usage: `Whether to call this webhook when signing X509 certificates, SSH certificates, or ALL certificates. Default is ALL.`,
 	}
 )
 
+// readSecret returns the webhook secret from the --secret or --secret-file
+// flags.
+func readSecret(ctx *cli.Context) (string, error) {
+	switch {
+	case ctx.String("secret") != "" && ctx.String("secret-file") != "":
+		return "", errs.IncompatibleFlagWithFlag(ctx, "secret", "secret-file")
+	case ctx.String("secret-file") != "":
+		return utils.ReadStringPasswordFromFile(ctx.String("secret-file"))
+	case ctx.String("secret") != "":
+		return ctx.String("secret"), nil
+	default:
+		return "", errs.RequiredOrFlag(ctx, "secret", "secret-file")
+	}
+}
+
 type crudClient interface {
 	GetProvisioner(...ca.ProvisionerOption) (*linkedca.Provisioner, error)
 	CreateProvisionerWebhook(provisionerName string, wh *linkedca.Webhook) (*linkedca.Webhook, error)
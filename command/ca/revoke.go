@@ -46,6 +46,13 @@ func revokeCertificateCommand() cli.Command {
 		UsageText: `**step ca revoke** <serial-number>
 [**--cert**=<file>] [**--key**=<file>] [**--token**=<ott>]
 [**--reason**=<string>] [**--reasonCode**=<code>] [**--offline**]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]
+
+**step ca revoke** [**--from-file**=<file>] [**--provisioner**=<name>]
+[**--issued-after**=<time|duration>] [**--issued-before**=<time|duration>]
+[**--reason**=<string>] [**--reasonCode**=<code>] [**--dry-run**] [**--force**]
+[**--concurrency**=<number>] [**--rate**=<number>] [**--kid**=<kid>]
+[**--provisioner-password-file**=<file>] [**--offline**] [**--ca-config**=<file>]
 [**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
 		Description: `
 **step ca revoke** command revokes a certificate with the given serial
@@ -79,6 +86,16 @@ mTLS is used to authorize the revocation.
 Certificates generated using an OIDC provisioner cannot be revoked
 by their serial number.
 
+**Bulk Revocation**: Many certificates can be revoked with a single command
+using **--from-file**, or using the **--provisioner**, **--issued-after**, and
+**--issued-before** selectors. The selectors read the valid certificates from
+the database of the CA, so they require the **--offline** flag. Certificates
+identified by serial number are revoked using tokens generated with a JWK
+provisioner, selected once for all of them. Certificates identified by a
+certificate and key pair are revoked using mTLS. The revocations run
+concurrently and a report with the result of each one is printed at the end;
+the command fails if any of them fails.
+
 ## POSITIONAL ARGUMENTS
 
 <serial-number>
@@ -129,6 +146,34 @@ will be validated against the root and intermediate certificates configured in
 the step CA):
 '''
 $ step ca revoke --offline --cert foo.crt --key foo.key
+'''
+
+Print the certificates in a CSV file that would be revoked:
+'''
+$ cat serials.csv
+serial,reasonCode
+308893286343609293989051180431574390766,keyCompromise
+0x1b2c3d,superseded
+$ step ca revoke --from-file serials.csv --dry-run
+'''
+
+Revoke the certificates in a JSON file, using a JWK provisioner and its password
+file, with up to 8 concurrent requests and at most 20 requests per second:
+'''
+$ cat revoke.json
+[
+  {"serial": "308893286343609293989051180431574390766", "reasonCode": 1},
+  {"cert": "foo.crt", "key": "foo.key", "reason": "laptop lost"}
+]
+$ step ca revoke --from-file revoke.json --kid 4vn46fbZT68Uxfs9LBwHkTvrjEvxQqx-W8nnE-qDjts \
+  --provisioner-password-file password.txt --concurrency 8 --rate 20 --force
+'''
+
+Revoke all the certificates issued by a provisioner in the last 24 hours, in
+offline mode:
+'''
+$ step ca revoke --offline --provisioner acme --issued-after -24h \
+  --reasonCode keyCompromise --reason "provisioner compromised"
 '''`,
 		Flags: []cli.Flag{
 			cli.StringFlag{
@@ -197,6 +242,54 @@ Note: This is specific to the CertificateHold reason and is only used in DeltaCR
 attribute certificate have been compromised (reasonCode=10).
 `,
 			},
+			cli.StringFlag{
+				Name: "from-file",
+				Usage: `Revoke the certificates in a CSV or JSON <file>. A CSV file has the serial,
+reason, reasonCode, cert and key columns in that order, or in any order with a
+header row; lines starting with # are ignored. A JSON file is an array of
+objects with the same properties. Rows with a cert and key are revoked using
+mTLS, and empty reasons default to **--reason** and **--reasonCode**.`,
+			},
+			cli.StringFlag{
+				Name: "provisioner",
+				Usage: `Revoke the valid certificates authorized by the provisioner with the given
+<name> or id. Requires **--offline**.`,
+			},
+			cli.StringFlag{
+				Name: "issued-after",
+				Usage: `Revoke the valid certificates issued after a <time|duration>. The
+<time|duration> is an RFC 3339 time, like 2026-01-02T15:04:05Z, or a duration
+from now, like -24h. Requires **--offline**.`,
+			},
+			cli.StringFlag{
+				Name: "issued-before",
+				Usage: `Revoke the valid certificates issued before a <time|duration>. Requires
+**--offline**.`,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the certificates that would be revoked without revoking them.`,
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: `Revoke many certificates without asking for confirmation.`,
+			},
+			cli.IntFlag{
+				Name:  "concurrency",
+				Usage: `The maximum <number> of concurrent revocations.`,
+				Value: 4,
+			},
+			cli.Float64Flag{
+				Name: "rate",
+				Usage: `The maximum <number> of revocations per second. A value of 0 does not limit
+the rate.`,
+			},
+			cli.StringFlag{
+				Name: "kid",
+				Usage: `The provisioner <kid> of the JWK provisioner used to generate the tokens of a
+bulk revocation.`,
+			},
+			flags.ProvisionerPasswordFile,
 			flags.Token,
 			flags.CaConfig,
 			flags.Offline,
@@ -208,6 +301,13 @@ attribute certificate have been compromised (reasonCode=10).
 }
 
 func revokeCertificateAction(ctx *cli.Context) error {
+	if isBulkRevocation(ctx) {
+		return bulkRevokeAction(ctx)
+	}
+	if ctx.Bool("dry-run") {
+		return errs.RequiredWithFlag(ctx, "dry-run", "from-file")
+	}
+
 	args := ctx.Args()
 	serial := args.Get(0)
 	certFile, keyFile := ctx.String("cert"), ctx.String("key")
@@ -262,11 +362,10 @@ func revokeCertificateAction(ctx *cli.Context) error {
 			return err
 		}
 
-		sn, ok := new(big.Int).SetString(serial, 0)
-		if !ok {
-			return fmt.Errorf("'%s' is not a valid serial number - use a base 10 representation or add a prefix indicating the base", serial)
+		serial, err = parseSerialNumber(serial)
+		if err != nil {
+			return err
 		}
-		serial = sn.String()
 		if token == "" {
 			// No token and no cert/key pair - so generate a token.
 			token, err = flow.GenerateToken(ctx, &serial)
@@ -284,6 +383,15 @@ func revokeCertificateAction(ctx *cli.Context) error {
 	return nil
 }
 
+// parseSerialNumber returns a serial number in decimal.
+func parseSerialNumber(serial string) (string, error) {
+	sn, ok := new(big.Int).SetString(serial, 0)
+	if !ok {
+		return "", fmt.Errorf("'%s' is not a valid serial number - use a base 10 representation or add a prefix indicating the base", serial)
+	}
+	return sn.String(), nil
+}
+
 type revokeTokenClaims struct {
 	SHA string `json:"sha"`
 	jose.Claims
@@ -406,23 +514,41 @@ func (f *revokeFlow) GenerateToken(ctx *cli.Context, subject *string) (string, e
 }
 
 func (f *revokeFlow) Revoke(ctx *cli.Context, serial, token string) error {
-	client, err := f.getClient(ctx, serial, token)
+	// Convert the reasonCode flag to an OCSP revocation code.
+	reasonCode, err := ReasonCodeToNum(ctx.String("reasonCode"))
 	if err != nil {
 		return err
 	}
-
-	reason := ctx.String("reason")
-	// Convert the reasonCode flag to an OCSP revocation code.
-	reasonCode, err := ReasonCodeToNum(ctx.String("reasonCode"))
+	client, err := f.getClient(ctx, serial, token)
 	if err != nil {
 		return err
 	}
+	return f.revoke(ctx, client, &revocation{
+		serial:     serial,
+		token:      token,
+		certFile:   ctx.String("cert"),
+		keyFile:    ctx.String("key"),
+		reason:     ctx.String("reason"),
+		reasonCode: reasonCode,
+	})
+}
+
+// revocation is a request to revoke a certificate, authorized by a token or by
+// the certificate and key over mTLS.
+type revocation struct {
+	serial            string
+	token             string
+	certFile, keyFile string
+	reason            string
+	reasonCode        int
+}
 
+func (f *revokeFlow) revoke(ctx *cli.Context, client cautils.CaClient, r *revocation) error {
 	var tr http.RoundTripper
 
 	// If token is not provided then set up mTLS client with expected cert and key.
-	if token == "" {
-		certFile, keyFile := ctx.String("cert"), ctx.String("key")
+	if r.token == "" {
+		certFile, keyFile := r.certFile, r.keyFile
 
 		certPEMBytes, err := os.ReadFile(certFile)
 		if err != nil {
@@ -468,13 +594,13 @@ func (f *revokeFlow) Revoke(ctx *cli.Context, serial, token string) error {
 	}
 
 	req := &api.RevokeRequest{
-		Serial:     serial,
-		Reason:     reason,
-		ReasonCode: reasonCode,
-		OTT:        token,
+		Serial:     r.serial,
+		Reason:     r.reason,
+		ReasonCode: r.reasonCode,
+		OTT:        r.token,
 		Passive:    true,
 	}
-	if _, err = client.Revoke(req, tr); err != nil {
+	if _, err := client.Revoke(req, tr); err != nil {
 		return err
 	}
 	return nil
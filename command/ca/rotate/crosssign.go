@@ -0,0 +1,229 @@
+package rotate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/rotateutil"
+)
+
+func crossSignCommand() cli.Command {
+	return cli.Command{
+		Name:   "cross-sign",
+		Action: cli.ActionFunc(crossSignAction),
+		Usage:  "cross-sign an old and a new root certificate",
+		UsageText: `**step ca rotate cross-sign** <old-root> <new-root>
+[**--old-key**=<file>] [**--old-key-password-file**=<file>]
+[**--new-key**=<file>] [**--new-key-password-file**=<file>]
+[**--kms**=<type>] [**--kms-old-root**=<uri>] [**--kms-new-root**=<uri>]
+[**--out-dir**=<dir>] [**--ca-config**=<file>] [**--force**]`,
+		Description: `**step ca rotate cross-sign** creates the certificates and bundles used
+to move a CA and its clients from an old root to a new root, then prints the
+checklist of the rotation.
+
+With the key of the old root, it creates the new root cross-signed by the old
+one: clients that only trust the old root validate the chains of the new
+intermediate through it. With the key of the new root, it creates the old root
+cross-signed by the new one, for the clients that only trust the new root and
+the certificates issued before the rotation. At least one of the keys is
+required; the keys are read from files, or from a key manager with the
+**--kms** flag.
+
+The files written to the output directory are:
+
+**new_root_cross_signed.crt**
+:  The new root cross-signed by the old root.
+
+**old_root_cross_signed.crt**
+:  The old root cross-signed by the new root.
+
+**roots.crt**
+:  The roots of the CA and the new root, used as "root" in ca.json during the
+rotation, so **step ca roots** and **step ca federation** return both.
+
+**federated_roots.crt**
+:  The old root and the federated roots of the CA, used as "federatedRoots" in
+ca.json after the rotation, so **step ca federation** still returns the old
+root until it expires.
+
+## POSITIONAL ARGUMENTS
+
+<old-root>
+: The root certificate currently used by the CA.
+
+<new-root>
+: The root certificate that replaces it, e.g. created with **step certificate
+create --profile root-ca**.
+
+## EXAMPLES
+
+Cross-sign the old and the new roots:
+'''
+$ step ca rotate cross-sign root_ca.crt new_root_ca.crt \
+  --old-key root_ca_key --new-key new_root_ca_key --out-dir rotation
+'''
+
+Cross-sign a new root in Azure Key Vault with the old root in a file:
+'''
+$ step ca rotate cross-sign root_ca.crt new_root_ca.crt --old-key root_ca_key \
+  --kms azurekms --kms-new-root 'azurekms:name=my-root-key-2;vault=my-vault' \
+  --out-dir rotation
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "old-key",
+				Usage: `The <file> with the key of the old root.`,
+			},
+			cli.StringFlag{
+				Name:  "old-key-password-file",
+				Usage: `The path to the <file> containing the password to decrypt the key of the old root.`,
+			},
+			cli.StringFlag{
+				Name:  "new-key",
+				Usage: `The <file> with the key of the new root.`,
+			},
+			cli.StringFlag{
+				Name:  "new-key-password-file",
+				Usage: `The path to the <file> containing the password to decrypt the key of the new root.`,
+			},
+			kmsFlag,
+			cli.StringFlag{
+				Name:  "kms-old-root",
+				Usage: `The kms <URI> of the key of the old root.`,
+			},
+			cli.StringFlag{
+				Name:  "kms-new-root",
+				Usage: `The kms <URI> of the key of the new root.`,
+			},
+			cli.StringFlag{
+				Name:  "out-dir",
+				Value: ".",
+				Usage: `The <dir> where the certificates and bundles are written.`,
+			},
+			flags.CaConfig,
+			flags.Force,
+		},
+	}
+}
+
+func crossSignAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 2); err != nil {
+		return err
+	}
+	oldFile, newFile := ctx.Args().Get(0), ctx.Args().Get(1)
+	hasOldKey := ctx.String("old-key") != "" || ctx.String("kms-old-root") != ""
+	hasNewKey := ctx.String("new-key") != "" || ctx.String("kms-new-root") != ""
+	if !hasOldKey && !hasNewKey {
+		return errs.RequiredOrFlag(ctx, "old-key", "kms-old-root", "new-key", "kms-new-root")
+	}
+
+	oldRoot, err := readRoot(oldFile)
+	if err != nil {
+		return err
+	}
+	newRoot, err := readRoot(newFile)
+	if err != nil {
+		return err
+	}
+	if oldRoot.Equal(newRoot) {
+		return errors.Errorf("certificates %s and %s are the same root", oldFile, newFile)
+	}
+
+	km, err := newKeyManager(ctx)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadOptionalConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	outDir := ctx.String("out-dir")
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		return errs.FileError(err, outDir)
+	}
+	files := &rootFiles{
+		roots:          filepath.Join(outDir, "roots.crt"),
+		federatedRoots: filepath.Join(outDir, "federated_roots.crt"),
+	}
+
+	if hasOldKey {
+		signer, err := loadSigner(ctx, km, oldRoot, "old-key", "kms-old-root", "old-key-password-file")
+		if err != nil {
+			return err
+		}
+		cert, err := rotateutil.CrossSign(newRoot, oldRoot, signer)
+		if err != nil {
+			return err
+		}
+		files.newRoot = filepath.Join(outDir, "new_root_cross_signed.crt")
+		if err := fileutil.WriteFile(files.newRoot, rotateutil.Bundle(cert), 0o600); err != nil {
+			return err
+		}
+		ui.PrintSelected("Cross-Signed New Root", files.newRoot)
+		warnPathLen(newRoot, oldRoot)
+	}
+	if hasNewKey {
+		signer, err := loadSigner(ctx, km, newRoot, "new-key", "kms-new-root", "new-key-password-file")
+		if err != nil {
+			return err
+		}
+		cert, err := rotateutil.CrossSign(oldRoot, newRoot, signer)
+		if err != nil {
+			return err
+		}
+		files.oldRoot = filepath.Join(outDir, "old_root_cross_signed.crt")
+		if err := fileutil.WriteFile(files.oldRoot, rotateutil.Bundle(cert), 0o600); err != nil {
+			return err
+		}
+		ui.PrintSelected("Cross-Signed Old Root", files.oldRoot)
+		warnPathLen(oldRoot, newRoot)
+	}
+
+	// The bundles keep the other roots of the CA, and exclude the new root
+	// from the federated roots.
+	roots := []*x509.Certificate{oldRoot}
+	federated := []*x509.Certificate{oldRoot}
+	if cfg != nil {
+		roots = append(roots, cfg.roots...)
+		for _, crt := range cfg.federatedRoots {
+			if !crt.Equal(newRoot) {
+				federated = append(federated, crt)
+			}
+		}
+	}
+	roots = append(roots, newRoot)
+	if err := fileutil.WriteFile(files.roots, rotateutil.Bundle(roots...), 0o600); err != nil {
+		return err
+	}
+	ui.PrintSelected("Roots", files.roots)
+	if err := fileutil.WriteFile(files.federatedRoots, rotateutil.Bundle(federated...), 0o600); err != nil {
+		return err
+	}
+	ui.PrintSelected("Federated Roots", files.federatedRoots)
+
+	c := newChecklist("Root rotation checklist:")
+	c.rootSteps(ctx.String("ca-config"), x509util.Fingerprint(oldRoot), x509util.Fingerprint(newRoot), files)
+	return nil
+}
+
+// warnPathLen prints a warning if the path length constraint of the issuer
+// does not allow the chains through the cross-signed certificate.
+func warnPathLen(cert, issuer *x509.Certificate) {
+	if !rotateutil.PathLenAllowsCrossSign(cert, issuer) {
+		fmt.Fprintf(os.Stderr, "Warning: the path length constraint of %q does not allow the chains through\n"+
+			"%q cross-signed by it; clients enforcing it will reject those chains.\n",
+			issuer.Subject, cert.Subject)
+	}
+}
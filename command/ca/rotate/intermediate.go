@@ -0,0 +1,256 @@
+package rotate
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/kms/apiv1"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/rotateutil"
+	"github.com/smallstep/cli/utils"
+)
+
+func intermediateCommand() cli.Command {
+	return cli.Command{
+		Name:   "intermediate",
+		Action: cli.ActionFunc(intermediateAction),
+		Usage:  "issue a new intermediate certificate for the CA",
+		UsageText: `**step ca rotate intermediate** <crt-file> [<key-file>]
+[**--root**=<file>] [**--key**=<file>] [**--key-password-file**=<file>]
+[**--password-file**=<file>] [**--kms**=<type>] [**--kms-root**=<uri>]
+[**--kms-intermediate**=<uri>] [**--subject**=<name>] [**--not-after**=<time|duration>]
+[**--cross-signed**=<file>] [**--ca-config**=<file>] [**--force**]`,
+		Description: `**step ca rotate intermediate** generates a new key and issues a new
+intermediate certificate signed by the root, then prints the checklist to
+deploy it.
+
+The root and the subject default to the root and the subject of the current
+intermediate in the CA configuration. The root key is read from a file, or
+from a key manager with the **--kms** and **--kms-root** flags. The new key
+is written to <key-file>, or created in the key manager with the
+**--kms-intermediate** flag.
+
+When the root is being rotated, use **--cross-signed** to append the new root
+cross-signed by the old one to <crt-file>. The CA serves the chain, so clients
+that only trust the old root can validate the certificates it issues.
+
+## POSITIONAL ARGUMENTS
+
+<crt-file>
+: File to write the new intermediate certificate (PEM format).
+
+<key-file>
+: File to write the new private key (PEM format). It is not used with
+**--kms-intermediate**.
+
+## EXAMPLES
+
+Issue a new intermediate with the root in the CA configuration:
+'''
+$ step ca rotate intermediate --key $(step path)/secrets/root_ca_key \
+  intermediate_ca_2.crt intermediate_ca_2_key
+'''
+
+Issue a new intermediate with a new root, including the cross-signed root:
+'''
+$ step ca rotate intermediate --root new_root_ca.crt --key new_root_ca_key \
+  --cross-signed rotation/new_root_cross_signed.crt \
+  intermediate_ca_2.crt intermediate_ca_2_key
+'''
+
+Issue a new intermediate with the keys in Azure Key Vault:
+'''
+$ step ca rotate intermediate --kms azurekms \
+  --kms-root 'azurekms:name=my-root-key;vault=my-vault' \
+  --kms-intermediate 'azurekms:name=my-intermediate-key-2;vault=my-vault' \
+  intermediate_ca_2.crt
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "root",
+				Usage: `The root certificate <file> that signs the intermediate. Defaults to the first root in the CA configuration.`,
+			},
+			cli.StringFlag{
+				Name:  "key",
+				Usage: `The <file> with the key of the root certificate.`,
+			},
+			cli.StringFlag{
+				Name:  "key-password-file",
+				Usage: `The path to the <file> containing the password to decrypt the root key.`,
+			},
+			cli.StringFlag{
+				Name:  "password-file",
+				Usage: `The path to the <file> containing the password to encrypt the new key.`,
+			},
+			kmsFlag,
+			kmsRootFlag,
+			kmsIntermediateFlag,
+			cli.StringFlag{
+				Name:  "subject",
+				Usage: `The common <name> of the new intermediate. Defaults to the subject of the current intermediate.`,
+			},
+			cli.StringFlag{
+				Name:  "not-after",
+				Value: "87600h",
+				Usage: `The <time|duration> when the validity of the new intermediate ends. The
+intermediate never outlives the root.`,
+			},
+			cli.StringFlag{
+				Name:  "cross-signed",
+				Usage: `The <file> with the root cross-signed by the previous root, appended to <crt-file>.`,
+			},
+			flags.CaConfig,
+			flags.Force,
+		},
+	}
+}
+
+func intermediateAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 1, 2); err != nil {
+		return err
+	}
+	crtFile, keyFile := ctx.Args().Get(0), ctx.Args().Get(1)
+	kmsIntermediate := ctx.String("kms-intermediate")
+	switch {
+	case keyFile != "" && kmsIntermediate != "":
+		return errors.New("positional argument <key-file> cannot be used with the flag '--kms-intermediate'")
+	case keyFile == "" && kmsIntermediate == "":
+		return errs.MissingArguments(ctx, "key-file")
+	}
+	notAfter, ok := flags.ParseTimeOrDuration(ctx.String("not-after"))
+	if !ok || notAfter.IsZero() {
+		return errs.InvalidFlagValue(ctx, "not-after", ctx.String("not-after"), "")
+	}
+
+	km, err := newKeyManager(ctx)
+	if err != nil {
+		return err
+	}
+	if kmsIntermediate != "" && km == nil {
+		return errs.RequiredWithFlag(ctx, "kms-intermediate", "kms")
+	}
+
+	cfg, err := loadOptionalConfig(ctx)
+	if err != nil {
+		return err
+	}
+	rootFile := ctx.String("root")
+	if rootFile == "" {
+		if cfg == nil {
+			return errs.RequiredFlag(ctx, "root")
+		}
+		rootFile = cfg.rootFiles[0]
+	}
+	root, err := readRoot(rootFile)
+	if err != nil {
+		return err
+	}
+	signer, err := loadSigner(ctx, km, root, "key", "kms-root", "key-password-file")
+	if err != nil {
+		return err
+	}
+
+	var crossSigned *x509.Certificate
+	if filename := ctx.String("cross-signed"); filename != "" {
+		if crossSigned, err = pemutil.ReadCertificate(filename); err != nil {
+			return err
+		}
+		if !bytes.Equal(crossSigned.RawSubjectPublicKeyInfo, root.RawSubjectPublicKeyInfo) {
+			return errors.Errorf("certificate %s is not a cross-signed certificate of %s", filename, rootFile)
+		}
+	}
+
+	var subject pkix.Name
+	switch {
+	case ctx.String("subject") != "":
+		subject = pkix.Name{CommonName: ctx.String("subject")}
+	case cfg != nil && cfg.intermediate != nil:
+		subject = cfg.intermediate.Subject
+	default:
+		ui.Println("What would you like to name the new intermediate?")
+		name, err := ui.Prompt("(e.g. Smallstep Intermediate CA)", ui.WithValidateNotEmpty())
+		if err != nil {
+			return err
+		}
+		subject = pkix.Name{CommonName: name}
+	}
+
+	// Create the new key in the key manager or in memory.
+	var (
+		pub  crypto.PublicKey
+		priv crypto.PrivateKey
+		pass []byte
+	)
+	if kmsIntermediate != "" {
+		resp, err := km.CreateKey(&apiv1.CreateKeyRequest{
+			Name:               kmsIntermediate,
+			SignatureAlgorithm: apiv1.ECDSAWithSHA256,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error creating %s", kmsIntermediate)
+		}
+		pub, keyFile = resp.PublicKey, resp.Name
+	} else {
+		if passFile := ctx.String("password-file"); passFile != "" {
+			if pass, err = utils.ReadPasswordFromFile(passFile); err != nil {
+				return errors.Wrap(err, "error reading encrypting password from file")
+			}
+		} else {
+			if pass, err = ui.PromptPassword("Please enter the password to encrypt the private key",
+				ui.WithValidateNotEmpty()); err != nil {
+				return errors.Wrap(err, "error reading password")
+			}
+		}
+		s, err := keyutil.GenerateDefaultSigner()
+		if err != nil {
+			return err
+		}
+		pub, priv = s.Public(), s
+	}
+
+	cert, err := rotateutil.NewIntermediate(subject, pub, root, signer, notAfter)
+	if err != nil {
+		return err
+	}
+	chain := []*x509.Certificate{cert}
+	if crossSigned != nil {
+		chain = append(chain, crossSigned)
+	}
+	if err := fileutil.WriteFile(crtFile, rotateutil.Bundle(chain...), 0o600); err != nil {
+		return err
+	}
+	ui.PrintSelected("Certificate", crtFile)
+
+	// Keys created in a KMS are not written to disk.
+	if priv == nil {
+		ui.PrintSelected("Key", keyFile)
+	} else {
+		if _, err := pemutil.Serialize(priv, pemutil.WithPassword(pass), pemutil.ToFile(keyFile, 0o600)); err != nil {
+			return err
+		}
+		ui.PrintSelected("Private Key", keyFile)
+	}
+
+	configFile := ctx.String("ca-config")
+	c := newChecklist("Checklist:")
+	if cfg != nil && !cfg.hasRoot(root) {
+		c.step("The root %s is not in %s; add it before using the new\n"+
+			"intermediate, following the root rotation checklist of 'step ca rotate plan'.", rootFile, configFile)
+		c.intermediateSteps(configFile, crtFile, keyFile)
+		return nil
+	}
+	c.intermediateSteps(configFile, crtFile, keyFile)
+	c.noBootstrapStep()
+	return nil
+}
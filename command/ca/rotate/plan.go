@@ -0,0 +1,144 @@
+package rotate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/rotateutil"
+)
+
+func planCommand() cli.Command {
+	return cli.Command{
+		Name:      "plan",
+		Action:    cli.ActionFunc(planAction),
+		Usage:     "print the status of the CA certificates and the rotation checklists",
+		UsageText: `**step ca rotate plan** [**--threshold**=<percent>] [**--ca-config**=<file>]`,
+		Description: `**step ca rotate plan** reads the roots, the intermediate, and the federated
+roots in the CA configuration, prints their validity and fingerprints, and
+prints the checklists to rotate the intermediate and the root. A rotation is
+due when the certificate has used more than the threshold of its lifetime.
+
+The checklists include the clients that need to run **step ca bootstrap**
+again. Use **step ca rotate intermediate** and **step ca rotate cross-sign** to
+create the certificates.
+
+## EXAMPLES
+
+Print the rotation plan of the CA in $(step path)/config/ca.json:
+'''
+$ step ca rotate plan
+'''
+
+Print the rotation plan with a threshold of 50% of the lifetime:
+'''
+$ step ca rotate plan --threshold 50 --ca-config /etc/step-ca/config/ca.json
+'''`,
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "threshold",
+				Value: 66,
+				Usage: `The <percent> of the lifetime of a certificate after which its rotation is due.`,
+			},
+			flags.CaConfig,
+		},
+	}
+}
+
+func planAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+	threshold := ctx.Int("threshold")
+	if threshold < 1 || threshold > 100 {
+		return errs.InvalidFlagValueMsg(ctx, "threshold", strconv.Itoa(threshold), "value must be between 1 and 100")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tw := new(tabwriter.Writer)
+	// Format in tab-separated columns with a tab stop of 8.
+	tw.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "ROLE\tSUBJECT\tNOT AFTER\tDAYS LEFT\tUSED\tFINGERPRINT")
+	row := func(role string, crt *x509.Certificate) {
+		left, used := rotateutil.Lifetime(crt, now)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.0f%%\t%s\n", role, crt.Subject,
+			crt.NotAfter.Format(time.RFC3339), int(left.Hours()/24), used*100, x509util.Fingerprint(crt))
+	}
+	for _, crt := range cfg.roots {
+		row("root", crt)
+	}
+	if cfg.intermediate != nil {
+		row("intermediate", cfg.intermediate)
+	}
+	for _, crt := range cfg.federatedRoots {
+		row("federated root", crt)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// The root that issued the intermediate, or the first one.
+	root, rootFile := cfg.roots[0], cfg.rootFiles[0]
+	if cfg.intermediate != nil {
+		for i, crt := range cfg.roots {
+			if cfg.intermediate.CheckSignatureFrom(crt) == nil {
+				root, rootFile = crt, cfg.rootFiles[i]
+				break
+			}
+		}
+	}
+
+	fmt.Println()
+	if cfg.intermediate != nil {
+		fmt.Println(dueMessage("Intermediate", cfg.intermediate, threshold, now))
+		if !cfg.intermediate.NotAfter.Before(root.NotAfter) {
+			fmt.Println("The intermediate expires with the root; a new intermediate requires a root rotation.")
+		}
+	}
+	fmt.Println(dueMessage("Root", root, threshold, now))
+
+	c := newChecklist("Intermediate rotation checklist:")
+	c.step("Issue a new intermediate with the current root:\n"+
+		"  step ca rotate intermediate --ca-config %s --root %s \\\n"+
+		"    --key <root-key> <new-crt-file> <new-key-file>", cfg.filename, rootFile)
+	c.intermediateSteps(cfg.filename, "<new-crt-file>", "<new-key-file>")
+	c.noBootstrapStep()
+
+	c = newChecklist("Root rotation checklist:")
+	c.step("Create the new root, e.g.:\n" +
+		"  step certificate create --profile root-ca \"<name> Root CA\" new_root_ca.crt new_root_ca_key")
+	c.step("Create the cross-signed certificates and the root bundles:\n"+
+		"  step ca rotate cross-sign --ca-config %s %s new_root_ca.crt \\\n"+
+		"    --old-key <root-key> --new-key new_root_ca_key --out-dir rotation", cfg.filename, rootFile)
+	c.rootSteps(cfg.filename, x509util.Fingerprint(root), "<new-root-fingerprint>", &rootFiles{
+		roots:          "rotation/roots.crt",
+		newRoot:        "rotation/new_root_cross_signed.crt",
+		oldRoot:        "rotation/old_root_cross_signed.crt",
+		federatedRoots: "rotation/federated_roots.crt",
+	})
+	return nil
+}
+
+// dueMessage returns whether the rotation of a certificate is due.
+func dueMessage(name string, crt *x509.Certificate, threshold int, now time.Time) string {
+	_, used := rotateutil.Lifetime(crt, now)
+	if used*100 >= float64(threshold) {
+		return fmt.Sprintf("%s rotation: due, %.0f%% of its lifetime used.", name, used*100)
+	}
+	lifetime := crt.NotAfter.Sub(crt.NotBefore)
+	due := crt.NotBefore.Add(lifetime / 100 * time.Duration(threshold))
+	return fmt.Sprintf("%s rotation: not due until %s.", name, due.Format(time.RFC3339))
+}
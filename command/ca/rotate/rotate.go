@@ -0,0 +1,312 @@
+package rotate
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/authority/config"
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/kms"
+	"go.step.sm/crypto/kms/apiv1"
+	_ "go.step.sm/crypto/kms/azurekms" // enable azurekms
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/utils"
+)
+
+// Command returns the rotate subcommand.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "rotate",
+		Usage:     "rotate the intermediate and root certificates of the CA",
+		UsageText: "**step ca rotate** <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Subcommands: cli.Commands{
+			planCommand(),
+			intermediateCommand(),
+			crossSignCommand(),
+		},
+		Description: `**step ca rotate** command group provides facilities to rotate the
+intermediate and the root certificates of an existing CA without downtime.
+
+An intermediate rotation issues a new intermediate certificate with the same
+root; clients do not need to be bootstrapped again. A root rotation creates
+cross-signed certificates between the old and the new root, so clients trusting
+either root can validate the chains of both, and root bundles with both roots
+for **step ca roots** and **step ca federation** while the clients are moved to
+the new root.
+
+The keys can be stored in files or, like in **step ca init**, in a key manager
+selected with the **--kms** flag.
+
+## EXAMPLES
+
+Print the status of the CA certificates and the rotation checklists:
+'''
+$ step ca rotate plan
+'''
+
+Issue a new intermediate with the current root:
+'''
+$ step ca rotate intermediate --root root_ca.crt --key root_ca_key \
+  intermediate_ca.crt intermediate_ca_key
+'''
+
+Cross-sign an old and a new root:
+'''
+$ step ca rotate cross-sign root_ca.crt new_root_ca.crt \
+  --old-key root_ca_key --new-key new_root_ca_key --out-dir rotation
+'''`,
+	}
+}
+
+var (
+	kmsFlag = cli.StringFlag{
+		Name: "kms",
+		Usage: `The key manager <type> used to store the keys. Options are:
+	**azurekms**
+	:  Uses Azure Key Vault to manage X.509 keys. The key URIs have
+	the following format <azurekms:name=key-name;vault=vault-name>.`,
+	}
+
+	kmsRootFlag = cli.StringFlag{
+		Name: "kms-root",
+		Usage: `The kms <URI> of the root certificate key. Examples are:
+	**azurekms**
+	:  azurekms:name=my-root-key;vault=my-vault`,
+	}
+
+	kmsIntermediateFlag = cli.StringFlag{
+		Name: "kms-intermediate",
+		Usage: `The kms <URI> used to generate the intermediate certificate key. Examples are:
+	**azurekms**
+	:  azurekms:name=my-intermediate-key;vault=my-vault`,
+	}
+)
+
+// newKeyManager returns the key manager selected with the --kms flag, or nil
+// if the keys are stored in files.
+func newKeyManager(ctx *cli.Context) (kms.KeyManager, error) {
+	kmsName := strings.ToLower(ctx.String("kms"))
+	switch kmsName {
+	case "":
+		return nil, nil
+	case "azurekms":
+		return kms.New(context.Background(), kms.Options{
+			Type: kms.Type(kmsName),
+		})
+	default:
+		return nil, errs.InvalidFlagValue(ctx, "kms", ctx.String("kms"), "azurekms")
+	}
+}
+
+// loadSigner returns the signer of a CA certificate, from the key in the given
+// file, or from the key with the given URI in the key manager.
+func loadSigner(ctx *cli.Context, km kms.KeyManager, cert *x509.Certificate, keyFlag, kmsFlag, passwordFileFlag string) (crypto.Signer, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch keyFile, keyURI := ctx.String(keyFlag), ctx.String(kmsFlag); {
+	case keyFile != "" && keyURI != "":
+		return nil, errs.IncompatibleFlagWithFlag(ctx, keyFlag, kmsFlag)
+	case keyURI != "":
+		if km == nil {
+			return nil, errs.RequiredWithFlag(ctx, kmsFlag, "kms")
+		}
+		if signer, err = km.CreateSigner(&apiv1.CreateSignerRequest{SigningKey: keyURI}); err != nil {
+			return nil, errors.Wrapf(err, "error loading %s", keyURI)
+		}
+	case keyFile != "":
+		var opts []pemutil.Options
+		if passwordFile := ctx.String(passwordFileFlag); passwordFile != "" {
+			opts = append(opts, pemutil.WithPasswordFile(passwordFile))
+		}
+		key, err := pemutil.Read(keyFile, opts...)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if signer, ok = key.(crypto.Signer); !ok {
+			return nil, errors.Errorf("file %s does not contain a valid private key", keyFile)
+		}
+	default:
+		return nil, errs.RequiredOrFlag(ctx, keyFlag, kmsFlag)
+	}
+
+	if !keyutil.Equal(cert.PublicKey, signer.Public()) {
+		return nil, errors.Errorf("the key does not match the certificate %q", cert.Subject)
+	}
+	return signer, nil
+}
+
+// readRoot reads a root certificate from a file.
+func readRoot(filename string) (*x509.Certificate, error) {
+	cert, err := pemutil.ReadCertificate(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA || cert.CheckSignatureFrom(cert) != nil {
+		return nil, errors.Errorf("certificate %s is not a root CA", filename)
+	}
+	return cert, nil
+}
+
+// caConfig is the part of the CA configuration used in the rotations.
+type caConfig struct {
+	filename       string
+	roots          []*x509.Certificate
+	rootFiles      []string
+	intermediate   *x509.Certificate
+	federatedRoots []*x509.Certificate
+}
+
+// loadConfig reads the CA configuration file set by the --ca-config flag and
+// the certificates in it.
+func loadConfig(ctx *cli.Context) (*caConfig, error) {
+	filename := ctx.String("ca-config")
+	b, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config.Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", filename)
+	}
+
+	c := &caConfig{filename: filename}
+	for _, name := range cfg.Root {
+		certs, err := pemutil.ReadCertificateBundle(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, crt := range certs {
+			c.roots = append(c.roots, crt)
+			c.rootFiles = append(c.rootFiles, name)
+		}
+	}
+	if len(c.roots) == 0 {
+		return nil, errors.Errorf("error reading %s: the CA does not have a root certificate", filename)
+	}
+	if cfg.IntermediateCert != "" {
+		certs, err := pemutil.ReadCertificateBundle(cfg.IntermediateCert)
+		if err != nil {
+			return nil, err
+		}
+		c.intermediate = certs[0]
+	}
+	for _, name := range cfg.FederatedRoots {
+		certs, err := pemutil.ReadCertificateBundle(name)
+		if err != nil {
+			return nil, err
+		}
+		c.federatedRoots = append(c.federatedRoots, certs...)
+	}
+	return c, nil
+}
+
+// loadOptionalConfig is like loadConfig, but it returns nil if the default
+// CA configuration file does not exist.
+func loadOptionalConfig(ctx *cli.Context) (*caConfig, error) {
+	if _, err := os.Stat(ctx.String("ca-config")); os.IsNotExist(err) && !ctx.IsSet("ca-config") {
+		return nil, nil
+	}
+	return loadConfig(ctx)
+}
+
+// hasRoot returns true if the configuration has the given root.
+func (c *caConfig) hasRoot(root *x509.Certificate) bool {
+	for _, r := range c.roots {
+		if r.Equal(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// checklist prints a numbered list of steps.
+type checklist struct {
+	w io.Writer
+	n int
+}
+
+func newChecklist(title string) *checklist {
+	fmt.Println()
+	fmt.Println(title)
+	return &checklist{w: os.Stdout}
+}
+
+func (c *checklist) step(format string, args ...any) {
+	c.n++
+	lines := strings.Split(fmt.Sprintf(format, args...), "\n")
+	fmt.Fprintf(c.w, "  %d. %s\n", c.n, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(c.w, "     %s\n", line)
+	}
+}
+
+// intermediateSteps adds the steps to deploy a new intermediate issued by a
+// root the clients already trust.
+func (c *checklist) intermediateSteps(configFile, crtFile, key string) {
+	c.step("Set \"crt\" to %s and \"key\" to %s in %s.", crtFile, key, configFile)
+	c.step("Restart the CA, or send it a SIGHUP, to sign with the new intermediate.")
+	c.step("Certificates issued with the old intermediate remain valid until they expire;\n" +
+		"servers get the new intermediate in their chains when they renew.")
+}
+
+// noBootstrapStep adds the step for the clients of an intermediate rotation.
+func (c *checklist) noBootstrapStep() {
+	c.step("Clients do not need to run 'step ca bootstrap' again: the root does not change.")
+}
+
+// rootFiles are the files created by a root rotation.
+type rootFiles struct {
+	// roots is the bundle with the old and new roots.
+	roots string
+	// newRoot is the new root cross-signed by the old root.
+	newRoot string
+	// oldRoot is the old root cross-signed by the new root.
+	oldRoot string
+	// federatedRoots is the bundle with the old root and the federated roots.
+	federatedRoots string
+}
+
+// rootSteps adds the steps to move the CA and its clients from the old root,
+// with the given fingerprint, to the new one.
+func (c *checklist) rootSteps(configFile, oldFingerprint, newFingerprint string, files *rootFiles) {
+	c.step("Set \"root\" to %s in %s, so 'step ca roots' and\n"+
+		"'step ca federation' return both roots.", files.roots, configFile)
+	if files.newRoot != "" {
+		c.step("Issue an intermediate with the new root, including the new root cross-signed\n"+
+			"by the old one for the clients that only trust the old root:\n"+
+			"  step ca rotate intermediate --root <new-root> --key <new-root-key> \\\n"+
+			"    --cross-signed %s intermediate_ca.crt intermediate_ca_key", files.newRoot)
+	} else {
+		c.step("Issue an intermediate with the new root:\n" +
+			"  step ca rotate intermediate --root <new-root> --key <new-root-key> \\\n" +
+			"    intermediate_ca.crt intermediate_ca_key\n" +
+			"Clients that only trust the old root cannot validate its chains.")
+	}
+	c.step("Set \"crt\" and \"key\" to the new intermediate and restart the CA.")
+	c.step("Run 'step ca bootstrap --fingerprint %s' again\n"+
+		"on the clients and contexts bootstrapped with the fingerprint of the old root,\n"+
+		"%s, and on the hosts running\n"+
+		"'step ca renew --daemon' with the old root file.", newFingerprint, oldFingerprint)
+	c.step("Add the new root to the trust stores, e.g.:\n"+
+		"  step certificate truststore sync --roots %s --all", files.roots)
+	c.step("Add the new root to \"federatedRoots\" in the CAs federated with this one.")
+	c.step("When no client depends on the old root, set \"root\" to the new root and\n"+
+		"\"federatedRoots\" to %s, and remove the cross-signed root\n"+
+		"from \"crt\".", files.federatedRoots)
+	c.step("When the old root expires, remove it from \"federatedRoots\" and from the\n" +
+		"trust stores with 'step certificate truststore sync'.")
+}
@@ -1,8 +1,10 @@
 package certificate
 
 import (
+	"crypto/x509"
 	"encoding/pem"
 	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -10,24 +12,44 @@ import (
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/errs"
 	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/step"
 	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+	"go.step.sm/crypto/x509util"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/chainutil"
 )
 
 func bundleCommand() cli.Command {
 	return cli.Command{
-		Name:      "bundle",
-		Action:    command.ActionFunc(bundleAction),
-		Usage:     `bundle a certificate with intermediate certificate(s) needed for certificate path validation`,
-		UsageText: `**step certificate bundle** <crt-file> <ca> <bundle-file>`,
+		Name:   "bundle",
+		Action: command.ActionFunc(bundleAction),
+		Usage:  `bundle a certificate with intermediate certificate(s) needed for certificate path validation`,
+		UsageText: `**step certificate bundle** <crt-file> <ca> <bundle-file>
+
+**step certificate bundle** **--auto** <crt-file> <bundle-file>
+[**--roots**=<root-bundle>] [**--servername**=<servername>]`,
 		Description: `**step certificate bundle** bundles a certificate
 		with any intermediates necessary to validate the certificate.
 
+With the **--auto** flag, the intermediates are found automatically. Starting
+with the certificate, the issuer of each certificate is looked up in the
+certificates after the first one in <crt-file>, in the cache, or downloaded
+from the CA issuers URL in the authority information access extension. DER,
+PEM and PKCS #7 (.p7c) responses are supported. Each issuer must have signed
+the previous certificate, and the chain must end in a root in **--roots** or,
+by default, in the system trust store. The root is not added to the bundle.
+
+The downloaded certificates are cached in $(step path)/cache/aia. The command
+reports where each certificate in the chain comes from.
+
 ## POSITIONAL ARGUMENTS
 
 <crt-file>
-: The path to a leaf certificate to bundle with issuing certificate(s).
+: The path to a leaf certificate to bundle with issuing certificate(s). With
+**--auto** it can also be an address or URL of a TLS server; the certificates
+sent by the server are used without verifying them.
 
 <ca>
 : The path to the Certificate Authority issuing certificate.
@@ -46,12 +68,61 @@ Bundle a certificate with the intermediate certificate authority (issuer):
 '''
 $ step certificate bundle foo.crt intermediate-ca.crt foo-bundle.crt
 '''
+
+Bundle a certificate downloading the missing intermediates:
+
+'''
+$ step certificate bundle --auto foo.crt foo-bundle.crt
+Certificate: CN=foo.example.com
+Issuer: CN=Example Issuing CA 2 (fetched from http://pki.example.com/issuing2.p7c)
+Issuer: CN=Example Intermediate CA (from the cache, http://pki.example.com/intermediate.cer)
+Root: CN=Example Root CA
+Your certificate has been saved in foo-bundle.crt.
+'''
+
+Fix the chain of a server that does not send its intermediates:
+
+'''
+$ step certificate bundle --auto https://broken.example.com fixed-chain.crt
+'''
+
+Bundle a certificate with a chain to a private root:
+
+'''
+$ step certificate bundle --auto --roots root_ca.crt foo.crt foo-bundle.crt
+'''
 `,
-		Flags: []cli.Flag{flags.Force},
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "auto",
+				Usage: `Find the intermediate certificates automatically, downloading the missing ones.`,
+			},
+			cli.StringFlag{
+				Name: "roots",
+				Usage: `Root certificate(s) that will be used to terminate the chain built with
+**--auto**. Defaults to the system trust store.
+
+: <roots> is a case-sensitive string and may be one of:
+
+    **file**
+	:  Relative or full path to a file. All certificates in the file will be used for path validation.
+
+    **list of files**
+	:  Comma-separated list of relative or full file paths. Every PEM encoded certificate from each file will be used for path validation.
+
+    **directory**
+	:  Relative or full path to a directory. Every PEM encoded certificate from each file in the directory will be used for path validation.`,
+			},
+			flags.ServerName,
+			flags.Force,
+		},
 	}
 }
 
 func bundleAction(ctx *cli.Context) error {
+	if ctx.Bool("auto") {
+		return bundleAutoAction(ctx)
+	}
 	if err := errs.NumberOfArguments(ctx, 3); err != nil {
 		return err
 	}
@@ -85,3 +156,80 @@ func bundleAction(ctx *cli.Context) error {
 	ui.Printf("Your certificate has been saved in %s.\n", chainFile)
 	return nil
 }
+
+func bundleAutoAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 2); err != nil {
+		return err
+	}
+
+	crtFile := ctx.Args().Get(0)
+	chainFile := ctx.Args().Get(1)
+	roots := ctx.String("roots")
+
+	var certs []*x509.Certificate
+	switch addr, isURL, err := trimURL(crtFile); {
+	case err != nil:
+		return err
+	case isURL:
+		// The chain is verified by the builder
+		if certs, err = getPeerCertificates(addr, startTLSProtocol(crtFile), ctx.String("servername"), roots, true); err != nil {
+			return err
+		}
+	default:
+		if certs, err = pemutil.ReadCertificateBundle(crtFile); err != nil {
+			return err
+		}
+	}
+
+	builder := &chainutil.Builder{
+		Intermediates: certs[1:],
+		CacheDir:      filepath.Join(step.Path(), "cache", "aia"),
+	}
+	if roots != "" {
+		var err error
+		if builder.Roots, err = x509util.ReadCertPool(roots); err != nil {
+			return errors.Wrapf(err, "failure to load root certificate pool from input path '%s'", roots)
+		}
+	}
+
+	chain, err := builder.Build(certs[0])
+	printChain(chain)
+	if err != nil {
+		return errors.Wrap(err, "error building the certificate chain")
+	}
+
+	var out []byte
+	for _, crt := range chain.Certificates() {
+		out = append(out, pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: crt.Raw,
+		})...)
+	}
+	if err := fileutil.WriteFile(chainFile, out, 0o600); err != nil {
+		return err
+	}
+
+	ui.Printf("Your certificate has been saved in %s.\n", chainFile)
+	return nil
+}
+
+// printChain reports where each certificate in the chain comes from.
+func printChain(chain *chainutil.Chain) {
+	if chain == nil {
+		return
+	}
+	ui.Printf("Certificate: %s\n", chain.Leaf.Subject)
+	for _, l := range chain.Links {
+		switch l.Source {
+		case chainutil.SourceFetched:
+			ui.Printf("Issuer: %s (fetched from %s)\n", l.Certificate.Subject, l.URL)
+		case chainutil.SourceCache:
+			ui.Printf("Issuer: %s (from the cache, %s)\n", l.Certificate.Subject, l.URL)
+		default:
+			ui.Printf("Issuer: %s (provided)\n", l.Certificate.Subject)
+		}
+	}
+	if chain.Root != nil {
+		ui.Printf("Root: %s\n", chain.Root.Subject)
+	}
+}
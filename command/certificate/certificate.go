@@ -94,7 +94,12 @@ $ step certificate uninstall root-ca.crt
 			keyCommand(),
 			installCommand(),
 			uninstallCommand(),
+			truststoreCommand(),
 			p12Command(),
+			scanCommand(),
+			sctCommand(),
+			ctSubmitCommand(),
+			reportCommand(),
 		},
 	}
 
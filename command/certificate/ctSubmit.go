@@ -0,0 +1,160 @@
+package certificate
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/internal/ctutil"
+)
+
+func ctSubmitCommand() cli.Command {
+	return cli.Command{
+		Name:   "ct-submit",
+		Action: cli.ActionFunc(ctSubmitAction),
+		Usage:  `submit a certificate chain to a certificate transparency log`,
+		UsageText: `**step certificate ct-submit** <crt-file> <log-url>
+[**--issuer**=<file>] [**--log-list**=<file>] [**--out**=<file>]
+[**--format**=<format>] [**--roots**=<root-bundle>]`,
+		Description: `**step certificate ct-submit** submits a certificate chain to the
+'add-chain' endpoint of a certificate transparency (CT) log, defined in RFC
+6962, and prints the signed certificate timestamp (SCT) returned by the log.
+
+The chain must start with the certificate to submit, followed by its
+intermediates. Logs only accept chains that end in one of their accepted
+roots, which may need to be included in the chain.
+
+With **--log-list**, the SCT is verified with the key of the log in the given
+JSON log list. With **--out**, the SCT is saved in its binary TLS encoding,
+the format used by web servers to send static SCTs.
+
+## POSITIONAL ARGUMENTS
+
+<crt-file>
+:  The path to the certificate or certificate bundle to submit.
+
+<log-url>
+:  The base URL of the log, for example, https://ct.googleapis.com/logs/us1/argon2025h1/.
+
+## EXAMPLES
+
+Submit a certificate bundle to a log:
+'''
+$ step certificate ct-submit bundle.crt https://ct.example.com/log/
+'''
+
+Submit a certificate and its issuer, verify the SCT, and save it:
+'''
+$ step certificate ct-submit leaf.crt https://ct.example.com/log/ \
+  --issuer intermediate.crt --log-list log_list.json --out leaf.sct
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "issuer",
+				Usage: `The issuer certificate <file> to append to the chain.`,
+			},
+			cli.StringFlag{
+				Name:  "log-list",
+				Usage: `The JSON <file> with the list of CT logs used to verify the SCT.`,
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Usage: `The <file> to write the SCT in its binary TLS encoding.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: `The output <format> of the SCT.
+
+: <format> is a string and must be one of:
+
+    **text**
+    :  Print output in unstructured text suitable for a human to read.
+
+    **json**
+    :  Print output in JSON format.`,
+			},
+			cli.StringFlag{
+				Name:  "roots",
+				Usage: `The root certificate(s) used to verify the TLS certificate of the log.`,
+			},
+		},
+	}
+}
+
+func ctSubmitAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 2); err != nil {
+		return err
+	}
+
+	var (
+		crtFile = ctx.Args().Get(0)
+		logURL  = ctx.Args().Get(1)
+		format  = ctx.String("format")
+		logs    *ctutil.LogList
+	)
+	if format != "text" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	}
+	if filename := ctx.String("log-list"); filename != "" {
+		var err error
+		if logs, err = ctutil.ReadLogList(filename); err != nil {
+			return err
+		}
+	}
+
+	chain, err := pemutil.ReadCertificateBundle(crtFile)
+	if err != nil {
+		return err
+	}
+	if filename := ctx.String("issuer"); filename != "" {
+		issuer, err := pemutil.ReadCertificate(filename)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, issuer)
+	}
+
+	client := &http.Client{}
+	if roots := ctx.String("roots"); roots != "" {
+		pool, err := x509util.ReadCertPool(roots)
+		if err != nil {
+			return err
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+		client.Transport = tr
+	}
+
+	sct, err := ctutil.AddChain(client, logURL, chain)
+	if err != nil {
+		return err
+	}
+
+	// The SCT returned by add-chain is signed over the certificate.
+	info := newSCTInfo("", sct, logs, chain[0], nil)
+	if out := ctx.String("out"); out != "" {
+		if err := fileutil.WriteFile(out, sct.Raw, 0o644); err != nil {
+			return err
+		}
+		ui.Printf("Your SCT has been saved in %s.\n", out)
+	}
+	if err := printSCTs([]*sctInfo{info}, format); err != nil {
+		return err
+	}
+	if info.Verified != nil && !*info.Verified {
+		return errors.New("failed to verify SCT")
+	}
+	return nil
+}
@@ -54,6 +54,11 @@ $ step certificate fingerprint https://smallstep.com
 e2c4f12edfc1816cc610755d32e6f45d5678ba21ecda1693bb5b246e3c48c03d
 '''
 
+Get the fingerprint for the certificate of an IMAP server after STARTTLS:
+'''
+$ step certificate fingerprint imap://mail.example.com
+'''
+
 Get the fingerprints for a remote certificate with its intermediate:
 '''
 $ step certificate fingerprint --bundle https://smallstep.com
@@ -136,7 +141,7 @@ func fingerprintAction(ctx *cli.Context) error {
 	case err != nil:
 		return err
 	case isURL:
-		certs, err = getPeerCertificates(addr, serverName, roots, insecure)
+		certs, err = getPeerCertificates(addr, startTLSProtocol(crtFile), serverName, roots, insecure)
 		if err != nil {
 			return err
 		}
@@ -3,8 +3,10 @@ package certificate
 import (
 	"bytes"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -15,24 +17,36 @@ import (
 	"github.com/smallstep/cli-utils/ui"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/certformat"
 	"github.com/smallstep/cli/utils"
 )
 
 func formatCommand() cli.Command {
 	return cli.Command{
-		Name:      "format",
-		Action:    command.ActionFunc(formatAction),
-		Usage:     `reformat certificate`,
-		UsageText: `**step certificate format** <crt-file> [**--out**=<file>]`,
+		Name:   "format",
+		Action: command.ActionFunc(formatAction),
+		Usage:  `reformat certificate`,
+		UsageText: `**step certificate format** <crt-file> [**--out**=<file>]
+[**--format**=<format>] [**--bundle**] [**--password-file**=<file>]`,
 		Description: `**step certificate format** prints the certificate or CSR in a different format.
 
-Only 2 formats are currently supported; PEM and ASN.1 DER. This tool will convert
-a certificate or CSR in one format to the other.
+The input format is detected automatically, and can be PEM, ASN.1 DER, base64
+encoded DER without PEM armor, a PKCS #7 bundle (.p7b or .p7c) in DER or PEM
+format, or a Java keystore in JKS or JCEKS format. The input may contain
+certificates, CSRs, and CRLs. Only the certificates of a Java keystore are
+converted, its private keys are not exported.
+
+Without **--format**, a certificate in PEM format is converted to DER, and a
+certificate in any other format is converted to PEM.
+
+By default, only the first certificate, CSR or CRL of the input is converted.
+Use **--bundle** to convert all of them, for example, a certificate chain or
+all the certificates of a keystore.
 
 ## POSITIONAL ARGUMENTS
 
 <crt-file>
-:  Path to a certificate or CSR file.
+:  Path to a certificate, CSR, CRL, PKCS #7 bundle, or Java keystore file.
 
 ## EXIT CODES
 
@@ -54,26 +68,94 @@ Convert PEM format to DER and write to disk:
 '''
 $ step certificate format foo.pem --out foo.der
 '''
+
+Convert a certificate chain to a PKCS #7 bundle:
+'''
+$ step certificate format --bundle --format p7b chain.crt --out chain.p7b
+'''
+
+Convert the certificates and CRLs of a PKCS #7 bundle to PEM:
+'''
+$ step certificate format --bundle chain.p7b
+'''
+
+Convert all the certificates of a Java keystore to PEM, verifying its
+integrity with the keystore password:
+'''
+$ step certificate format --bundle --password-file pass.txt keystore.jks
+'''
+
+Print a certificate in base64 without PEM armor:
+'''
+$ step certificate format --format base64 foo.crt
+'''
+
+Export a root certificate in the certdata.txt format used by NSS:
+'''
+$ step certificate format --format nss root_ca.crt
+'''
 `,
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "out",
 				Usage: `Path to write the reformatted result.`,
 			},
+			cli.StringFlag{
+				Name: "format",
+				Usage: `The output <format>.
+
+: <format> is a string and must be one of:
+
+    **pem**
+    :  PEM format.
+
+    **der**
+    :  ASN.1 DER format, only one certificate, CSR or CRL can be converted.
+
+    **p7b**
+    :  A PKCS #7 bundle in DER format, with the certificates and CRLs of the input.
+
+    **base64**
+    :  Base64 encoded DER without PEM armor, one line per certificate, CSR or CRL.
+
+    **nss**
+    :  The certdata.txt format used by NSS for its built-in trust anchors.`,
+			},
+			cli.BoolFlag{
+				Name:  "bundle",
+				Usage: `Convert all the certificates, CSRs and CRLs of the input, not only the first one.`,
+			},
+			cli.StringFlag{
+				Name:  "password-file",
+				Usage: `The path to the <file> containing the password used to verify the integrity of a Java keystore.`,
+			},
 			flags.Force,
 		},
 	}
 }
 
+// formatItem is a certificate, CSR or CRL converted by the format command. The
+// type is the type of its PEM block.
+type formatItem struct {
+	Type  string
+	Bytes []byte
+}
+
 func formatAction(ctx *cli.Context) error {
 	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
 		return err
 	}
 
 	var (
-		out = ctx.String("out")
-		ob  []byte
+		out    = ctx.String("out")
+		format = ctx.String("format")
+		bundle = ctx.Bool("bundle")
 	)
+	switch format {
+	case "", "pem", "der", "p7b", "base64", "nss":
+	default:
+		return errs.InvalidFlagValue(ctx, "format", format, "pem, der, p7b, base64, nss")
+	}
 
 	var crtFile string
 	if ctx.NArg() == 1 {
@@ -87,27 +169,59 @@ func formatAction(ctx *cli.Context) error {
 		return errs.FileError(err, crtFile)
 	}
 
+	var (
+		items    []formatItem
+		inputPEM bool
+	)
 	switch {
-	case bytes.Contains(crtBytes, []byte("-----BEGIN ")): // PEM format
-		ob, err = decodeCertificatePem(crtBytes)
+	case certformat.IsKeyStore(crtBytes):
+		var password []byte
+		if passwordFile := ctx.String("password-file"); passwordFile != "" {
+			if password, err = utils.ReadPasswordFromFile(passwordFile); err != nil {
+				return err
+			}
+		} else {
+			ui.Println("The integrity of the keystore has not been verified, use the '--password-file' flag to verify it.")
+		}
+		entries, err := certformat.ParseKeyStore(crtBytes, password)
 		if err != nil {
 			return err
 		}
-	default: // assuming DER format
-		if crt, err := x509.ParseCertificate(crtBytes); err == nil {
-			ob = pem.EncodeToMemory(&pem.Block{
-				Type:  "CERTIFICATE",
-				Bytes: crt.Raw,
-			})
-		} else if csr, err := x509.ParseCertificateRequest(crtBytes); err == nil {
-			ob = pem.EncodeToMemory(&pem.Block{
-				Type:  "CERTIFICATE REQUEST",
-				Bytes: csr.Raw,
-			})
+		for _, e := range entries {
+			for _, crt := range e.Certificates {
+				items = append(items, formatItem{Type: "CERTIFICATE", Bytes: crt.Raw})
+			}
+		}
+	case bytes.Contains(crtBytes, []byte("-----BEGIN ")): // PEM format
+		if items, err = decodeCertificatePem(crtBytes); err != nil {
+			return err
+		}
+		inputPEM = true
+	default:
+		if items, err = decodeCertificateDer(crtBytes); err != nil {
+			if items, err = decodeCertificateBase64(crtBytes); err != nil {
+				return errors.Errorf("error parsing DER format certificate or certificate request")
+			}
+		}
+	}
+	if len(items) == 0 {
+		return errors.Errorf("error decoding certificate: no certificates found")
+	}
+	if !bundle {
+		items = items[:1]
+	}
+
+	if format == "" {
+		if inputPEM {
+			format = "der"
 		} else {
-			return errors.Errorf("error parsing DER format certificate or certificate request")
+			format = "pem"
 		}
 	}
+	ob, err := encodeFormatItems(items, format)
+	if err != nil {
+		return err
+	}
 
 	if out == "" {
 		os.Stdout.Write(ob)
@@ -127,11 +241,19 @@ func formatAction(ctx *cli.Context) error {
 	return nil
 }
 
-func decodeCertificatePem(b []byte) ([]byte, error) {
-	var block *pem.Block
-	for len(b) > 0 {
+// decodeCertificatePem returns the certificates, CSRs and CRLs in PEM format,
+// including the ones in PKCS #7 blocks. Other blocks are ignored.
+func decodeCertificatePem(b []byte) ([]formatItem, error) {
+	var (
+		block *pem.Block
+		items []formatItem
+	)
+	for len(bytes.TrimSpace(b)) > 0 {
 		block, b = pem.Decode(b)
 		if block == nil {
+			if len(items) > 0 {
+				break
+			}
 			return nil, errors.Errorf("error decoding certificate: invalid PEM block")
 		}
 		switch block.Type {
@@ -140,17 +262,150 @@ func decodeCertificatePem(b []byte) ([]byte, error) {
 			if err != nil {
 				return nil, errors.Wrap(err, "error parsing certificate")
 			}
-			return crt.Raw, nil
-		case "CERTIFICATE REQUEST":
+			items = append(items, formatItem{Type: block.Type, Bytes: crt.Raw})
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
 			csr, err := x509.ParseCertificateRequest(block.Bytes)
 			if err != nil {
 				return nil, errors.Wrap(err, "error parsing certificate request")
 			}
-			return csr.Raw, nil
+			items = append(items, formatItem{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})
+		case "X509 CRL":
+			crl, err := x509.ParseRevocationList(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "error parsing CRL")
+			}
+			items = append(items, formatItem{Type: block.Type, Bytes: crl.Raw})
+		case "PKCS7":
+			p7, err := decodePKCS7(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, p7...)
 		default:
 			continue
 		}
 	}
 
-	return nil, errors.Errorf("error decoding certificate: invalid PEM block")
+	if len(items) == 0 {
+		return nil, errors.Errorf("error decoding certificate: invalid PEM block")
+	}
+	return items, nil
+}
+
+// decodeCertificateDer returns the certificate, CSR or CRL in DER format, or
+// the certificates and CRLs in a DER encoded PKCS #7 bundle.
+func decodeCertificateDer(b []byte) ([]formatItem, error) {
+	if crt, err := x509.ParseCertificate(b); err == nil {
+		return []formatItem{{Type: "CERTIFICATE", Bytes: crt.Raw}}, nil
+	}
+	if csr, err := x509.ParseCertificateRequest(b); err == nil {
+		return []formatItem{{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw}}, nil
+	}
+	if crl, err := x509.ParseRevocationList(b); err == nil {
+		return []formatItem{{Type: "X509 CRL", Bytes: crl.Raw}}, nil
+	}
+	return decodePKCS7(b)
+}
+
+// decodeCertificateBase64 returns the items in base64 encoded DER format, one
+// per line, or a single item split in multiple lines.
+func decodeCertificateBase64(b []byte) ([]formatItem, error) {
+	if der, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(b), nil))); err == nil {
+		if items, err := decodeCertificateDer(der); err == nil {
+			return items, nil
+		}
+	}
+
+	var items []formatItem
+	for _, line := range bytes.Fields(b) {
+		der, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil {
+			return nil, errors.Wrap(err, "error decoding base64")
+		}
+		lineItems, err := decodeCertificateDer(der)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, lineItems...)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("error decoding base64: no data")
+	}
+	return items, nil
+}
+
+func decodePKCS7(b []byte) ([]formatItem, error) {
+	certs, crls, err := certformat.ParsePKCS7(b)
+	if err != nil {
+		return nil, err
+	}
+	var items []formatItem
+	for _, crt := range certs {
+		items = append(items, formatItem{Type: "CERTIFICATE", Bytes: crt.Raw})
+	}
+	for _, crl := range crls {
+		items = append(items, formatItem{Type: "X509 CRL", Bytes: crl.Raw})
+	}
+	return items, nil
+}
+
+// encodeFormatItems returns the items in the given format.
+func encodeFormatItems(items []formatItem, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "pem":
+		for _, item := range items {
+			buf.Write(pem.EncodeToMemory(&pem.Block{Type: item.Type, Bytes: item.Bytes}))
+		}
+	case "der":
+		if len(items) > 1 {
+			return nil, errors.New("the DER format does not support multiple certificates, use '--format p7b'")
+		}
+		buf.Write(items[0].Bytes)
+	case "base64":
+		for _, item := range items {
+			buf.WriteString(base64.StdEncoding.EncodeToString(item.Bytes))
+			buf.WriteString("\n")
+		}
+	case "p7b", "nss":
+		var (
+			certs []*x509.Certificate
+			crls  []*x509.RevocationList
+		)
+		for _, item := range items {
+			switch item.Type {
+			case "CERTIFICATE":
+				crt, err := x509.ParseCertificate(item.Bytes)
+				if err != nil {
+					return nil, errors.Wrap(err, "error parsing certificate")
+				}
+				certs = append(certs, crt)
+			case "X509 CRL":
+				if format == "nss" {
+					return nil, errors.New("the NSS format does not support CRLs")
+				}
+				crl, err := x509.ParseRevocationList(item.Bytes)
+				if err != nil {
+					return nil, errors.Wrap(err, "error parsing CRL")
+				}
+				crls = append(crls, crl)
+			default:
+				return nil, errors.Errorf("the %s format does not support certificate requests", strings.ToUpper(format))
+			}
+		}
+		var (
+			b   []byte
+			err error
+		)
+		if format == "p7b" {
+			b, err = certformat.MarshalPKCS7(certs, crls)
+		} else {
+			b, err = certformat.MarshalCertData(certs)
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
 }
@@ -37,6 +37,10 @@ If crt-file contains multiple certificates (i.e., it is a certificate "bundle")
 the first certificate in the bundle will be output. Pass the --bundle option to
 print all certificates in the order in which they appear in the bundle.
 
+The text format decodes the signed certificate timestamps (SCTs) embedded in a
+certificate. Use **step certificate sct** to print the SCTs sent by a remote
+server and to verify them against a log list.
+
 ## POSITIONAL ARGUMENTS
 
 <crt-file>
@@ -78,6 +82,12 @@ Inspect a remote certificate (using the standard port derived from the URL prefi
 $ step certificate inspect smtps://smtp.gmail.com
 '''
 
+Inspect a remote certificate after upgrading the connection with STARTTLS (the
+smtp, imap, pop3, ldap, postgres, mysql, and xmpp prefixes use STARTTLS):
+'''
+$ step certificate inspect smtp://smtp.gmail.com:587
+'''
+
 Inspect an invalid remote certificate:
 '''
 $ step certificate inspect --insecure https://expired.badssl.com
@@ -212,7 +222,7 @@ func inspectAction(ctx *cli.Context) error {
 	case err != nil:
 		return err
 	case isURL:
-		peerCertificates, err := getPeerCertificates(addr, serverName, roots, insecure)
+		peerCertificates, err := getPeerCertificates(addr, startTLSProtocol(crtFile), serverName, roots, insecure)
 		if err != nil {
 			return err
 		}
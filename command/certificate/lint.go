@@ -1,9 +1,12 @@
 package certificate
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"maps"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -11,8 +14,10 @@ import (
 	"github.com/smallstep/cli-utils/errs"
 	zx509 "github.com/smallstep/zcrypto/x509"
 	"github.com/smallstep/zlint"
+	"github.com/smallstep/zlint/lints"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/lintprofile"
 )
 
 func lintCommand() cli.Command {
@@ -21,13 +26,64 @@ func lintCommand() cli.Command {
 		Action: cli.ActionFunc(lintAction),
 		Usage:  `lint certificate details`,
 		UsageText: `**step certificate lint** <crt-file> [**--roots**=<root-bundle>]
-[**--servername**=<servername>]`,
-		Description: `**step certificate lint** checks a certificate for common errors and outputs the result in JSON format. It is intended for evaluating Web PKI certificates, and may not be appropriate for internal PKIs.
+[**--servername**=<servername>] [**--profile**=<file>] [**--format**=<format>]`,
+		Description: `**step certificate lint** checks a certificate for common errors and outputs the result in JSON format. By default, it runs all the lints of zlint, which are intended for evaluating Web PKI certificates, and may not be appropriate for internal PKIs.
+
+A lint profile adapts the command to a private PKI. A profile is a YAML or
+JSON file that disables lint sources or individual lints, and adds custom
+rules. The lint sources are 'cabf_br', 'rfc5280', 'rfc5891', 'zlint', and
+'awslabs'. The custom rules are:
+
+**keyTypes**
+:  The allowed key types. Each element has a **type**, EC, RSA or OKP, and
+optionally the **minSize** of RSA keys or the allowed **curves** of EC keys.
+
+**maxValidity**
+:  The maximum validity of the certificate, as a duration like '2160h' or a
+number of days like '90d'. For CRLs, it is the maximum time between the this
+update and next update fields.
+
+**sans**
+:  The rules of the subject alternative names. If **required** is true, the
+extension must be present. The **dns**, **ip**, **email**, and **uri** lists
+contain regular expressions, and all the names of that type must match one of
+them.
+
+**requiredExtKeyUsages**
+:  The extended key usages that must be present, as names like 'serverAuth'
+and 'clientAuth', or OIDs.
+
+**forbiddenExtensions**
+:  The extensions that must not be present, as names like 'nameConstraints'
+and 'authorityInfoAccess', or OIDs.
+
+With a profile, the command can also lint certificate signing requests (CSRs)
+and CRLs. zlint does not apply to them, so only the custom rules run.
+
+For example:
+'''
+name: internal
+disabledSources: [cabf_br, rfc5891]
+disabledLints: [w_ext_subject_key_identifier_missing_sub_cert]
+rules:
+  keyTypes:
+  - type: EC
+    curves: [P-256, P-384]
+  - type: RSA
+    minSize: 3072
+  maxValidity: 90d
+  sans:
+    required: true
+    dns: ['^[a-z0-9-]+\.internal\.example\.com$']
+  requiredExtKeyUsages: [serverAuth]
+  forbiddenExtensions: [nameConstraints]
+'''
 
 ## POSITIONAL ARGUMENTS
 
 <crt-file>
-:  Path to a certificate or certificate signing request (CSR) to lint.
+:  Path to a certificate, certificate signing request (CSR) or CRL to lint, in
+PEM or DER format.
 
 ## EXIT CODES
 
@@ -45,6 +101,11 @@ Lint a remote certificate (using the default root certificate bundle to verify t
 $ step certificate lint https://smallstep.com
 '''
 
+Lint the certificate of an LDAP server after STARTTLS:
+'''
+$ step certificate lint ldap://ldap.example.com
+'''
+
 Lint a remote certificate using a custom root certificate to verify the server:
 
 '''
@@ -63,6 +124,16 @@ Lint a remote certificate using a custom directory of root certificates to verif
 '''
 $ step certificate lint https://smallstep.com --roots "./path/to/certificates/"
 '''
+
+Lint a certificate with a custom profile:
+'''
+$ step certificate lint ./certificate.crt --profile internal.yaml
+'''
+
+Lint a CSR with a custom profile, and output the findings in SARIF format:
+'''
+$ step certificate lint ./certificate.csr --profile internal.yaml --format sarif > lint.sarif
+'''
 `,
 		Flags: []cli.Flag{
 			cli.StringFlag{
@@ -87,6 +158,23 @@ authenticity of the remote server.
 debugging invalid certificates remotely.`,
 			},
 			flags.ServerName,
+			cli.StringFlag{
+				Name:  "profile",
+				Usage: `The <file> with the lint profile, in YAML or JSON format.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: `The output <format> of the results.
+
+: <format> is a string and must be one of:
+
+    **json**
+    :  Print the zlint results in JSON format.
+
+    **sarif**
+    :  Print the findings in SARIF format, used by code scanning tools.`,
+			},
 		},
 	}
 }
@@ -101,13 +189,25 @@ func lintAction(ctx *cli.Context) error {
 		roots      = ctx.String("roots")
 		serverName = ctx.String("servername")
 		insecure   = ctx.Bool("insecure")
+		format     = ctx.String("format")
+		profile    *lintprofile.Profile
 		block      *pem.Block
 	)
+	if format != "json" && format != "sarif" {
+		return errs.InvalidFlagValue(ctx, "format", format, "json, sarif")
+	}
+	if filename := ctx.String("profile"); filename != "" {
+		var err error
+		if profile, err = lintprofile.Load(filename); err != nil {
+			return err
+		}
+	}
+
 	switch addr, isURL, err := trimURL(crtFile); {
 	case err != nil:
 		return err
 	case isURL:
-		peerCertificates, err := getPeerCertificates(addr, serverName, roots, insecure)
+		peerCertificates, err := getPeerCertificates(addr, startTLSProtocol(crtFile), serverName, roots, insecure)
 		if err != nil {
 			return err
 		}
@@ -121,20 +221,68 @@ func lintAction(ctx *cli.Context) error {
 		if err != nil {
 			return errs.FileError(err, crtFile)
 		}
-		block, _ = pem.Decode(crtBytes)
-		if block == nil {
-			return errors.Errorf("could not parse certificate file '%s'", crtFile)
+		if block, _ = pem.Decode(crtBytes); block == nil {
+			if block = derBlock(crtBytes); block == nil {
+				return errors.Errorf("could not parse certificate file '%s'", crtFile)
+			}
 		}
 	}
 
-	zcrt, err := zx509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return errors.WithStack(err)
+	var resultSet *zlint.ResultSet
+	switch block.Type {
+	case "CERTIFICATE":
+		zcrt, err := zx509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if profile == nil {
+			resultSet = zlint.LintCertificate(zcrt)
+			break
+		}
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		resultSet = newLintResultSet()
+		for name, l := range lints.Lints {
+			if profile.Enabled(name, l) {
+				resultSet.Results[name] = l.Execute(zcrt)
+			}
+		}
+		maps.Copy(resultSet.Results, profile.CheckCertificate(crt))
+	case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+		if profile == nil {
+			return errors.New("linting a certificate signing request requires the '--profile' flag")
+		}
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		resultSet = newLintResultSet()
+		maps.Copy(resultSet.Results, profile.CheckCSR(csr))
+	case "X509 CRL":
+		if profile == nil {
+			return errors.New("linting a CRL requires the '--profile' flag")
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		resultSet = newLintResultSet()
+		maps.Copy(resultSet.Results, profile.CheckCRL(crl))
+	default:
+		return errors.Errorf("could not parse certificate file '%s': unsupported PEM type %s", crtFile, block.Type)
 	}
-	zlintResult := zlint.LintCertificate(zcrt)
-	b, err := json.MarshalIndent(struct {
+
+	setLintResultFlags(resultSet)
+
+	var v any = struct {
 		*zlint.ResultSet
-	}{zlintResult}, "", " ")
+	}{resultSet}
+	if format == "sarif" {
+		v = lintprofile.NewSARIF(crtFile, resultSet.Results)
+	}
+	b, err := json.MarshalIndent(v, "", " ")
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -142,3 +290,43 @@ func lintAction(ctx *cli.Context) error {
 
 	return nil
 }
+
+// derBlock returns a PEM block with the DER encoded certificate, CSR or CRL.
+func derBlock(b []byte) *pem.Block {
+	if _, err := x509.ParseCertificate(b); err == nil {
+		return &pem.Block{Type: "CERTIFICATE", Bytes: b}
+	}
+	if _, err := x509.ParseCertificateRequest(b); err == nil {
+		return &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: b}
+	}
+	if _, err := x509.ParseRevocationList(b); err == nil {
+		return &pem.Block{Type: "X509 CRL", Bytes: b}
+	}
+	return nil
+}
+
+// newLintResultSet returns an empty result set. The present flags are set by
+// setLintResultFlags once all the lints have run.
+func newLintResultSet() *zlint.ResultSet {
+	return &zlint.ResultSet{
+		Version:   zlint.Version,
+		Timestamp: time.Now().Unix(),
+		Results:   make(map[string]*lints.LintResult),
+	}
+}
+
+// setLintResultFlags sets the present flags of a result set.
+func setLintResultFlags(rs *zlint.ResultSet) {
+	for _, res := range rs.Results {
+		switch res.Status {
+		case lints.Notice:
+			rs.NoticesPresent = true
+		case lints.Warn:
+			rs.WarningsPresent = true
+		case lints.Error:
+			rs.ErrorsPresent = true
+		case lints.Fatal:
+			rs.FatalsPresent = true
+		}
+	}
+}
@@ -65,6 +65,12 @@ of its vlaidity period:
 $ step certificate needs-renewal https://smallstep.com
 '''
 
+Check if the certificate of a mail relay, only available after STARTTLS, has
+passed 66 percent of its validity period:
+'''
+$ step certificate needs-renewal smtp://mail.example.com
+'''
+
 Check if any certificate in the bundle for smallstep.com has has passed 66 percent
 of its validity period:
 '''
@@ -161,7 +167,7 @@ func needsRenewalAction(ctx *cli.Context) error {
 	case err != nil:
 		return errs.NewExitError(err, 255)
 	case isURL:
-		certs, err = getPeerCertificates(addr, serverName, roots, false)
+		certs, err = getPeerCertificates(addr, startTLSProtocol(certFile), serverName, roots, false)
 		if err != nil {
 			return errs.NewExitError(err, 255)
 		}
@@ -31,6 +31,9 @@ func p12Command() cli.Command {
 file containing certificates and keys. This can then be used to import
 into Windows / Firefox / Java applications.
 
+Use **step certificate p12 extract** to extract the certificates and keys from
+a .p12 file, and **step certificate p12 inspect** to print its contents.
+
 ## EXIT CODES
 
 This command returns 0 on success and \>0 if any error occurs.
@@ -87,6 +90,10 @@ multiple CAs or intermediates.`,
 			flags.Force,
 			flags.Insecure,
 		},
+		Subcommands: cli.Commands{
+			p12ExtractCommand(),
+			p12InspectCommand(),
+		},
 	}
 }
 
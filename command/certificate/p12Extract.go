@@ -0,0 +1,269 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/pkcs12util"
+	"github.com/smallstep/cli/utils"
+)
+
+func p12ExtractCommand() cli.Command {
+	return cli.Command{
+		Name:   "extract",
+		Action: command.ActionFunc(p12ExtractAction),
+		Usage:  `extract the certificates and the private key from a .p12 file`,
+		UsageText: `**step certificate p12 extract** <p12-path> [<crt-path>] [<key-path>]
+[**--ca**=<file>] [**--name**=<name>] [**--password-file**=<file>]
+[**--new-password-file**=<file>] [**--no-password**] [**--insecure**] [**--force**]`,
+		Description: `**step certificate p12 extract** extracts the certificate, the certificate
+chain and the private key from a .p12 (PFX / PKCS12) file, and writes them in
+PEM format. Files using modern algorithms (PBES2 with AES and PBMAC1) and
+legacy algorithms (RC2 and 3DES) are supported.
+
+The certificate written to <crt-path> is the one matching the private key, by
+local key ID or by public key, followed by its certificate chain. If **--name**
+is used, the private key with that friendly name is selected; if there is no
+such key, the certificate with that friendly name and its chain are extracted
+instead. If **--ca** is
+used, the certificate chain is written to that file instead. If the .p12 file
+does not contain a private key, like a Java trust store, all its certificates
+are written. If <crt-path> is not provided, the certificates are printed to
+STDOUT.
+
+The private key is written to <key-path> encrypted with a new password, unless
+**--no-password** and **--insecure** are used.
+
+If the .p12 file is not protected with an empty password, the password is read
+from **--password-file** or prompted.
+
+## POSITIONAL ARGUMENTS
+
+<p12-path>
+:  The path to the .p12 file.
+
+<crt-path>
+:  The path to write the certificate and the certificate chain.
+
+<key-path>
+:  The path to write the private key.
+
+## EXIT CODES
+
+This command returns 0 on success and \>0 if any error occurs.
+
+## EXAMPLES
+
+Extract the certificate chain and the private key:
+'''
+$ step certificate p12 extract foo.p12 foo.crt foo.key
+'''
+
+Extract the certificate and the certificate chain to different files:
+'''
+$ step certificate p12 extract foo.p12 foo.crt foo.key --ca intermediate.crt
+'''
+
+Print the certificates in a .p12 file:
+'''
+$ step certificate p12 extract foo.p12
+'''
+
+Extract the entry with the given friendly name from a .p12 file with multiple keys:
+'''
+$ step certificate p12 extract --name server store.p12 server.crt server.key
+'''
+
+Extract the certificate with the given friendly name and its chain from a trust store:
+'''
+$ step certificate p12 extract --name intermediate truststore.p12 intermediate.crt
+'''
+
+Extract all the certificates in a trust store:
+'''
+$ step certificate p12 extract --password-file changeit.txt truststore.p12 roots.crt
+'''
+
+Extract the private key without encrypting it:
+'''
+$ step certificate p12 extract --no-password --insecure foo.p12 foo.crt foo.key
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "ca",
+				Usage: `The path to the <file> to write the certificate chain.`,
+			},
+			cli.StringFlag{
+				Name: "name",
+				Usage: `The friendly <name> of the private key or certificate to extract, required if
+the .p12 file contains multiple private keys. Certificates are matched if no
+private key has that name.`,
+			},
+			cli.StringFlag{
+				Name:  "password-file",
+				Usage: `The path to the <file> containing the password to decrypt the .p12 file.`,
+			},
+			cli.StringFlag{
+				Name:  "new-password-file",
+				Usage: `The path to the <file> containing the password to encrypt the private key.`,
+			},
+			flags.NoPassword,
+			flags.Force,
+			flags.Insecure,
+		},
+	}
+}
+
+func p12ExtractAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 1, 3); err != nil {
+		return err
+	}
+
+	p12File := ctx.Args().Get(0)
+	crtFile := ctx.Args().Get(1)
+	keyFile := ctx.Args().Get(2)
+	caFile := ctx.String("ca")
+	name := ctx.String("name")
+	noPass := ctx.Bool("no-password")
+
+	switch {
+	case noPass && ctx.String("new-password-file") != "":
+		return errs.IncompatibleFlagWithFlag(ctx, "no-password", "new-password-file")
+	case noPass && !ctx.Bool("insecure"):
+		return errs.RequiredInsecureFlag(ctx, "no-password")
+	}
+
+	p12, err := readP12(ctx, p12File)
+	if err != nil {
+		return err
+	}
+
+	// Select the identity to extract
+	ids := p12.Identities()
+	if name != "" {
+		var filtered []*pkcs12util.Identity
+		for _, id := range ids {
+			if id.FriendlyName == name {
+				filtered = append(filtered, id)
+			}
+		}
+		if len(filtered) == 0 {
+			if keyFile != "" {
+				return errors.Errorf("%s does not contain a private key with the friendly name %q", p12File, name)
+			}
+			if filtered = p12.NamedCertificates(name); len(filtered) == 0 {
+				return errors.Errorf("%s does not contain a private key or certificate with the friendly name %q", p12File, name)
+			}
+		}
+		ids = filtered
+	}
+
+	var certs, chain []*x509.Certificate
+	var id *pkcs12util.Identity
+	switch len(ids) {
+	case 0:
+		if keyFile != "" {
+			return errors.Errorf("%s does not contain a private key", p12File)
+		}
+		certs = p12.Certificates()
+		if len(certs) == 0 {
+			return errors.Errorf("%s does not contain certificates", p12File)
+		}
+	case 1:
+		id = ids[0]
+		if id.Certificate == nil {
+			return errors.Errorf("%s does not contain a certificate for the private key", p12File)
+		}
+		certs = []*x509.Certificate{id.Certificate}
+		chain = id.Chain
+	default:
+		if name != "" {
+			return errors.Errorf("%s contains %d entries with the friendly name %q", p12File, len(ids), name)
+		}
+		var names []string
+		for _, id := range ids {
+			names = append(names, fmt.Sprintf("%q", id.FriendlyName))
+		}
+		return errors.Errorf("%s contains %d private keys: use the '--name' flag to select one of %s",
+			p12File, len(ids), strings.Join(names, ", "))
+	}
+
+	if caFile == "" {
+		certs = append(certs, chain...)
+		chain = nil
+	} else if len(chain) == 0 {
+		return errors.Errorf("%s does not contain the certificate chain", p12File)
+	}
+
+	// Write the certificates
+	if crtFile == "" {
+		os.Stdout.Write(encodeCertificates(certs))
+	} else {
+		if err := fileutil.WriteFile(crtFile, encodeCertificates(certs), 0o600); err != nil {
+			return errs.FileError(err, crtFile)
+		}
+	}
+	if caFile != "" {
+		if err := fileutil.WriteFile(caFile, encodeCertificates(chain), 0o600); err != nil {
+			return errs.FileError(err, caFile)
+		}
+	}
+
+	// Write the private key
+	if keyFile != "" {
+		opts := []pemutil.Options{pemutil.ToFile(keyFile, 0o600)}
+		if !noPass {
+			var pass []byte
+			if passFile := ctx.String("new-password-file"); passFile != "" {
+				if pass, err = utils.ReadPasswordFromFile(passFile); err != nil {
+					return errors.Wrap(err, "error reading encrypting password from file")
+				}
+			} else {
+				pass, err = ui.PromptPassword("Please enter the password to encrypt the private key",
+					ui.WithValidateNotEmpty())
+				if err != nil {
+					return errors.Wrap(err, "error reading password")
+				}
+			}
+			opts = append(opts, pemutil.WithPassword(pass))
+		}
+		if _, err := pemutil.Serialize(id.PrivateKey, opts...); err != nil {
+			return err
+		}
+	}
+
+	if crtFile != "" {
+		ui.Printf("Your certificate has been saved in %s.\n", crtFile)
+	}
+	if caFile != "" {
+		ui.Printf("Your certificate chain has been saved in %s.\n", caFile)
+	}
+	if keyFile != "" {
+		ui.Printf("Your private key has been saved in %s.\n", keyFile)
+	}
+	return nil
+}
+
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	var b []byte
+	for _, crt := range certs {
+		b = append(b, pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: crt.Raw,
+		})...)
+	}
+	return b
+}
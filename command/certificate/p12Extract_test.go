@@ -0,0 +1,82 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/minica"
+	"go.step.sm/crypto/pemutil"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func runP12Extract(t *testing.T, args ...string) error {
+	t.Helper()
+	fs := flag.NewFlagSet(t.Name(), 0)
+	for _, f := range p12ExtractCommand().Flags {
+		f.Apply(fs)
+	}
+	require.NoError(t, fs.Parse(args))
+	return p12ExtractAction(cli.NewContext(cli.NewApp(), fs, nil))
+}
+
+func Test_p12ExtractAction(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		DNSNames:  []string{"leaf.example.com"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	leafOnly := filepath.Join(dir, "leaf.p12")
+	data, err := pkcs12.Passwordless.Encode(key, leaf, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(leafOnly, data, 0o600))
+	trustStore := filepath.Join(dir, "truststore.p12")
+	data, err = pkcs12.Passwordless.EncodeTrustStoreEntries([]pkcs12.TrustStoreEntry{
+		{Cert: ca.Root, FriendlyName: "root"},
+		{Cert: ca.Intermediate, FriendlyName: "intermediate"},
+	}, "")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(trustStore, data, 0o600))
+
+	t.Run("fail/ca-without-chain", func(t *testing.T) {
+		crtFile := filepath.Join(dir, "leaf.crt")
+		caFile := filepath.Join(dir, "ca.crt")
+		err := runP12Extract(t, "--ca", caFile, leafOnly, crtFile)
+		assert.ErrorContains(t, err, "does not contain the certificate chain")
+		assert.NoFileExists(t, crtFile)
+		assert.NoFileExists(t, caFile)
+	})
+
+	t.Run("ok/certificate-name", func(t *testing.T) {
+		crtFile := filepath.Join(dir, "intermediate.crt")
+		require.NoError(t, runP12Extract(t, "--name", "intermediate", trustStore, crtFile))
+		certs, err := pemutil.ReadCertificateBundle(crtFile)
+		require.NoError(t, err)
+		assert.Equal(t, []*x509.Certificate{ca.Intermediate, ca.Root}, certs)
+	})
+
+	t.Run("fail/certificate-name-with-key", func(t *testing.T) {
+		err := runP12Extract(t, "--name", "intermediate", trustStore, filepath.Join(dir, "a.crt"), filepath.Join(dir, "a.key"))
+		assert.ErrorContains(t, err, `does not contain a private key with the friendly name "intermediate"`)
+	})
+
+	t.Run("fail/unknown-name", func(t *testing.T) {
+		err := runP12Extract(t, "--name", "missing", trustStore, filepath.Join(dir, "b.crt"))
+		assert.ErrorContains(t, err, `does not contain a private key or certificate with the friendly name "missing"`)
+		assert.NoFileExists(t, filepath.Join(dir, "b.crt"))
+	})
+}
@@ -0,0 +1,303 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/internal/pkcs12util"
+	"github.com/smallstep/cli/utils"
+)
+
+func p12InspectCommand() cli.Command {
+	return cli.Command{
+		Name:   "inspect",
+		Action: command.ActionFunc(p12InspectAction),
+		Usage:  `print the contents of a .p12 file in human readable format`,
+		UsageText: `**step certificate p12 inspect** <p12-path>
+[**--password-file**=<file>] [**--format**=<format>]`,
+		Description: `**step certificate p12 inspect** prints the structure of a .p12 (PFX /
+PKCS12) file: the MAC algorithm, the safe contents and the algorithms used to
+encrypt them, and the bags in each one, with their friendly names, local key
+IDs, and a summary of the certificates and private keys.
+
+Algorithms considered weak, like RC2, RC4, 3DES and the SHA-1 MAC are marked as
+legacy. OpenSSL 3 requires the legacy provider to read files using them.
+
+If the .p12 file is not protected with an empty password, the password is read
+from **--password-file** or prompted.
+
+## POSITIONAL ARGUMENTS
+
+<p12-path>
+:  The path to the .p12 file.
+
+## EXIT CODES
+
+This command returns 0 on success and \>0 if any error occurs.
+
+## EXAMPLES
+
+Inspect a .p12 file:
+'''
+$ step certificate p12 inspect foo.p12
+Please enter the password to decrypt foo.p12:
+MAC: SHA-256, 2048 iterations, 8 bytes of salt
+Safe 1: encrypted with PBES2 (AES-256-CBC, PBKDF2-HMAC-SHA256), 2048 iterations
+  Certificate bag:
+    Friendly name: foo
+    Local key ID: 8b1f...
+    Subject: CN=foo
+    Issuer: CN=Smallstep Intermediate CA
+    Serial number: 2734...
+    Validity: 2024-05-01T10:00:00Z to 2024-05-02T10:00:00Z
+    Fingerprint: 3e1d...
+Safe 2: not encrypted
+  PKCS #8 shrouded key bag:
+    Encryption: PBES2 (AES-256-CBC, PBKDF2-HMAC-SHA256), 2048 iterations
+    Friendly name: foo
+    Local key ID: 8b1f...
+    Private key: EC P-256
+'''
+
+Inspect a .p12 file in JSON format:
+'''
+$ step certificate p12 inspect --format json --password-file pass.txt foo.p12
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "password-file",
+				Usage: `The path to the <file> containing the password to decrypt the .p12 file.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: `The output format for printing the introspection details.
+
+: <format> is a string and must be one of:
+
+    **text**
+    :  Print output in unstructured text suitable for a human to read.
+
+    **json**
+    :  Print output in JSON format.`,
+			},
+		},
+	}
+}
+
+type p12Bag struct {
+	Type         string                `json:"type"`
+	FriendlyName string                `json:"friendlyName,omitempty"`
+	LocalKeyID   string                `json:"localKeyID,omitempty"`
+	Attributes   []string              `json:"attributes,omitempty"`
+	Encryption   *pkcs12util.Algorithm `json:"encryption,omitempty"`
+	Subject      string                `json:"subject,omitempty"`
+	Issuer       string                `json:"issuer,omitempty"`
+	SerialNumber string                `json:"serialNumber,omitempty"`
+	NotBefore    *time.Time            `json:"notBefore,omitempty"`
+	NotAfter     *time.Time            `json:"notAfter,omitempty"`
+	Fingerprint  string                `json:"fingerprint,omitempty"`
+	PrivateKey   string                `json:"privateKey,omitempty"`
+	Bags         []p12Bag              `json:"bags,omitempty"`
+}
+
+type p12Safe struct {
+	Encryption *pkcs12util.Algorithm `json:"encryption"`
+	Bags       []p12Bag              `json:"bags"`
+}
+
+type p12Info struct {
+	Version int             `json:"version"`
+	MAC     *pkcs12util.MAC `json:"mac"`
+	Safes   []p12Safe       `json:"safes"`
+}
+
+var p12BagNames = map[string]string{
+	"keyBag":              "Key bag",
+	"pkcs8ShroudedKeyBag": "PKCS #8 shrouded key bag",
+	"certBag":             "Certificate bag",
+	"crlBag":              "CRL bag",
+	"secretBag":           "Secret bag",
+	"safeContentsBag":     "Safe contents bag",
+}
+
+func p12InspectAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	format := ctx.String("format")
+	if format != "text" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	}
+
+	p12, err := readP12(ctx, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	info := p12Info{
+		Version: p12.Version,
+		MAC:     p12.MAC,
+	}
+	for _, s := range p12.Safes {
+		info.Safes = append(info.Safes, p12Safe{
+			Encryption: s.Encryption,
+			Bags:       newP12Bags(s.Bags),
+		})
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling p12 info")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	if info.MAC == nil {
+		fmt.Println("MAC: none")
+	} else {
+		fmt.Printf("MAC: %s, %d bytes of salt\n", describeAlgorithm(info.MAC.Algorithm), info.MAC.SaltLength)
+	}
+	for i, s := range info.Safes {
+		if s.Encryption == nil {
+			fmt.Printf("Safe %d: not encrypted\n", i+1)
+		} else {
+			fmt.Printf("Safe %d: encrypted with %s\n", i+1, describeAlgorithm(s.Encryption))
+		}
+		printP12Bags(s.Bags, "  ")
+	}
+	return nil
+}
+
+// readP12 reads and decrypts a .p12 file. It tries the empty password first,
+// and then the password in the password-file flag or the one prompted.
+func readP12(ctx *cli.Context, filename string) (*pkcs12util.File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+
+	var pass string
+	if passFile := ctx.String("password-file"); passFile != "" {
+		if pass, err = utils.ReadStringPasswordFromFile(passFile); err != nil {
+			return nil, err
+		}
+		return pkcs12util.Parse(data, pass)
+	}
+
+	p12, err := pkcs12util.Parse(data, "")
+	if !errors.Is(err, pkcs12util.ErrIncorrectPassword) {
+		return p12, err
+	}
+	b, err := ui.PromptPassword(fmt.Sprintf("Please enter the password to decrypt %s", filename))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading password")
+	}
+	return pkcs12util.Parse(data, string(b))
+}
+
+func newP12Bags(bags []*pkcs12util.Bag) []p12Bag {
+	var ret []p12Bag
+	for _, b := range bags {
+		bag := p12Bag{
+			Type:         b.Type,
+			FriendlyName: b.FriendlyName,
+			Attributes:   b.Attributes,
+			Encryption:   b.Encryption,
+			Bags:         newP12Bags(b.Bags),
+		}
+		if len(b.LocalKeyID) > 0 {
+			bag.LocalKeyID = hex.EncodeToString(b.LocalKeyID)
+		}
+		if crt := b.Certificate; crt != nil {
+			bag.Subject = crt.Subject.String()
+			bag.Issuer = crt.Issuer.String()
+			bag.SerialNumber = crt.SerialNumber.String()
+			bag.NotBefore = &crt.NotBefore
+			bag.NotAfter = &crt.NotAfter
+			bag.Fingerprint = x509util.Fingerprint(crt)
+		}
+		if b.PrivateKey != nil {
+			bag.PrivateKey = describePrivateKey(b.PrivateKey)
+		}
+		ret = append(ret, bag)
+	}
+	return ret
+}
+
+func printP12Bags(bags []p12Bag, indent string) {
+	for _, b := range bags {
+		name, ok := p12BagNames[b.Type]
+		if !ok {
+			name = "Unknown bag " + b.Type
+		}
+		fmt.Printf("%s%s:\n", indent, name)
+		in := indent + "  "
+		if b.Encryption != nil {
+			fmt.Printf("%sEncryption: %s\n", in, describeAlgorithm(b.Encryption))
+		}
+		if b.FriendlyName != "" {
+			fmt.Printf("%sFriendly name: %s\n", in, b.FriendlyName)
+		}
+		if b.LocalKeyID != "" {
+			fmt.Printf("%sLocal key ID: %s\n", in, b.LocalKeyID)
+		}
+		if len(b.Attributes) > 0 {
+			fmt.Printf("%sOther attributes: %s\n", in, strings.Join(b.Attributes, ", "))
+		}
+		if b.Subject != "" || b.Issuer != "" {
+			fmt.Printf("%sSubject: %s\n", in, b.Subject)
+			fmt.Printf("%sIssuer: %s\n", in, b.Issuer)
+			fmt.Printf("%sSerial number: %s\n", in, b.SerialNumber)
+			fmt.Printf("%sValidity: %s to %s\n", in, b.NotBefore.UTC().Format(time.RFC3339), b.NotAfter.UTC().Format(time.RFC3339))
+			fmt.Printf("%sFingerprint: %s\n", in, b.Fingerprint)
+		}
+		if b.PrivateKey != "" {
+			fmt.Printf("%sPrivate key: %s\n", in, b.PrivateKey)
+		}
+		printP12Bags(b.Bags, in)
+	}
+}
+
+func describeAlgorithm(a *pkcs12util.Algorithm) string {
+	s := a.String()
+	if a.Iterations > 0 {
+		s += fmt.Sprintf(", %d iterations", a.Iterations)
+	}
+	if a.Legacy {
+		s += " [legacy]"
+	}
+	return s
+}
+
+func describePrivateKey(key crypto.PrivateKey) string {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		return "EC " + k.Curve.Params().Name
+	case ed25519.PrivateKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", k)
+	}
+}
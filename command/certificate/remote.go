@@ -7,17 +7,43 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/internal/starttls"
 )
 
 var urlPrefixes = map[string]uint16{
-	"tcp://":   443,
-	"tls://":   443,
-	"https://": 443,
-	"smtps://": 465,
-	"ldaps://": 636,
+	"tcp://":      443,
+	"tls://":      443,
+	"https://":    443,
+	"smtps://":    465,
+	"ldaps://":    636,
+	"smtp://":     25,
+	"imap://":     143,
+	"pop3://":     110,
+	"ldap://":     389,
+	"postgres://": 5432,
+	"mysql://":    3306,
+	"xmpp://":     5222,
+}
+
+// startTLSProtocol returns the STARTTLS protocol used to connect to the given
+// URL, or an empty string if the URL prefix uses implicit TLS.
+//
+// Examples:
+// startTLSProtocol("smtp://mail.smallstep.com") -> "smtp"
+// startTLSProtocol("smtps://mail.smallstep.com") -> ""
+func startTLSProtocol(ref string) string {
+	tmp := strings.ToLower(ref)
+	for _, protocol := range starttls.Protocols() {
+		if strings.HasPrefix(tmp, protocol+"://") {
+			return protocol
+		}
+	}
+	return ""
 }
 
 // getPeerCertificates creates a connection to a remote server and returns the
@@ -28,11 +54,23 @@ var urlPrefixes = map[string]uint16{
 // Params
 //
 //	*addr*:       can be a host (e.g. smallstep.com) or an IP (e.g. 127.0.0.1)
+//	*protocol*:   the STARTTLS protocol used to upgrade the connection, if any
 //	*serverName*: use a specific Server Name Indication (e.g. smallstep.com)
 //	*roots*:      a file, a directory, or a comma-separated list of files.
 //	*insecure*:   do not verify that the server's certificate has been signed by
 //	              a trusted root
-func getPeerCertificates(addr, serverName, roots string, insecure bool) ([]*x509.Certificate, error) {
+func getPeerCertificates(addr, protocol, serverName, roots string, insecure bool) ([]*x509.Certificate, error) {
+	state, err := getConnectionState(addr, protocol, serverName, roots, insecure)
+	if err != nil {
+		return nil, err
+	}
+	return state.PeerCertificates, nil
+}
+
+// getConnectionState creates a connection to a remote server and returns the
+// state of the TLS connection. The parameters are the same as in
+// getPeerCertificates.
+func getConnectionState(addr, protocol, serverName, roots string, insecure bool) (*tls.ConnectionState, error) {
 	var (
 		err     error
 		rootCAs *x509.CertPool
@@ -56,12 +94,47 @@ func getPeerCertificates(addr, serverName, roots string, insecure bool) ([]*x509
 	if serverName != "" {
 		tlsConfig.ServerName = serverName
 	}
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	conn, err := dialTLS(&net.Dialer{}, addr, protocol, tlsConfig)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to connect")
 	}
 	conn.Close()
-	return conn.ConnectionState().PeerCertificates, nil
+	state := conn.ConnectionState()
+	return &state, nil
+}
+
+// dialTLS connects to the given address and performs the TLS handshake. If a
+// STARTTLS protocol is given, the connection is upgraded before the handshake.
+// The timeout of the dialer, if any, also applies to the handshake.
+func dialTLS(dialer *net.Dialer, addr, protocol string, tlsConfig *tls.Config) (*tls.Conn, error) {
+	if protocol == "" {
+		return tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+	if err := starttls.Upgrade(conn, protocol, tlsConfig.ServerName); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
 }
 
 // trimURL returns the host[:port] if the input is a URL, otherwise returns an
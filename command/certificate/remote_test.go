@@ -21,6 +21,8 @@ func TestTrimURL(t *testing.T) {
 		"false":          {"./certs/root_ca.crt", "", false, nil},
 		"false-err":      {"https://google.com hello", "", false, errors.New("error parsing URL 'https://google.com hello'")},
 		"true-http-case": {"hTtPs://sMaLlStEp.cOm", "sMaLlStEp.cOm:443", true, nil},
+		"true-smtp":      {"smtp://mail.smallstep.com", "mail.smallstep.com:25", true, nil},
+		"true-postgres":  {"postgres://db.smallstep.com:6432/app", "db.smallstep.com:6432", true, nil},
 	}
 
 	for name, tc := range tests {
@@ -39,6 +41,27 @@ func TestTrimURL(t *testing.T) {
 	}
 }
 
+func TestStartTLSProtocol(t *testing.T) {
+	tests := map[string]string{
+		"https://smallstep.com":       "",
+		"smtps://mail.smallstep.com":  "",
+		"smtp://mail.smallstep.com":   "smtp",
+		"IMAP://mail.smallstep.com":   "imap",
+		"pop3://mail.smallstep.com":   "pop3",
+		"ldap://ldap.smallstep.com":   "ldap",
+		"ldaps://ldap.smallstep.com":  "",
+		"postgres://db.smallstep.com": "postgres",
+		"mysql://db.smallstep.com":    "mysql",
+		"xmpp://chat.smallstep.com":   "xmpp",
+		"./certs/smtp.crt":            "",
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			assert.Equals(t, want, startTLSProtocol(input))
+		})
+	}
+}
+
 func TestGetPeerCertificateServerName(t *testing.T) {
 	host := "smallstep.com"
 	serverName := host
@@ -71,7 +94,7 @@ func TestGetPeerCertificateServerName(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			_, err := getPeerCertificates(tc.addr, tc.serverName, "", false)
+			_, err := getPeerCertificates(tc.addr, "", tc.serverName, "", false)
 			if err != nil {
 				if assert.NotNil(t, tc.err) {
 					assert.HasPrefix(t, err.Error(), tc.err.Error())
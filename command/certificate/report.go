@@ -0,0 +1,695 @@
+package certificate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v3"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/pemutil"
+	"go.step.sm/crypto/x509util"
+)
+
+// Exit codes of the report command.
+const (
+	reportExitWarning  = 1
+	reportExitCritical = 2
+	reportExitError    = 3
+)
+
+// reportExtensions are the extensions of the files read from a directory.
+var reportExtensions = map[string]bool{
+	".crt":  true,
+	".cer":  true,
+	".cert": true,
+	".pem":  true,
+	".der":  true,
+	".yaml": true,
+	".yml":  true,
+}
+
+func reportCommand() cli.Command {
+	return cli.Command{
+		Name:   "report",
+		Action: cli.ActionFunc(reportAction),
+		Usage:  `report the expiration of certificates from many sources`,
+		UsageText: `**step certificate report** [<source>...] [**--hosts**=<file>]
+[**--warn**=<percent|duration>] [**--critical**=<percent|duration>]
+[**--format**=<format>] [**--bundle**] [**--roots**=<root-bundle>]
+[**--concurrency**=<number>] [**--timeout**=<duration>]`,
+		Description: `**step certificate report** inspects the certificates in many sources
+concurrently, and reports the subject, SANs, issuer, expiration, percent of
+the lifetime used, key type, and chain status of each one.
+
+A source can be a certificate file, a directory, a glob pattern, a
+Kubernetes secret in YAML format, or the URL of a remote server. Directories
+are read recursively, and only files with the extensions .crt, .cer, .cert,
+.pem, .der, .yaml, and .yml are inspected. In YAML files, the command reads
+the keys ending in .crt or .pem of the Secret objects, like 'tls.crt' and
+'ca.crt'. The **--hosts** flag reads a list of servers from a file, one per
+line, as a host, host:port, or URL. Lines starting with '#' are ignored.
+
+The status of each certificate is 'ok', 'warning' if it has passed the
+**--warn** threshold, 'critical' if it has passed the **--critical**
+threshold, or 'expired'. The chain status is 'valid' if the certificate can
+be verified with the other certificates in the same source as intermediates.
+
+## POSITIONAL ARGUMENTS
+
+<source>
+:  A certificate file, directory, glob pattern, Kubernetes secret YAML file,
+or URL.
+
+## EXIT CODES
+
+This command returns '0' if all the certificates are ok, '1' if any
+certificate has passed the warning threshold, '2' if any certificate has
+passed the critical threshold or has expired, '3' if any source cannot be
+read and no certificate has passed a threshold, and '255' for any other
+error.
+
+## EXAMPLES
+
+Report the certificates in a directory:
+'''
+$ step certificate report /etc/ssl/private
+'''
+
+Report the certificates of the servers in a file, and in a few local files:
+'''
+$ step certificate report --hosts hosts.txt 'certs/*.crt'
+'''
+
+Report the certificates in Kubernetes secrets in CSV format:
+'''
+$ kubectl get secrets -A --field-selector type=kubernetes.io/tls -o yaml > secrets.yaml
+$ step certificate report secrets.yaml --format csv
+'''
+
+Warn about certificates expiring in 30 days, and fail if they expire in 7:
+'''
+$ step certificate report --hosts hosts.txt --warn 720h --critical 168h
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "hosts",
+				Usage: `The <file> with the list of servers to inspect, one per line.`,
+			},
+			cli.StringFlag{
+				Name:  "warn",
+				Value: "66%",
+				Usage: `The <percent|duration> threshold of the warning status. If using
+<percent>, the certificate has passed the threshold when it has used that
+percent of its lifetime. If using <duration>, the certificate has passed the
+threshold when it expires within that time.`,
+			},
+			cli.StringFlag{
+				Name:  "critical",
+				Value: "90%",
+				Usage: `The <percent|duration> threshold of the critical status.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "table",
+				Usage: `The output <format> of the report.
+
+: <format> is a string and must be one of:
+
+    **table**
+    :  Print a table suitable for a human to read.
+
+    **json**
+    :  Print output in JSON format.
+
+    **csv**
+    :  Print output in CSV format.`,
+			},
+			cli.BoolFlag{
+				Name:  "bundle",
+				Usage: `Report all the certificates in each source, not only the first one.`,
+			},
+			cli.StringFlag{
+				Name: "roots",
+				Usage: `Root certificate(s) used to verify the chains. Defaults to the system's
+trust store.
+
+: <roots> is a case-sensitive string and may be one of:
+
+    **file**
+	:  Relative or full path to a file. All certificates in the file will be used for path validation.
+
+    **list of files**
+	:  Comma-separated list of relative or full file paths. Every PEM encoded certificate from each file will be used for path validation.
+
+    **directory**
+	:  Relative or full path to a directory. Every PEM encoded certificate from each file in the directory will be used for path validation.`,
+			},
+			cli.IntFlag{
+				Name:  "concurrency",
+				Value: 10,
+				Usage: `The maximum <number> of sources inspected at the same time.`,
+			},
+			cli.DurationFlag{
+				Name:  "timeout",
+				Value: 10 * time.Second,
+				Usage: `The <duration> to wait for each connection to a remote server.`,
+			},
+		},
+	}
+}
+
+// threshold is a limit on the remaining lifetime of a certificate, as the
+// percent of the lifetime used or as the time until expiration.
+type threshold struct {
+	percent   int
+	duration  time.Duration
+	isPercent bool
+}
+
+func parseThreshold(ctx *cli.Context, name string) (threshold, error) {
+	value := ctx.String(name)
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil {
+			return threshold{}, errs.InvalidFlagValue(ctx, name, value, "")
+		}
+		if percent > 100 || percent < 0 {
+			return threshold{}, errs.InvalidFlagValueMsg(ctx, name, value, "value must be in range 0-100%")
+		}
+		return threshold{percent: percent, isPercent: true}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return threshold{}, errs.InvalidFlagValue(ctx, name, value, "")
+	}
+	return threshold{duration: d}, nil
+}
+
+// exceeded returns true if the certificate has passed the threshold.
+func (t threshold) exceeded(crt *x509.Certificate, percentUsed float64, now time.Time) bool {
+	if t.isPercent {
+		return percentUsed >= float64(t.percent)
+	}
+	return crt.NotAfter.Sub(now) <= t.duration
+}
+
+// reportSource is a location with certificates.
+type reportSource struct {
+	Name     string
+	Filename string
+	Address  string
+	Protocol string
+}
+
+// reportEntry is a line of the report.
+type reportEntry struct {
+	Source      string    `json:"source"`
+	Subject     string    `json:"subject,omitempty"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	NotBefore   time.Time `json:"notBefore,omitempty"`
+	NotAfter    time.Time `json:"notAfter,omitempty"`
+	DaysLeft    int       `json:"daysLeft"`
+	PercentUsed float64   `json:"percentUsed"`
+	KeyType     string    `json:"keyType,omitempty"`
+	Chain       string    `json:"chain,omitempty"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type reportOptions struct {
+	warn, critical threshold
+	roots          *x509.CertPool
+	bundle         bool
+	timeout        time.Duration
+	now            time.Time
+}
+
+func reportAction(ctx *cli.Context) error {
+	var (
+		format      = ctx.String("format")
+		concurrency = ctx.Int("concurrency")
+		hosts       = ctx.String("hosts")
+	)
+	if ctx.NArg() == 0 && hosts == "" {
+		return errs.NewExitError(errs.TooFewArguments(ctx), 255)
+	}
+	if format != "table" && format != "json" && format != "csv" {
+		return errs.NewExitError(errs.InvalidFlagValue(ctx, "format", format, "table, json, csv"), 255)
+	}
+	if concurrency < 1 {
+		return errs.NewExitError(errs.InvalidFlagValueMsg(ctx, "concurrency", strconv.Itoa(concurrency), "value must be greater than 0"), 255)
+	}
+
+	opts := reportOptions{
+		bundle:  ctx.Bool("bundle"),
+		timeout: ctx.Duration("timeout"),
+		now:     time.Now(),
+	}
+	var err error
+	if opts.warn, err = parseThreshold(ctx, "warn"); err != nil {
+		return errs.NewExitError(err, 255)
+	}
+	if opts.critical, err = parseThreshold(ctx, "critical"); err != nil {
+		return errs.NewExitError(err, 255)
+	}
+	if roots := ctx.String("roots"); roots != "" {
+		if opts.roots, err = x509util.ReadCertPool(roots); err != nil {
+			return errs.NewExitError(errors.Wrapf(err, "failure to load root certificate pool from input path '%s'", roots), 255)
+		}
+	}
+
+	sources, err := reportSources(ctx.Args(), hosts)
+	if err != nil {
+		return errs.NewExitError(err, 255)
+	}
+
+	// Inspect the sources concurrently, keeping the order of the input.
+	results := make([][]reportEntry, len(sources))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, src reportSource) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = inspectSource(src, &opts)
+		}(i, src)
+	}
+	wg.Wait()
+
+	var entries []reportEntry
+	for _, r := range results {
+		entries = append(entries, r...)
+	}
+	if err := writeReport(os.Stdout, entries, format); err != nil {
+		return errs.NewExitError(err, 255)
+	}
+	return reportExitCode(entries)
+}
+
+// reportSources returns the list of sources from the arguments and the hosts
+// file.
+func reportSources(args []string, hostsFile string) ([]reportSource, error) {
+	var sources []reportSource
+	for _, arg := range args {
+		addr, isURL, err := trimURL(arg)
+		switch {
+		case err != nil:
+			return nil, err
+		case isURL:
+			sources = append(sources, reportSource{Name: arg, Address: addr, Protocol: startTLSProtocol(arg)})
+			continue
+		}
+
+		fi, err := os.Stat(arg)
+		switch {
+		case err == nil && fi.IsDir():
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.Type().IsRegular() && reportExtensions[strings.ToLower(filepath.Ext(path))] {
+					sources = append(sources, reportSource{Name: path, Filename: path})
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading directory %s", arg)
+			}
+		case err == nil:
+			sources = append(sources, reportSource{Name: arg, Filename: arg})
+		default:
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing pattern %s", arg)
+			}
+			if len(matches) == 0 {
+				return nil, errors.Errorf("no files match %s", arg)
+			}
+			for _, m := range matches {
+				sources = append(sources, reportSource{Name: m, Filename: m})
+			}
+		}
+	}
+
+	if hostsFile != "" {
+		hosts, err := readHostsFile(hostsFile)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, hosts...)
+	}
+	return sources, nil
+}
+
+// readHostsFile reads a file with a host, host:port, or URL per line.
+func readHostsFile(filename string) ([]reportSource, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	defer f.Close()
+
+	var sources []reportSource
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, isURL, err := trimURL(line)
+		if err != nil {
+			return nil, err
+		}
+		src := reportSource{Name: line, Address: addr}
+		if isURL {
+			src.Protocol = startTLSProtocol(line)
+		} else if _, _, err := net.SplitHostPort(line); err != nil {
+			src.Address = net.JoinHostPort(line, "443")
+		} else {
+			src.Address = line
+		}
+		sources = append(sources, src)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", filename)
+	}
+	return sources, nil
+}
+
+// inspectSource reads the certificates in a source and returns their entries.
+func inspectSource(src reportSource, opts *reportOptions) []reportEntry {
+	groups, err := readSource(src, opts.timeout)
+	if err != nil {
+		return []reportEntry{{Source: src.Name, Status: "error", Error: err.Error()}}
+	}
+
+	var entries []reportEntry
+	for _, g := range groups {
+		for i, crt := range g.certs {
+			entries = append(entries, newReportEntry(g.name, crt, g.certs[i+1:], opts))
+			if !opts.bundle {
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// certificateGroup is a list of certificates from the same location, like a
+// bundle or a key of a Kubernetes secret.
+type certificateGroup struct {
+	name  string
+	certs []*x509.Certificate
+}
+
+func readSource(src reportSource, timeout time.Duration) ([]certificateGroup, error) {
+	if src.Address != "" {
+		conn, err := dialTLS(&net.Dialer{Timeout: timeout}, src.Address, src.Protocol, &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true, //nolint:gosec // the chain is verified by the report
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to connect")
+		}
+		conn.Close()
+		return []certificateGroup{{name: src.Name, certs: conn.ConnectionState().PeerCertificates}}, nil
+	}
+
+	b, err := os.ReadFile(src.Filename)
+	if err != nil {
+		return nil, errs.FileError(err, src.Filename)
+	}
+	if ext := strings.ToLower(filepath.Ext(src.Filename)); ext == ".yaml" || ext == ".yml" {
+		return parseSecrets(src.Name, b)
+	}
+	certs, err := pemutil.ParseCertificateBundle(b)
+	if err != nil {
+		return nil, err
+	}
+	return []certificateGroup{{name: src.Name, certs: certs}}, nil
+}
+
+// kubernetesObject contains the fields of a Kubernetes Secret or List.
+type kubernetesObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Data       map[string]string   `yaml:"data"`
+	StringData map[string]string   `yaml:"stringData"`
+	Items      []*kubernetesObject `yaml:"items"`
+}
+
+// parseSecrets returns the certificates in the Kubernetes secrets of a YAML
+// file. The file can contain multiple documents and lists of objects.
+func parseSecrets(name string, b []byte) ([]certificateGroup, error) {
+	var groups []certificateGroup
+	var add func(obj *kubernetesObject) error
+	add = func(obj *kubernetesObject) error {
+		switch obj.Kind {
+		case "List", "SecretList":
+			for _, item := range obj.Items {
+				if err := add(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "Secret":
+		default:
+			return nil
+		}
+
+		secret := obj.Metadata.Name
+		if obj.Metadata.Namespace != "" {
+			secret = obj.Metadata.Namespace + "/" + secret
+		}
+		read := func(key string, data []byte) error {
+			if !strings.HasSuffix(key, ".crt") && !strings.HasSuffix(key, ".pem") {
+				return nil
+			}
+			certs, err := pemutil.ParseCertificateBundle(data)
+			if err != nil {
+				return errors.Wrapf(err, "error parsing key %s of secret %s", key, secret)
+			}
+			groups = append(groups, certificateGroup{
+				name:  name + "#" + secret + "/" + key,
+				certs: certs,
+			})
+			return nil
+		}
+		for _, key := range sortedKeys(obj.Data) {
+			data, err := base64.StdEncoding.DecodeString(obj.Data[key])
+			if err != nil {
+				return errors.Wrapf(err, "error decoding key %s of secret %s", key, secret)
+			}
+			if err := read(key, data); err != nil {
+				return err
+			}
+		}
+		for _, key := range sortedKeys(obj.StringData) {
+			if err := read(key, []byte(obj.StringData[key])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var obj kubernetesObject
+		if err := dec.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrapf(err, "error parsing %s", name)
+		}
+		if err := add(&obj); err != nil {
+			return nil, err
+		}
+	}
+	if len(groups) == 0 {
+		return nil, errors.Errorf("%s does not contain secrets with certificates", name)
+	}
+	return groups, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newReportEntry(source string, crt *x509.Certificate, intermediates []*x509.Certificate, opts *reportOptions) reportEntry {
+	lifetime := crt.NotAfter.Sub(crt.NotBefore)
+	percentUsed := 100.0
+	if lifetime > 0 {
+		percentUsed = float64(opts.now.Sub(crt.NotBefore)) / float64(lifetime) * 100
+	}
+	percentUsed = max(0, min(100, percentUsed))
+
+	e := reportEntry{
+		Source:      source,
+		Subject:     crt.Subject.String(),
+		SANs:        subjectAlternativeNames(crt),
+		Issuer:      crt.Issuer.String(),
+		NotBefore:   crt.NotBefore,
+		NotAfter:    crt.NotAfter,
+		DaysLeft:    int(crt.NotAfter.Sub(opts.now).Hours() / 24),
+		PercentUsed: float64(int(percentUsed*10)) / 10,
+		KeyType:     keyType(crt),
+		Chain:       "valid",
+		Status:      "ok",
+	}
+
+	pool := x509.NewCertPool()
+	for _, c := range intermediates {
+		pool.AddCert(c)
+	}
+	if _, err := crt.Verify(x509.VerifyOptions{
+		Roots:         opts.roots,
+		Intermediates: pool,
+		CurrentTime:   opts.now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		e.Chain = err.Error()
+	}
+
+	switch {
+	case opts.now.After(crt.NotAfter):
+		e.Status = "expired"
+	case opts.critical.exceeded(crt, percentUsed, opts.now):
+		e.Status = "critical"
+	case opts.warn.exceeded(crt, percentUsed, opts.now):
+		e.Status = "warning"
+	}
+	return e
+}
+
+func subjectAlternativeNames(crt *x509.Certificate) []string {
+	sans := append([]string{}, crt.DNSNames...)
+	for _, ip := range crt.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, crt.EmailAddresses...)
+	for _, u := range crt.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+func keyType(crt *x509.Certificate) string {
+	switch pub := crt.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + pub.Curve.Params().Name
+	case *rsa.PublicKey:
+		return "RSA " + strconv.Itoa(pub.N.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return crt.PublicKeyAlgorithm.String()
+	}
+}
+
+func writeReport(w io.Writer, entries []reportEntry, format string) error {
+	switch format {
+	case "json":
+		if entries == nil {
+			entries = []reportEntry{}
+		}
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling report")
+		}
+		fmt.Fprintln(w, string(b))
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"source", "subject", "sans", "issuer", "not_before", "not_after", "days_left", "percent_used", "key_type", "chain", "status", "error"})
+		for _, e := range entries {
+			var notBefore, notAfter string
+			if e.Error == "" {
+				notBefore, notAfter = e.NotBefore.Format(time.RFC3339), e.NotAfter.Format(time.RFC3339)
+			}
+			cw.Write([]string{
+				e.Source, e.Subject, strings.Join(e.SANs, " "), e.Issuer, notBefore, notAfter,
+				strconv.Itoa(e.DaysLeft), strconv.FormatFloat(e.PercentUsed, 'f', 1, 64),
+				e.KeyType, e.Chain, e.Status, e.Error,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		tw := new(tabwriter.Writer)
+		// Format in tab-separated columns with a tab stop of 8.
+		tw.Init(w, 0, 8, 1, '\t', 0)
+		fmt.Fprintln(tw, "SOURCE\tSUBJECT\tSANS\tISSUER\tNOT AFTER\tUSED\tKEY\tCHAIN\tSTATUS")
+		for _, e := range entries {
+			if e.Error != "" {
+				fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t-\t-\t%s: %s\n", e.Source, e.Status, e.Error)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.1f%%\t%s\t%s\t%s\n",
+				e.Source, e.Subject, strings.Join(e.SANs, ","), e.Issuer,
+				e.NotAfter.Format(time.RFC3339), e.PercentUsed, e.KeyType, e.Chain, e.Status)
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+// reportExitCode returns the error with the exit code of the most severe
+// status in the report.
+func reportExitCode(entries []reportEntry) error {
+	var warning, critical, failed int
+	for _, e := range entries {
+		switch e.Status {
+		case "warning":
+			warning++
+		case "critical", "expired":
+			critical++
+		case "error":
+			failed++
+		}
+	}
+	switch {
+	case critical > 0:
+		return errs.NewExitError(errors.Errorf("%d certificate(s) passed the critical threshold or expired", critical), reportExitCritical)
+	case warning > 0:
+		return errs.NewExitError(errors.Errorf("%d certificate(s) passed the warning threshold", warning), reportExitWarning)
+	case failed > 0:
+		return errs.NewExitError(errors.Errorf("%d source(s) could not be read", failed), reportExitError)
+	default:
+		return nil
+	}
+}
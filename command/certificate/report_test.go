@@ -0,0 +1,179 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallstep/assert"
+)
+
+func newReportCertificate(t *testing.T, cn string, notBefore, notAfter time.Time) (*x509.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.FatalError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		DNSNames:              []string{cn},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.FatalError(t, err)
+	crt, err := x509.ParseCertificate(der)
+	assert.FatalError(t, err)
+	return crt, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestNewReportEntry(t *testing.T) {
+	now := time.Now()
+	roots := x509.NewCertPool()
+	opts := &reportOptions{
+		warn:     threshold{percent: 66, isPercent: true},
+		critical: threshold{duration: 24 * time.Hour},
+		now:      now,
+		roots:    roots,
+	}
+
+	ok, _ := newReportCertificate(t, "ok.example.com", now.Add(-time.Hour), now.Add(99*time.Hour))
+	warning, _ := newReportCertificate(t, "warning.example.com", now.Add(-70*time.Hour), now.Add(30*time.Hour))
+	critical, _ := newReportCertificate(t, "critical.example.com", now.Add(-time.Hour), now.Add(time.Hour))
+	expired, _ := newReportCertificate(t, "expired.example.com", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	roots.AddCert(ok)
+
+	e := newReportEntry("ok.crt", ok, nil, opts)
+	assert.Equals(t, "ok", e.Status)
+	assert.Equals(t, "valid", e.Chain)
+	assert.Equals(t, []string{"ok.example.com"}, e.SANs)
+	assert.Equals(t, "ECDSA P-256", e.KeyType)
+	assert.Equals(t, 1.0, e.PercentUsed)
+	assert.Equals(t, 4, e.DaysLeft)
+
+	e = newReportEntry("warning.crt", warning, nil, opts)
+	assert.Equals(t, "warning", e.Status)
+	assert.Equals(t, 70.0, e.PercentUsed)
+	assert.NotEquals(t, "valid", e.Chain)
+
+	assert.Equals(t, "critical", newReportEntry("critical.crt", critical, nil, opts).Status)
+	e = newReportEntry("expired.crt", expired, nil, opts)
+	assert.Equals(t, "expired", e.Status)
+	assert.Equals(t, 100.0, e.PercentUsed)
+}
+
+func TestReportExitCode(t *testing.T) {
+	tests := map[string]struct {
+		statuses []string
+		code     int
+	}{
+		"ok":       {[]string{"ok", "ok"}, 0},
+		"warning":  {[]string{"ok", "warning", "error"}, reportExitWarning},
+		"critical": {[]string{"warning", "critical"}, reportExitCritical},
+		"expired":  {[]string{"expired", "error"}, reportExitCritical},
+		"error":    {[]string{"ok", "error"}, reportExitError},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var entries []reportEntry
+			for _, s := range tc.statuses {
+				entries = append(entries, reportEntry{Status: s})
+			}
+			err := reportExitCode(entries)
+			if tc.code == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Equals(t, tc.code, err.(interface{ ExitCode() int }).ExitCode())
+			}
+		})
+	}
+}
+
+func TestParseSecrets(t *testing.T) {
+	now := time.Now()
+	_, leaf := newReportCertificate(t, "leaf.example.com", now, now.Add(time.Hour))
+	_, ca := newReportCertificate(t, "ca.example.com", now, now.Add(time.Hour))
+
+	data := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Secret
+  type: kubernetes.io/tls
+  metadata:
+    name: web
+    namespace: default
+  data:
+    tls.crt: ` + base64.StdEncoding.EncodeToString(leaf) + `
+    tls.key: c2VjcmV0
+    ca.crt: ` + base64.StdEncoding.EncodeToString(ca) + `
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: config
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: other
+stringData:
+  cert.pem: |
+    ` + strings.ReplaceAll(strings.TrimSpace(string(leaf)), "\n", "\n    ") + "\n"
+
+	groups, err := parseSecrets("secrets.yaml", []byte(data))
+	assert.FatalError(t, err)
+	if assert.Len(t, 3, groups) {
+		assert.Equals(t, "secrets.yaml#default/web/ca.crt", groups[0].name)
+		assert.Equals(t, "ca.example.com", groups[0].certs[0].Subject.CommonName)
+		assert.Equals(t, "secrets.yaml#default/web/tls.crt", groups[1].name)
+		assert.Equals(t, "leaf.example.com", groups[1].certs[0].Subject.CommonName)
+		assert.Equals(t, "secrets.yaml#other/cert.pem", groups[2].name)
+	}
+
+	_, err = parseSecrets("empty.yaml", []byte("kind: ConfigMap\n"))
+	assert.Error(t, err)
+	_, err = parseSecrets("bad.yaml", []byte("kind: Secret\ndata:\n  tls.crt: '%%%'\n"))
+	assert.Error(t, err)
+}
+
+func TestReportSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.crt", "b.pem", "c.key", "sub/d.yaml"} {
+		path := filepath.Join(dir, name)
+		assert.FatalError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.FatalError(t, os.WriteFile(path, []byte("data"), 0o600))
+	}
+	hosts := filepath.Join(dir, "hosts.txt")
+	assert.FatalError(t, os.WriteFile(hosts, []byte("# servers\nsmallstep.com\n\nsmallstep.com:8443\nsmtp://mail.smallstep.com\n"), 0o600))
+
+	sources, err := reportSources([]string{dir, filepath.Join(dir, "*.pem"), "https://smallstep.com"}, hosts)
+	assert.FatalError(t, err)
+	assert.Equals(t, []reportSource{
+		{Name: filepath.Join(dir, "a.crt"), Filename: filepath.Join(dir, "a.crt")},
+		{Name: filepath.Join(dir, "b.pem"), Filename: filepath.Join(dir, "b.pem")},
+		{Name: filepath.Join(dir, "sub/d.yaml"), Filename: filepath.Join(dir, "sub/d.yaml")},
+		{Name: filepath.Join(dir, "b.pem"), Filename: filepath.Join(dir, "b.pem")},
+		{Name: "https://smallstep.com", Address: "smallstep.com:443"},
+		{Name: "smallstep.com", Address: "smallstep.com:443"},
+		{Name: "smallstep.com:8443", Address: "smallstep.com:8443"},
+		{Name: "smtp://mail.smallstep.com", Address: "mail.smallstep.com:25", Protocol: "smtp"},
+	}, sources)
+
+	_, err = reportSources([]string{filepath.Join(dir, "*.der")}, "")
+	assert.Error(t, err)
+	_, err = reportSources(nil, filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,321 @@
+package certificate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/starttls"
+	"github.com/smallstep/cli/internal/tlsscan"
+)
+
+func scanCommand() cli.Command {
+	return cli.Command{
+		Name:   "scan",
+		Action: cli.ActionFunc(scanAction),
+		Usage:  `Report the TLS configuration of a remote server`,
+		UsageText: `**step certificate scan** <host:port or url>
+[**--servername**=<servername>] [**--roots**=<root-bundle>]
+[**--starttls**=<protocol>] [**--alpn**=<protocol>] [**--timeout**=<duration>]
+[**--format**=<format>]`,
+		Description: `**step certificate scan** connects to a remote server multiple times and
+reports its TLS configuration: the protocol versions and cipher suites it
+accepts, the ALPN protocols it negotiates, the stapled OCSP response, the
+signed certificate timestamps (SCTs) sent in the handshake, embedded in the
+certificate, or included in the OCSP response, the differences between
+the certificates sent with and without the server name indication (SNI), the
+problems in the order of the certificate chain, and the expiration of every
+certificate in the chain.
+
+The connection is upgraded with STARTTLS if the URL scheme is one of 'smtp',
+'imap', 'pop3', 'ldap', 'postgres', 'mysql' or 'xmpp', or if the
+**--starttls** flag is used.
+
+The command returns '0' if the scan completes, even if the chain cannot be
+verified. It returns '1' if the server cannot be reached.
+
+## POSITIONAL ARGUMENTS
+
+<host:port or url>
+:  The address of the server. If the port is not specified, it defaults to
+'443', the port of the URL scheme, or the port of the **--starttls**
+protocol.
+
+## EXAMPLES
+
+Scan a web server:
+'''
+$ step certificate scan smallstep.com
+'''
+
+Scan a server using a custom root certificate and print the report in JSON:
+'''
+$ step certificate scan https://ca.internal:9000 --roots ./root-ca.crt --format json
+'''
+
+Scan a mail server after upgrading the connection with STARTTLS:
+'''
+$ step certificate scan mail.example.com:587 --starttls smtp
+'''
+
+Scan a PostgreSQL server on the default port:
+'''
+$ step certificate scan postgres://db.example.com
+'''
+
+Scan an LDAP server on the default port after upgrading the connection with STARTTLS:
+'''
+$ step certificate scan ldap.example.com --starttls ldap
+'''
+
+Probe only the HTTP/2 ALPN protocol:
+'''
+$ step certificate scan smallstep.com --alpn h2
+'''`,
+		Flags: []cli.Flag{
+			flags.ServerName,
+			cli.StringFlag{
+				Name: "roots",
+				Usage: `Root certificate(s) that will be used to verify the
+authenticity of the remote server. Defaults to the system's trust store.
+
+: <roots> is a case-sensitive string and may be one of:
+
+    **file**
+	:  Relative or full path to a file. All certificates in the file will be used for path validation.
+
+    **list of files**
+	:  Comma-separated list of relative or full file paths. Every PEM encoded certificate from each file will be used for path validation.
+
+    **directory**
+	:  Relative or full path to a directory. Every PEM encoded certificate from each file in the directory will be used for path validation.`,
+			},
+			cli.StringFlag{
+				Name: "starttls",
+				Usage: `The <protocol> used to upgrade the connection to TLS.
+
+: <protocol> is a string and must be one of:
+
+    **smtp**
+    :  Send the SMTP STARTTLS command.
+
+    **imap**
+    :  Send the IMAP STARTTLS command.
+
+    **pop3**
+    :  Send the POP3 STLS command.
+
+    **ldap**
+    :  Send the LDAP StartTLS extended operation.
+
+    **postgres**
+    :  Send the PostgreSQL SSLRequest message.
+
+    **mysql**
+    :  Send the MySQL SSLRequest packet.
+
+    **xmpp**
+    :  Send the XMPP STARTTLS command.`,
+			},
+			cli.StringSliceFlag{
+				Name: "alpn",
+				Usage: `The ALPN <protocol> to probe. Use the flag multiple times to probe
+multiple protocols. Defaults to "h2" and "http/1.1".`,
+			},
+			cli.DurationFlag{
+				Name:  "timeout",
+				Value: 10 * time.Second,
+				Usage: `The <duration> to wait for each connection to the server.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: `The output <format> of the report.
+
+: <format> is a string and must be one of:
+
+    **text**
+    :  Print the report in a human readable format.
+
+    **json**
+    :  Print the report in JSON format.`,
+			},
+		},
+	}
+}
+
+func scanAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	var (
+		address  = ctx.Args().First()
+		protocol = ctx.String("starttls")
+		roots    = ctx.String("roots")
+		format   = ctx.String("format")
+	)
+
+	switch {
+	case format != "text" && format != "json":
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	case protocol != "" && !starttls.IsSupported(protocol):
+		return errs.InvalidFlagValue(ctx, "starttls", protocol, strings.Join(starttls.Protocols(), ", "))
+	}
+
+	addr, isURL, err := trimURL(address)
+	switch {
+	case err != nil:
+		return err
+	case isURL:
+		if p := startTLSProtocol(address); p != "" {
+			if protocol != "" && protocol != p {
+				return errors.Errorf("flag '--starttls %s' is incompatible with the URL scheme '%s'", protocol, p)
+			}
+			protocol = p
+		}
+		address = addr
+	default:
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			port := uint16(443)
+			if p, ok := urlPrefixes[protocol+"://"]; ok {
+				port = p
+			}
+			address = net.JoinHostPort(address, strconv.FormatUint(uint64(port), 10))
+		}
+	}
+
+	opts := tlsscan.Options{
+		Address:    address,
+		ServerName: ctx.String("servername"),
+		StartTLS:   protocol,
+		ALPN:       ctx.StringSlice("alpn"),
+		Timeout:    ctx.Duration("timeout"),
+	}
+	if roots != "" {
+		if opts.Roots, err = x509util.ReadCertPool(roots); err != nil {
+			return errors.Wrapf(err, "failure to load root certificate pool from input path '%s'", roots)
+		}
+	}
+
+	report, err := tlsscan.Scan(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling report")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	printScanReport(report)
+	return nil
+}
+
+func printScanReport(r *tlsscan.Report) {
+	fmt.Printf("Address: %s\n", r.Address)
+	if r.ServerName != "" {
+		fmt.Printf("Server name: %s\n", r.ServerName)
+	}
+	if r.StartTLS != "" {
+		fmt.Printf("STARTTLS: %s\n", r.StartTLS)
+	}
+
+	fmt.Println("Protocol versions:")
+	for _, v := range r.Versions {
+		if !v.Supported {
+			fmt.Printf("    %s: not supported\n", v.Name)
+			continue
+		}
+		fmt.Printf("    %s: supported\n", v.Name)
+		for _, cs := range v.CipherSuites {
+			fmt.Printf("        %s\n", cs)
+		}
+	}
+
+	if len(r.ALPN) == 0 {
+		fmt.Println("ALPN: none")
+	} else {
+		fmt.Printf("ALPN: %s\n", strings.Join(r.ALPN, ", "))
+	}
+
+	fmt.Println("Certificate chain:")
+	for i, c := range r.Certificates {
+		fmt.Printf("    %d: %s\n", i, c.Subject)
+		fmt.Printf("        Issuer: %s\n", c.Issuer)
+		fmt.Printf("        SHA256 Fingerprint: %s\n", c.Fingerprint)
+		fmt.Printf("        Not After: %s (%s)\n", c.NotAfter.Format(time.RFC3339), expiration(c))
+	}
+	if r.Verified {
+		fmt.Println("Verification: ok")
+	} else {
+		fmt.Printf("Verification: failed: %s\n", r.VerifyError)
+	}
+	if len(r.ChainIssues) > 0 {
+		fmt.Println("Chain issues:")
+		for _, issue := range r.ChainIssues {
+			fmt.Printf("    %s\n", issue)
+		}
+	}
+
+	if sni := r.SNI; sni != nil {
+		switch {
+		case sni.Required:
+			fmt.Printf("SNI: required, connection without SNI failed: %s\n", sni.Error)
+		case sni.Different:
+			fmt.Printf("SNI: required, a different certificate is sent without SNI: %s\n", sni.Certificate.Subject)
+		default:
+			fmt.Println("SNI: not required, the same certificate is sent without SNI")
+		}
+	}
+
+	if o := r.OCSP; o != nil {
+		switch {
+		case !o.Stapled:
+			fmt.Println("OCSP stapling: no")
+		case o.Error != "":
+			fmt.Printf("OCSP stapling: yes, invalid response: %s\n", o.Error)
+		default:
+			fmt.Printf("OCSP stapling: yes, status %s", o.Status)
+			if o.NextUpdate != nil {
+				fmt.Printf(", next update %s", o.NextUpdate.Format(time.RFC3339))
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(r.SCTs) == 0 {
+		fmt.Println("SCTs: none")
+	} else {
+		fmt.Println("SCTs:")
+		for _, sct := range r.SCTs {
+			fmt.Printf("    %s: log %s at %s\n", sct.Source, sct.LogID, sct.Timestamp.Format(time.RFC3339))
+		}
+	}
+}
+
+func expiration(c tlsscan.Certificate) string {
+	switch {
+	case c.Expired:
+		return "expired"
+	case c.DaysLeft == 1:
+		return "expires in 1 day"
+	default:
+		return fmt.Sprintf("expires in %d days", c.DaysLeft)
+	}
+}
@@ -0,0 +1,296 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/ctutil"
+)
+
+func sctCommand() cli.Command {
+	return cli.Command{
+		Name:   "sct",
+		Action: cli.ActionFunc(sctAction),
+		Usage:  `print and verify the signed certificate timestamps of a certificate`,
+		UsageText: `**step certificate sct** <crt-file or url>
+[**--issuer**=<file>] [**--log-list**=<file>] [**--format**=<format>]
+[**--roots**=<root-bundle>] [**--servername**=<servername>] [**--insecure**]`,
+		Description: `**step certificate sct** prints the signed certificate timestamps (SCTs)
+of a certificate. SCTs are the promises of certificate transparency (CT) logs
+to include a certificate, defined in RFC 6962.
+
+For a local certificate, the command prints the SCTs embedded in the
+certificate. For a remote server, it also prints the SCTs sent in the TLS
+extension during the handshake.
+
+With **--log-list**, the command finds the log that issued each SCT in the
+given log list and verifies the SCT signature with the log key. The list uses
+the JSON format of the log lists published by Google and Apple, for example,
+https://www.gstatic.com/ct/log_list/v3/log_list.json. Verifying an embedded
+SCT requires the issuer of the certificate, taken from the bundle or from
+**--issuer**.
+
+## POSITIONAL ARGUMENTS
+
+<crt-file or url>
+:  The path to a certificate or certificate bundle, or the URL of a remote
+server.
+
+## EXIT CODES
+
+This command returns 0 on success, and \>0 if any error occurs or any SCT
+fails verification.
+
+## EXAMPLES
+
+Print the SCTs embedded in a certificate:
+'''
+$ step certificate sct leaf.crt
+'''
+
+Print and verify the SCTs of a remote server:
+'''
+$ step certificate sct https://smallstep.com --log-list log_list.json
+'''
+
+Verify the SCTs embedded in a certificate using the issuer in a separate file:
+'''
+$ step certificate sct leaf.crt --issuer intermediate.crt --log-list log_list.json
+'''
+
+Print the SCTs of a certificate in JSON format:
+'''
+$ step certificate sct leaf.crt --format json
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "issuer",
+				Usage: `The issuer certificate <file>, required to verify the embedded SCTs if
+the certificate is not in a bundle with its issuer.`,
+			},
+			cli.StringFlag{
+				Name:  "log-list",
+				Usage: `The JSON <file> with the list of CT logs used to verify the SCTs.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: `The output <format> of the SCTs.
+
+: <format> is a string and must be one of:
+
+    **text**
+    :  Print output in unstructured text suitable for a human to read.
+
+    **json**
+    :  Print output in JSON format.`,
+			},
+			cli.StringFlag{
+				Name: "roots",
+				Usage: `Root certificate(s) that will be used to verify the
+authenticity of the remote server.
+
+: <roots> is a case-sensitive string and may be one of:
+
+    **file**
+	:  Relative or full path to a file. All certificates in the file will be used for path validation.
+
+    **list of files**
+	:  Comma-separated list of relative or full file paths. Every PEM encoded certificate from each file will be used for path validation.
+
+    **directory**
+	:  Relative or full path to a directory. Every PEM encoded certificate from each file in the directory will be used for path validation.`,
+			},
+			flags.ServerName,
+			cli.BoolFlag{
+				Name: "insecure",
+				Usage: `Use an insecure client to retrieve a remote peer certificate. Useful for
+debugging invalid certificates remotely.`,
+			},
+		},
+	}
+}
+
+// sctInfo is the representation of an SCT printed by the sct and ct-submit
+// commands.
+type sctInfo struct {
+	Source             string    `json:"source,omitempty"`
+	Version            int       `json:"version"`
+	LogID              string    `json:"logID"`
+	Log                string    `json:"log,omitempty"`
+	Timestamp          time.Time `json:"timestamp"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	Signature          string    `json:"signature"`
+	Verified           *bool     `json:"verified,omitempty"`
+	Error              string    `json:"error,omitempty"`
+}
+
+// newSCTInfo returns the representation of an SCT. If a log list is given,
+// the SCT is verified with the key of its log.
+func newSCTInfo(source string, sct *ctutil.SCT, logs *ctutil.LogList, cert, issuer *x509.Certificate) *sctInfo {
+	info := &sctInfo{
+		Source:             source,
+		Version:            int(sct.Version) + 1,
+		LogID:              sct.LogIDString(),
+		Timestamp:          sct.Timestamp,
+		SignatureAlgorithm: sct.SignatureAlgorithmString(),
+		Signature:          hex.EncodeToString(sct.Signature),
+	}
+	if logs == nil {
+		return info
+	}
+
+	verified := false
+	info.Verified = &verified
+	log := logs.Find(sct.LogID)
+	if log == nil {
+		info.Error = "the log is not in the log list"
+		return info
+	}
+	info.Log = log.Description
+	key, err := log.PublicKey()
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	if err := sct.Verify(key, cert, issuer); err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	verified = true
+	return info
+}
+
+func printSCTs(infos []*sctInfo, format string) error {
+	if format == "json" {
+		if infos == nil {
+			infos = []*sctInfo{}
+		}
+		b, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling SCTs")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("SCT [%d]:\n", i)
+		if info.Source != "" {
+			fmt.Printf("    Source: %s\n", info.Source)
+		}
+		fmt.Printf("    Version: v%d\n", info.Version)
+		fmt.Printf("    Log ID: %s\n", info.LogID)
+		if info.Log != "" {
+			fmt.Printf("    Log: %s\n", info.Log)
+		}
+		fmt.Printf("    Timestamp: %s\n", info.Timestamp.Format(time.RFC3339Nano))
+		fmt.Printf("    Signature Algorithm: %s\n", info.SignatureAlgorithm)
+		fmt.Printf("    Signature: %s\n", info.Signature)
+		if info.Verified != nil {
+			if *info.Verified {
+				fmt.Println("    Verified: yes")
+			} else {
+				fmt.Printf("    Verified: no, %s\n", info.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func sctAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	var (
+		crtFile = ctx.Args().First()
+		format  = ctx.String("format")
+		logs    *ctutil.LogList
+		certs   []*x509.Certificate
+		tlsSCTs [][]byte
+	)
+	if format != "text" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	}
+	if filename := ctx.String("log-list"); filename != "" {
+		var err error
+		if logs, err = ctutil.ReadLogList(filename); err != nil {
+			return err
+		}
+	}
+
+	switch addr, isURL, err := trimURL(crtFile); {
+	case err != nil:
+		return err
+	case isURL:
+		state, err := getConnectionState(addr, startTLSProtocol(crtFile), ctx.String("servername"), ctx.String("roots"), ctx.Bool("insecure"))
+		if err != nil {
+			return err
+		}
+		certs = state.PeerCertificates
+		tlsSCTs = state.SignedCertificateTimestamps
+	default:
+		if certs, err = pemutil.ReadCertificateBundle(crtFile); err != nil {
+			return err
+		}
+	}
+
+	cert := certs[0]
+	var issuer *x509.Certificate
+	if filename := ctx.String("issuer"); filename != "" {
+		var err error
+		if issuer, err = pemutil.ReadCertificate(filename); err != nil {
+			return err
+		}
+	} else if len(certs) > 1 {
+		issuer = certs[1]
+	}
+
+	embedded, err := ctutil.EmbeddedSCTs(cert)
+	if err != nil {
+		return err
+	}
+	if len(embedded) > 0 && issuer == nil && logs != nil {
+		return errors.New("the issuer certificate is required to verify the embedded SCTs, use the '--issuer' flag")
+	}
+
+	var infos []*sctInfo
+	for _, sct := range embedded {
+		infos = append(infos, newSCTInfo("embedded", sct, logs, cert, issuer))
+	}
+	for _, b := range tlsSCTs {
+		sct, err := ctutil.ParseSCT(b)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, newSCTInfo("tls", sct, logs, cert, nil))
+	}
+
+	if len(infos) == 0 && format == "text" {
+		fmt.Println("The certificate does not have SCTs.")
+		return nil
+	}
+	if err := printSCTs(infos, format); err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if info.Verified != nil && !*info.Verified {
+			return errors.New("failed to verify SCTs")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/truststore"
+	"go.step.sm/crypto/pemutil"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/internal/truststoreutil"
+)
+
+func truststoreCommand() cli.Command {
+	return cli.Command{
+		Name:      "truststore",
+		Usage:     "list and synchronize the root certificates in the trust stores",
+		UsageText: "**step certificate truststore** <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `**step certificate truststore** command group provides facilities to list
+the root certificates installed by step in the supported trust stores, and to
+converge those trust stores to a set of roots.
+
+Besides the system's default trust store, the Java key store, and the Firefox
+NSS security databases, the commands support NSS databases in custom locations
+and directories of PEM files, like the ones used to add roots to container
+images.
+
+A root certificate is managed by step if its name in the trust store is the
+name used by **step certificate install**: the prefix, by default the common
+name of the certificate, followed by its serial number. Use **--prefix** with
+the roots installed with a custom prefix.
+
+## EXAMPLES
+
+List the roots installed by step in the system's default trust store:
+'''
+$ step certificate truststore list
+'''
+
+Converge all the supported trust stores to the roots in a bundle:
+'''
+$ step certificate truststore sync --roots roots.pem --all
+'''`,
+		Subcommands: cli.Commands{
+			truststoreListCommand(),
+			truststoreSyncCommand(),
+		},
+	}
+}
+
+func truststoreListCommand() cli.Command {
+	return cli.Command{
+		Name:   "list",
+		Action: command.ActionFunc(truststoreListAction),
+		Usage:  "list the root certificates installed by step in the trust stores",
+		UsageText: `**step certificate truststore list**
+[**--format**=<format>] [**--prefix**=<name>] [**--all**] [**--java**] [**--firefox**]
+[**--nss-db**=<path>] [**--dir**=<path>] [**--no-system**]`,
+		Description: `**step certificate truststore list** lists the root certificates
+installed by step in the selected trust stores, with their SHA-256
+fingerprints.
+
+Listing the system's default trust store is only supported on Linux and
+FreeBSD, where the roots added locally are listed.
+
+## EXAMPLES
+
+List the roots installed by step in the system's default trust store:
+'''
+$ step certificate truststore list
+'''
+
+List the roots installed by step in all the supported trust stores:
+'''
+$ step certificate truststore list --all
+'''
+
+List the roots in an NSS database and in a directory, in JSON format:
+'''
+$ step certificate truststore list --no-system \
+  --nss-db $HOME/.pki/nssdb --dir /usr/local/share/ca-certificates \
+  --format json
+'''`,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "format",
+				Value: "table",
+				Usage: `The output <format> of the list.
+
+: <format> is a string and must be one of:
+
+    **table**
+    :  Print a table suitable for a human to read.
+
+    **json**
+    :  Print output in JSON format.`,
+			},
+			truststorePrefixFlag,
+		}, truststoreFlags("list")...),
+	}
+}
+
+func truststoreSyncCommand() cli.Command {
+	return cli.Command{
+		Name:   "sync",
+		Action: command.ActionFunc(truststoreSyncAction),
+		Usage:  "converge the trust stores to a set of root certificates",
+		UsageText: `**step certificate truststore sync** **--roots**=<file>
+[**--prefix**=<name>] [**--dry-run**] [**--all**] [**--java**] [**--firefox**]
+[**--nss-db**=<path>] [**--dir**=<path>] [**--no-system**]`,
+		Description: `**step certificate truststore sync** converges the selected trust
+stores to exactly the root certificates in a bundle: the roots missing in a
+trust store are installed, and the roots installed by step that are not in the
+bundle anymore are removed. A root is only removed if its name is exactly the
+prefix followed by its serial number, the name used by **step certificate
+install**; roots not installed by step are never removed.
+
+On platforms where the system's default trust store cannot be listed, the
+roots are installed but retired roots are not removed from it; use **step
+certificate uninstall** to remove them.
+
+## EXAMPLES
+
+Converge the system's default trust store to the roots in a bundle:
+'''
+$ step certificate truststore sync --roots roots.pem
+'''
+
+Show the changes without modifying the trust stores:
+'''
+$ step certificate truststore sync --roots roots.pem --all --dry-run
+'''
+
+Converge the directory of a container image to the roots in a bundle:
+'''
+$ step certificate truststore sync --roots roots.pem \
+  --no-system --dir rootfs/usr/local/share/ca-certificates
+'''`,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "roots",
+				Usage: `The <file> with the PEM bundle of root certificates the trust stores converge to.`,
+			},
+			truststorePrefixFlag,
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: `Print the changes without modifying the trust stores.`,
+			},
+		}, truststoreFlags("sync")...),
+	}
+}
+
+var truststorePrefixFlag = cli.StringFlag{
+	Name: "prefix",
+	Usage: `The prefix used to <name> the CA in the trust store. Defaults to the
+certificate common name.`,
+}
+
+// truststorePrefix returns the function that returns the prefix of the name
+// of a root certificate in the trust stores.
+func truststorePrefix(ctx *cli.Context) func(*x509.Certificate) string {
+	if p := ctx.String("prefix"); p != "" {
+		return func(*x509.Certificate) string { return p }
+	}
+	return truststoreutil.DefaultPrefix
+}
+
+// truststoreFlags returns the flags that select the trust stores.
+func truststoreFlags(verb string) []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "java",
+			Usage: verb + " the Java key store",
+		},
+		cli.BoolFlag{
+			Name:  "firefox",
+			Usage: verb + " the Firefox NSS security databases",
+		},
+		cli.StringSliceFlag{
+			Name: "nss-db",
+			Usage: verb + ` the NSS security database in the given <path>. Use the flag multiple
+times to select multiple databases.`,
+		},
+		cli.StringSliceFlag{
+			Name: "dir",
+			Usage: verb + ` the directory of PEM files in the given <path>, like
+/usr/local/share/ca-certificates in a container image. Use the flag multiple
+times to select multiple directories.`,
+		},
+		cli.BoolFlag{
+			Name:  "no-system",
+			Usage: "disables the " + verb + " of the system's default trust store",
+		},
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: verb + " Firefox's, Java's, and the system's default trust store",
+		},
+	}
+}
+
+// truststores returns the trust stores selected by the flags.
+func truststores(ctx *cli.Context) ([]truststoreutil.Store, error) {
+	var stores []truststoreutil.Store
+	if !ctx.Bool("no-system") {
+		stores = append(stores, &truststoreutil.SystemStore{})
+	}
+	if ctx.Bool("all") || ctx.Bool("java") {
+		s, err := truststoreutil.NewJavaStore()
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, s)
+	}
+
+	nssPaths := ctx.StringSlice("nss-db")
+	if ctx.Bool("all") || ctx.Bool("firefox") {
+		paths := truststoreutil.DefaultNSSPaths()
+		if len(paths) == 0 && ctx.Bool("firefox") {
+			return nil, errors.New("error finding the Firefox NSS security databases")
+		}
+		nssPaths = append(paths, nssPaths...)
+	}
+	for _, p := range nssPaths {
+		s, err := truststoreutil.NewNSSStore(p)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, s)
+	}
+
+	for _, p := range ctx.StringSlice("dir") {
+		stores = append(stores, &truststoreutil.DirStore{Path: p})
+	}
+
+	if len(stores) == 0 {
+		return nil, errors.New("no trust store selected")
+	}
+	return stores, nil
+}
+
+type truststoreEntry struct {
+	Store       string `json:"store"`
+	Name        string `json:"name,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func truststoreListAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+	format := ctx.String("format")
+	if format != "table" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "table, json")
+	}
+
+	stores, err := truststores(ctx)
+	if err != nil {
+		return err
+	}
+
+	prefix := truststorePrefix(ctx)
+	list := []truststoreEntry{}
+	for _, s := range stores {
+		entries, err := s.List()
+		switch {
+		case errors.Is(err, truststoreutil.ErrNotSupported):
+			list = append(list, truststoreEntry{Store: s.Name(), Error: err.Error()})
+			continue
+		case err != nil:
+			return truststoreError(err, "failed to list "+s.Name())
+		}
+		truststoreutil.SortEntries(entries)
+		for _, e := range entries {
+			if !e.IsManaged(prefix(e.Certificate)) {
+				continue
+			}
+			list = append(list, truststoreEntry{
+				Store:       s.Name(),
+				Name:        e.Name,
+				Fingerprint: e.Fingerprint(),
+				Subject:     e.Certificate.Subject.String(),
+			})
+		}
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling trust stores")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	tw := new(tabwriter.Writer)
+	// Format in tab-separated columns with a tab stop of 8.
+	tw.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, "STORE\tNAME\tFINGERPRINT\tSUBJECT")
+	for _, e := range list {
+		if e.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\t%s\n", e.Store, e.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Store, e.Name, e.Fingerprint, e.Subject)
+	}
+	return tw.Flush()
+}
+
+func truststoreSyncAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+	filename := ctx.String("roots")
+	if filename == "" {
+		return errs.RequiredFlag(ctx, "roots")
+	}
+	roots, err := pemutil.ReadCertificateBundle(filename)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if !root.IsCA || root.CheckSignatureFrom(root) != nil {
+			return errors.Errorf("certificate %q in %s is not a root CA", root.Subject, filename)
+		}
+	}
+
+	stores, err := truststores(ctx)
+	if err != nil {
+		return err
+	}
+
+	prefix := truststorePrefix(ctx)
+	dryRun := ctx.Bool("dry-run")
+	action := func(verb string) string {
+		if dryRun {
+			return "Would " + strings.ToLower(verb)
+		}
+		return verb
+	}
+
+	var changes int
+	for _, s := range stores {
+		// If the trust store cannot be listed, all the roots are added and
+		// none is removed.
+		entries, err := s.List()
+		switch {
+		case errors.Is(err, truststoreutil.ErrNotSupported):
+			fmt.Printf("Trust store %s cannot be listed, retired roots will not be removed.\n", s.Name())
+		case err != nil:
+			return truststoreError(err, "failed to list "+s.Name())
+		}
+
+		add, remove := truststoreutil.Plan(entries, roots, prefix)
+		for _, root := range add {
+			name := truststoreutil.EntryName(prefix(root), root)
+			fmt.Printf("%s %s to %s (%s)\n", action("Add"), name, s.Name(), x509util.Fingerprint(root))
+			if !dryRun {
+				if err := s.Add(prefix(root), root); err != nil {
+					return truststoreError(err, "failed to add "+name+" to "+s.Name())
+				}
+			}
+		}
+		for _, e := range remove {
+			fmt.Printf("%s %s from %s (%s)\n", action("Remove"), e.Name, s.Name(), e.Fingerprint())
+			if !dryRun {
+				if err := s.Remove(e); err != nil {
+					return truststoreError(err, "failed to remove "+e.Name+" from "+s.Name())
+				}
+			}
+		}
+		changes += len(add) + len(remove)
+	}
+
+	switch {
+	case changes == 0:
+		fmt.Println("The trust stores are up to date.")
+	case dryRun:
+		fmt.Printf("%d changes would be made.\n", changes)
+	default:
+		fmt.Printf("%d changes have been made.\n", changes)
+	}
+	return nil
+}
+
+// truststoreError returns the error of a trust store operation, with the
+// command and output if the operation ran an external command.
+func truststoreError(err error, msg string) error {
+	var truststoreErr *truststore.CmdError
+	if errors.As(err, &truststoreErr) {
+		return errors.Errorf("failed to execute \"%s\" failed with: %s",
+			strings.Join(truststoreErr.Cmd().Args, " "), truststoreErr.Err())
+	}
+	return errors.Wrap(err, msg)
+}
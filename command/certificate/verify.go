@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -28,7 +30,8 @@ func verifyCommand() cli.Command {
 		Usage:  `verify a certificate`,
 		UsageText: `**step certificate verify** <crt-file> [**--host**=<host>]
 [**--roots**=<root-bundle>] [**--servername**=<servername>]
-[**--issuing-ca**=<ca-cert-file>] [**--verbose**]
+[**--purpose**=<purpose>] [**--at**=<time|duration>] [**--max-path-len**=<number>]
+[**--format**=<format>] [**--issuing-ca**=<ca-cert-file>] [**--verbose**]
 [**--verify-ocsp**]] [**--ocsp-endpoint**]=url
 [**--verify-crl**] [**--crl-endpoint**]=url`,
 		Description: `**step certificate verify** executes the certificate path
@@ -36,6 +39,15 @@ validation algorithm for x.509 certificates defined in RFC 5280. If the
 certificate is valid this command will return '0'. If validation fails, or if
 an error occurs, this command will produce a non-zero return value.
 
+By default, the certificate is valid for any purpose. Use **--purpose** to
+check the extended key usage of the certificate and its issuers, for example,
+to check that a certificate can be used as a TLS client. Use **--at** to verify
+the certificate at a time in the past or the future.
+
+With **--format json**, the result lists every chain found from the
+certificate to a root, whether each one is valid, the reason the invalid ones
+failed, and the name constraints in the chain.
+
 ## POSITIONAL ARGUMENTS
 
 <crt-file>
@@ -59,6 +71,12 @@ Verify a remote certificate using your operating system's default root certifica
 $ step certificate verify https://smallstep.com
 '''
 
+Verify the certificate of a PostgreSQL server, only available after STARTTLS:
+
+'''
+$ step certificate verify postgres://db.example.com --roots ./root-ca.crt
+'''
+
 Verify a certificate using a custom root certificate for path validation:
 
 '''
@@ -78,6 +96,44 @@ Verify a certificate using a custom directory of root certificates for path vali
 $ step certificate verify ./certificate.crt --roots ./root-certificates/
 '''
 
+Verify that a certificate can be used for TLS client authentication:
+
+'''
+$ step certificate verify ./client.crt --roots ./root-certificate.crt --purpose client
+'''
+
+Verify a certificate as it will be in 30 days:
+
+'''
+$ step certificate verify ./certificate.crt --at 720h
+'''
+
+Verify a certificate at a time in the past, with at most one intermediate:
+
+'''
+$ step certificate verify ./certificate.crt --at 2024-01-01T00:00:00Z --max-path-len 1
+'''
+
+Print the chains found, and why the ones not valid failed:
+
+'''
+$ step certificate verify ./server.crt --roots ./root-certificate.crt --purpose client --format json
+{
+  "valid": false,
+  "purpose": "client",
+  "time": "2024-05-01T10:00:00Z",
+  "chains": [
+    {
+      "valid": false,
+      "pathLength": 1,
+      "certificates": [...],
+      "error": "x509: certificate specifies an incompatible key usage"
+    }
+  ],
+  "error": "x509: certificate specifies an incompatible key usage"
+}
+'''
+
 Verify a certificate including OCSP and CRL using CRL and OCSP defined in the certificate
 
 '''
@@ -115,6 +171,58 @@ authenticity of the remote server.
 
     **directory**
 	:  Relative or full path to a directory. Every PEM encoded certificate from each file in the directory will be used for path validation.`,
+			},
+			cli.StringFlag{
+				Name:  "purpose",
+				Value: "any",
+				Usage: `The <purpose> the certificate must be valid for.
+
+: <purpose> is a case-sensitive string and must be one of:
+
+    **any**
+    :  Any purpose (default).
+
+    **server**
+    :  TLS server authentication.
+
+    **client**
+    :  TLS client authentication.
+
+    **code-signing**
+    :  Code signing.
+
+    **email**
+    :  Email protection (S/MIME).
+
+    **ocsp**
+    :  OCSP response signing.
+
+    **timestamp**
+    :  Time stamping.`,
+			},
+			cli.StringFlag{
+				Name: "at",
+				Usage: `The <time|duration> to verify the certificate at. The <time|duration> is an
+RFC 3339 time or a duration relative to the current time, like "720h" or "-24h".
+Defaults to the current time.`,
+			},
+			cli.IntFlag{
+				Name:  "max-path-len",
+				Value: -1,
+				Usage: `The maximum <number> of intermediate certificates in a valid chain.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: `The output <format> of the result.
+
+: <format> is a string and must be one of:
+
+    **text**
+    :  Print only the errors, or the result with **--verbose**.
+
+    **json**
+    :  Print the chains found and the result in JSON format.`,
 			},
 			cli.StringFlag{
 				Name:  "issuing-ca",
@@ -163,6 +271,9 @@ func verifyAction(ctx *cli.Context) error {
 		verbose          = ctx.Bool("verbose")
 		issuerFile       = ctx.String("issuing-ca")
 		insecure         = ctx.Bool("insecure")
+		purpose          = ctx.String("purpose")
+		maxPathLen       = ctx.Int("max-path-len")
+		format           = ctx.String("format")
 		intermediatePool = x509.NewCertPool()
 		rootPool         *x509.CertPool
 		cert             *x509.Certificate
@@ -170,11 +281,31 @@ func verifyAction(ctx *cli.Context) error {
 		httpClient       *http.Client
 	)
 
+	keyUsage, ok := verifyPurposes[purpose]
+	if !ok {
+		return errs.InvalidFlagValue(ctx, "purpose", purpose, "any, server, client, code-signing, email, ocsp, timestamp")
+	}
+	at, ok := flags.ParseTimeOrDuration(ctx.String("at"))
+	if !ok {
+		return errs.InvalidFlagValue(ctx, "at", ctx.String("at"), "")
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	switch {
+	case format != "text" && format != "json":
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	case format == "json" && verifyCRL:
+		return errs.IncompatibleFlagWithFlag(ctx, "format json", "verify-crl")
+	case format == "json" && verifyOCSP:
+		return errs.IncompatibleFlagWithFlag(ctx, "format json", "verify-ocsp")
+	}
+
 	switch addr, isURL, err := trimURL(crtFile); {
 	case err != nil:
 		return err
 	case isURL:
-		peerCertificates, err := getPeerCertificates(addr, serverName, roots, false)
+		peerCertificates, err := getPeerCertificates(addr, startTLSProtocol(crtFile), serverName, roots, false)
 		if err != nil {
 			return err
 		}
@@ -224,7 +355,7 @@ func verifyAction(ctx *cli.Context) error {
 		var err error
 		rootPool, err = x509util.ReadCertPool(roots)
 		if err != nil {
-			errors.Wrapf(err, "failure to load root certificate pool from input path '%s'", roots)
+			return errors.Wrapf(err, "failure to load root certificate pool from input path '%s'", roots)
 		}
 	}
 
@@ -232,21 +363,41 @@ func verifyAction(ctx *cli.Context) error {
 		DNSName:       host,
 		Roots:         rootPool,
 		Intermediates: intermediatePool,
-		// Support verification of any type of cert.
-		//
-		// TODO: add something like --purpose client,server,... and configure
-		// this property accordingly.
-		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{keyUsage},
 	}
 
-	if _, err := cert.Verify(opts); err != nil {
-		return errors.Wrapf(err, "failed to verify certificate")
+	result := verifyChains(cert, opts, maxPathLen)
+	result.Purpose = purpose
+	result.Host = host
+	if format == "json" {
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling result")
+		}
+		fmt.Println(string(b))
+		if !result.Valid {
+			return errors.New("failed to verify certificate")
+		}
+		return nil
+	}
+	if !result.Valid {
+		return errors.Wrapf(result.err, "failed to verify certificate")
 	}
 
 	verboseMSG := "certificate validated against roots\n"
+	if purpose != "any" {
+		verboseMSG += fmt.Sprintf("certificate valid for %s purpose\n", purpose)
+	}
 	if host != "" {
 		verboseMSG += "certificate host name validated\n"
 	}
+	for _, c := range result.Chains {
+		if c.Valid && len(c.NameConstraints) > 0 {
+			verboseMSG += "certificate names validated against name constraints\n"
+			break
+		}
+	}
 
 	switch {
 	case (verifyCRL || verifyOCSP) && roots != "":
@@ -455,3 +606,160 @@ func VerifyCRLEndpoint(endpoint string, cert, issuer *x509.Certificate, httpClie
 
 	return true, nil
 }
+
+var verifyPurposes = map[string]x509.ExtKeyUsage{
+	"any":          x509.ExtKeyUsageAny,
+	"server":       x509.ExtKeyUsageServerAuth,
+	"client":       x509.ExtKeyUsageClientAuth,
+	"code-signing": x509.ExtKeyUsageCodeSigning,
+	"email":        x509.ExtKeyUsageEmailProtection,
+	"ocsp":         x509.ExtKeyUsageOCSPSigning,
+	"timestamp":    x509.ExtKeyUsageTimeStamping,
+}
+
+type verifyResult struct {
+	Valid   bool          `json:"valid"`
+	Purpose string        `json:"purpose"`
+	Time    time.Time     `json:"time"`
+	Host    string        `json:"host,omitempty"`
+	Chains  []verifyChain `json:"chains"`
+	Error   string        `json:"error,omitempty"`
+	err     error
+}
+
+type verifyChain struct {
+	Valid           bool                `json:"valid"`
+	PathLength      int                 `json:"pathLength"`
+	Certificates    []verifyCertificate `json:"certificates"`
+	NameConstraints []nameConstraints   `json:"nameConstraints,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+type verifyCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	Fingerprint  string    `json:"fingerprint"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+}
+
+type nameConstraints struct {
+	Subject                 string   `json:"subject"`
+	Critical                bool     `json:"critical"`
+	PermittedDNSDomains     []string `json:"permittedDNSDomains,omitempty"`
+	ExcludedDNSDomains      []string `json:"excludedDNSDomains,omitempty"`
+	PermittedIPRanges       []string `json:"permittedIPRanges,omitempty"`
+	ExcludedIPRanges        []string `json:"excludedIPRanges,omitempty"`
+	PermittedEmailAddresses []string `json:"permittedEmailAddresses,omitempty"`
+	ExcludedEmailAddresses  []string `json:"excludedEmailAddresses,omitempty"`
+	PermittedURIDomains     []string `json:"permittedURIDomains,omitempty"`
+	ExcludedURIDomains      []string `json:"excludedURIDomains,omitempty"`
+}
+
+// verifyChains verifies the certificate and returns all the chains from the
+// certificate to a root. To find the chains that are not valid for the given
+// options, the chains are first built ignoring the key usage, the host name
+// and, if necessary, the time; and then each chain is verified individually.
+func verifyChains(cert *x509.Certificate, opts x509.VerifyOptions, maxPathLen int) *verifyResult {
+	result := &verifyResult{
+		Time: opts.CurrentTime,
+	}
+
+	relaxed := opts
+	relaxed.DNSName = ""
+	relaxed.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	candidates, err := cert.Verify(relaxed)
+	if err != nil {
+		relaxed.CurrentTime = cert.NotBefore.Add(cert.NotAfter.Sub(cert.NotBefore) / 2)
+		candidates, _ = cert.Verify(relaxed)
+	}
+	if len(candidates) == 0 {
+		_, err = cert.Verify(opts)
+		if err == nil {
+			err = errors.New("no chains found")
+		}
+		result.err = err
+		result.Error = err.Error()
+		return result
+	}
+
+	for _, chain := range candidates {
+		vc := verifyChain{
+			Valid:      true,
+			PathLength: len(chain) - 2,
+		}
+		if vc.PathLength < 0 {
+			vc.PathLength = 0
+		}
+		for _, crt := range chain {
+			vc.Certificates = append(vc.Certificates, verifyCertificate{
+				Subject:      crt.Subject.String(),
+				Issuer:       crt.Issuer.String(),
+				SerialNumber: crt.SerialNumber.String(),
+				Fingerprint:  x509util.Fingerprint(crt),
+				NotBefore:    crt.NotBefore,
+				NotAfter:     crt.NotAfter,
+			})
+			if nc := getNameConstraints(crt); nc != nil {
+				vc.NameConstraints = append(vc.NameConstraints, *nc)
+			}
+		}
+
+		// Verify the chain using only its certificates.
+		strict := opts
+		strict.Roots = x509.NewCertPool()
+		strict.Roots.AddCert(chain[len(chain)-1])
+		strict.Intermediates = x509.NewCertPool()
+		for _, crt := range chain[1 : len(chain)-1] {
+			strict.Intermediates.AddCert(crt)
+		}
+		if len(chain) == 1 {
+			strict.Roots = opts.Roots
+		}
+		_, err := cert.Verify(strict)
+		if err == nil && maxPathLen >= 0 && vc.PathLength > maxPathLen {
+			err = errors.Errorf("chain has %d intermediate certificates, the maximum is %d", vc.PathLength, maxPathLen)
+		}
+		if err != nil {
+			vc.Valid = false
+			vc.Error = err.Error()
+			if result.err == nil {
+				result.err = err
+			}
+		}
+		result.Valid = result.Valid || vc.Valid
+		result.Chains = append(result.Chains, vc)
+	}
+
+	if result.Valid {
+		result.err = nil
+	} else {
+		result.Error = result.err.Error()
+	}
+	return result
+}
+
+func getNameConstraints(crt *x509.Certificate) *nameConstraints {
+	nc := &nameConstraints{
+		Subject:                 crt.Subject.String(),
+		Critical:                crt.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:     crt.PermittedDNSDomains,
+		ExcludedDNSDomains:      crt.ExcludedDNSDomains,
+		PermittedEmailAddresses: crt.PermittedEmailAddresses,
+		ExcludedEmailAddresses:  crt.ExcludedEmailAddresses,
+		PermittedURIDomains:     crt.PermittedURIDomains,
+		ExcludedURIDomains:      crt.ExcludedURIDomains,
+	}
+	for _, ip := range crt.PermittedIPRanges {
+		nc.PermittedIPRanges = append(nc.PermittedIPRanges, ip.String())
+	}
+	for _, ip := range crt.ExcludedIPRanges {
+		nc.ExcludedIPRanges = append(nc.ExcludedIPRanges, ip.String())
+	}
+	if len(nc.PermittedDNSDomains)+len(nc.ExcludedDNSDomains)+len(nc.PermittedIPRanges)+len(nc.ExcludedIPRanges)+
+		len(nc.PermittedEmailAddresses)+len(nc.ExcludedEmailAddresses)+len(nc.PermittedURIDomains)+len(nc.ExcludedURIDomains) == 0 {
+		return nil
+	}
+	return nc
+}
@@ -0,0 +1,111 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smallstep/assert"
+)
+
+func newVerifyTestCert(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.FatalError(t, err)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	assert.FatalError(t, err)
+	crt, err := x509.ParseCertificate(der)
+	assert.FatalError(t, err)
+	return crt, key
+}
+
+func Test_verifyChains(t *testing.T) {
+	now := time.Now()
+	root, rootKey := newVerifyTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Root"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	intermediate, intKey := newVerifyTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Intermediate"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		PermittedDNSDomains:   []string{"example.com"},
+	}, root, rootKey)
+	leaf, _ := newVerifyTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, intermediate, intKey)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+	opts := func(usage x509.ExtKeyUsage, at time.Time) x509.VerifyOptions {
+		return x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   at,
+			KeyUsages:     []x509.ExtKeyUsage{usage},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		opts       x509.VerifyOptions
+		maxPathLen int
+		valid      bool
+		chains     int
+	}{
+		{"ok", opts(x509.ExtKeyUsageAny, now), -1, true, 1},
+		{"ok server", opts(x509.ExtKeyUsageServerAuth, now), -1, true, 1},
+		{"ok max-path-len", opts(x509.ExtKeyUsageServerAuth, now), 1, true, 1},
+		{"fail client", opts(x509.ExtKeyUsageClientAuth, now), -1, false, 1},
+		{"fail expired", opts(x509.ExtKeyUsageAny, now.Add(2*time.Hour)), -1, false, 1},
+		{"fail max-path-len", opts(x509.ExtKeyUsageAny, now), 0, false, 1},
+		{"fail no roots", x509.VerifyOptions{Roots: x509.NewCertPool(), CurrentTime: now}, -1, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verifyChains(leaf, tt.opts, tt.maxPathLen)
+			assert.Equals(t, tt.valid, got.Valid)
+			assert.Len(t, tt.chains, got.Chains)
+			if tt.valid {
+				assert.Nil(t, got.err)
+				assert.Equals(t, "", got.Error)
+			} else {
+				assert.Error(t, got.err)
+				assert.Equals(t, got.err.Error(), got.Error)
+			}
+			for _, c := range got.Chains {
+				assert.Equals(t, 1, c.PathLength)
+				assert.Len(t, 3, c.Certificates)
+				assert.Equals(t, []nameConstraints{{
+					Subject:             "CN=Intermediate",
+					PermittedDNSDomains: []string{"example.com"},
+				}}, c.NameConstraints)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package cose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/internal/cose"
+	"github.com/smallstep/cli/utils"
+)
+
+// Command returns the cli.Command for cose and related subcommands.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "cose",
+		Usage:     "sign, verify and encrypt data using CBOR Object Signing and Encryption (COSE)",
+		UsageText: "step crypto cose <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `CBOR Object Signing and Encryption (COSE) defines how to create and process
+signatures, message authentication codes, and encryption using CBOR for
+serialization. COSE is the binary counterpart of JOSE and is commonly used in
+constrained environments like IoT devices.
+
+The **step crypto cose** command group supports the single signer and single
+recipient structures defined in RFC 9052:
+
+  * COSE_Sign1, created with an asymmetric key using **step crypto cose sign**.
+  * COSE_Mac0, created with a symmetric key using **step crypto cose sign**.
+  * COSE_Encrypt0, created with a symmetric key using **step crypto cose encrypt**.
+
+Keys are read the same way that **step crypto jws** reads them: a JWK, a JWK
+set, a JWK encrypted as a JWE payload, or a PEM encoded key. Signatures can also
+be created with a key in a KMS using the **--kms** flag.
+
+COSE messages are binary. By default they are written as base64url without
+padding, and they can be read as raw CBOR or as their base64, base64url or
+hexadecimal representation.
+
+## EXAMPLES
+
+Create a COSE_Sign1 message using a P-256 key:
+'''
+$ echo -n message | step crypto cose sign --key p256.priv.json
+0oRDoQEmoQRYK1E1MzZ1TEpnUEhwMzZMY0ZQeUVIZ2dUZEN6aTV6MGRMRnhoMkdGNFRMV1VHbWVzc2FnZVhAaKs...
+'''
+
+Verify the message and display the payload:
+'''
+$ echo $COSE | step crypto cose verify --key p256.pub.json
+message
+'''
+
+Verify the message and display its JSON representation:
+'''
+$ echo $COSE | step crypto cose verify --key p256.pub.json --json
+{
+  "type": "COSE_Sign1",
+  "tags": [18],
+  "protected": {
+    "alg": "ES256"
+  },
+  "unprotected": {
+    "kid": "UTUzNnVMSmdQSHAzNkxjRlB5RUhnZ1RkQ3ppNXowZExGeGgyR0Y0VExXVUc="
+  },
+  "payload": "bWVzc2FnZQ==",
+  "signature": "aKs..."
+}
+'''
+
+Create a COSE_Mac0 message using a symmetric JWK:
+'''
+$ step crypto jwk create oct.json oct.json --kty oct --size 32 --use sig --insecure --no-password
+$ echo -n message | step crypto cose sign --key oct.json --format hex
+d18443a10105a10458...
+'''
+
+Encrypt and decrypt a message using a 256-bit symmetric JWK:
+'''
+$ step crypto jwk create enc.json enc.json --kty oct --size 32 --use enc --insecure --no-password
+$ echo -n secret | step crypto cose encrypt --key enc.json > secret.cose
+$ step crypto cose decrypt --key enc.json secret.cose
+secret
+'''
+
+Inspect any COSE message without verifying it:
+'''
+$ echo $COSE | step crypto cose inspect --insecure
+'''`,
+		Subcommands: cli.Commands{
+			signCommand(),
+			verifyCommand(),
+			encryptCommand(),
+			decryptCommand(),
+			inspectCommand(),
+		},
+	}
+}
+
+var formatFlag = cli.StringFlag{
+	Name:  "format",
+	Value: cose.FormatBase64URL,
+	Usage: `The <format> used to write the COSE message.
+
+: <format> is a string and must be one of:
+
+    **base64url** (default)
+    :  Base64 URL-safe encoding without padding
+
+    **base64**
+    :  Standard base64 encoding with padding
+
+    **hex**
+    :  Hexadecimal encoding
+
+    **raw**
+    :  Raw CBOR bytes`,
+}
+
+var externalAADFlag = cli.StringFlag{
+	Name: "external-aad",
+	Usage: `The <file> containing the externally supplied data that is authenticated
+with the message but is not part of it.`,
+}
+
+var kidFlag = cli.StringFlag{
+	Name: "kid",
+	Usage: `The ID of the key used to sign or encrypt the message. The <kid> argument is a
+case-sensitive string. When used with **--jwks** (a JWK Set) the <kid> value
+must match the **"kid"** member of one of the JWKs in the JWK Set.`,
+}
+
+var jwksFlag = cli.StringFlag{
+	Name: "jwks",
+	Usage: `The JWK Set containing the key to use. The <jwks> argument should be the name
+of a file. The file contents should be a JWK Set or a JWE with a JWK Set
+payload. The **--jwks** flag requires the use of the **--kid** flag to specify
+which key to use.`,
+}
+
+var untaggedFlag = cli.BoolFlag{
+	Name:  "untagged",
+	Usage: `Do not enclose the message in its COSE CBOR tag.`,
+}
+
+// readInput reads the contents of the given file, or STDIN if the filename is
+// empty or "-".
+func readInput(filename string) ([]byte, error) {
+	switch filename {
+	case "":
+		st, err := os.Stdin.Stat()
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading data")
+		}
+		if st.Size() == 0 && st.Mode()&os.ModeNamedPipe == 0 {
+			return []byte{}, nil
+		}
+		return utils.ReadAll(os.Stdin)
+	case "-":
+		return utils.ReadAll(os.Stdin)
+	default:
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errs.FileError(err, filename)
+		}
+		return b, nil
+	}
+}
+
+// readMessage reads and parses the COSE message in the first argument or
+// STDIN.
+func readMessage(ctx *cli.Context) (*cose.Message, error) {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return nil, err
+	}
+	b, err := readInput(ctx.Args().First())
+	if err != nil {
+		return nil, err
+	}
+	if b, err = cose.Decode(b); err != nil {
+		return nil, err
+	}
+	return cose.Parse(b)
+}
+
+// readExternalAAD reads the external additional authenticated data if the
+// flag is set.
+func readExternalAAD(ctx *cli.Context) ([]byte, error) {
+	filename := ctx.String("external-aad")
+	if filename == "" {
+		return nil, nil
+	}
+	b, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readKey reads the key using the --key, --jwks and --kms flags. The given kid
+// is used to select the key from a JWK Set.
+func readKey(ctx *cli.Context, use, kid string) (*jose.JSONWebKey, error) {
+	key := ctx.String("key")
+	jwks := ctx.String("jwks")
+	switch {
+	case key == "" && jwks == "":
+		return nil, errs.RequiredOrFlag(ctx, "key", "jwks")
+	case key != "" && jwks != "":
+		return nil, errs.MutuallyExclusiveFlags(ctx, "key", "jwks")
+	case jwks != "" && kid == "":
+		return nil, errs.RequiredWithFlag(ctx, "kid", "jwks")
+	case jwks != "" && ctx.String("kms") != "":
+		return nil, errs.IncompatibleFlagWithFlag(ctx, "kms", "jwks")
+	}
+
+	options := []jose.Option{jose.WithUse(use)}
+	if kid != "" {
+		options = append(options, jose.WithKid(kid))
+	}
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		options = append(options, jose.WithPasswordFile(passwordFile))
+	}
+
+	var (
+		jwk *jose.JSONWebKey
+		err error
+	)
+	if jwks != "" {
+		jwk, err = jose.ReadKeySet(jwks, options...)
+	} else {
+		jwk, err = cose.ReadKey(ctx.String("kms"), key, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Use != use && jwk.Use != "" {
+		return nil, errors.Errorf("invalid jwk use: found '%s', expecting '%s'", jwk.Use, use)
+	}
+	return jwk, nil
+}
+
+// parseAlgorithm returns the algorithm in the --alg flag, or 0 if the flag is
+// not set.
+func parseAlgorithm(ctx *cli.Context) (cose.Algorithm, error) {
+	name := ctx.String("alg")
+	if name == "" {
+		return 0, nil
+	}
+	alg, err := cose.ParseAlgorithm(name)
+	if err != nil {
+		return 0, errs.InvalidFlagValueMsg(ctx, "alg", name, err.Error())
+	}
+	return alg, nil
+}
+
+// checkAlgorithm validates that the algorithm of the message matches the
+// expected one.
+func checkAlgorithm(m *cose.Message, expected cose.Algorithm) error {
+	if expected == 0 {
+		return nil
+	}
+	if alg, ok := m.Algorithm(); !ok || alg != expected {
+		return errors.Errorf("validation failed: alg %s does not match the alg on the message (%s)", expected, alg)
+	}
+	return nil
+}
+
+// writeMessage encodes and writes the message to STDOUT using the given
+// format.
+func writeMessage(m *cose.Message, format string) error {
+	b, err := m.MarshalCBOR()
+	if err != nil {
+		return errors.Wrap(err, "error encoding message")
+	}
+	out, err := cose.Encode(b, format)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(out)
+	if format != cose.FormatRaw {
+		fmt.Println()
+	}
+	return nil
+}
+
+type messageJSON struct {
+	Type        string                 `json:"type"`
+	Tags        []uint64               `json:"tags,omitempty"`
+	Protected   map[string]interface{} `json:"protected"`
+	Unprotected map[string]interface{} `json:"unprotected"`
+	Payload     *string                `json:"payload,omitempty"`
+	Ciphertext  *string                `json:"ciphertext,omitempty"`
+	Signature   string                 `json:"signature,omitempty"`
+	Tag         string                 `json:"tag,omitempty"`
+}
+
+// printMessage prints the JSON representation of the message. Byte strings
+// are encoded using base64.
+func printMessage(m *cose.Message) error {
+	v := messageJSON{
+		Type:        m.Type.String(),
+		Tags:        m.Tags,
+		Protected:   m.Protected.Map(),
+		Unprotected: m.Unprotected.Map(),
+	}
+	var data *string
+	if m.Payload != nil {
+		s := base64.StdEncoding.EncodeToString(m.Payload)
+		data = &s
+	}
+	switch m.Type {
+	case cose.Encrypt0:
+		v.Ciphertext = data
+	case cose.Mac0:
+		v.Payload = data
+		v.Tag = base64.StdEncoding.EncodeToString(m.Signature)
+	default:
+		v.Payload = data
+		v.Signature = base64.StdEncoding.EncodeToString(m.Signature)
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling message")
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,86 @@
+package cose
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cose"
+)
+
+func decryptCommand() cli.Command {
+	return cli.Command{
+		Name:   "decrypt",
+		Action: cli.ActionFunc(decryptAction),
+		Usage:  "decrypt a COSE_Encrypt0 message and return the payload",
+		UsageText: `**step crypto cose decrypt** [- | <filename>]
+[**--alg**=<algorithm>] [**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>]
+[**--password-file**=<file>] [**--external-aad**=<file>]`,
+		Description: `**step crypto cose decrypt** reads a COSE_Encrypt0 message from a file or
+STDIN, decrypts it using a symmetric key, and outputs the decrypted payload on
+STDOUT. If decryption fails a non-zero failure code is returned.
+
+For examples, see **step help crypto cose**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The content encryption <algorithm> expected in the message. See **step crypto
+cose encrypt** for the list of supported algorithms.`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the symmetric JWK (or a JWK encrypted as a JWE payload)
+used to decrypt the message.`,
+			},
+			jwksFlag,
+			kidFlag,
+			flags.PasswordFile,
+			externalAADFlag,
+		},
+	}
+}
+
+func decryptAction(ctx *cli.Context) error {
+	m, err := readMessage(ctx)
+	if err != nil {
+		return err
+	}
+	if m.Type != cose.Encrypt0 {
+		return errors.Errorf("cannot decrypt a %s message: use 'step crypto cose verify'", m.Type)
+	}
+
+	expected, err := parseAlgorithm(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkAlgorithm(m, expected); err != nil {
+		return err
+	}
+	external, err := readExternalAAD(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Use the kid in the message to select the key from a JWK Set
+	kid := ctx.String("kid")
+	if kid == "" && ctx.String("jwks") != "" {
+		kid = string(m.KeyID())
+	}
+	jwk, err := readKey(ctx, "enc", kid)
+	if err != nil {
+		return err
+	}
+	key, ok := jwk.Key.([]byte)
+	if !ok {
+		return errors.New("cannot use an asymmetric key for decryption: a symmetric key is required")
+	}
+
+	plaintext, err := m.Decrypt(key, external)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(plaintext)
+	return nil
+}
@@ -0,0 +1,106 @@
+package cose
+
+import (
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cose"
+)
+
+func encryptCommand() cli.Command {
+	return cli.Command{
+		Name:   "encrypt",
+		Action: cli.ActionFunc(encryptAction),
+		Usage:  "encrypt a payload creating a COSE_Encrypt0 message",
+		UsageText: `**step crypto cose encrypt** [- | <filename>]
+[**--alg**=<algorithm>] [**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>]
+[**--password-file**=<file>] [**--external-aad**=<file>] [**--untagged**]
+[**--format**=<format>]`,
+		Description: `**step crypto cose encrypt** encrypts a payload using a symmetric key and
+creates a COSE_Encrypt0 message. A random IV is generated for each message and
+added to its unprotected header. By default, the payload to encrypt is read
+from STDIN and the message will be written to STDOUT.
+
+For examples, see **step help crypto cose**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The content encryption <algorithm> to use. If not specified, a default is
+selected depending on the key size.
+
+: <algorithm> is a case-insensitive string and must be one of:
+
+    **A128GCM**
+    :  AES-GCM mode w/ 128-bit key, 128-bit tag (default for 16 byte keys)
+
+    **A192GCM**
+    :  AES-GCM mode w/ 192-bit key, 128-bit tag (default for 24 byte keys)
+
+    **A256GCM**
+    :  AES-GCM mode w/ 256-bit key, 128-bit tag (default for 32 byte keys)
+
+    **ChaCha20/Poly1305**
+    :  ChaCha20/Poly1305 w/ 256-bit key, 128-bit tag`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the symmetric JWK (or a JWK encrypted as a JWE payload)
+used to encrypt the payload.`,
+			},
+			jwksFlag,
+			kidFlag,
+			flags.PasswordFile,
+			externalAADFlag,
+			untaggedFlag,
+			formatFlag,
+		},
+	}
+}
+
+func encryptAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	plaintext, err := readInput(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	external, err := readExternalAAD(ctx)
+	if err != nil {
+		return err
+	}
+	alg, err := parseAlgorithm(ctx)
+	if err != nil {
+		return err
+	}
+	jwk, err := readKey(ctx, "enc", ctx.String("kid"))
+	if err != nil {
+		return err
+	}
+	key, ok := jwk.Key.([]byte)
+	if !ok {
+		return errors.New("cannot use an asymmetric key for encryption: a symmetric key is required")
+	}
+
+	opts := &cose.SignOptions{
+		Unprotected: cose.Header{},
+		ExternalAAD: external,
+	}
+	if jwk.KeyID != "" {
+		opts.Unprotected[cose.HeaderKeyID] = []byte(jwk.KeyID)
+	}
+	if !ctx.Bool("untagged") {
+		opts.Tags = []uint64{cose.TagEncrypt0}
+	}
+
+	m, err := cose.Encrypt(key, alg, plaintext, opts)
+	if err != nil {
+		return err
+	}
+
+	return writeMessage(m, ctx.String("format"))
+}
@@ -0,0 +1,41 @@
+package cose
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+)
+
+func inspectCommand() cli.Command {
+	return cli.Command{
+		Name:   "inspect",
+		Action: cli.ActionFunc(inspectAction),
+		Usage:  `return the decoded COSE message without verification`,
+		UsageText: `**step crypto cose inspect** [- | <filename>]
+**--insecure**`,
+		Description: `**step crypto cose inspect** reads a COSE_Sign1, COSE_Mac0 or COSE_Encrypt0
+message from a file or STDIN, decodes it, and outputs its type, tags, headers,
+payload or ciphertext, and signature or tag as a JSON object. Byte strings are
+encoded using base64. Since this command does not verify the message you must
+pass **--insecure** as a misuse prevention mechanism.
+
+For examples, see **step help crypto cose**.`,
+		Flags: []cli.Flag{
+			flags.InsecureHidden,
+		},
+	}
+}
+
+func inspectAction(ctx *cli.Context) error {
+	if !ctx.Bool("insecure") {
+		return errs.InsecureCommand(ctx)
+	}
+
+	m, err := readMessage(ctx)
+	if err != nil {
+		return err
+	}
+	return printMessage(m)
+}
@@ -0,0 +1,170 @@
+package cose
+
+import (
+	"crypto"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cose"
+)
+
+func signCommand() cli.Command {
+	return cli.Command{
+		Name:   "sign",
+		Action: cli.ActionFunc(signAction),
+		Usage:  "create a COSE_Sign1 or COSE_Mac0 message",
+		UsageText: `**step crypto cose sign** [- | <filename>]
+[**--alg**=<algorithm>] [**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>]
+[**--kms**=<uri>] [**--password-file**=<file>] [**--cty**=<content-type>]
+[**--external-aad**=<file>] [**--detached**] [**--untagged**] [**--format**=<format>]`,
+		Description: `**step crypto cose sign** creates a COSE message by computing a digital
+signature or message authentication code for an arbitrary payload. Asymmetric
+keys create a COSE_Sign1 message and symmetric keys create a COSE_Mac0
+message. By default, the payload to sign is read from STDIN and the message will
+be written to STDOUT.
+
+For examples, see **step help crypto cose**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The signature or MAC <algorithm> to use. The selected algorithm must be
+compatible with the key type. If not specified, a default is selected depending
+on the key type.
+
+: <algorithm> is a case-insensitive string and must be one of:
+
+    **ES256**
+    :  ECDSA using P-256 and SHA-256 (default for P-256 keys)
+
+    **ES384**
+    :  ECDSA using P-384 and SHA-384 (default for P-384 keys)
+
+    **ES512**
+    :  ECDSA using P-521 and SHA-512 (default for P-521 keys)
+
+    **EdDSA**
+    :  EdDSA signature algorithm (default for Ed25519 keys)
+
+    **PS256**
+    :  RSASSA-PSS using SHA-256 and MGF1 with SHA-256 (default for RSA keys)
+
+    **PS384**
+    :  RSASSA-PSS using SHA-384 and MGF1 with SHA-384
+
+    **PS512**
+    :  RSASSA-PSS using SHA-512 and MGF1 with SHA-512
+
+    **RS256**
+    :  RSASSA-PKCS1-v1_5 using SHA-256
+
+    **RS384**
+    :  RSASSA-PKCS1-v1_5 using SHA-384
+
+    **RS512**
+    :  RSASSA-PKCS1-v1_5 using SHA-512
+
+    **HMAC256/64**
+    :  HMAC using SHA-256 truncated to 64 bits
+
+    **HMAC256/256** (or HS256)
+    :  HMAC using SHA-256 (default for 32 to 47 byte symmetric keys)
+
+    **HMAC384/384** (or HS384)
+    :  HMAC using SHA-384 (default for 48 to 63 byte symmetric keys)
+
+    **HMAC512/512** (or HS512)
+    :  HMAC using SHA-512 (default for symmetric keys of 64 or more bytes)`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the key with which to sign the message. Messages can
+be signed using a private JWK (or a JWK encrypted as a JWE payload), a PEM
+encoded private key (or a private key encrypted using the modes described on
+RFC 1423 or with PBES2+PBKDF2 described in RFC 2898), or a symmetric JWK. If
+**--kms** is used, <file> is the name of the key in the KMS.`,
+			},
+			jwksFlag,
+			kidFlag,
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.StringFlag{
+				Name: "cty",
+				Usage: `The <content-type> of the payload, added to the protected header of the
+message.`,
+			},
+			externalAADFlag,
+			cli.BoolFlag{
+				Name: "detached",
+				Usage: `Create a message with a detached payload. The payload will be required to
+verify the message.`,
+			},
+			untaggedFlag,
+			formatFlag,
+		},
+	}
+}
+
+func signAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	payload, err := readInput(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	external, err := readExternalAAD(ctx)
+	if err != nil {
+		return err
+	}
+	alg, err := parseAlgorithm(ctx)
+	if err != nil {
+		return err
+	}
+	jwk, err := readKey(ctx, "sig", ctx.String("kid"))
+	if err != nil {
+		return err
+	}
+	key, err := cose.SigningKey(jwk)
+	if err != nil {
+		return err
+	}
+
+	opts := &cose.SignOptions{
+		Protected:   cose.Header{},
+		Unprotected: cose.Header{},
+		ExternalAAD: external,
+		Detached:    ctx.Bool("detached"),
+	}
+	if cty := ctx.String("cty"); cty != "" {
+		opts.Protected[cose.HeaderContentType] = cty
+	}
+	if jwk.KeyID != "" {
+		opts.Unprotected[cose.HeaderKeyID] = []byte(jwk.KeyID)
+	}
+
+	var m *cose.Message
+	switch k := key.(type) {
+	case []byte:
+		if !ctx.Bool("untagged") {
+			opts.Tags = []uint64{cose.TagMac0}
+		}
+		m, err = cose.MAC(k, alg, payload, opts)
+	case crypto.Signer:
+		if !ctx.Bool("untagged") {
+			opts.Tags = []uint64{cose.TagSign1}
+		}
+		m, err = cose.Sign(k, alg, payload, opts)
+	default:
+		return errors.Errorf("unsupported key type %T", k)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeMessage(m, ctx.String("format"))
+}
@@ -0,0 +1,133 @@
+package cose
+
+import (
+	"crypto"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cose"
+	"github.com/smallstep/cli/internal/cryptoutil"
+)
+
+func verifyCommand() cli.Command {
+	return cli.Command{
+		Name:   "verify",
+		Action: cli.ActionFunc(verifyAction),
+		Usage:  "verify a COSE_Sign1 or COSE_Mac0 message and return the payload",
+		UsageText: `**step crypto cose verify** [- | <filename>]
+[**--alg**=<algorithm>] [**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>]
+[**--kms**=<uri>] [**--payload**=<file>] [**--external-aad**=<file>] [**--json**]`,
+		Description: `**step crypto cose verify** reads a COSE_Sign1 or COSE_Mac0 message from a
+file or STDIN; checks that the algorithm is compatible with the key and in
+agreement with expectations; verifies the digital signature or message
+authentication code as appropriate; and outputs the payload of the message on
+STDOUT. If verification fails a non-zero failure code is returned. If
+verification succeeds the command returns 0.
+
+For a message to be verified successfully:
+  * The message must be well formed (no errors during decoding)
+  * The <algorithm>, if given, must match the **alg** header of the message
+  * The algorithm must be compatible with the key
+  * The message must not contain critical headers that are not understood
+  * The signature or message authentication code must be successfully verified
+
+For examples, see **step help crypto cose**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The signature or MAC <algorithm> expected in the message. See **step crypto
+cose sign** for the list of supported algorithms.`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the key with which to verify the message. The contents
+of the file can be a public or private JWK (or a JWK encrypted as a JWE
+payload), a public or private PEM, or a symmetric JWK. If **--kms** is used,
+<file> is the name of the key in the KMS.`,
+			},
+			jwksFlag,
+			kidFlag,
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.StringFlag{
+				Name:  "payload",
+				Usage: `The <file> containing the payload of a message with a detached payload.`,
+			},
+			externalAADFlag,
+			cli.BoolFlag{
+				Name: "json",
+				Usage: `Displays the type, headers, payload and signature of the message as a JSON
+object. Byte strings are encoded using base64.`,
+			},
+		},
+	}
+}
+
+func verifyAction(ctx *cli.Context) error {
+	m, err := readMessage(ctx)
+	if err != nil {
+		return err
+	}
+	if m.Type == cose.Encrypt0 {
+		return errors.New("cannot verify a COSE_Encrypt0 message: use 'step crypto cose decrypt'")
+	}
+
+	expected, err := parseAlgorithm(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkAlgorithm(m, expected); err != nil {
+		return err
+	}
+
+	var payload []byte
+	if filename := ctx.String("payload"); filename != "" {
+		if payload, err = readInput(filename); err != nil {
+			return err
+		}
+	}
+	external, err := readExternalAAD(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Use the kid in the message to select the key from a JWK Set
+	kid := ctx.String("kid")
+	if kid == "" && ctx.String("jwks") != "" {
+		kid = string(m.KeyID())
+	}
+
+	var key crypto.PublicKey
+	if kms := ctx.String("kms"); kms != "" && cryptoutil.IsKMS(kms) {
+		if key, err = cryptoutil.PublicKey(kms, ctx.String("key")); err != nil {
+			return err
+		}
+	} else {
+		jwk, err := readKey(ctx, "sig", kid)
+		if err != nil {
+			return err
+		}
+		key = cose.VerificationKey(jwk)
+	}
+
+	if k, ok := key.([]byte); ok {
+		err = m.VerifyMAC(k, payload, external)
+	} else {
+		err = m.Verify(key, payload, external)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("json") {
+		return printMessage(m)
+	}
+	if payload == nil {
+		payload = m.Payload
+	}
+	os.Stdout.Write(payload)
+	return nil
+}
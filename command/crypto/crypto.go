@@ -5,7 +5,10 @@ import (
 
 	"github.com/smallstep/cli-utils/command"
 
+	"github.com/smallstep/cli/command/crypto/cose"
+	"github.com/smallstep/cli/command/crypto/cwt"
 	"github.com/smallstep/cli/command/crypto/hash"
+	"github.com/smallstep/cli/command/crypto/httpsig"
 	"github.com/smallstep/cli/command/crypto/jose"
 	"github.com/smallstep/cli/command/crypto/jwe"
 	"github.com/smallstep/cli/command/crypto/jwk"
@@ -15,6 +18,7 @@ import (
 	"github.com/smallstep/cli/command/crypto/key"
 	"github.com/smallstep/cli/command/crypto/nacl"
 	"github.com/smallstep/cli/command/crypto/otp"
+	"github.com/smallstep/cli/command/crypto/paseto"
 	"github.com/smallstep/cli/command/crypto/rand"
 	"github.com/smallstep/cli/command/crypto/winpe"
 )
@@ -165,6 +169,12 @@ risks. That said, many of these factors are beyond the scope of this tool.
 			jwe.Command(),
 			jws.Command(),
 			jose.Command(),
+			cose.Command(),
+			cwt.Command(),
+			paseto.Command(),
+			httpsig.Command(),
+			signBlobCommand(),
+			verifyBlobCommand(),
 			hash.Command(),
 			kdf.Command(),
 			key.Command(),
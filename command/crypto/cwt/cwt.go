@@ -0,0 +1,226 @@
+package cwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/internal/cose"
+	"github.com/smallstep/cli/utils"
+)
+
+// Command returns the cli.Command for cwt and related subcommands.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "cwt",
+		Usage:     "sign and verify data using CBOR Web Tokens (CWT)",
+		UsageText: "step crypto cwt <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `A CBOR Web Token or CWT is a compact means of representing claims to be
+transferred between two parties, defined in RFC 8392. CWTs are the CBOR
+counterpart of JSON Web Tokens (JWT): the claims are encoded as a CBOR map and
+protected using a COSE_Sign1 or a COSE_Mac0 message. CWTs are commonly used by
+IoT devices and in other constrained environments.
+
+The registered claims **iss**, **sub**, **aud**, **exp**, **nbf**, **iat** and
+**cti** are encoded using their integer keys, other claims are encoded using
+their names.
+
+Keys are read the same way that **step crypto jwt** reads them: a JWK, a JWK
+set, a JWK encrypted as a JWE payload, or a PEM encoded key. Tokens can also be
+signed with a key in a KMS using the **--kms** flag. Symmetric keys create a
+COSE_Mac0 message.
+
+CWTs are binary. By default they are written as base64url without padding, and
+they can be read as raw CBOR or as their base64, base64url or hexadecimal
+representation.
+
+## EXAMPLES
+
+Create a signed CWT using a JWK:
+'''
+$ step crypto cwt sign --key p256.priv.json --iss "joe@example.com" \
+      --aud "coap://light.example.com" --sub device-1234 --exp $(date -v+1d +"%s")
+2D3ShEOhASahBFgrUTUzNnVMSmdQSHAzNkxjRlB5RUhnZ1RkQ3ppNXowZExGeGgyR0Y0VExXVUdYW6cBb2pv...
+'''
+
+Create a signed CWT with additional claims:
+'''
+$ echo '{"scope":"read write"}' | step crypto cwt sign \
+      --key p256.priv.json --iss "joe@example.com" \
+      --aud "coap://light.example.com" --sub device-1234 --exp $(date -v+1d +"%s")
+'''
+
+Verify the previous token:
+'''
+$ echo $TOKEN | step crypto cwt verify --key p256.pub.json \
+      --iss "joe@example.com" --aud "coap://light.example.com"
+{
+  "type": "COSE_Sign1",
+  "tags": [61, 18],
+  "protected": {
+    "alg": "ES256"
+  },
+  "unprotected": {
+    "kid": "UTUzNnVMSmdQSHAzNkxjRlB5RUhnZ1RkQ3ppNXowZExGeGgyR0Y0VExXVUc="
+  },
+  "payload": {
+    "aud": "coap://light.example.com",
+    "cti": "9e8f4f4a2c5c8a1e9d3f6b7a1c2d3e4f",
+    "exp": 1735689600,
+    "iat": 1735603200,
+    "iss": "joe@example.com",
+    "nbf": 1735603200,
+    "sub": "device-1234"
+  },
+  "signature": "y8e0..."
+}
+'''
+
+Read the information in a token without verifying it, the token can be in
+hexadecimal form:
+'''
+$ echo d83dd28443a10126a104... | step crypto cwt inspect --insecure
+'''`,
+		Subcommands: cli.Commands{
+			signCommand(),
+			verifyCommand(),
+			inspectCommand(),
+		},
+	}
+}
+
+// readInput reads the contents of the given file, or STDIN if the filename is
+// empty or "-".
+func readInput(filename string) ([]byte, error) {
+	switch filename {
+	case "":
+		st, err := os.Stdin.Stat()
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading data")
+		}
+		if st.Size() == 0 && st.Mode()&os.ModeNamedPipe == 0 {
+			return []byte{}, nil
+		}
+		return utils.ReadAll(os.Stdin)
+	case "-":
+		return utils.ReadAll(os.Stdin)
+	default:
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errs.FileError(err, filename)
+		}
+		return b, nil
+	}
+}
+
+// readToken reads the token in the first argument or STDIN and returns the
+// COSE message and the claims it contains.
+func readToken(ctx *cli.Context) (*cose.Message, cose.Claims, error) {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return nil, nil, err
+	}
+	b, err := readInput(ctx.Args().First())
+	if err != nil {
+		return nil, nil, err
+	}
+	if b, err = cose.Decode(b); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing token")
+	}
+	m, err := cose.Parse(b)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing token")
+	}
+	if m.Type == cose.Encrypt0 {
+		return nil, nil, errors.New("error parsing token: encrypted CWTs are not supported")
+	}
+	if m.IsDetached() {
+		return nil, nil, errors.New("error parsing token: the claims are detached")
+	}
+	c, err := cose.ParseClaims(m.Payload)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing token")
+	}
+	return m, c, nil
+}
+
+// readKey reads the key using the --key, --jwks and --kms flags. The given kid
+// is used to select the key from a JWK Set.
+func readKey(ctx *cli.Context, kid string) (*jose.JSONWebKey, error) {
+	key := ctx.String("key")
+	jwks := ctx.String("jwks")
+	switch {
+	case key == "" && jwks == "":
+		return nil, errs.RequiredOrFlag(ctx, "key", "jwks")
+	case key != "" && jwks != "":
+		return nil, errs.MutuallyExclusiveFlags(ctx, "key", "jwks")
+	case jwks != "" && kid == "":
+		return nil, errs.RequiredWithFlag(ctx, "kid", "jwks")
+	case jwks != "" && ctx.String("kms") != "":
+		return nil, errs.IncompatibleFlagWithFlag(ctx, "kms", "jwks")
+	}
+
+	options := []jose.Option{jose.WithUse("sig")}
+	if kid != "" {
+		options = append(options, jose.WithKid(kid))
+	}
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		options = append(options, jose.WithPasswordFile(passwordFile))
+	}
+
+	var (
+		jwk *jose.JSONWebKey
+		err error
+	)
+	if jwks != "" {
+		jwk, err = jose.ReadKeySet(jwks, options...)
+	} else {
+		jwk, err = cose.ReadKey(ctx.String("kms"), key, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Use != "sig" && jwk.Use != "" {
+		return nil, errors.Errorf("invalid jwk use: found '%s', expecting 'sig' (signature)", jwk.Use)
+	}
+	return jwk, nil
+}
+
+type tokenJSON struct {
+	Type        string                 `json:"type"`
+	Tags        []uint64               `json:"tags,omitempty"`
+	Protected   map[string]interface{} `json:"protected"`
+	Unprotected map[string]interface{} `json:"unprotected"`
+	Payload     map[string]interface{} `json:"payload"`
+	Signature   string                 `json:"signature,omitempty"`
+	Tag         string                 `json:"tag,omitempty"`
+}
+
+// printToken prints the JSON representation of the token.
+func printToken(m *cose.Message, c cose.Claims) error {
+	v := tokenJSON{
+		Type:        m.Type.String(),
+		Tags:        m.Tags,
+		Protected:   m.Protected.Map(),
+		Unprotected: m.Unprotected.Map(),
+		Payload:     c.Map(),
+	}
+	if m.Type == cose.Mac0 {
+		v.Tag = base64.StdEncoding.EncodeToString(m.Signature)
+	} else {
+		v.Signature = base64.StdEncoding.EncodeToString(m.Signature)
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling token")
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,42 @@
+package cwt
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+)
+
+func inspectCommand() cli.Command {
+	return cli.Command{
+		Name:   "inspect",
+		Action: cli.ActionFunc(inspectAction),
+		Usage:  `return the decoded CWT without verification`,
+		UsageText: `**step crypto cwt inspect** [- | <filename>]
+**--insecure**`,
+		Description: `**step crypto cwt inspect** reads a CWT data structure from a file or STDIN,
+decodes it, and outputs the headers, claims and signature as a JSON object.
+Registered claims are displayed using their names and byte strings in the
+claims are displayed using their hexadecimal representation. Since this
+command does not verify the CWT you must pass **--insecure** as a misuse
+prevention mechanism.
+
+For examples, see **step help crypto cwt**.`,
+		Flags: []cli.Flag{
+			flags.InsecureHidden,
+		},
+	}
+}
+
+func inspectAction(ctx *cli.Context) error {
+	if !ctx.Bool("insecure") {
+		return errs.InsecureCommand(ctx)
+	}
+
+	m, claims, err := readToken(ctx)
+	if err != nil {
+		return err
+	}
+	return printToken(m, claims)
+}
@@ -0,0 +1,287 @@
+package cwt
+
+import (
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/randutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cose"
+)
+
+func signCommand() cli.Command {
+	return cli.Command{
+		Name:   "sign",
+		Action: cli.ActionFunc(signAction),
+		Usage:  "create a signed CWT data structure",
+		UsageText: `**step crypto cwt sign** [- | <filename>]
+[**--alg**=<algorithm>] [**--aud**=<audience>] [**--iss**=<issuer>] [**--sub**=<sub>]
+[**--exp**=<expiration>] [**--iat**=<issued_at>] [**--nbf**=<not-before>]
+[**--cti**=<cti>] [**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>]
+[**--kms**=<uri>] [**--password-file**=<file>] [**--untagged**] [**--format**=<format>]`,
+		Description: `**step crypto cwt sign** command generates a signed CBOR Web Token (CWT) by
+computing a digital signature or message authentication code for a set of
+claims. Additional claims can be passed as a JSON object in a file or STDIN.
+The token will be written to STDOUT.
+
+Some optional arguments introduce subtle security considerations if omitted.
+These considerations should be carefully analyzed. Therefore, omitting <subtle>
+arguments requires the use of the **--subtle** flag as a misuse prevention
+mechanism.
+
+For examples, see **step help crypto cwt**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The signature or MAC <algorithm> to use. If not specified, a default is
+selected depending on the key type. See **step crypto cose sign** for the list
+of supported algorithms.`,
+			},
+			cli.StringFlag{
+				Name: "iss, issuer",
+				Usage: `The issuer of this CWT. The processing of this claim is generally
+application specific.
+
+: <issuer> is a case-sensitive string.`,
+			},
+			cli.StringSliceFlag{
+				Name: "aud, audience",
+				Usage: `The intended recipient(s) of the CWT, encoded as the **aud** claim in the
+CWT. This flag can be used multiple times to generate a CWT with multiple
+intended recipients.
+
+: Each <audience> is a case-sensitive string.`,
+			},
+			cli.StringFlag{
+				Name: "sub, subject",
+				Usage: `The subject of this CWT. The claims are normally interpreted as statements
+about this subject.
+
+: <subject> is a case-sensitive string.`,
+			},
+			cli.Int64Flag{
+				Name: "exp, expiration",
+				Usage: `The expiration time on or after which the CWT must not be accepted.
+<expiration> must be a numeric value representing a Unix timestamp.`,
+			},
+			cli.Int64Flag{
+				Name: "nbf, not-before",
+				Usage: `The time before which the CWT must not be accepted. <not-before> must be a
+numeric value representing a Unix timestamp. If not provided, the current time
+is used.`,
+			},
+			cli.Int64Flag{
+				Name: "iat, issued-at",
+				Usage: `The time at which the CWT was issued. <issued_at> must be a numeric value
+representing a Unix timestamp. If not provided, the current time is used.`,
+			},
+			cli.StringFlag{
+				Name: "cti, cwt-id",
+				Usage: `A unique identifier for the CWT, encoded as a byte string. The <cti> argument
+is the hexadecimal representation of the identifier. If not provided, a random
+128-bit identifier is generated.`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the key with which to sign the CWT. CWTs can be signed
+using a private JWK (or a JWK encrypted as a JWE payload), a PEM encoded
+private key (or a private key encrypted using the modes described on RFC 1423
+or with PBES2+PBKDF2 described in RFC 2898), or a symmetric JWK. If **--kms**
+is used, <file> is the name of the key in the KMS.`,
+			},
+			cli.StringFlag{
+				Name: "jwks",
+				Usage: `The JWK Set containing the key to use to sign the CWT. The <jwks> argument
+should be the name of a file. The file contents should be a JWK Set or a JWE
+with a JWK Set payload. The **--jwks** flag requires the use of the **--kid**
+flag to specify which key to use.`,
+			},
+			cli.StringFlag{
+				Name: "kid",
+				Usage: `The ID of the key used to sign the CWT. The <kid> argument is a case-sensitive
+string. When used with **--jwks** (a JWK Set) the <kid> value must match the
+**"kid"** member of one of the JWKs in the JWK Set.`,
+			},
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.BoolFlag{
+				Name: "untagged",
+				Usage: `Do not enclose the token in the CWT CBOR tag. The COSE message tag is always
+present.`,
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: cose.FormatBase64URL,
+				Usage: `The <format> used to write the CWT.
+
+: <format> is a string and must be one of:
+
+    **base64url** (default)
+    :  Base64 URL-safe encoding without padding
+
+    **base64**
+    :  Standard base64 encoding with padding
+
+    **hex**
+    :  Hexadecimal encoding
+
+    **raw**
+    :  Raw CBOR bytes`,
+			},
+			flags.SubtleHidden,
+		},
+	}
+}
+
+func signAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	// Read additional claims if provided
+	b, err := readInput(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	claims := cose.Claims{}
+	if len(b) > 0 {
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return errors.Wrap(err, "error parsing payload")
+		}
+		claims = cose.ClaimsFromMap(m)
+	}
+
+	isSubtle := ctx.Bool("subtle")
+	now := time.Now()
+
+	if iss := ctx.String("iss"); iss != "" {
+		claims.Set(cose.ClaimIssuer, iss)
+	}
+	if sub := ctx.String("sub"); sub != "" {
+		claims.Set(cose.ClaimSubject, sub)
+	}
+	switch aud := ctx.StringSlice("aud"); len(aud) {
+	case 0:
+	case 1:
+		claims.Set(cose.ClaimAudience, aud[0])
+	default:
+		values := make([]interface{}, len(aud))
+		for i, a := range aud {
+			values[i] = a
+		}
+		claims.Set(cose.ClaimAudience, values)
+	}
+	if ctx.IsSet("exp") {
+		claims.Set(cose.ClaimExpiry, ctx.Int64("exp"))
+	}
+	if ctx.IsSet("nbf") {
+		claims.Set(cose.ClaimNotBefore, ctx.Int64("nbf"))
+	} else if _, ok := claims[cose.ClaimNotBefore]; !ok {
+		claims.Set(cose.ClaimNotBefore, now.Unix())
+	}
+	if ctx.IsSet("iat") {
+		claims.Set(cose.ClaimIssuedAt, ctx.Int64("iat"))
+	} else if _, ok := claims[cose.ClaimIssuedAt]; !ok {
+		claims.Set(cose.ClaimIssuedAt, now.Unix())
+	}
+	if cti := ctx.String("cti"); cti != "" {
+		v, err := hex.DecodeString(cti)
+		if err != nil {
+			return errs.InvalidFlagValueMsg(ctx, "cti", cti, "value must be a hexadecimal string")
+		}
+		claims.Set(cose.ClaimCWTID, v)
+	} else if _, ok := claims[cose.ClaimCWTID]; !ok {
+		v, err := randutil.Salt(16)
+		if err != nil {
+			return errors.Wrap(err, "error generating random CWT ID")
+		}
+		claims.Set(cose.ClaimCWTID, v)
+	}
+
+	// Validate recommended claims
+	if !isSubtle {
+		exp, hasExp := claims.Time(cose.ClaimExpiry)
+		switch {
+		case claims[cose.ClaimIssuer] == nil:
+			return errors.New("flag '--iss' is required unless '--subtle' is used")
+		case claims[cose.ClaimAudience] == nil:
+			return errors.New("flag '--aud' is required unless '--subtle' is used")
+		case claims[cose.ClaimSubject] == nil:
+			return errors.New("flag '--sub' is required unless '--subtle' is used")
+		case !hasExp:
+			return errors.New("flag '--exp' is required unless '--subtle' is used")
+		case exp.Before(now):
+			return errors.New("flag '--exp' must be in the future unless '--subtle' is used")
+		}
+	}
+
+	var alg cose.Algorithm
+	if name := ctx.String("alg"); name != "" {
+		if alg, err = cose.ParseAlgorithm(name); err != nil {
+			return errs.InvalidFlagValueMsg(ctx, "alg", name, err.Error())
+		}
+	}
+
+	jwk, err := readKey(ctx, ctx.String("kid"))
+	if err != nil {
+		return err
+	}
+	key, err := cose.SigningKey(jwk)
+	if err != nil {
+		return err
+	}
+
+	payload, err := claims.Payload()
+	if err != nil {
+		return err
+	}
+
+	opts := &cose.SignOptions{
+		Unprotected: cose.Header{},
+	}
+	if jwk.KeyID != "" {
+		opts.Unprotected[cose.HeaderKeyID] = []byte(jwk.KeyID)
+	}
+
+	var m *cose.Message
+	switch k := key.(type) {
+	case []byte:
+		opts.Tags = []uint64{cose.TagCWT, cose.TagMac0}
+		m, err = cose.MAC(k, alg, payload, opts)
+	case crypto.Signer:
+		opts.Tags = []uint64{cose.TagCWT, cose.TagSign1}
+		m, err = cose.Sign(k, alg, payload, opts)
+	default:
+		return errors.Errorf("unsupported key type %T", k)
+	}
+	if err != nil {
+		return err
+	}
+	if ctx.Bool("untagged") {
+		m.Tags = m.Tags[1:]
+	}
+
+	b, err = m.MarshalCBOR()
+	if err != nil {
+		return errors.Wrap(err, "error encoding token")
+	}
+	out, err := cose.Encode(b, ctx.String("format"))
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(out)
+	if ctx.String("format") != cose.FormatRaw {
+		fmt.Println()
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package cwt
+
+import (
+	"crypto"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cose"
+	"github.com/smallstep/cli/internal/cryptoutil"
+)
+
+func verifyCommand() cli.Command {
+	return cli.Command{
+		Name:   "verify",
+		Action: cli.ActionFunc(verifyAction),
+		Usage:  "verify a signed CWT data structure and return the payload",
+		UsageText: `**step crypto cwt verify** [- | <filename>]
+[**--aud**=<audience>] [**--iss**=<issuer>] [**--sub**=<subject>] [**--alg**=<algorithm>]
+[**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>] [**--kms**=<uri>]`,
+		Description: `**step crypto cwt verify** reads a CWT data structure from a file or STDIN;
+checks that the audience, issuer, and algorithm are in agreement with
+expectations; verifies the digital signature or message authentication code as
+appropriate; and outputs the decoded token as JSON on STDOUT. If verification
+fails a non-zero failure code is returned. If verification succeeds the command
+returns 0.
+
+For a CWT to be verified successfully:
+
+  * The CWT must be well formed (no errors during decoding)
+  * The <algorithm>, if given, must match the **alg** header of the CWT
+  * The <issuer> and <audience> must match the **iss** and **aud** claims in the CWT,
+    respectively
+  * The <subject>, if given, must match the **sub** claim in the CWT
+  * The CWT signature or MAC must be successfully verified
+  * The CWT must not be expired and must be valid already (**exp** and **nbf** claims)
+
+For examples, see **step help crypto cwt**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "iss, issuer",
+				Usage: `The issuer of this CWT. The <issuer> must match the value of the **iss** claim in
+the CWT. <issuer> is a case-sensitive string. Required unless disabled with the **--subtle** flag.`,
+			},
+			cli.StringFlag{
+				Name: "aud, audience",
+				Usage: `The identity of the principal running this command. The <audience> specified
+must match one of the values in the **aud** claim, indicating the intended
+recipient(s) of the CWT. <audience> is a case-sensitive string. Required unless
+disabled with the **--subtle** flag.`,
+			},
+			cli.StringFlag{
+				Name: "sub, subject",
+				Usage: `The subject of this CWT. If set, the <subject> must match the value of the
+**sub** claim in the CWT.`,
+			},
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The signature or MAC <algorithm> expected in the CWT. See **step crypto cose
+sign** for the list of supported algorithms.`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the key to use to verify the CWT. The contents of the
+file can be a public or private JWK (or a JWK encrypted as a JWE payload), a
+public or private PEM, or a symmetric JWK. If **--kms** is used, <file> is the
+name of the key in the KMS.`,
+			},
+			cli.StringFlag{
+				Name: "jwks",
+				Usage: `The JWK Set containing the key to use to verify the CWT. The <jwks> argument
+should be the name of a file. The file contents should be a JWK Set or a JWE
+with a JWK Set payload. The CWT being verified should have a **kid** header
+that matches the "kid" of one of the JWKs in the JWK Set. If the CWT does not
+have a **kid** header the '--kid' flag can be used.`,
+			},
+			cli.StringFlag{
+				Name: "kid",
+				Usage: `The ID of the key used to sign the CWT, used to select a JWK from a JWK Set.
+The <kid> argument is a case-sensitive string.`,
+			},
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.BoolFlag{
+				Name:   "no-exp-check",
+				Hidden: true,
+			},
+			flags.SubtleHidden,
+			flags.InsecureHidden,
+		},
+	}
+}
+
+func verifyAction(ctx *cli.Context) error {
+	m, claims, err := readToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Validate subtle
+	iss := ctx.String("iss")
+	aud := ctx.String("aud")
+	if !ctx.Bool("subtle") {
+		switch {
+		case iss == "":
+			return errs.RequiredUnlessSubtleFlag(ctx, "iss")
+		case aud == "":
+			return errs.RequiredUnlessSubtleFlag(ctx, "aud")
+		}
+	}
+
+	// Validate no-exp-check with insecure
+	if ctx.Bool("no-exp-check") && !ctx.Bool("insecure") {
+		return errs.RequiredInsecureFlag(ctx, "no-exp-check")
+	}
+
+	if name := ctx.String("alg"); name != "" {
+		expected, err := cose.ParseAlgorithm(name)
+		if err != nil {
+			return errs.InvalidFlagValueMsg(ctx, "alg", name, err.Error())
+		}
+		if alg, ok := m.Algorithm(); !ok || alg != expected {
+			return errors.Errorf("alg %s does not match the alg on CWT (%s)", expected, alg)
+		}
+	}
+
+	// Use the kid in the token to select the key from a JWK Set
+	kid := ctx.String("kid")
+	if kid == "" && ctx.String("jwks") != "" {
+		kid = string(m.KeyID())
+	}
+
+	var key crypto.PublicKey
+	if kms := ctx.String("kms"); kms != "" && cryptoutil.IsKMS(kms) {
+		if key, err = cryptoutil.PublicKey(kms, ctx.String("key")); err != nil {
+			return err
+		}
+	} else {
+		jwk, err := readKey(ctx, kid)
+		if err != nil {
+			return err
+		}
+		key = cose.VerificationKey(jwk)
+	}
+
+	if k, ok := key.([]byte); ok {
+		err = m.VerifyMAC(k, nil, nil)
+	} else {
+		err = m.Verify(key, nil, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	expected := cose.Expected{
+		Issuer:   iss,
+		Subject:  ctx.String("sub"),
+		Audience: aud,
+	}
+	if ctx.Bool("no-exp-check") {
+		delete(claims, cose.ClaimExpiry)
+	}
+	if err := claims.Validate(expected); err != nil {
+		return err
+	}
+
+	return printToken(m, claims)
+}
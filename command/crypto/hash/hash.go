@@ -8,13 +8,9 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"crypto/subtle"
-	"encoding/binary"
 	"encoding/hex"
 	"fmt"
-	"hash"
-	"io"
 	"os"
-	"path"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -23,10 +19,9 @@ import (
 	"github.com/smallstep/cli-utils/errs"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/hashutil"
 )
 
-type hashConstructor func() hash.Hash
-
 // Command returns the jwk subcommand.
 func Command() cli.Command {
 	return cli.Command{
@@ -211,9 +206,9 @@ func digestAction(ctx *cli.Context) error {
 
 		var sum []byte
 		if st.IsDir() {
-			sum, err = hashDir(hc, filename)
+			sum, err = hashutil.Dir(hc, filename)
 		} else {
-			sum, err = hashFile(hc(), filename)
+			sum, err = hashutil.File(hc(), filename)
 		}
 		if err != nil {
 			return err
@@ -249,9 +244,9 @@ func compareAction(ctx *cli.Context) error {
 
 	var sum []byte
 	if st.IsDir() {
-		sum, err = hashDir(hc, filename)
+		sum, err = hashutil.Dir(hc, filename)
 	} else {
-		sum, err = hashFile(hc(), filename)
+		sum, err = hashutil.File(hc(), filename)
 	}
 	if err != nil {
 		return err
@@ -269,7 +264,7 @@ func compareAction(ctx *cli.Context) error {
 
 // getHash returns a new hash constructor for the given algorithm. MD5
 // algorithm can only be used if the insecure flag is passed.
-func getHash(ctx *cli.Context, alg string, insecure bool) (hashConstructor, error) {
+func getHash(ctx *cli.Context, alg string, insecure bool) (hashutil.Constructor, error) {
 	switch strings.ToLower(alg) {
 	case "sha", "sha1":
 		return sha1.New, nil
@@ -294,94 +289,3 @@ func getHash(ctx *cli.Context, alg string, insecure bool) (hashConstructor, erro
 		return nil, errs.InvalidFlagValue(ctx, "alg", alg, "")
 	}
 }
-
-// hashFile returns the hash of the given file using the given hash function.
-func hashFile(h hash.Hash, filename string) ([]byte, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, errs.FileError(err, filename)
-	}
-
-	if _, err := io.Copy(h, f); err != nil {
-		return nil, errs.FileError(err, filename)
-	}
-
-	return h.Sum(nil), nil
-}
-
-// hashDir creates a hash of a directory adding the following data to the
-// hash:
-//  1. Add directory mode bits to the hash
-//  2. For each file/directory in directory:
-//     2.1 If file: add file mode bits and sum
-//     2.2 If directory: do hashDir and add sum
-//  3. return sum
-func hashDir(hc hashConstructor, dirname string) ([]byte, error) {
-	// ReadDir returns the entries sorted by filename
-	dirEntries, err := os.ReadDir(dirname)
-	if err != nil {
-		return nil, errs.FileError(err, dirname)
-	}
-	st, err := os.Stat(dirname)
-	if err != nil {
-		return nil, errs.FileError(err, dirname)
-	}
-
-	var sum []byte
-	mode := make([]byte, 4)
-
-	// calculate sum of contents and mode
-	h := hc()
-	binary.LittleEndian.PutUint32(mode, uint32(st.Mode()))
-	h.Write(mode)
-	for _, dirEntry := range dirEntries {
-		fi, err := dirEntry.Info()
-		if err != nil {
-			return nil, errs.FileError(err, dirEntry.Name())
-		}
-		name := path.Join(dirname, fi.Name())
-		switch {
-		case fi.IsDir():
-			sum, err = hashDir(hc, name)
-		case fi.Mode()&os.ModeSymlink != 0:
-			binary.LittleEndian.PutUint32(mode, uint32(fi.Mode()))
-			h.Write(mode)
-			sum, err = hashSymlink(hc, name)
-		default:
-			binary.LittleEndian.PutUint32(mode, uint32(fi.Mode()))
-			h.Write(mode)
-			sum, err = hashFile(hc(), name)
-		}
-		if err != nil {
-			return nil, err
-		}
-		h.Write(sum)
-	}
-
-	return h.Sum(nil), nil
-}
-
-func hashSymlink(hc hashConstructor, symname string) ([]byte, error) {
-	fullname, err := os.Readlink(symname)
-	if err != nil {
-		return nil, errs.FileError(err, symname)
-	}
-	if !path.IsAbs(fullname) {
-		fullname = path.Join(path.Dir(symname), fullname)
-	}
-
-	// Fails if the link points to a file that does not exist.
-	// TODO: Should we ignore it?
-	st, err := os.Stat(fullname)
-	if err != nil {
-		return nil, errs.FileError(err, fullname)
-	}
-	switch {
-	case st.Mode()&os.ModeSymlink != 0:
-		return hashSymlink(hc, fullname)
-	case st.IsDir():
-		return hashDir(hc, fullname)
-	default:
-		return hashFile(hc(), fullname)
-	}
-}
@@ -0,0 +1,215 @@
+package httpsig
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/internal/cryptoutil"
+	"github.com/smallstep/cli/internal/httpsig"
+	"github.com/smallstep/cli/utils"
+)
+
+// Command returns the cli.Command for httpsig and related subcommands.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "httpsig",
+		Usage:     "sign and verify HTTP messages using HTTP Message Signatures",
+		UsageText: "step crypto httpsig <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `HTTP Message Signatures, defined in RFC 9421, allow to sign and verify
+components of an HTTP request or response, like the method, the target URI or
+header fields. Signatures are transported in the **Signature-Input** and
+**Signature** header fields, so they can be added to existing messages and
+survive proxies that do not modify the covered components.
+
+The **step crypto httpsig** commands read raw HTTP/1.1 messages, as they are
+sent on the wire, from a file or STDIN. Messages can use CRLF or LF line
+endings. The body of a message can be protected by covering the
+**Content-Digest** field defined in RFC 9530; **step crypto httpsig sign** can
+add it using the **--digest** flag, and **step crypto httpsig verify** always
+checks it if it is covered.
+
+The supported algorithms are **ecdsa-p256-sha256**, **ecdsa-p384-sha384**,
+**ed25519**, **rsa-pss-sha512**, **rsa-v1_5-sha256** and **hmac-sha256**.
+
+Keys are read the same way that **step crypto jws** reads them: a JWK, a JWK
+set, a JWK encrypted as a JWE payload, or a PEM encoded key. Signatures can also
+be created with a key in a KMS using the **--kms** flag.
+
+## EXAMPLES
+
+Given the following request in the file request.txt:
+'''
+POST /webhook HTTP/1.1
+Host: example.com
+Content-Type: application/json
+Content-Length: 18
+
+{"hello": "world"}
+'''
+
+Sign the method, target URI, content type and body of the request:
+'''
+$ step crypto httpsig sign --key ed.key --keyid my-key --digest sha-256 request.txt
+POST /webhook HTTP/1.1
+Host: example.com
+Content-Type: application/json
+Content-Length: 18
+Content-Digest: sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:
+Signature-Input: sig1=("@method" "@target-uri" "content-type" "content-digest");created=1760788800;keyid="my-key"
+Signature: sig1=:b2AxVDz5BcsLlE0a1iNk9ODHOMTG6AcFhDpnCYjKNuOKTo2JVE0j7dnJxjVCeyZyHhlXYsXE/jtHsm9tR21GBQ==:
+
+{"hello": "world"}
+'''
+
+Print only the headers to add to the request:
+'''
+$ step crypto httpsig sign --key ed.key --keyid my-key --digest sha-256 --headers request.txt
+Content-Digest: sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:
+Signature-Input: sig1=("@method" "@target-uri" "content-type" "content-digest");created=1760788800;keyid="my-key"
+Signature: sig1=:b2AxVDz5BcsLlE0a1iNk9ODHOMTG6AcFhDpnCYjKNuOKTo2JVE0j7dnJxjVCeyZyHhlXYsXE/jtHsm9tR21GBQ==:
+'''
+
+Sign specific components, including a query parameter, using a key in a KMS:
+'''
+$ step crypto httpsig sign --kms 'pkcs11:module-path=/usr/local/lib/softhsm/libsofthsm2.so;token=smallstep?pin-value=password' \
+      --key 'pkcs11:id=1000' --component @method --component @path \
+      --component '"@query-param";name="id"' request.txt
+'''
+
+Verify a signed request with a public key, requiring the body to be covered:
+'''
+$ step crypto httpsig verify --key ed.pub --component content-digest signed.txt
+[
+  {
+    "label": "sig1",
+    "alg": "ed25519",
+    "keyid": "my-key",
+    "created": "2025-10-18T12:00:00Z",
+    "components": ["@method", "@target-uri", "content-type", "content-digest"]
+  }
+]
+'''
+
+Verify a signed request selecting the key from a JWK Set using the **keyid**
+parameter of the signature:
+'''
+$ step crypto httpsig verify --jwks keys.json signed.txt
+'''`,
+		Subcommands: cli.Commands{
+			signCommand(),
+			verifyCommand(),
+		},
+	}
+}
+
+var schemeFlag = cli.StringFlag{
+	Name:  "scheme",
+	Value: "https",
+	Usage: `The <scheme> used to build the **@target-uri** and **@scheme** components of
+requests in origin form.`,
+}
+
+var componentUsage = `Derived components start with **@**, and must be one of **@method**,
+**@target-uri**, **@authority**, **@scheme**, **@request-target**, **@path**,
+**@query**, **@query-param** or **@status**. The **@query-param** component
+requires the **name** parameter, for example **'"@query-param";name="id"'**.
+Any other value is the lowercase name of a header field.`
+
+// readInput reads the contents of the given file, or STDIN if the filename is
+// empty or "-".
+func readInput(filename string) ([]byte, error) {
+	switch filename {
+	case "", "-":
+		return utils.ReadAll(os.Stdin)
+	default:
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errs.FileError(err, filename)
+		}
+		return b, nil
+	}
+}
+
+// readMessage reads and parses the HTTP message in the first argument or
+// STDIN.
+func readMessage(ctx *cli.Context) (*httpsig.Message, error) {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return nil, err
+	}
+	b, err := readInput(ctx.Args().First())
+	if err != nil {
+		return nil, err
+	}
+	return httpsig.ReadMessage(b, ctx.String("scheme"))
+}
+
+// readKey reads the key using the --key, --jwks and --kms flags. If --kms is
+// used the key of the returned JWK is a crypto.Signer backed by the KMS. The
+// given kid is used to select the key from a JWK Set.
+func readKey(ctx *cli.Context, kid string) (*jose.JSONWebKey, error) {
+	key := ctx.String("key")
+	jwks := ctx.String("jwks")
+	kms := ctx.String("kms")
+	switch {
+	case key == "" && jwks == "":
+		return nil, errs.RequiredOrFlag(ctx, "key", "jwks")
+	case key != "" && jwks != "":
+		return nil, errs.MutuallyExclusiveFlags(ctx, "key", "jwks")
+	case jwks != "" && kid == "":
+		return nil, errs.RequiredWithFlag(ctx, "kid", "jwks")
+	case jwks != "" && kms != "":
+		return nil, errs.IncompatibleFlagWithFlag(ctx, "kms", "jwks")
+	}
+
+	if kms != "" && cryptoutil.IsKMS(kms) {
+		signer, err := cryptoutil.CreateSigner(kms, key)
+		if err != nil {
+			return nil, err
+		}
+		return &jose.JSONWebKey{Key: signer, Use: "sig"}, nil
+	}
+
+	options := []jose.Option{jose.WithUse("sig")}
+	if kid != "" {
+		options = append(options, jose.WithKid(kid))
+	}
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		options = append(options, jose.WithPasswordFile(passwordFile))
+	}
+
+	var (
+		jwk *jose.JSONWebKey
+		err error
+	)
+	if jwks != "" {
+		jwk, err = jose.ReadKeySet(jwks, options...)
+	} else {
+		jwk, err = jose.ReadKey(key, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Use != "sig" && jwk.Use != "" {
+		return nil, errors.Errorf("invalid jwk use: found '%s', expecting 'sig' (signature)", jwk.Use)
+	}
+	return jwk, nil
+}
+
+// parseAlgorithm returns the algorithm in the --alg flag.
+func parseAlgorithm(ctx *cli.Context) (string, error) {
+	alg := ctx.String("alg")
+	if alg == "" {
+		return "", nil
+	}
+	for _, a := range httpsig.Algorithms() {
+		if a == alg {
+			return alg, nil
+		}
+	}
+	return "", errs.InvalidFlagValue(ctx, "alg", alg, "ecdsa-p256-sha256, ecdsa-p384-sha384, ed25519, rsa-pss-sha512, rsa-v1_5-sha256, hmac-sha256")
+}
@@ -0,0 +1,241 @@
+package httpsig
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/httpsig"
+)
+
+func signCommand() cli.Command {
+	return cli.Command{
+		Name:   "sign",
+		Action: cli.ActionFunc(signAction),
+		Usage:  "sign an HTTP request or response",
+		UsageText: `**step crypto httpsig sign** [- | <filename>]
+[**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>] [**--kms**=<uri>]
+[**--password-file**=<file>] [**--component**=<component>] [**--digest**=<algorithm>]
+[**--alg**=<algorithm>] [**--include-alg**] [**--label**=<label>] [**--keyid**=<keyid>]
+[**--expires**=<time|duration>] [**--nonce**=<nonce>] [**--tag**=<tag>]
+[**--scheme**=<scheme>] [**--headers**]`,
+		Description: `**step crypto httpsig sign** reads a raw HTTP request or response from a file
+or STDIN, signs the given components, and writes the message with the
+**Signature-Input** and **Signature** header fields to STDOUT. The rest of the
+message is not modified.
+
+If no components are given, requests cover **@method**, **@target-uri** and,
+if present, **content-type** and **content-digest**; responses cover **@status**
+and, if present, **content-type** and **content-digest**.
+
+For examples, see **step help crypto httpsig**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the key with which to sign the message. The key can be
+a private JWK (or a JWK encrypted as a JWE payload), a PEM encoded private key,
+or a symmetric JWK for **hmac-sha256**. If **--kms** is used, <file> is the name
+of the key in the KMS.`,
+			},
+			cli.StringFlag{
+				Name: "jwks",
+				Usage: `The JWK Set containing the key to use to sign the message. The <jwks> argument
+should be the name of a file. The file contents should be a JWK Set or a JWE
+with a JWK Set payload. The **--jwks** flag requires the use of the **--kid**
+flag to specify which key to use.`,
+			},
+			cli.StringFlag{
+				Name: "kid",
+				Usage: `The ID of the key in the JWK Set used to sign the message. The <kid> argument
+is a case-sensitive string.`,
+			},
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.StringSliceFlag{
+				Name: "component",
+				Usage: `The <component> to cover with the signature. Use the flag multiple times to
+cover multiple components, they are signed in the order given.
+
+: ` + componentUsage,
+			},
+			cli.StringFlag{
+				Name: "digest",
+				Usage: `Add a **Content-Digest** header field with the digest of the body using the
+given <algorithm>. The header field is covered by default.
+
+: <algorithm> is a string and must be one of:
+
+    **sha-256**
+    :  SHA-256 digest
+
+    **sha-512**
+    :  SHA-512 digest`,
+			},
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The signature <algorithm> to use. If not specified, a default is selected
+depending on the key type.
+
+: <algorithm> is a string and must be one of:
+
+    **ecdsa-p256-sha256**
+    :  ECDSA using P-256 and SHA-256 (default for P-256 keys)
+
+    **ecdsa-p384-sha384**
+    :  ECDSA using P-384 and SHA-384 (default for P-384 keys)
+
+    **ed25519**
+    :  EdDSA using Ed25519 (default for Ed25519 keys)
+
+    **rsa-pss-sha512**
+    :  RSASSA-PSS using SHA-512 (default for RSA keys)
+
+    **rsa-v1_5-sha256**
+    :  RSASSA-PKCS1-v1_5 using SHA-256
+
+    **hmac-sha256**
+    :  HMAC using SHA-256 (default for symmetric keys)`,
+			},
+			cli.BoolFlag{
+				Name:  "include-alg",
+				Usage: `Add the **alg** parameter with the signature algorithm to the signature.`,
+			},
+			cli.StringFlag{
+				Name:  "label",
+				Value: httpsig.DefaultLabel,
+				Usage: `The <label> of the signature in the **Signature-Input** and **Signature** fields.`,
+			},
+			cli.StringFlag{
+				Name: "keyid",
+				Usage: `The <keyid> parameter of the signature. Defaults to the **"kid"** of the key
+if it has one.`,
+			},
+			cli.StringFlag{
+				Name: "expires",
+				Usage: `The <time|duration> after which the signature must not be accepted. The time
+must be in RFC 3339 format, and the duration is a sequence of decimal numbers,
+each with optional fraction and a unit suffix, such as "300s" or "1h".`,
+			},
+			cli.StringFlag{
+				Name:  "nonce",
+				Usage: `A unique <nonce> added to the signature to prevent replays.`,
+			},
+			cli.StringFlag{
+				Name:  "tag",
+				Usage: `The application-specific <tag> of the signature.`,
+			},
+			schemeFlag,
+			cli.BoolFlag{
+				Name:  "headers",
+				Usage: `Print only the header fields added to the message.`,
+			},
+		},
+	}
+}
+
+func signAction(ctx *cli.Context) error {
+	m, err := readMessage(ctx)
+	if err != nil {
+		return err
+	}
+	alg, err := parseAlgorithm(ctx)
+	if err != nil {
+		return err
+	}
+
+	var expires time.Time
+	if s := ctx.String("expires"); s != "" {
+		var ok bool
+		if expires, ok = flags.ParseTimeOrDuration(s); !ok {
+			return errs.InvalidFlagValue(ctx, "expires", s, "")
+		}
+	}
+
+	jwk, err := readKey(ctx, ctx.String("kid"))
+	if err != nil {
+		return err
+	}
+	var key interface{}
+	switch k := jwk.Key.(type) {
+	case []byte, crypto.Signer:
+		key = k
+	default:
+		if jwk.IsPublic() {
+			return errors.New("cannot use a public key for signing")
+		}
+		return errors.Errorf("unsupported key type %T", k)
+	}
+
+	var added []string
+	addHeader := func(name, value string) {
+		m.AddHeader(name, value)
+		added = append(added, name+": "+value)
+	}
+
+	if d := ctx.String("digest"); d != "" {
+		value, err := httpsig.ContentDigest(d, m.Body)
+		if err != nil {
+			return errs.InvalidFlagValue(ctx, "digest", d, "sha-256, sha-512")
+		}
+		if m.Header().Get(httpsig.ContentDigestHeader) != "" {
+			return errors.New("message already contains a Content-Digest header")
+		}
+		addHeader(httpsig.ContentDigestHeader, value)
+	}
+
+	components := ctx.StringSlice("component")
+	if len(components) == 0 {
+		components = defaultComponents(m)
+	}
+	keyID := ctx.String("keyid")
+	if keyID == "" {
+		keyID = jwk.KeyID
+	}
+
+	input, signature, err := m.Sign(key, httpsig.SignOptions{
+		Label:            ctx.String("label"),
+		Components:       components,
+		Algorithm:        alg,
+		IncludeAlgorithm: ctx.Bool("include-alg"),
+		KeyID:            keyID,
+		Created:          time.Now(),
+		Expires:          expires,
+		Nonce:            ctx.String("nonce"),
+		Tag:              ctx.String("tag"),
+	})
+	if err != nil {
+		return err
+	}
+	addHeader(httpsig.SignatureInputHeader, input)
+	addHeader(httpsig.SignatureHeader, signature)
+
+	if ctx.Bool("headers") {
+		fmt.Println(strings.Join(added, "\n"))
+		return nil
+	}
+	os.Stdout.Write(m.Bytes())
+	return nil
+}
+
+// defaultComponents returns the components covered if the --component flag
+// is not used.
+func defaultComponents(m *httpsig.Message) []string {
+	components := []string{"@method", "@target-uri"}
+	if m.Response != nil {
+		components = []string{"@status"}
+	}
+	for _, name := range []string{"Content-Type", httpsig.ContentDigestHeader} {
+		if m.Header().Get(name) != "" {
+			components = append(components, strings.ToLower(name))
+		}
+	}
+	return components
+}
@@ -0,0 +1,238 @@
+package httpsig
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cryptoutil"
+	"github.com/smallstep/cli/internal/httpsig"
+)
+
+// leeway is the clock skew allowed when validating the created and expires
+// parameters.
+const leeway = time.Minute
+
+func verifyCommand() cli.Command {
+	return cli.Command{
+		Name:   "verify",
+		Action: cli.ActionFunc(verifyAction),
+		Usage:  "verify the signatures of an HTTP request or response",
+		UsageText: `**step crypto httpsig verify** [- | <filename>]
+[**--key**=<file>] [**--jwks**=<jwks>] [**--kid**=<kid>] [**--kms**=<uri>]
+[**--password-file**=<file>] [**--label**=<label>] [**--alg**=<algorithm>]
+[**--component**=<component>] [**--max-age**=<duration>] [**--tag**=<tag>]
+[**--scheme**=<scheme>]`,
+		Description: `**step crypto httpsig verify** reads a raw HTTP request or response from a
+file or STDIN, verifies its signatures, and outputs the parameters of the
+verified signatures as JSON on STDOUT. If verification fails a non-zero failure
+code is returned. If verification succeeds the command returns 0.
+
+By default all the signatures in the message are verified. Use **--label** to
+verify only one of them.
+
+For a signature to be verified successfully:
+
+  * The **Signature-Input** and **Signature** fields must be well formed
+  * The <algorithm>, if given, must match the **alg** parameter of the signature
+  * The algorithm must be compatible with the key
+  * The signature must cover all the given components
+  * The signature must not be expired (**expires** parameter), or older than
+    the <duration> given in **--max-age** (**created** parameter)
+  * The <tag>, if given, must match the **tag** parameter of the signature
+  * The signature must be successfully verified
+  * If the signature covers the **Content-Digest** field, it must match the
+    body of the message
+
+For examples, see **step help crypto httpsig**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the key with which to verify the message. The contents
+of the file can be a public or private JWK (or a JWK encrypted as a JWE
+payload), a public or private PEM, or a symmetric JWK. If **--kms** is used,
+<file> is the name of the key in the KMS.`,
+			},
+			cli.StringFlag{
+				Name: "jwks",
+				Usage: `The JWK Set containing the key to use to verify the message. The <jwks>
+argument should be the name of a file. The file contents should be a JWK Set or
+a JWE with a JWK Set payload. The key is selected using the **keyid** parameter
+of each signature, or the '--kid' flag.`,
+			},
+			cli.StringFlag{
+				Name: "kid",
+				Usage: `The ID of the key in the JWK Set used to verify the message. The <kid>
+argument is a case-sensitive string.`,
+			},
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.StringFlag{
+				Name:  "label",
+				Usage: `The <label> of the signature to verify.`,
+			},
+			cli.StringFlag{
+				Name: "alg, algorithm",
+				Usage: `The signature <algorithm> expected. It is required for RSA keys if the
+signature does not have the **alg** parameter and it is not **rsa-pss-sha512**.
+See **step crypto httpsig sign** for the list of supported algorithms.`,
+			},
+			cli.StringSliceFlag{
+				Name: "component",
+				Usage: `The <component> that must be covered by the signature. Use the flag multiple
+times to require multiple components.
+
+: ` + componentUsage,
+			},
+			cli.DurationFlag{
+				Name:  "max-age",
+				Usage: `The maximum age of the signature, as a <duration> from its **created** parameter.`,
+			},
+			cli.StringFlag{
+				Name:  "tag",
+				Usage: `The application-specific <tag> that the signature must have.`,
+			},
+			schemeFlag,
+		},
+	}
+}
+
+type signatureJSON struct {
+	Label      string     `json:"label"`
+	Algorithm  string     `json:"alg,omitempty"`
+	KeyID      string     `json:"keyid,omitempty"`
+	Created    *time.Time `json:"created,omitempty"`
+	Expires    *time.Time `json:"expires,omitempty"`
+	Nonce      string     `json:"nonce,omitempty"`
+	Tag        string     `json:"tag,omitempty"`
+	Components []string   `json:"components"`
+}
+
+func verifyAction(ctx *cli.Context) error {
+	m, err := readMessage(ctx)
+	if err != nil {
+		return err
+	}
+	alg, err := parseAlgorithm(ctx)
+	if err != nil {
+		return err
+	}
+
+	sigs, err := m.Signatures()
+	if err != nil {
+		return err
+	}
+	if label := ctx.String("label"); label != "" {
+		var found []*httpsig.Signature
+		for _, s := range sigs {
+			if s.Label == label {
+				found = append(found, s)
+			}
+		}
+		if len(found) == 0 {
+			return errors.Errorf("signature %s not found", label)
+		}
+		sigs = found
+	}
+
+	var result []signatureJSON
+	now := time.Now()
+	for _, s := range sigs {
+		for _, c := range ctx.StringSlice("component") {
+			if !s.Covers(c) {
+				return errors.Errorf("validation failed: signature %s does not cover %s", s.Label, c)
+			}
+		}
+		if tag := ctx.String("tag"); tag != "" && s.Tag != tag {
+			return errors.Errorf("validation failed: signature %s has tag %q", s.Label, s.Tag)
+		}
+		if !s.Expires.IsZero() && now.Add(-leeway).After(s.Expires) {
+			return errors.Errorf("validation failed: signature %s is expired", s.Label)
+		}
+		if !s.Created.IsZero() && now.Add(leeway).Before(s.Created) {
+			return errors.Errorf("validation failed: signature %s is created in the future", s.Label)
+		}
+		if maxAge := ctx.Duration("max-age"); maxAge > 0 {
+			if s.Created.IsZero() {
+				return errors.Errorf("validation failed: signature %s does not have the created parameter", s.Label)
+			}
+			if now.Sub(s.Created) > maxAge {
+				return errors.Errorf("validation failed: signature %s is older than %s", s.Label, maxAge)
+			}
+		}
+
+		key, err := verificationKey(ctx, s)
+		if err != nil {
+			return err
+		}
+		if err := m.Verify(s, key, alg); err != nil {
+			return errors.Wrapf(err, "signature %s", s.Label)
+		}
+		if s.Covers("content-digest") {
+			if err := m.VerifyContentDigest(); err != nil {
+				return err
+			}
+		}
+
+		v := signatureJSON{
+			Label:      s.Label,
+			Algorithm:  s.Algorithm,
+			KeyID:      s.KeyID,
+			Nonce:      s.Nonce,
+			Tag:        s.Tag,
+			Components: s.Components,
+		}
+		if v.Algorithm == "" {
+			if v.Algorithm = alg; alg == "" {
+				v.Algorithm, _ = httpsig.DefaultAlgorithm(key)
+			}
+		}
+		if !s.Created.IsZero() {
+			t := s.Created.UTC()
+			v.Created = &t
+		}
+		if !s.Expires.IsZero() {
+			t := s.Expires.UTC()
+			v.Expires = &t
+		}
+		result = append(result, v)
+	}
+
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling signatures")
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// verificationKey returns the public or symmetric key used to verify the given
+// signature.
+func verificationKey(ctx *cli.Context, s *httpsig.Signature) (interface{}, error) {
+	if kms := ctx.String("kms"); kms != "" && cryptoutil.IsKMS(kms) {
+		return cryptoutil.PublicKey(kms, ctx.String("key"))
+	}
+
+	// Use the keyid of the signature to select the key from a JWK Set
+	kid := ctx.String("kid")
+	if kid == "" && ctx.String("jwks") != "" {
+		if kid = s.KeyID; kid == "" {
+			return nil, errs.RequiredWithFlag(ctx, "kid", "jwks")
+		}
+	}
+	jwk, err := readKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if signer, ok := jwk.Key.(crypto.Signer); ok {
+		return signer.Public(), nil
+	}
+	return jwk.Key, nil
+}
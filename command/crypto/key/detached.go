@@ -0,0 +1,417 @@
+package key
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/keyutil"
+
+	"github.com/smallstep/cli/internal/hashutil"
+)
+
+// Types of detached signatures.
+const (
+	detachedFile     = "file"
+	detachedManifest = "manifest"
+)
+
+// Signature algorithms of detached signatures.
+const (
+	algECDSA       = "ECDSA"
+	algRSAPKCS1v15 = "RSA-PKCS1v15"
+	algRSAPSS      = "RSA-PSS"
+	algEd25519ph   = "Ed25519ph"
+)
+
+// detachedSignature is the format of detached signatures and manifests. The
+// digest of a file is the digest of its contents, and the digest of a
+// directory is the hash tree computed by 'step crypto hash digest'. The
+// signature is computed over the hash of the JSON encoding of all the other
+// fields, so the digest and its metadata cannot be modified independently;
+// the hash must be the one used with the key and match the digest length.
+type detachedSignature struct {
+	Version        int             `json:"version"`
+	Type           string          `json:"type"`
+	Algorithm      string          `json:"algorithm"`
+	Hash           string          `json:"hash"`
+	KeyFingerprint string          `json:"keyFingerprint"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Name           string          `json:"name,omitempty"`
+	Size           int64           `json:"size,omitempty"`
+	Digest         string          `json:"digest"`
+	Files          []manifestEntry `json:"files,omitempty"`
+	Signature      string          `json:"signature"`
+}
+
+// manifestEntry is the digest of a file in a directory manifest.
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// hashNames are the hash functions supported in detached signatures. MD5 and
+// SHA-1 are only supported in raw RSA signatures.
+var hashNames = map[crypto.Hash]string{
+	crypto.SHA224:     "sha224",
+	crypto.SHA256:     "sha256",
+	crypto.SHA384:     "sha384",
+	crypto.SHA512:     "sha512",
+	crypto.SHA512_224: "sha512-224",
+	crypto.SHA512_256: "sha512-256",
+}
+
+// parseHash returns the hash function with the given name in the --alg flag.
+func parseHash(name string) (crypto.Hash, error) {
+	switch strings.ToLower(name) {
+	case "sha", "sha1":
+		return crypto.SHA1, nil
+	case "md5":
+		return crypto.MD5, nil
+	}
+	for h, n := range hashNames {
+		if n == strings.ToLower(name) {
+			return h, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported algorithm %s", name)
+}
+
+// detachedHash returns the hash function of a detached signature.
+func detachedHash(name string) (crypto.Hash, error) {
+	for h, n := range hashNames {
+		if n == name {
+			return h, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported hash %q in detached signature", name)
+}
+
+// curveHash returns the hash function used with the given curve.
+func curveHash(curve elliptic.Curve) (crypto.Hash, error) {
+	switch curve {
+	case elliptic.P224():
+		return crypto.SHA224, nil
+	case elliptic.P256():
+		return crypto.SHA256, nil
+	case elliptic.P384():
+		return crypto.SHA384, nil
+	case elliptic.P521():
+		return crypto.SHA512, nil
+	default:
+		return 0, errors.Errorf("unsupported elliptic curve %s", curve.Params().Name)
+	}
+}
+
+// detachedOptions returns the algorithm and signer options used to create a
+// detached signature with the given key. Ed25519 keys use Ed25519ph, so the
+// message can be hashed in chunks.
+func detachedOptions(ctx *cli.Context, key crypto.PublicKey) (string, crypto.SignerOpts, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		h, err := curveHash(k.Curve)
+		return algECDSA, h, err
+	case *rsa.PublicKey:
+		opts, err := rsaHash(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, ok := hashNames[opts.HashFunc()]; !ok {
+			return "", nil, errors.Errorf("algorithm %s is not supported in detached signatures", ctx.String("alg"))
+		}
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			return algRSAPSS, opts, nil
+		}
+		return algRSAPKCS1v15, opts, nil
+	case ed25519.PublicKey:
+		return algEd25519ph, &ed25519.Options{Hash: crypto.SHA512}, nil
+	default:
+		return "", nil, errors.Errorf("unsupported key type %T", k)
+	}
+}
+
+// digestInput returns the digest of a file or STDIN, and the number of bytes
+// read. Files are read in chunks.
+func digestInput(h crypto.Hash, input string) ([]byte, int64, error) {
+	if input != "-" {
+		st, err := os.Stat(input)
+		if err != nil {
+			return nil, 0, errs.FileError(err, input)
+		}
+		sum, err := hashutil.File(h.New(), input)
+		return sum, st.Size(), err
+	}
+	hh := h.New()
+	n, err := io.Copy(hh, os.Stdin)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error reading from STDIN")
+	}
+	return hh.Sum(nil), n, nil
+}
+
+// buildManifest returns the digests of all the files in the given directory,
+// sorted by path. Symbolic links are followed like in the hash tree.
+func buildManifest(h crypto.Hash, dirname string) ([]manifestEntry, error) {
+	var entries []manifestEntry
+	err := filepath.WalkDir(dirname, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errs.FileError(err, name)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		var sum []byte
+		if d.Type()&os.ModeSymlink != 0 {
+			sum, err = hashutil.Symlink(h.New, name)
+		} else {
+			sum, err = hashutil.File(h.New(), name)
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dirname, name)
+		if err != nil {
+			return errors.Wrapf(err, "error reading %s", name)
+		}
+		entries = append(entries, manifestEntry{
+			Path:   filepath.ToSlash(rel),
+			Digest: hex.EncodeToString(sum),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// createDetached creates a detached signature for a file, STDIN, or a
+// directory if recursive is true.
+func createDetached(ctx *cli.Context, signer crypto.Signer, input string, recursive bool) (*detachedSignature, error) {
+	alg, opts, err := detachedOptions(ctx, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	fp, err := keyutil.Fingerprint(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	h := opts.HashFunc()
+	sig := &detachedSignature{
+		Version:        1,
+		Type:           detachedFile,
+		Algorithm:      alg,
+		Hash:           hashNames[h],
+		KeyFingerprint: fp,
+		Timestamp:      time.Now().UTC().Truncate(time.Second),
+	}
+	if input != "-" {
+		sig.Name = filepath.Base(input)
+	}
+
+	var digest []byte
+	if recursive {
+		sig.Type = detachedManifest
+		if digest, err = hashutil.Dir(h.New, input); err != nil {
+			return nil, err
+		}
+		if sig.Files, err = buildManifest(h, input); err != nil {
+			return nil, err
+		}
+	} else if digest, sig.Size, err = digestInput(h, input); err != nil {
+		return nil, err
+	}
+
+	sig.Digest = hex.EncodeToString(digest)
+	signed, err := sig.signedDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	b, err := signer.Sign(rand.Reader, signed, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing message")
+	}
+	sig.Signature = base64.StdEncoding.EncodeToString(b)
+	return sig, nil
+}
+
+// signedDigest returns the hash of the canonical encoding of the detached
+// signature without the signature field. This is the value that is signed.
+func (s *detachedSignature) signedDigest(h crypto.Hash) ([]byte, error) {
+	v := *s
+	v.Signature = ""
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling signature")
+	}
+	hh := h.New()
+	hh.Write(b)
+	return hh.Sum(nil), nil
+}
+
+// readDetached reads a detached signature or manifest.
+func readDetached(filename string) (*detachedSignature, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	var sig detachedSignature
+	if err := json.Unmarshal(b, &sig); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", filename)
+	}
+	if sig.Version != 1 {
+		return nil, errors.Errorf("error parsing %s: unsupported version %d", filename, sig.Version)
+	}
+	return &sig, nil
+}
+
+// decodeDigest returns the digest in the detached signature, which must have
+// the size of the given hash.
+func (s *detachedSignature) decodeDigest(h crypto.Hash) ([]byte, error) {
+	digest, err := hex.DecodeString(s.Digest)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding digest")
+	}
+	if len(digest) != h.Size() {
+		return nil, errors.Errorf("digest length %d does not match the hash %s", len(digest), s.Hash)
+	}
+	return digest, nil
+}
+
+// verifySignature verifies the signature of the detached signature over the
+// digest and the metadata in it.
+func (s *detachedSignature) verifySignature(key crypto.PublicKey) error {
+	if fp, err := keyutil.Fingerprint(key); err == nil && s.KeyFingerprint != "" && fp != s.KeyFingerprint {
+		return errors.Errorf("signature was created with a different key (%s)", s.KeyFingerprint)
+	}
+	h, err := detachedHash(s.Hash)
+	if err != nil {
+		return err
+	}
+	if _, err := s.decodeDigest(h); err != nil {
+		return err
+	}
+	digest, err := s.signedDigest(h)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return errors.Wrap(err, "error decoding base64 signature")
+	}
+
+	var ok bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		// The hash is not part of an ECDSA signature, it must be the one used
+		// with the curve.
+		want, err := curveHash(k.Curve)
+		if err != nil {
+			return err
+		}
+		ok = s.Algorithm == algECDSA && h == want && ecdsa.VerifyASN1(k, digest, sig)
+	case *rsa.PublicKey:
+		switch s.Algorithm {
+		case algRSAPKCS1v15:
+			ok = rsa.VerifyPKCS1v15(k, h, digest, sig) == nil
+		case algRSAPSS:
+			ok = rsa.VerifyPSS(k, h, digest, sig, nil) == nil
+		}
+	case ed25519.PublicKey:
+		ok = s.Algorithm == algEd25519ph && h == crypto.SHA512 &&
+			ed25519.VerifyWithOptions(k, digest, sig, &ed25519.Options{Hash: h}) == nil
+	default:
+		return errors.Errorf("unsupported key type %T", k)
+	}
+	if !ok {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// verifyContent checks that the digest of the file or directory matches the
+// digest in the detached signature. For manifests, the error lists the files
+// that were modified, added or removed.
+func (s *detachedSignature) verifyContent(input string) error {
+	h, err := detachedHash(s.Hash)
+	if err != nil {
+		return err
+	}
+	expected, err := s.decodeDigest(h)
+	if err != nil {
+		return err
+	}
+
+	var digest []byte
+	switch s.Type {
+	case detachedFile:
+		if digest, _, err = digestInput(h, input); err != nil {
+			return err
+		}
+	case detachedManifest:
+		if digest, err = hashutil.Dir(h.New, input); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unsupported signature type %q", s.Type)
+	}
+
+	if subtle.ConstantTimeCompare(digest, expected) == 1 {
+		return nil
+	}
+	if s.Type == detachedFile {
+		return errors.Errorf("digest of %s does not match the signature", input)
+	}
+
+	// Find the differences with the manifest.
+	entries, err := buildManifest(h, input)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]string, len(entries))
+	for _, e := range entries {
+		current[e.Path] = e.Digest
+	}
+	var changes []string
+	for _, e := range s.Files {
+		d, ok := current[e.Path]
+		switch {
+		case !ok:
+			changes = append(changes, "removed: "+e.Path)
+		case d != e.Digest:
+			changes = append(changes, "modified: "+e.Path)
+		}
+		delete(current, e.Path)
+	}
+	for _, e := range entries {
+		if _, ok := current[e.Path]; ok {
+			changes = append(changes, "added: "+e.Path)
+		}
+	}
+	if len(changes) == 0 {
+		return errors.Errorf("digest of %s does not match the manifest: file modes or links have changed", input)
+	}
+	return errors.Errorf("digest of %s does not match the manifest:\n  %s", input, strings.Join(changes, "\n  "))
+}
@@ -0,0 +1,251 @@
+package key
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/pemutil"
+)
+
+func newKeyContext(t *testing.T, cmd cli.Command, args ...string) *cli.Context {
+	t.Helper()
+	fs := flag.NewFlagSet(t.Name(), 0)
+	for _, f := range cmd.Flags {
+		f.Apply(fs)
+	}
+	require.NoError(t, fs.Parse(args))
+	return cli.NewContext(cli.NewApp(), fs, nil)
+}
+
+func mustSigner(t *testing.T, kty string) crypto.Signer {
+	t.Helper()
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch kty {
+	case "P256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "P384":
+		signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "RSA":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "Ed25519":
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	}
+	require.NoError(t, err)
+	return signer
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o700))
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+}
+
+func Test_createDetached_file(t *testing.T) {
+	tests := []struct {
+		name    string
+		kty     string
+		args    []string
+		wantAlg string
+		wantH   string
+	}{
+		{"ecdsa-p256", "P256", nil, algECDSA, "sha256"},
+		{"ecdsa-p384", "P384", nil, algECDSA, "sha384"},
+		{"rsa-pkcs1", "RSA", []string{"--alg", "sha384"}, algRSAPKCS1v15, "sha384"},
+		{"rsa-pss", "RSA", []string{"--pss", "--alg", "sha512"}, algRSAPSS, "sha512"},
+		{"ed25519ph", "Ed25519", nil, algEd25519ph, "sha512"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "image.iso")
+			writeFile(t, filename, "the contents of a large file")
+			signer := mustSigner(t, tt.kty)
+
+			ctx := newKeyContext(t, signCommand(), tt.args...)
+			sig, err := createDetached(ctx, signer, filename, false)
+			require.NoError(t, err)
+			assert.Equal(t, 1, sig.Version)
+			assert.Equal(t, detachedFile, sig.Type)
+			assert.Equal(t, tt.wantAlg, sig.Algorithm)
+			assert.Equal(t, tt.wantH, sig.Hash)
+			assert.Equal(t, "image.iso", sig.Name)
+			assert.Equal(t, int64(28), sig.Size)
+			assert.Empty(t, sig.Files)
+
+			// Round trip.
+			require.NoError(t, sig.verifySignature(signer.Public()))
+			require.NoError(t, sig.verifyContent(filename))
+
+			// Different key.
+			other := mustSigner(t, tt.kty)
+			assert.ErrorContains(t, sig.verifySignature(other.Public()), "different key")
+			sig.KeyFingerprint = ""
+			assert.Error(t, sig.verifySignature(other.Public()))
+
+			// Tampered file.
+			writeFile(t, filename, "the contents of a large file!")
+			assert.ErrorContains(t, sig.verifyContent(filename), "does not match the signature")
+		})
+	}
+}
+
+func Test_createDetached_unsupportedHash(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file.txt")
+	writeFile(t, filename, "message")
+	signer := mustSigner(t, "RSA")
+	for _, alg := range []string{"sha1", "md5"} {
+		ctx := newKeyContext(t, signCommand(), "--alg", alg)
+		_, err := createDetached(ctx, signer, filename, false)
+		assert.ErrorContains(t, err, "not supported in detached signatures")
+	}
+}
+
+func Test_detachedSignature_verifySignature_hash(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file.txt")
+	writeFile(t, filename, "message")
+
+	ecSigner := mustSigner(t, "P256")
+	ecSig, err := createDetached(newKeyContext(t, signCommand()), ecSigner, filename, false)
+	require.NoError(t, err)
+	edSigner := mustSigner(t, "Ed25519")
+	edSig, err := createDetached(newKeyContext(t, signCommand()), edSigner, filename, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		sig         detachedSignature
+		key         crypto.PublicKey
+		errContains string
+	}{
+		{"ecdsa/same-length-hash", *ecSig, ecSigner.Public(), "signature does not match"},
+		{"ecdsa/short-hash", *ecSig, ecSigner.Public(), "does not match the hash"},
+		{"ecdsa/md5", *ecSig, ecSigner.Public(), "unsupported hash"},
+		{"ecdsa/sha1", *ecSig, ecSigner.Public(), "unsupported hash"},
+		{"ecdsa/algorithm", *ecSig, ecSigner.Public(), "signature does not match"},
+		{"ed25519/hash", *edSig, edSigner.Public(), "does not match the hash"},
+		{"ed25519/digest", *edSig, edSigner.Public(), "signature does not match"},
+	}
+	tests[0].sig.Hash = "sha512-256"
+	tests[1].sig.Hash = "sha224"
+	tests[2].sig.Hash = "md5"
+	tests[3].sig.Hash = "sha1"
+	tests[4].sig.Algorithm = algRSAPKCS1v15
+	tests[5].sig.Hash = "sha256"
+	tests[6].sig.Digest = "00" + tests[6].sig.Digest[2:]
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.sig.verifySignature(tt.key), tt.errContains)
+		})
+	}
+}
+
+func Test_detachedSignature_verifySignature_metadata(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file.txt")
+	writeFile(t, filename, "message")
+	signer := mustSigner(t, "P256")
+	sig, err := createDetached(newKeyContext(t, signCommand()), signer, filename, false)
+	require.NoError(t, err)
+	require.NoError(t, sig.verifySignature(signer.Public()))
+
+	tests := map[string]func(s *detachedSignature){
+		"timestamp":      func(s *detachedSignature) { s.Timestamp = s.Timestamp.Add(time.Hour) },
+		"name":           func(s *detachedSignature) { s.Name = "other.txt" },
+		"size":           func(s *detachedSignature) { s.Size++ },
+		"keyFingerprint": func(s *detachedSignature) { s.KeyFingerprint = "" },
+		"type":           func(s *detachedSignature) { s.Type = detachedManifest },
+		"files":          func(s *detachedSignature) { s.Files = []manifestEntry{{Path: "file.txt", Digest: s.Digest}} },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := *sig
+			modify(&s)
+			assert.EqualError(t, s.verifySignature(signer.Public()), "signature does not match")
+		})
+	}
+}
+
+func Test_createDetached_manifest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dist")
+	writeFile(t, filepath.Join(dir, "a.txt"), "a")
+	writeFile(t, filepath.Join(dir, "sub", "b.txt"), "b")
+	writeFile(t, filepath.Join(dir, "sub", "c.txt"), "c")
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+
+	signer := mustSigner(t, "P256")
+	sig, err := createDetached(newKeyContext(t, signCommand()), signer, dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, detachedManifest, sig.Type)
+	assert.Equal(t, "dist", sig.Name)
+	paths := make([]string, len(sig.Files))
+	for i, e := range sig.Files {
+		paths[i] = e.Path
+		assert.Len(t, e.Digest, 64)
+	}
+	assert.Equal(t, []string{"a.txt", "link", "sub/b.txt", "sub/c.txt"}, paths)
+
+	require.NoError(t, sig.verifySignature(signer.Public()))
+	require.NoError(t, sig.verifyContent(dir))
+
+	// Modified, added, and removed files are reported.
+	writeFile(t, filepath.Join(dir, "a.txt"), "modified")
+	writeFile(t, filepath.Join(dir, "sub", "d.txt"), "d")
+	require.NoError(t, os.Remove(filepath.Join(dir, "sub", "c.txt")))
+	err = sig.verifyContent(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "modified: a.txt")
+	// Links are followed, so the link to a.txt is modified too.
+	assert.Contains(t, err.Error(), "modified: link")
+	assert.Contains(t, err.Error(), "removed: sub/c.txt")
+	assert.Contains(t, err.Error(), "added: sub/d.txt")
+	assert.NotContains(t, err.Error(), "sub/b.txt")
+}
+
+func Test_verifyAction_manifest(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "image.iso")
+	writeFile(t, filename, "image")
+	copyFile := filepath.Join(dir, "copy", "image.iso")
+	writeFile(t, copyFile, "image")
+	otherFile := filepath.Join(dir, "other.iso")
+	writeFile(t, otherFile, "other")
+
+	signer := mustSigner(t, "Ed25519")
+	pubFile := filepath.Join(dir, "pub.pem")
+	_, err := pemutil.Serialize(signer.Public(), pemutil.ToFile(pubFile, 0o600))
+	require.NoError(t, err)
+
+	sig, err := createDetached(newKeyContext(t, signCommand()), signer, filename, false)
+	require.NoError(t, err)
+	b, err := json.Marshal(sig)
+	require.NoError(t, err)
+	sigFile := filepath.Join(dir, "image.iso.sig")
+	writeFile(t, sigFile, string(b))
+
+	verify := func(args ...string) error {
+		return verifyAction(newKeyContext(t, verifyCommand(), append([]string{"--key", pubFile, "--manifest", sigFile}, args...)...))
+	}
+	// The default input is the name in the signature.
+	assert.NoError(t, verify())
+	// The positional argument is the file verified.
+	assert.NoError(t, verify(copyFile))
+	assert.ErrorContains(t, verify(otherFile), "does not match the signature")
+	assert.ErrorContains(t, verify(dir), "is a directory")
+	assert.ErrorContains(t, verify(filename, otherFile), "too many positional arguments")
+	assert.ErrorContains(t, verifyAction(newKeyContext(t, verifyCommand(), "--key", pubFile, "--signature", "Zm9v", filename, otherFile)), "too many positional arguments")
+	assert.Error(t, verifyAction(newKeyContext(t, verifyCommand(), "--key", pubFile, "--manifest", sigFile, "--signature", "Zm9v", filename)))
+}
@@ -4,13 +4,12 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -60,8 +59,9 @@ func signCommand() cli.Command {
 		Name:   "sign",
 		Action: command.ActionFunc(signAction),
 		Usage:  `sign a message using an asymmetric key`,
-		UsageText: `**step crypto key sign** [<file>] **--key**=<key-file>
-[**--alg**=<algorithm>] [**--pss**] [**--raw**] [**--password-file**=<file>]`,
+		UsageText: `**step crypto key sign** [<file-or-directory>] **--key**=<key-file>
+[**--alg**=<algorithm>] [**--pss**] [**--raw**] [**--detached**]
+[**--recursive**] [**--password-file**=<file>]`,
 		Description: `**step crypto key sign** generates a signature of the digest of a file or a message
 using an asymmetric key.
 
@@ -69,10 +69,27 @@ For an RSA key, the resulting signature is either a PKCS #1 v1.5 or PSS
 signature. For an (EC)DSA key, it is a DER-serialized, ASN.1 signature
 structure.
 
+With the **--detached** flag, the signature is written as a JSON document
+with the signature algorithm, the hash algorithm, the fingerprint of the key,
+the creation time, and the digest of the file. Files are hashed in chunks, so
+they can be of any size; for this reason Ed25519 keys use Ed25519ph (the
+pre-hashed variant of Ed25519 using SHA-512) in detached signatures. The
+signature covers the digest and all the other fields in the document, so none
+of them can be modified without invalidating it. The hash algorithm must be the
+one used with the key and match the length of the digest. MD5 and SHA-1 are
+not supported in detached signatures.
+
+With the **--recursive** flag, the positional argument must be a directory and
+the detached signature becomes a manifest. The digest is the hash tree of the
+directory, the same value printed by **step crypto hash digest** using the
+algorithm in the **hash** field. The manifest also contains the digest of each
+file, to report the files modified, added or removed when it is verified with
+**step crypto key verify --manifest**.
+
 ## POSITIONAL ARGUMENTS
 
-<file>
-:  File to sign
+<file-or-directory>
+:  File to sign, or directory if **--recursive** is used.
 
 ## EXAMPLES
 
@@ -99,6 +116,29 @@ $ step crypto key sign --key rsa.key file.txt
 Sign a file using the RSA-PSS scheme:
 '''
 $ step crypto key sign --key rsa.key --pss file.txt
+'''
+
+Create a detached signature for a large file:
+'''
+$ step crypto key sign --key priv.key --detached image.iso > image.iso.sig
+$ cat image.iso.sig
+{
+  "version": 1,
+  "type": "file",
+  "algorithm": "ECDSA",
+  "hash": "sha256",
+  "keyFingerprint": "SHA256:Y8gShkjd0O2lxHBU/uVTJgzX3n6Vtg5Xp0tEOGSiLgk=",
+  "timestamp": "2025-10-18T12:00:00Z",
+  "name": "image.iso",
+  "size": 4289724416,
+  "digest": "6c8e4b1d4a0b0e26b1b07b5f6bd02bde1f14b9cf8b21d5a0bc1f9c3c12e2e0ad",
+  "signature": "MEUCIQD..."
+}
+'''
+
+Create a signed manifest of a directory with build outputs:
+'''
+$ step crypto key sign --key priv.key --detached --recursive dist/ > dist.manifest
 '''`,
 		Flags: []cli.Flag{
 			cli.StringFlag{
@@ -114,6 +154,15 @@ $ step crypto key sign --key rsa.key --pss file.txt
 				Name:  "raw",
 				Usage: "Print the raw bytes instead of the base64 format.",
 			},
+			cli.BoolFlag{
+				Name:  "detached",
+				Usage: "Print a detached signature with metadata in JSON format.",
+			},
+			cli.BoolFlag{
+				Name: "recursive",
+				Usage: `Sign all the files in a directory, creating a manifest. Requires the
+**--detached** flag.`,
+			},
 			cli.StringFlag{
 				Name:  "password-file",
 				Usage: "The path to the <file> containing passphrase to decrypt the private key.",
@@ -132,6 +181,17 @@ func signAction(ctx *cli.Context) error {
 		return errs.RequiredFlag(ctx, "key")
 	}
 
+	detached := ctx.Bool("detached")
+	recursive := ctx.Bool("recursive")
+	switch {
+	case recursive && !detached:
+		return errs.RequiredWithFlag(ctx, "recursive", "detached")
+	case detached && ctx.Bool("raw"):
+		return errs.IncompatibleFlagWithFlag(ctx, "raw", "detached")
+	case recursive && ctx.NArg() == 0:
+		return errs.TooFewArguments(ctx)
+	}
+
 	var input string
 	switch ctx.NArg() {
 	case 0:
@@ -142,12 +202,11 @@ func signAction(ctx *cli.Context) error {
 		return errs.TooManyArguments(ctx)
 	}
 
-	b, err := utils.ReadFile(input)
-	if err != nil {
-		return errs.FileError(err, input)
+	var opts []pemutil.Options
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		opts = append(opts, pemutil.WithPasswordFile(passwordFile))
 	}
-
-	key, err := pemutil.Read(keyFile)
+	key, err := pemutil.Read(keyFile, opts...)
 	if err != nil {
 		return err
 	}
@@ -157,37 +216,54 @@ func signAction(ctx *cli.Context) error {
 		return errors.Errorf("key %s is not a signer", keyFile)
 	}
 
+	if detached {
+		if st, err := os.Stat(input); err == nil && st.IsDir() != recursive {
+			if recursive {
+				return errors.Errorf("%s is not a directory", input)
+			}
+			return errors.Errorf("%s is a directory: use the '--recursive' flag", input)
+		}
+		sig, err := createDetached(ctx, signer, input, recursive)
+		if err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(sig, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling signature")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
 	var digest []byte
-	var opts crypto.SignerOpts
+	var signerOpts crypto.SignerOpts
 	switch k := key.(type) {
 	case *ecdsa.PrivateKey:
-		opts = crypto.Hash(0)
-		switch k.Curve {
-		case elliptic.P224():
-			digest = hash(crypto.SHA224, b)
-		case elliptic.P256():
-			digest = hash(crypto.SHA256, b)
-		case elliptic.P384():
-			digest = hash(crypto.SHA384, b)
-		case elliptic.P521():
-			digest = hash(crypto.SHA512, b)
-		default:
-			return errors.Errorf("unsupported elliptic curve %s", k.Params().Name)
+		var h crypto.Hash
+		if h, err = curveHash(k.Curve); err != nil {
+			return err
 		}
+		signerOpts = h
+		digest, _, err = digestInput(h, input)
 	case *rsa.PrivateKey:
-		opts, err = rsaHash(ctx)
-		if err != nil {
+		if signerOpts, err = rsaHash(ctx); err != nil {
 			return err
 		}
-		digest = hash(opts.HashFunc(), b)
+		digest, _, err = digestInput(signerOpts.HashFunc(), input)
 	case ed25519.PrivateKey:
-		opts = crypto.Hash(0)
-		digest = b
+		// Ed25519 signs the message, so it has to be read in memory.
+		signerOpts = crypto.Hash(0)
+		if digest, err = utils.ReadFile(input); err != nil {
+			err = errs.FileError(err, input)
+		}
 	default:
 		return errors.Errorf("unsupported key type %T", k)
 	}
+	if err != nil {
+		return err
+	}
 
-	sig, err := signer.Sign(rand.Reader, digest, opts)
+	sig, err := signer.Sign(rand.Reader, digest, signerOpts)
 	if err != nil {
 		return errors.Wrap(err, "error signing message")
 	}
@@ -208,26 +284,13 @@ func hash(h crypto.Hash, data []byte) []byte {
 }
 
 func rsaHash(ctx *cli.Context) (crypto.SignerOpts, error) {
-	var h crypto.Hash
-	switch strings.ToLower(ctx.String("alg")) {
-	case "sha1":
-		h = crypto.SHA1
-	case "sha224":
-		h = crypto.SHA224
-	case "sha256", "":
-		h = crypto.SHA256
-	case "sha384":
-		h = crypto.SHA384
-	case "sha512":
-		h = crypto.SHA512
-	case "sha512-224":
-		h = crypto.SHA512_224
-	case "sha512-256":
-		h = crypto.SHA512_256
-	case "md5":
-		h = crypto.MD5
-	default:
-		return nil, errors.Errorf("unsupported algorithm %s", ctx.String("alg"))
+	alg := ctx.String("alg")
+	if alg == "" {
+		alg = "sha256"
+	}
+	h, err := parseHash(alg)
+	if err != nil {
+		return nil, err
 	}
 
 	if ctx.Bool("pss") {
@@ -1,13 +1,13 @@
 package key
 
 import (
-	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
-	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -24,14 +24,24 @@ func verifyCommand() cli.Command {
 		Name:   "verify",
 		Action: command.ActionFunc(verifyAction),
 		Usage:  `verify a signed message`,
-		UsageText: `**step crypto key verify** [<file>] **--key**=<key-file> **--signature**=<base64>
-[**--alg**=<algorithm>] [**--pss**]`,
+		UsageText: `**step crypto key verify** [<file-or-directory>] **--key**=<key-file>
+[**--signature**=<base64>] [**--manifest**=<file>] [**--alg**=<algorithm>]
+[**--pss**]`,
 		Description: `**step crypto key verify** verifies the signature of a file or a message.
 
+With the **--manifest** flag, the signature is read from a detached signature
+or a manifest created with **step crypto key sign --detached**. The algorithm
+is read from the signature, the signature must cover the digest and all the
+metadata in the document, and the digest of the file or the hash tree of the
+directory must match the signed digest. If the positional argument is not given,
+the file or directory is the **name** in the signature, relative to the
+location of the signature file. If a directory does not match its manifest, the
+files modified, added or removed are reported.
+
 ## POSITIONAL ARGUMENTS
 
-<file>
-:  File to verify.
+<file-or-directory>
+:  File to verify, or directory if **--manifest** is a directory manifest.
 
 ## EXAMPLES
 
@@ -54,6 +64,18 @@ $ step crypto key verify --key rsa.pub --alg sha512 --sig "base64...=" file.txt
 Verify a file using the RSA-PSS scheme:
 '''
 $ step crypto key verify --key rsa.pub --pss --sig "base64...=" file.txt
+'''
+
+Verify a file with a detached signature:
+'''
+$ step crypto key verify --key pub.key --manifest image.iso.sig image.iso
+true
+'''
+
+Verify a directory with a manifest:
+'''
+$ step crypto key verify --key pub.key --manifest dist.manifest dist/
+true
 '''`,
 		Flags: []cli.Flag{
 			cli.StringFlag{
@@ -64,6 +86,11 @@ $ step crypto key verify --key rsa.pub --pss --sig "base64...=" file.txt
 				Name:  "signature,sig",
 				Usage: "The <base64> version of the signature.",
 			},
+			cli.StringFlag{
+				Name: "manifest",
+				Usage: `The <file> containing a detached signature or a directory manifest created
+with **step crypto key sign --detached**.`,
+			},
 			hashAlgFlag,
 			cli.BoolFlag{
 				Name:  "pss",
@@ -86,23 +113,30 @@ func verifyAction(ctx *cli.Context) error {
 	}
 
 	signature := ctx.String("signature")
-	if signature == "" {
-		return errs.RequiredFlag(ctx, "signature")
+	manifest := ctx.String("manifest")
+	switch {
+	case signature == "" && manifest == "":
+		return errs.RequiredOrFlag(ctx, "signature", "manifest")
+	case signature != "" && manifest != "":
+		return errs.MutuallyExclusiveFlags(ctx, "signature", "manifest")
 	}
 
-	var input string
-	switch ctx.NArg() {
-	case 0:
-		input = "-"
-	case 1:
-		input = ctx.Args().First()
-	default:
-		return errs.TooManyArguments(ctx)
+	key, err := pemutil.Read(keyFile)
+	if err != nil {
+		return err
 	}
 
-	b, err := utils.ReadFile(input)
-	if err != nil {
-		return errs.FileError(err, input)
+	// With --manifest the default input is the name in the signature.
+	input := ctx.Args().First()
+	if manifest != "" {
+		if err := verifyManifest(ctx, key, manifest, input); err != nil {
+			return err
+		}
+		fmt.Println(true)
+		return nil
+	}
+	if input == "" {
+		input = "-"
 	}
 
 	sig, err := base64.StdEncoding.DecodeString(signature)
@@ -110,11 +144,6 @@ func verifyAction(ctx *cli.Context) error {
 		return errors.Wrap(err, "error decoding base64 signature")
 	}
 
-	key, err := pemutil.Read(keyFile)
-	if err != nil {
-		return err
-	}
-
 	printAndReturn := func(b bool) error {
 		if b {
 			fmt.Println(b)
@@ -126,17 +155,12 @@ func verifyAction(ctx *cli.Context) error {
 	var digest []byte
 	switch k := key.(type) {
 	case *ecdsa.PublicKey:
-		switch k.Curve {
-		case elliptic.P224():
-			digest = hash(crypto.SHA224, b)
-		case elliptic.P256():
-			digest = hash(crypto.SHA256, b)
-		case elliptic.P384():
-			digest = hash(crypto.SHA384, b)
-		case elliptic.P521():
-			digest = hash(crypto.SHA512, b)
-		default:
-			return errors.Errorf("unsupported elliptic curve %s", k.Params().Name)
+		h, err := curveHash(k.Curve)
+		if err != nil {
+			return err
+		}
+		if digest, _, err = digestInput(h, input); err != nil {
+			return err
 		}
 		return printAndReturn(ecdsa.VerifyASN1(k, digest, sig))
 	case *rsa.PublicKey:
@@ -144,14 +168,54 @@ func verifyAction(ctx *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		digest = hash(opts.HashFunc(), b)
+		if digest, _, err = digestInput(opts.HashFunc(), input); err != nil {
+			return err
+		}
 		if pssOptions, ok := opts.(*rsa.PSSOptions); ok {
 			return printAndReturn(rsa.VerifyPSS(k, opts.HashFunc(), digest, sig, pssOptions) == nil)
 		}
 		return printAndReturn(rsa.VerifyPKCS1v15(k, opts.HashFunc(), digest, sig) == nil)
 	case ed25519.PublicKey:
+		b, err := utils.ReadFile(input)
+		if err != nil {
+			return errs.FileError(err, input)
+		}
 		return printAndReturn(ed25519.Verify(k, b, sig))
 	default:
 		return errors.Errorf("unsupported public key %s", keyFile)
 	}
 }
+
+// verifyManifest verifies a detached signature or a directory manifest of
+// the given input, or of the file or directory named in the signature if the
+// input is empty.
+func verifyManifest(ctx *cli.Context, key interface{}, filename, input string) error {
+	sig, err := readDetached(filename)
+	if err != nil {
+		return err
+	}
+
+	if input == "" {
+		if sig.Name == "" {
+			return errs.TooFewArguments(ctx)
+		}
+		input = filepath.Join(filepath.Dir(filename), sig.Name)
+	}
+	if input == "-" {
+		if sig.Type == detachedManifest {
+			return errors.Errorf("%s is a directory manifest", filename)
+		}
+	} else if st, err := os.Stat(input); err != nil {
+		return errs.FileError(err, input)
+	} else if st.IsDir() != (sig.Type == detachedManifest) {
+		if st.IsDir() {
+			return errors.Errorf("%s is a directory, but %s is not a directory manifest", input, filename)
+		}
+		return errors.Errorf("%s is not a directory, but %s is a directory manifest", input, filename)
+	}
+
+	if err := sig.verifySignature(key); err != nil {
+		return err
+	}
+	return sig.verifyContent(input)
+}
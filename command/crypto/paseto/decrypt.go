@@ -0,0 +1,72 @@
+package paseto
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/paseto"
+)
+
+func decryptCommand() cli.Command {
+	return cli.Command{
+		Name:   "decrypt",
+		Action: cli.ActionFunc(decryptAction),
+		Usage:  "decrypt a v4.local token and return its claims",
+		UsageText: `**step crypto paseto decrypt** [- | <filename>]
+[**--iss**=<issuer>] [**--aud**=<audience>] [**--sub**=<subject>]
+[**--implicit**=<implicit>] [**--key**=<file>] [**--password-file**=<file>]`,
+		Description: `**step crypto paseto decrypt** reads a v4.local token from a file or STDIN;
+authenticates and decrypts it; checks that the issuer, audience and subject are
+in agreement with expectations; and outputs the claims and footer of the token
+as JSON on STDOUT. If decryption or validation fails a non-zero failure code is
+returned.
+
+For a token to be decrypted successfully:
+
+  * The token must be a well formed v4.local token
+  * The authentication tag must be successfully verified using the footer and
+    the <implicit> assertion, if given
+  * The <issuer> and <audience> must match the **iss** and **aud** claims in the token,
+    respectively
+  * The <subject>, if given, must match the **sub** claim in the token
+  * The token must not be expired and must be valid already (**exp** and **nbf** claims)
+
+For examples, see **step help crypto paseto**.`,
+		Flags: append(append([]cli.Flag{}, validationFlags...),
+			implicitFlag,
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the 256-bit symmetric JWK (or a JWK encrypted as a JWE
+payload) with which to decrypt the token.`,
+			},
+			flags.PasswordFile,
+			flags.SubtleHidden,
+			flags.InsecureHidden,
+		),
+	}
+}
+
+func decryptAction(ctx *cli.Context) error {
+	token, t, err := readToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	jwk, err := readKey(ctx, "enc")
+	if err != nil {
+		return err
+	}
+	key, err := localKey(jwk)
+	if err != nil {
+		return err
+	}
+
+	message, _, err := paseto.Decrypt(token, key, []byte(ctx.String("implicit")))
+	if err != nil {
+		return err
+	}
+	if _, err := validateClaims(ctx, message); err != nil {
+		return err
+	}
+	return printToken(t, message)
+}
@@ -0,0 +1,77 @@
+package paseto
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/paseto"
+)
+
+func encryptCommand() cli.Command {
+	return cli.Command{
+		Name:   "encrypt",
+		Action: cli.ActionFunc(encryptAction),
+		Usage:  "create an encrypted v4.local token",
+		UsageText: `**step crypto paseto encrypt** [- | <filename>]
+[**--iss**=<issuer>] [**--aud**=<audience>] [**--sub**=<subject>]
+[**--exp**=<time|duration>] [**--nbf**=<time|duration>] [**--iat**=<time|duration>]
+[**--jti**=<id>] [**--footer**=<footer>] [**--implicit**=<implicit>]
+[**--key**=<file>] [**--password-file**=<file>] [**--subtle**]`,
+		Description: `**step crypto paseto encrypt** creates a v4.local token encrypting a set of
+claims with a 256-bit symmetric key. Additional claims can be passed as a JSON
+object in a file or STDIN. The token will be written to STDOUT.
+
+Some optional arguments introduce subtle security considerations if omitted.
+These considerations should be carefully analyzed. Therefore, omitting <subtle>
+arguments requires the use of the **--subtle** flag as a misuse prevention
+mechanism.
+
+For examples, see **step help crypto paseto**.`,
+		Flags: append(append([]cli.Flag{}, claimsFlags...),
+			footerFlag,
+			implicitFlag,
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the 256-bit symmetric JWK (or a JWK encrypted as a JWE
+payload) with which to encrypt the token.`,
+			},
+			flags.PasswordFile,
+			flags.SubtleHidden,
+		),
+	}
+}
+
+func encryptAction(ctx *cli.Context) error {
+	claims, err := buildClaims(ctx)
+	if err != nil {
+		return err
+	}
+
+	jwk, err := readKey(ctx, "enc")
+	if err != nil {
+		return err
+	}
+	key, err := localKey(jwk)
+	if err != nil {
+		return err
+	}
+	f, err := footer(ctx, jwk)
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling claims")
+	}
+	token, err := paseto.Encrypt(key, message, f, []byte(ctx.String("implicit")))
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
@@ -0,0 +1,120 @@
+package paseto
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/paseto"
+)
+
+func inspectCommand() cli.Command {
+	return cli.Command{
+		Name:   "inspect",
+		Action: cli.ActionFunc(inspectAction),
+		Usage:  "return the decoded token without verification",
+		UsageText: `**step crypto paseto inspect** [- | <filename>]
+[**--key**=<file>] [**--implicit**=<implicit>] [**--password-file**=<file>]
+**--insecure**`,
+		Description: `**step crypto paseto inspect** reads a token from a file or STDIN, decodes
+it, and outputs its version, purpose, footer and, for v4.public tokens, its
+claims as a JSON object. The claims of a v4.local token are encrypted and are
+only displayed if a key is given.
+
+If **--key** is given, the token is also verified or decrypted using the
+<implicit> assertion, and the result is displayed in the **verified** member.
+This is useful to check if a token is bound to an implicit assertion. The
+claims are never validated.
+
+Since this command does not validate the token you must pass **--insecure** as
+a misuse prevention mechanism.
+
+For examples, see **step help crypto paseto**.`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the Ed25519 key or the symmetric JWK used to verify or
+decrypt the token.`,
+			},
+			implicitFlag,
+			flags.PasswordFile,
+			flags.InsecureHidden,
+		},
+	}
+}
+
+func inspectAction(ctx *cli.Context) error {
+	if !ctx.Bool("insecure") {
+		return errs.InsecureCommand(ctx)
+	}
+	if ctx.IsSet("implicit") && ctx.String("key") == "" {
+		return errs.RequiredWithFlag(ctx, "implicit", "key")
+	}
+
+	token, t, err := readToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	v := tokenJSON{
+		Version: t.Version,
+		Purpose: t.Purpose,
+		Footer:  jsonValue(t.Footer),
+	}
+	if t.Purpose == paseto.PurposePublic {
+		message, _ := t.Message()
+		v.Payload = jsonValue(message)
+	}
+
+	if ctx.String("key") != "" {
+		message, err := open(ctx, token, t)
+		verified := err == nil
+		v.Verified = &verified
+		if err != nil {
+			v.Error = err.Error()
+		} else {
+			v.Payload = jsonValue(message)
+		}
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling token")
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// open verifies or decrypts the token using the --key and --implicit flags and
+// returns its message.
+func open(ctx *cli.Context, token string, t *paseto.Token) ([]byte, error) {
+	implicit := []byte(ctx.String("implicit"))
+	if t.Purpose == paseto.PurposePublic {
+		jwk, err := readKey(ctx, "sig")
+		if err != nil {
+			return nil, err
+		}
+		key, err := publicKey(jwk)
+		if err != nil {
+			return nil, err
+		}
+		message, _, err := paseto.Verify(token, key, implicit)
+		return message, err
+	}
+
+	jwk, err := readKey(ctx, "enc")
+	if err != nil {
+		return nil, err
+	}
+	key, err := localKey(jwk)
+	if err != nil {
+		return nil, err
+	}
+	message, _, err := paseto.Decrypt(token, key, implicit)
+	return message, err
+}
@@ -0,0 +1,444 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/jose"
+	"go.step.sm/crypto/randutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/paseto"
+	"github.com/smallstep/cli/utils"
+)
+
+// Command returns the cli.Command for paseto and related subcommands.
+func Command() cli.Command {
+	return cli.Command{
+		Name:      "paseto",
+		Usage:     "sign, verify and encrypt data using Platform-Agnostic Security Tokens (PASETO)",
+		UsageText: "step crypto paseto <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `Platform-Agnostic Security Tokens (PASETO) are a compact means of
+representing claims to be transferred between two parties. Unlike JWTs, each
+version of PASETO defines a single cryptographic suite, so the algorithm of a
+token is never negotiated.
+
+The **step crypto paseto** command group supports version 4 tokens:
+
+  * v4.public tokens are signed with an Ed25519 key using **step crypto paseto sign**
+    and verified using **step crypto paseto verify**.
+  * v4.local tokens are encrypted with XChaCha20 and authenticated with keyed
+    BLAKE2b using a 256-bit symmetric key with **step crypto paseto encrypt**,
+    and decrypted using **step crypto paseto decrypt**.
+
+The payload of a token is a JSON object with the claims. The registered claims
+**iss**, **sub**, **aud** and **jti** are strings, and the time claims **exp**,
+**nbf** and **iat** are RFC 3339 timestamps.
+
+Tokens can carry an unencrypted but authenticated footer, usually with the ID
+of the key, and can be bound to an implicit assertion: data that is not part of
+the token, but that must be provided to verify or decrypt it.
+
+Keys are read the same way that **step crypto jwt** reads them: an OKP JWK or
+PEM file, like the ones created by **step crypto keypair --kty OKP**, a JWK
+encrypted as a JWE payload, or a symmetric JWK for v4.local tokens.
+
+## EXAMPLES
+
+Create an Ed25519 key pair and a signed token:
+'''
+$ step crypto keypair ed.pub ed.key --kty OKP --crv Ed25519 --no-password --insecure
+$ step crypto paseto sign --key ed.key --iss "joe@example.com" \
+      --aud "https://example.com" --sub auth --exp 1h
+v4.public.eyJhdWQiOiJodHRwczovL2V4YW1wbGUuY29tIiwiZXhwIjoiMjAyNi0xMC0xOFQxM...
+'''
+
+Verify the previous token:
+'''
+$ echo $TOKEN | step crypto paseto verify --key ed.pub \
+      --iss "joe@example.com" --aud "https://example.com"
+{
+  "version": "v4",
+  "purpose": "public",
+  "payload": {
+    "aud": "https://example.com",
+    "exp": "2026-10-18T13:04:05Z",
+    "iat": "2026-10-18T12:04:05Z",
+    "iss": "joe@example.com",
+    "jti": "2d7b1c2b8aeb3e3b9ec0a4ee2f8d1b6f5a3f1c3a5d9e2b7c8f0a1d2e3f4b5c6d",
+    "nbf": "2026-10-18T12:04:05Z",
+    "sub": "auth"
+  }
+}
+'''
+
+Create a signed token with a footer and an implicit assertion:
+'''
+$ step crypto paseto sign --key ed.key --iss "joe@example.com" \
+      --aud "https://example.com" --sub auth --exp 1h \
+      --footer '{"kid":"my-key"}' --implicit "user-1234"
+'''
+
+Encrypt and decrypt a token using a 256-bit symmetric JWK:
+'''
+$ step crypto jwk create local.json local.json --kty oct --size 32 --use enc --insecure --no-password
+$ step crypto paseto encrypt --key local.json --subtle > token.txt
+$ step crypto paseto decrypt --key local.json --subtle token.txt
+'''
+
+Read the footer and the claims of a token without verifying it:
+'''
+$ echo $TOKEN | step crypto paseto inspect --insecure
+'''`,
+		Subcommands: cli.Commands{
+			signCommand(),
+			verifyCommand(),
+			encryptCommand(),
+			decryptCommand(),
+			inspectCommand(),
+		},
+	}
+}
+
+var footerFlag = cli.StringFlag{
+	Name: "footer",
+	Usage: `The <footer> of the token. The footer is authenticated but not encrypted, and
+it is usually a JSON object with the ID of the key. If not provided and the key
+has a **"kid"**, the footer will be a JSON object with it.`,
+}
+
+var implicitFlag = cli.StringFlag{
+	Name: "implicit",
+	Usage: `The <implicit> assertion bound to the token. The implicit assertion is not
+part of the token, and the same value must be provided to verify or decrypt
+it.`,
+}
+
+var claimsFlags = []cli.Flag{
+	cli.StringFlag{
+		Name: "iss, issuer",
+		Usage: `The issuer of this token. The processing of this claim is generally
+application specific.
+
+: <issuer> is a case-sensitive string.`,
+	},
+	cli.StringFlag{
+		Name: "aud, audience",
+		Usage: `The intended recipient of the token.
+
+: <audience> is a case-sensitive string.`,
+	},
+	cli.StringFlag{
+		Name: "sub, subject",
+		Usage: `The subject of this token. The claims are normally interpreted as statements
+about this subject.
+
+: <subject> is a case-sensitive string.`,
+	},
+	cli.StringFlag{
+		Name: "exp, expiration",
+		Usage: `The <time|duration> after which the token must not be accepted. The time
+must be in RFC 3339 format, and the duration is a sequence of decimal numbers,
+each with optional fraction and a unit suffix, such as "300ms", "-1.5h" or
+"2h45m". Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".`,
+	},
+	cli.StringFlag{
+		Name: "nbf, not-before",
+		Usage: `The <time|duration> before which the token must not be accepted. If not
+provided, the current time is used.`,
+	},
+	cli.StringFlag{
+		Name: "iat, issued-at",
+		Usage: `The <time|duration> at which the token was issued. If not provided, the
+current time is used.`,
+	},
+	cli.StringFlag{
+		Name: "jti, id",
+		Usage: `A unique identifier for the token. If not provided, a random identifier is
+generated.
+
+: <id> is a case-sensitive string.`,
+	},
+}
+
+var validationFlags = []cli.Flag{
+	cli.StringFlag{
+		Name: "iss, issuer",
+		Usage: `The issuer of this token. The <issuer> must match the value of the **iss**
+claim in the token. <issuer> is a case-sensitive string. Required unless disabled
+with the **--subtle** flag.`,
+	},
+	cli.StringFlag{
+		Name: "aud, audience",
+		Usage: `The identity of the principal running this command. The <audience> must
+match the value of the **aud** claim in the token. <audience> is a
+case-sensitive string. Required unless disabled with the **--subtle** flag.`,
+	},
+	cli.StringFlag{
+		Name: "sub, subject",
+		Usage: `The subject of this token. If set, the <subject> must match the value of the
+**sub** claim in the token.`,
+	},
+	cli.BoolFlag{
+		Name:   "no-exp-check",
+		Hidden: true,
+	},
+}
+
+// readInput reads the contents of the given file, or STDIN if the filename is
+// empty or "-".
+func readInput(filename string) ([]byte, error) {
+	switch filename {
+	case "":
+		st, err := os.Stdin.Stat()
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading data")
+		}
+		if st.Size() == 0 && st.Mode()&os.ModeNamedPipe == 0 {
+			return []byte{}, nil
+		}
+		return utils.ReadAll(os.Stdin)
+	case "-":
+		return utils.ReadAll(os.Stdin)
+	default:
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, errs.FileError(err, filename)
+		}
+		return b, nil
+	}
+}
+
+// readToken reads the token in the first argument or STDIN.
+func readToken(ctx *cli.Context) (string, *paseto.Token, error) {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return "", nil, err
+	}
+	b, err := readInput(ctx.Args().First())
+	if err != nil {
+		return "", nil, err
+	}
+	t, err := paseto.Parse(string(b))
+	if err != nil {
+		return "", nil, err
+	}
+	return string(b), t, nil
+}
+
+// readKey reads the key in the --key flag with the given use.
+func readKey(ctx *cli.Context, use string) (*jose.JSONWebKey, error) {
+	key := ctx.String("key")
+	if key == "" {
+		return nil, errs.RequiredFlag(ctx, "key")
+	}
+
+	options := []jose.Option{jose.WithUse(use)}
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		options = append(options, jose.WithPasswordFile(passwordFile))
+	}
+	jwk, err := jose.ReadKey(key, options...)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Use != use && jwk.Use != "" {
+		return nil, errors.Errorf("invalid jwk use: found '%s', expecting '%s'", jwk.Use, use)
+	}
+	return jwk, nil
+}
+
+// publicKey returns the Ed25519 public key of the given JWK.
+func publicKey(jwk *jose.JSONWebKey) (ed25519.PublicKey, error) {
+	switch k := jwk.Key.(type) {
+	case ed25519.PublicKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k.Public().(ed25519.PublicKey), nil
+	default:
+		return nil, errors.Errorf("invalid key type %T: v4.public tokens require an Ed25519 key", k)
+	}
+}
+
+// localKey returns the symmetric key of the given JWK.
+func localKey(jwk *jose.JSONWebKey) ([]byte, error) {
+	k, ok := jwk.Key.([]byte)
+	if !ok {
+		return nil, errors.Errorf("invalid key type %T: v4.local tokens require a symmetric key", jwk.Key)
+	}
+	if len(k) != paseto.KeySize {
+		return nil, errors.Errorf("invalid key size %d: v4.local tokens require a %d byte key", len(k), paseto.KeySize)
+	}
+	return k, nil
+}
+
+// footer returns the value of the --footer flag, or a footer with the kid of
+// the key.
+func footer(ctx *cli.Context, jwk *jose.JSONWebKey) ([]byte, error) {
+	if ctx.IsSet("footer") {
+		return []byte(ctx.String("footer")), nil
+	}
+	if jwk.KeyID == "" {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]string{"kid": jwk.KeyID})
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling footer")
+	}
+	return b, nil
+}
+
+// parseTime parses a time claim flag.
+func parseTime(ctx *cli.Context, name string) (time.Time, bool, error) {
+	s := ctx.String(name)
+	if s == "" {
+		return time.Time{}, false, nil
+	}
+	t, ok := flags.ParseTimeOrDuration(s)
+	if !ok {
+		return time.Time{}, false, errs.InvalidFlagValue(ctx, name, s, "")
+	}
+	return t, true, nil
+}
+
+// buildClaims returns the claims of a new token using the additional claims
+// in the first argument or STDIN, and the claims flags.
+func buildClaims(ctx *cli.Context) (paseto.Claims, error) {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return nil, err
+	}
+
+	claims := paseto.Claims{}
+	b, err := readInput(ctx.Args().First())
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 {
+		if claims, err = paseto.ParseClaims(b); err != nil {
+			return nil, errors.Wrap(err, "error parsing payload")
+		}
+	}
+
+	for _, name := range []string{"iss", "aud", "sub", "jti"} {
+		if v := ctx.String(name); v != "" {
+			claims[name] = v
+		}
+	}
+	if _, ok := claims["jti"]; !ok {
+		jti, err := randutil.Hex(64)
+		if err != nil {
+			return nil, errors.Wrap(err, "error generating random token ID")
+		}
+		claims["jti"] = jti
+	}
+
+	now := time.Now()
+	for _, name := range []string{"exp", "nbf", "iat"} {
+		t, ok, err := parseTime(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			claims.SetTime(name, t)
+		} else if _, ok := claims[name]; !ok && name != "exp" {
+			claims.SetTime(name, now)
+		}
+	}
+
+	// Validate recommended claims
+	if !ctx.Bool("subtle") {
+		exp, hasExp, err := claims.Time("exp")
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case claims["iss"] == nil:
+			return nil, errs.RequiredUnlessSubtleFlag(ctx, "iss")
+		case claims["aud"] == nil:
+			return nil, errs.RequiredUnlessSubtleFlag(ctx, "aud")
+		case claims["sub"] == nil:
+			return nil, errs.RequiredUnlessSubtleFlag(ctx, "sub")
+		case !hasExp:
+			return nil, errs.RequiredUnlessSubtleFlag(ctx, "exp")
+		case exp.Before(now):
+			return nil, errors.New("flag '--exp' must be in the future unless '--subtle' is used")
+		}
+	}
+	return claims, nil
+}
+
+// validateClaims validates the claims in the message using the validation
+// flags.
+func validateClaims(ctx *cli.Context, message []byte) (paseto.Claims, error) {
+	iss := ctx.String("iss")
+	aud := ctx.String("aud")
+	if !ctx.Bool("subtle") {
+		switch {
+		case iss == "":
+			return nil, errs.RequiredUnlessSubtleFlag(ctx, "iss")
+		case aud == "":
+			return nil, errs.RequiredUnlessSubtleFlag(ctx, "aud")
+		}
+	}
+	if ctx.Bool("no-exp-check") && !ctx.Bool("insecure") {
+		return nil, errs.RequiredInsecureFlag(ctx, "no-exp-check")
+	}
+
+	claims, err := paseto.ParseClaims(message)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Bool("no-exp-check") {
+		delete(claims, "exp")
+	}
+	if err := claims.Validate(paseto.Expected{
+		Issuer:   iss,
+		Subject:  ctx.String("sub"),
+		Audience: aud,
+	}); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+type tokenJSON struct {
+	Version  string      `json:"version"`
+	Purpose  string      `json:"purpose"`
+	Payload  interface{} `json:"payload,omitempty"`
+	Footer   interface{} `json:"footer,omitempty"`
+	Verified *bool       `json:"verified,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// jsonValue returns b as a JSON value if it is valid JSON, or as a string
+// otherwise.
+func jsonValue(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	return string(b)
+}
+
+// printToken prints the JSON representation of the token with the given
+// message.
+func printToken(t *paseto.Token, message []byte) error {
+	b, err := json.MarshalIndent(tokenJSON{
+		Version: t.Version,
+		Purpose: t.Purpose,
+		Payload: jsonValue(message),
+		Footer:  jsonValue(t.Footer),
+	}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling token")
+	}
+	fmt.Println(string(b))
+	return nil
+}
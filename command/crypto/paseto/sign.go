@@ -0,0 +1,79 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/paseto"
+)
+
+func signCommand() cli.Command {
+	return cli.Command{
+		Name:   "sign",
+		Action: cli.ActionFunc(signAction),
+		Usage:  "create a signed v4.public token",
+		UsageText: `**step crypto paseto sign** [- | <filename>]
+[**--iss**=<issuer>] [**--aud**=<audience>] [**--sub**=<subject>]
+[**--exp**=<time|duration>] [**--nbf**=<time|duration>] [**--iat**=<time|duration>]
+[**--jti**=<id>] [**--footer**=<footer>] [**--implicit**=<implicit>]
+[**--key**=<file>] [**--password-file**=<file>] [**--subtle**]`,
+		Description: `**step crypto paseto sign** creates a v4.public token signing a set of
+claims with an Ed25519 key. Additional claims can be passed as a JSON object in
+a file or STDIN. The token will be written to STDOUT.
+
+Some optional arguments introduce subtle security considerations if omitted.
+These considerations should be carefully analyzed. Therefore, omitting <subtle>
+arguments requires the use of the **--subtle** flag as a misuse prevention
+mechanism.
+
+For examples, see **step help crypto paseto**.`,
+		Flags: append(append([]cli.Flag{}, claimsFlags...),
+			footerFlag,
+			implicitFlag,
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the Ed25519 private key with which to sign the token.
+The key can be a private OKP JWK (or a JWK encrypted as a JWE payload), or a
+PEM encoded private key.`,
+			},
+			flags.PasswordFile,
+			flags.SubtleHidden,
+		),
+	}
+}
+
+func signAction(ctx *cli.Context) error {
+	claims, err := buildClaims(ctx)
+	if err != nil {
+		return err
+	}
+
+	jwk, err := readKey(ctx, "sig")
+	if err != nil {
+		return err
+	}
+	key, ok := jwk.Key.(ed25519.PrivateKey)
+	if !ok {
+		return errors.Errorf("invalid key type %T: v4.public tokens require an Ed25519 private key", jwk.Key)
+	}
+	f, err := footer(ctx, jwk)
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling claims")
+	}
+	token, err := paseto.Sign(key, message, f, []byte(ctx.String("implicit")))
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
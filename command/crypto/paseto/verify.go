@@ -0,0 +1,73 @@
+package paseto
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/paseto"
+)
+
+func verifyCommand() cli.Command {
+	return cli.Command{
+		Name:   "verify",
+		Action: cli.ActionFunc(verifyAction),
+		Usage:  "verify a v4.public token and return its claims",
+		UsageText: `**step crypto paseto verify** [- | <filename>]
+[**--iss**=<issuer>] [**--aud**=<audience>] [**--sub**=<subject>]
+[**--implicit**=<implicit>] [**--key**=<file>] [**--password-file**=<file>]`,
+		Description: `**step crypto paseto verify** reads a v4.public token from a file or STDIN;
+verifies its signature; checks that the issuer, audience and subject are in
+agreement with expectations; and outputs the claims and footer of the token as
+JSON on STDOUT. If verification fails a non-zero failure code is returned. If
+verification succeeds the command returns 0.
+
+For a token to be verified successfully:
+
+  * The token must be a well formed v4.public token
+  * The signature must be successfully verified using the footer and the
+    <implicit> assertion, if given
+  * The <issuer> and <audience> must match the **iss** and **aud** claims in the token,
+    respectively
+  * The <subject>, if given, must match the **sub** claim in the token
+  * The token must not be expired and must be valid already (**exp** and **nbf** claims)
+
+For examples, see **step help crypto paseto**.`,
+		Flags: append(append([]cli.Flag{}, validationFlags...),
+			implicitFlag,
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The <file> containing the Ed25519 key with which to verify the token. The
+key can be a public or private OKP JWK (or a JWK encrypted as a JWE payload),
+or a public or private PEM.`,
+			},
+			flags.PasswordFile,
+			flags.SubtleHidden,
+			flags.InsecureHidden,
+		),
+	}
+}
+
+func verifyAction(ctx *cli.Context) error {
+	token, t, err := readToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	jwk, err := readKey(ctx, "sig")
+	if err != nil {
+		return err
+	}
+	key, err := publicKey(jwk)
+	if err != nil {
+		return err
+	}
+
+	message, _, err := paseto.Verify(token, key, []byte(ctx.String("implicit")))
+	if err != nil {
+		return err
+	}
+	if _, err := validateClaims(ctx, message); err != nil {
+		return err
+	}
+	return printToken(t, message)
+}
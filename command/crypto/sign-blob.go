@@ -0,0 +1,237 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cryptoutil"
+	"github.com/smallstep/cli/internal/sigstore"
+)
+
+func signBlobCommand() cli.Command {
+	return cli.Command{
+		Name:   "sign-blob",
+		Action: command.ActionFunc(signBlobAction),
+		Usage:  "sign a file and optionally write a Sigstore bundle",
+		UsageText: `**step crypto sign-blob** [<file>] **--key**=<key-file>
+[**--kms**=<uri>] [**--password-file**=<file>] [**--cert**=<file>]
+[**--tsa**=<url>] [**--bundle**=<format>] [**--output-file**=<file>]`,
+		Description: `**step crypto sign-blob** signs a file with a private key in a file or in a
+KMS. By default it prints the base64 encoded signature. With **--bundle
+sigstore** it writes a Sigstore bundle with the message signature, the public
+key hint or the certificate chain, and optionally an RFC 3161 timestamp of the
+signature. The bundle can be verified offline with **step crypto verify-blob**
+and by other tools that support the Sigstore bundle format.
+
+ECDSA keys sign the SHA-256, SHA-384 or SHA-512 digest of the file, depending
+on the curve, with an ASN.1 encoded signature. RSA keys sign the SHA-256 digest
+using PKCS #1 v1.5, and Ed25519 keys sign the file.
+
+If the bundle does not have a certificate, it uses the version 0.3 of the
+Sigstore bundle format with a hint of the public key: the base64 encoded
+SHA-256 digest of the DER encoded public key. Bundles with a single certificate
+use the version 0.3 of the format, and bundles with a certificate chain use the
+version 0.2. Transparency log entries are not supported.
+
+## POSITIONAL ARGUMENTS
+
+<file>
+:  The file to sign. If not provided, the data is read from STDIN.
+
+## EXAMPLES
+
+Sign a file and print the base64 signature:
+'''
+$ step crypto sign-blob --key priv.key artifact.tar.gz
+MEUCIQD...
+'''
+
+Sign a file with a certificate from **step ca certificate** and write a Sigstore bundle:
+'''
+$ step ca certificate --kty EC releases@example.com releases.crt releases.key
+$ step crypto sign-blob --key releases.key --cert releases.crt \
+  --bundle sigstore --output-file artifact.sigstore.json artifact.tar.gz
+'''
+
+Add an RFC 3161 timestamp to the bundle:
+'''
+$ step crypto sign-blob --key releases.key --cert releases.crt \
+  --tsa https://freetsa.org/tsr \
+  --bundle sigstore --output-file artifact.sigstore.json artifact.tar.gz
+'''
+
+Sign a file with a key in a KMS and the certificate stored along the key:
+'''
+$ step crypto sign-blob --kms 'pkcs11:module-path=/usr/local/lib/softhsm/libsofthsm2.so;token=smallstep?pin-value=password' \
+  --key 'pkcs11:id=1000' --cert 'pkcs11:id=1000' \
+  --bundle sigstore artifact.tar.gz
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "key",
+				Usage: "The path to the <file> or the KMS URI of the private key used to sign.",
+			},
+			flags.KMSUri,
+			flags.PasswordFile,
+			cli.StringFlag{
+				Name: "cert",
+				Usage: `The path to the <file> with the certificate or the certificate chain of the
+signing key. If **--kms** is used, <file> can be the name of a certificate in
+the KMS.`,
+			},
+			cli.StringFlag{
+				Name:  "tsa",
+				Usage: `The <url> of an RFC 3161 timestamp authority used to timestamp the signature.`,
+			},
+			cli.StringFlag{
+				Name: "bundle",
+				Usage: `The <format> of the output bundle.
+
+: <format> must be one of:
+
+    **sigstore**
+    :  A Sigstore bundle in JSON format`,
+			},
+			cli.StringFlag{
+				Name:  "output-file",
+				Usage: "The destination <file> of the signature or the bundle.",
+			},
+			flags.Force,
+		},
+	}
+}
+
+func signBlobAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	keyFile := ctx.String("key")
+	if keyFile == "" {
+		return errs.RequiredFlag(ctx, "key")
+	}
+	switch format := ctx.String("bundle"); format {
+	case "":
+		if ctx.String("cert") != "" {
+			return errs.RequiredWithFlag(ctx, "cert", "bundle")
+		}
+		if ctx.String("tsa") != "" {
+			return errs.RequiredWithFlag(ctx, "tsa", "bundle")
+		}
+	case "sigstore":
+	default:
+		return errs.InvalidFlagValue(ctx, "bundle", format, "sigstore")
+	}
+
+	kms := ctx.String("kms")
+	var opts []pemutil.Options
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		opts = append(opts, pemutil.WithPasswordFile(passwordFile))
+	}
+	signer, err := cryptoutil.CreateSigner(kms, keyFile, opts...)
+	if err != nil {
+		return err
+	}
+
+	var chain []*x509.Certificate
+	if certFile := ctx.String("cert"); certFile != "" {
+		if cryptoutil.IsKMS(kms) {
+			chain, err = cryptoutil.LoadCertificate(kms, certFile)
+		} else {
+			chain, err = pemutil.ReadCertificateBundle(certFile)
+		}
+		if err != nil {
+			return err
+		}
+		if err := keyutil.VerifyPair(chain[0].PublicKey, signer); err != nil {
+			return errors.Wrapf(err, "certificate %s does not match the signing key", certFile)
+		}
+	}
+
+	var r io.Reader
+	switch name := ctx.Args().First(); name {
+	case "", "-":
+		r = os.Stdin
+	default:
+		f, err := os.Open(name)
+		if err != nil {
+			return errs.FileError(err, name)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	sig, err := sigstore.Sign(signer, r)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if ctx.String("bundle") == "" {
+		out = []byte(base64.StdEncoding.EncodeToString(sig.Signature) + "\n")
+	} else {
+		var timestamps [][]byte
+		if tsaURL := ctx.String("tsa"); tsaURL != "" {
+			token, err := sigstore.RequestTimestamp(tsaURL, sig.Signature)
+			if err != nil {
+				return err
+			}
+			timestamps = append(timestamps, token)
+		}
+		bundle, err := sigstore.NewBundle(sig, signer.Public(), chain, timestamps)
+		if err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling bundle")
+		}
+		out = append(b, '\n')
+	}
+
+	if outFile := ctx.String("output-file"); outFile != "" {
+		if err := fileutil.WriteFile(outFile, out, 0o644); err != nil {
+			return errs.FileError(err, outFile)
+		}
+		ui.Printf("The signature has been saved in %s.\n", outFile)
+		return nil
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+// blobPublicKey returns the public key used to verify a blob, from a file or a
+// KMS.
+func blobPublicKey(kms, name string) (crypto.PublicKey, error) {
+	if cryptoutil.IsKMS(kms) {
+		return cryptoutil.PublicKey(kms, name)
+	}
+	key, err := pemutil.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case crypto.Signer:
+		return k.Public(), nil
+	case *x509.Certificate:
+		return k.PublicKey, nil
+	default:
+		return k, nil
+	}
+}
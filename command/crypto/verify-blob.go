@@ -0,0 +1,237 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/sigstore"
+)
+
+func verifyBlobCommand() cli.Command {
+	return cli.Command{
+		Name:   "verify-blob",
+		Action: command.ActionFunc(verifyBlobAction),
+		Usage:  "verify the signature or the Sigstore bundle of a file",
+		UsageText: `**step crypto verify-blob** [<file>]
+(**--bundle**=<file> | **--signature**=<base64>)
+[**--key**=<key-file>] [**--kms**=<uri>] [**--roots**=<file>]
+[**--intermediates**=<file>] [**--tsa-roots**=<file>]`,
+		Description: `**step crypto verify-blob** verifies a file with a signature or a Sigstore
+bundle created by **step crypto sign-blob**, or by other tools supporting the
+Sigstore bundle format. The verification is done offline; transparency log
+entries in the bundle are ignored.
+
+If the bundle has a certificate, the certificate chain must be valid using the
+root certificates in **--roots**. If **--tsa-roots** is given, the bundle must
+have RFC 3161 timestamps signed by a timestamp authority that chains to those
+roots, and the signing certificate must be valid at the time of each
+timestamp; otherwise the signing certificate must be valid at the current time.
+
+If the bundle does not have a certificate, or **--signature** is used, the
+public key in **--key** is used to verify the signature.
+
+On success, a JSON summary of the verification is printed.
+
+## POSITIONAL ARGUMENTS
+
+<file>
+:  The file to verify. If not provided, the data is read from STDIN.
+
+## EXAMPLES
+
+Verify a file with a base64 signature:
+'''
+$ step crypto verify-blob --key pub.key --signature "MEUCIQD...=" artifact.tar.gz
+'''
+
+Verify a file with a Sigstore bundle signed with a public key:
+'''
+$ step crypto verify-blob --key pub.key --bundle artifact.sigstore.json artifact.tar.gz
+'''
+
+Verify a file with a Sigstore bundle signed with a certificate from **step ca certificate**:
+'''
+$ step crypto verify-blob --roots $(step path)/certs/root_ca.crt \
+  --bundle artifact.sigstore.json artifact.tar.gz
+{
+  "verified": true,
+  "mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+  "subject": "releases@example.com",
+  "emailAddresses": ["releases@example.com"],
+  ...
+}
+'''
+
+Verify a bundle with an RFC 3161 timestamp, the signing certificate can be
+expired but it must be valid at the time the signature was timestamped:
+'''
+$ step crypto verify-blob --roots root_ca.crt --tsa-roots tsa_root.crt \
+  --bundle artifact.sigstore.json artifact.tar.gz
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "bundle",
+				Usage: "The <file> with the Sigstore bundle.",
+			},
+			cli.StringFlag{
+				Name:  "signature,sig",
+				Usage: "The <base64> version of the signature.",
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The path to the <file> or the KMS URI of the public key used to verify. The
+<file> can also be a private key or a certificate.`,
+			},
+			flags.KMSUri,
+			cli.StringFlag{
+				Name:  "roots",
+				Usage: `The path to the <file> with the root certificates used to verify the signing certificate.`,
+			},
+			cli.StringFlag{
+				Name:  "intermediates",
+				Usage: `The path to the <file> with additional intermediate certificates.`,
+			},
+			cli.StringFlag{
+				Name:  "tsa-roots",
+				Usage: `The path to the <file> with the root certificates of the timestamp authorities.`,
+			},
+		},
+	}
+}
+
+type verifyBlobResult struct {
+	Verified       bool        `json:"verified"`
+	MediaType      string      `json:"mediaType,omitempty"`
+	Subject        string      `json:"subject,omitempty"`
+	Issuer         string      `json:"issuer,omitempty"`
+	SerialNumber   string      `json:"serialNumber,omitempty"`
+	DNSNames       []string    `json:"dnsNames,omitempty"`
+	EmailAddresses []string    `json:"emailAddresses,omitempty"`
+	URIs           []string    `json:"uris,omitempty"`
+	Timestamps     []time.Time `json:"timestamps,omitempty"`
+}
+
+func verifyBlobAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	bundleFile := ctx.String("bundle")
+	signature := ctx.String("signature")
+	switch {
+	case bundleFile == "" && signature == "":
+		return errs.RequiredOrFlag(ctx, "bundle", "signature")
+	case bundleFile != "" && signature != "":
+		return errs.MutuallyExclusiveFlags(ctx, "bundle", "signature")
+	case signature != "" && ctx.String("key") == "":
+		return errs.RequiredWithFlag(ctx, "signature", "key")
+	}
+
+	var bundle *sigstore.Bundle
+	if bundleFile != "" {
+		b, err := os.ReadFile(bundleFile)
+		if err != nil {
+			return errs.FileError(err, bundleFile)
+		}
+		if bundle, err = sigstore.Parse(b); err != nil {
+			return err
+		}
+	} else {
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+		if err != nil {
+			return errors.Wrap(err, "error decoding signature")
+		}
+		bundle = &sigstore.Bundle{
+			VerificationMaterial: &sigstore.VerificationMaterial{},
+			MessageSignature:     &sigstore.MessageSignature{Signature: sig},
+		}
+	}
+
+	var err error
+	var opts sigstore.VerifyOptions
+	if keyFile := ctx.String("key"); keyFile != "" {
+		if opts.PublicKey, err = blobPublicKey(ctx.String("kms"), keyFile); err != nil {
+			return err
+		}
+	}
+	if opts.Roots, err = readCertPool(ctx.String("roots")); err != nil {
+		return err
+	}
+	if opts.Intermediates, err = readCertPool(ctx.String("intermediates")); err != nil {
+		return err
+	}
+	if opts.TSARoots, err = readCertPool(ctx.String("tsa-roots")); err != nil {
+		return err
+	}
+
+	var r io.Reader
+	switch name := ctx.Args().First(); name {
+	case "", "-":
+		r = os.Stdin
+	default:
+		f, err := os.Open(name)
+		if err != nil {
+			return errs.FileError(err, name)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	res, err := bundle.Verify(r, opts)
+	if err != nil {
+		return err
+	}
+
+	out := verifyBlobResult{
+		Verified:   true,
+		MediaType:  bundle.MediaType,
+		Timestamps: res.Timestamps,
+	}
+	if crt := res.Certificate; crt != nil {
+		out.Subject = crt.Subject.CommonName
+		out.Issuer = crt.Issuer.CommonName
+		out.SerialNumber = crt.SerialNumber.String()
+		out.DNSNames = crt.DNSNames
+		out.EmailAddresses = crt.EmailAddresses
+		for _, u := range crt.URIs {
+			out.URIs = append(out.URIs, u.String())
+		}
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling result")
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// readCertPool returns a pool with the certificates in the given file. It
+// returns nil if the filename is empty.
+func readCertPool(filename string) (*x509.CertPool, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	certs, err := pemutil.ReadCertificateBundle(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, crt := range certs {
+		pool.AddCert(crt)
+	}
+	return pool, nil
+}
@@ -0,0 +1,183 @@
+package ocsp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/pemutil"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/ocsputil"
+	"github.com/smallstep/cli/utils"
+)
+
+func inspectCommand() cli.Command {
+	return cli.Command{
+		Name:   "inspect",
+		Action: command.ActionFunc(inspectAction),
+		Usage:  "print the details of an OCSP response",
+		UsageText: `**step ocsp inspect** <file|url> [**--issuer**=<file>] [**--cert**=<file>]
+[**--format**=<format>] [**--servername**=<servername>] [**--roots**=<root-bundle>]
+[**--insecure**]`,
+		Description: `**step ocsp inspect** prints the details of an OCSP response in a file, or the
+OCSP response stapled by a TLS server.
+
+The signature of a response in a file is verified if **--issuer** is passed.
+The signature of a stapled response is verified using the issuer sent by the
+server, or the one passed with **--issuer**.
+
+## POSITIONAL ARGUMENTS
+
+<file|url>
+:  The path to a file with an OCSP response in DER, PEM or base64 format, or
+the URL of a TLS server. URLs must start with https:// or tls://.
+
+## EXAMPLES
+
+Inspect an OCSP response:
+'''
+$ step ocsp inspect response.der
+'''
+
+Inspect and verify an OCSP response for a certificate:
+'''
+$ step ocsp inspect --issuer intermediate.crt --cert leaf.crt response.der
+'''
+
+Inspect the OCSP response stapled by a server:
+'''
+$ step ocsp inspect https://smallstep.com
+'''
+
+Inspect the OCSP response stapled by a server in JSON:
+'''
+$ step ocsp inspect --format json tls://127.0.0.1:8443 --servername example.com
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "issuer",
+				Usage: `The certificate <file> of the issuer used to verify the response.`,
+			},
+			cli.StringFlag{
+				Name:  "cert",
+				Usage: `The certificate <file> the response must be for.`,
+			},
+			formatFlag,
+			flags.ServerName,
+			cli.StringFlag{
+				Name: "roots",
+				Usage: `Root certificate(s) that will be used to verify the
+authenticity of the remote server.`,
+			},
+			flags.Insecure,
+		},
+	}
+}
+
+func inspectAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 1); err != nil {
+		return err
+	}
+
+	format := ctx.String("format")
+	if format != "text" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	}
+
+	var cert, issuer *x509.Certificate
+	if filename := ctx.String("cert"); filename != "" {
+		crt, err := pemutil.ReadCertificate(filename)
+		if err != nil {
+			return err
+		}
+		cert = crt
+	}
+	if filename := ctx.String("issuer"); filename != "" {
+		crt, err := pemutil.ReadCertificate(filename)
+		if err != nil {
+			return err
+		}
+		issuer = crt
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	name := ctx.Args().First()
+	if isURL(name) {
+		var peers []*x509.Certificate
+		b, peers, err = getStapledResponse(name, ctx.String("servername"), ctx.String("roots"), ctx.Bool("insecure"))
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			cert = peers[0]
+		}
+		if issuer == nil && len(peers) > 1 {
+			issuer = peers[1]
+		}
+	} else {
+		if b, err = utils.ReadFile(name); err != nil {
+			return err
+		}
+		if b, err = ocsputil.Decode(b); err != nil {
+			return err
+		}
+	}
+
+	resp, err := parseResponse(b, cert, issuer)
+	if err != nil {
+		return err
+	}
+	return printResponse(newResponseInfo(resp, ocsputil.ResponseNonce(resp), issuer != nil), format)
+}
+
+func isURL(s string) bool {
+	s = strings.ToLower(s)
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "tls://")
+}
+
+// getStapledResponse connects to a TLS server and returns the OCSP response
+// stapled by the server and the server certificates.
+func getStapledResponse(rawURL, serverName, roots string, insecure bool) ([]byte, []*x509.Certificate, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error parsing %s", rawURL)
+	}
+	addr := u.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "443")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         serverName,
+		InsecureSkipVerify: insecure, //nolint:gosec // explicitly requested with --insecure
+	}
+	if roots != "" {
+		if tlsConfig.RootCAs, err = x509util.ReadCertPool(roots); err != nil {
+			return nil, nil, errors.Wrapf(err, "failure to load root certificate pool from input path '%s'", roots)
+		}
+	}
+	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error connecting %s", rawURL)
+	}
+	conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.OCSPResponse) == 0 {
+		return nil, nil, errors.Errorf("%s did not staple an OCSP response", rawURL)
+	}
+	return state.OCSPResponse, state.PeerCertificates, nil
+}
@@ -0,0 +1,292 @@
+package ocsp
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/fileutil"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/internal/ocsputil"
+)
+
+// init creates and registers the ocsp command
+func init() {
+	cmd := cli.Command{
+		Name:      "ocsp",
+		Usage:     "create, send, inspect and answer OCSP requests",
+		UsageText: "**step ocsp** <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `**step ocsp** command group provides facilities to work with the Online
+Certificate Status Protocol (OCSP) defined in RFC 6960.
+
+## EXAMPLES
+
+Create an OCSP request with a nonce:
+'''
+$ step ocsp request --nonce leaf.crt intermediate.crt
+'''
+
+Query the status of a certificate:
+'''
+$ step ocsp query http://ocsp.example.com leaf.crt intermediate.crt
+'''
+
+Inspect the OCSP response stapled by a server:
+'''
+$ step ocsp inspect https://smallstep.com
+'''
+
+Run an OCSP responder for testing:
+'''
+$ step ocsp respond --issuer intermediate.crt --key intermediate.key \
+  --crl intermediate.crl --address :8080
+'''`,
+		Subcommands: cli.Commands{
+			requestCommand(),
+			queryCommand(),
+			inspectCommand(),
+			respondCommand(),
+		},
+	}
+
+	command.Register(cmd)
+}
+
+var (
+	hashFlag = cli.StringFlag{
+		Name:  "hash",
+		Value: "sha1",
+		Usage: `The hash <algorithm> used to identify the issuer in the request. Most
+responders only support SHA-1.
+
+: <algorithm> is a case-sensitive string and must be one of:
+
+    **sha1**
+    :  SHA-1 (default).
+
+    **sha256**
+    :  SHA-256.
+
+    **sha384**
+    :  SHA-384.
+
+    **sha512**
+    :  SHA-512.`,
+	}
+
+	nonceFlag = cli.BoolFlag{
+		Name:  "nonce",
+		Usage: `Add a random nonce to the request. Many responders ignore nonces.`,
+	}
+
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Value: "text",
+		Usage: `The output <format> of the response.
+
+: <format> is a string and must be one of:
+
+    **text**
+    :  Print output in unstructured text suitable for a human to read.
+	   This is the default format.
+
+    **json**
+    :  Print output in JSON format.`,
+	}
+)
+
+var hashAlgorithms = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+var revocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "key compromise",
+	ocsp.CACompromise:         "CA compromise",
+	ocsp.AffiliationChanged:   "affiliation changed",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessation of operation",
+	ocsp.CertificateHold:      "certificate hold",
+	ocsp.RemoveFromCRL:        "remove from CRL",
+	ocsp.PrivilegeWithdrawn:   "privilege withdrawn",
+	ocsp.AACompromise:         "AA compromise",
+}
+
+func parseHash(ctx *cli.Context) (crypto.Hash, error) {
+	name := ctx.String("hash")
+	h, ok := hashAlgorithms[name]
+	if !ok {
+		return 0, errs.InvalidFlagValue(ctx, "hash", name, "sha1, sha256, sha384, sha512")
+	}
+	return h, nil
+}
+
+// readCertificateAndIssuer reads the certificate to check and its issuer. If
+// the issuer file is empty, the issuer must be the second certificate in the
+// certificate file.
+func readCertificateAndIssuer(crtFile, issuerFile string) (*x509.Certificate, *x509.Certificate, error) {
+	certs, err := pemutil.ReadCertificateBundle(crtFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if issuerFile != "" {
+		issuer, err := pemutil.ReadCertificate(issuerFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certs[0], issuer, nil
+	}
+	if len(certs) < 2 {
+		return nil, nil, errors.Errorf("%s does not contain the issuer certificate, use the <issuer-file> argument", crtFile)
+	}
+	return certs[0], certs[1], nil
+}
+
+// writeOutput writes the DER data to the given file, or prints it in base64
+// if the file is empty.
+func writeOutput(filename, name string, der []byte) error {
+	if filename == "" {
+		fmt.Println(base64.StdEncoding.EncodeToString(der))
+		return nil
+	}
+	if err := fileutil.WriteFile(filename, der, 0o600); err != nil {
+		return err
+	}
+	ui.Printf("Your %s has been saved in %s.\n", name, filename)
+	return nil
+}
+
+type responseInfo struct {
+	Status               string     `json:"status"`
+	SerialNumber         string     `json:"serialNumber"`
+	ProducedAt           time.Time  `json:"producedAt"`
+	ThisUpdate           time.Time  `json:"thisUpdate"`
+	NextUpdate           *time.Time `json:"nextUpdate,omitempty"`
+	RevokedAt            *time.Time `json:"revokedAt,omitempty"`
+	RevocationReason     string     `json:"revocationReason,omitempty"`
+	ResponderName        string     `json:"responderName,omitempty"`
+	ResponderKeyHash     string     `json:"responderKeyHash,omitempty"`
+	ResponderCertificate string     `json:"responderCertificate,omitempty"`
+	SignatureAlgorithm   string     `json:"signatureAlgorithm"`
+	Nonce                string     `json:"nonce,omitempty"`
+	Verified             bool       `json:"verified"`
+}
+
+func newResponseInfo(resp *ocsp.Response, nonce []byte, verified bool) *responseInfo {
+	info := &responseInfo{
+		Status:             statusString(resp.Status),
+		SerialNumber:       resp.SerialNumber.String(),
+		ProducedAt:         resp.ProducedAt,
+		ThisUpdate:         resp.ThisUpdate,
+		SignatureAlgorithm: resp.SignatureAlgorithm.String(),
+		Verified:           verified,
+	}
+	if !resp.NextUpdate.IsZero() {
+		info.NextUpdate = &resp.NextUpdate
+	}
+	if resp.Status == ocsp.Revoked {
+		info.RevokedAt = &resp.RevokedAt
+		info.RevocationReason = revocationReasons[resp.RevocationReason]
+	}
+	if len(resp.RawResponderName) > 0 {
+		var rdn pkix.RDNSequence
+		if _, err := asn1.Unmarshal(resp.RawResponderName, &rdn); err == nil {
+			var name pkix.Name
+			name.FillFromRDNSequence(&rdn)
+			info.ResponderName = name.String()
+		}
+	}
+	if len(resp.ResponderKeyHash) > 0 {
+		info.ResponderKeyHash = hex.EncodeToString(resp.ResponderKeyHash)
+	}
+	if resp.Certificate != nil {
+		info.ResponderCertificate = resp.Certificate.Subject.String()
+	}
+	if len(nonce) > 0 {
+		info.Nonce = hex.EncodeToString(nonce)
+	}
+	return info
+}
+
+func printResponse(info *responseInfo, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling response")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	fmt.Printf("Status: %s\n", info.Status)
+	fmt.Printf("Serial Number: %s\n", info.SerialNumber)
+	if info.RevokedAt != nil {
+		fmt.Printf("Revoked At: %s\n", info.RevokedAt.UTC().Format(time.RFC3339))
+		fmt.Printf("Revocation Reason: %s\n", info.RevocationReason)
+	}
+	fmt.Printf("Produced At: %s\n", info.ProducedAt.UTC().Format(time.RFC3339))
+	fmt.Printf("This Update: %s\n", info.ThisUpdate.UTC().Format(time.RFC3339))
+	if info.NextUpdate != nil {
+		fmt.Printf("Next Update: %s\n", info.NextUpdate.UTC().Format(time.RFC3339))
+	}
+	if info.ResponderName != "" {
+		fmt.Printf("Responder ID: %s\n", info.ResponderName)
+	} else {
+		fmt.Printf("Responder ID: key hash %s\n", info.ResponderKeyHash)
+	}
+	if info.ResponderCertificate != "" {
+		fmt.Printf("Responder Certificate: %s\n", info.ResponderCertificate)
+	}
+	fmt.Printf("Signature Algorithm: %s\n", info.SignatureAlgorithm)
+	if info.Nonce != "" {
+		fmt.Printf("Nonce: %s\n", info.Nonce)
+	}
+	if info.Verified {
+		fmt.Println("Signature: verified")
+	} else {
+		fmt.Println("Signature: not verified")
+	}
+	return nil
+}
+
+func statusString(status int) string {
+	switch status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// parseResponse parses an OCSP response. If the issuer is not nil the
+// signature of the response is verified.
+func parseResponse(b []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	resp, err := ocsputil.ParseResponse(b, cert, issuer)
+	if err != nil {
+		var respErr ocsp.ResponseError
+		if errors.As(err, &respErr) {
+			return nil, errors.Errorf("OCSP responder returned an error: %s", respErr.Status)
+		}
+		return nil, errors.Wrap(err, "error parsing OCSP response")
+	}
+	return resp, nil
+}
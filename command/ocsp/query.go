@@ -0,0 +1,168 @@
+package ocsp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/ocsputil"
+)
+
+func queryCommand() cli.Command {
+	return cli.Command{
+		Name:   "query",
+		Action: command.ActionFunc(queryAction),
+		Usage:  "query the status of a certificate from an OCSP responder",
+		UsageText: `**step ocsp query** <url> <crt-file> [<issuer-file>]
+[**--method**=<method>] [**--hash**=<algorithm>] [**--nonce**]
+[**--format**=<format>] [**--out**=<file>] [**--roots**=<root-bundle>]`,
+		Description: `**step ocsp query** sends an OCSP request for a certificate to an OCSP
+responder, and prints the status of the certificate, the this and next update
+times, and the responder ID of the response.
+
+The signature of the response is verified using the issuer certificate, and,
+if **--nonce** is used and the responder returns a nonce, the nonce must match
+the one in the request.
+
+## POSITIONAL ARGUMENTS
+
+<url>
+:  The URL of the OCSP responder. The URL is usually in the Authority
+Information Access extension of the certificate, and can be printed with
+**step certificate inspect**.
+
+<crt-file>
+:  The path to the certificate to check. If <issuer-file> is not passed, this
+file must also contain the issuer certificate.
+
+<issuer-file>
+:  The path to the certificate of the issuer of <crt-file>.
+
+## EXAMPLES
+
+Query the status of a certificate:
+'''
+$ step ocsp query http://ocsp.example.com leaf.crt intermediate.crt
+Status: good
+Serial Number: 256377419451367139063862466155124133573
+Produced At: 2024-05-01T10:00:00Z
+This Update: 2024-05-01T10:00:00Z
+Next Update: 2024-05-08T10:00:00Z
+Responder ID: CN=Example Intermediate CA
+Signature Algorithm: ECDSA-SHA256
+Signature: verified
+'''
+
+Query the status of a certificate using GET, with a nonce, and print the
+response in JSON:
+'''
+$ step ocsp query --method GET --nonce --format json http://ocsp.example.com chain.crt
+'''
+
+Query the status of a certificate and save the response:
+'''
+$ step ocsp query --out response.der http://ocsp.example.com leaf.crt intermediate.crt
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "method",
+				Value: "POST",
+				Usage: `The HTTP <method> used to send the request. Options are GET or POST.`,
+			},
+			hashFlag,
+			nonceFlag,
+			formatFlag,
+			cli.StringFlag{
+				Name:  "out",
+				Usage: `The <file> to write the response to in DER format.`,
+			},
+			cli.StringFlag{
+				Name: "roots",
+				Usage: `Root certificate(s) that will be used to verify the
+authenticity of the OCSP responder if it uses HTTPS.`,
+			},
+			flags.Force,
+		},
+	}
+}
+
+func queryAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 2, 3); err != nil {
+		return err
+	}
+
+	method := ctx.String("method")
+	switch method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		return errs.InvalidFlagValue(ctx, "method", method, "GET, POST")
+	}
+	format := ctx.String("format")
+	if format != "text" && format != "json" {
+		return errs.InvalidFlagValue(ctx, "format", format, "text, json")
+	}
+	h, err := parseHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := ctx.Args()
+	server := args.Get(0)
+	cert, issuer, err := readCertificateAndIssuer(args.Get(1), args.Get(2))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+	if roots := ctx.String("roots"); roots != "" {
+		pool, err := x509util.ReadCertPool(roots)
+		if err != nil {
+			return err
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+		client.Transport = tr
+	}
+
+	opts := &ocsputil.RequestOptions{Hash: h}
+	if ctx.Bool("nonce") {
+		if opts.Nonce, err = ocsputil.NewNonce(); err != nil {
+			return err
+		}
+	}
+	req, err := ocsputil.CreateRequest(cert, issuer, opts)
+	if err != nil {
+		return err
+	}
+	b, err := ocsputil.Query(client, server, req, method)
+	if err != nil {
+		return err
+	}
+	resp, err := parseResponse(b, cert, issuer)
+	if err != nil {
+		return err
+	}
+
+	nonce := ocsputil.ResponseNonce(resp)
+	if len(opts.Nonce) > 0 && len(nonce) > 0 && !bytes.Equal(opts.Nonce, nonce) {
+		return errors.New("error validating OCSP response: the nonce does not match the request")
+	}
+
+	if filename := ctx.String("out"); filename != "" {
+		if err := writeOutput(filename, "OCSP response", b); err != nil {
+			return err
+		}
+	}
+	return printResponse(newResponseInfo(resp, nonce, true), format)
+}
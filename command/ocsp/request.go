@@ -0,0 +1,99 @@
+package ocsp
+
+import (
+	"encoding/hex"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/ocsputil"
+)
+
+func requestCommand() cli.Command {
+	return cli.Command{
+		Name:   "request",
+		Action: command.ActionFunc(requestAction),
+		Usage:  "create an OCSP request",
+		UsageText: `**step ocsp request** <crt-file> [<issuer-file>]
+[**--hash**=<algorithm>] [**--nonce**] [**--out**=<file>] [**--force**]`,
+		Description: `**step ocsp request** creates an OCSP request for a certificate. The request
+is printed in base64, the format used in OCSP GET requests, or saved in DER
+format with **--out**.
+
+## POSITIONAL ARGUMENTS
+
+<crt-file>
+:  The path to the certificate to check. If <issuer-file> is not passed, this
+file must also contain the issuer certificate.
+
+<issuer-file>
+:  The path to the certificate of the issuer of <crt-file>.
+
+## EXAMPLES
+
+Create an OCSP request:
+'''
+$ step ocsp request leaf.crt intermediate.crt
+'''
+
+Create an OCSP request with a nonce and SHA-256, and save it in a file:
+'''
+$ step ocsp request --nonce --hash sha256 --out request.der leaf.crt intermediate.crt
+'''
+
+Create and send an OCSP request using curl:
+'''
+$ step ocsp request --out request.der chain.crt
+$ curl --data-binary @request.der -H "Content-Type: application/ocsp-request" \
+  http://ocsp.example.com > response.der
+$ step ocsp inspect --issuer intermediate.crt response.der
+'''`,
+		Flags: []cli.Flag{
+			hashFlag,
+			nonceFlag,
+			cli.StringFlag{
+				Name:  "out",
+				Usage: `The <file> to write the request to in DER format.`,
+			},
+			flags.Force,
+		},
+	}
+}
+
+func requestAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 1, 2); err != nil {
+		return err
+	}
+
+	h, err := parseHash(ctx)
+	if err != nil {
+		return err
+	}
+	cert, issuer, err := readCertificateAndIssuer(ctx.Args().Get(0), ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	opts := &ocsputil.RequestOptions{Hash: h}
+	if ctx.Bool("nonce") {
+		if opts.Nonce, err = ocsputil.NewNonce(); err != nil {
+			return err
+		}
+	}
+	req, err := ocsputil.CreateRequest(cert, issuer, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := writeOutput(ctx.String("out"), "OCSP request", req); err != nil {
+		return err
+	}
+	if len(opts.Nonce) > 0 {
+		ui.Printf("Request nonce: %s\n", hex.EncodeToString(opts.Nonce))
+	}
+	return nil
+}
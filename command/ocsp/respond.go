@@ -0,0 +1,241 @@
+package ocsp
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/cryptoutil"
+	"github.com/smallstep/cli/internal/ocsputil"
+	"github.com/smallstep/cli/utils"
+)
+
+func respondCommand() cli.Command {
+	return cli.Command{
+		Name:   "respond",
+		Action: command.ActionFunc(respondAction),
+		Usage:  "answer OCSP requests for testing",
+		UsageText: `**step ocsp respond** [<request-file>] **--issuer**=<file> **--key**=<file>
+[**--cert**=<file>] [**--password-file**=<file>] [**--kms**=<uri>]
+[**--crl**=<file>] [**--index**=<file>] [**--validity**=<duration>]
+[**--address**=<address>] [**--out**=<file>] [**--force**]`,
+		Description: `**step ocsp respond** creates signed OCSP responses using the status of the
+certificates in a CRL or in an OpenSSL CA index file. It is a minimal responder
+intended for testing, not for production use.
+
+With a CRL, the certificates in the CRL are revoked and all other certificates
+are good. With an index file, the valid certificates are good, the revoked
+certificates are revoked, and all other certificates are unknown.
+
+If **--address** is passed, the command runs an HTTP server that answers GET
+and POST OCSP requests. Otherwise, it answers the request in <request-file>,
+and prints the response in base64, or saves it in DER format with **--out**.
+
+Responses are signed with the issuer key, or with a delegated responder
+certificate passed with **--cert**. If the request has a nonce, the response
+includes the same nonce.
+
+## POSITIONAL ARGUMENTS
+
+<request-file>
+:  The path to an OCSP request in DER, PEM or base64 format. Use '-' to read
+from the standard input.
+
+## EXAMPLES
+
+Run an OCSP responder using a CRL:
+'''
+$ step ocsp respond --issuer intermediate.crt --key intermediate.key \
+  --crl intermediate.crl --address :8080
+'''
+
+Run an OCSP responder with a delegated responder certificate and an OpenSSL
+index file, with responses valid for one day:
+'''
+$ step ocsp respond --issuer intermediate.crt --cert ocsp.crt --key ocsp.key \
+  --index index.txt --validity 24h --address 127.0.0.1:8080
+'''
+
+Answer a request in a file:
+'''
+$ step ocsp request --out request.der leaf.crt intermediate.crt
+$ step ocsp respond --issuer intermediate.crt --key intermediate.key \
+  --crl intermediate.crl --out response.der request.der
+$ step ocsp inspect --issuer intermediate.crt response.der
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "issuer",
+				Usage: `The certificate <file> of the CA that issued the certificates.`,
+			},
+			cli.StringFlag{
+				Name:  "cert",
+				Usage: `The delegated responder certificate <file> used to sign the responses.`,
+			},
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The private key <file> used to sign the responses. It must be the key of
+the responder certificate, or of the issuer if **--cert** is not passed.`,
+			},
+			flags.PasswordFile,
+			flags.KMSUri,
+			cli.StringFlag{
+				Name:  "crl",
+				Usage: `The CRL <file> with the revoked certificates.`,
+			},
+			cli.StringFlag{
+				Name:  "index",
+				Usage: `The OpenSSL CA index <file> with the status of the certificates.`,
+			},
+			cli.DurationFlag{
+				Name:  "validity",
+				Value: time.Hour,
+				Usage: `The <duration> between the this update and the next update of the responses.`,
+			},
+			cli.StringFlag{
+				Name:  "address",
+				Usage: `The TCP <address> to listen on (e.g. ":8080").`,
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Usage: `The <file> to write the response to in DER format.`,
+			},
+			flags.Force,
+		},
+	}
+}
+
+func respondAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	issuerFile := ctx.String("issuer")
+	keyFile := ctx.String("key")
+	crlFile := ctx.String("crl")
+	indexFile := ctx.String("index")
+	address := ctx.String("address")
+	switch {
+	case issuerFile == "":
+		return errs.RequiredFlag(ctx, "issuer")
+	case keyFile == "":
+		return errs.RequiredFlag(ctx, "key")
+	case crlFile == "" && indexFile == "":
+		return errs.RequiredOrFlag(ctx, "crl", "index")
+	case crlFile != "" && indexFile != "":
+		return errs.IncompatibleFlagWithFlag(ctx, "crl", "index")
+	case address == "" && ctx.NArg() == 0:
+		return errs.TooFewArguments(ctx)
+	case address != "" && ctx.NArg() > 0:
+		return errors.New("positional argument <request-file> cannot be used with flag '--address'")
+	}
+
+	issuer, err := pemutil.ReadCertificate(issuerFile)
+	if err != nil {
+		return err
+	}
+	var cert *x509.Certificate
+	if certFile := ctx.String("cert"); certFile != "" {
+		if cert, err = pemutil.ReadCertificate(certFile); err != nil {
+			return err
+		}
+	}
+	var opts []pemutil.Options
+	if passwordFile := ctx.String("password-file"); passwordFile != "" {
+		opts = append(opts, pemutil.WithPasswordFile(passwordFile))
+	}
+	signer, err := cryptoutil.CreateSigner(ctx.String("kms"), keyFile, opts...)
+	if err != nil {
+		return err
+	}
+
+	var db *ocsputil.Database
+	if crlFile != "" {
+		b, err := utils.ReadFile(crlFile)
+		if err != nil {
+			return err
+		}
+		if block, _ := pem.Decode(b); block != nil {
+			b = block.Bytes
+		}
+		crl, err := x509.ParseRevocationList(b)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", crlFile, err)
+		}
+		db = ocsputil.NewDatabaseFromCRL(crl)
+	} else {
+		b, err := utils.ReadFile(indexFile)
+		if err != nil {
+			return err
+		}
+		if db, err = ocsputil.ParseIndex(bytes.NewReader(b)); err != nil {
+			return err
+		}
+	}
+
+	responder := &ocsputil.Responder{
+		Issuer:      issuer,
+		Certificate: cert,
+		Signer:      signer,
+		Database:    db,
+		Validity:    ctx.Duration("validity"),
+	}
+
+	if address == "" {
+		b, err := utils.ReadFile(ctx.Args().First())
+		if err != nil {
+			return err
+		}
+		if b, err = ocsputil.Decode(b); err != nil {
+			return err
+		}
+		resp, err := responder.Respond(b)
+		if err != nil {
+			return err
+		}
+		return writeOutput(ctx.String("out"), "OCSP response", resp)
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("error listening at %s: %w", address, err)
+	}
+	srv := &http.Server{
+		Handler:           responder,
+		ReadHeaderTimeout: 15 * time.Second,
+	}
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		<-signals
+		log.Println("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	log.Printf("serving OCSP responses at http://%s\n", l.Addr().String())
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("OCSP responder failed: %w", err)
+	}
+	return nil
+}
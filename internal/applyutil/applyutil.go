@@ -0,0 +1,315 @@
+// Package applyutil implements the plans used to apply a declarative
+// configuration to a certificate authority: the changes required to turn the
+// current state into the desired one, their diffs, and their application.
+package applyutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+)
+
+// Action is the type of a change.
+type Action string
+
+// Supported actions.
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+var actionSymbols = map[Action]string{
+	Create: "+",
+	Update: "~",
+	Delete: "-",
+}
+
+// Change is a change of a plan.
+type Change struct {
+	Action Action
+	// Kind is the type of resource, e.g. provisioner or admin.
+	Kind string
+	// Name identifies the resource.
+	Name string
+	// Diff is the difference between the current and desired state of the
+	// resource, as returned by Diff.
+	Diff string
+	// Apply applies the change.
+	Apply func() error
+}
+
+// Plan is the ordered list of changes required to reach the desired state.
+type Plan []*Change
+
+// Print writes a summary of the changes and their diffs to w.
+func (p Plan) Print(w io.Writer) {
+	if len(p) == 0 {
+		fmt.Fprintln(w, "No changes. The current state matches the configuration.")
+		return
+	}
+	for _, c := range p {
+		fmt.Fprintf(w, "%s %s %s %s\n", actionSymbols[c.Action], c.Action, c.Kind, c.Name)
+		if c.Diff != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(c.Diff, "\n"), "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, p.Summary())
+}
+
+// Summary returns the number of changes of each type.
+func (p Plan) Summary() string {
+	var n = map[Action]int{}
+	for _, c := range p {
+		n[c.Action]++
+	}
+	return fmt.Sprintf("Plan: %d to create, %d to update, %d to delete.", n[Create], n[Update], n[Delete])
+}
+
+// Apply applies the changes in order and writes the progress to w. It stops
+// at the first error.
+func (p Plan) Apply(w io.Writer) error {
+	for _, c := range p {
+		if err := c.Apply(); err != nil {
+			return errors.Wrapf(err, "error trying to %s %s %s", c.Action, c.Kind, c.Name)
+		}
+		fmt.Fprintf(w, "%s %s %s: %sd\n", actionSymbols[c.Action], c.Kind, c.Name, c.Action)
+	}
+	return nil
+}
+
+// Run prints the plan to w and applies it, unless it is empty or dryRun is
+// true. Unless force is true, it asks for confirmation before applying the
+// changes; verb is the action in the prompt, e.g. apply or import.
+func (p Plan) Run(w io.Writer, verb string, dryRun, force bool) error {
+	p.Print(w)
+	if len(p) == 0 || dryRun {
+		return nil
+	}
+	if !force {
+		ok, err := ui.PromptYesNo(fmt.Sprintf("Do you want to %s these changes? [y/n]", verb))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return p.Apply(w)
+}
+
+// ReadList reads a YAML or JSON file with a list of resources and returns the
+// JSON encoding of each one. The list can be the top-level value of the file
+// or the value of the given key.
+func ReadList(filename, key string) ([]json.RawMessage, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	var v any
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", filename)
+	}
+	if m, ok := v.(map[string]any); ok {
+		if v, ok = m[key]; !ok {
+			return nil, errors.Errorf("error parsing %s: property %q not found", filename, key)
+		}
+	}
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, errors.Errorf("error parsing %s: %s must be a list", filename, key)
+	}
+	list := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		if list[i], err = json.Marshal(item); err != nil {
+			return nil, errors.Wrapf(err, "error parsing %s: invalid %s at index %d", filename, key, i)
+		}
+	}
+	return list, nil
+}
+
+// Canonical returns a stable YAML representation of the given JSON, with
+// sorted keys and without empty values, suitable to compare and diff
+// resources. It returns an empty string if the JSON is empty.
+func Canonical(b []byte) (string, error) {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", errors.Wrap(err, "error parsing JSON")
+	}
+	if v = prune(v); v == nil {
+		return "", nil
+	}
+	out, err := encode(v, "yaml")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Marshal returns the stable YAML or JSON encoding of v, with sorted keys and
+// without empty values. The value is first encoded to JSON, so it can contain
+// the JSON encoding of other resources as json.RawMessage.
+func Marshal(v any, format string) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling JSON")
+	}
+	var x any
+	if err := json.Unmarshal(b, &x); err != nil {
+		return nil, errors.Wrap(err, "error parsing JSON")
+	}
+	if x = prune(x); x == nil {
+		x = map[string]any{}
+	}
+	return encode(x, format)
+}
+
+func encode(v any, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshaling JSON")
+		}
+		return append(b, '\n'), nil
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return nil, errors.Wrap(err, "error marshaling YAML")
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.Errorf("unsupported format %q", format)
+	}
+}
+
+// prune removes the null values and the empty objects and lists.
+func prune(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, vv := range v {
+			if vv = prune(vv); vv == nil {
+				delete(v, k)
+			} else {
+				v[k] = vv
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		for i := range v {
+			v[i] = prune(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// contextLines is the number of unchanged lines around the changes of a diff.
+const contextLines = 2
+
+// Diff returns a line diff between a and b. Removed lines are prefixed by
+// "- ", added lines by "+ ", and the unchanged lines around them by "  ".
+// Skipped unchanged lines are replaced by "  ...". It returns an empty string
+// if both are equal.
+func Diff(a, b string) string {
+	if a == b {
+		return ""
+	}
+	x := splitLines(a)
+	y := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and
+	// y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i]})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', x[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', y[j]})
+			j++
+		}
+	}
+
+	// Keep only the changes and their context.
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op != ' ' {
+			for c := max(0, k-contextLines); c <= min(len(lines)-1, k+contextLines); c++ {
+				keep[c] = true
+			}
+		}
+	}
+	var sb strings.Builder
+	skipped := false
+	for k, l := range lines {
+		if !keep[k] {
+			if !skipped {
+				sb.WriteString("  ...\n")
+				skipped = true
+			}
+			continue
+		}
+		skipped = false
+		sb.WriteByte(l.op)
+		sb.WriteByte(' ')
+		sb.WriteString(l.text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
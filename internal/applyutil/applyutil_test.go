@@ -0,0 +1,168 @@
+package applyutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadList(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, s string) string {
+		fn := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fn, []byte(s), 0o600))
+		return fn
+	}
+
+	list, err := ReadList(write("provisioners.yaml", `
+provisioners:
+- name: acme
+  type: ACME
+  details:
+    ACME:
+      forceCN: true
+- name: jwk
+  type: JWK
+`), "provisioners")
+	require.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.JSONEq(t, `{"name":"acme","type":"ACME","details":{"ACME":{"forceCN":true}}}`, string(list[0]))
+		assert.JSONEq(t, `{"name":"jwk","type":"JWK"}`, string(list[1]))
+	}
+
+	list, err = ReadList(write("admins.json", `[{"subject": "max@smallstep.com"}]`), "admins")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	list, err = ReadList(write("empty.yaml", `admins: []`), "admins")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = ReadList(write("missing.yaml", `provisioners: []`), "admins")
+	assert.Error(t, err)
+	_, err = ReadList(write("map.yaml", `admins: {subject: foo}`), "admins")
+	assert.Error(t, err)
+	_, err = ReadList(write("bad.yaml", `admins: [`), "admins")
+	assert.Error(t, err)
+	_, err = ReadList(filepath.Join(dir, "notfound.yaml"), "admins")
+	assert.Error(t, err)
+}
+
+func TestCanonical(t *testing.T) {
+	s, err := Canonical([]byte(`{"type":"ACME","name":"acme","claims":{},"webhooks":[],"details":{"ACME":{"forceCN":true,"challenges":null}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "details:\n  ACME:\n    forceCN: true\nname: acme\ntype: ACME\n", s)
+
+	s, err = Canonical([]byte(`{"claims":{"x509":{}}}`))
+	require.NoError(t, err)
+	assert.Empty(t, s)
+
+	_, err = Canonical([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestMarshal(t *testing.T) {
+	v := map[string]any{
+		"provisioners": []json.RawMessage{
+			json.RawMessage(`{"type":"ACME","name":"acme","claims":{}}`),
+		},
+		"admins": nil,
+	}
+	b, err := Marshal(v, "yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "provisioners:\n  - name: acme\n    type: ACME\n", string(b))
+
+	b, err = Marshal(v, "json")
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"provisioners\": [\n    {\n      \"name\": \"acme\",\n      \"type\": \"ACME\"\n    }\n  ]\n}\n", string(b))
+
+	b, err = Marshal(map[string]any{}, "json")
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(b))
+
+	_, err = Marshal(v, "toml")
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	assert.Empty(t, Diff("a\nb\n", "a\nb\n"))
+	assert.Equal(t, "+ a\n+ b\n", Diff("", "a\nb\n"))
+	assert.Equal(t, "- a\n", Diff("a\n", ""))
+	assert.Equal(t, "- 1\n+ 2\n  3\n  4\n  ...\n", Diff("1\n3\n4\n5\n6\n", "2\n3\n4\n5\n6\n"))
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+	assert.Equal(t, "  ...\n  3\n  4\n- 5\n+ five\n  6\n  7\n  8\n  9\n+ 10\n", Diff(a, b))
+}
+
+func TestPlan(t *testing.T) {
+	var applied []string
+	change := func(action Action, name, diff string) *Change {
+		return &Change{Action: action, Kind: "provisioner", Name: name, Diff: diff, Apply: func() error {
+			applied = append(applied, name)
+			if name == "fail" {
+				return errors.New("forbidden")
+			}
+			return nil
+		}}
+	}
+
+	var buf bytes.Buffer
+	Plan{}.Print(&buf)
+	assert.Equal(t, "No changes. The current state matches the configuration.\n", buf.String())
+
+	p := Plan{
+		change(Create, "acme", ""),
+		change(Update, "jwk", "- type: OIDC\n+ type: JWK\n"),
+		change(Delete, "old", ""),
+	}
+	buf.Reset()
+	p.Print(&buf)
+	assert.Equal(t, `+ create provisioner acme
+~ update provisioner jwk
+    - type: OIDC
+    + type: JWK
+- delete provisioner old
+
+Plan: 1 to create, 1 to update, 1 to delete.
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, p.Apply(&buf))
+	assert.Equal(t, []string{"acme", "jwk", "old"}, applied)
+	assert.Equal(t, "+ provisioner acme: created\n~ provisioner jwk: updated\n- provisioner old: deleted\n", buf.String())
+
+	applied = nil
+	p = Plan{change(Create, "fail", ""), change(Create, "next", "")}
+	err := p.Apply(&buf)
+	assert.EqualError(t, err, "error trying to create provisioner fail: forbidden")
+	assert.Equal(t, []string{"fail"}, applied)
+}
+
+func TestPlan_Run(t *testing.T) {
+	var applied int
+	p := Plan{{Action: Create, Kind: "admin", Name: "jane", Apply: func() error {
+		applied++
+		return nil
+	}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Run(&buf, "apply", true, false))
+	assert.Equal(t, 0, applied)
+	assert.Contains(t, buf.String(), "+ create admin jane")
+
+	buf.Reset()
+	require.NoError(t, Plan{}.Run(&buf, "apply", false, false))
+	assert.Equal(t, "No changes. The current state matches the configuration.\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, p.Run(&buf, "import", false, true))
+	assert.Equal(t, 1, applied)
+	assert.Contains(t, buf.String(), "+ admin jane: created\n")
+}
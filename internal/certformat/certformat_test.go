@@ -0,0 +1,209 @@
+package certformat
+
+import (
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // used to create test keystores
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/minica"
+)
+
+func newLeaf(t *testing.T, ca *minica.CA) *x509.Certificate {
+	t.Helper()
+	key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	crt, err := ca.Sign(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		PublicKey:    key.Public(),
+	})
+	require.NoError(t, err)
+	return crt
+}
+
+func TestPKCS7(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	root, leaf := ca.Root, newLeaf(t, ca)
+	crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, ca.Root, ca.RootSigner)
+	require.NoError(t, err)
+	crl, err := x509.ParseRevocationList(crlDER)
+	require.NoError(t, err)
+
+	b, err := MarshalPKCS7([]*x509.Certificate{leaf, root}, []*x509.RevocationList{crl})
+	require.NoError(t, err)
+	certs, crls, err := ParsePKCS7(b)
+	require.NoError(t, err)
+	assert.Equal(t, []*x509.Certificate{leaf, root}, certs)
+	if assert.Len(t, crls, 1) {
+		assert.Equal(t, crl.Raw, crls[0].Raw)
+	}
+
+	b, err = MarshalPKCS7([]*x509.Certificate{root}, nil)
+	require.NoError(t, err)
+	certs, crls, err = ParsePKCS7(b)
+	require.NoError(t, err)
+	assert.Equal(t, []*x509.Certificate{root}, certs)
+	assert.Empty(t, crls)
+
+	_, _, err = ParsePKCS7(root.Raw)
+	assert.Error(t, err)
+}
+
+// keyStoreEntry is an entry written by marshalKeyStore.
+type keyStoreEntry struct {
+	tag   uint32
+	alias string
+	certs []*x509.Certificate
+}
+
+// marshalKeyStore returns a version 2 keystore with the given entries.
+func marshalKeyStore(magic uint32, password string, entries ...keyStoreEntry) []byte {
+	var b []byte
+	u16 := func(s string) {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+		b = append(b, s...)
+	}
+	cert := func(crt *x509.Certificate) {
+		u16("X.509")
+		b = binary.BigEndian.AppendUint32(b, uint32(len(crt.Raw)))
+		b = append(b, crt.Raw...)
+	}
+	b = binary.BigEndian.AppendUint32(b, magic)
+	b = binary.BigEndian.AppendUint32(b, 2)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(entries)))
+	for _, e := range entries {
+		b = binary.BigEndian.AppendUint32(b, e.tag)
+		u16(e.alias)
+		b = binary.BigEndian.AppendUint64(b, uint64(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).UnixMilli()))
+		switch e.tag {
+		case privateKeyTag:
+			protected := []byte("protected key")
+			b = binary.BigEndian.AppendUint32(b, uint32(len(protected)))
+			b = append(b, protected...)
+			b = binary.BigEndian.AppendUint32(b, uint32(len(e.certs)))
+			for _, crt := range e.certs {
+				cert(crt)
+			}
+		case trustedCertTag:
+			cert(e.certs[0])
+		case secretKeyTag:
+			b = append(b, 0xac, 0xed)
+		}
+	}
+
+	// The digest uses the UTF-16 password.
+	h := sha1.New() //nolint:gosec // used to create test keystores
+	for _, c := range password {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(b)
+	return h.Sum(b)
+}
+
+func TestParseKeyStore(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	root, leaf := ca.Root, newLeaf(t, ca)
+
+	for name, magic := range map[string]uint32{"jks": jksMagic, "jceks": jceksMagic} {
+		t.Run(name, func(t *testing.T) {
+			b := marshalKeyStore(magic, "changeit",
+				keyStoreEntry{tag: privateKeyTag, alias: "server", certs: []*x509.Certificate{leaf, root}},
+				keyStoreEntry{tag: trustedCertTag, alias: "root", certs: []*x509.Certificate{root}},
+			)
+			assert.True(t, IsKeyStore(b))
+
+			entries, err := ParseKeyStore(b, []byte("changeit"))
+			require.NoError(t, err)
+			assert.Equal(t, []KeyStoreEntry{
+				{Alias: "server", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), PrivateKey: true, Certificates: []*x509.Certificate{leaf, root}},
+				{Alias: "root", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Certificates: []*x509.Certificate{root}},
+			}, entries)
+
+			// Without password the integrity is not verified.
+			_, err = ParseKeyStore(b, nil)
+			assert.NoError(t, err)
+			_, err = ParseKeyStore(b, []byte("password"))
+			assert.ErrorContains(t, err, "password is incorrect")
+
+			b[11] ^= 0xff
+			_, err = ParseKeyStore(b, nil)
+			assert.Error(t, err)
+		})
+	}
+
+	b := marshalKeyStore(jceksMagic, "changeit", keyStoreEntry{tag: secretKeyTag, alias: "secret"})
+	_, err = ParseKeyStore(b, []byte("changeit"))
+	assert.ErrorContains(t, err, "secret key entries are not supported")
+
+	assert.False(t, IsKeyStore(root.Raw))
+	assert.False(t, IsKeyStore(nil))
+}
+
+func TestMarshalCertData(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	root, err := ca.Sign(&x509.Certificate{
+		SerialNumber:          big.NewInt(0x1234),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2034, 1, 1, 0, 0, 0, 0, time.UTC),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		PublicKey:             key.Public(),
+	})
+	require.NoError(t, err)
+	leaf := newLeaf(t, ca)
+
+	b, err := MarshalCertData([]*x509.Certificate{root, leaf})
+	require.NoError(t, err)
+	s := string(b)
+	assert.Equal(t, 2, strings.Count(s, "CKA_CLASS CK_OBJECT_CLASS CKO_CERTIFICATE\n"))
+	assert.Equal(t, 2, strings.Count(s, "CKA_CLASS CK_OBJECT_CLASS CKO_NSS_TRUST\n"))
+	assert.Contains(t, s, "# Certificate \"Test Root CA\"\n")
+	assert.Contains(t, s, "# Serial Number: 12:34\n")
+	assert.Contains(t, s, "# Not Valid Before: Mon Jan 01 00:00:00 2024\n")
+	assert.Contains(t, s, "CKA_LABEL UTF8 \"leaf\"\n")
+	// The serial number is DER encoded.
+	assert.Contains(t, s, "CKA_SERIAL_NUMBER MULTILINE_OCTAL\n\\002\\002\\022\\064\nEND\n")
+	assert.Equal(t, 3, strings.Count(s, "CK_TRUST CKT_NSS_TRUSTED_DELEGATOR\n"))
+	assert.Equal(t, 3, strings.Count(s, "CK_TRUST CKT_NSS_MUST_VERIFY_TRUST\n"))
+
+	// The value contains the certificate in octal, 16 bytes per line.
+	i := strings.Index(s, "CKA_VALUE MULTILINE_OCTAL\n")
+	require.NotEqual(t, -1, i)
+	lines := strings.Split(s[i:], "\n")
+	assert.Len(t, lines[1], 16*4)
+	var der []byte
+	for _, line := range lines[1:] {
+		if line == "END" {
+			break
+		}
+		for _, o := range strings.Split(line, "\\")[1:] {
+			var c byte
+			for _, d := range o {
+				c = c*8 + byte(d-'0')
+			}
+			der = append(der, c)
+		}
+	}
+	assert.Equal(t, root.Raw, der)
+}
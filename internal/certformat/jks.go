@@ -0,0 +1,170 @@
+package certformat
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // SHA-1 is the integrity algorithm of Java keystores
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"time"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// Magic numbers of the keystore formats.
+const (
+	jksMagic   = 0xFEEDFEED
+	jceksMagic = 0xCECECECE
+)
+
+// Tags of the keystore entries.
+const (
+	privateKeyTag  = 1
+	trustedCertTag = 2
+	secretKeyTag   = 3
+)
+
+// keystoreWhitener is the string appended to the password to compute the
+// integrity digest of a keystore.
+var keystoreWhitener = []byte("Mighty Aphrodite")
+
+// KeyStoreEntry is an entry of a Java keystore.
+type KeyStoreEntry struct {
+	Alias        string
+	Date         time.Time
+	PrivateKey   bool
+	Certificates []*x509.Certificate
+}
+
+// IsKeyStore returns true if the data starts with the magic number of a JKS
+// or JCEKS keystore.
+func IsKeyStore(b []byte) bool {
+	if len(b) < 4 {
+		return false
+	}
+	magic := binary.BigEndian.Uint32(b)
+	return magic == jksMagic || magic == jceksMagic
+}
+
+// ParseKeyStore returns the entries of a JKS or JCEKS keystore. The private
+// key entries contain the certificate chain of the key, the key is not
+// decrypted. If password is not nil, the integrity of the keystore is
+// verified with it.
+//
+// Secret key entries, only present in JCEKS keystores, are not supported.
+func ParseKeyStore(b []byte, password []byte) ([]KeyStoreEntry, error) {
+	if len(b) < sha1.Size {
+		return nil, errors.New("error parsing keystore: data is too short")
+	}
+	data, digest := b[:len(b)-sha1.Size], b[len(b)-sha1.Size:]
+	if password != nil {
+		if subtle.ConstantTimeCompare(keyStoreDigest(data, password), digest) != 1 {
+			return nil, errors.New("error parsing keystore: password is incorrect or keystore was tampered with")
+		}
+	}
+
+	var (
+		magic, version, count uint32
+		s                     = cryptobyte.String(data)
+	)
+	if !s.ReadUint32(&magic) || !s.ReadUint32(&version) || !s.ReadUint32(&count) {
+		return nil, errors.New("error parsing keystore: malformed header")
+	}
+	if magic != jksMagic && magic != jceksMagic {
+		return nil, errors.New("error parsing keystore: invalid magic number")
+	}
+	if version != 1 && version != 2 {
+		return nil, errors.Errorf("error parsing keystore: unsupported version %d", version)
+	}
+
+	var entries []KeyStoreEntry
+	for i := uint32(0); i < count; i++ {
+		var (
+			tag   uint32
+			alias cryptobyte.String
+			ts    uint64
+		)
+		if !s.ReadUint32(&tag) || !s.ReadUint16LengthPrefixed(&alias) || !s.ReadUint64(&ts) {
+			return nil, errors.New("error parsing keystore: malformed entry")
+		}
+		entry := KeyStoreEntry{
+			Alias: string(alias),
+			Date:  time.UnixMilli(int64(ts)).UTC(), //nolint:gosec // timestamps are positive
+		}
+
+		switch tag {
+		case privateKeyTag:
+			var (
+				key        cryptobyte.String
+				chainCount uint32
+			)
+			if !readUint32LengthPrefixed(&s, &key) || !s.ReadUint32(&chainCount) {
+				return nil, errors.Errorf("error parsing keystore: malformed private key entry %q", entry.Alias)
+			}
+			entry.PrivateKey = true
+			for j := uint32(0); j < chainCount; j++ {
+				crt, err := readKeyStoreCertificate(&s, version)
+				if err != nil {
+					return nil, errors.Wrapf(err, "error parsing keystore entry %q", entry.Alias)
+				}
+				entry.Certificates = append(entry.Certificates, crt)
+			}
+		case trustedCertTag:
+			crt, err := readKeyStoreCertificate(&s, version)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing keystore entry %q", entry.Alias)
+			}
+			entry.Certificates = []*x509.Certificate{crt}
+		case secretKeyTag:
+			return nil, errors.Errorf("error parsing keystore entry %q: secret key entries are not supported", entry.Alias)
+		default:
+			return nil, errors.Errorf("error parsing keystore entry %q: unknown tag %d", entry.Alias, tag)
+		}
+		entries = append(entries, entry)
+	}
+	if !s.Empty() {
+		return nil, errors.New("error parsing keystore: trailing data")
+	}
+	return entries, nil
+}
+
+func readKeyStoreCertificate(s *cryptobyte.String, version uint32) (*x509.Certificate, error) {
+	if version == 2 {
+		var typ cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&typ) {
+			return nil, errors.New("malformed certificate")
+		}
+		if string(typ) != "X.509" {
+			return nil, errors.Errorf("unsupported certificate type %q", string(typ))
+		}
+	}
+	var der cryptobyte.String
+	if !readUint32LengthPrefixed(s, &der) {
+		return nil, errors.New("malformed certificate")
+	}
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate")
+	}
+	return crt, nil
+}
+
+// readUint32LengthPrefixed reads a 32-bit length-prefixed byte string.
+func readUint32LengthPrefixed(s *cryptobyte.String, out *cryptobyte.String) bool {
+	var n uint32
+	return s.ReadUint32(&n) && s.ReadBytes((*[]byte)(out), int(n))
+}
+
+// keyStoreDigest returns the integrity digest of the keystore data, the
+// SHA-1 of the UTF-16 password, the whitener, and the data.
+func keyStoreDigest(data, password []byte) []byte {
+	h := sha1.New() //nolint:gosec // SHA-1 is the integrity algorithm of Java keystores
+	for _, c := range utf16.Encode(bytes.Runes(password)) {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write(keystoreWhitener)
+	h.Write(data)
+	return h.Sum(nil)
+}
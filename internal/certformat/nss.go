@@ -0,0 +1,112 @@
+package certformat
+
+import (
+	"bytes"
+	"crypto/md5"  //nolint:gosec // MD5 hash is an attribute of NSS trust objects
+	"crypto/sha1" //nolint:gosec // SHA-1 hash is an attribute of NSS trust objects
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarshalCertData returns the certificates in the certdata.txt format used by
+// NSS to define its built-in trust anchors, as generated by the NSS addbuiltin
+// tool. Each certificate is encoded as a certificate object and a trust
+// object. CA certificates are trusted to issue server authentication, email
+// protection, and code signing certificates, the trust of other certificates
+// must be verified.
+func MarshalCertData(certs []*x509.Certificate) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, crt := range certs {
+		serial, err := asn1.Marshal(crt.SerialNumber)
+		if err != nil {
+			return nil, err
+		}
+		label := certDataLabel(crt)
+		sha1Sum := sha1.Sum(crt.Raw) //nolint:gosec // required by NSS
+		md5Sum := md5.Sum(crt.Raw)   //nolint:gosec // required by NSS
+		sha256Sum := sha256.Sum256(crt.Raw)
+
+		comments := func() {
+			fmt.Fprintf(&buf, "# Issuer: %s\n", crt.Issuer)
+			fmt.Fprintf(&buf, "# Serial Number: %s\n", colonHex(crt.SerialNumber.Bytes()))
+			fmt.Fprintf(&buf, "# Subject: %s\n", crt.Subject)
+			fmt.Fprintf(&buf, "# Not Valid Before: %s\n", crt.NotBefore.UTC().Format("Mon Jan 02 15:04:05 2006"))
+			fmt.Fprintf(&buf, "# Not Valid After : %s\n", crt.NotAfter.UTC().Format("Mon Jan 02 15:04:05 2006"))
+			fmt.Fprintf(&buf, "# Fingerprint (SHA-256): %s\n", colonHex(sha256Sum[:]))
+			fmt.Fprintf(&buf, "# Fingerprint (SHA1): %s\n", colonHex(sha1Sum[:]))
+		}
+
+		buf.WriteString("\n#\n")
+		fmt.Fprintf(&buf, "# Certificate %s\n", label)
+		buf.WriteString("#\n")
+		comments()
+		buf.WriteString("CKA_CLASS CK_OBJECT_CLASS CKO_CERTIFICATE\n")
+		buf.WriteString("CKA_TOKEN CK_BBOOL CK_TRUE\n")
+		buf.WriteString("CKA_PRIVATE CK_BBOOL CK_FALSE\n")
+		buf.WriteString("CKA_MODIFIABLE CK_BBOOL CK_FALSE\n")
+		fmt.Fprintf(&buf, "CKA_LABEL UTF8 %s\n", label)
+		buf.WriteString("CKA_CERTIFICATE_TYPE CK_CERTIFICATE_TYPE CKC_X_509\n")
+		writeOctal(&buf, "CKA_SUBJECT", crt.RawSubject)
+		buf.WriteString("CKA_ID UTF8 \"0\"\n")
+		writeOctal(&buf, "CKA_ISSUER", crt.RawIssuer)
+		writeOctal(&buf, "CKA_SERIAL_NUMBER", serial)
+		writeOctal(&buf, "CKA_VALUE", crt.Raw)
+
+		trust := "CKT_NSS_MUST_VERIFY_TRUST"
+		if crt.IsCA {
+			trust = "CKT_NSS_TRUSTED_DELEGATOR"
+		}
+		buf.WriteString("\n")
+		fmt.Fprintf(&buf, "# Trust for %s\n", label)
+		comments()
+		buf.WriteString("CKA_CLASS CK_OBJECT_CLASS CKO_NSS_TRUST\n")
+		buf.WriteString("CKA_TOKEN CK_BBOOL CK_TRUE\n")
+		buf.WriteString("CKA_PRIVATE CK_BBOOL CK_FALSE\n")
+		buf.WriteString("CKA_MODIFIABLE CK_BBOOL CK_FALSE\n")
+		fmt.Fprintf(&buf, "CKA_LABEL UTF8 %s\n", label)
+		writeOctal(&buf, "CKA_CERT_SHA1_HASH", sha1Sum[:])
+		writeOctal(&buf, "CKA_CERT_MD5_HASH", md5Sum[:])
+		writeOctal(&buf, "CKA_ISSUER", crt.RawIssuer)
+		writeOctal(&buf, "CKA_SERIAL_NUMBER", serial)
+		fmt.Fprintf(&buf, "CKA_TRUST_SERVER_AUTH CK_TRUST %s\n", trust)
+		fmt.Fprintf(&buf, "CKA_TRUST_EMAIL_PROTECTION CK_TRUST %s\n", trust)
+		fmt.Fprintf(&buf, "CKA_TRUST_CODE_SIGNING CK_TRUST %s\n", trust)
+		buf.WriteString("CKA_TRUST_STEP_UP_APPROVED CK_BBOOL CK_FALSE\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// certDataLabel returns the quoted label of a certificate, its common name or
+// its subject if the common name is empty.
+func certDataLabel(crt *x509.Certificate) string {
+	label := crt.Subject.CommonName
+	if label == "" {
+		label = crt.Subject.String()
+	}
+	return strconv.Quote(label)
+}
+
+// writeOctal writes an attribute with the MULTILINE_OCTAL encoding, 16 bytes
+// per line.
+func writeOctal(buf *bytes.Buffer, name string, b []byte) {
+	fmt.Fprintf(buf, "%s MULTILINE_OCTAL\n", name)
+	for i, c := range b {
+		fmt.Fprintf(buf, "\\%03o", c)
+		if i%16 == 15 || i == len(b)-1 {
+			buf.WriteString("\n")
+		}
+	}
+	buf.WriteString("END\n")
+}
+
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,91 @@
+// Package certformat implements the certificate container formats supported
+// by the certificate format command that are not available in the standard
+// library: PKCS #7 certificate bundles, Java keystores, and the certdata.txt
+// format used by NSS.
+package certformat
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+	"go.mozilla.org/pkcs7"
+)
+
+var (
+	oidData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []asn1.RawValue `asn1:"set"`
+}
+
+// ParsePKCS7 returns the certificates and CRLs in a DER encoded PKCS #7
+// SignedData structure, like the .p7b and .p7c files.
+func ParsePKCS7(der []byte) ([]*x509.Certificate, []*x509.RevocationList, error) {
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PKCS #7")
+	}
+	crls := make([]*x509.RevocationList, 0, len(p7.CRLs))
+	for _, c := range p7.CRLs {
+		b, err := asn1.Marshal(c)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error parsing PKCS #7 CRL")
+		}
+		crl, err := x509.ParseRevocationList(b)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error parsing PKCS #7 CRL")
+		}
+		crls = append(crls, crl)
+	}
+	return p7.Certificates, crls, nil
+}
+
+// MarshalPKCS7 returns the DER encoding of a degenerate PKCS #7 SignedData
+// structure, without content or signers, with the given certificates and CRLs.
+func MarshalPKCS7(certs []*x509.Certificate, crls []*x509.RevocationList) ([]byte, error) {
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		SignerInfos:      []asn1.RawValue{},
+	}
+	if len(certs) > 0 {
+		sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true}
+		for _, crt := range certs {
+			sd.Certificates.Bytes = append(sd.Certificates.Bytes, crt.Raw...)
+		}
+	}
+	if len(crls) > 0 {
+		sd.CRLs = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true}
+		for _, crl := range crls {
+			sd.CRLs.Bytes = append(sd.CRLs.Bytes, crl.Raw...)
+		}
+	}
+
+	content, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling PKCS #7")
+	}
+	b, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling PKCS #7")
+	}
+	return b, nil
+}
@@ -0,0 +1,316 @@
+// Package certinventory implements the records of the certificates issued by
+// a CA, as stored in the step-ca database, and the filters used to search
+// them.
+package certinventory
+
+import (
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Tables of the step-ca database with the X.509 certificates.
+var (
+	CertsTable        = []byte("x509_certs")
+	CertsDataTable    = []byte("x509_certs_data")
+	RevokedCertsTable = []byte("revoked_x509_certs")
+)
+
+// Provisioner is the provisioner that authorized a certificate.
+type Provisioner struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Revocation is the revocation information of a certificate.
+type Revocation struct {
+	ProvisionerID string    `json:"ProvisionerID"`
+	ReasonCode    int       `json:"ReasonCode"`
+	Reason        string    `json:"Reason"`
+	RevokedAt     time.Time `json:"RevokedAt"`
+}
+
+// Record is a certificate issued by the CA.
+type Record struct {
+	Certificate *x509.Certificate
+	Provisioner *Provisioner
+	Revocation  *Revocation
+}
+
+// certificateData is the value of the certificates data table.
+type certificateData struct {
+	Provisioner *Provisioner `json:"provisioner,omitempty"`
+}
+
+// ParseRecord returns the record of a certificate from the values of the
+// certificates, certificates data, and revoked certificates tables. The data
+// and revocation are optional.
+func ParseRecord(der, data, revocation []byte) (*Record, error) {
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate")
+	}
+	r := &Record{Certificate: crt}
+	if len(data) > 0 {
+		var cd certificateData
+		if err := json.Unmarshal(data, &cd); err != nil {
+			return nil, errors.Wrapf(err, "error parsing data of certificate %s", crt.SerialNumber)
+		}
+		r.Provisioner = cd.Provisioner
+	}
+	if len(revocation) > 0 {
+		r.Revocation = new(Revocation)
+		if err := json.Unmarshal(revocation, r.Revocation); err != nil {
+			return nil, errors.Wrapf(err, "error parsing revocation of certificate %s", crt.SerialNumber)
+		}
+	}
+	return r, nil
+}
+
+// Status values of a certificate.
+const (
+	StatusValid   = "valid"
+	StatusExpired = "expired"
+	StatusRevoked = "revoked"
+)
+
+// Status returns the status of the certificate at the given time.
+func (r *Record) Status(now time.Time) string {
+	switch {
+	case r.Revocation != nil:
+		return StatusRevoked
+	case now.After(r.Certificate.NotAfter):
+		return StatusExpired
+	default:
+		return StatusValid
+	}
+}
+
+// Names returns the subject alternative names of the certificate.
+func (r *Record) Names() []string {
+	crt := r.Certificate
+	names := append([]string{}, crt.DNSNames...)
+	for _, ip := range crt.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, crt.EmailAddresses...)
+	for _, u := range crt.URIs {
+		names = append(names, u.String())
+	}
+	return names
+}
+
+// ParseSerial parses a serial number in decimal, or in hexadecimal with a 0x
+// prefix or with colons, and returns it in decimal, the format used as key in
+// the step-ca database.
+func ParseSerial(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	var (
+		n  = new(big.Int)
+		ok bool
+	)
+	switch {
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		_, ok = n.SetString(s[2:], 16)
+	case strings.Contains(s, ":"):
+		var b []byte
+		b, ok = decodeHex(strings.ReplaceAll(s, ":", ""))
+		n.SetBytes(b)
+	default:
+		_, ok = n.SetString(s, 10)
+	}
+	if !ok || n.Sign() < 0 {
+		return "", errors.Errorf("invalid serial number %q", s)
+	}
+	return n.String(), nil
+}
+
+func decodeHex(s string) ([]byte, bool) {
+	b, err := hex.DecodeString(s)
+	return b, err == nil && len(b) > 0
+}
+
+// Filter selects certificates. Empty fields match any certificate.
+type Filter struct {
+	// Subject matches a substring of the subject, case insensitive.
+	Subject string
+	// SAN matches a subject alternative name, case insensitive. A leading
+	// "*." matches any subdomain.
+	SAN string
+	// Provisioner matches the name or id of the provisioner.
+	Provisioner string
+	// Serial matches the serial number in decimal.
+	Serial string
+	// Query matches a substring of the subject, the subject alternative
+	// names, the serial number, or the provisioner name, case insensitive.
+	Query         string
+	IssuedAfter   time.Time
+	IssuedBefore  time.Time
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	// Status is valid, expired, or revoked.
+	Status string
+}
+
+// Match returns true if the record matches all the conditions of the filter
+// at the given time.
+func (f *Filter) Match(r *Record, now time.Time) bool {
+	crt := r.Certificate
+	switch {
+	case f.Subject != "" && !containsFold(crt.Subject.String(), f.Subject):
+		return false
+	case f.SAN != "" && !matchSAN(r.Names(), f.SAN):
+		return false
+	case f.Provisioner != "" && (r.Provisioner == nil || (r.Provisioner.Name != f.Provisioner && r.Provisioner.ID != f.Provisioner)):
+		return false
+	case f.Serial != "" && crt.SerialNumber.String() != f.Serial:
+		return false
+	case f.Query != "" && !matchQuery(r, f.Query):
+		return false
+	case !f.IssuedAfter.IsZero() && crt.NotBefore.Before(f.IssuedAfter):
+		return false
+	case !f.IssuedBefore.IsZero() && !crt.NotBefore.Before(f.IssuedBefore):
+		return false
+	case !f.ExpiresAfter.IsZero() && crt.NotAfter.Before(f.ExpiresAfter):
+		return false
+	case !f.ExpiresBefore.IsZero() && !crt.NotAfter.Before(f.ExpiresBefore):
+		return false
+	case f.Status != "" && r.Status(now) != f.Status:
+		return false
+	default:
+		return true
+	}
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func matchSAN(names []string, san string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, san) {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(san, "*"); ok && suffix != "" &&
+			strings.HasSuffix(strings.ToLower(name), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchQuery(r *Record, q string) bool {
+	crt := r.Certificate
+	if containsFold(crt.Subject.String(), q) || strings.Contains(crt.SerialNumber.String(), q) ||
+		strings.EqualFold(fmt.Sprintf("%x", crt.SerialNumber), strings.TrimPrefix(strings.ToLower(q), "0x")) {
+		return true
+	}
+	if r.Provisioner != nil && containsFold(r.Provisioner.Name, q) {
+		return true
+	}
+	for _, name := range r.Names() {
+		if containsFold(name, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort sorts the records by issuance time, oldest first, and by serial
+// number.
+func Sort(records []*Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i].Certificate, records[j].Certificate
+		if !a.NotBefore.Equal(b.NotBefore) {
+			return a.NotBefore.Before(b.NotBefore)
+		}
+		return a.SerialNumber.Cmp(b.SerialNumber) < 0
+	})
+}
+
+// Output is the representation of a record in JSON and CSV.
+type Output struct {
+	Serial      string    `json:"serial"`
+	Subject     string    `json:"subject"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	Provisioner string    `json:"provisioner,omitempty"`
+	Status      string    `json:"status"`
+	RevokedAt   string    `json:"revokedAt,omitempty"`
+	Reason      string    `json:"revocationReason,omitempty"`
+}
+
+// NewOutput returns the output of a record at the given time.
+func NewOutput(r *Record, now time.Time) *Output {
+	crt := r.Certificate
+	out := &Output{
+		Serial:    crt.SerialNumber.String(),
+		Subject:   crt.Subject.String(),
+		SANs:      r.Names(),
+		Issuer:    crt.Issuer.String(),
+		NotBefore: crt.NotBefore.UTC(),
+		NotAfter:  crt.NotAfter.UTC(),
+		Status:    r.Status(now),
+	}
+	if r.Provisioner != nil {
+		out.Provisioner = r.Provisioner.Name
+	}
+	if r.Revocation != nil {
+		out.RevokedAt = r.Revocation.RevokedAt.UTC().Format(time.RFC3339)
+		out.Reason = r.Revocation.Reason
+	}
+	return out
+}
+
+// Write writes the records to w in the given format: table, json, or csv.
+func Write(w io.Writer, format string, records []*Record, now time.Time) error {
+	switch format {
+	case "json":
+		list := make([]*Output, len(records))
+		for i, r := range records {
+			list[i] = NewOutput(r, now)
+		}
+		b, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling certificates")
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"serial", "subject", "sans", "issuer", "not_before", "not_after", "provisioner", "status", "revoked_at", "revocation_reason"})
+		for _, r := range records {
+			o := NewOutput(r, now)
+			cw.Write([]string{
+				o.Serial, o.Subject, strings.Join(o.SANs, " "), o.Issuer,
+				o.NotBefore.Format(time.RFC3339), o.NotAfter.Format(time.RFC3339),
+				o.Provisioner, o.Status, o.RevokedAt, o.Reason,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		format := "%-40s%-30s%-22s%-22s%-9s%s\n"
+		fmt.Fprintf(w, format, "Serial", "Subject", "Not After", "Provisioner", "Status", "SANs")
+		for _, r := range records {
+			o := NewOutput(r, now)
+			fmt.Fprintf(w, format, o.Serial, r.Certificate.Subject.CommonName, o.NotAfter.Format(time.RFC3339),
+				o.Provisioner, o.Status, strings.Join(o.SANs, ","))
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported format %q", format)
+	}
+}
@@ -0,0 +1,160 @@
+package certinventory
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCertificate(t *testing.T, serial int64, cn string, dnsNames []string, notBefore time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	return der
+}
+
+func TestParseRecord(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	der := newCertificate(t, 1234, "www.example.com", []string{"www.example.com"}, now)
+
+	r, err := ParseRecord(der, []byte(`{"provisioner":{"id":"prov-id","name":"acme","type":"ACME"}}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &Provisioner{ID: "prov-id", Name: "acme", Type: "ACME"}, r.Provisioner)
+	assert.Nil(t, r.Revocation)
+	assert.Equal(t, StatusValid, r.Status(now))
+	assert.Equal(t, StatusExpired, r.Status(now.Add(48*time.Hour)))
+	assert.Equal(t, []string{"www.example.com", "10.0.0.1"}, r.Names())
+
+	revocation, err := json.Marshal(map[string]any{
+		"Serial": "1234", "ReasonCode": 1, "Reason": "key compromise", "RevokedAt": now, "MTLS": false,
+	})
+	require.NoError(t, err)
+	r, err = ParseRecord(der, nil, revocation)
+	require.NoError(t, err)
+	assert.Nil(t, r.Provisioner)
+	assert.Equal(t, StatusRevoked, r.Status(now))
+	assert.Equal(t, "key compromise", r.Revocation.Reason)
+	assert.True(t, now.Equal(r.Revocation.RevokedAt))
+
+	_, err = ParseRecord([]byte("foo"), nil, nil)
+	assert.Error(t, err)
+	_, err = ParseRecord(der, []byte("{"), nil)
+	assert.Error(t, err)
+}
+
+func TestParseSerial(t *testing.T) {
+	for _, s := range []string{"1234", "0x4d2", "0X04D2", "04:d2"} {
+		serial, err := ParseSerial(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, "1234", serial, s)
+	}
+	for _, s := range []string{"", "foo", "0xzz", "-1", "zz:zz"} {
+		_, err := ParseSerial(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	parse := func(serial int64, cn string, dnsNames []string, notBefore time.Time, prov string, revoked bool) *Record {
+		r, err := ParseRecord(newCertificate(t, serial, cn, dnsNames, notBefore), nil, nil)
+		require.NoError(t, err)
+		if prov != "" {
+			r.Provisioner = &Provisioner{ID: prov + "-id", Name: prov}
+		}
+		if revoked {
+			r.Revocation = &Revocation{Reason: "superseded"}
+		}
+		return r
+	}
+	web := parse(1, "www.example.com", []string{"www.example.com"}, now.Add(-time.Hour), "acme", false)
+	api := parse(2, "api", []string{"api.internal.example.com"}, now.Add(-48*time.Hour), "jwk", false)
+	old := parse(3, "old", []string{"old.example.org"}, now.Add(-time.Hour), "acme", true)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []bool
+	}{
+		{"empty", Filter{}, []bool{true, true, true}},
+		{"subject", Filter{Subject: "CN=WWW"}, []bool{true, false, false}},
+		{"san", Filter{SAN: "API.internal.example.com"}, []bool{false, true, false}},
+		{"san wildcard", Filter{SAN: "*.example.com"}, []bool{true, true, false}},
+		{"san ip", Filter{SAN: "10.0.0.1"}, []bool{true, true, true}},
+		{"provisioner name", Filter{Provisioner: "acme"}, []bool{true, false, true}},
+		{"provisioner id", Filter{Provisioner: "jwk-id"}, []bool{false, true, false}},
+		{"serial", Filter{Serial: "2"}, []bool{false, true, false}},
+		{"query", Filter{Query: "example.org"}, []bool{false, false, true}},
+		{"query provisioner", Filter{Query: "JWK"}, []bool{false, true, false}},
+		{"issued after", Filter{IssuedAfter: now.Add(-2 * time.Hour)}, []bool{true, false, true}},
+		{"issued before", Filter{IssuedBefore: now.Add(-2 * time.Hour)}, []bool{false, true, false}},
+		{"expires before", Filter{ExpiresBefore: now}, []bool{false, true, false}},
+		{"expires after", Filter{ExpiresAfter: now}, []bool{true, false, true}},
+		{"valid", Filter{Status: StatusValid}, []bool{true, false, false}},
+		{"expired", Filter{Status: StatusExpired}, []bool{false, true, false}},
+		{"revoked", Filter{Status: StatusRevoked}, []bool{false, false, true}},
+		{"combined", Filter{Provisioner: "acme", Status: StatusValid}, []bool{true, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []bool{tt.filter.Match(web, now), tt.filter.Match(api, now), tt.filter.Match(old, now)}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSortAndWrite(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	var records []*Record
+	for i, cn := range []string{"b", "a"} {
+		r, err := ParseRecord(newCertificate(t, int64(i+1), cn, nil, now.Add(-time.Duration(i)*time.Hour)), nil, nil)
+		require.NoError(t, err)
+		records = append(records, r)
+	}
+	records[0].Provisioner = &Provisioner{Name: "acme"}
+	Sort(records)
+	assert.Equal(t, "a", records[0].Certificate.Subject.CommonName)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "json", records, now))
+	var out []Output
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	require.Len(t, out, 2)
+	assert.Equal(t, "2", out[0].Serial)
+	assert.Equal(t, "acme", out[1].Provisioner)
+	assert.Equal(t, StatusValid, out[1].Status)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, "csv", records, now))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[2], "1,CN=b,10.0.0.1,CN=b,"))
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, "table", records, now))
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 3)
+
+	assert.Error(t, Write(&buf, "xml", records, now))
+}
@@ -0,0 +1,323 @@
+// Package chainutil builds certificate chains, fetching the missing
+// intermediates using the CA issuers URLs in the authority information access
+// extension of the certificates.
+package chainutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mozilla.org/pkcs7"
+)
+
+// DefaultMaxDepth is the default maximum number of intermediates in a chain.
+const DefaultMaxDepth = 8
+
+// maxResponseSize is the maximum size of a CA issuers response.
+const maxResponseSize = 1 << 20
+
+// Source indicates where a certificate in the chain comes from.
+type Source string
+
+// Certificate sources.
+const (
+	// SourceProvided is used for the certificates given to the builder.
+	SourceProvided Source = "provided"
+	// SourceCache is used for the certificates read from the cache.
+	SourceCache Source = "cache"
+	// SourceFetched is used for the certificates downloaded from a CA
+	// issuers URL.
+	SourceFetched Source = "fetched"
+)
+
+// Link is a certificate in the chain and the location it was obtained from.
+type Link struct {
+	Certificate *x509.Certificate
+	Source      Source
+	// URL is the CA issuers URL of the certificate, if it was fetched or read
+	// from the cache.
+	URL string
+}
+
+// Chain is a certificate chain built from a leaf certificate.
+type Chain struct {
+	Leaf *x509.Certificate
+	// Links are the intermediates from the issuer of the leaf to the
+	// certificate issued by the root.
+	Links []Link
+	// Root is the trusted root certificate that terminates the chain.
+	Root *x509.Certificate
+}
+
+// Certificates returns the leaf and the intermediates of the chain, the root
+// is not included.
+func (c *Chain) Certificates() []*x509.Certificate {
+	certs := []*x509.Certificate{c.Leaf}
+	for _, l := range c.Links {
+		certs = append(certs, l.Certificate)
+	}
+	return certs
+}
+
+// Builder builds certificate chains.
+type Builder struct {
+	// Roots are the trusted roots. If nil, the system roots are used.
+	Roots *x509.CertPool
+	// Intermediates are certificates that are used before fetching them.
+	Intermediates []*x509.Certificate
+	// CacheDir is the directory used to cache the downloaded certificates.
+	// If empty, the cache is not used.
+	CacheDir string
+	// Client is the client used to download the certificates. If nil, a
+	// client with a 30 seconds timeout is used.
+	Client *http.Client
+	// MaxDepth is the maximum number of intermediates. If 0,
+	// DefaultMaxDepth is used.
+	MaxDepth int
+}
+
+// Build builds the chain of the given certificate until a trusted root. Each
+// intermediate must have signed the previous certificate in the chain, and the
+// finished chain is verified, so intermediates that are expired, are not CAs,
+// or do not allow the length of the chain are reported.
+func (b *Builder) Build(leaf *x509.Certificate) (*Chain, error) {
+	roots := b.Roots
+	if roots == nil {
+		var err error
+		if roots, err = x509.SystemCertPool(); err != nil {
+			return nil, errors.Wrap(err, "error loading the system roots")
+		}
+	}
+	maxDepth := b.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	chain := &Chain{Leaf: leaf}
+	cur := leaf
+	for {
+		root, err := trustedIssuer(cur, roots)
+		if err != nil {
+			return chain, err
+		}
+		if root != nil {
+			chain.Root = root
+			return chain, chain.verify(roots)
+		}
+		if isSelfSigned(cur) {
+			return chain, errors.Errorf("certificate %q is a self-signed certificate not in the trusted roots", cur.Subject)
+		}
+		if len(chain.Links) == maxDepth {
+			return chain, errors.Errorf("certificate chain is longer than %d intermediates", maxDepth)
+		}
+		link, err := b.findIssuer(cur)
+		if err != nil {
+			return chain, err
+		}
+		for _, l := range chain.Links {
+			if l.Certificate.Equal(link.Certificate) {
+				return chain, errors.Errorf("certificate chain has a loop in %q", link.Certificate.Subject)
+			}
+		}
+		chain.Links = append(chain.Links, *link)
+		cur = link.Certificate
+	}
+}
+
+// trustedIssuer returns the root that issued the given certificate, or nil if
+// the certificate is not issued by a trusted root.
+func trustedIssuer(crt *x509.Certificate, roots *x509.CertPool) (*x509.Certificate, error) {
+	chains, err := crt.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		var cie x509.CertificateInvalidError
+		if errors.As(err, &cie) && cie.Reason == x509.Expired {
+			return nil, errors.Wrapf(err, "certificate %q is not valid", crt.Subject)
+		}
+		return nil, nil
+	}
+	c := chains[0]
+	return c[len(c)-1], nil
+}
+
+// verify verifies the chain from the leaf to the trusted roots using only the
+// intermediates in the chain.
+func (c *Chain) verify(roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, l := range c.Links {
+		intermediates.AddCert(l.Certificate)
+	}
+	if _, err := c.Leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return errors.Wrap(err, "certificate chain is not valid")
+	}
+	return nil
+}
+
+func isSelfSigned(crt *x509.Certificate) bool {
+	return bytes.Equal(crt.RawIssuer, crt.RawSubject) && crt.CheckSignatureFrom(crt) == nil
+}
+
+// findIssuer looks for the issuer of the certificate in the provided
+// intermediates, the cache, and the CA issuers URLs.
+func (b *Builder) findIssuer(crt *x509.Certificate) (*Link, error) {
+	if issuer := selectIssuer(crt, b.Intermediates); issuer != nil {
+		return &Link{Certificate: issuer, Source: SourceProvided}, nil
+	}
+
+	var urls []string
+	for _, u := range crt.IssuingCertificateURL {
+		if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.Errorf("cannot find the issuer of %q: the certificate does not have a CA issuers URL", crt.Subject)
+	}
+
+	// Look in the cache first
+	for _, u := range urls {
+		if certs, err := b.readCache(u); err == nil {
+			if issuer := selectIssuer(crt, certs); issuer != nil {
+				return &Link{Certificate: issuer, Source: SourceCache, URL: u}, nil
+			}
+		}
+	}
+
+	var errs []string
+	for _, u := range urls {
+		certs, err := b.fetch(u)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		issuer := selectIssuer(crt, certs)
+		if issuer == nil {
+			errs = append(errs, "certificates in "+u+" did not sign the certificate")
+			continue
+		}
+		// Errors writing the cache are ignored
+		_ = b.writeCache(u, certs)
+		return &Link{Certificate: issuer, Source: SourceFetched, URL: u}, nil
+	}
+	return nil, errors.Errorf("cannot find the issuer of %q: %s", crt.Subject, strings.Join(errs, "; "))
+}
+
+// selectIssuer returns the certificate in the list that signed the given
+// certificate.
+func selectIssuer(crt *x509.Certificate, certs []*x509.Certificate) *x509.Certificate {
+	for _, c := range certs {
+		if bytes.Equal(c.RawSubject, crt.RawIssuer) && crt.CheckSignatureFrom(c) == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+func (b *Builder) fetch(u string) ([]*x509.Certificate, error) {
+	client := b.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error downloading %s", u)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error downloading %s: %s", u, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error downloading %s", u)
+	}
+	certs, err := ParseCertificates(body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", u)
+	}
+	return certs, nil
+}
+
+// ParseCertificates parses the certificates in a CA issuers response. It
+// supports DER and PEM encoded certificates, and PKCS #7 certs-only
+// structures, usually served with the .p7c extension.
+func ParseCertificates(b []byte) ([]*x509.Certificate, error) {
+	if certs, err := x509.ParseCertificates(b); err == nil && len(certs) > 0 {
+		return certs, nil
+	}
+
+	var certs []*x509.Certificate
+	if block, rest := pem.Decode(b); block != nil {
+		for ; block != nil; block, rest = pem.Decode(rest) {
+			switch block.Type {
+			case "CERTIFICATE":
+				crt, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, errors.Wrap(err, "error parsing certificate")
+				}
+				certs = append(certs, crt)
+			case "PKCS7", "CERTIFICATE CHAIN":
+				p7, err := pkcs7.Parse(block.Bytes)
+				if err != nil {
+					return nil, errors.Wrap(err, "error parsing PKCS #7")
+				}
+				certs = append(certs, p7.Certificates...)
+			}
+		}
+	} else if p7, err := pkcs7.Parse(b); err == nil {
+		certs = p7.Certificates
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+func (b *Builder) cacheFile(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(b.CacheDir, hex.EncodeToString(sum[:])+".crt")
+}
+
+func (b *Builder) readCache(u string) ([]*x509.Certificate, error) {
+	if b.CacheDir == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(b.cacheFile(u))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificates(data)
+}
+
+func (b *Builder) writeCache(u string, certs []*x509.Certificate) error {
+	if b.CacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(b.CacheDir, 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, crt := range certs {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw}); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(b.cacheFile(u), buf.Bytes(), 0o600)
+}
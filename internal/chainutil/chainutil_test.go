@@ -0,0 +1,238 @@
+package chainutil
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mozilla.org/pkcs7"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/minica"
+	"go.step.sm/crypto/x509util"
+)
+
+// caTemplate is a CA template without a path length constraint, the test
+// chains have two intermediates.
+const caTemplate = `{
+	"subject": {{ toJson .Subject }},
+	"keyUsage": ["certSign", "crlSign"],
+	"basicConstraints": {
+		"isCA": true,
+		"maxPathLen": -1
+	}
+}`
+
+type testPKI struct {
+	root, int1, int2, leaf *x509.Certificate
+	srv                    *httptest.Server
+	requests               atomic.Int32
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := new(testPKI)
+	mux := http.NewServeMux()
+	p.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.requests.Add(1)
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(p.srv.Close)
+
+	ca, err := minica.New(minica.WithRootTemplate(caTemplate), minica.WithIntermediateTemplate(caTemplate))
+	require.NoError(t, err)
+	p.root, p.int1 = ca.Root, ca.Intermediate
+
+	int2Key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	p.int2, err = ca.Sign(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Intermediate 2"},
+		PublicKey:             int2Key.Public(),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		IssuingCertificateURL: []string{"ldap://example.com/ignored", p.srv.URL + "/int1.p7c"},
+	})
+	require.NoError(t, err)
+
+	leafKey, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	p.leaf, err = x509util.CreateCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "leaf"},
+		NotBefore:             p.int2.NotBefore,
+		NotAfter:              p.int2.NotAfter,
+		IssuingCertificateURL: []string{"ldap://example.com/ignored", p.srv.URL + "/int2.cer"},
+	}, p.int2, leafKey.Public(), int2Key)
+	require.NoError(t, err)
+
+	p7c, err := pkcs7.DegenerateCertificate(p.int1.Raw)
+	require.NoError(t, err)
+	mux.HandleFunc("/int1.p7c", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(p7c)
+	})
+	mux.HandleFunc("/int2.cer", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(p.int2.Raw)
+	})
+	return p
+}
+
+func (p *testPKI) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.root)
+	return pool
+}
+
+func TestBuilder_Build(t *testing.T) {
+	p := newTestPKI(t)
+	cacheDir := t.TempDir()
+
+	b := &Builder{Roots: p.roots(), CacheDir: cacheDir}
+	chain, err := b.Build(p.leaf)
+	require.NoError(t, err)
+	assert.Equal(t, p.root, chain.Root)
+	require.Len(t, chain.Links, 2)
+	assert.Equal(t, Link{Certificate: p.int2, Source: SourceFetched, URL: p.srv.URL + "/int2.cer"}, chain.Links[0])
+	assert.Equal(t, Link{Certificate: p.int1, Source: SourceFetched, URL: p.srv.URL + "/int1.p7c"}, chain.Links[1])
+	assert.Equal(t, []*x509.Certificate{p.leaf, p.int2, p.int1}, chain.Certificates())
+	assert.Equal(t, int32(2), p.requests.Load())
+
+	// The second time the certificates are read from the cache.
+	chain, err = b.Build(p.leaf)
+	require.NoError(t, err)
+	require.Len(t, chain.Links, 2)
+	assert.Equal(t, SourceCache, chain.Links[0].Source)
+	assert.Equal(t, SourceCache, chain.Links[1].Source)
+	assert.Equal(t, int32(2), p.requests.Load())
+
+	// Provided intermediates are used first.
+	b = &Builder{Roots: p.roots(), Intermediates: []*x509.Certificate{p.int1, p.int2}}
+	chain, err = b.Build(p.leaf)
+	require.NoError(t, err)
+	assert.Equal(t, []Link{
+		{Certificate: p.int2, Source: SourceProvided},
+		{Certificate: p.int1, Source: SourceProvided},
+	}, chain.Links)
+	assert.Equal(t, int32(2), p.requests.Load())
+
+	// An intermediate can be the starting point.
+	chain, err = b.Build(p.int1)
+	require.NoError(t, err)
+	assert.Empty(t, chain.Links)
+	assert.Equal(t, p.root, chain.Root)
+}
+
+func TestBuilder_Build_errors(t *testing.T) {
+	p := newTestPKI(t)
+	other := newTestPKI(t)
+
+	// Untrusted root
+	b := &Builder{Roots: other.roots(), Intermediates: []*x509.Certificate{p.int1, p.int2, p.root}}
+	_, err := b.Build(p.leaf)
+	assert.EqualError(t, err, `certificate "CN=MiniCA Root CA" is a self-signed certificate not in the trusted roots`)
+
+	// Missing AIA
+	b = &Builder{Roots: p.roots()}
+	_, err = b.Build(p.int1)
+	assert.NoError(t, err)
+	b = &Builder{Roots: other.roots()}
+	_, err = b.Build(p.int1)
+	assert.EqualError(t, err, `cannot find the issuer of "CN=MiniCA Intermediate CA": the certificate does not have a CA issuers URL`)
+
+	// Server down
+	p.srv.Close()
+	b = &Builder{Roots: p.roots()}
+	_, err = b.Build(p.leaf)
+	assert.ErrorContains(t, err, `cannot find the issuer of "CN=leaf": error downloading `+p.srv.URL+"/int2.cer")
+
+	// Max depth
+	b = &Builder{Roots: p.roots(), Intermediates: []*x509.Certificate{p.int1, p.int2}, MaxDepth: 1}
+	_, err = b.Build(p.leaf)
+	assert.EqualError(t, err, "certificate chain is longer than 1 intermediates")
+}
+
+func TestBuilder_Build_invalidIntermediate(t *testing.T) {
+	// The minica intermediate has a path length of 0.
+	ca, err := minica.New()
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Root)
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		wantErr   string
+	}{
+		{"expired", now.Add(-2 * time.Hour), now.Add(-time.Hour), `certificate "CN=Intermediate 2" is not valid: x509: certificate has expired or is not yet valid`},
+		{"path length", now, now.Add(time.Hour), "certificate chain is not valid: x509: too many intermediates for path length constraint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := keyutil.GenerateDefaultSigner()
+			require.NoError(t, err)
+			intermediate, err := ca.Sign(&x509.Certificate{
+				Subject:               pkix.Name{CommonName: "Intermediate 2"},
+				PublicKey:             key.Public(),
+				NotBefore:             tt.notBefore,
+				NotAfter:              tt.notAfter,
+				IsCA:                  true,
+				BasicConstraintsValid: true,
+				KeyUsage:              x509.KeyUsageCertSign,
+			})
+			require.NoError(t, err)
+			leafKey, err := keyutil.GenerateDefaultSigner()
+			require.NoError(t, err)
+			leaf, err := x509util.CreateCertificate(&x509.Certificate{
+				Subject:   pkix.Name{CommonName: "leaf"},
+				NotBefore: now,
+				NotAfter:  now.Add(time.Hour),
+			}, intermediate, leafKey.Public(), key)
+			require.NoError(t, err)
+
+			b := &Builder{Roots: roots, Intermediates: []*x509.Certificate{intermediate, ca.Intermediate}}
+			_, err = b.Build(leaf)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseCertificates(t *testing.T) {
+	p := newTestPKI(t)
+	p7c, err := pkcs7.DegenerateCertificate(append(p.int1.Raw, p.int2.Raw...))
+	require.NoError(t, err)
+	pemBundle := append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.int1.Raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.int2.Raw})...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []*x509.Certificate
+		wantErr bool
+	}{
+		{"der", p.int1.Raw, []*x509.Certificate{p.int1}, false},
+		{"pem", pemBundle, []*x509.Certificate{p.int1, p.int2}, false},
+		{"p7c", p7c, []*x509.Certificate{p.int1, p.int2}, false},
+		{"p7c pem", pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: p7c}), []*x509.Certificate{p.int1, p.int2}, false},
+		{"fail", []byte("not a certificate"), nil, true},
+		{"fail pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("foo")}), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCertificates(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
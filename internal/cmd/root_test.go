@@ -22,7 +22,7 @@ func TestAppHasAllCommands(t *testing.T) {
 	require.Equal(t, []string{
 		"help", "api", "base64", "fileserver", "path",
 		"certificate", "completion", "context", "crl",
-		"crypto", "oauth", "version", "ca", "beta", "ssh",
+		"crypto", "oauth", "ocsp", "version", "ca", "beta", "ssh",
 	}, names)
 }
 
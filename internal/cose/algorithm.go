@@ -0,0 +1,247 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithm is a COSE algorithm identifier as registered in the IANA "COSE
+// Algorithms" registry.
+type Algorithm int64
+
+// Supported algorithms.
+const (
+	// Signature algorithms.
+	ES256 Algorithm = -7
+	ES384 Algorithm = -35
+	ES512 Algorithm = -36
+	EdDSA Algorithm = -8
+	PS256 Algorithm = -37
+	PS384 Algorithm = -38
+	PS512 Algorithm = -39
+	RS256 Algorithm = -257
+	RS384 Algorithm = -258
+	RS512 Algorithm = -259
+
+	// MAC algorithms.
+	HMAC256_64 Algorithm = 4
+	HMAC256    Algorithm = 5
+	HMAC384    Algorithm = 6
+	HMAC512    Algorithm = 7
+
+	// Content encryption algorithms.
+	A128GCM          Algorithm = 1
+	A192GCM          Algorithm = 2
+	A256GCM          Algorithm = 3
+	ChaCha20Poly1305 Algorithm = 24
+)
+
+var algorithmNames = map[Algorithm]string{
+	ES256:            "ES256",
+	ES384:            "ES384",
+	ES512:            "ES512",
+	EdDSA:            "EdDSA",
+	PS256:            "PS256",
+	PS384:            "PS384",
+	PS512:            "PS512",
+	RS256:            "RS256",
+	RS384:            "RS384",
+	RS512:            "RS512",
+	HMAC256_64:       "HMAC256/64",
+	HMAC256:          "HMAC256/256",
+	HMAC384:          "HMAC384/384",
+	HMAC512:          "HMAC512/512",
+	A128GCM:          "A128GCM",
+	A192GCM:          "A192GCM",
+	A256GCM:          "A256GCM",
+	ChaCha20Poly1305: "ChaCha20/Poly1305",
+}
+
+// ParseAlgorithm returns the algorithm for the given name. Names are case
+// insensitive and the JOSE names of the HMAC algorithms (HS256, HS384 and
+// HS512) are also accepted.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch strings.ToUpper(name) {
+	case "HS256":
+		return HMAC256, nil
+	case "HS384":
+		return HMAC384, nil
+	case "HS512":
+		return HMAC512, nil
+	case "CHACHA20POLY1305", "C20P":
+		return ChaCha20Poly1305, nil
+	}
+	for alg, s := range algorithmNames {
+		if strings.EqualFold(s, name) {
+			return alg, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported algorithm %q", name)
+}
+
+// String returns the name of the algorithm.
+func (a Algorithm) String() string {
+	if s, ok := algorithmNames[a]; ok {
+		return s
+	}
+	return fmt.Sprintf("Algorithm(%d)", int64(a))
+}
+
+// IsSignature returns true if a is a digital signature algorithm.
+func (a Algorithm) IsSignature() bool {
+	switch a {
+	case ES256, ES384, ES512, EdDSA, PS256, PS384, PS512, RS256, RS384, RS512:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsMAC returns true if a is a message authentication code algorithm.
+func (a Algorithm) IsMAC() bool {
+	switch a {
+	case HMAC256_64, HMAC256, HMAC384, HMAC512:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsEncryption returns true if a is a content encryption algorithm.
+func (a Algorithm) IsEncryption() bool {
+	switch a {
+	case A128GCM, A192GCM, A256GCM, ChaCha20Poly1305:
+		return true
+	default:
+		return false
+	}
+}
+
+// HashFunc returns the hash function used by signature and MAC algorithms.
+func (a Algorithm) HashFunc() crypto.Hash {
+	switch a {
+	case ES256, PS256, RS256, HMAC256_64, HMAC256:
+		return crypto.SHA256
+	case ES384, PS384, RS384, HMAC384:
+		return crypto.SHA384
+	case ES512, PS512, RS512, HMAC512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+// keySize returns the size in bytes of the symmetric key required by MAC and
+// content encryption algorithms.
+func (a Algorithm) keySize() int {
+	switch a {
+	case A128GCM:
+		return 16
+	case A192GCM:
+		return 24
+	case A256GCM, ChaCha20Poly1305, HMAC256_64, HMAC256:
+		return 32
+	case HMAC384:
+		return 48
+	case HMAC512:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// SignatureAlgorithm returns the default signature algorithm for the given
+// public key.
+func SignatureAlgorithm(pub crypto.PublicKey) (Algorithm, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ES256, nil
+		case elliptic.P384():
+			return ES384, nil
+		case elliptic.P521():
+			return ES512, nil
+		default:
+			return 0, errors.Errorf("unsupported elliptic curve %s", k.Params().Name)
+		}
+	case *rsa.PublicKey:
+		return PS256, nil
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	default:
+		return 0, errors.Errorf("unsupported key type %T", pub)
+	}
+}
+
+// MACAlgorithm returns the default MAC algorithm for a key of the given size.
+func MACAlgorithm(key []byte) (Algorithm, error) {
+	switch {
+	case len(key) >= 64:
+		return HMAC512, nil
+	case len(key) >= 48:
+		return HMAC384, nil
+	case len(key) >= 32:
+		return HMAC256, nil
+	default:
+		return 0, errors.Errorf("invalid key size %d: keys must be at least 32 bytes", len(key))
+	}
+}
+
+// EncryptionAlgorithm returns the default content encryption algorithm for a
+// key of the given size.
+func EncryptionAlgorithm(key []byte) (Algorithm, error) {
+	switch len(key) {
+	case 16:
+		return A128GCM, nil
+	case 24:
+		return A192GCM, nil
+	case 32:
+		return A256GCM, nil
+	default:
+		return 0, errors.Errorf("invalid key size %d: keys must be 16, 24 or 32 bytes", len(key))
+	}
+}
+
+// validateKey checks that the given public key can be used with a signature
+// algorithm.
+func (a Algorithm) validateKey(pub crypto.PublicKey) error {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		var crv elliptic.Curve
+		switch a {
+		case ES256:
+			crv = elliptic.P256()
+		case ES384:
+			crv = elliptic.P384()
+		case ES512:
+			crv = elliptic.P521()
+		}
+		if crv == nil || k.Curve != crv {
+			return errors.Errorf("algorithm %s cannot be used with a %s key", a, k.Params().Name)
+		}
+	case *rsa.PublicKey:
+		switch a {
+		case PS256, PS384, PS512, RS256, RS384, RS512:
+		default:
+			return errors.Errorf("algorithm %s cannot be used with an RSA key", a)
+		}
+		if k.Size() < 256 {
+			return errors.Errorf("invalid RSA key size %d: keys must be at least 2048 bits", k.Size()*8)
+		}
+	case ed25519.PublicKey:
+		if a != EdDSA {
+			return errors.Errorf("algorithm %s cannot be used with an Ed25519 key", a)
+		}
+	default:
+		return errors.Errorf("unsupported key type %T", pub)
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+// Package cose implements the subset of CBOR Object Signing and Encryption
+// (COSE) defined in RFC 9052 and RFC 9053 used by the step crypto cose and
+// step crypto cwt commands: single signer (COSE_Sign1), single recipient MAC
+// (COSE_Mac0) and single recipient encryption (COSE_Encrypt0) messages, and
+// CBOR Web Tokens (CWT) as defined in RFC 8392.
+package cose
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+)
+
+// MessageType is the type of a COSE message.
+type MessageType int
+
+// Supported COSE message types.
+const (
+	Sign1 MessageType = iota + 1
+	Mac0
+	Encrypt0
+)
+
+// CBOR tags for the supported COSE messages and CWT.
+const (
+	TagEncrypt0 uint64 = 16
+	TagMac0     uint64 = 17
+	TagSign1    uint64 = 18
+	TagCWT      uint64 = 61
+)
+
+// String returns the name of the message type.
+func (t MessageType) String() string {
+	switch t {
+	case Sign1:
+		return "COSE_Sign1"
+	case Mac0:
+		return "COSE_Mac0"
+	case Encrypt0:
+		return "COSE_Encrypt0"
+	default:
+		return fmt.Sprintf("MessageType(%d)", int(t))
+	}
+}
+
+// Tag returns the CBOR tag of the message type.
+func (t MessageType) Tag() uint64 {
+	switch t {
+	case Sign1:
+		return TagSign1
+	case Mac0:
+		return TagMac0
+	case Encrypt0:
+		return TagEncrypt0
+	default:
+		return 0
+	}
+}
+
+// Common header parameter labels.
+const (
+	HeaderAlgorithm   int64 = 1
+	HeaderCritical    int64 = 2
+	HeaderContentType int64 = 3
+	HeaderKeyID       int64 = 4
+	HeaderIV          int64 = 5
+	HeaderPartialIV   int64 = 6
+)
+
+var headerNames = map[int64]string{
+	HeaderAlgorithm:   "alg",
+	HeaderCritical:    "crit",
+	HeaderContentType: "content type",
+	HeaderKeyID:       "kid",
+	HeaderIV:          "IV",
+	HeaderPartialIV:   "Partial IV",
+}
+
+var (
+	encMode cbor.EncMode
+	decMode cbor.DecMode
+)
+
+func init() {
+	var err error
+	if encMode, err = cbor.CoreDetEncOptions().EncMode(); err != nil {
+		panic(err)
+	}
+	if decMode, err = (cbor.DecOptions{
+		IntDec: cbor.IntDecConvertSignedOrFail,
+	}).DecMode(); err != nil {
+		panic(err)
+	}
+}
+
+// Marshal encodes v using the deterministic CBOR encoding.
+func Marshal(v interface{}) ([]byte, error) {
+	return encMode.Marshal(v)
+}
+
+// Unmarshal decodes the CBOR data into v. Integers decoded into an empty
+// interface are always returned as int64.
+func Unmarshal(data []byte, v interface{}) error {
+	return decMode.Unmarshal(data, v)
+}
+
+// Header is a COSE header map. Labels are int64 or string values.
+type Header map[interface{}]interface{}
+
+// Get returns the value for the given label.
+func (h Header) Get(label int64) (interface{}, bool) {
+	if h == nil {
+		return nil, false
+	}
+	v, ok := h[label]
+	return v, ok
+}
+
+// Algorithm returns the value of the alg header parameter.
+func (h Header) Algorithm() (Algorithm, bool) {
+	v, ok := h.Get(HeaderAlgorithm)
+	if !ok {
+		return 0, false
+	}
+	switch a := v.(type) {
+	case Algorithm:
+		return a, true
+	case int64:
+		return Algorithm(a), true
+	case string:
+		alg, err := ParseAlgorithm(a)
+		return alg, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// KeyID returns the value of the kid header parameter.
+func (h Header) KeyID() []byte {
+	if v, ok := h.Get(HeaderKeyID); ok {
+		if b, ok := v.([]byte); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// Map returns a representation of the header that can be encoded as JSON,
+// using the parameter names registered by IANA when possible.
+func (h Header) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(h))
+	for k, v := range h {
+		name := fmt.Sprint(k)
+		if label, ok := k.(int64); ok {
+			if s, ok := headerNames[label]; ok {
+				name = s
+			}
+			if label == HeaderAlgorithm {
+				if a, ok := v.(int64); ok {
+					v = Algorithm(a).String()
+				}
+			}
+		}
+		m[name] = jsonValue(v)
+	}
+	return m
+}
+
+func (h Header) encode() ([]byte, error) {
+	if len(h) == 0 {
+		return []byte{}, nil
+	}
+	return Marshal(map[interface{}]interface{}(h))
+}
+
+func decodeHeader(b []byte) (Header, error) {
+	if len(b) == 0 {
+		return Header{}, nil
+	}
+	var h Header
+	if err := Unmarshal(b, &h); err != nil {
+		return nil, errors.Wrap(err, "error decoding protected header")
+	}
+	return h, nil
+}
+
+// Message is a COSE_Sign1, COSE_Mac0 or COSE_Encrypt0 message.
+type Message struct {
+	Type        MessageType
+	Protected   Header
+	Unprotected Header
+	// Payload is the content of a COSE_Sign1 or COSE_Mac0 message, and the
+	// ciphertext of a COSE_Encrypt0 message. It is nil if the content is
+	// detached.
+	Payload []byte
+	// Signature is the signature of a COSE_Sign1 message or the tag of a
+	// COSE_Mac0 message.
+	Signature []byte
+	// Tags are the CBOR tags that enclose the message, from the outermost
+	// to the innermost.
+	Tags []uint64
+
+	rawProtected []byte
+}
+
+// Algorithm returns the algorithm of the message, looking first in the
+// protected header.
+func (m *Message) Algorithm() (Algorithm, bool) {
+	if alg, ok := m.Protected.Algorithm(); ok {
+		return alg, true
+	}
+	return m.Unprotected.Algorithm()
+}
+
+// KeyID returns the key id of the message, looking first in the protected
+// header.
+func (m *Message) KeyID() []byte {
+	if kid := m.Protected.KeyID(); kid != nil {
+		return kid
+	}
+	return m.Unprotected.KeyID()
+}
+
+// IsDetached returns true if the content of the message is not included.
+func (m *Message) IsDetached() bool {
+	return m.Payload == nil
+}
+
+func (m *Message) protectedBytes() ([]byte, error) {
+	if m.rawProtected != nil {
+		return m.rawProtected, nil
+	}
+	b, err := m.Protected.encode()
+	if err != nil {
+		return nil, errors.Wrap(err, "error encoding protected header")
+	}
+	m.rawProtected = b
+	return b, nil
+}
+
+// MarshalCBOR encodes the message, adding its tags.
+func (m *Message) MarshalCBOR() ([]byte, error) {
+	protected, err := m.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	unprotected := m.Unprotected
+	if unprotected == nil {
+		unprotected = Header{}
+	}
+
+	var payload interface{}
+	if m.Payload != nil {
+		payload = m.Payload
+	}
+
+	var v interface{}
+	switch m.Type {
+	case Sign1, Mac0:
+		v = []interface{}{protected, map[interface{}]interface{}(unprotected), payload, m.Signature}
+	case Encrypt0:
+		v = []interface{}{protected, map[interface{}]interface{}(unprotected), payload}
+	default:
+		return nil, errors.Errorf("unsupported message type %s", m.Type)
+	}
+
+	for i := len(m.Tags) - 1; i >= 0; i-- {
+		v = cbor.Tag{Number: m.Tags[i], Content: v}
+	}
+	return Marshal(v)
+}
+
+// Parse parses a tagged or untagged COSE message. An untagged message is
+// identified by its length and the presence of an algorithm in the headers:
+// three elements for a COSE_Encrypt0 message, and four for a COSE_Sign1 or a
+// COSE_Mac0, which are told apart by the algorithm.
+func Parse(data []byte) (*Message, error) {
+	var tags []uint64
+	content := data
+	for len(content) > 0 && content[0]>>5 == 6 {
+		var tag cbor.RawTag
+		if err := Unmarshal(content, &tag); err != nil {
+			return nil, errors.Wrap(err, "error decoding message")
+		}
+		tags = append(tags, tag.Number)
+		content = tag.Content
+	}
+
+	var parts []cbor.RawMessage
+	if err := Unmarshal(content, &parts); err != nil {
+		return nil, errors.Wrap(err, "error decoding message")
+	}
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, errors.Errorf("error decoding message: unexpected number of elements %d", len(parts))
+	}
+
+	m := &Message{Tags: tags}
+	if err := Unmarshal(parts[0], &m.rawProtected); err != nil {
+		return nil, errors.Wrap(err, "error decoding protected header")
+	}
+	if m.rawProtected == nil {
+		m.rawProtected = []byte{}
+	}
+	var err error
+	if m.Protected, err = decodeHeader(m.rawProtected); err != nil {
+		return nil, err
+	}
+	if err := Unmarshal(parts[1], &m.Unprotected); err != nil {
+		return nil, errors.Wrap(err, "error decoding unprotected header")
+	}
+	if m.Unprotected == nil {
+		m.Unprotected = Header{}
+	}
+	if !bytes.Equal(parts[2], []byte{0xf6}) {
+		if err := Unmarshal(parts[2], &m.Payload); err != nil {
+			return nil, errors.Wrap(err, "error decoding payload")
+		}
+		if m.Payload == nil {
+			m.Payload = []byte{}
+		}
+	}
+
+	if len(parts) == 3 {
+		m.Type = Encrypt0
+	} else {
+		if err := Unmarshal(parts[3], &m.Signature); err != nil {
+			return nil, errors.Wrap(err, "error decoding signature")
+		}
+		m.Type = Sign1
+		if alg, ok := m.Algorithm(); ok && alg.IsMAC() {
+			m.Type = Mac0
+		}
+	}
+
+	// The innermost COSE tag takes precedence over the algorithm.
+	for i := len(tags) - 1; i >= 0; i-- {
+		switch tags[i] {
+		case TagSign1, TagMac0:
+			if m.Type == Encrypt0 {
+				return nil, errors.Errorf("error decoding message: unexpected number of elements %d", len(parts))
+			}
+			m.Type = Sign1
+			if tags[i] == TagMac0 {
+				m.Type = Mac0
+			}
+			return m, nil
+		case TagEncrypt0:
+			if m.Type != Encrypt0 {
+				return nil, errors.Errorf("error decoding message: unexpected number of elements %d", len(parts))
+			}
+			return m, nil
+		}
+	}
+
+	return m, nil
+}
+
+// jsonValue converts a decoded CBOR value into a value that can be encoded as
+// JSON.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = jsonValue(v)
+		}
+		return s
+	case cbor.Tag:
+		return map[string]interface{}{
+			"tag":   t.Number,
+			"value": jsonValue(t.Content),
+		}
+	default:
+		return v
+	}
+}
@@ -0,0 +1,191 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		signer  crypto.Signer
+		alg     Algorithm
+		wantAlg Algorithm
+	}{
+		{"P-256", p256, 0, ES256},
+		{"P-521", p521, ES512, ES512},
+		{"Ed25519", ed, 0, EdDSA},
+		{"RSA-PSS", rsaKey, 0, PS256},
+		{"RSA-PKCS1", rsaKey, RS384, RS384},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload := []byte("This is the content.")
+			m, err := Sign(tc.signer, tc.alg, payload, &SignOptions{
+				Protected: Header{HeaderContentType: "text/plain"},
+				Tags:      []uint64{TagSign1},
+			})
+			require.NoError(t, err)
+
+			b, err := m.MarshalCBOR()
+			require.NoError(t, err)
+			assert.Equal(t, byte(0xd2), b[0])
+
+			m, err = Parse(b)
+			require.NoError(t, err)
+			assert.Equal(t, Sign1, m.Type)
+			alg, ok := m.Algorithm()
+			assert.True(t, ok)
+			assert.Equal(t, tc.wantAlg, alg)
+			assert.Equal(t, payload, m.Payload)
+			require.NoError(t, m.Verify(tc.signer.Public(), nil, nil))
+
+			assert.Error(t, m.Verify(tc.signer.Public(), nil, []byte("external")))
+			m.Payload = []byte("This is not the content.")
+			assert.Error(t, m.Verify(tc.signer.Public(), nil, nil))
+		})
+	}
+}
+
+func TestSignDetached(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	payload := []byte("detached content")
+	m, err := Sign(key, 0, payload, &SignOptions{
+		ExternalAAD: []byte("aad"),
+		Detached:    true,
+	})
+	require.NoError(t, err)
+	b, err := m.MarshalCBOR()
+	require.NoError(t, err)
+
+	m, err = Parse(b)
+	require.NoError(t, err)
+	assert.Equal(t, Sign1, m.Type)
+	assert.True(t, m.IsDetached())
+	assert.Error(t, m.Verify(key.Public(), nil, []byte("aad")))
+	assert.NoError(t, m.Verify(key.Public(), payload, []byte("aad")))
+}
+
+func TestMAC(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	for _, alg := range []Algorithm{0, HMAC256_64, HMAC256} {
+		m, err := MAC(key, alg, []byte("payload"), nil)
+		require.NoError(t, err)
+		b, err := m.MarshalCBOR()
+		require.NoError(t, err)
+
+		// Untagged messages are identified by the algorithm.
+		m, err = Parse(b)
+		require.NoError(t, err)
+		assert.Equal(t, Mac0, m.Type)
+		assert.NoError(t, m.VerifyMAC(key, nil, nil))
+		assert.Error(t, m.VerifyMAC(key[1:], nil, nil))
+	}
+
+	_, err = MAC(key, HMAC512, []byte("payload"), nil)
+	assert.Error(t, err)
+}
+
+func TestEncrypt(t *testing.T) {
+	for _, alg := range []Algorithm{A128GCM, A256GCM, ChaCha20Poly1305} {
+		key := make([]byte, alg.keySize())
+		_, err := rand.Read(key)
+		require.NoError(t, err)
+
+		m, err := Encrypt(key, alg, []byte("secret"), &SignOptions{
+			Unprotected: Header{HeaderKeyID: []byte("our-secret")},
+			Tags:        []uint64{TagEncrypt0},
+		})
+		require.NoError(t, err)
+		b, err := m.MarshalCBOR()
+		require.NoError(t, err)
+
+		m, err = Parse(b)
+		require.NoError(t, err)
+		assert.Equal(t, Encrypt0, m.Type)
+		assert.Equal(t, []byte("our-secret"), m.KeyID())
+		plaintext, err := m.Decrypt(key, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("secret"), plaintext)
+
+		_, err = m.Decrypt(key, []byte("external"))
+		assert.Error(t, err)
+	}
+}
+
+func TestParseCritical(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	m, err := Sign(key, 0, []byte("payload"), &SignOptions{
+		Protected: Header{HeaderCritical: []interface{}{int64(99)}, int64(99): "value"},
+	})
+	require.NoError(t, err)
+	b, err := m.MarshalCBOR()
+	require.NoError(t, err)
+	m, err = Parse(b)
+	require.NoError(t, err)
+	assert.EqualError(t, m.Verify(key.Public(), nil, nil), "validation failed: unrecognized critical header 99")
+}
+
+func TestCWT(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	claims := ClaimsFromMap(map[string]interface{}{
+		"iss": "coap://as.example.com",
+		"sub": "erikw",
+		"aud": "coap://light.example.com",
+		"exp": float64(now.Add(time.Hour).Unix()),
+		"cti": "0b71",
+		"foo": "bar",
+	})
+	payload, err := claims.Payload()
+	require.NoError(t, err)
+
+	m, err := Sign(key, 0, payload, &SignOptions{Tags: []uint64{TagCWT, TagSign1}})
+	require.NoError(t, err)
+	b, err := m.MarshalCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xd8, 0x3d, 0xd2}, b[:3])
+
+	m, err = Parse(b)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{TagCWT, TagSign1}, m.Tags)
+	require.NoError(t, m.Verify(key.Public(), nil, nil))
+
+	c, err := ParseClaims(m.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, "erikw", c.Map()["sub"])
+	assert.Equal(t, "bar", c.Map()["foo"])
+	assert.NoError(t, c.Validate(Expected{
+		Issuer:   "coap://as.example.com",
+		Audience: "coap://light.example.com",
+	}))
+	assert.Error(t, c.Validate(Expected{Audience: "coap://other.example.com"}))
+	assert.Error(t, c.Validate(Expected{Time: now.Add(2 * time.Hour)}))
+}
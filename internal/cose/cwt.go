@@ -0,0 +1,256 @@
+package cose
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Registered CWT claim keys as defined in RFC 8392.
+const (
+	ClaimIssuer    int64 = 1
+	ClaimSubject   int64 = 2
+	ClaimAudience  int64 = 3
+	ClaimExpiry    int64 = 4
+	ClaimNotBefore int64 = 5
+	ClaimIssuedAt  int64 = 6
+	ClaimCWTID     int64 = 7
+	ClaimConfirm   int64 = 8
+	ClaimScope     int64 = 9
+	ClaimNonce     int64 = 10
+)
+
+var claimNames = map[int64]string{
+	ClaimIssuer:    "iss",
+	ClaimSubject:   "sub",
+	ClaimAudience:  "aud",
+	ClaimExpiry:    "exp",
+	ClaimNotBefore: "nbf",
+	ClaimIssuedAt:  "iat",
+	ClaimCWTID:     "cti",
+	ClaimConfirm:   "cnf",
+	ClaimScope:     "scope",
+	ClaimNonce:     "nonce",
+}
+
+// DefaultLeeway is the default time leeway used to validate the exp and nbf
+// claims.
+const DefaultLeeway = time.Minute
+
+// Claims is the set of claims of a CBOR Web Token. Claim keys are int64 or
+// string values.
+type Claims map[interface{}]interface{}
+
+// ParseClaims decodes the CBOR encoded claims set.
+func ParseClaims(b []byte) (Claims, error) {
+	var c Claims
+	if err := Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrap(err, "error decoding claims")
+	}
+	if c == nil {
+		return nil, errors.New("error decoding claims: claims set is empty")
+	}
+	return c, nil
+}
+
+// ClaimsFromMap creates a claims set from a map with string keys, like one
+// decoded from JSON. Registered claim names are converted to their integer
+// keys, and keys that are integers are converted to int64. Integral JSON
+// numbers are converted to int64, and the cti claim is converted to a byte
+// string decoding its hexadecimal representation.
+func ClaimsFromMap(m map[string]interface{}) Claims {
+	c := make(Claims, len(m))
+	for k, v := range m {
+		var key interface{} = k
+		if label, ok := claimKey(k); ok {
+			key = label
+		}
+		v = cborValue(v)
+		if key == ClaimCWTID {
+			if s, ok := v.(string); ok {
+				if b, err := hex.DecodeString(s); err == nil {
+					v = b
+				} else {
+					v = []byte(s)
+				}
+			}
+		}
+		c[key] = v
+	}
+	return c
+}
+
+func claimKey(name string) (int64, bool) {
+	for k, v := range claimNames {
+		if v == name {
+			return k, true
+		}
+	}
+	if i, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return i, true
+	}
+	return 0, false
+}
+
+func cborValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return int64(t)
+		}
+		return t
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(t))
+		for k, v := range t {
+			m[k] = cborValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = cborValue(v)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// Set sets the value of a claim.
+func (c Claims) Set(key int64, v interface{}) {
+	c[key] = v
+}
+
+// String returns the value of a text string claim.
+func (c Claims) String(key int64) (string, bool) {
+	s, ok := c[key].(string)
+	return s, ok
+}
+
+// Time returns the value of a NumericDate claim.
+func (c Claims) Time(key int64) (time.Time, bool) {
+	switch t := c[key].(type) {
+	case int64:
+		return time.Unix(t, 0), true
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	case float32:
+		sec, frac := math.Modf(float64(t))
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// Audience returns the values of the aud claim, which can be a text string or
+// an array of text strings.
+func (c Claims) Audience() []string {
+	switch aud := c[ClaimAudience].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		var s []string
+		for _, a := range aud {
+			if v, ok := a.(string); ok {
+				s = append(s, v)
+			}
+		}
+		return s
+	default:
+		return nil
+	}
+}
+
+// Map returns a representation of the claims that can be encoded as JSON,
+// using the registered claim names when possible. Byte strings are encoded
+// using their hexadecimal representation.
+func (c Claims) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(c))
+	for k, v := range c {
+		name := fmt.Sprint(k)
+		if key, ok := k.(int64); ok {
+			if s, ok := claimNames[key]; ok {
+				name = s
+			}
+		}
+		if b, ok := v.([]byte); ok {
+			v = fmt.Sprintf("%x", b)
+		}
+		m[name] = jsonValue(v)
+	}
+	return m
+}
+
+// Expected are the values used to validate a claims set.
+type Expected struct {
+	Issuer   string
+	Subject  string
+	Audience string
+	CWTID    []byte
+	Time     time.Time
+	Leeway   time.Duration
+}
+
+// Validate checks the claims against the expected values. The exp and nbf
+// claims are always validated if present using the expected time or the
+// current time.
+func (c Claims) Validate(e Expected) error {
+	if e.Issuer != "" {
+		if iss, _ := c.String(ClaimIssuer); iss != e.Issuer {
+			return errors.Errorf("validation failed: invalid issuer claim (iss): found %q", iss)
+		}
+	}
+	if e.Subject != "" {
+		if sub, _ := c.String(ClaimSubject); sub != e.Subject {
+			return errors.Errorf("validation failed: invalid subject claim (sub): found %q", sub)
+		}
+	}
+	if e.Audience != "" {
+		var found bool
+		for _, aud := range c.Audience() {
+			if aud == e.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("validation failed: invalid audience claim (aud)")
+		}
+	}
+	if e.CWTID != nil {
+		cti, _ := c[ClaimCWTID].([]byte)
+		if !bytes.Equal(cti, e.CWTID) {
+			return errors.New("validation failed: invalid CWT ID claim (cti)")
+		}
+	}
+
+	now, leeway := e.Time, e.Leeway
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if leeway == 0 {
+		leeway = DefaultLeeway
+	}
+	if exp, ok := c.Time(ClaimExpiry); ok && now.Add(-leeway).After(exp) {
+		return errors.New("validation failed: token is expired (exp)")
+	}
+	if nbf, ok := c.Time(ClaimNotBefore); ok && now.Add(leeway).Before(nbf) {
+		return errors.New("validation failed: token not valid yet (nbf)")
+	}
+	return nil
+}
+
+// Payload returns the CBOR encoding of the claims set.
+func (c Claims) Payload() ([]byte, error) {
+	b, err := Marshal(map[interface{}]interface{}(c))
+	if err != nil {
+		return nil, errors.Wrap(err, "error encoding claims")
+	}
+	return b, nil
+}
@@ -0,0 +1,63 @@
+package cose
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Output formats supported by Encode.
+const (
+	FormatBase64URL = "base64url"
+	FormatBase64    = "base64"
+	FormatHex       = "hex"
+	FormatRaw       = "raw"
+)
+
+// Encode encodes the CBOR data using the given format.
+func Encode(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatBase64URL, "":
+		return []byte(base64.RawURLEncoding.EncodeToString(data)), nil
+	case FormatBase64:
+		return []byte(base64.StdEncoding.EncodeToString(data)), nil
+	case FormatHex:
+		return []byte(hex.EncodeToString(data)), nil
+	case FormatRaw:
+		return data, nil
+	default:
+		return nil, errors.Errorf("unsupported format %q", format)
+	}
+}
+
+// Decode returns the CBOR data in b. The data can be raw CBOR, or its
+// hexadecimal, base64 or base64url representation. Whitespace around encoded
+// data is ignored.
+func Decode(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, errors.New("error decoding message: data is empty")
+	}
+	// A COSE message or a CWT is always a CBOR array or tag.
+	if major := b[0] >> 5; major == 4 || major == 6 {
+		return b, nil
+	}
+
+	s := strings.Join(strings.Fields(string(b)), "")
+	if v, err := hex.DecodeString(s); err == nil {
+		return v, nil
+	}
+	s = strings.TrimRight(s, "=")
+	if v, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return v, nil
+	}
+	if v, err := base64.RawStdEncoding.DecodeString(s); err == nil {
+		return v, nil
+	}
+	if bytes.HasPrefix(b, []byte("{")) {
+		return nil, errors.New("error decoding message: data looks like JSON, not CBOR")
+	}
+	return nil, errors.New("error decoding message: data is not CBOR, hex, or base64")
+}
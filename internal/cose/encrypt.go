@@ -0,0 +1,118 @@
+package cose
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Encrypt creates a COSE_Encrypt0 message using the given symmetric key. If
+// alg is 0 the default algorithm for the key size is used. A random IV is
+// added to the unprotected header.
+func Encrypt(key []byte, alg Algorithm, plaintext []byte, opts *SignOptions) (*Message, error) {
+	if alg == 0 {
+		var err error
+		if alg, err = EncryptionAlgorithm(key); err != nil {
+			return nil, err
+		}
+	}
+	if !alg.IsEncryption() {
+		return nil, errors.Errorf("algorithm %s is not a content encryption algorithm", alg)
+	}
+	aead, err := newAEAD(key, alg)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, errors.Wrap(err, "error generating IV")
+	}
+
+	m := newMessage(Encrypt0, alg, nil, opts)
+	m.Unprotected[HeaderIV] = iv
+	var external []byte
+	if opts != nil {
+		external = opts.ExternalAAD
+	}
+	aad, err := m.encStructure(external)
+	if err != nil {
+		return nil, err
+	}
+	m.Payload = aead.Seal(nil, iv, plaintext, aad)
+	return m, nil
+}
+
+// Decrypt decrypts a COSE_Encrypt0 message using the given symmetric key.
+func (m *Message) Decrypt(key, external []byte) ([]byte, error) {
+	if m.Type != Encrypt0 {
+		return nil, errors.Errorf("cannot decrypt a %s message", m.Type)
+	}
+	alg, ok := m.Algorithm()
+	if !ok {
+		return nil, errors.New("decryption failed: message does not have an algorithm")
+	}
+	if !alg.IsEncryption() {
+		return nil, errors.Errorf("decryption failed: algorithm %s is not a content encryption algorithm", alg)
+	}
+	if err := m.checkCritical(); err != nil {
+		return nil, err
+	}
+	if m.Payload == nil {
+		return nil, errors.New("decryption failed: the ciphertext is detached")
+	}
+
+	aead, err := newAEAD(key, alg)
+	if err != nil {
+		return nil, err
+	}
+	iv, ok := m.Unprotected.Get(HeaderIV)
+	if !ok {
+		iv, ok = m.Protected.Get(HeaderIV)
+	}
+	nonce, _ := iv.([]byte)
+	if !ok || len(nonce) != aead.NonceSize() {
+		return nil, errors.New("decryption failed: missing or invalid IV")
+	}
+	aad, err := m.encStructure(external)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, m.Payload, aad)
+	if err != nil {
+		return nil, errors.New("decryption failed: invalid ciphertext or key")
+	}
+	return plaintext, nil
+}
+
+// encStructure returns the Enc_structure used as additional authenticated
+// data.
+func (m *Message) encStructure(external []byte) ([]byte, error) {
+	protected, err := m.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if external == nil {
+		external = []byte{}
+	}
+	return Marshal([]interface{}{"Encrypt0", protected, external})
+}
+
+func newAEAD(key []byte, alg Algorithm) (cipher.AEAD, error) {
+	if len(key) != alg.keySize() {
+		return nil, errors.Errorf("invalid key size %d: algorithm %s requires %d bytes", len(key), alg, alg.keySize())
+	}
+	if alg == ChaCha20Poly1305 {
+		aead, err := chacha20poly1305.New(key)
+		return aead, errors.Wrap(err, "error creating cipher")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "error creating cipher")
+}
@@ -0,0 +1,57 @@
+package cose
+
+import (
+	"crypto"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/internal/cryptoutil"
+)
+
+// ReadKey reads the key used to sign, verify, authenticate or encrypt a COSE
+// message. If kmsURI is empty, name is a file with a JWK, a JWK encrypted as a
+// JWE payload, or a PEM encoded key, and the key is read using the given
+// options. Otherwise name is a key in the KMS, and the returned key is a
+// crypto.Signer backed by the KMS.
+func ReadKey(kmsURI, name string, opts ...jose.Option) (*jose.JSONWebKey, error) {
+	if kmsURI == "" || !cryptoutil.IsKMS(kmsURI) {
+		return jose.ReadKey(name, opts...)
+	}
+	signer, err := cryptoutil.CreateSigner(kmsURI, name)
+	if err != nil {
+		return nil, err
+	}
+	return &jose.JSONWebKey{
+		Key: signer,
+		Use: "sig",
+	}, nil
+}
+
+// SigningKey returns the private key or the symmetric key in the given JWK.
+func SigningKey(jwk *jose.JSONWebKey) (interface{}, error) {
+	switch k := jwk.Key.(type) {
+	case []byte:
+		return k, nil
+	case crypto.Signer:
+		return k, nil
+	default:
+		if jwk.IsPublic() {
+			return nil, errors.New("cannot use a public key for signing")
+		}
+		return nil, errors.Errorf("unsupported key type %T", k)
+	}
+}
+
+// VerificationKey returns the public key or the symmetric key in the given
+// JWK.
+func VerificationKey(jwk *jose.JSONWebKey) interface{} {
+	switch k := jwk.Key.(type) {
+	case []byte:
+		return k
+	case crypto.Signer:
+		return k.Public()
+	default:
+		return jwk.Key
+	}
+}
@@ -0,0 +1,81 @@
+package cose
+
+import (
+	"crypto/hmac"
+
+	"github.com/pkg/errors"
+)
+
+// MAC creates a COSE_Mac0 message using the given symmetric key. If alg is 0
+// the default algorithm for the key size is used.
+func MAC(key []byte, alg Algorithm, payload []byte, opts *SignOptions) (*Message, error) {
+	if alg == 0 {
+		var err error
+		if alg, err = MACAlgorithm(key); err != nil {
+			return nil, err
+		}
+	}
+	if !alg.IsMAC() {
+		return nil, errors.Errorf("algorithm %s is not a MAC algorithm", alg)
+	}
+	if len(key) < alg.keySize() {
+		return nil, errors.Errorf("invalid key size %d: algorithm %s requires at least %d bytes", len(key), alg, alg.keySize())
+	}
+
+	m := newMessage(Mac0, alg, payload, opts)
+	var external []byte
+	if opts != nil {
+		external = opts.ExternalAAD
+	}
+	tbm, err := m.toBeSigned("MAC0", m.Payload, external)
+	if err != nil {
+		return nil, err
+	}
+	m.Signature = computeMAC(key, alg, tbm)
+	if opts != nil && opts.Detached {
+		m.Payload = nil
+	}
+	return m, nil
+}
+
+// VerifyMAC verifies the tag of a COSE_Mac0 message using the given symmetric
+// key. If the message is detached the payload must be provided.
+func (m *Message) VerifyMAC(key, payload, external []byte) error {
+	if m.Type != Mac0 {
+		return errors.Errorf("cannot verify the tag of a %s message", m.Type)
+	}
+	alg, ok := m.Algorithm()
+	if !ok {
+		return errors.New("validation failed: message does not have an algorithm")
+	}
+	if !alg.IsMAC() {
+		return errors.Errorf("validation failed: algorithm %s is not a MAC algorithm", alg)
+	}
+	if err := m.checkCritical(); err != nil {
+		return err
+	}
+	if payload == nil {
+		if m.Payload == nil {
+			return errors.New("validation failed: the payload is detached")
+		}
+		payload = m.Payload
+	}
+	tbm, err := m.toBeSigned("MAC0", payload, external)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(computeMAC(key, alg, tbm), m.Signature) {
+		return errors.New("validation failed: invalid tag")
+	}
+	return nil
+}
+
+func computeMAC(key []byte, alg Algorithm, data []byte) []byte {
+	h := hmac.New(alg.HashFunc().New, key)
+	h.Write(data)
+	tag := h.Sum(nil)
+	if alg == HMAC256_64 {
+		tag = tag[:8]
+	}
+	return tag
+}
@@ -0,0 +1,232 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// SignOptions are the options used to create a COSE_Sign1 or COSE_Mac0
+// message.
+type SignOptions struct {
+	// Protected and Unprotected are additional header parameters. The
+	// algorithm is always added to the protected header.
+	Protected   Header
+	Unprotected Header
+	// ExternalAAD is the externally supplied data authenticated with the
+	// message.
+	ExternalAAD []byte
+	// Detached removes the payload from the resulting message.
+	Detached bool
+	// Tags are the CBOR tags enclosing the message. If empty the message
+	// is not tagged.
+	Tags []uint64
+}
+
+func newMessage(typ MessageType, alg Algorithm, payload []byte, opts *SignOptions) *Message {
+	if opts == nil {
+		opts = new(SignOptions)
+	}
+	protected := Header{}
+	for k, v := range opts.Protected {
+		protected[k] = v
+	}
+	protected[HeaderAlgorithm] = int64(alg)
+	unprotected := Header{}
+	for k, v := range opts.Unprotected {
+		unprotected[k] = v
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	return &Message{
+		Type:        typ,
+		Protected:   protected,
+		Unprotected: unprotected,
+		Payload:     payload,
+		Tags:        opts.Tags,
+	}
+}
+
+// toBeSigned returns the Sig_structure or MAC_structure of the message.
+func (m *Message) toBeSigned(context string, payload, external []byte) ([]byte, error) {
+	protected, err := m.protectedBytes()
+	if err != nil {
+		return nil, err
+	}
+	if external == nil {
+		external = []byte{}
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	return Marshal([]interface{}{context, protected, external, payload})
+}
+
+// Sign creates a COSE_Sign1 message with the given signer and algorithm. If
+// alg is 0 the default algorithm for the key is used.
+func Sign(signer crypto.Signer, alg Algorithm, payload []byte, opts *SignOptions) (*Message, error) {
+	if alg == 0 {
+		var err error
+		if alg, err = SignatureAlgorithm(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+	if !alg.IsSignature() {
+		return nil, errors.Errorf("algorithm %s is not a signature algorithm", alg)
+	}
+	if err := alg.validateKey(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	m := newMessage(Sign1, alg, payload, opts)
+	var external []byte
+	if opts != nil {
+		external = opts.ExternalAAD
+	}
+	tbs, err := m.toBeSigned("Signature1", m.Payload, external)
+	if err != nil {
+		return nil, err
+	}
+	if m.Signature, err = sign(signer, alg, tbs); err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.Detached {
+		m.Payload = nil
+	}
+	return m, nil
+}
+
+// Verify verifies the signature of a COSE_Sign1 message using the given public
+// key. If the message is detached the payload must be provided.
+func (m *Message) Verify(pub crypto.PublicKey, payload, external []byte) error {
+	if m.Type != Sign1 {
+		return errors.Errorf("cannot verify the signature of a %s message", m.Type)
+	}
+	alg, ok := m.Algorithm()
+	if !ok {
+		return errors.New("validation failed: message does not have an algorithm")
+	}
+	if !alg.IsSignature() {
+		return errors.Errorf("validation failed: algorithm %s is not a signature algorithm", alg)
+	}
+	if err := alg.validateKey(pub); err != nil {
+		return errors.Wrap(err, "validation failed")
+	}
+	if err := m.checkCritical(); err != nil {
+		return err
+	}
+	if payload == nil {
+		if m.Payload == nil {
+			return errors.New("validation failed: the payload is detached")
+		}
+		payload = m.Payload
+	}
+	tbs, err := m.toBeSigned("Signature1", payload, external)
+	if err != nil {
+		return err
+	}
+	if !verify(pub, alg, tbs, m.Signature) {
+		return errors.New("validation failed: invalid signature")
+	}
+	return nil
+}
+
+// checkCritical fails if the message marks as critical any header parameter
+// not understood by this package.
+func (m *Message) checkCritical() error {
+	v, ok := m.Protected.Get(HeaderCritical)
+	if !ok {
+		return nil
+	}
+	labels, ok := v.([]interface{})
+	if !ok || len(labels) == 0 {
+		return errors.New("validation failed: invalid critical header")
+	}
+	for _, l := range labels {
+		label, ok := l.(int64)
+		if !ok {
+			return errors.Errorf("validation failed: unrecognized critical header %v", l)
+		}
+		if _, ok := headerNames[label]; !ok {
+			return errors.Errorf("validation failed: unrecognized critical header %d", label)
+		}
+	}
+	return nil
+}
+
+func sign(signer crypto.Signer, alg Algorithm, tbs []byte) ([]byte, error) {
+	var digest []byte
+	var opts crypto.SignerOpts
+	switch alg {
+	case EdDSA:
+		digest, opts = tbs, crypto.Hash(0)
+	case PS256, PS384, PS512:
+		h := alg.HashFunc()
+		digest = hash(h, tbs)
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+	default:
+		h := alg.HashFunc()
+		digest, opts = hash(h, tbs), h
+	}
+
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing message")
+	}
+
+	// COSE uses the concatenation of r and s as the ECDSA signature.
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); ok {
+		var esig struct {
+			R, S *big.Int
+		}
+		if _, err := asn1.Unmarshal(sig, &esig); err != nil {
+			return nil, errors.Wrap(err, "error decoding ECDSA signature")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		esig.R.FillBytes(sig[:size])
+		esig.S.FillBytes(sig[size:])
+	}
+
+	return sig, nil
+}
+
+func verify(pub crypto.PublicKey, alg Algorithm, tbs, sig []byte) bool {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, hash(alg.HashFunc(), tbs), r, s)
+	case *rsa.PublicKey:
+		h := alg.HashFunc()
+		switch alg {
+		case PS256, PS384, PS512:
+			return rsa.VerifyPSS(k, h, hash(h, tbs), sig, &rsa.PSSOptions{
+				SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h,
+			}) == nil
+		default:
+			return rsa.VerifyPKCS1v15(k, h, hash(h, tbs), sig) == nil
+		}
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, tbs, sig)
+	default:
+		return false
+	}
+}
+
+func hash(h crypto.Hash, data []byte) []byte {
+	v := h.New()
+	v.Write(data)
+	return v.Sum(nil)
+}
@@ -75,6 +75,38 @@ func CreateSigner(kmsURI, name string, opts ...pemutil.Options) (crypto.Signer,
 	return newKMSSigner(kmsURI, name)
 }
 
+// GenerateSigner creates a new key with the given name in the kms using
+// `step-kms-plugin create`, and returns a signer for it. The key type, curve
+// and size are optional.
+func GenerateSigner(kmsURI, name, kty, crv string, size int) (crypto.Signer, error) {
+	pluginName, err := plugin.LookPath("kms")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"create"}
+	if kty != "" {
+		args = append(args, "--kty", kty)
+	}
+	if crv != "" {
+		args = append(args, "--crv", crv)
+	}
+	if size > 0 {
+		args = append(args, "--size", strconv.Itoa(size))
+	}
+	if kmsURI != "" {
+		args = append(args, "--kms", kmsURI)
+	}
+	args = append(args, name)
+
+	cmd := exec.Command(pluginName, args...)
+	if _, err := cmd.Output(); err != nil {
+		return nil, exitError(cmd, err)
+	}
+
+	return newKMSSigner(kmsURI, name)
+}
+
 func isSoftKMS(kmsURI string) bool {
 	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(kmsURI)), "softkms")
 }
@@ -0,0 +1,109 @@
+// Package ctutil implements helpers to work with the signed certificate
+// timestamps (SCTs) defined in RFC 6962.
+package ctutil
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+var (
+	// OIDSCTList is the object identifier of the certificate extension with
+	// the embedded SCTs.
+	OIDSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	// OIDOCSPSCTList is the object identifier of the OCSP single extension
+	// with the SCTs.
+	OIDOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// SCT is a signed certificate timestamp.
+type SCT struct {
+	Version            uint8
+	LogID              [32]byte
+	Timestamp          time.Time
+	Extensions         []byte
+	HashAlgorithm      uint8
+	SignatureAlgorithm uint8
+	Signature          []byte
+	Raw                []byte
+}
+
+// LogIDString returns the log ID in base64, the format used in the log lists.
+func (s *SCT) LogIDString() string {
+	return base64.StdEncoding.EncodeToString(s.LogID[:])
+}
+
+// ParseSCT parses a TLS encoded SCT.
+func ParseSCT(b []byte) (*SCT, error) {
+	var (
+		sct       = &SCT{Raw: b}
+		logID     []byte
+		timestamp uint64
+		ext, sig  cryptobyte.String
+	)
+	s := cryptobyte.String(b)
+	if !s.ReadUint8(&sct.Version) || sct.Version != 0 {
+		return nil, errors.New("error parsing SCT: unsupported version")
+	}
+	if !s.ReadBytes(&logID, 32) ||
+		!s.ReadUint64(&timestamp) ||
+		!s.ReadUint16LengthPrefixed(&ext) ||
+		!s.ReadUint8(&sct.HashAlgorithm) ||
+		!s.ReadUint8(&sct.SignatureAlgorithm) ||
+		!s.ReadUint16LengthPrefixed(&sig) ||
+		!s.Empty() {
+		return nil, errors.New("error parsing SCT: malformed data")
+	}
+	copy(sct.LogID[:], logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp)).UTC()
+	sct.Extensions = []byte(ext)
+	sct.Signature = []byte(sig)
+	return sct, nil
+}
+
+// ParseSCTList parses a TLS encoded SignedCertificateTimestampList.
+func ParseSCTList(b []byte) ([]*SCT, error) {
+	var list cryptobyte.String
+	s := cryptobyte.String(b)
+	if !s.ReadUint16LengthPrefixed(&list) || !s.Empty() {
+		return nil, errors.New("error parsing SCT list: malformed data")
+	}
+	var scts []*SCT
+	for !list.Empty() {
+		var raw cryptobyte.String
+		if !list.ReadUint16LengthPrefixed(&raw) {
+			return nil, errors.New("error parsing SCT list: malformed data")
+		}
+		sct, err := ParseSCT(raw)
+		if err != nil {
+			return nil, err
+		}
+		scts = append(scts, sct)
+	}
+	return scts, nil
+}
+
+// ParseExtension parses the value of the certificate or OCSP extension with
+// SCTs, an OCTET STRING with a SignedCertificateTimestampList.
+func ParseExtension(value []byte) ([]*SCT, error) {
+	var b []byte
+	if rest, err := asn1.Unmarshal(value, &b); err != nil || len(rest) > 0 {
+		return nil, errors.New("error parsing SCT extension: malformed data")
+	}
+	return ParseSCTList(b)
+}
+
+// EmbeddedSCTs returns the SCTs embedded in a certificate.
+func EmbeddedSCTs(cert *x509.Certificate) ([]*SCT, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(OIDSCTList) {
+			return ParseExtension(ext.Value)
+		}
+	}
+	return nil, nil
+}
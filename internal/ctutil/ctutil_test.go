@@ -0,0 +1,256 @@
+package ctutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/minica"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func marshalSCT(t *testing.T, logID byte, timestamp time.Time) []byte {
+	t.Helper()
+	var b cryptobyte.Builder
+	b.AddUint8(0)
+	for i := 0; i < 32; i++ {
+		b.AddUint8(logID)
+	}
+	b.AddUint64(uint64(timestamp.UnixMilli()))
+	b.AddUint16LengthPrefixed(func(*cryptobyte.Builder) {})
+	b.AddUint8(4) // sha256
+	b.AddUint8(3) // ecdsa
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte("signature"))
+	})
+	return b.BytesOrPanic()
+}
+
+func marshalSCTList(t *testing.T, scts ...[]byte) []byte {
+	t.Helper()
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(sct)
+			})
+		}
+	})
+	return b.BytesOrPanic()
+}
+
+func TestParseSCT(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 123e6, time.UTC)
+	b := marshalSCT(t, 1, ts)
+	sct, err := ParseSCT(b)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0), sct.Version)
+	assert.Equal(t, ts, sct.Timestamp)
+	assert.Equal(t, "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=", sct.LogIDString())
+	assert.Equal(t, uint8(4), sct.HashAlgorithm)
+	assert.Equal(t, uint8(3), sct.SignatureAlgorithm)
+	assert.Equal(t, []byte("signature"), sct.Signature)
+	assert.Empty(t, sct.Extensions)
+	assert.Equal(t, b, sct.Raw)
+
+	_, err = ParseSCT(append(b, 0))
+	assert.Error(t, err)
+	_, err = ParseSCT(b[:40])
+	assert.Error(t, err)
+	b[0] = 1
+	_, err = ParseSCT(b)
+	assert.Error(t, err)
+}
+
+func TestEmbeddedSCTs(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	list := marshalSCTList(t, marshalSCT(t, 1, ts), marshalSCT(t, 2, ts.Add(time.Second)))
+	value, err := asn1.Marshal(list)
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{Extensions: []pkix.Extension{{Id: OIDSCTList, Value: value}}}
+	scts, err := EmbeddedSCTs(cert)
+	require.NoError(t, err)
+	if assert.Len(t, scts, 2) {
+		assert.Equal(t, byte(1), scts[0].LogID[0])
+		assert.Equal(t, byte(2), scts[1].LogID[0])
+		assert.Equal(t, ts.Add(time.Second), scts[1].Timestamp)
+	}
+
+	scts, err = EmbeddedSCTs(&x509.Certificate{})
+	assert.NoError(t, err)
+	assert.Nil(t, scts)
+
+	_, err = ParseExtension(list)
+	assert.Error(t, err)
+	_, err = ParseSCTList(list[:len(list)-1])
+	assert.Error(t, err)
+}
+
+// testLog is a stand-in certificate transparency log.
+type testLog struct {
+	key *ecdsa.PrivateKey
+	id  [32]byte
+}
+
+func newTestLog(t *testing.T) *testLog {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	return &testLog{key: key, id: sha256.Sum256(der)}
+}
+
+func (l *testLog) sign(t *testing.T, cert, issuer *x509.Certificate, ts time.Time) *SCT {
+	t.Helper()
+	sct := &SCT{LogID: l.id, Timestamp: ts, HashAlgorithm: 4, SignatureAlgorithm: 3}
+	input, err := signatureInput(sct, cert, issuer)
+	require.NoError(t, err)
+	sum := sha256.Sum256(input)
+	sct.Signature, err = ecdsa.SignASN1(rand.Reader, l.key, sum[:])
+	require.NoError(t, err)
+	sct.Raw, err = sct.Marshal()
+	require.NoError(t, err)
+	return sct
+}
+
+func (l *testLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req addChainRequest
+	if r.URL.Path != "/ct/v1/add-chain" || json.NewDecoder(r.Body).Decode(&req) != nil || len(req.Chain) == 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	cert, err := x509.ParseCertificate(req.Chain[0])
+	if err != nil {
+		http.Error(w, "bad certificate", http.StatusBadRequest)
+		return
+	}
+	sct := &SCT{LogID: l.id, Timestamp: time.Now().Truncate(time.Millisecond)}
+	input, _ := signatureInput(sct, cert, nil)
+	sum := sha256.Sum256(input)
+	sig, _ := ecdsa.SignASN1(rand.Reader, l.key, sum[:])
+	var b cryptobyte.Builder
+	b.AddUint8(4)
+	b.AddUint8(3)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sig) })
+	json.NewEncoder(w).Encode(addChainResponse{
+		ID:        l.id[:],
+		Timestamp: uint64(sct.Timestamp.UnixMilli()),
+		Signature: b.BytesOrPanic(),
+	})
+}
+
+func TestSCT_Verify(t *testing.T) {
+	log := newTestLog(t)
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		PublicKey:    key.Public(),
+	}
+	precert, err := ca.Sign(template)
+	require.NoError(t, err)
+	ts := time.Now().Truncate(time.Millisecond)
+
+	// SCT embedded in the final certificate, signed over the precertificate.
+	embedded := log.sign(t, precert, ca.Intermediate, ts)
+	value, err := asn1.Marshal(marshalSCTList(t, embedded.Raw))
+	require.NoError(t, err)
+	template.ExtraExtensions = []pkix.Extension{{Id: OIDSCTList, Value: value}}
+	cert, err := ca.Sign(template)
+	require.NoError(t, err)
+	scts, err := EmbeddedSCTs(cert)
+	require.NoError(t, err)
+	require.Len(t, scts, 1)
+	assert.NoError(t, scts[0].Verify(log.key.Public(), cert, ca.Intermediate))
+	assert.Error(t, scts[0].Verify(log.key.Public(), cert, nil))
+	assert.Equal(t, "ECDSA-SHA256", scts[0].SignatureAlgorithmString())
+
+	// SCT sent in the TLS extension, signed over the certificate.
+	sct := log.sign(t, cert, nil, ts)
+	assert.NoError(t, sct.Verify(log.key.Public(), cert, nil))
+	assert.Error(t, sct.Verify(log.key.Public(), precert, nil))
+	other := newTestLog(t)
+	assert.EqualError(t, sct.Verify(other.key.Public(), cert, nil), "invalid SCT signature")
+}
+
+func TestAddChain(t *testing.T) {
+	log := newTestLog(t)
+	srv := httptest.NewServer(log)
+	defer srv.Close()
+
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	cert, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "example.com"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+
+	sct, err := AddChain(srv.Client(), srv.URL+"/", []*x509.Certificate{cert})
+	require.NoError(t, err)
+	assert.Equal(t, log.id, sct.LogID)
+	assert.NoError(t, sct.Verify(log.key.Public(), cert, nil))
+	parsed, err := ParseSCT(sct.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, sct, parsed)
+
+	_, err = AddChain(srv.Client(), srv.URL+"/foo", []*x509.Certificate{cert})
+	assert.ErrorContains(t, err, "status code 400: bad request")
+	_, err = AddChain(srv.Client(), srv.URL, nil)
+	assert.Error(t, err)
+}
+
+func TestParseLogList(t *testing.T) {
+	log := newTestLog(t)
+	der, err := x509.MarshalPKIXPublicKey(log.key.Public())
+	require.NoError(t, err)
+	key := base64.StdEncoding.EncodeToString(der)
+	id := base64.StdEncoding.EncodeToString(log.id[:])
+
+	list, err := ParseLogList([]byte(`{"version": "3.0", "operators": [{"name": "Test", "logs": [
+		{"description": "Test Log", "log_id": "` + id + `", "key": "` + key + `", "url": "https://ct.example.com/"}
+	]}]}`))
+	require.NoError(t, err)
+	l := list.Find(log.id)
+	if assert.NotNil(t, l) {
+		assert.Equal(t, "Test Log", l.Description)
+		assert.Equal(t, "Test", l.Operator)
+		pub, err := l.PublicKey()
+		assert.NoError(t, err)
+		assert.True(t, log.key.PublicKey.Equal(pub))
+	}
+	assert.Nil(t, list.Find([32]byte{}))
+
+	// The log id is optional.
+	list, err = ParseLogList([]byte(`{"logs": [{"description": "Local", "key": "` + key + `"}]}`))
+	require.NoError(t, err)
+	assert.NotNil(t, list.Find(log.id))
+
+	_, err = ParseLogList([]byte(`{"logs": [{"description": "Local", "log_id": "AAAA", "key": "` + key + `"}]}`))
+	assert.ErrorContains(t, err, "does not match its key")
+	_, err = ParseLogList([]byte(`{"logs": [{"description": "Local"}]}`))
+	assert.ErrorContains(t, err, "does not have a key")
+}
@@ -0,0 +1,104 @@
+package ctutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Log is a certificate transparency log in a log list.
+type Log struct {
+	Description   string `json:"description"`
+	LogID         []byte `json:"log_id"`
+	Key           []byte `json:"key"`
+	URL           string `json:"url,omitempty"`
+	SubmissionURL string `json:"submission_url,omitempty"`
+	Operator      string `json:"-"`
+}
+
+// PublicKey returns the public key of the log.
+func (l *Log) PublicKey() (crypto.PublicKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(l.Key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing the key of log %s", l.Description)
+	}
+	return pub, nil
+}
+
+// LogList is a list of certificate transparency logs.
+type LogList struct {
+	Logs []*Log
+}
+
+// logListJSON is the JSON format of the log lists published by Google and
+// Apple, version 3. A top level list of logs is also accepted.
+type logListJSON struct {
+	Operators []struct {
+		Name      string `json:"name"`
+		Logs      []*Log `json:"logs"`
+		TiledLogs []*Log `json:"tiled_logs"`
+	} `json:"operators"`
+	Logs []*Log `json:"logs"`
+}
+
+// ParseLogList parses a log list in JSON format.
+func ParseLogList(b []byte) (*LogList, error) {
+	var v logListJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrap(err, "error parsing log list")
+	}
+	list := &LogList{}
+	add := func(operator string, logs []*Log) error {
+		for _, l := range logs {
+			if len(l.Key) == 0 {
+				return errors.Errorf("error parsing log list: log %s does not have a key", l.Description)
+			}
+			// The log ID is the SHA-256 hash of the public key.
+			id := sha256.Sum256(l.Key)
+			if len(l.LogID) == 0 {
+				l.LogID = id[:]
+			} else if !bytes.Equal(l.LogID, id[:]) {
+				return errors.Errorf("error parsing log list: the id of log %s does not match its key", l.Description)
+			}
+			l.Operator = operator
+			list.Logs = append(list.Logs, l)
+		}
+		return nil
+	}
+	for _, op := range v.Operators {
+		if err := add(op.Name, op.Logs); err != nil {
+			return nil, err
+		}
+		if err := add(op.Name, op.TiledLogs); err != nil {
+			return nil, err
+		}
+	}
+	if err := add("", v.Logs); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ReadLogList reads a log list from a file.
+func ReadLogList(filename string) (*LogList, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", filename)
+	}
+	return ParseLogList(b)
+}
+
+// Find returns the log with the given id, or nil if it is not in the list.
+func (l *LogList) Find(id [32]byte) *Log {
+	for _, log := range l.Logs {
+		if bytes.Equal(log.LogID, id[:]) {
+			return log
+		}
+	}
+	return nil
+}
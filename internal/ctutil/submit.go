@@ -0,0 +1,108 @@
+package ctutil
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// maxResponseSize is the maximum size of an add-chain response.
+const maxResponseSize = 1 << 20
+
+type addChainRequest struct {
+	Chain [][]byte `json:"chain"`
+}
+
+type addChainResponse struct {
+	SCTVersion uint8  `json:"sct_version"`
+	ID         []byte `json:"id"`
+	Timestamp  uint64 `json:"timestamp"`
+	Extensions string `json:"extensions"`
+	Signature  []byte `json:"signature"`
+}
+
+// AddChain submits a certificate chain to the add-chain endpoint of a log,
+// defined in RFC 6962, section 4.1, and returns the SCT issued by the log.
+// The chain must start with the leaf certificate.
+func AddChain(client *http.Client, logURL string, chain []*x509.Certificate) (*SCT, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("the certificate chain is empty")
+	}
+
+	req := addChainRequest{}
+	for _, crt := range chain {
+		req.Chain = append(req.Chain, crt.Raw)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling request")
+	}
+
+	u := strings.TrimSuffix(logURL, "/") + "/ct/v1/add-chain"
+	resp, err := client.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error contacting log %s", logURL)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading response from log %s", logURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error submitting chain to log %s: status code %d: %s", logURL, resp.StatusCode, bytes.TrimSpace(b))
+	}
+
+	var r addChainResponse
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, errors.Wrapf(err, "error parsing response from log %s", logURL)
+	}
+	return r.sct()
+}
+
+func (r *addChainResponse) sct() (*SCT, error) {
+	if len(r.ID) != 32 {
+		return nil, errors.New("error parsing add-chain response: invalid log id")
+	}
+	sct := &SCT{
+		Version:    r.SCTVersion,
+		Timestamp:  time.UnixMilli(int64(r.Timestamp)).UTC(),
+		Extensions: []byte{},
+	}
+	copy(sct.LogID[:], r.ID)
+	if r.Extensions != "" {
+		ext, err := base64.StdEncoding.DecodeString(r.Extensions)
+		if err != nil {
+			return nil, errors.New("error parsing add-chain response: invalid extensions")
+		}
+		sct.Extensions = ext
+	}
+
+	// The signature is a TLS encoded DigitallySigned struct.
+	var sig cryptobyte.String
+	s := cryptobyte.String(r.Signature)
+	if !s.ReadUint8(&sct.HashAlgorithm) ||
+		!s.ReadUint8(&sct.SignatureAlgorithm) ||
+		!s.ReadUint16LengthPrefixed(&sig) ||
+		!s.Empty() {
+		return nil, errors.New("error parsing add-chain response: invalid signature")
+	}
+	sct.Signature = []byte(sig)
+
+	raw, err := sct.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	sct.Raw = raw
+	return sct, nil
+}
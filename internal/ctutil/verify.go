@@ -0,0 +1,199 @@
+package ctutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// Hash and signature algorithms of the TLS DigitallySigned struct.
+const (
+	hashSHA256     = 4
+	signatureRSA   = 1
+	signatureECDSA = 3
+)
+
+// Entry types of the signed data.
+const (
+	x509Entry    = 0
+	precertEntry = 1
+)
+
+// SignatureAlgorithmString returns a human readable name of the hash and
+// signature algorithms of the SCT.
+func (s *SCT) SignatureAlgorithmString() string {
+	var hash, sig string
+	switch s.HashAlgorithm {
+	case hashSHA256:
+		hash = "SHA256"
+	default:
+		hash = "UNKNOWN"
+	}
+	switch s.SignatureAlgorithm {
+	case signatureRSA:
+		sig = "RSA"
+	case signatureECDSA:
+		sig = "ECDSA"
+	default:
+		sig = "UNKNOWN"
+	}
+	return sig + "-" + hash
+}
+
+// Marshal returns the TLS encoding of the SCT, the format used to store SCTs
+// in files and to send them in the TLS extension.
+func (s *SCT) Marshal() ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint8(s.Version)
+	b.AddBytes(s.LogID[:])
+	b.AddUint64(uint64(s.Timestamp.UnixMilli()))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.Extensions)
+	})
+	b.AddUint8(s.HashAlgorithm)
+	b.AddUint8(s.SignatureAlgorithm)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.Signature)
+	})
+	return b.Bytes()
+}
+
+// Verify checks the signature of the SCT with the public key of the log.
+//
+// SCTs embedded in a certificate are signed over the precertificate, so the
+// issuer of the certificate is required to verify them. SCTs sent in the TLS
+// extension or in an OCSP response are signed over the certificate, and the
+// issuer must be nil.
+func (s *SCT) Verify(key crypto.PublicKey, cert, issuer *x509.Certificate) error {
+	input, err := signatureInput(s, cert, issuer)
+	if err != nil {
+		return err
+	}
+	if s.HashAlgorithm != hashSHA256 {
+		return errors.Errorf("unsupported SCT hash algorithm %d", s.HashAlgorithm)
+	}
+	sum := sha256.Sum256(input)
+
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		if s.SignatureAlgorithm != signatureECDSA {
+			return errors.New("SCT signature algorithm does not match the log key")
+		}
+		if !ecdsa.VerifyASN1(pub, sum[:], s.Signature) {
+			return errors.New("invalid SCT signature")
+		}
+	case *rsa.PublicKey:
+		if s.SignatureAlgorithm != signatureRSA {
+			return errors.New("SCT signature algorithm does not match the log key")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], s.Signature); err != nil {
+			return errors.New("invalid SCT signature")
+		}
+	default:
+		return errors.Errorf("unsupported log key type %T", key)
+	}
+	return nil
+}
+
+// signatureInput returns the data signed by the log, the digitally-signed
+// struct defined in RFC 6962, section 3.2.
+func signatureInput(s *SCT, cert, issuer *x509.Certificate) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint8(s.Version)
+	b.AddUint8(0) // certificate_timestamp
+	b.AddUint64(uint64(s.Timestamp.UnixMilli()))
+	if issuer == nil {
+		b.AddUint16(x509Entry)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(cert.Raw)
+		})
+	} else {
+		tbs, err := removeSCTList(cert.RawTBSCertificate)
+		if err != nil {
+			return nil, err
+		}
+		issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		b.AddUint16(precertEntry)
+		b.AddBytes(issuerKeyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(tbs)
+		})
+	}
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(s.Extensions)
+	})
+	return b.Bytes()
+}
+
+// removeSCTList returns the DER encoding of the TBSCertificate without the
+// SCT list extension, the TBSCertificate of the precertificate signed by the
+// log.
+func removeSCTList(tbs []byte) ([]byte, error) {
+	var fields cryptobyte.String
+	input := cryptobyte.String(tbs)
+	if !input.ReadASN1(&fields, cbasn1.SEQUENCE) || !input.Empty() {
+		return nil, errors.New("error parsing certificate: malformed TBSCertificate")
+	}
+
+	extensionsTag := cbasn1.Tag(3).Constructed().ContextSpecific()
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !fields.Empty() {
+			var (
+				field cryptobyte.String
+				tag   cbasn1.Tag
+			)
+			if !fields.ReadAnyASN1Element(&field, &tag) {
+				b.SetError(errors.New("error parsing certificate: malformed TBSCertificate"))
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(field)
+				continue
+			}
+
+			var wrapper, extensions cryptobyte.String
+			if !field.ReadASN1(&wrapper, extensionsTag) || !wrapper.ReadASN1(&extensions, cbasn1.SEQUENCE) {
+				b.SetError(errors.New("error parsing certificate: malformed extensions"))
+				return
+			}
+			var kept [][]byte
+			for !extensions.Empty() {
+				var (
+					ext, element, content cryptobyte.String
+					oid                   asn1.ObjectIdentifier
+				)
+				if !extensions.ReadASN1Element(&ext, cbasn1.SEQUENCE) {
+					b.SetError(errors.New("error parsing certificate: malformed extension"))
+					return
+				}
+				element = ext
+				if !element.ReadASN1(&content, cbasn1.SEQUENCE) || !content.ReadASN1ObjectIdentifier(&oid) {
+					b.SetError(errors.New("error parsing certificate: malformed extension"))
+					return
+				}
+				if !oid.Equal(OIDSCTList) {
+					kept = append(kept, ext)
+				}
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for _, ext := range kept {
+						b.AddBytes(ext)
+					}
+				})
+			})
+		}
+	})
+	return b.Bytes()
+}
@@ -0,0 +1,111 @@
+// Package hashutil implements the hashing of files and directories used by
+// step crypto hash and step crypto key.
+package hashutil
+
+import (
+	"encoding/binary"
+	"hash"
+	"io"
+	"os"
+	"path"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// Constructor is a function that returns a new hash.
+type Constructor func() hash.Hash
+
+// File returns the hash of the given file using the given hash function. The
+// file is read in chunks, so it can be of any size.
+func File(h hash.Hash, filename string) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Dir creates a hash of a directory adding the following data to the
+// hash:
+//  1. Add directory mode bits to the hash
+//  2. For each file/directory in directory:
+//     2.1 If file: add file mode bits and sum
+//     2.2 If directory: do Dir and add sum
+//  3. return sum
+func Dir(hc Constructor, dirname string) ([]byte, error) {
+	// ReadDir returns the entries sorted by filename
+	dirEntries, err := os.ReadDir(dirname)
+	if err != nil {
+		return nil, errs.FileError(err, dirname)
+	}
+	st, err := os.Stat(dirname)
+	if err != nil {
+		return nil, errs.FileError(err, dirname)
+	}
+
+	var sum []byte
+	mode := make([]byte, 4)
+
+	// calculate sum of contents and mode
+	h := hc()
+	binary.LittleEndian.PutUint32(mode, uint32(st.Mode()))
+	h.Write(mode)
+	for _, dirEntry := range dirEntries {
+		fi, err := dirEntry.Info()
+		if err != nil {
+			return nil, errs.FileError(err, dirEntry.Name())
+		}
+		name := path.Join(dirname, fi.Name())
+		switch {
+		case fi.IsDir():
+			sum, err = Dir(hc, name)
+		case fi.Mode()&os.ModeSymlink != 0:
+			binary.LittleEndian.PutUint32(mode, uint32(fi.Mode()))
+			h.Write(mode)
+			sum, err = Symlink(hc, name)
+		default:
+			binary.LittleEndian.PutUint32(mode, uint32(fi.Mode()))
+			h.Write(mode)
+			sum, err = File(hc(), name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.Write(sum)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Symlink returns the hash of the file or directory the given symbolic link
+// points to.
+func Symlink(hc Constructor, symname string) ([]byte, error) {
+	fullname, err := os.Readlink(symname)
+	if err != nil {
+		return nil, errs.FileError(err, symname)
+	}
+	if !path.IsAbs(fullname) {
+		fullname = path.Join(path.Dir(symname), fullname)
+	}
+
+	// Fails if the link points to a file that does not exist.
+	// TODO: Should we ignore it?
+	st, err := os.Stat(fullname)
+	if err != nil {
+		return nil, errs.FileError(err, fullname)
+	}
+	switch {
+	case st.Mode()&os.ModeSymlink != 0:
+		return Symlink(hc, fullname)
+	case st.IsDir():
+		return Dir(hc, fullname)
+	default:
+		return File(hc(), fullname)
+	}
+}
@@ -0,0 +1,43 @@
+package hashutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(name, []byte("hello\n"), 0600))
+
+	sum, err := File(sha256.New(), name)
+	require.NoError(t, err)
+	assert.Equal(t, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", hex.EncodeToString(sum))
+
+	_, err = File(sha256.New(), filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0600))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+
+	sum, err := Dir(sha256.New, dir)
+	require.NoError(t, err)
+	again, err := Dir(sha256.New, dir)
+	require.NoError(t, err)
+	assert.Equal(t, sum, again)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("c"), 0600))
+	changed, err := Dir(sha256.New, dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, sum, changed)
+}
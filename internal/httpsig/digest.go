@@ -0,0 +1,79 @@
+package httpsig
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+
+	"github.com/pkg/errors"
+)
+
+// Content-Digest algorithms registered in RFC 9530.
+const (
+	DigestSHA256 = "sha-256"
+	DigestSHA512 = "sha-512"
+)
+
+// ContentDigestHeader is the name of the Content-Digest header field.
+const ContentDigestHeader = "Content-Digest"
+
+func digest(alg string, body []byte) ([]byte, error) {
+	switch alg {
+	case DigestSHA256:
+		sum := sha256.Sum256(body)
+		return sum[:], nil
+	case DigestSHA512:
+		sum := sha512.Sum512(body)
+		return sum[:], nil
+	default:
+		return nil, errors.Errorf("unsupported digest algorithm %q", alg)
+	}
+}
+
+// ContentDigest returns the value of the Content-Digest field for the given
+// body.
+func ContentDigest(alg string, body []byte) (string, error) {
+	sum, err := digest(alg, body)
+	if err != nil {
+		return "", err
+	}
+	return alg + "=" + Item{Value: sum}.String(), nil
+}
+
+// VerifyContentDigest checks the Content-Digest field of the message against
+// its body. Unknown algorithms are ignored, but at least one supported
+// algorithm must be present.
+func (m *Message) VerifyContentDigest() error {
+	value, ok := m.fieldValue("content-digest")
+	if !ok {
+		return errors.New("message does not contain a Content-Digest header")
+	}
+	d, err := ParseDictionary(value)
+	if err != nil {
+		return errors.Wrap(err, "error parsing Content-Digest")
+	}
+
+	var verified bool
+	for _, member := range d {
+		expected, err := digest(member.Key, m.Body)
+		if err != nil {
+			continue
+		}
+		item, ok := member.Value.(Item)
+		if !ok {
+			return errors.Errorf("error parsing Content-Digest: %s is not a byte sequence", member.Key)
+		}
+		sum, ok := item.Value.([]byte)
+		if !ok {
+			return errors.Errorf("error parsing Content-Digest: %s is not a byte sequence", member.Key)
+		}
+		if subtle.ConstantTimeCompare(sum, expected) != 1 {
+			return errors.Errorf("validation failed: %s content digest does not match the body", member.Key)
+		}
+		verified = true
+	}
+	if !verified {
+		return errors.New("validation failed: Content-Digest does not contain a supported algorithm")
+	}
+	return nil
+}
@@ -0,0 +1,446 @@
+// Package httpsig implements HTTP Message Signatures as defined in RFC 9421,
+// and the Content-Digest field defined in RFC 9530.
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Signature algorithms registered in RFC 9421.
+const (
+	RSAPSSSHA512    = "rsa-pss-sha512"
+	RSAv15SHA256    = "rsa-v1_5-sha256"
+	HMACSHA256      = "hmac-sha256"
+	ECDSAP256SHA256 = "ecdsa-p256-sha256"
+	ECDSAP384SHA384 = "ecdsa-p384-sha384"
+	Ed25519         = "ed25519"
+)
+
+// Header field names.
+const (
+	SignatureInputHeader = "Signature-Input"
+	SignatureHeader      = "Signature"
+)
+
+// DefaultLabel is the label used if none is given.
+const DefaultLabel = "sig1"
+
+// Algorithms returns the list of supported algorithms.
+func Algorithms() []string {
+	return []string{RSAPSSSHA512, RSAv15SHA256, HMACSHA256, ECDSAP256SHA256, ECDSAP384SHA384, Ed25519}
+}
+
+// DefaultAlgorithm returns the default algorithm for the given key. The key
+// can be a crypto.Signer, a public key or a []byte with a symmetric key.
+func DefaultAlgorithm(key interface{}) (string, error) {
+	if s, ok := key.(crypto.Signer); ok {
+		key = s.Public()
+	}
+	switch k := key.(type) {
+	case []byte:
+		return HMACSHA256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ECDSAP256SHA256, nil
+		case elliptic.P384():
+			return ECDSAP384SHA384, nil
+		default:
+			return "", errors.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+	case *rsa.PublicKey:
+		return RSAPSSSHA512, nil
+	case ed25519.PublicKey:
+		return Ed25519, nil
+	default:
+		return "", errors.Errorf("unsupported key type %T", k)
+	}
+}
+
+// validateKey checks that the algorithm is compatible with the key.
+func validateKey(alg string, key interface{}) error {
+	if s, ok := key.(crypto.Signer); ok {
+		key = s.Public()
+	}
+	var ok bool
+	switch alg {
+	case RSAPSSSHA512, RSAv15SHA256:
+		_, ok = key.(*rsa.PublicKey)
+	case HMACSHA256:
+		_, ok = key.([]byte)
+	case ECDSAP256SHA256:
+		k, isEC := key.(*ecdsa.PublicKey)
+		ok = isEC && k.Curve == elliptic.P256()
+	case ECDSAP384SHA384:
+		k, isEC := key.(*ecdsa.PublicKey)
+		ok = isEC && k.Curve == elliptic.P384()
+	case Ed25519:
+		_, ok = key.(ed25519.PublicKey)
+	default:
+		return errors.Errorf("unsupported algorithm %q", alg)
+	}
+	if !ok {
+		return errors.Errorf("algorithm %s is not compatible with key type %T", alg, key)
+	}
+	return nil
+}
+
+// SignOptions are the options used to create a signature.
+type SignOptions struct {
+	// Label is the label of the signature, sig1 by default.
+	Label string
+	// Components are the covered component identifiers, for example @method,
+	// content-type or "@query-param";name="id".
+	Components []string
+	// Algorithm is the signature algorithm. If empty, a default is selected
+	// based on the key.
+	Algorithm string
+	// IncludeAlgorithm adds the alg parameter to the signature parameters.
+	IncludeAlgorithm bool
+	KeyID            string
+	Created          time.Time
+	Expires          time.Time
+	Nonce            string
+	Tag              string
+}
+
+// ParseComponent parses a component identifier. Plain names are accepted as
+// well as serialized identifiers with parameters.
+func ParseComponent(s string) (Item, error) {
+	if !strings.HasPrefix(s, `"`) {
+		s = `"` + s + `"`
+	}
+	p := &sfParser{s: s}
+	item, err := p.parseItem()
+	if err != nil {
+		return Item{}, errors.Wrapf(err, "error parsing component %s", s)
+	}
+	if _, ok := item.Value.(string); !ok || !p.eof() {
+		return Item{}, errors.Errorf("error parsing component %s", s)
+	}
+	return item, nil
+}
+
+// Sign signs the message and returns the values of the Signature-Input and
+// Signature fields. The key is a crypto.Signer or a []byte with a symmetric
+// key.
+func (m *Message) Sign(key interface{}, opts SignOptions) (input, signature string, err error) {
+	alg := opts.Algorithm
+	if alg == "" {
+		if alg, err = DefaultAlgorithm(key); err != nil {
+			return "", "", err
+		}
+	}
+	if err := validateKey(alg, key); err != nil {
+		return "", "", err
+	}
+	label := opts.Label
+	if label == "" {
+		label = DefaultLabel
+	}
+	if _, err := (&sfParser{s: label}).parseKey(); err != nil {
+		return "", "", errors.Errorf("invalid label %q", label)
+	}
+
+	var l InnerList
+	for _, s := range opts.Components {
+		c, err := ParseComponent(s)
+		if err != nil {
+			return "", "", err
+		}
+		l.Items = append(l.Items, c)
+	}
+	if !opts.Created.IsZero() {
+		l.Params = append(l.Params, Param{"created", opts.Created.Unix()})
+	}
+	if !opts.Expires.IsZero() {
+		l.Params = append(l.Params, Param{"expires", opts.Expires.Unix()})
+	}
+	if opts.Nonce != "" {
+		l.Params = append(l.Params, Param{"nonce", opts.Nonce})
+	}
+	if opts.IncludeAlgorithm {
+		l.Params = append(l.Params, Param{"alg", alg})
+	}
+	if opts.KeyID != "" {
+		l.Params = append(l.Params, Param{"keyid", opts.KeyID})
+	}
+	if opts.Tag != "" {
+		l.Params = append(l.Params, Param{"tag", opts.Tag})
+	}
+
+	base, err := m.SignatureBase(l)
+	if err != nil {
+		return "", "", err
+	}
+	sig, err := sign(key, alg, base)
+	if err != nil {
+		return "", "", err
+	}
+	return label + "=" + l.String(), label + "=" + Item{Value: sig}.String(), nil
+}
+
+// SignatureBase returns the signature base for the given covered components
+// and signature parameters.
+func (m *Message) SignatureBase(l InnerList) ([]byte, error) {
+	var sb strings.Builder
+	seen := make(map[string]bool)
+	for _, c := range l.Items {
+		id := c.String()
+		if seen[id] {
+			return nil, errors.Errorf("component %s is duplicated", id)
+		}
+		seen[id] = true
+		values, err := m.componentValues(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if strings.ContainsAny(v, "\r\n") {
+				return nil, errors.Errorf("component %s contains a newline", id)
+			}
+			sb.WriteString(id)
+			sb.WriteString(": ")
+			sb.WriteString(v)
+			sb.WriteByte('\n')
+		}
+	}
+	sb.WriteString(`"@signature-params": `)
+	sb.WriteString(l.String())
+	return []byte(sb.String()), nil
+}
+
+// Signature is a signature in a message.
+type Signature struct {
+	Label      string
+	Components []string
+	Created    time.Time
+	Expires    time.Time
+	Nonce      string
+	Algorithm  string
+	KeyID      string
+	Tag        string
+	Value      []byte
+	input      InnerList
+}
+
+// Signatures returns the signatures in the Signature-Input and Signature
+// fields of the message.
+func (m *Message) Signatures() ([]*Signature, error) {
+	inputValue, ok := m.fieldValue(strings.ToLower(SignatureInputHeader))
+	if !ok {
+		return nil, errors.New("message does not contain a Signature-Input header")
+	}
+	sigValue, ok := m.fieldValue(strings.ToLower(SignatureHeader))
+	if !ok {
+		return nil, errors.New("message does not contain a Signature header")
+	}
+	inputs, err := ParseDictionary(inputValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing Signature-Input")
+	}
+	sigs, err := ParseDictionary(sigValue)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing Signature")
+	}
+
+	var ret []*Signature
+	for _, in := range inputs {
+		l, ok := in.Value.(InnerList)
+		if !ok {
+			return nil, errors.Errorf("error parsing Signature-Input: %s is not an inner list", in.Key)
+		}
+		v, ok := sigs.Get(in.Key)
+		if !ok {
+			return nil, errors.Errorf("signature %s not found in the Signature header", in.Key)
+		}
+		item, ok := v.(Item)
+		if !ok {
+			return nil, errors.Errorf("error parsing Signature: %s is not a byte sequence", in.Key)
+		}
+		value, ok := item.Value.([]byte)
+		if !ok {
+			return nil, errors.Errorf("error parsing Signature: %s is not a byte sequence", in.Key)
+		}
+
+		s := &Signature{
+			Label:     in.Key,
+			Nonce:     l.Params.String("nonce"),
+			Algorithm: l.Params.String("alg"),
+			KeyID:     l.Params.String("keyid"),
+			Tag:       l.Params.String("tag"),
+			Value:     value,
+			input:     l,
+		}
+		for _, c := range l.Items {
+			s.Components = append(s.Components, componentName(c))
+		}
+		if v, ok := l.Params.Get("created"); ok {
+			n, ok := v.(int64)
+			if !ok {
+				return nil, errors.Errorf("error parsing Signature-Input: %s has an invalid created parameter", in.Key)
+			}
+			s.Created = time.Unix(n, 0)
+		}
+		if v, ok := l.Params.Get("expires"); ok {
+			n, ok := v.(int64)
+			if !ok {
+				return nil, errors.Errorf("error parsing Signature-Input: %s has an invalid expires parameter", in.Key)
+			}
+			s.Expires = time.Unix(n, 0)
+		}
+		ret = append(ret, s)
+	}
+	return ret, nil
+}
+
+// componentName returns the name of a component identifier without quotes if
+// it does not have parameters, or its serialization otherwise.
+func componentName(c Item) string {
+	if name, ok := c.Value.(string); ok && len(c.Params) == 0 {
+		return name
+	}
+	return c.String()
+}
+
+// Covers returns true if the signature covers the given component identifier.
+func (s *Signature) Covers(component string) bool {
+	c, err := ParseComponent(component)
+	if err != nil {
+		return false
+	}
+	id := c.String()
+	for _, v := range s.input.Items {
+		if v.String() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify verifies the signature in the message with the given public key or
+// symmetric key. The algorithm is taken from the signature parameters; if it
+// is not present the given algorithm or the default algorithm for the key is
+// used.
+func (m *Message) Verify(s *Signature, key interface{}, alg string) error {
+	switch {
+	case s.Algorithm != "" && alg != "" && s.Algorithm != alg:
+		return errors.Errorf("validation failed: alg %s does not match the alg of the signature (%s)", alg, s.Algorithm)
+	case s.Algorithm != "":
+		alg = s.Algorithm
+	case alg == "":
+		var err error
+		if alg, err = DefaultAlgorithm(key); err != nil {
+			return err
+		}
+	}
+	if err := validateKey(alg, key); err != nil {
+		return err
+	}
+
+	base, err := m.SignatureBase(s.input)
+	if err != nil {
+		return err
+	}
+	return verify(key, alg, base, s.Value)
+}
+
+func sign(key interface{}, alg string, base []byte) ([]byte, error) {
+	if k, ok := key.([]byte); ok {
+		h := hmac.New(sha256.New, k)
+		h.Write(base)
+		return h.Sum(nil), nil
+	}
+
+	signer := key.(crypto.Signer)
+	var (
+		digest []byte
+		opts   crypto.SignerOpts
+	)
+	switch alg {
+	case RSAPSSSHA512:
+		sum := sha512.Sum512(base)
+		digest, opts = sum[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}
+	case RSAv15SHA256, ECDSAP256SHA256:
+		sum := sha256.Sum256(base)
+		digest, opts = sum[:], crypto.SHA256
+	case ECDSAP384SHA384:
+		sum := sha512.Sum384(base)
+		digest, opts = sum[:], crypto.SHA384
+	case Ed25519:
+		digest, opts = base, crypto.Hash(0)
+	}
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing message")
+	}
+
+	// ECDSA signatures are the concatenation of r and s.
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); ok {
+		var v struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &v); err != nil {
+			return nil, errors.Wrap(err, "error parsing ECDSA signature")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		v.R.FillBytes(sig[:size])
+		v.S.FillBytes(sig[size:])
+	}
+	return sig, nil
+}
+
+func verify(key interface{}, alg string, base, sig []byte) error {
+	if s, ok := key.(crypto.Signer); ok {
+		key = s.Public()
+	}
+	var ok bool
+	switch k := key.(type) {
+	case []byte:
+		h := hmac.New(sha256.New, k)
+		h.Write(base)
+		ok = hmac.Equal(h.Sum(nil), sig)
+	case *rsa.PublicKey:
+		if alg == RSAPSSSHA512 {
+			sum := sha512.Sum512(base)
+			ok = rsa.VerifyPSS(k, crypto.SHA512, sum[:], sig, &rsa.PSSOptions{SaltLength: 64}) == nil
+		} else {
+			sum := sha256.Sum256(base)
+			ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig) == nil
+		}
+	case *ecdsa.PublicKey:
+		var digest []byte
+		if alg == ECDSAP384SHA384 {
+			sum := sha512.Sum384(base)
+			digest = sum[:]
+		} else {
+			sum := sha256.Sum256(base)
+			digest = sum[:]
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(k, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, base, sig)
+	}
+	if !ok {
+		return errors.New("validation failed: invalid signature")
+	}
+	return nil
+}
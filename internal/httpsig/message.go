@@ -0,0 +1,219 @@
+package httpsig
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Message is a raw HTTP request or response. The raw bytes are kept to be
+// able to add headers without altering the rest of the message.
+type Message struct {
+	Request  *http.Request
+	Response *http.Response
+	Body     []byte
+	scheme   string
+	raw      []byte
+	eol      string
+}
+
+// ReadMessage parses a raw HTTP/1.x request or response. The scheme is used to
+// build the target URI of requests in origin form; it defaults to https.
+func ReadMessage(b []byte, scheme string) (*Message, error) {
+	// Raw messages written by hand usually use LF instead of CRLF.
+	crlf := bytes.Index(b, []byte("\r\n\r\n"))
+	lf := bytes.Index(b, []byte("\n\n"))
+	eol := "\n"
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		eol = "\r\n"
+	case lf >= 0:
+	default:
+		// Add the empty line after a message without body.
+		if i := bytes.IndexByte(b, '\n'); i > 0 && b[i-1] == '\r' {
+			eol = "\r\n"
+		}
+		b = append(bytes.TrimRight(b, "\r\n"), eol+eol...)
+	}
+
+	if scheme == "" {
+		scheme = "https"
+	}
+	m := &Message{
+		scheme: strings.ToLower(scheme),
+		raw:    b,
+		eol:    eol,
+	}
+
+	var (
+		body io.ReadCloser
+		err  error
+	)
+	r := bufio.NewReader(bytes.NewReader(b))
+	if bytes.HasPrefix(b, []byte("HTTP/")) {
+		if m.Response, err = http.ReadResponse(r, nil); err != nil {
+			return nil, errors.Wrap(err, "error parsing HTTP response")
+		}
+		body = m.Response.Body
+	} else {
+		if m.Request, err = http.ReadRequest(r); err != nil {
+			return nil, errors.Wrap(err, "error parsing HTTP request")
+		}
+		body = m.Request.Body
+	}
+	defer body.Close()
+	if m.Body, err = io.ReadAll(body); err != nil {
+		return nil, errors.Wrap(err, "error reading HTTP message body")
+	}
+	return m, nil
+}
+
+// Header returns the header of the message.
+func (m *Message) Header() http.Header {
+	if m.Response != nil {
+		return m.Response.Header
+	}
+	return m.Request.Header
+}
+
+// AddHeader adds a header field line to the message.
+func (m *Message) AddHeader(name, value string) {
+	m.Header().Add(name, value)
+	end := bytes.Index(m.raw, []byte(m.eol+m.eol))
+	line := m.eol + name + ": " + value
+	raw := make([]byte, 0, len(m.raw)+len(line))
+	raw = append(raw, m.raw[:end]...)
+	raw = append(raw, line...)
+	raw = append(raw, m.raw[end:]...)
+	m.raw = raw
+}
+
+// Bytes returns the raw message.
+func (m *Message) Bytes() []byte {
+	return m.raw
+}
+
+// fieldValue returns the canonical value of a header field: the values of all
+// the field lines with the given name joined by a comma and a space.
+func (m *Message) fieldValue(name string) (string, bool) {
+	if name == "host" && m.Request != nil {
+		return m.Request.Host, m.Request.Host != ""
+	}
+	values := m.Header().Values(name)
+	if len(values) == 0 {
+		return "", false
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), true
+}
+
+// componentValues returns the values of the given component identifier. All
+// components have a single value but @query-param, that has one value for
+// each instance of the parameter.
+func (m *Message) componentValues(c Item) ([]string, error) {
+	name, ok := c.Value.(string)
+	if !ok {
+		return nil, errors.New("component identifier is not a string")
+	}
+	for _, p := range c.Params {
+		if p.Key != "name" {
+			return nil, errors.Errorf("component %s: unsupported parameter %q", c, p.Key)
+		}
+	}
+
+	if !strings.HasPrefix(name, "@") {
+		if name != strings.ToLower(name) {
+			return nil, errors.Errorf("component %s: field names must be lowercase", c)
+		}
+		if _, ok := c.Params.Get("name"); ok {
+			return nil, errors.Errorf("component %s: unsupported parameter \"name\"", c)
+		}
+		v, ok := m.fieldValue(name)
+		if !ok {
+			return nil, errors.Errorf("component %s: header field not found", c)
+		}
+		return []string{v}, nil
+	}
+
+	if name == "@status" {
+		if m.Response == nil {
+			return nil, errors.Errorf("component %s is only valid in responses", c)
+		}
+		return []string{strconv.Itoa(m.Response.StatusCode)}, nil
+	}
+	if m.Request == nil {
+		return nil, errors.Errorf("component %s is only valid in requests", c)
+	}
+
+	r := m.Request
+	switch name {
+	case "@method":
+		return []string{r.Method}, nil
+	case "@target-uri":
+		if r.URL.IsAbs() {
+			return []string{r.RequestURI}, nil
+		}
+		return []string{m.scheme + "://" + m.authority() + r.RequestURI}, nil
+	case "@authority":
+		return []string{m.authority()}, nil
+	case "@scheme":
+		if r.URL.IsAbs() {
+			return []string{strings.ToLower(r.URL.Scheme)}, nil
+		}
+		return []string{m.scheme}, nil
+	case "@request-target":
+		return []string{r.RequestURI}, nil
+	case "@path":
+		if p := r.URL.EscapedPath(); p != "" {
+			return []string{p}, nil
+		}
+		return []string{"/"}, nil
+	case "@query":
+		return []string{"?" + r.URL.RawQuery}, nil
+	case "@query-param":
+		param := c.Params.String("name")
+		if param == "" {
+			return nil, errors.Errorf("component %s requires the \"name\" parameter", c)
+		}
+		query, err := url.ParseQuery(r.URL.RawQuery)
+		if err != nil {
+			return nil, errors.Wrapf(err, "component %s: error parsing query", c)
+		}
+		values, ok := query[param]
+		if !ok {
+			return nil, errors.Errorf("component %s: query parameter not found", c)
+		}
+		encoded := make([]string, len(values))
+		for i, v := range values {
+			encoded[i] = strings.ReplaceAll(url.QueryEscape(v), "+", "%20")
+		}
+		return encoded, nil
+	default:
+		return nil, errors.Errorf("unsupported derived component %s", c)
+	}
+}
+
+// authority returns the normalized authority of the request, without the
+// default port of the scheme.
+func (m *Message) authority() string {
+	host := m.Request.Host
+	if m.Request.URL.IsAbs() {
+		host = m.Request.URL.Host
+	}
+	host = strings.ToLower(host)
+	switch {
+	case m.scheme == "https" && strings.HasSuffix(host, ":443"):
+		host = strings.TrimSuffix(host, ":443")
+	case m.scheme == "http" && strings.HasSuffix(host, ":80"):
+		host = strings.TrimSuffix(host, ":80")
+	}
+	return host
+}
@@ -0,0 +1,408 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// This file implements the subset of Structured Field Values for HTTP (RFC
+// 8941) required to process the Signature-Input, Signature and Content-Digest
+// fields: dictionaries of items and inner lists with parameters.
+
+// Token is a structured field token.
+type Token string
+
+// Param is a structured field parameter.
+type Param struct {
+	Key   string
+	Value interface{}
+}
+
+// Params is an ordered list of parameters.
+type Params []Param
+
+// Get returns the value of the parameter with the given key.
+func (p Params) Get(key string) (interface{}, bool) {
+	for _, v := range p {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns the value of a string parameter.
+func (p Params) String(key string) string {
+	v, _ := p.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// Item is a structured field item. The bare value is an int64, a string, a
+// Token, a []byte or a bool.
+type Item struct {
+	Value  interface{}
+	Params Params
+}
+
+// InnerList is a structured field inner list.
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+// Member is a member of a structured field dictionary. The value is an Item
+// or an InnerList.
+type Member struct {
+	Key   string
+	Value interface{}
+}
+
+// Dictionary is an ordered structured field dictionary.
+type Dictionary []Member
+
+// Get returns the value of the member with the given key.
+func (d Dictionary) Get(key string) (interface{}, bool) {
+	// The last member wins if a key is duplicated.
+	for i := len(d) - 1; i >= 0; i-- {
+		if d[i].Key == key {
+			return d[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+type sfParser struct {
+	s   string
+	pos int
+}
+
+// ParseDictionary parses a structured field dictionary.
+func ParseDictionary(s string) (Dictionary, error) {
+	p := &sfParser{s: s}
+	p.skipSP()
+	var d Dictionary
+	for !p.eof() {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if p.consume('=') {
+			if value, err = p.parseItemOrInnerList(); err != nil {
+				return nil, err
+			}
+		} else {
+			params, err := p.parseParams()
+			if err != nil {
+				return nil, err
+			}
+			value = Item{Value: true, Params: params}
+		}
+		d = append(d, Member{Key: key, Value: value})
+
+		p.skipOWS()
+		if p.eof() {
+			return d, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected ','")
+		}
+		p.skipOWS()
+		if p.eof() {
+			return nil, p.errorf("trailing comma")
+		}
+	}
+	return d, nil
+}
+
+func (p *sfParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *sfParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if p.peek() == c && !p.eof() {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sfParser) skipSP() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.pos++
+	}
+}
+
+func (p *sfParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("error parsing structured field at position %d: "+format, append([]interface{}{p.pos}, args...)...)
+}
+
+func isLCAlpha(c byte) bool { return c >= 'a' && c <= 'z' }
+func isAlpha(c byte) bool   { return isLCAlpha(c) || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool   { return c >= '0' && c <= '9' }
+
+func (p *sfParser) parseKey() (string, error) {
+	if c := p.peek(); !isLCAlpha(c) && c != '*' {
+		return "", p.errorf("invalid key")
+	}
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if !isLCAlpha(c) && !isDigit(c) && !strings.ContainsRune("_-.*", rune(c)) {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *sfParser) parseItemOrInnerList() (interface{}, error) {
+	if p.peek() == '(' {
+		return p.parseInnerList()
+	}
+	return p.parseItem()
+}
+
+func (p *sfParser) parseInnerList() (InnerList, error) {
+	var l InnerList
+	if !p.consume('(') {
+		return l, p.errorf("expected '('")
+	}
+	for !p.eof() {
+		p.skipSP()
+		if p.consume(')') {
+			params, err := p.parseParams()
+			if err != nil {
+				return l, err
+			}
+			l.Params = params
+			return l, nil
+		}
+		item, err := p.parseItem()
+		if err != nil {
+			return l, err
+		}
+		l.Items = append(l.Items, item)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return l, p.errorf("expected ' ' or ')'")
+		}
+	}
+	return l, p.errorf("unterminated inner list")
+}
+
+func (p *sfParser) parseItem() (Item, error) {
+	v, err := p.parseBareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	params, err := p.parseParams()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: v, Params: params}, nil
+}
+
+func (p *sfParser) parseParams() (Params, error) {
+	var params Params
+	for p.consume(';') {
+		p.skipSP()
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{} = true
+		if p.consume('=') {
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, Param{Key: key, Value: value})
+	}
+	return params, nil
+}
+
+func (p *sfParser) parseBareItem() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == '-' || isDigit(c):
+		return p.parseInteger()
+	case c == '"':
+		return p.parseString()
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		return p.parseBoolean()
+	case isAlpha(c) || c == '*':
+		return p.parseToken()
+	default:
+		return nil, p.errorf("unexpected character %q", c)
+	}
+}
+
+func (p *sfParser) parseInteger() (int64, error) {
+	start := p.pos
+	p.consume('-')
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+	if p.peek() == '.' {
+		return 0, p.errorf("decimals are not supported")
+	}
+	s := p.s[start:p.pos]
+	if len(strings.TrimPrefix(s, "-")) == 0 || len(strings.TrimPrefix(s, "-")) > 15 {
+		return 0, p.errorf("invalid integer")
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, p.errorf("invalid integer")
+	}
+	return n, nil
+}
+
+func (p *sfParser) parseString() (string, error) {
+	var sb strings.Builder
+	p.pos++
+	for !p.eof() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			c = p.s[p.pos]
+			p.pos++
+			if c != '"' && c != '\\' {
+				return "", p.errorf("invalid escape sequence")
+			}
+			sb.WriteByte(c)
+		case c == '"':
+			return sb.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid character in string")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *sfParser) parseByteSequence() ([]byte, error) {
+	p.pos++
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+	v := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		if b, err = base64.RawStdEncoding.DecodeString(v); err != nil {
+			return nil, p.errorf("invalid byte sequence")
+		}
+	}
+	return b, nil
+}
+
+func (p *sfParser) parseBoolean() (bool, error) {
+	p.pos++
+	switch {
+	case p.consume('1'):
+		return true, nil
+	case p.consume('0'):
+		return false, nil
+	default:
+		return false, p.errorf("invalid boolean")
+	}
+}
+
+func (p *sfParser) parseToken() (Token, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c <= 0x20 || c >= 0x7f || strings.ContainsRune(`"(),;<=>?@[\]{}`, rune(c)) {
+			break
+		}
+		p.pos++
+	}
+	return Token(p.s[start:p.pos]), nil
+}
+
+func serializeBareItem(sb *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case int64:
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case string:
+		sb.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			if v[i] == '"' || v[i] == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(v[i])
+		}
+		sb.WriteByte('"')
+	case Token:
+		sb.WriteString(string(v))
+	case []byte:
+		sb.WriteByte(':')
+		sb.WriteString(base64.StdEncoding.EncodeToString(v))
+		sb.WriteByte(':')
+	case bool:
+		if v {
+			sb.WriteString("?1")
+		} else {
+			sb.WriteString("?0")
+		}
+	}
+}
+
+func serializeParams(sb *strings.Builder, params Params) {
+	for _, p := range params {
+		sb.WriteByte(';')
+		sb.WriteString(p.Key)
+		if b, ok := p.Value.(bool); ok && b {
+			continue
+		}
+		sb.WriteByte('=')
+		serializeBareItem(sb, p.Value)
+	}
+}
+
+// String returns the serialization of the item.
+func (i Item) String() string {
+	var sb strings.Builder
+	serializeBareItem(&sb, i.Value)
+	serializeParams(&sb, i.Params)
+	return sb.String()
+}
+
+// String returns the serialization of the inner list.
+func (l InnerList) String() string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for i, item := range l.Items {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(item.String())
+	}
+	sb.WriteByte(')')
+	serializeParams(&sb, l.Params)
+	return sb.String()
+}
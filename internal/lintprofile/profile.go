@@ -0,0 +1,464 @@
+// Package lintprofile implements the lint profiles of the certificate lint
+// command. A profile selects the zlint sources and lints to run, and adds
+// custom rules for private PKIs, like the allowed key types, the maximum
+// validity, and the required subject alternative names and extended key
+// usages.
+package lintprofile
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/zlint/lints"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources maps the names used in profiles to the zlint lint sources.
+var Sources = map[string]lints.LintSource{
+	"cabf_br": lints.CABFBaselineRequirements,
+	"rfc5280": lints.RFC5280,
+	"rfc5891": lints.RFC5891,
+	"zlint":   lints.ZLint,
+	"awslabs": lints.AWSLabs,
+}
+
+// Names of the custom rules.
+const (
+	RuleKeyType             = "e_profile_key_type"
+	RuleMaxValidity         = "e_profile_max_validity"
+	RuleSANRequired         = "e_profile_san_required"
+	RuleSANPattern          = "e_profile_san_pattern"
+	RuleRequiredExtKeyUsage = "e_profile_required_ext_key_usage"
+	RuleForbiddenExtension  = "e_profile_forbidden_extension"
+)
+
+// Rules contains the description of the custom rules.
+var Rules = map[string]string{
+	RuleKeyType:             "The public key must be one of the key types allowed by the profile",
+	RuleMaxValidity:         "The validity period must not be longer than the maximum allowed by the profile",
+	RuleSANRequired:         "The subject alternative name extension must be present",
+	RuleSANPattern:          "The subject alternative names must match the patterns allowed by the profile",
+	RuleRequiredExtKeyUsage: "The extended key usage extension must contain the usages required by the profile",
+	RuleForbiddenExtension:  "The extensions forbidden by the profile must not be present",
+}
+
+var (
+	oidExtensionExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtensionSAN         = asn1.ObjectIdentifier{2, 5, 29, 17}
+)
+
+// extKeyUsages maps the names of the extended key usages to their OIDs.
+var extKeyUsages = map[string]string{
+	"any":             "2.5.29.37.0",
+	"serverAuth":      "1.3.6.1.5.5.7.3.1",
+	"clientAuth":      "1.3.6.1.5.5.7.3.2",
+	"codeSigning":     "1.3.6.1.5.5.7.3.3",
+	"emailProtection": "1.3.6.1.5.5.7.3.4",
+	"timeStamping":    "1.3.6.1.5.5.7.3.8",
+	"OCSPSigning":     "1.3.6.1.5.5.7.3.9",
+}
+
+// extensions maps the names of common extensions to their OIDs.
+var extensions = map[string]string{
+	"subjectKeyIdentifier":   "2.5.29.14",
+	"keyUsage":               "2.5.29.15",
+	"subjectAltName":         "2.5.29.17",
+	"basicConstraints":       "2.5.29.19",
+	"crlNumber":              "2.5.29.20",
+	"nameConstraints":        "2.5.29.30",
+	"crlDistributionPoints":  "2.5.29.31",
+	"certificatePolicies":    "2.5.29.32",
+	"policyConstraints":      "2.5.29.36",
+	"authorityKeyIdentifier": "2.5.29.35",
+	"extKeyUsage":            "2.5.29.37",
+	"freshestCRL":            "2.5.29.46",
+	"inhibitAnyPolicy":       "2.5.29.54",
+	"authorityInfoAccess":    "1.3.6.1.5.5.7.1.1",
+	"ocspNoCheck":            "1.3.6.1.5.5.7.48.1.5",
+	"sctList":                "1.3.6.1.4.1.11129.2.4.2",
+	"ctPoison":               "1.3.6.1.4.1.11129.2.4.3",
+}
+
+// Profile is a lint profile.
+type Profile struct {
+	Name            string   `yaml:"name"`
+	DisabledSources []string `yaml:"disabledSources"`
+	DisabledLints   []string `yaml:"disabledLints"`
+	Rules           Options  `yaml:"rules"`
+
+	sources     map[lints.LintSource]bool
+	maxValidity time.Duration
+	sanPatterns map[string][]*regexp.Regexp
+	ekus        []string
+	forbidden   []string
+}
+
+// Options are the options of the custom rules of a profile.
+type Options struct {
+	KeyTypes             []KeyType `yaml:"keyTypes"`
+	MaxValidity          string    `yaml:"maxValidity"`
+	SANs                 SANs      `yaml:"sans"`
+	RequiredExtKeyUsages []string  `yaml:"requiredExtKeyUsages"`
+	ForbiddenExtensions  []string  `yaml:"forbiddenExtensions"`
+}
+
+// KeyType is a key type allowed by a profile. The type is EC, RSA or OKP.
+// MinSize is the minimum size of RSA keys, and Curves the allowed curves of EC
+// keys. If they are not set, any size or curve is allowed.
+type KeyType struct {
+	Type    string   `yaml:"type"`
+	MinSize int      `yaml:"minSize"`
+	Curves  []string `yaml:"curves"`
+}
+
+// SANs are the rules of the subject alternative names. If a list of
+// patterns is set, all the names of that type must match one of the regular
+// expressions in the list.
+type SANs struct {
+	Required bool     `yaml:"required"`
+	DNS      []string `yaml:"dns"`
+	IP       []string `yaml:"ip"`
+	Email    []string `yaml:"email"`
+	URI      []string `yaml:"uri"`
+}
+
+// Load reads and parses the profile in the given file.
+func Load(filename string) (*Profile, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", filename)
+	}
+	p, err := Parse(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", filename)
+	}
+	return p, nil
+}
+
+// Parse parses a profile in YAML or JSON format.
+func Parse(b []byte) (*Profile, error) {
+	p := new(Profile)
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil {
+		return nil, err
+	}
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Profile) init() error {
+	p.sources = make(map[lints.LintSource]bool)
+	for _, name := range p.DisabledSources {
+		src, ok := Sources[name]
+		if !ok {
+			return errors.Errorf("unknown lint source %q", name)
+		}
+		p.sources[src] = true
+	}
+	for _, name := range p.DisabledLints {
+		if _, ok := lints.Lints[name]; !ok {
+			return errors.Errorf("unknown lint %q", name)
+		}
+	}
+
+	for _, kt := range p.Rules.KeyTypes {
+		switch kt.Type {
+		case "EC":
+			for _, crv := range kt.Curves {
+				if crv != "P-256" && crv != "P-384" && crv != "P-521" {
+					return errors.Errorf("unsupported curve %q", crv)
+				}
+			}
+		case "RSA", "OKP":
+		default:
+			return errors.Errorf("unsupported key type %q", kt.Type)
+		}
+	}
+
+	if s := p.Rules.MaxValidity; s != "" {
+		d, err := parseDuration(s)
+		if err != nil {
+			return errors.Errorf("invalid maxValidity %q", s)
+		}
+		p.maxValidity = d
+	}
+
+	p.sanPatterns = make(map[string][]*regexp.Regexp)
+	for typ, patterns := range map[string][]string{
+		"DNS": p.Rules.SANs.DNS, "IP": p.Rules.SANs.IP,
+		"email": p.Rules.SANs.Email, "URI": p.Rules.SANs.URI,
+	} {
+		for _, s := range patterns {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return errors.Wrapf(err, "invalid %s pattern", typ)
+			}
+			p.sanPatterns[typ] = append(p.sanPatterns[typ], re)
+		}
+	}
+
+	for _, s := range p.Rules.RequiredExtKeyUsages {
+		oid, err := parseOID(s, extKeyUsages)
+		if err != nil {
+			return errors.Wrap(err, "invalid extended key usage")
+		}
+		p.ekus = append(p.ekus, oid)
+	}
+	for _, s := range p.Rules.ForbiddenExtensions {
+		oid, err := parseOID(s, extensions)
+		if err != nil {
+			return errors.Wrap(err, "invalid extension")
+		}
+		p.forbidden = append(p.forbidden, oid)
+	}
+	return nil
+}
+
+// parseDuration parses a Go duration, or a number of days with the suffix
+// "d".
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, errors.New("invalid duration")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, errors.New("invalid duration")
+	}
+	return d, nil
+}
+
+// parseOID returns the OID of a name in the given map, or the OID in dotted
+// notation.
+func parseOID(s string, names map[string]string) (string, error) {
+	if oid, ok := names[s]; ok {
+		return oid, nil
+	}
+	for _, part := range strings.Split(s, ".") {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return "", errors.Errorf("%q is not a known name or an OID", s)
+		}
+	}
+	return s, nil
+}
+
+// Enabled returns true if the given zlint lint must run. Without a profile
+// all the lints run.
+func (p *Profile) Enabled(name string, l *lints.Lint) bool {
+	if p == nil {
+		return true
+	}
+	return !p.sources[l.Source] && !slices.Contains(p.DisabledLints, name)
+}
+
+// CheckCertificate runs the custom rules on a certificate.
+func (p *Profile) CheckCertificate(crt *x509.Certificate) map[string]*lints.LintResult {
+	results := make(map[string]*lints.LintResult)
+	p.checkKey(results, crt.PublicKey)
+	if p.maxValidity > 0 {
+		results[RuleMaxValidity] = p.checkValidity(crt.NotBefore, crt.NotAfter)
+	}
+	p.checkSANs(results, crt.Extensions, crt.DNSNames, crt.IPAddresses, crt.EmailAddresses, crt.URIs)
+	p.checkExtensions(results, crt.Extensions)
+	return results
+}
+
+// CheckCSR runs the custom rules on a certificate signing request. The
+// maximum validity rule does not apply to CSRs.
+func (p *Profile) CheckCSR(csr *x509.CertificateRequest) map[string]*lints.LintResult {
+	results := make(map[string]*lints.LintResult)
+	p.checkKey(results, csr.PublicKey)
+	p.checkSANs(results, csr.Extensions, csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs)
+	p.checkExtensions(results, csr.Extensions)
+	return results
+}
+
+// CheckCRL runs the custom rules on a CRL. The maximum validity applies to the
+// time between the this update and next update fields, and the forbidden
+// extensions to the CRL extensions. Other rules do not apply to CRLs.
+func (p *Profile) CheckCRL(crl *x509.RevocationList) map[string]*lints.LintResult {
+	results := make(map[string]*lints.LintResult)
+	if p.maxValidity > 0 {
+		if crl.NextUpdate.IsZero() {
+			results[RuleMaxValidity] = &lints.LintResult{Status: lints.Error, Details: "the CRL does not have a next update time"}
+		} else {
+			results[RuleMaxValidity] = p.checkValidity(crl.ThisUpdate, crl.NextUpdate)
+		}
+	}
+	if len(p.forbidden) > 0 {
+		results[RuleForbiddenExtension] = p.checkForbidden(crl.Extensions)
+	}
+	return results
+}
+
+func (p *Profile) checkKey(results map[string]*lints.LintResult, key any) {
+	if len(p.Rules.KeyTypes) == 0 {
+		return
+	}
+	var desc string
+	for _, kt := range p.Rules.KeyTypes {
+		switch pub := key.(type) {
+		case *ecdsa.PublicKey:
+			desc = "EC " + pub.Curve.Params().Name
+			if kt.Type == "EC" && (len(kt.Curves) == 0 || slices.Contains(kt.Curves, pub.Curve.Params().Name)) {
+				results[RuleKeyType] = pass()
+				return
+			}
+		case *rsa.PublicKey:
+			desc = "RSA " + strconv.Itoa(pub.N.BitLen())
+			if kt.Type == "RSA" && pub.N.BitLen() >= kt.MinSize {
+				results[RuleKeyType] = pass()
+				return
+			}
+		case ed25519.PublicKey:
+			desc = "OKP Ed25519"
+			if kt.Type == "OKP" {
+				results[RuleKeyType] = pass()
+				return
+			}
+		default:
+			desc = "unsupported"
+		}
+	}
+	results[RuleKeyType] = &lints.LintResult{
+		Status:  lints.Error,
+		Details: "key type " + desc + " is not allowed",
+	}
+}
+
+func (p *Profile) checkValidity(notBefore, notAfter time.Time) *lints.LintResult {
+	if d := notAfter.Sub(notBefore); d > p.maxValidity {
+		return &lints.LintResult{
+			Status:  lints.Error,
+			Details: "validity of " + d.String() + " exceeds the maximum of " + p.maxValidity.String(),
+		}
+	}
+	return pass()
+}
+
+func (p *Profile) checkSANs(results map[string]*lints.LintResult, exts []pkix.Extension, dnsNames []string, ips []net.IP, emails []string, uris []*url.URL) {
+	if p.Rules.SANs.Required {
+		if hasExtension(exts, oidExtensionSAN) {
+			results[RuleSANRequired] = pass()
+		} else {
+			results[RuleSANRequired] = &lints.LintResult{Status: lints.Error, Details: "the subject alternative name extension is missing"}
+		}
+	}
+	if len(p.sanPatterns) == 0 {
+		return
+	}
+
+	names := map[string][]string{"DNS": dnsNames, "email": emails}
+	for _, ip := range ips {
+		names["IP"] = append(names["IP"], ip.String())
+	}
+	for _, u := range uris {
+		names["URI"] = append(names["URI"], u.String())
+	}
+	var invalid []string
+	for _, typ := range []string{"DNS", "IP", "email", "URI"} {
+		patterns, ok := p.sanPatterns[typ]
+		if !ok {
+			continue
+		}
+		for _, name := range names[typ] {
+			if !slices.ContainsFunc(patterns, func(re *regexp.Regexp) bool {
+				return re.MatchString(name)
+			}) {
+				invalid = append(invalid, typ+":"+name)
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		results[RuleSANPattern] = &lints.LintResult{
+			Status:  lints.Error,
+			Details: "names do not match the allowed patterns: " + strings.Join(invalid, ", "),
+		}
+	} else {
+		results[RuleSANPattern] = pass()
+	}
+}
+
+func (p *Profile) checkExtensions(results map[string]*lints.LintResult, exts []pkix.Extension) {
+	if len(p.ekus) > 0 {
+		results[RuleRequiredExtKeyUsage] = p.checkExtKeyUsages(exts)
+	}
+	if len(p.forbidden) > 0 {
+		results[RuleForbiddenExtension] = p.checkForbidden(exts)
+	}
+}
+
+func (p *Profile) checkExtKeyUsages(exts []pkix.Extension) *lints.LintResult {
+	var present []string
+	for _, ext := range exts {
+		if !ext.Id.Equal(oidExtensionExtKeyUsage) {
+			continue
+		}
+		var oids []asn1.ObjectIdentifier
+		if rest, err := asn1.Unmarshal(ext.Value, &oids); err != nil || len(rest) > 0 {
+			return &lints.LintResult{Status: lints.Error, Details: "the extended key usage extension is malformed"}
+		}
+		for _, oid := range oids {
+			present = append(present, oid.String())
+		}
+	}
+	var missing []string
+	for _, oid := range p.ekus {
+		if !slices.Contains(present, oid) {
+			missing = append(missing, oid)
+		}
+	}
+	if len(missing) > 0 {
+		return &lints.LintResult{
+			Status:  lints.Error,
+			Details: "missing extended key usages: " + strings.Join(missing, ", "),
+		}
+	}
+	return pass()
+}
+
+func (p *Profile) checkForbidden(exts []pkix.Extension) *lints.LintResult {
+	var found []string
+	for _, ext := range exts {
+		if slices.Contains(p.forbidden, ext.Id.String()) {
+			found = append(found, ext.Id.String())
+		}
+	}
+	if len(found) > 0 {
+		return &lints.LintResult{
+			Status:  lints.Error,
+			Details: "forbidden extensions present: " + strings.Join(found, ", "),
+		}
+	}
+	return pass()
+}
+
+func hasExtension(exts []pkix.Extension, oid asn1.ObjectIdentifier) bool {
+	return slices.ContainsFunc(exts, func(ext pkix.Extension) bool {
+		return ext.Id.Equal(oid)
+	})
+}
+
+func pass() *lints.LintResult {
+	return &lints.LintResult{Status: lints.Pass}
+}
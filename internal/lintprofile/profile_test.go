@@ -0,0 +1,260 @@
+package lintprofile
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smallstep/zlint/lints"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+const testProfile = `
+name: internal
+disabledSources: [cabf_br]
+disabledLints: [w_ext_subject_key_identifier_missing_sub_cert]
+rules:
+  keyTypes:
+  - type: EC
+    curves: [P-256]
+  - type: RSA
+    minSize: 3072
+  maxValidity: 90d
+  sans:
+    required: true
+    dns: ['^[a-z0-9-]+\.internal\.example\.com$']
+  requiredExtKeyUsages: [serverAuth, 1.3.6.1.5.5.7.3.2]
+  forbiddenExtensions: [nameConstraints]
+`
+
+func newTemplate() *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.internal.example.com"},
+		DNSNames:     []string{"www.internal.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+}
+
+func signCertificate(t *testing.T, ca *minica.CA, template *x509.Certificate, key crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template.PublicKey = key.Public()
+	crt, err := ca.Sign(template)
+	require.NoError(t, err)
+	return crt
+}
+
+func statuses(results map[string]*lints.LintResult) map[string]lints.LintStatus {
+	m := make(map[string]lints.LintStatus, len(results))
+	for name, res := range results {
+		m[name] = res.Status
+	}
+	return m
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testProfile))
+	require.NoError(t, err)
+	assert.Equal(t, "internal", p.Name)
+	assert.Equal(t, 90*24*time.Hour, p.maxValidity)
+	assert.Equal(t, []string{"1.3.6.1.5.5.7.3.1", "1.3.6.1.5.5.7.3.2"}, p.ekus)
+	assert.Equal(t, []string{"2.5.29.30"}, p.forbidden)
+
+	for name, l := range lints.Lints {
+		switch {
+		case name == "w_ext_subject_key_identifier_missing_sub_cert":
+			assert.False(t, p.Enabled(name, l))
+		case l.Source == lints.CABFBaselineRequirements:
+			assert.False(t, p.Enabled(name, l), name)
+		default:
+			assert.True(t, p.Enabled(name, l), name)
+		}
+	}
+	var nilProfile *Profile
+	assert.True(t, nilProfile.Enabled("e_foo", &lints.Lint{}))
+
+	// JSON is also accepted.
+	_, err = Parse([]byte(`{"name": "json", "rules": {"maxValidity": "24h"}}`))
+	assert.NoError(t, err)
+
+	tests := map[string]string{
+		"unknown field":  "foo: bar",
+		"unknown source": "disabledSources: [mozilla]",
+		"unknown lint":   "disabledLints: [e_foo]",
+		"bad key type":   "rules: {keyTypes: [{type: DSA}]}",
+		"bad curve":      "rules: {keyTypes: [{type: EC, curves: [P-224]}]}",
+		"bad validity":   "rules: {maxValidity: 10x}",
+		"bad pattern":    "rules: {sans: {dns: ['(']}}",
+		"bad usage":      "rules: {requiredExtKeyUsages: [fooAuth]}",
+		"bad extension":  "rules: {forbiddenExtensions: [1.2.x]}",
+	}
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(s))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProfile_CheckCertificate(t *testing.T) {
+	p, err := Parse([]byte(testProfile))
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	crt := signCertificate(t, ca, newTemplate(), ecKey)
+	assert.Equal(t, map[string]lints.LintStatus{
+		RuleKeyType:             lints.Pass,
+		RuleMaxValidity:         lints.Pass,
+		RuleSANRequired:         lints.Pass,
+		RuleSANPattern:          lints.Pass,
+		RuleRequiredExtKeyUsage: lints.Pass,
+		RuleForbiddenExtension:  lints.Pass,
+	}, statuses(p.CheckCertificate(crt)))
+
+	template := newTemplate()
+	template.DNSNames = append(template.DNSNames, "www.example.com")
+	template.NotAfter = template.NotBefore.Add(91 * 24 * time.Hour)
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	template.PermittedDNSDomains = []string{"internal.example.com"}
+	results := p.CheckCertificate(signCertificate(t, ca, template, rsaKey))
+	assert.Equal(t, map[string]lints.LintStatus{
+		RuleKeyType:             lints.Error,
+		RuleMaxValidity:         lints.Error,
+		RuleSANRequired:         lints.Pass,
+		RuleSANPattern:          lints.Error,
+		RuleRequiredExtKeyUsage: lints.Error,
+		RuleForbiddenExtension:  lints.Error,
+	}, statuses(results))
+	assert.Equal(t, "key type RSA 2048 is not allowed", results[RuleKeyType].Details)
+	assert.Equal(t, "names do not match the allowed patterns: DNS:www.example.com", results[RuleSANPattern].Details)
+	assert.Equal(t, "missing extended key usages: 1.3.6.1.5.5.7.3.2", results[RuleRequiredExtKeyUsage].Details)
+	assert.Equal(t, "forbidden extensions present: 2.5.29.30", results[RuleForbiddenExtension].Details)
+
+	template = newTemplate()
+	template.DNSNames = nil
+	results = p.CheckCertificate(signCertificate(t, ca, template, p384Key))
+	assert.Equal(t, lints.Error, results[RuleKeyType].Status)
+	assert.Equal(t, lints.Error, results[RuleSANRequired].Status)
+	assert.Equal(t, lints.Pass, results[RuleSANPattern].Status)
+}
+
+func TestProfile_CheckCSR(t *testing.T) {
+	p, err := Parse([]byte(testProfile))
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "db.example.com"},
+		DNSNames: []string{"db.example.com"},
+	}, key)
+	require.NoError(t, err)
+	csr, err := x509.ParseCertificateRequest(der)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]lints.LintStatus{
+		RuleKeyType:             lints.Pass,
+		RuleSANRequired:         lints.Pass,
+		RuleSANPattern:          lints.Error,
+		RuleRequiredExtKeyUsage: lints.Error,
+		RuleForbiddenExtension:  lints.Pass,
+	}, statuses(p.CheckCSR(csr)))
+}
+
+func TestProfile_CheckCRL(t *testing.T) {
+	p, err := Parse([]byte(testProfile))
+	require.NoError(t, err)
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now,
+		NextUpdate: now.Add(100 * 24 * time.Hour),
+	}, ca.Intermediate, ca.Signer)
+	require.NoError(t, err)
+	crl, err := x509.ParseRevocationList(der)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]lints.LintStatus{
+		RuleMaxValidity:        lints.Error,
+		RuleForbiddenExtension: lints.Pass,
+	}, statuses(p.CheckCRL(crl)))
+}
+
+func TestNewSARIF(t *testing.T) {
+	s := NewSARIF("leaf.crt", map[string]*lints.LintResult{
+		RuleKeyType:              {Status: lints.Error, Details: "key type RSA 2048 is not allowed"},
+		RuleMaxValidity:          {Status: lints.Pass},
+		"e_sub_cert_aia_missing": {Status: lints.NA},
+		"w_sub_cert_aia_does_not_contain_issuing_ca_url": {Status: lints.Warn},
+	})
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	var v struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID               string `json:"id"`
+						ShortDescription struct {
+							Text string `json:"text"`
+						} `json:"shortDescription"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(b, &v))
+	assert.Equal(t, "2.1.0", v.Version)
+	require.Len(t, v.Runs, 1)
+	run := v.Runs[0]
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	require.Len(t, run.Results, 2)
+
+	assert.Equal(t, RuleKeyType, run.Tool.Driver.Rules[0].ID)
+	assert.Equal(t, Rules[RuleKeyType], run.Tool.Driver.Rules[0].ShortDescription.Text)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, "key type RSA 2048 is not allowed", run.Results[0].Message.Text)
+	assert.Equal(t, "leaf.crt", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+
+	assert.Equal(t, "w_sub_cert_aia_does_not_contain_issuing_ca_url", run.Results[1].RuleID)
+	assert.Equal(t, 1, run.Results[1].RuleIndex)
+	assert.Equal(t, "warning", run.Results[1].Level)
+	assert.NotEmpty(t, run.Results[1].Message.Text)
+}
@@ -0,0 +1,125 @@
+package lintprofile
+
+import (
+	"sort"
+
+	"github.com/smallstep/zlint/lints"
+)
+
+// SARIF is a static analysis results interchange format (SARIF) 2.1.0 log,
+// the format used by code scanning tools.
+type SARIF struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	Properties       *sarifRuleProps `json:"properties,omitempty"`
+}
+
+type sarifRuleProps struct {
+	Citation string `json:"citation,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+// NewSARIF returns a SARIF log with the results of linting the given
+// artifact. Only notices, warnings, errors and fatal results are reported.
+func NewSARIF(uri string, results map[string]*lints.LintResult) *SARIF {
+	names := make([]string, 0, len(results))
+	for name, res := range results {
+		if res.Status >= lints.Notice {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "step certificate lint",
+			InformationURI: "https://smallstep.com/docs/step-cli/reference/certificate/lint",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	for i, name := range names {
+		res := results[name]
+		rule := sarifRule{ID: name}
+		if desc, ok := Rules[name]; ok {
+			rule.ShortDescription.Text = desc
+		} else if l, ok := lints.Lints[name]; ok {
+			rule.ShortDescription.Text = l.Description
+			if l.Citation != "" {
+				rule.Properties = &sarifRuleProps{Citation: l.Citation}
+			}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+
+		message := res.Details
+		if message == "" {
+			message = rule.ShortDescription.Text
+		}
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = uri
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    name,
+			RuleIndex: i,
+			Level:     sarifLevel(res.Status),
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	return &SARIF{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifLevel(status lints.LintStatus) string {
+	switch status {
+	case lints.Notice:
+		return "note"
+	case lints.Warn:
+		return "warning"
+	default:
+		return "error"
+	}
+}
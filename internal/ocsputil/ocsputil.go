@@ -0,0 +1,274 @@
+// Package ocsputil implements helpers to create, send and answer OCSP
+// requests as defined in RFC 6960.
+package ocsputil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	//nolint:gosec // SHA-1 is the default hash in OCSP
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/pkg/errors"
+)
+
+// NonceSize is the size of the nonces created by NewNonce. RFC 8954
+// recommends 32 bytes.
+const NonceSize = 32
+
+// maxResponseSize is the maximum size of an OCSP response.
+const maxResponseSize = 1 << 20
+
+// oidNonce is the object identifier of the OCSP nonce extension.
+var oidNonce = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+var hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   {1, 3, 14, 3, 2, 26},
+	crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+	crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+	crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+}
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type singleRequest struct {
+	Cert            certID
+	ExtensionsBytes asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type tbsRequest struct {
+	Version       int           `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName asn1.RawValue `asn1:"explicit,tag:1,optional"`
+	RequestList   []singleRequest
+	Extensions    []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type ocspRequest struct {
+	TBSRequest        tbsRequest
+	OptionalSignature asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// Request is an OCSP request for a single certificate.
+type Request struct {
+	HashAlgorithm  crypto.Hash
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+	Nonce          []byte
+}
+
+// RequestOptions are the options used to create an OCSP request.
+type RequestOptions struct {
+	// Hash is the hash function used in the CertID. Defaults to SHA-1.
+	Hash crypto.Hash
+	// Nonce is the value of the nonce extension. The extension is not added if
+	// the nonce is empty.
+	Nonce []byte
+}
+
+// NewNonce returns a random nonce of NonceSize bytes.
+func NewNonce() ([]byte, error) {
+	b := make([]byte, NonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "error generating nonce")
+	}
+	return b, nil
+}
+
+// CreateRequest returns the DER encoding of an OCSP request for the given
+// certificate and issuer.
+func CreateRequest(cert, issuer *x509.Certificate, opts *RequestOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &RequestOptions{}
+	}
+	h := opts.Hash
+	if h == 0 {
+		h = crypto.SHA1
+	}
+	hashOID, ok := hashOIDs[h]
+	if !ok || !h.Available() {
+		return nil, errors.Errorf("unsupported hash algorithm %s", h)
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, h)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ocspRequest{
+		TBSRequest: tbsRequest{
+			RequestList: []singleRequest{{
+				Cert: certID{
+					HashAlgorithm: pkix.AlgorithmIdentifier{
+						Algorithm:  hashOID,
+						Parameters: asn1.RawValue{Tag: 5 /* ASN.1 NULL */},
+					},
+					NameHash:      nameHash,
+					IssuerKeyHash: keyHash,
+					SerialNumber:  cert.SerialNumber,
+				},
+			}},
+		},
+	}
+	if len(opts.Nonce) > 0 {
+		value, err := asn1.Marshal(opts.Nonce)
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshaling nonce")
+		}
+		req.TBSRequest.Extensions = []pkix.Extension{{
+			Id:    oidNonce,
+			Value: value,
+		}}
+	}
+
+	b, err := asn1.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling OCSP request")
+	}
+	return b, nil
+}
+
+// ParseRequest parses an OCSP request. Only requests for a single certificate
+// are supported.
+func ParseRequest(b []byte) (*Request, error) {
+	var req ocspRequest
+	rest, err := asn1.Unmarshal(b, &req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing OCSP request")
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("error parsing OCSP request: trailing data")
+	}
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, errors.New("error parsing OCSP request: only requests for a single certificate are supported")
+	}
+
+	id := req.TBSRequest.RequestList[0].Cert
+	ret := &Request{
+		IssuerNameHash: id.NameHash,
+		IssuerKeyHash:  id.IssuerKeyHash,
+		SerialNumber:   id.SerialNumber,
+	}
+	for h, oid := range hashOIDs {
+		if id.HashAlgorithm.Algorithm.Equal(oid) {
+			ret.HashAlgorithm = h
+			break
+		}
+	}
+	if ret.HashAlgorithm == 0 {
+		return nil, errors.Errorf("error parsing OCSP request: unsupported hash algorithm %s", id.HashAlgorithm.Algorithm)
+	}
+	for _, ext := range req.TBSRequest.Extensions {
+		if ext.Id.Equal(oidNonce) {
+			ret.Nonce = unmarshalNonce(ext.Value)
+		}
+	}
+	return ret, nil
+}
+
+// unmarshalNonce returns the nonce in an extension value. RFC 8954 defines
+// the value as an OCTET STRING, but some implementations use the raw bytes.
+func unmarshalNonce(value []byte) []byte {
+	var nonce []byte
+	if rest, err := asn1.Unmarshal(value, &nonce); err == nil && len(rest) == 0 {
+		return nonce
+	}
+	return value
+}
+
+// Query sends an OCSP request to the given responder URL and returns the raw
+// response. The method must be GET or POST; GET requests encode the request
+// in the URL as described in RFC 6960, Appendix A.
+func Query(client *http.Client, server string, req []byte, method string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var (
+		httpReq *http.Request
+		err     error
+	)
+	switch strings.ToUpper(method) {
+	case "", http.MethodPost:
+		httpReq, err = http.NewRequest(http.MethodPost, server, bytes.NewReader(req))
+		if err == nil {
+			httpReq.Header.Set("Content-Type", "application/ocsp-request")
+		}
+	case http.MethodGet:
+		u := strings.TrimSuffix(server, "/") + "/" + url.PathEscape(base64.StdEncoding.EncodeToString(req))
+		httpReq, err = http.NewRequest(http.MethodGet, u, http.NoBody)
+	default:
+		return nil, errors.Errorf("unsupported method %s", method)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating request to %s", server)
+	}
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error contacting OCSP server %s", server)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading response from OCSP server %s", server)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, errors.Errorf("error contacting OCSP server %s: status code %d", server, resp.StatusCode)
+	}
+	return b, nil
+}
+
+// Decode returns the DER bytes of an OCSP request or response in DER, PEM or
+// base64 format.
+func Decode(b []byte) ([]byte, error) {
+	if len(b) > 0 && b[0] == 0x30 {
+		return b, nil
+	}
+	if block, _ := pem.Decode(b); block != nil {
+		return block.Bytes, nil
+	}
+	s := strings.Join(strings.Fields(string(b)), "")
+	der, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("error decoding data: it is not in DER, PEM or base64 format")
+	}
+	return der, nil
+}
+
+// issuerHashes returns the hashes of the issuer name and key used in a CertID.
+func issuerHashes(issuer *x509.Certificate, h crypto.Hash) ([]byte, []byte, error) {
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing issuer public key")
+	}
+
+	hash := h.New()
+	hash.Write(issuer.RawSubject)
+	nameHash := hash.Sum(nil)
+
+	hash.Reset()
+	hash.Write(publicKeyInfo.PublicKey.RightAlign())
+	return nameHash, hash.Sum(nil), nil
+}
@@ -0,0 +1,293 @@
+package ocsputil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/minica"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testPKI struct {
+	issuer, responder, good, revoked *x509.Certificate
+	issuerKey, responderKey          crypto.Signer
+	crl                              *x509.RevocationList
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	ca, err := minica.New()
+	require.NoError(t, err)
+	sign := func(serial int64, template *x509.Certificate) (*x509.Certificate, crypto.Signer) {
+		key, err := keyutil.GenerateDefaultSigner()
+		require.NoError(t, err)
+		template.SerialNumber = big.NewInt(serial)
+		template.PublicKey = key.Public()
+		crt, err := ca.Sign(template)
+		require.NoError(t, err)
+		return crt, key
+	}
+
+	p := &testPKI{issuer: ca.Intermediate, issuerKey: ca.Signer}
+	p.responder, p.responderKey = sign(2, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "Responder"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	})
+	p.good, _ = sign(3, &x509.Certificate{Subject: pkix.Name{CommonName: "good"}})
+	p.revoked, _ = sign(4, &x509.Certificate{Subject: pkix.Name{CommonName: "revoked"}})
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{
+			SerialNumber:   p.revoked.SerialNumber,
+			RevocationTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			ReasonCode:     ocsp.KeyCompromise,
+		}},
+	}, p.issuer, p.issuerKey)
+	require.NoError(t, err)
+	p.crl, err = x509.ParseRevocationList(der)
+	require.NoError(t, err)
+	return p
+}
+
+func TestCreateRequest(t *testing.T) {
+	p := newTestPKI(t)
+	nonce, err := NewNonce()
+	require.NoError(t, err)
+	assert.Len(t, nonce, NonceSize)
+
+	for _, h := range []crypto.Hash{0, crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		for _, n := range [][]byte{nil, nonce} {
+			b, err := CreateRequest(p.good, p.issuer, &RequestOptions{Hash: h, Nonce: n})
+			require.NoError(t, err)
+
+			want := h
+			if want == 0 {
+				want = crypto.SHA1
+			}
+			req, err := ParseRequest(b)
+			require.NoError(t, err)
+			assert.Equal(t, want, req.HashAlgorithm)
+			assert.Equal(t, p.good.SerialNumber, req.SerialNumber)
+			assert.Equal(t, n, req.Nonce)
+
+			// The request must be compatible with x/crypto/ocsp.
+			xreq, err := ocsp.ParseRequest(b)
+			require.NoError(t, err)
+			assert.Equal(t, want, xreq.HashAlgorithm)
+			assert.Equal(t, req.IssuerNameHash, xreq.IssuerNameHash)
+			assert.Equal(t, req.IssuerKeyHash, xreq.IssuerKeyHash)
+			assert.Equal(t, p.good.SerialNumber, xreq.SerialNumber)
+		}
+	}
+
+	_, err = CreateRequest(p.good, p.issuer, &RequestOptions{Hash: crypto.MD5})
+	assert.Error(t, err)
+}
+
+func TestParseRequest(t *testing.T) {
+	p := newTestPKI(t)
+	b, err := ocsp.CreateRequest(p.good, p.issuer, nil)
+	require.NoError(t, err)
+	req, err := ParseRequest(b)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.SHA1, req.HashAlgorithm)
+	assert.Equal(t, p.good.SerialNumber, req.SerialNumber)
+	assert.Nil(t, req.Nonce)
+
+	_, err = ParseRequest([]byte("foo"))
+	assert.Error(t, err)
+	_, err = ParseRequest(append(b, 0))
+	assert.Error(t, err)
+}
+
+func TestResponder(t *testing.T) {
+	p := newTestPKI(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	index := strings.Join([]string{
+		"V\t300101000000Z\t\t03\tunknown\t/CN=good",
+		"R\t300101000000Z\t240102030405Z,keyCompromise\t04\tunknown\t/CN=revoked",
+		"",
+	}, "\n")
+	indexDB, err := ParseIndex(strings.NewReader(index))
+	require.NoError(t, err)
+
+	other := newTestPKI(t)
+	tests := []struct {
+		name       string
+		db         *Database
+		cert       *x509.Certificate
+		signer     *x509.Certificate
+		wantStatus int
+	}{
+		{"crl good", NewDatabaseFromCRL(p.crl), p.good, nil, ocsp.Good},
+		{"crl revoked", NewDatabaseFromCRL(p.crl), p.revoked, nil, ocsp.Revoked},
+		{"index good", indexDB, p.good, p.responder, ocsp.Good},
+		{"index revoked", indexDB, p.revoked, p.responder, ocsp.Revoked},
+		{"index unknown", indexDB, p.responder, p.responder, ocsp.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Responder{
+				Issuer:      p.issuer,
+				Certificate: tt.signer,
+				Signer:      p.issuerKey,
+				Database:    tt.db,
+				Validity:    time.Hour,
+				Now:         func() time.Time { return now },
+			}
+			if tt.signer != nil {
+				r.Signer = p.responderKey
+			}
+			nonce, err := NewNonce()
+			require.NoError(t, err)
+			req, err := CreateRequest(tt.cert, p.issuer, &RequestOptions{Hash: crypto.SHA256, Nonce: nonce})
+			require.NoError(t, err)
+
+			b, err := r.Respond(req)
+			require.NoError(t, err)
+			resp, err := ocsp.ParseResponseForCert(b, tt.cert, p.issuer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.Equal(t, crypto.SHA256, resp.IssuerHash)
+			assert.Equal(t, now, resp.ThisUpdate)
+			assert.Equal(t, now.Add(time.Hour), resp.NextUpdate)
+			assert.Equal(t, nonce, ResponseNonce(resp))
+			if tt.wantStatus == ocsp.Revoked {
+				assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), resp.RevokedAt)
+				assert.Equal(t, ocsp.KeyCompromise, resp.RevocationReason)
+			}
+			if tt.signer != nil {
+				assert.Equal(t, tt.signer, resp.Certificate)
+			} else {
+				assert.Nil(t, resp.Certificate)
+			}
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		r := &Responder{Issuer: p.issuer, Signer: p.issuerKey, Database: NewDatabaseFromCRL(p.crl)}
+		req, err := CreateRequest(other.good, other.issuer, nil)
+		require.NoError(t, err)
+		b, err := r.Respond(req)
+		require.NoError(t, err)
+		_, err = ocsp.ParseResponse(b, nil)
+		assert.Equal(t, ocsp.ResponseError{Status: ocsp.Unauthorized}, err)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		r := &Responder{Issuer: p.issuer, Signer: p.issuerKey, Database: NewDatabaseFromCRL(p.crl)}
+		b, err := r.Respond([]byte("foo"))
+		require.NoError(t, err)
+		_, err = ocsp.ParseResponse(b, nil)
+		assert.Equal(t, ocsp.ResponseError{Status: ocsp.Malformed}, err)
+	})
+}
+
+func TestResponder_keyTypes(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	for _, key := range []crypto.Signer{rsaKey, p384Key} {
+		t.Run(fmt.Sprintf("%T", key), func(t *testing.T) {
+			ca, err := minica.New(minica.WithGetSignerFunc(func() (crypto.Signer, error) {
+				return key, nil
+			}))
+			require.NoError(t, err)
+			issuer := ca.Intermediate
+			leaf := &x509.Certificate{SerialNumber: big.NewInt(2)}
+
+			r := &Responder{Issuer: issuer, Signer: key, Database: &Database{defaultStatus: ocsp.Good}}
+			req, err := CreateRequest(leaf, issuer, nil)
+			require.NoError(t, err)
+			b, err := r.Respond(req)
+			require.NoError(t, err)
+			resp, err := ocsp.ParseResponseForCert(b, leaf, issuer)
+			require.NoError(t, err)
+			assert.Equal(t, ocsp.Good, resp.Status)
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	p := newTestPKI(t)
+	srv := httptest.NewServer(&Responder{
+		Issuer:   p.issuer,
+		Signer:   p.issuerKey,
+		Database: NewDatabaseFromCRL(p.crl),
+	})
+	defer srv.Close()
+
+	for _, method := range []string{"", "GET", "post"} {
+		t.Run(method, func(t *testing.T) {
+			req, err := CreateRequest(p.revoked, p.issuer, nil)
+			require.NoError(t, err)
+			b, err := Query(srv.Client(), srv.URL, req, method)
+			require.NoError(t, err)
+			resp, err := ocsp.ParseResponseForCert(b, p.revoked, p.issuer)
+			require.NoError(t, err)
+			assert.Equal(t, ocsp.Revoked, resp.Status)
+			assert.True(t, resp.NextUpdate.IsZero())
+		})
+	}
+
+	req, err := CreateRequest(p.good, p.issuer, nil)
+	require.NoError(t, err)
+	_, err = Query(srv.Client(), srv.URL, req, "PUT")
+	assert.Error(t, err)
+}
+
+func TestParseIndex(t *testing.T) {
+	db, err := ParseIndex(strings.NewReader("V\t300101000000Z\t\t0A\tunknown\t/CN=a\n" +
+		"R\t300101000000Z\t20240102030405Z\t0b\tunknown\t/CN=b\n" +
+		"E\t200101000000Z\t\t0C\tunknown\t/CN=c\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Entry{Status: ocsp.Good}, db.Lookup(big.NewInt(10)))
+	assert.Equal(t, Entry{
+		Status:    ocsp.Revoked,
+		RevokedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, db.Lookup(big.NewInt(11)))
+	assert.Equal(t, Entry{Status: ocsp.Unknown}, db.Lookup(big.NewInt(12)))
+	assert.Equal(t, Entry{Status: ocsp.Unknown}, db.Lookup(big.NewInt(13)))
+
+	for _, s := range []string{"V\t300101000000Z\t\n", "V\t300101000000Z\t\tzz\tunknown\t/CN=a\n", "R\t300101000000Z\tfoo\t0A\tunknown\t/CN=a\n"} {
+		_, err := ParseIndex(strings.NewReader(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	der := []byte{0x30, 0x03, 0x0A, 0x01, 0x06}
+	for _, b := range [][]byte{
+		der,
+		pem.EncodeToMemory(&pem.Block{Type: "OCSP RESPONSE", Bytes: der}),
+		[]byte(base64.StdEncoding.EncodeToString(der) + "\n"),
+	} {
+		got, err := Decode(b)
+		require.NoError(t, err)
+		assert.Equal(t, der, got)
+	}
+	_, err := Decode([]byte("%%%"))
+	assert.Error(t, err)
+}
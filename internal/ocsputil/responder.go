@@ -0,0 +1,224 @@
+package ocsputil
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+// reasons maps the revocation reason names used in OpenSSL index files to
+// the reason codes defined in RFC 5280.
+var reasons = map[string]int{
+	"unspecified":          ocsp.Unspecified,
+	"keyCompromise":        ocsp.KeyCompromise,
+	"CACompromise":         ocsp.CACompromise,
+	"affiliationChanged":   ocsp.AffiliationChanged,
+	"superseded":           ocsp.Superseded,
+	"cessationOfOperation": ocsp.CessationOfOperation,
+	"certificateHold":      ocsp.CertificateHold,
+	"removeFromCRL":        ocsp.RemoveFromCRL,
+	"privilegeWithdrawn":   ocsp.PrivilegeWithdrawn,
+	"AACompromise":         ocsp.AACompromise,
+}
+
+// Entry is the status of a certificate.
+type Entry struct {
+	Status           int
+	RevokedAt        time.Time
+	RevocationReason int
+}
+
+// Database is a set of certificate statuses indexed by serial number.
+type Database struct {
+	entries       map[string]Entry
+	defaultStatus int
+}
+
+// NewDatabaseFromCRL returns a database where the certificates in the CRL are
+// revoked, and all the other certificates are good.
+func NewDatabaseFromCRL(crl *x509.RevocationList) *Database {
+	db := &Database{
+		entries:       make(map[string]Entry, len(crl.RevokedCertificateEntries)),
+		defaultStatus: ocsp.Good,
+	}
+	for _, e := range crl.RevokedCertificateEntries {
+		db.entries[e.SerialNumber.String()] = Entry{
+			Status:           ocsp.Revoked,
+			RevokedAt:        e.RevocationTime,
+			RevocationReason: e.ReasonCode,
+		}
+	}
+	return db
+}
+
+// ParseIndex parses a certificate database in the OpenSSL CA index format and
+// returns a database where the valid certificates are good, the revoked
+// certificates are revoked, and all the other certificates are unknown.
+//
+// Each line of the index has the status (V, R or E), the expiration time, the
+// revocation time with an optional reason, the serial number in hexadecimal,
+// the file name and the subject, separated by tabs.
+func ParseIndex(r io.Reader) (*Database, error) {
+	db := &Database{
+		entries:       make(map[string]Entry),
+		defaultStatus: ocsp.Unknown,
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			return nil, errors.Errorf("error parsing index: line %d: invalid number of fields", n)
+		}
+		serial, ok := new(big.Int).SetString(fields[3], 16)
+		if !ok {
+			return nil, errors.Errorf("error parsing index: line %d: invalid serial number %q", n, fields[3])
+		}
+
+		var entry Entry
+		switch fields[0] {
+		case "V":
+			entry.Status = ocsp.Good
+		case "R":
+			entry.Status = ocsp.Revoked
+			revokedAt, reason, _ := strings.Cut(fields[2], ",")
+			t, err := parseIndexTime(revokedAt)
+			if err != nil {
+				return nil, errors.Errorf("error parsing index: line %d: invalid revocation time %q", n, revokedAt)
+			}
+			entry.RevokedAt = t
+			if reason != "" {
+				if code, ok := reasons[reason]; ok {
+					entry.RevocationReason = code
+				}
+			}
+		default:
+			entry.Status = ocsp.Unknown
+		}
+		db.entries[serial.String()] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading index")
+	}
+	return db, nil
+}
+
+// parseIndexTime parses the UTCTime or GeneralizedTime used in an index.
+func parseIndexTime(s string) (time.Time, error) {
+	if len(s) == len("060102150405Z") {
+		return time.Parse("060102150405Z", s)
+	}
+	return time.Parse("20060102150405Z", s)
+}
+
+// Lookup returns the status of the certificate with the given serial number.
+func (db *Database) Lookup(serial *big.Int) Entry {
+	if e, ok := db.entries[serial.String()]; ok {
+		return e
+	}
+	return Entry{Status: db.defaultStatus}
+}
+
+// Responder creates OCSP responses for the certificates issued by an issuer.
+type Responder struct {
+	// Issuer is the certificate of the CA that issued the certificates.
+	Issuer *x509.Certificate
+	// Certificate is the certificate used to sign the responses. If it is not
+	// set, or it is the issuer, the responses are signed by the issuer.
+	Certificate *x509.Certificate
+	// Signer is the private key of the responder certificate.
+	Signer crypto.Signer
+	// Database contains the status of the certificates.
+	Database *Database
+	// Validity is the time between the this update and the next update of the
+	// responses. If it is zero, the responses do not have a next update.
+	Validity time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Respond returns the DER encoding of the response to an OCSP request.
+// Requests for certificates of other issuers get an unauthorized response, and
+// invalid requests a malformed request response.
+func (r *Responder) Respond(b []byte) ([]byte, error) {
+	req, err := ParseRequest(b)
+	if err != nil {
+		return ocsp.MalformedRequestErrorResponse, nil
+	}
+	nameHash, keyHash, err := issuerHashes(r.Issuer, req.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(nameHash, req.IssuerNameHash) || !bytes.Equal(keyHash, req.IssuerKeyHash) {
+		return ocsp.UnauthorizedErrorResponse, nil
+	}
+
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	thisUpdate := now().UTC().Truncate(time.Second)
+	entry := r.Database.Lookup(req.SerialNumber)
+	resp := &response{
+		Request:          req,
+		Status:           entry.Status,
+		ThisUpdate:       thisUpdate,
+		RevokedAt:        entry.RevokedAt,
+		RevocationReason: entry.RevocationReason,
+	}
+	if r.Validity > 0 {
+		resp.NextUpdate = thisUpdate.Add(r.Validity)
+	}
+	responderCert := r.Issuer
+	if r.Certificate != nil {
+		responderCert = r.Certificate
+	}
+	return createResponse(r.Issuer, responderCert, r.Signer, resp)
+}
+
+// ServeHTTP implements an OCSP responder over HTTP, accepting GET and POST
+// requests.
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var (
+		b   []byte
+		err error
+	)
+	switch req.Method {
+	case http.MethodGet:
+		var s string
+		if s, err = url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/")); err == nil {
+			b, err = base64.StdEncoding.DecodeString(s)
+		}
+	case http.MethodPost:
+		b, err = io.ReadAll(io.LimitReader(req.Body, 1<<16))
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		b = nil
+	}
+
+	resp, err := r.Respond(b)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.Write(resp)
+}
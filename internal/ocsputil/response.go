@@ -0,0 +1,249 @@
+package ocsputil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+// oidBasicResponse is the object identifier of id-pkix-ocsp-basic.
+var oidBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+var (
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []singleResponse
+	Extensions  []pkix.Extension `asn1:"optional,explicit,tag:1"`
+}
+
+type singleResponse struct {
+	CertID     certID
+	Good       asn1.Flag   `asn1:"tag:0,optional"`
+	Revoked    revokedInfo `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag   `asn1:"tag:2,optional"`
+	ThisUpdate time.Time   `asn1:"generalized"`
+	NextUpdate time.Time   `asn1:"generalized,explicit,tag:0,optional"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// response contains the fields of a response created by a Responder.
+type response struct {
+	Request          *Request
+	Status           int
+	ThisUpdate       time.Time
+	NextUpdate       time.Time
+	RevokedAt        time.Time
+	RevocationReason int
+}
+
+// createResponse creates and signs a basic OCSP response. Unlike
+// ocsp.CreateResponse, the nonce is added to the responseExtensions as
+// defined in RFC 8954.
+func createResponse(issuer, responderCert *x509.Certificate, signer crypto.Signer, r *response) ([]byte, error) {
+	hashOID, ok := hashOIDs[r.Request.HashAlgorithm]
+	if !ok {
+		return nil, errors.Errorf("unsupported hash algorithm %s", r.Request.HashAlgorithm)
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, r.Request.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	sigAlg, h, err := signatureAlgorithm(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	single := singleResponse{
+		CertID: certID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  hashOID,
+				Parameters: asn1.RawValue{Tag: 5 /* ASN.1 NULL */},
+			},
+			NameHash:      nameHash,
+			IssuerKeyHash: keyHash,
+			SerialNumber:  r.Request.SerialNumber,
+		},
+		ThisUpdate: r.ThisUpdate.UTC(),
+		NextUpdate: r.NextUpdate.UTC(),
+	}
+	switch r.Status {
+	case ocsp.Good:
+		single.Good = true
+	case ocsp.Revoked:
+		single.Revoked = revokedInfo{
+			RevocationTime: r.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(r.RevocationReason),
+		}
+	default:
+		single.Unknown = true
+	}
+
+	// The responder is identified by the SHA-1 hash of its public key.
+	_, responderKeyHash, err := issuerHashes(responderCert, crypto.SHA1)
+	if err != nil {
+		return nil, err
+	}
+	keyHashBytes, err := asn1.Marshal(responderKeyHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling responder ID")
+	}
+	tbs := responseData{
+		ResponderID: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        2,
+			IsCompound: true,
+			Bytes:      keyHashBytes,
+		},
+		ProducedAt: r.ThisUpdate.UTC(),
+		Responses:  []singleResponse{single},
+	}
+	if len(r.Request.Nonce) > 0 {
+		value, err := asn1.Marshal(r.Request.Nonce)
+		if err != nil {
+			return nil, errors.Wrap(err, "error marshaling nonce")
+		}
+		tbs.Extensions = []pkix.Extension{{
+			Id:    oidNonce,
+			Value: value,
+		}}
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling response data")
+	}
+
+	hash := h.New()
+	hash.Write(tbsBytes)
+	signature, err := signer.Sign(rand.Reader, hash.Sum(nil), h)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing OCSP response")
+	}
+
+	basic := basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbsBytes},
+		SignatureAlgorithm: sigAlg,
+		Signature: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	}
+	if !responderCert.Equal(issuer) {
+		basic.Certificates = []asn1.RawValue{{FullBytes: responderCert.Raw}}
+	}
+	basicBytes, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling OCSP response")
+	}
+	b, err := asn1.Marshal(ocspResponse{
+		Status: asn1.Enumerated(ocsp.Success),
+		Response: responseBytes{
+			ResponseType: oidBasicResponse,
+			Response:     basicBytes,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling OCSP response")
+	}
+	return b, nil
+}
+
+// signatureAlgorithm returns the signature algorithm and hash used to sign
+// responses with the given key.
+func signatureAlgorithm(pub crypto.PublicKey) (pkix.AlgorithmIdentifier, crypto.Hash, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{
+			Algorithm:  oidSHA256WithRSA,
+			Parameters: asn1.NullRawValue,
+		}, crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}, crypto.SHA256, nil
+		case elliptic.P384():
+			return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA384}, crypto.SHA384, nil
+		case elliptic.P521():
+			return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA512}, crypto.SHA512, nil
+		default:
+			return pkix.AlgorithmIdentifier{}, 0, errors.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+	default:
+		return pkix.AlgorithmIdentifier{}, 0, errors.Errorf("unsupported key type %T", pub)
+	}
+}
+
+// ResponseNonce returns the value of the nonce extension of a response, or
+// nil if the response does not have one. RFC 8954 places the nonce in the
+// responseExtensions, but some responders use the singleExtensions.
+func ResponseNonce(resp *ocsp.Response) []byte {
+	var tbs struct {
+		Version     int `asn1:"optional,default:0,explicit,tag:0"`
+		ResponderID asn1.RawValue
+		ProducedAt  time.Time `asn1:"generalized"`
+		Responses   []asn1.RawValue
+		Extensions  []pkix.Extension `asn1:"optional,explicit,tag:1"`
+	}
+	extensions := resp.Extensions
+	if _, err := asn1.Unmarshal(resp.TBSResponseData, &tbs); err == nil {
+		extensions = append(tbs.Extensions, extensions...)
+	}
+	for _, ext := range extensions {
+		if ext.Id.Equal(oidNonce) {
+			return unmarshalNonce(ext.Value)
+		}
+	}
+	return nil
+}
+
+// ParseResponse parses an OCSP response for the given certificate and verifies
+// its signature with the issuer. Unlike ocsp.ParseResponseForCert, it also
+// accepts responses signed by the issuer that embed the issuer certificate.
+func ParseResponse(b []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	resp, err := ocsp.ParseResponseForCert(b, cert, issuer)
+	if err != nil && issuer != nil {
+		// x/crypto/ocsp verifies an embedded certificate as a delegated
+		// responder certificate, so it rejects the issuer certificate.
+		if r, e := ocsp.ParseResponseForCert(b, cert, nil); e == nil && r.Certificate != nil && r.Certificate.Equal(issuer) {
+			return r, nil
+		}
+	}
+	return resp, err
+}
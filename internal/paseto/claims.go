@@ -0,0 +1,105 @@
+package paseto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLeeway is the default time leeway used to validate the exp and nbf
+// claims.
+const DefaultLeeway = time.Minute
+
+// Claims is the JSON payload of a token. The registered claims are strings,
+// and the time claims (exp, nbf and iat) use the RFC 3339 format.
+type Claims map[string]interface{}
+
+// ParseClaims decodes the JSON payload of a token.
+func ParseClaims(b []byte) (Claims, error) {
+	var c Claims
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrap(err, "error parsing claims: payload is not a JSON object")
+	}
+	if c == nil {
+		return nil, errors.New("error parsing claims: payload is not a JSON object")
+	}
+	return c, nil
+}
+
+// SetTime sets a time claim using the RFC 3339 format.
+func (c Claims) SetTime(key string, t time.Time) {
+	c[key] = t.UTC().Format(time.RFC3339)
+}
+
+// Time returns the value of a time claim.
+func (c Claims) Time(key string) (time.Time, bool, error) {
+	v, ok := c[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, true, errors.Errorf("validation failed: invalid %s claim: value is not a string", key)
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, true, errors.Errorf("validation failed: invalid %s claim: %q is not an RFC 3339 time", key, s)
+	}
+	return t, true, nil
+}
+
+// Expected are the values used to validate a claims set.
+type Expected struct {
+	Issuer   string
+	Subject  string
+	Audience string
+	Time     time.Time
+	Leeway   time.Duration
+}
+
+// Validate checks the claims against the expected values. The exp and nbf
+// claims are always validated if present using the expected time or the
+// current time.
+func (c Claims) Validate(e Expected) error {
+	for _, v := range []struct {
+		name, expected string
+	}{
+		{"iss", e.Issuer},
+		{"sub", e.Subject},
+		{"aud", e.Audience},
+	} {
+		if v.expected == "" {
+			continue
+		}
+		if s, _ := c[v.name].(string); s != v.expected {
+			return errors.Errorf("validation failed: invalid %s claim: found %q", v.name, s)
+		}
+	}
+
+	now, leeway := e.Time, e.Leeway
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if leeway == 0 {
+		leeway = DefaultLeeway
+	}
+	exp, ok, err := c.Time("exp")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(-leeway).After(exp) {
+		return errors.New("validation failed: token is expired (exp)")
+	}
+	nbf, ok, err := c.Time("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(leeway).Before(nbf) {
+		return errors.New("validation failed: token not valid yet (nbf)")
+	}
+	if _, _, err := c.Time("iat"); err != nil {
+		return err
+	}
+	return nil
+}
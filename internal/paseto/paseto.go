@@ -0,0 +1,262 @@
+// Package paseto implements version 4 of Platform-Agnostic Security Tokens
+// (PASETO): v4.local tokens, encrypted with XChaCha20 and authenticated with
+// keyed BLAKE2b, and v4.public tokens, signed with Ed25519.
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+// Token headers.
+const (
+	HeaderLocal  = "v4.local."
+	HeaderPublic = "v4.public."
+)
+
+// Token purposes.
+const (
+	PurposeLocal  = "local"
+	PurposePublic = "public"
+)
+
+// KeySize is the size of a v4.local key.
+const KeySize = 32
+
+const (
+	nonceSize = 32
+	macSize   = 32
+)
+
+// Token is a decoded but not verified PASETO token.
+type Token struct {
+	Version string
+	Purpose string
+	// Payload is the decoded payload of the token: the message and signature
+	// of a public token, or the nonce, ciphertext and tag of a local token.
+	Payload []byte
+	Footer  []byte
+}
+
+// Header returns the header of the token.
+func (t *Token) Header() string {
+	return t.Version + "." + t.Purpose + "."
+}
+
+// Message returns the message of a public token without verifying it.
+func (t *Token) Message() ([]byte, error) {
+	if t.Purpose != PurposePublic {
+		return nil, errors.New("the message of a local token is encrypted")
+	}
+	return t.Payload[:len(t.Payload)-ed25519.SignatureSize], nil
+}
+
+// Parse decodes a PASETO token without verifying it.
+func Parse(token string) (*Token, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, errors.New("error parsing token: invalid number of segments")
+	}
+	t := &Token{
+		Version: parts[0],
+		Purpose: parts[1],
+	}
+	if t.Version != "v4" {
+		return nil, errors.Errorf("error parsing token: unsupported version %q", t.Version)
+	}
+
+	var err error
+	if t.Payload, err = base64.RawURLEncoding.Strict().DecodeString(parts[2]); err != nil {
+		return nil, errors.Wrap(err, "error parsing token: invalid payload")
+	}
+	switch t.Purpose {
+	case PurposeLocal:
+		if len(t.Payload) < nonceSize+macSize {
+			return nil, errors.New("error parsing token: payload is too short")
+		}
+	case PurposePublic:
+		if len(t.Payload) < ed25519.SignatureSize {
+			return nil, errors.New("error parsing token: payload is too short")
+		}
+	default:
+		return nil, errors.Errorf("error parsing token: unsupported purpose %q", t.Purpose)
+	}
+
+	if len(parts) == 4 {
+		if t.Footer, err = base64.RawURLEncoding.Strict().DecodeString(parts[3]); err != nil {
+			return nil, errors.Wrap(err, "error parsing token: invalid footer")
+		}
+	}
+	return t, nil
+}
+
+func encode(header string, payload, footer []byte) string {
+	s := header + base64.RawURLEncoding.EncodeToString(payload)
+	if len(footer) > 0 {
+		s += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+	return s
+}
+
+// Sign creates a v4.public token signing the message, the footer and the
+// implicit assertion with the given key.
+func Sign(key ed25519.PrivateKey, message, footer, implicit []byte) (string, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return "", errors.New("invalid Ed25519 private key")
+	}
+	m2 := pae([]byte(HeaderPublic), message, footer, implicit)
+	sig := ed25519.Sign(key, m2)
+	payload := append(append([]byte{}, message...), sig...)
+	return encode(HeaderPublic, payload, footer), nil
+}
+
+// Verify verifies a v4.public token with the given key and implicit assertion
+// and returns the message and the footer.
+func Verify(token string, key ed25519.PublicKey, implicit []byte) (message, footer []byte, err error) {
+	t, err := Parse(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.Purpose != PurposePublic {
+		return nil, nil, errors.Errorf("cannot verify a %s token", t.Header())
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, nil, errors.New("invalid Ed25519 public key")
+	}
+
+	message, _ = t.Message()
+	sig := t.Payload[len(message):]
+	m2 := pae([]byte(HeaderPublic), message, t.Footer, implicit)
+	if !ed25519.Verify(key, m2, sig) {
+		return nil, nil, errors.New("validation failed: invalid signature")
+	}
+	return message, t.Footer, nil
+}
+
+// Encrypt creates a v4.local token encrypting the message with the given key,
+// and authenticating it along with the footer and the implicit assertion.
+func Encrypt(key, message, footer, implicit []byte) (string, error) {
+	n := make([]byte, nonceSize)
+	if _, err := rand.Read(n); err != nil {
+		return "", errors.Wrap(err, "error generating nonce")
+	}
+	return encrypt(key, n, message, footer, implicit)
+}
+
+func encrypt(key, n, message, footer, implicit []byte) (string, error) {
+	if len(key) != KeySize {
+		return "", errors.Errorf("invalid key size %d: v4.local keys must be %d bytes", len(key), KeySize)
+	}
+	ek, n2, ak, err := splitKey(key, n)
+	if err != nil {
+		return "", err
+	}
+
+	c := make([]byte, len(message))
+	xc, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating cipher")
+	}
+	xc.XORKeyStream(c, message)
+
+	preAuth := pae([]byte(HeaderLocal), n, c, footer, implicit)
+	tag, err := keyedHash(macSize, ak, preAuth)
+	if err != nil {
+		return "", err
+	}
+
+	payload := make([]byte, 0, len(n)+len(c)+len(tag))
+	payload = append(payload, n...)
+	payload = append(payload, c...)
+	payload = append(payload, tag...)
+	return encode(HeaderLocal, payload, footer), nil
+}
+
+// Decrypt decrypts a v4.local token with the given key and implicit assertion
+// and returns the message and the footer.
+func Decrypt(token string, key, implicit []byte) (message, footer []byte, err error) {
+	t, err := Parse(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.Purpose != PurposeLocal {
+		return nil, nil, errors.Errorf("cannot decrypt a %s token", t.Header())
+	}
+	if len(key) != KeySize {
+		return nil, nil, errors.Errorf("invalid key size %d: v4.local keys must be %d bytes", len(key), KeySize)
+	}
+
+	n := t.Payload[:nonceSize]
+	c := t.Payload[nonceSize : len(t.Payload)-macSize]
+	tag := t.Payload[len(t.Payload)-macSize:]
+
+	ek, n2, ak, err := splitKey(key, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	preAuth := pae([]byte(HeaderLocal), n, c, t.Footer, implicit)
+	expected, err := keyedHash(macSize, ak, preAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hmac.Equal(expected, tag) {
+		return nil, nil, errors.New("decryption failed: invalid authentication tag")
+	}
+
+	message = make([]byte, len(c))
+	xc, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error creating cipher")
+	}
+	xc.XORKeyStream(message, c)
+	return message, t.Footer, nil
+}
+
+// splitKey derives the encryption key, the XChaCha20 nonce and the
+// authentication key from the key and the nonce.
+func splitKey(key, n []byte) (ek, n2, ak []byte, err error) {
+	tmp, err := keyedHash(56, key, append([]byte("paseto-encryption-key"), n...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if ak, err = keyedHash(32, key, append([]byte("paseto-auth-key-for-aead"), n...)); err != nil {
+		return nil, nil, nil, err
+	}
+	return tmp[:32], tmp[32:], ak, nil
+}
+
+func keyedHash(size int, key, data []byte) ([]byte, error) {
+	h, err := blake2b.New(size, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating BLAKE2b hash")
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// pae implements the Pre-Authentication Encoding.
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(le64(len(pieces)))
+	for _, p := range pieces {
+		buf.Write(le64(len(p)))
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+func le64(n int) []byte {
+	b := make([]byte, 8)
+	//nolint:gosec // n is always a positive length
+	binary.LittleEndian.PutUint64(b, uint64(n)&(1<<63-1))
+	return b
+}
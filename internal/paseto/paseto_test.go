@@ -0,0 +1,166 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// Test vector 4-S-1 from the PASETO specification.
+func TestSignVector(t *testing.T) {
+	key := ed25519.PrivateKey(mustHex(t, "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"))
+	message := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+	want := "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+
+	token, err := Sign(key, message, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, want, token)
+
+	got, footer, err := Verify(token, key.Public().(ed25519.PublicKey), nil)
+	require.NoError(t, err)
+	assert.Equal(t, message, got)
+	assert.Nil(t, footer)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	message := []byte(`{"sub":"mariano"}`)
+	footer := []byte(`{"kid":"my-key"}`)
+	token, err := Sign(priv, message, footer, []byte("implicit"))
+	require.NoError(t, err)
+
+	got, gotFooter, err := Verify(token, pub, []byte("implicit"))
+	require.NoError(t, err)
+	assert.Equal(t, message, got)
+	assert.Equal(t, footer, gotFooter)
+
+	_, _, err = Verify(token, pub, nil)
+	assert.EqualError(t, err, "validation failed: invalid signature")
+
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, _, err = Verify(token, other, []byte("implicit"))
+	assert.Error(t, err)
+
+	tok, err := Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, "v4.public.", tok.Header())
+	assert.Equal(t, footer, tok.Footer)
+}
+
+// Test vectors 4-E-1 to 4-E-9 from the PASETO specification.
+func TestEncryptVectors(t *testing.T) {
+	key := mustHex(t, "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	zeroNonce := make([]byte, nonceSize)
+	nonce := mustHex(t, "df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8")
+	secret := `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`
+	hidden := `{"data":"this is a hidden message","exp":"2022-01-01T00:00:00+00:00"}`
+	kid := `{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`
+
+	tests := []struct {
+		name     string
+		nonce    []byte
+		message  string
+		footer   string
+		implicit string
+		want     string
+	}{
+		{"4-E-1", zeroNonce, secret, "", "", "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg"},
+		{"4-E-2", zeroNonce, hidden, "", "", "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvS2csCgglvpk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XIemu9chy3WVKvRBfg6t8wwYHK0ArLxxfZP73W_vfwt5A"},
+		{"4-E-3", nonce, secret, "", "", "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6-tyebyWG6Ov7kKvBdkrrAJ837lKP3iDag2hzUPHuMKA"},
+		{"4-E-4", nonce, hidden, "", "", "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4gt6TiLm55vIH8c_lGxxZpE3AWlH4WTR0v45nsWoU3gQ"},
+		{"4-E-5", nonce, secret, kid, "", "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4x-RMNXtQNbz7FvFZ_G-lFpk5RG3EOrwDL6CgDqcerSQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"},
+		{"4-E-6", nonce, hidden, kid, "", "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6pWSA5HX2wjb3P-xLQg5K5feUCX4P2fpVK3ZLWFbMSxQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"},
+		{"4-E-7", nonce, secret, kid, `{"test-vector":"4-E-7"}`, "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t40KCCWLA7GYL9KFHzKlwY9_RnIfRrMQpueydLEAZGGcA.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"},
+		{"4-E-8", nonce, hidden, kid, `{"test-vector":"4-E-8"}`, "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t5uvqQbMGlLLNYBc7A6_x7oqnpUK5WLvj24eE4DVPDZjw.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"},
+		{"4-E-9", nonce, hidden, "arbitrary-string-that-isn't-json", `{"test-vector":"4-E-9"}`, "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6tybdlmnMwcDMw0YxA_gFSE_IUWl78aMtOepFYSWYfQA.YXJiaXRyYXJ5LXN0cmluZy10aGF0LWlzbid0LWpzb24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := encrypt(key, tt.nonce, []byte(tt.message), []byte(tt.footer), []byte(tt.implicit))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, token)
+
+			message, footer, err := Decrypt(tt.want, key, []byte(tt.implicit))
+			require.NoError(t, err)
+			assert.Equal(t, tt.message, string(message))
+			assert.Equal(t, tt.footer, string(footer))
+		})
+	}
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	key := make([]byte, KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	message := []byte(`{"data":"this is a secret message"}`)
+	footer := []byte(`{"kid":"local-key"}`)
+	token, err := Encrypt(key, message, footer, []byte("implicit"))
+	require.NoError(t, err)
+	assert.Contains(t, token, HeaderLocal)
+
+	got, gotFooter, err := Decrypt(token, key, []byte("implicit"))
+	require.NoError(t, err)
+	assert.Equal(t, message, got)
+	assert.Equal(t, footer, gotFooter)
+
+	_, _, err = Decrypt(token, key, []byte("other"))
+	assert.EqualError(t, err, "decryption failed: invalid authentication tag")
+
+	// The same message with the same key produces different tokens.
+	token2, err := Encrypt(key, message, footer, []byte("implicit"))
+	require.NoError(t, err)
+	assert.NotEqual(t, token, token2)
+
+	_, _, err = Verify(token, make([]byte, ed25519.PublicKeySize), nil)
+	assert.EqualError(t, err, "cannot verify a v4.local. token")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"segments", "v4.public", "error parsing token: invalid number of segments"},
+		{"version", "v3.public.AAAA", `error parsing token: unsupported version "v3"`},
+		{"purpose", "v4.secret.AAAA", `error parsing token: unsupported purpose "secret"`},
+		{"short", "v4.public.AAAA", "error parsing token: payload is too short"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.token)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestClaimsValidate(t *testing.T) {
+	now := time.Now()
+	c := Claims{"iss": "issuer", "aud": "audience"}
+	c.SetTime("exp", now.Add(time.Hour))
+	c.SetTime("nbf", now)
+
+	assert.NoError(t, c.Validate(Expected{Issuer: "issuer", Audience: "audience"}))
+	assert.Error(t, c.Validate(Expected{Issuer: "other"}))
+	assert.EqualError(t, c.Validate(Expected{Time: now.Add(2 * time.Hour)}), "validation failed: token is expired (exp)")
+	assert.EqualError(t, c.Validate(Expected{Time: now.Add(-time.Hour)}), "validation failed: token not valid yet (nbf)")
+
+	c["exp"] = 1234
+	assert.Error(t, c.Validate(Expected{}))
+}
@@ -0,0 +1,421 @@
+package pkcs12util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec // 3DES is required to decrypt legacy files
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec // SHA-1 is required to decrypt legacy files
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	oidPBEWithSHAAnd128BitRC4        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 1}
+	oidPBEWithSHAAnd40BitRC4         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 2}
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd2KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 4}
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBKDF2                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidPBES2                         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBMAC1                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 14}
+	oidHmacWithSHA1                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHmacWithSHA224                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHmacWithSHA256                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHmacWithSHA384                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHmacWithSHA512                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidDESEDE3CBC                    = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidAES128CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidSHA1                          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA224                        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 4}
+	oidSHA256                        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384                        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512                        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// Algorithm describes an encryption or MAC algorithm used in a PKCS #12 file.
+type Algorithm struct {
+	// Name is the name of the algorithm, e.g. "PBES2" or
+	// "pbeWithSHAAnd3-KeyTripleDES-CBC".
+	Name string `json:"name"`
+	// Cipher is the encryption scheme used by PBES2.
+	Cipher string `json:"cipher,omitempty"`
+	// PRF is the pseudorandom function used by PBKDF2 in PBES2 and PBMAC1, or
+	// the HMAC used by PBMAC1.
+	PRF string `json:"prf,omitempty"`
+	// Iterations is the number of iterations used to derive the key.
+	Iterations int `json:"iterations,omitempty"`
+	// Legacy is true if the algorithm is considered weak, like RC2, RC4, 3DES
+	// or the SHA-1 MAC. OpenSSL 3 does not support these algorithms without
+	// the legacy provider.
+	Legacy bool `json:"legacy"`
+}
+
+func (a *Algorithm) String() string {
+	s := a.Name
+	switch {
+	case a.Cipher != "" && a.PRF != "":
+		s += " (" + a.Cipher + ", PBKDF2-" + a.PRF + ")"
+	case a.PRF != "":
+		s += " (PBKDF2-" + a.PRF + ")"
+	}
+	return s
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbkdf2Params struct {
+	Salt       asn1.RawValue
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type pbes2Params struct {
+	KDF              pkix.AlgorithmIdentifier
+	EncryptionScheme pkix.AlgorithmIdentifier
+}
+
+type pbmac1Params struct {
+	KDF    pkix.AlgorithmIdentifier
+	MacAlg pkix.AlgorithmIdentifier
+}
+
+// password contains the two encodings of a password used in PKCS #12 files.
+// The PKCS #12 key derivation function uses a null terminated BMPString, and
+// PBKDF2 uses UTF-8.
+type password struct {
+	bmp  []byte
+	utf8 []byte
+}
+
+func newPassword(s string) password {
+	var bmp []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		bmp = append(bmp, byte(r>>8), byte(r))
+	}
+	return password{
+		bmp:  append(bmp, 0, 0),
+		utf8: []byte(s),
+	}
+}
+
+// pkcs12KDF derives a key using the algorithm defined in RFC 7292, appendix
+// B.2. The id is 1 for encryption keys, 2 for IVs and 3 for MAC keys.
+func pkcs12KDF(h func() hash.Hash, v int, salt, pass []byte, id byte, iterations, size int) []byte {
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := v * ((len(b) + v - 1) / v)
+		out := make([]byte, n)
+		for i := 0; i < n; i += len(b) {
+			copy(out[i:], b)
+		}
+		return out
+	}
+
+	d := bytes.Repeat([]byte{id}, v)
+	in := append(fill(salt), fill(pass)...)
+	hh := h()
+	var out []byte
+	for len(out) < size {
+		hh.Reset()
+		hh.Write(d)
+		hh.Write(in)
+		a := hh.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(a[:0])
+		}
+		out = append(out, a...)
+		if len(out) >= size {
+			break
+		}
+		// I_j = (I_j + B + 1) mod 2^(v*8)
+		b := fill(a)[:v]
+		for j := 0; j < len(in); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				carry += int(in[j+k]) + int(b[k])
+				in[j+k] = byte(carry)
+				carry >>= 8
+			}
+		}
+	}
+	return out[:size]
+}
+
+func hmacHash(oid asn1.ObjectIdentifier) (func() hash.Hash, string, error) {
+	switch {
+	case oid == nil, oid.Equal(oidHmacWithSHA1):
+		return sha1.New, "HMAC-SHA1", nil
+	case oid.Equal(oidHmacWithSHA224):
+		return sha256.New224, "HMAC-SHA224", nil
+	case oid.Equal(oidHmacWithSHA256):
+		return sha256.New, "HMAC-SHA256", nil
+	case oid.Equal(oidHmacWithSHA384):
+		return sha512.New384, "HMAC-SHA384", nil
+	case oid.Equal(oidHmacWithSHA512):
+		return sha512.New, "HMAC-SHA512", nil
+	default:
+		return nil, "", errors.Errorf("unsupported HMAC algorithm %s", oid)
+	}
+}
+
+// pbkdf2Key derives a key using the PBKDF2 parameters. If the parameters do
+// not define the key length, size is used.
+func pbkdf2Key(params asn1.RawValue, pass []byte, size int) ([]byte, *Algorithm, error) {
+	var p pbkdf2Params
+	if err := unmarshal(params.FullBytes, &p); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PBKDF2 parameters")
+	}
+	if p.Salt.Tag != asn1.TagOctetString {
+		return nil, nil, errors.New("unsupported PBKDF2 salt, only octet strings are supported")
+	}
+	prf, name, err := hmacHash(p.PRF.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.KeyLength > 0 {
+		size = p.KeyLength
+	}
+	key, err := pbkdf2.Key(prf, string(pass), p.Salt.Bytes, p.Iterations, size)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error deriving key")
+	}
+	return key, &Algorithm{
+		Name:       "PBES2",
+		PRF:        name,
+		Iterations: p.Iterations,
+		Legacy:     name == "HMAC-SHA1",
+	}, nil
+}
+
+// describeEncryption returns the description of an encryption algorithm.
+func describeEncryption(alg pkix.AlgorithmIdentifier) *Algorithm {
+	a, _, _ := newDecrypter(alg, nil, false)
+	if a == nil {
+		return &Algorithm{Name: alg.Algorithm.String()}
+	}
+	return a
+}
+
+// newDecrypter returns the description of the encryption algorithm and, if
+// derive is true, a cipher to decrypt with the given password.
+func newDecrypter(alg pkix.AlgorithmIdentifier, pass *password, derive bool) (*Algorithm, cipher.BlockMode, error) {
+	oid := alg.Algorithm
+	if oid.Equal(oidPBES2) {
+		return newPBES2Decrypter(alg, pass, derive)
+	}
+
+	var (
+		name    string
+		keySize int
+		newFn   func(key []byte) (cipher.Block, error)
+	)
+	switch {
+	case oid.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		name, keySize, newFn = "pbeWithSHAAnd3-KeyTripleDES-CBC", 24, des.NewTripleDESCipher
+	case oid.Equal(oidPBEWithSHAAnd2KeyTripleDESCBC):
+		name, keySize = "pbeWithSHAAnd2-KeyTripleDES-CBC", 16
+		newFn = func(key []byte) (cipher.Block, error) {
+			return des.NewTripleDESCipher(append(key, key[:8]...))
+		}
+	case oid.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+		name, keySize = "pbeWithSHAAnd128BitRC2-CBC", 16
+		newFn = func(key []byte) (cipher.Block, error) { return newRC2Cipher(key, 128) }
+	case oid.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		name, keySize = "pbeWithSHAAnd40BitRC2-CBC", 5
+		newFn = func(key []byte) (cipher.Block, error) { return newRC2Cipher(key, 40) }
+	case oid.Equal(oidPBEWithSHAAnd128BitRC4):
+		name = "pbeWithSHAAnd128BitRC4"
+	case oid.Equal(oidPBEWithSHAAnd40BitRC4):
+		name = "pbeWithSHAAnd40BitRC4"
+	default:
+		return nil, nil, errors.Errorf("unsupported encryption algorithm %s", oid)
+	}
+
+	var params pbeParams
+	if err := unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, errors.Wrapf(err, "error parsing %s parameters", name)
+	}
+	a := &Algorithm{Name: name, Iterations: params.Iterations, Legacy: true}
+	if !derive {
+		return a, nil, nil
+	}
+	if newFn == nil {
+		return a, nil, errors.Errorf("unsupported encryption algorithm %s", name)
+	}
+	key := pkcs12KDF(sha1.New, 64, params.Salt, pass.bmp, 1, params.Iterations, keySize)
+	iv := pkcs12KDF(sha1.New, 64, params.Salt, pass.bmp, 2, params.Iterations, 8)
+	block, err := newFn(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, cipher.NewCBCDecrypter(block, iv), nil
+}
+
+func newPBES2Decrypter(alg pkix.AlgorithmIdentifier, pass *password, derive bool) (*Algorithm, cipher.BlockMode, error) {
+	var params pbes2Params
+	if err := unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PBES2 parameters")
+	}
+	if !params.KDF.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, errors.Errorf("unsupported PBES2 key derivation function %s", params.KDF.Algorithm)
+	}
+
+	var (
+		name    string
+		keySize int
+		legacy  bool
+		newFn   func(key []byte) (cipher.Block, error)
+	)
+	switch enc := params.EncryptionScheme.Algorithm; {
+	case enc.Equal(oidAES128CBC):
+		name, keySize, newFn = "AES-128-CBC", 16, aes.NewCipher
+	case enc.Equal(oidAES192CBC):
+		name, keySize, newFn = "AES-192-CBC", 24, aes.NewCipher
+	case enc.Equal(oidAES256CBC):
+		name, keySize, newFn = "AES-256-CBC", 32, aes.NewCipher
+	case enc.Equal(oidDESEDE3CBC):
+		name, keySize, newFn, legacy = "DES-EDE3-CBC", 24, des.NewTripleDESCipher, true
+	default:
+		return nil, nil, errors.Errorf("unsupported PBES2 encryption scheme %s", enc)
+	}
+
+	var utf8 []byte
+	if pass != nil {
+		utf8 = pass.utf8
+	}
+	key, a, err := pbkdf2Key(params.KDF.Parameters, utf8, keySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	a.Cipher = name
+	a.Legacy = a.Legacy || legacy
+	if !derive {
+		return a, nil, nil
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, errors.Wrap(err, "error parsing PBES2 IV")
+	}
+	block, err := newFn(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, errors.New("error parsing PBES2 IV: invalid length")
+	}
+	return a, cipher.NewCBCDecrypter(block, iv), nil
+}
+
+// decrypt decrypts the given data and removes the PKCS #7 padding.
+func decrypt(alg pkix.AlgorithmIdentifier, pass *password, data []byte) ([]byte, *Algorithm, error) {
+	a, cbc, err := newDecrypter(alg, pass, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs := cbc.BlockSize()
+	if len(data) == 0 || len(data)%bs != 0 {
+		return nil, nil, errors.New("error decrypting data: invalid length")
+	}
+	out := make([]byte, len(data))
+	cbc.CryptBlocks(out, data)
+	n := int(out[len(out)-1])
+	if n == 0 || n > bs || n > len(out) {
+		return nil, nil, ErrIncorrectPassword
+	}
+	for _, b := range out[len(out)-n:] {
+		if int(b) != n {
+			return nil, nil, ErrIncorrectPassword
+		}
+	}
+	return out[:len(out)-n], a, nil
+}
+
+// verifyMAC verifies the MAC of the data and returns the algorithm used.
+func verifyMAC(md *macData, pass *password, data []byte) (*MAC, error) {
+	var (
+		h   func() hash.Hash
+		key []byte
+	)
+	m := &MAC{
+		SaltLength: len(md.MacSalt),
+	}
+	switch oid := md.Mac.Algorithm.Algorithm; {
+	case oid.Equal(oidPBMAC1):
+		var params pbmac1Params
+		if err := unmarshal(md.Mac.Algorithm.Parameters.FullBytes, &params); err != nil {
+			return nil, errors.Wrap(err, "error parsing PBMAC1 parameters")
+		}
+		if !params.KDF.Algorithm.Equal(oidPBKDF2) {
+			return nil, errors.Errorf("unsupported PBMAC1 key derivation function %s", params.KDF.Algorithm)
+		}
+		var name string
+		var err error
+		if h, name, err = hmacHash(params.MacAlg.Algorithm); err != nil {
+			return nil, err
+		}
+		key, m.Algorithm, err = pbkdf2Key(params.KDF.Parameters, pass.utf8, h().Size())
+		if err != nil {
+			return nil, err
+		}
+		m.Algorithm.Name = "PBMAC1"
+		m.Algorithm.Cipher = ""
+		m.Algorithm.PRF = name
+		m.Algorithm.Legacy = name == "HMAC-SHA1"
+	default:
+		var name string
+		var v int
+		switch {
+		case oid.Equal(oidSHA1):
+			h, name, v = sha1.New, "SHA-1", 64
+		case oid.Equal(oidSHA224):
+			h, name, v = sha256.New224, "SHA-224", 64
+		case oid.Equal(oidSHA256):
+			h, name, v = sha256.New, "SHA-256", 64
+		case oid.Equal(oidSHA384):
+			h, name, v = sha512.New384, "SHA-384", 128
+		case oid.Equal(oidSHA512):
+			h, name, v = sha512.New, "SHA-512", 128
+		default:
+			return nil, errors.Errorf("unsupported MAC algorithm %s", oid)
+		}
+		iterations := md.Iterations
+		if iterations == 0 {
+			iterations = 1
+		}
+		m.Algorithm = &Algorithm{
+			Name:       name,
+			Iterations: iterations,
+			Legacy:     name == "SHA-1",
+		}
+		key = pkcs12KDF(h, v, md.MacSalt, pass.bmp, 3, iterations, h().Size())
+	}
+
+	mac := hmac.New(h, key)
+	mac.Write(data)
+	if subtle.ConstantTimeCompare(mac.Sum(nil), md.Mac.Digest) != 1 {
+		return m, ErrIncorrectPassword
+	}
+	return m, nil
+}
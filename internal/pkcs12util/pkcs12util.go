@@ -0,0 +1,464 @@
+// Package pkcs12util implements the parsing of PKCS #12 (PFX) files. Unlike
+// software.sslmate.com/src/go-pkcs12, it returns all the bags in the file with
+// their attributes, and the algorithms used to protect them, so it can be used
+// to inspect and extract files created by other tools.
+package pkcs12util
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// ErrIncorrectPassword is returned when the password is not valid.
+var ErrIncorrectPassword = errors.New("pkcs12: decryption password incorrect")
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCRLBag                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 4}
+	oidSecretBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 5}
+	oidSafeContentsBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 6}
+	oidX509CertificateType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidMicrosoftCSPName      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 17, 1}
+	oidMicrosoftLocalMachine = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 17, 2}
+	oidJavaTrustedKeyUsage   = asn1.ObjectIdentifier{2, 16, 840, 1, 113894, 746875, 1, 1}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// File is a parsed PKCS #12 file.
+type File struct {
+	Version int `json:"version"`
+	// MAC is the integrity protection of the file, it is nil if the file
+	// does not have a MAC.
+	MAC   *MAC    `json:"mac"`
+	Safes []*Safe `json:"safes"`
+}
+
+// MAC describes the integrity protection of a PKCS #12 file.
+type MAC struct {
+	Algorithm  *Algorithm `json:"algorithm"`
+	SaltLength int        `json:"saltLength"`
+}
+
+// Safe is one of the safe contents in the authenticated safe of a PKCS #12
+// file.
+type Safe struct {
+	// Encryption is the algorithm used to encrypt the safe contents, it is
+	// nil if the contents are not encrypted.
+	Encryption *Algorithm `json:"encryption"`
+	Bags       []*Bag     `json:"bags"`
+}
+
+// Bag is a safe bag in a PKCS #12 file.
+type Bag struct {
+	// Type is the type of bag: keyBag, pkcs8ShroudedKeyBag, certBag, crlBag,
+	// secretBag or safeContentsBag.
+	Type         string `json:"type"`
+	FriendlyName string `json:"friendlyName,omitempty"`
+	LocalKeyID   []byte `json:"localKeyID,omitempty"`
+	// Attributes are the names or the OIDs of other attributes in the bag.
+	Attributes []string `json:"attributes,omitempty"`
+	// Encryption is the algorithm used to encrypt a pkcs8ShroudedKeyBag.
+	Encryption  *Algorithm        `json:"encryption,omitempty"`
+	Certificate *x509.Certificate `json:"-"`
+	PrivateKey  crypto.PrivateKey `json:"-"`
+	Bags        []*Bag            `json:"bags,omitempty"`
+}
+
+// Parse parses a DER encoded PKCS #12 file. The password is used to verify
+// the MAC and to decrypt the safe contents and the private keys.
+func Parse(data []byte, pass string) (*File, error) {
+	var pfx pfxPdu
+	if err := unmarshal(data, &pfx); err != nil {
+		return nil, errors.Wrap(err, "error parsing PKCS #12 file")
+	}
+	if pfx.Version != 3 {
+		return nil, errors.Errorf("error parsing PKCS #12 file: unsupported version %d", pfx.Version)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, errors.New("error parsing PKCS #12 file: only password integrity mode is supported")
+	}
+	var authSafe []byte
+	if err := unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, errors.Wrap(err, "error parsing PKCS #12 file")
+	}
+
+	f := &File{Version: pfx.Version}
+	pw := newPassword(pass)
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		m, err := verifyMAC(&pfx.MacData, &pw, authSafe)
+		if errors.Is(err, ErrIncorrectPassword) && pass == "" {
+			// Some implementations use an empty byte array for the empty
+			// password.
+			pw.bmp = nil
+			m, err = verifyMAC(&pfx.MacData, &pw, authSafe)
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.MAC = m
+	}
+
+	var safes []contentInfo
+	if err := unmarshal(authSafe, &safes); err != nil {
+		return nil, errors.Wrap(err, "error parsing PKCS #12 authenticated safe")
+	}
+	for _, ci := range safes {
+		safe := new(Safe)
+		var contents []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if err := unmarshal(ci.Content.Bytes, &contents); err != nil {
+				return nil, errors.Wrap(err, "error parsing PKCS #12 safe contents")
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var ed encryptedData
+			if err := unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, errors.Wrap(err, "error parsing PKCS #12 encrypted data")
+			}
+			var err error
+			eci := ed.EncryptedContentInfo
+			if contents, safe.Encryption, err = decrypt(eci.ContentEncryptionAlgorithm, &pw, eci.EncryptedContent); err != nil {
+				return nil, errors.Wrap(err, "error decrypting PKCS #12 safe contents")
+			}
+		default:
+			return nil, errors.Errorf("error parsing PKCS #12 file: unsupported content type %s", ci.ContentType)
+		}
+		bags, err := parseSafeContents(contents, &pw)
+		if err != nil {
+			return nil, err
+		}
+		safe.Bags = bags
+		f.Safes = append(f.Safes, safe)
+	}
+	return f, nil
+}
+
+func parseSafeContents(data []byte, pw *password) ([]*Bag, error) {
+	var safeBags []safeBag
+	if err := unmarshal(data, &safeBags); err != nil {
+		return nil, errors.Wrap(err, "error parsing PKCS #12 safe contents")
+	}
+	bags := make([]*Bag, 0, len(safeBags))
+	for i := range safeBags {
+		bag, err := parseBag(&safeBags[i], pw)
+		if err != nil {
+			return nil, err
+		}
+		bags = append(bags, bag)
+	}
+	return bags, nil
+}
+
+func parseBag(sb *safeBag, pw *password) (*Bag, error) {
+	bag := new(Bag)
+	for _, attr := range sb.Attributes {
+		switch {
+		case attr.ID.Equal(oidFriendlyName):
+			var v asn1.RawValue
+			if err := unmarshal(attr.Value.Bytes, &v); err != nil {
+				return nil, errors.Wrap(err, "error parsing friendlyName attribute")
+			}
+			s, err := decodeBMPString(v.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "error parsing friendlyName attribute")
+			}
+			bag.FriendlyName = s
+		case attr.ID.Equal(oidLocalKeyID):
+			if err := unmarshal(attr.Value.Bytes, &bag.LocalKeyID); err != nil {
+				return nil, errors.Wrap(err, "error parsing localKeyID attribute")
+			}
+		case attr.ID.Equal(oidMicrosoftCSPName):
+			bag.Attributes = append(bag.Attributes, "Microsoft CSP Name")
+		case attr.ID.Equal(oidMicrosoftLocalMachine):
+			bag.Attributes = append(bag.Attributes, "Microsoft Local Machine Keyset")
+		case attr.ID.Equal(oidJavaTrustedKeyUsage):
+			bag.Attributes = append(bag.Attributes, "Java Trusted Key Usage")
+		default:
+			bag.Attributes = append(bag.Attributes, attr.ID.String())
+		}
+	}
+
+	switch {
+	case sb.ID.Equal(oidKeyBag):
+		bag.Type = "keyBag"
+		key, err := x509.ParsePKCS8PrivateKey(sb.Value.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS #12 key bag")
+		}
+		bag.PrivateKey = key
+	case sb.ID.Equal(oidPKCS8ShroudedKeyBag):
+		bag.Type = "pkcs8ShroudedKeyBag"
+		var epki encryptedPrivateKeyInfo
+		if err := unmarshal(sb.Value.Bytes, &epki); err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS #12 shrouded key bag")
+		}
+		der, alg, err := decrypt(epki.AlgorithmIdentifier, pw, epki.EncryptedData)
+		if err != nil {
+			return nil, errors.Wrap(err, "error decrypting PKCS #12 shrouded key bag")
+		}
+		bag.Encryption = alg
+		if bag.PrivateKey, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS #12 shrouded key bag")
+		}
+	case sb.ID.Equal(oidCertBag):
+		bag.Type = "certBag"
+		var cb certBag
+		if err := unmarshal(sb.Value.Bytes, &cb); err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS #12 certificate bag")
+		}
+		if !cb.ID.Equal(oidX509CertificateType) {
+			return nil, errors.Errorf("error parsing PKCS #12 certificate bag: unsupported certificate type %s", cb.ID)
+		}
+		crt, err := x509.ParseCertificate(cb.Data)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing PKCS #12 certificate bag")
+		}
+		bag.Certificate = crt
+	case sb.ID.Equal(oidCRLBag):
+		bag.Type = "crlBag"
+	case sb.ID.Equal(oidSecretBag):
+		bag.Type = "secretBag"
+	case sb.ID.Equal(oidSafeContentsBag):
+		bag.Type = "safeContentsBag"
+		bags, err := parseSafeContents(sb.Value.Bytes, pw)
+		if err != nil {
+			return nil, err
+		}
+		bag.Bags = bags
+	default:
+		bag.Type = sb.ID.String()
+	}
+	return bag, nil
+}
+
+// Bags returns all the bags in the file, including the ones in nested safe
+// contents bags.
+func (f *File) Bags() []*Bag {
+	var bags []*Bag
+	var walk func([]*Bag)
+	walk = func(bs []*Bag) {
+		for _, b := range bs {
+			bags = append(bags, b)
+			walk(b.Bags)
+		}
+	}
+	for _, s := range f.Safes {
+		walk(s.Bags)
+	}
+	return bags
+}
+
+func unmarshal(in []byte, out interface{}) error {
+	rest, err := asn1.Unmarshal(in, out)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.New("trailing data found")
+	}
+	return nil
+}
+
+func decodeBMPString(b []byte) (string, error) {
+	if len(b)%2 != 0 {
+		return "", errors.New("odd-length BMP string")
+	}
+	// Strip the terminator if present.
+	if n := len(b); n >= 2 && b[n-1] == 0 && b[n-2] == 0 {
+		b = b[:n-2]
+	}
+	s := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		s = append(s, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(s)), nil
+}
+
+// Identity is a private key in a PKCS #12 file with its certificate and the
+// certificate chain.
+type Identity struct {
+	FriendlyName string
+	PrivateKey   crypto.PrivateKey
+	// Certificate is the certificate with the same local key ID or the same
+	// public key as the private key. It can be nil.
+	Certificate *x509.Certificate
+	// Chain are the issuers of the certificate in the file, starting with
+	// the issuer of the certificate.
+	Chain []*x509.Certificate
+}
+
+// Certificates returns all the certificates in the file.
+func (f *File) Certificates() []*x509.Certificate {
+	var certs []*x509.Certificate
+	for _, b := range f.Bags() {
+		if b.Certificate != nil {
+			certs = append(certs, b.Certificate)
+		}
+	}
+	return certs
+}
+
+// Identities returns the private keys in the file with their certificates.
+func (f *File) Identities() []*Identity {
+	var keys, certs []*Bag
+	for _, b := range f.Bags() {
+		switch {
+		case b.PrivateKey != nil:
+			keys = append(keys, b)
+		case b.Certificate != nil:
+			certs = append(certs, b)
+		}
+	}
+
+	var ids []*Identity
+	for _, k := range keys {
+		id := &Identity{
+			FriendlyName: k.FriendlyName,
+			PrivateKey:   k.PrivateKey,
+		}
+		// Match by local key ID first, and then by public key.
+		for _, c := range certs {
+			if len(k.LocalKeyID) > 0 && bytes.Equal(k.LocalKeyID, c.LocalKeyID) {
+				id.Certificate = c.Certificate
+				break
+			}
+		}
+		if id.Certificate == nil {
+			if signer, ok := k.PrivateKey.(crypto.Signer); ok {
+				pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+				for _, c := range certs {
+					if ok && pub.Equal(c.Certificate.PublicKey) {
+						id.Certificate = c.Certificate
+						break
+					}
+				}
+			}
+		}
+		if id.Certificate != nil {
+			if id.FriendlyName == "" {
+				for _, c := range certs {
+					if c.Certificate == id.Certificate {
+						id.FriendlyName = c.FriendlyName
+					}
+				}
+			}
+			id.Chain = buildChain(id.Certificate, certs)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// NamedCertificates returns an identity without a private key for each
+// certificate with the given friendly name, with its certificate chain. It is
+// used to select the certificates in files without private keys, like Java
+// trust stores.
+func (f *File) NamedCertificates(name string) []*Identity {
+	var certs []*Bag
+	for _, b := range f.Bags() {
+		if b.Certificate != nil {
+			certs = append(certs, b)
+		}
+	}
+
+	var ids []*Identity
+	for _, c := range certs {
+		if c.FriendlyName == name {
+			ids = append(ids, &Identity{
+				FriendlyName: c.FriendlyName,
+				Certificate:  c.Certificate,
+				Chain:        buildChain(c.Certificate, certs),
+			})
+		}
+	}
+	return ids
+}
+
+// buildChain returns the issuers of the given certificate in the bags.
+func buildChain(crt *x509.Certificate, bags []*Bag) []*x509.Certificate {
+	var chain []*x509.Certificate
+	used := map[*x509.Certificate]bool{crt: true}
+	for cur := crt; !bytes.Equal(cur.RawIssuer, cur.RawSubject); {
+		var next *x509.Certificate
+		for _, b := range bags {
+			c := b.Certificate
+			if !used[c] && bytes.Equal(c.RawSubject, cur.RawIssuer) && cur.CheckSignatureFrom(c) == nil {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		used[next] = true
+		chain = append(chain, next)
+		cur = next
+	}
+	return chain
+}
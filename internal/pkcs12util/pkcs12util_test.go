@@ -0,0 +1,180 @@
+package pkcs12util
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // used to test the PKCS #12 KDF
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type testChain struct {
+	key   crypto.Signer
+	leaf  *x509.Certificate
+	chain []*x509.Certificate
+}
+
+func newTestChain(t *testing.T, key crypto.Signer) *testChain {
+	t.Helper()
+	ca, err := minica.New()
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "leaf"},
+		DNSNames:  []string{"leaf.example.com"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+	return &testChain{key: key, leaf: leaf, chain: []*x509.Certificate{ca.Intermediate, ca.Root}}
+}
+
+func TestParse(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	type want struct {
+		mac        string
+		macLegacy  bool
+		certEnc    string
+		keyEnc     string
+		encLegacy  bool
+		iterations int
+	}
+	tests := []struct {
+		name    string
+		encoder *pkcs12.Encoder
+		key     crypto.Signer
+		want    want
+	}{
+		{"LegacyRC2", pkcs12.LegacyRC2, rsaKey, want{"SHA-1", true, "pbeWithSHAAnd40BitRC2-CBC", "pbeWithSHAAnd3-KeyTripleDES-CBC", true, 2048}},
+		{"LegacyDES", pkcs12.LegacyDES, ecKey, want{"SHA-1", true, "pbeWithSHAAnd3-KeyTripleDES-CBC", "pbeWithSHAAnd3-KeyTripleDES-CBC", true, 2048}},
+		{"Modern2023", pkcs12.Modern2023, ecKey, want{"SHA-256", false, "PBES2", "PBES2", false, 2048}},
+		{"Modern2023 Ed25519", pkcs12.Modern2023.WithIterations(100), edKey, want{"SHA-256", false, "PBES2", "PBES2", false, 100}},
+		{"Modern2026", pkcs12.Modern2026, rsaKey, want{"PBMAC1", false, "PBES2", "PBES2", false, 2048}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestChain(t, tt.key)
+			data, err := tt.encoder.Encode(tc.key, tc.leaf, tc.chain, "password")
+			require.NoError(t, err)
+
+			_, err = Parse(data, "wrong")
+			assert.ErrorIs(t, err, ErrIncorrectPassword)
+
+			f, err := Parse(data, "password")
+			require.NoError(t, err)
+			assert.Equal(t, 3, f.Version)
+			require.NotNil(t, f.MAC)
+			assert.Equal(t, tt.want.mac, f.MAC.Algorithm.Name)
+			assert.Equal(t, tt.want.macLegacy, f.MAC.Algorithm.Legacy)
+
+			require.Len(t, f.Safes, 2)
+			certSafe, keySafe := f.Safes[0], f.Safes[1]
+			require.NotNil(t, certSafe.Encryption)
+			assert.Equal(t, tt.want.certEnc, certSafe.Encryption.Name)
+			assert.Equal(t, tt.want.encLegacy, certSafe.Encryption.Legacy)
+			assert.Equal(t, tt.want.iterations, certSafe.Encryption.Iterations)
+			assert.Nil(t, keySafe.Encryption)
+			require.Len(t, certSafe.Bags, 3)
+			require.Len(t, keySafe.Bags, 1)
+			assert.Equal(t, "certBag", certSafe.Bags[0].Type)
+			assert.Equal(t, "pkcs8ShroudedKeyBag", keySafe.Bags[0].Type)
+			assert.Equal(t, tt.want.keyEnc, keySafe.Bags[0].Encryption.Name)
+			if tt.want.keyEnc == "PBES2" {
+				assert.Equal(t, "AES-256-CBC", keySafe.Bags[0].Encryption.Cipher)
+				assert.Equal(t, "HMAC-SHA256", keySafe.Bags[0].Encryption.PRF)
+			}
+			assert.NotEmpty(t, keySafe.Bags[0].LocalKeyID)
+			assert.Equal(t, keySafe.Bags[0].LocalKeyID, certSafe.Bags[0].LocalKeyID)
+
+			assert.Equal(t, []*x509.Certificate{tc.leaf, tc.chain[0], tc.chain[1]}, f.Certificates())
+			ids := f.Identities()
+			require.Len(t, ids, 1)
+			assert.Equal(t, tc.key, ids[0].PrivateKey)
+			assert.Equal(t, tc.leaf, ids[0].Certificate)
+			assert.Equal(t, tc.chain, ids[0].Chain)
+		})
+	}
+}
+
+func TestParse_trustStore(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tc := newTestChain(t, key)
+	entries := []pkcs12.TrustStoreEntry{
+		{Cert: tc.chain[1], FriendlyName: "root"},
+		{Cert: tc.chain[0], FriendlyName: "intermediate"},
+	}
+
+	t.Run("password", func(t *testing.T) {
+		data, err := pkcs12.Modern2023.EncodeTrustStoreEntries(entries, "password")
+		require.NoError(t, err)
+		f, err := Parse(data, "password")
+		require.NoError(t, err)
+		bags := f.Bags()
+		require.Len(t, bags, 2)
+		assert.Equal(t, "root", bags[0].FriendlyName)
+		assert.Equal(t, []string{"Java Trusted Key Usage"}, bags[0].Attributes)
+		assert.Equal(t, "intermediate", bags[1].FriendlyName)
+		assert.Empty(t, f.Identities())
+		assert.Equal(t, []*x509.Certificate{tc.chain[1], tc.chain[0]}, f.Certificates())
+
+		ids := f.NamedCertificates("intermediate")
+		require.Len(t, ids, 1)
+		assert.Equal(t, "intermediate", ids[0].FriendlyName)
+		assert.Nil(t, ids[0].PrivateKey)
+		assert.Equal(t, tc.chain[0], ids[0].Certificate)
+		assert.Equal(t, []*x509.Certificate{tc.chain[1]}, ids[0].Chain)
+		assert.Empty(t, f.NamedCertificates("leaf"))
+	})
+
+	t.Run("passwordless", func(t *testing.T) {
+		data, err := pkcs12.Passwordless.EncodeTrustStoreEntries(entries, "")
+		require.NoError(t, err)
+		f, err := Parse(data, "")
+		require.NoError(t, err)
+		assert.Nil(t, f.MAC)
+		require.Len(t, f.Safes, 1)
+		assert.Nil(t, f.Safes[0].Encryption)
+		assert.Len(t, f.Certificates(), 2)
+	})
+}
+
+func TestParse_errors(t *testing.T) {
+	_, err := Parse([]byte("not a pkcs12 file"), "")
+	assert.ErrorContains(t, err, "error parsing PKCS #12 file")
+}
+
+func Test_pkcs12KDF(t *testing.T) {
+	// Bouncy Castle test vectors with the password "smeg".
+	salt, err := hex.DecodeString("0a58cf64530d823f")
+	require.NoError(t, err)
+	pw := newPassword("smeg")
+	assert.Equal(t, "8aaae6297b6cb04642ab5b077851284eb7128f1a2a7fbca3",
+		hex.EncodeToString(pkcs12KDF(sha1.New, 64, salt, pw.bmp, 1, 1, 24)))
+	assert.Equal(t, "79993dfe048d3b76",
+		hex.EncodeToString(pkcs12KDF(sha1.New, 64, salt, pw.bmp, 2, 1, 8)))
+}
+
+func Test_decodeBMPString(t *testing.T) {
+	pw := newPassword("añb€")
+	s, err := decodeBMPString(pw.bmp)
+	require.NoError(t, err)
+	assert.Equal(t, "añb€", s)
+	_, err = decodeBMPString([]byte{0})
+	assert.Error(t, err)
+}
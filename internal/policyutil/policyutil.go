@@ -0,0 +1,434 @@
+// Package policyutil evaluates the names of a certificate against the
+// certificate issuance policies with the policy engine of step-ca, and explains
+// the rule that allowed or denied each name.
+package policyutil
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/smallstep/certificates/policy"
+)
+
+// Kind is the type of a name or rule.
+type Kind string
+
+// Supported kinds. The values match the names of the policy subcommands.
+const (
+	DNS        Kind = "dns"
+	IP         Kind = "ip"
+	Email      Kind = "email"
+	URI        Kind = "uri"
+	CommonName Kind = "cn"
+	Principal  Kind = "principal"
+)
+
+// Names is a list of allowed or denied names.
+type Names struct {
+	DNS             []string
+	DNSRegex        []string
+	IPs             []string
+	Emails          []string
+	EmailRegex      []string
+	URIs            []string
+	URIConstraints  []string
+	URIRegex        []string
+	CommonNames     []string
+	CommonNameRegex []string
+	Principals      []string
+	PrincipalRegex  []string
+}
+
+func (n *Names) empty() bool {
+	return len(n.DNS)+len(n.IPs)+len(n.Emails)+len(n.URIs)+len(n.CommonNames)+
+		len(n.Principals) == 0
+}
+
+// Rules are the allowed and denied names of a policy for a type of
+// certificate.
+type Rules struct {
+	Allow Names
+	Deny  Names
+	// AllowWildcardNames allows DNS names like *.example.com. It only applies
+	// to X.509 policies.
+	AllowWildcardNames bool
+}
+
+// Empty returns true if the rules do not allow or deny any name. The rules not
+// supported by the policy engine are ignored.
+func (r *Rules) Empty() bool {
+	return r == nil || (r.Allow.empty() && r.Deny.empty())
+}
+
+// Name is a name of a certificate.
+type Name struct {
+	Kind  Kind
+	Value string
+}
+
+func (n Name) String() string {
+	return string(n.Kind) + " " + n.Value
+}
+
+// Result is the result of the evaluation of a name.
+type Result struct {
+	Name    Name
+	Allowed bool
+	// Rule is the rule that allowed or denied the name, e.g.
+	// `dns "*.example.com"`. It is empty if no rule matched.
+	Rule string
+	// Reason explains the result.
+	Reason string
+}
+
+// Evaluate evaluates the names against the rules using the policy engine of
+// step-ca, and explains the rule that allowed or denied each name. Each name is
+// evaluated as if it were the only name in the certificate. SSH principals are
+// evaluated as host principals if sshHost is true, and as user principals
+// otherwise.
+//
+// The policy engine does not support regular expressions and URI
+// constraints; these rules are returned by Unsupported and are not used.
+func (r *Rules) Evaluate(names []Name, sshHost bool) ([]Result, error) {
+	if r == nil {
+		r = new(Rules)
+	}
+	e, err := r.engine()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(names))
+	for i, name := range names {
+		allowed, err := isAllowed(e, name, sshHost)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = r.explain(name, allowed, sshHost)
+	}
+	return results, nil
+}
+
+// Unsupported returns the rules that are not supported by the policy engine
+// and are not used in Evaluate.
+func (r *Rules) Unsupported() []string {
+	if r == nil {
+		return nil
+	}
+	var rules []string
+	add := func(kind string, values []string) {
+		for _, v := range values {
+			rules = append(rules, fmt.Sprintf("%s %q", kind, v))
+		}
+	}
+	for _, n := range []Names{r.Allow, r.Deny} {
+		add("dns-regex", n.DNSRegex)
+		add("email-regex", n.EmailRegex)
+		add("uri-constraint", n.URIConstraints)
+		add("uri-regex", n.URIRegex)
+		add("cn-regex", n.CommonNameRegex)
+		add("principal-regex", n.PrincipalRegex)
+	}
+	return rules
+}
+
+// engine returns the policy engine of step-ca with the rules.
+func (r *Rules) engine() (*policy.NamePolicyEngine, error) {
+	opts := []policy.NamePolicyOption{
+		policy.WithSubjectCommonNameVerification(),
+		policy.WithPermittedDNSDomains(r.Allow.DNS...),
+		policy.WithExcludedDNSDomains(r.Deny.DNS...),
+		policy.WithPermittedIPsOrCIDRs(r.Allow.IPs...),
+		policy.WithExcludedIPsOrCIDRs(r.Deny.IPs...),
+		policy.WithPermittedEmailAddresses(r.Allow.Emails...),
+		policy.WithExcludedEmailAddresses(r.Deny.Emails...),
+		policy.WithPermittedURIDomains(r.Allow.URIs...),
+		policy.WithExcludedURIDomains(r.Deny.URIs...),
+		policy.WithPermittedCommonNames(r.Allow.CommonNames...),
+		policy.WithExcludedCommonNames(r.Deny.CommonNames...),
+		policy.WithPermittedPrincipals(r.Allow.Principals...),
+		policy.WithExcludedPrincipals(r.Deny.Principals...),
+	}
+	if r.AllowWildcardNames {
+		opts = append(opts, policy.WithAllowLiteralWildcardNames())
+	}
+	e, err := policy.New(opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating policy engine")
+	}
+	return e, nil
+}
+
+// isAllowed returns true if the policy engine allows a certificate with the
+// name.
+func isAllowed(e *policy.NamePolicyEngine, name Name, sshHost bool) (bool, error) {
+	var err error
+	switch name.Kind {
+	case Principal:
+		certType := uint32(ssh.UserCert)
+		if sshHost {
+			certType = ssh.HostCert
+		}
+		err = e.IsSSHCertificateAllowed(&ssh.Certificate{
+			CertType:        certType,
+			ValidPrincipals: []string{name.Value},
+		})
+	case CommonName:
+		err = e.IsX509CertificateAllowed(&x509.Certificate{
+			Subject: pkix.Name{CommonName: name.Value},
+		})
+	default:
+		cert := new(x509.Certificate)
+		switch name.Kind {
+		case DNS:
+			cert.DNSNames = []string{name.Value}
+		case IP:
+			ip := net.ParseIP(name.Value)
+			if ip == nil {
+				return false, errors.Errorf("error parsing IP address %q", name.Value)
+			}
+			cert.IPAddresses = []net.IP{ip}
+		case Email:
+			cert.EmailAddresses = []string{name.Value}
+		case URI:
+			u, err := url.Parse(name.Value)
+			if err != nil {
+				return false, errors.Wrapf(err, "error parsing URI %q", name.Value)
+			}
+			cert.URIs = []*url.URL{u}
+		default:
+			return false, errors.Errorf("unsupported name kind %q", name.Kind)
+		}
+		err = e.IsX509CertificateAllowed(cert)
+	}
+	// The engine returns errors for names that cannot be used, like URI
+	// principals; these names are not allowed.
+	return err == nil, nil
+}
+
+// explain returns the result of a name with the rule that allowed or denied
+// it, given the decision of the policy engine.
+func (r *Rules) explain(name Name, allowed, sshHost bool) Result {
+	res := Result{Name: name, Allowed: allowed}
+	kinds := []Kind{name.Kind}
+	switch name.Kind {
+	case Principal:
+		// Principals are split like the policy engine does.
+		switch kind := Detect(name.Value); {
+		case kind == URI:
+			res.Reason = "URI principals are not allowed"
+			return res
+		case kind == IP && !sshHost:
+			res.Reason = "IP principals are not allowed in user certificates"
+			return res
+		case kind != DNS || sshHost:
+			kinds[0] = kind
+		}
+	case CommonName:
+		// Common names not allowed by the common name rules are evaluated
+		// as the name they look like.
+		kinds = append(kinds, Detect(name.Value))
+	}
+
+	if allowed {
+		if r.Empty() {
+			res.Reason = "allowed, no rules"
+			return res
+		}
+		for _, kind := range kinds {
+			if rule := r.Allow.match(kind, name.Value); rule != "" {
+				res.Rule, res.Reason = rule, "allowed by "+rule
+				return res
+			}
+		}
+		kind := kinds[len(kinds)-1]
+		switch {
+		case r.Allow.empty():
+			res.Reason = "allowed, no allow rules"
+		case !r.Allow.has(kind):
+			res.Reason = fmt.Sprintf("allowed, no %s allow rules", kind)
+		default:
+			res.Reason = "allowed"
+		}
+		return res
+	}
+
+	for _, kind := range kinds {
+		if rule := r.Deny.match(kind, name.Value); rule != "" {
+			res.Rule, res.Reason = rule, "denied by "+rule
+			return res
+		}
+	}
+	kind := kinds[len(kinds)-1]
+	switch {
+	case kind == DNS && strings.HasPrefix(name.Value, "*.") && !r.AllowWildcardNames:
+		res.Reason = "wildcard names are not allowed"
+	case kind == DNS && !validDomain(name.Value):
+		res.Reason = "not allowed, invalid DNS name"
+	case !r.Allow.has(kind) && !r.Deny.has(kind):
+		res.Reason = fmt.Sprintf("not allowed, no %s rules", kind)
+	default:
+		res.Reason = "not allowed, no allow rule matches"
+	}
+	return res
+}
+
+// Detect returns the kind of a subject alternative name: an IP address, a
+// URI, an email address, or a DNS name. Names are split like step-ca does.
+func Detect(s string) Kind {
+	if net.ParseIP(s) != nil {
+		return IP
+	}
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" {
+		return URI
+	}
+	if strings.Contains(s, "@") {
+		return Email
+	}
+	return DNS
+}
+
+// validDomain returns true if the DNS name, without the wildcard label, only
+// has non-empty labels with printable ASCII characters other than spaces.
+func validDomain(s string) bool {
+	for _, label := range strings.Split(strings.TrimPrefix(s, "*."), ".") {
+		if label == "" {
+			return false
+		}
+		for _, c := range label {
+			if c < 33 || c > 126 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// has returns true if there are rules of the given kind supported by the
+// policy engine.
+func (n *Names) has(kind Kind) bool {
+	switch kind {
+	case DNS:
+		return len(n.DNS) > 0
+	case IP:
+		return len(n.IPs) > 0
+	case Email:
+		return len(n.Emails) > 0
+	case URI:
+		return len(n.URIs) > 0
+	case CommonName:
+		return len(n.CommonNames) > 0
+	case Principal:
+		return len(n.Principals) > 0
+	default:
+		return false
+	}
+}
+
+// match returns the first rule of the given kind that matches the value, or
+// an empty string if none does.
+func (n *Names) match(kind Kind, value string) string {
+	var (
+		rules   []string
+		matches func(rule, value string) bool
+	)
+	switch kind {
+	case DNS:
+		rules, matches = n.DNS, matchDomain
+	case IP:
+		rules, matches = n.IPs, matchIP
+	case Email:
+		rules, matches = n.Emails, matchEmail
+	case URI:
+		rules, matches = n.URIs, matchURI
+	case CommonName:
+		rules, matches = n.CommonNames, matchCommonName
+	case Principal:
+		rules, matches = n.Principals, matchPrincipal
+	default:
+		return ""
+	}
+	for _, rule := range rules {
+		if matches(rule, value) {
+			return fmt.Sprintf("%s %q", kind, rule)
+		}
+	}
+	return ""
+}
+
+// matchCommonName matches a common name, ignoring the case.
+func matchCommonName(rule, value string) bool {
+	return strings.EqualFold(rule, value)
+}
+
+// matchDomain matches a DNS name against a domain. A domain starting with
+// "*." matches a single label, e.g. *.example.com matches www.example.com but
+// not example.com or a.www.example.com, and a domain starting with "."
+// matches any subdomain.
+func matchDomain(rule, value string) bool {
+	rule, value = strings.ToLower(rule), strings.ToLower(strings.TrimSuffix(value, "."))
+	switch {
+	case rule == value:
+		return true
+	case strings.HasPrefix(rule, "*."):
+		label, ok := strings.CutSuffix(value, rule[1:])
+		return ok && label != "" && !strings.Contains(label, ".")
+	case strings.HasPrefix(rule, "."):
+		return strings.HasSuffix(value, rule) && len(value) > len(rule)
+	default:
+		return false
+	}
+}
+
+// matchIP matches an IP address against an IP address or a CIDR.
+func matchIP(rule, value string) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	if _, ipNet, err := net.ParseCIDR(rule); err == nil {
+		return ipNet.Contains(ip)
+	}
+	if r := net.ParseIP(rule); r != nil {
+		return r.Equal(ip)
+	}
+	return false
+}
+
+// matchEmail matches an email address against a mailbox, e.g.
+// jane@example.com, or a domain, e.g. @example.com, example.com or
+// .example.com.
+func matchEmail(rule, value string) bool {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok {
+		return false
+	}
+	if l, d, ok := strings.Cut(rule, "@"); ok {
+		if l == "" {
+			return matchDomain(d, domain)
+		}
+		return l == local && strings.EqualFold(d, domain)
+	}
+	return matchDomain(rule, domain)
+}
+
+// matchURI matches the host of a URI against a domain.
+func matchURI(rule, value string) bool {
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return matchDomain(rule, u.Hostname())
+}
+
+// matchPrincipal matches an SSH principal, ignoring the case. The principal
+// "*" matches any principal.
+func matchPrincipal(rule, value string) bool {
+	return rule == "*" || strings.EqualFold(rule, value)
+}
@@ -0,0 +1,235 @@
+package policyutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules_Evaluate(t *testing.T) {
+	rules := &Rules{
+		Allow: Names{
+			DNS:         []string{"*.example.com", "example.com"},
+			IPs:         []string{"10.0.0.0/8"},
+			Emails:      []string{"@example.com"},
+			URIs:        []string{"*.example.com"},
+			CommonNames: []string{"Jane Doe"},
+		},
+		Deny: Names{
+			DNS: []string{"internal.example.com"},
+			IPs: []string{"10.1.0.1"},
+		},
+	}
+
+	results, err := rules.Evaluate([]Name{
+		{DNS, "www.example.com"},
+		{DNS, "example.com"},
+		{DNS, "a.www.example.com"},
+		{DNS, "internal.example.com"},
+		{DNS, "*.example.com"},
+		{IP, "10.2.3.4"},
+		{IP, "10.1.0.1"},
+		{Email, "jane@example.com"},
+		{Email, "jane@example.org"},
+		{URI, "spiffe://api.example.com/workload"},
+		{URI, "spiffe://example.org/workload"},
+		{CommonName, "jane doe"},
+		{CommonName, "www.example.com"},
+		{CommonName, "Jane"},
+	}, false)
+	require.NoError(t, err)
+
+	want := []struct {
+		allowed bool
+		reason  string
+	}{
+		{true, `allowed by dns "*.example.com"`},
+		{true, `allowed by dns "example.com"`},
+		{false, "not allowed, no allow rule matches"},
+		{false, `denied by dns "internal.example.com"`},
+		{false, "wildcard names are not allowed"},
+		{true, `allowed by ip "10.0.0.0/8"`},
+		{false, `denied by ip "10.1.0.1"`},
+		{true, `allowed by email "@example.com"`},
+		{false, "not allowed, no allow rule matches"},
+		{true, `allowed by uri "*.example.com"`},
+		{false, "not allowed, no allow rule matches"},
+		{true, `allowed by cn "Jane Doe"`},
+		{true, `allowed by dns "*.example.com"`},
+		{false, "not allowed, no allow rule matches"},
+	}
+	require.Len(t, results, len(want))
+	for i, w := range want {
+		assert.Equal(t, w.allowed, results[i].Allowed, results[i].Name.String())
+		assert.Equal(t, w.reason, results[i].Reason, results[i].Name.String())
+	}
+}
+
+func TestRules_Evaluate_SSH(t *testing.T) {
+	host := &Rules{
+		Allow: Names{DNS: []string{"*.internal"}, Principals: []string{"bastion"}},
+		Deny:  Names{IPs: []string{"192.168.0.0/16"}},
+	}
+	results, err := host.Evaluate([]Name{
+		{Principal, "db.internal"},
+		{Principal, "bastion"},
+		{Principal, "192.168.1.1"},
+	}, true)
+	require.NoError(t, err)
+	assert.True(t, results[0].Allowed)
+	assert.Equal(t, `dns "*.internal"`, results[0].Rule)
+	// Host principals are evaluated as DNS names, not principals.
+	assert.False(t, results[1].Allowed)
+	assert.False(t, results[2].Allowed)
+	assert.Equal(t, `ip "192.168.0.0/16"`, results[2].Rule)
+
+	user := &Rules{
+		Allow: Names{Emails: []string{"@example.com"}, Principals: []string{"jane"}},
+		Deny:  Names{Principals: []string{"root"}},
+	}
+	results, err = user.Evaluate([]Name{
+		{Principal, "jane"},
+		{Principal, "jane@example.com"},
+		{Principal, "root"},
+		{Principal, "Jane1"},
+	}, false)
+	require.NoError(t, err)
+	assert.True(t, results[0].Allowed)
+	assert.True(t, results[1].Allowed)
+	assert.False(t, results[2].Allowed)
+	assert.False(t, results[3].Allowed)
+}
+
+func TestRules_Evaluate_noRules(t *testing.T) {
+	var rules *Rules
+	assert.True(t, rules.Empty())
+	results, err := rules.Evaluate([]Name{{DNS, "www.example.com"}}, false)
+	require.NoError(t, err)
+	assert.True(t, results[0].Allowed)
+
+	rules = &Rules{Deny: Names{DNS: []string{"www.example.com"}}}
+	assert.False(t, rules.Empty())
+	results, err = rules.Evaluate([]Name{{DNS, "www.example.com"}, {IP, "127.0.0.1"}}, false)
+	require.NoError(t, err)
+	assert.False(t, results[0].Allowed)
+	assert.True(t, results[1].Allowed)
+	assert.Equal(t, "allowed, no allow rules", results[1].Reason)
+
+	rules = &Rules{Deny: Names{IPs: []string{"not-an-ip"}}}
+	_, err = rules.Evaluate([]Name{{DNS, "www.example.com"}}, false)
+	assert.ErrorContains(t, err, "error creating policy engine")
+}
+
+func TestRules_Unsupported(t *testing.T) {
+	rules := &Rules{
+		Allow: Names{DNS: []string{"example.com"}, URIConstraints: []string{"spiffe://example.com/*"}},
+		Deny:  Names{DNSRegex: []string{`^internal\.`}, PrincipalRegex: []string{"^root$"}},
+	}
+	assert.Equal(t, []string{
+		`uri-constraint "spiffe://example.com/*"`,
+		`dns-regex "^internal\\."`,
+		`principal-regex "^root$"`,
+	}, rules.Unsupported())
+
+	// Unsupported rules are not used.
+	rules.Allow.DNS = nil
+	assert.True(t, rules.Empty())
+	results, err := rules.Evaluate([]Name{{DNS, "internal.example.com"}}, false)
+	require.NoError(t, err)
+	assert.True(t, results[0].Allowed)
+	assert.Equal(t, "allowed, no rules", results[0].Reason)
+}
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, IP, Detect("::1"))
+	assert.Equal(t, URI, Detect("spiffe://example.com/foo"))
+	assert.Equal(t, Email, Detect("jane@example.com"))
+	assert.Equal(t, DNS, Detect("www.example.com"))
+}
+
+func TestRules_Evaluate_explanation(t *testing.T) {
+	x509Names := []Name{
+		{DNS, "www.example.com"}, {DNS, "WWW.Example.COM"}, {DNS, "example.com"},
+		{DNS, "a.www.example.com"}, {DNS, "internal.example.com"}, {DNS, "*.example.com"},
+		{DNS, "*.other.org"}, {DNS, "other.org"},
+		{IP, "10.2.3.4"}, {IP, "10.1.0.1"}, {IP, "192.168.1.1"}, {IP, "2001:db8::1"},
+		{Email, "jane@example.com"}, {Email, "jane@EXAMPLE.com"}, {Email, "bad@example.com"},
+		{Email, "jane@example.org"}, {Email, "jane@sub.example.com"},
+		{URI, "https://api.example.com/v1"}, {URI, "spiffe://example.com/workload"}, {URI, "https://example.org"},
+		{CommonName, "Jane Doe"}, {CommonName, "jane doe"}, {CommonName, "Other"},
+		{CommonName, "www.example.com"}, {CommonName, "internal.example.com"}, {CommonName, "10.2.3.4"},
+		{CommonName, "jane@example.com"}, {CommonName, "https://api.example.com"},
+	}
+	sshNames := []Name{
+		{Principal, "db.internal"}, {Principal, "bastion"}, {Principal, "jane"}, {Principal, "JANE"},
+		{Principal, "root"}, {Principal, "bob"}, {Principal, "192.168.1.1"}, {Principal, "10.0.0.1"},
+		{Principal, "jane@example.com"}, {Principal, "jane@other.com"},
+	}
+
+	tests := []struct {
+		name    string
+		rules   *Rules
+		sshHost bool
+		names   []Name
+	}{
+		{"x509/no-rules", &Rules{}, false, x509Names},
+		{"x509/allow-and-deny", &Rules{
+			Allow: Names{
+				DNS:         []string{"*.example.com", "example.com"},
+				IPs:         []string{"10.0.0.0/8"},
+				Emails:      []string{"@example.com"},
+				URIs:        []string{"*.example.com"},
+				CommonNames: []string{"Jane Doe"},
+			},
+			Deny: Names{
+				DNS:    []string{"internal.example.com"},
+				IPs:    []string{"10.1.0.1"},
+				Emails: []string{"bad@example.com"},
+			},
+		}, false, x509Names},
+		{"x509/deny-only-kinds", &Rules{
+			Allow: Names{IPs: []string{"10.0.0.0/8"}},
+			Deny:  Names{DNS: []string{"internal.example.com"}, CommonNames: []string{"Other"}},
+		}, false, x509Names},
+		{"x509/deny-only", &Rules{
+			Deny: Names{DNS: []string{"*.example.com"}, IPs: []string{"192.168.0.0/16"}, Emails: []string{"example.org"}},
+		}, false, x509Names},
+		{"x509/wildcards", &Rules{
+			Allow:              Names{DNS: []string{"*.example.com"}, CommonNames: []string{"other"}},
+			AllowWildcardNames: true,
+		}, false, x509Names},
+		{"ssh-host", &Rules{
+			Allow: Names{DNS: []string{"*.internal"}, Principals: []string{"bastion"}},
+			Deny:  Names{IPs: []string{"192.168.0.0/16"}},
+		}, true, sshNames},
+		{"ssh-user", &Rules{
+			Allow: Names{Emails: []string{"@example.com"}, Principals: []string{"jane", "bob"}},
+			Deny:  Names{Principals: []string{"root", "bob"}},
+		}, false, sshNames},
+		{"ssh-user/no-rules", &Rules{}, false, sshNames},
+		{"ssh-user/any-principal", &Rules{
+			Allow: Names{Principals: []string{"*"}},
+		}, false, sshNames},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := tt.rules.Evaluate(tt.names, tt.sshHost)
+			require.NoError(t, err)
+			// The explanation agrees with the decision of the engine.
+			for _, res := range results {
+				switch {
+				case res.Allowed && res.Rule != "":
+					assert.Equal(t, "allowed by "+res.Rule, res.Reason, res.Name.String())
+				case res.Allowed:
+					assert.True(t, strings.HasPrefix(res.Reason, "allowed"), "%s: %s", res.Name, res.Reason)
+				case res.Rule != "":
+					assert.Equal(t, "denied by "+res.Rule, res.Reason, res.Name.String())
+				default:
+					assert.False(t, strings.HasPrefix(res.Reason, "allowed"), "%s: %s", res.Name, res.Reason)
+				}
+			}
+		})
+	}
+}
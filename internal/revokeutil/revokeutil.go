@@ -0,0 +1,225 @@
+// Package revokeutil implements the input files and the rate limited runner
+// used to revoke certificates in bulk.
+package revokeutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// Entry is a certificate to revoke. A certificate is identified by its serial
+// number, or by a certificate and key pair used to authorize the revocation
+// over mTLS. Empty reasons use the defaults of the command.
+type Entry struct {
+	Serial     string `json:"serial,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	ReasonCode string `json:"reasonCode,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+}
+
+// IsMTLS returns true if the entry is revoked using mTLS.
+func (e *Entry) IsMTLS() bool {
+	return e.Cert != "" || e.Key != ""
+}
+
+// Validate checks that the entry identifies a certificate.
+func (e *Entry) Validate() error {
+	switch {
+	case e.IsMTLS() && e.Cert == "":
+		return errors.New("key requires a cert")
+	case e.IsMTLS() && e.Key == "":
+		return errors.New("cert requires a key")
+	case !e.IsMTLS() && e.Serial == "":
+		return errors.New("serial or cert and key are required")
+	default:
+		return nil
+	}
+}
+
+// csvColumns are the columns of a CSV file with a header row.
+var csvColumns = []string{"serial", "reason", "reasoncode", "cert", "key"}
+
+// ReadFile reads the entries in a JSON or CSV file. A JSON file is an array
+// of entries. A CSV file has the serial, reason, reasonCode, cert and key
+// columns in that order, or in any order with a header row. Relative cert and
+// key paths are relative to the directory of the file.
+func ReadFile(filename string) ([]Entry, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+
+	var entries []Entry
+	if strings.EqualFold(filepath.Ext(filename), ".json") || bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		entries, err = parseJSON(b)
+	} else {
+		entries, err = parseCSV(b)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", filename)
+	}
+
+	dir := filepath.Dir(filename)
+	for i := range entries {
+		e := &entries[i]
+		if err := e.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "error parsing %s: entry %d", filename, i+1)
+		}
+		if e.Cert != "" && !filepath.IsAbs(e.Cert) {
+			e.Cert = filepath.Join(dir, e.Cert)
+		}
+		if e.Key != "" && !filepath.IsAbs(e.Key) {
+			e.Key = filepath.Join(dir, e.Key)
+		}
+	}
+	return entries, nil
+}
+
+func parseJSON(b []byte) ([]Entry, error) {
+	var raw []struct {
+		Serial     any    `json:"serial"`
+		Reason     string `json:"reason"`
+		ReasonCode any    `json:"reasonCode"`
+		Cert       string `json:"cert"`
+		Key        string `json:"key"`
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	d.DisallowUnknownFields()
+	if err := d.Decode(&raw); err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, len(raw))
+	for i, r := range raw {
+		entries[i] = Entry{
+			Serial:     jsonString(r.Serial),
+			Reason:     r.Reason,
+			ReasonCode: jsonString(r.ReasonCode),
+			Cert:       r.Cert,
+			Key:        r.Key,
+		}
+	}
+	return entries, nil
+}
+
+// jsonString returns a string or number as a string.
+func jsonString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+func parseCSV(b []byte) ([]Entry, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// By default the columns are in the documented order.
+	columns := map[string]int{}
+	for i, name := range csvColumns {
+		columns[name] = i
+	}
+	if len(records) > 0 && isHeader(records[0]) {
+		columns = map[string]int{}
+		for i, name := range records[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			for _, c := range csvColumns {
+				if name == c {
+					columns[c] = i
+				}
+			}
+		}
+		records = records[1:]
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for _, record := range records {
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		e := Entry{
+			Serial:     get("serial"),
+			Reason:     get("reason"),
+			ReasonCode: get("reasoncode"),
+			Cert:       get("cert"),
+			Key:        get("key"),
+		}
+		if e == (Entry{}) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func isHeader(record []string) bool {
+	for _, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "serial", "cert":
+			return true
+		}
+	}
+	return false
+}
+
+// Run calls fn for each item using up to concurrency goroutines, and at most
+// rate calls per second if rate is positive. The errors are in the order of
+// the items.
+func Run[T any](items []T, concurrency int, rate float64, fn func(T) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		// Rates over one call per nanosecond are truncated to 0, an invalid
+		// interval for a ticker.
+		interval := max(time.Duration(float64(time.Second)/rate), time.Nanosecond)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	results := make([]error, len(items))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		if tick != nil && i > 0 {
+			<-tick
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,108 @@
+package revokeutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+	return filename
+}
+
+func TestReadFile_CSV(t *testing.T) {
+	filename := writeFile(t, "serials.csv", `# incident 42
+1234,key compromise,1
+0x4d3
+
+5678,,superseded
+`)
+	entries, err := ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Serial: "1234", Reason: "key compromise", ReasonCode: "1"},
+		{Serial: "0x4d3"},
+		{Serial: "5678", ReasonCode: "superseded"},
+	}, entries)
+
+	filename = writeFile(t, "certs.csv", `cert,key,reasonCode
+foo.crt,foo.key,keyCompromise
+/tmp/bar.crt,/tmp/bar.key,
+`)
+	entries, err = ReadFile(filename)
+	require.NoError(t, err)
+	dir := filepath.Dir(filename)
+	assert.Equal(t, []Entry{
+		{Cert: filepath.Join(dir, "foo.crt"), Key: filepath.Join(dir, "foo.key"), ReasonCode: "keyCompromise"},
+		{Cert: "/tmp/bar.crt", Key: "/tmp/bar.key"},
+	}, entries)
+	assert.True(t, entries[0].IsMTLS())
+
+	_, err = ReadFile(writeFile(t, "bad.csv", "cert,key\nfoo.crt,\n"))
+	assert.Error(t, err)
+	_, err = ReadFile(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
+
+func TestReadFile_JSON(t *testing.T) {
+	filename := writeFile(t, "serials.json", `[
+  {"serial": "1234", "reasonCode": 1, "reason": "laptop lost"},
+  {"serial": 308893286343609293989051180431574390766},
+  {"cert": "foo.crt", "key": "foo.key"}
+]`)
+	entries, err := ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Serial: "1234", Reason: "laptop lost", ReasonCode: "1"},
+		{Serial: "308893286343609293989051180431574390766"},
+		{Cert: filepath.Join(filepath.Dir(filename), "foo.crt"), Key: filepath.Join(filepath.Dir(filename), "foo.key")},
+	}, entries)
+
+	_, err = ReadFile(writeFile(t, "bad.json", `[{"serial": "1", "foo": "bar"}]`))
+	assert.Error(t, err)
+	_, err = ReadFile(writeFile(t, "empty.json", `[{"reason": "foo"}]`))
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	items := []string{"1", "2", "3", "4"}
+	var calls atomic.Int32
+	results := Run(items, 2, 0, func(serial string) error {
+		calls.Add(1)
+		if serial == "3" {
+			return errors.New("not found")
+		}
+		return nil
+	})
+	assert.Equal(t, int32(4), calls.Load())
+	require.Len(t, results, 4)
+	for i, err := range results {
+		if i == 2 {
+			assert.EqualError(t, err, "not found")
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	start := time.Now()
+	Run(items[:3], 3, 50, func(string) error { return nil })
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	// Rates with an interval under a nanosecond do not panic.
+	calls.Store(0)
+	results = Run(items, 2, 2e9, func(string) error {
+		calls.Add(1)
+		return nil
+	})
+	assert.Equal(t, int32(4), calls.Load())
+	assert.Len(t, results, 4)
+}
@@ -0,0 +1,134 @@
+// Package rotateutil implements the certificates and bundles used to rotate the
+// intermediate and root certificates of a CA.
+package rotateutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/x509util"
+)
+
+// backdate is the time subtracted from the start of the validity of the new
+// certificates to tolerate clock skew.
+const backdate = time.Minute
+
+// NewIntermediate returns an intermediate certificate for the given subject
+// and public key issued by the root. The certificate is valid until notAfter,
+// but it never outlives the root.
+func NewIntermediate(subject pkix.Name, pub crypto.PublicKey, root *x509.Certificate, signer crypto.Signer, notAfter time.Time) (*x509.Certificate, error) {
+	template := &x509.Certificate{
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-backdate),
+		NotAfter:              minTime(notAfter, root.NotAfter),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            0,
+		MaxPathLenZero:        true,
+	}
+	cert, err := x509util.CreateCertificate(template, root, pub, signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating intermediate certificate")
+	}
+	return cert, nil
+}
+
+// CrossSign returns a certificate with the subject, key, and constraints of
+// the given CA certificate, issued by another CA. The certificate is valid
+// until the first of both certificates expires.
+func CrossSign(cert, issuer *x509.Certificate, signer crypto.Signer) (*x509.Certificate, error) {
+	if !cert.IsCA {
+		return nil, errors.Errorf("certificate %q is not a CA", cert.Subject)
+	}
+	if bytes.Equal(cert.RawSubjectPublicKeyInfo, issuer.RawSubjectPublicKeyInfo) {
+		return nil, errors.Errorf("certificate %q cannot be cross-signed with its own key", cert.Subject)
+	}
+	template := &x509.Certificate{
+		Subject:               cert.Subject,
+		SubjectKeyId:          cert.SubjectKeyId,
+		NotBefore:             time.Now().Add(-backdate),
+		NotAfter:              minTime(cert.NotAfter, issuer.NotAfter),
+		KeyUsage:              cert.KeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            cert.MaxPathLen,
+		MaxPathLenZero:        cert.MaxPathLenZero,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		UnknownExtKeyUsage:    cert.UnknownExtKeyUsage,
+		PolicyIdentifiers:     cert.PolicyIdentifiers,
+		Policies:              cert.Policies,
+
+		PermittedDNSDomainsCritical: cert.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:         cert.PermittedDNSDomains,
+		ExcludedDNSDomains:          cert.ExcludedDNSDomains,
+		PermittedIPRanges:           cert.PermittedIPRanges,
+		ExcludedIPRanges:            cert.ExcludedIPRanges,
+		PermittedEmailAddresses:     cert.PermittedEmailAddresses,
+		ExcludedEmailAddresses:      cert.ExcludedEmailAddresses,
+		PermittedURIDomains:         cert.PermittedURIDomains,
+		ExcludedURIDomains:          cert.ExcludedURIDomains,
+	}
+	crt, err := x509util.CreateCertificate(template, issuer, cert.PublicKey, signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating cross-signed certificate")
+	}
+	return crt, nil
+}
+
+// PathLenAllowsCrossSign returns false if the path length constraint of the
+// issuer does not allow a chain through the cross-signed certificate of cert
+// with the intermediates below it. Clients enforcing the constraint will
+// reject those chains.
+func PathLenAllowsCrossSign(cert, issuer *x509.Certificate) bool {
+	if !issuer.BasicConstraintsValid || issuer.MaxPathLen < 0 {
+		return true
+	}
+	if cert.MaxPathLen < 0 {
+		return false
+	}
+	return issuer.MaxPathLen > cert.MaxPathLen
+}
+
+// Bundle returns the PEM encoding of the given certificates, skipping the
+// duplicates.
+func Bundle(certs ...*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	seen := make(map[string]bool, len(certs))
+	for _, crt := range certs {
+		fp := x509util.Fingerprint(crt)
+		if seen[fp] {
+			continue
+		}
+		seen[fp] = true
+		buf.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw}))
+	}
+	return buf.Bytes()
+}
+
+// Lifetime returns the time left until the certificate expires and the
+// fraction of its lifetime already used, between 0 and 1.
+func Lifetime(cert *x509.Certificate, now time.Time) (left time.Duration, used float64) {
+	total := cert.NotAfter.Sub(cert.NotBefore)
+	left = cert.NotAfter.Sub(now)
+	switch {
+	case total <= 0 || left <= 0:
+		return left, 1
+	case left >= total:
+		return left, 0
+	default:
+		return left, 1 - float64(left)/float64(total)
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
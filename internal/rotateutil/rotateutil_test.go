@@ -0,0 +1,198 @@
+package rotateutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/minica"
+	"go.step.sm/crypto/pemutil"
+	"go.step.sm/crypto/x509util"
+)
+
+// rootTemplate is the minica root template with a configurable path length.
+const rootTemplate = `{
+	"subject": {{ toJson .Subject }},
+	"issuer": {{ toJson .Subject }},
+	"keyUsage": ["certSign", "crlSign"],
+	"basicConstraints": {
+		"isCA": true,
+		"maxPathLen": %d
+	}
+}`
+
+func newRoot(t *testing.T, name string, maxPathLen int) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	ca, err := minica.New(minica.WithName(name), minica.WithRootTemplate(fmt.Sprintf(rootTemplate, maxPathLen)))
+	require.NoError(t, err)
+	return ca.Root, ca.RootSigner
+}
+
+func newLeaf(t *testing.T, issuer *x509.Certificate, signer crypto.Signer) *x509.Certificate {
+	t.Helper()
+	key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	cert, err := x509util.CreateCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "leaf.example.com"},
+		DNSNames:    []string{"leaf.example.com"},
+		NotBefore:   issuer.NotBefore,
+		NotAfter:    issuer.NotAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, issuer, key.Public(), signer)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestNewIntermediate(t *testing.T) {
+	root, rootKey := newRoot(t, "Test", 1)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	subject := pkix.Name{CommonName: "Intermediate CA", Organization: []string{"Smallstep"}}
+	cert, err := NewIntermediate(subject, key.Public(), root, rootKey, time.Now().Add(10*365*24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "CN=Intermediate CA,O=Smallstep", cert.Subject.String())
+	assert.True(t, cert.IsCA)
+	assert.Equal(t, 0, cert.MaxPathLen)
+	assert.True(t, cert.MaxPathLenZero)
+	assert.Equal(t, root.NotAfter, cert.NotAfter)
+	assert.NoError(t, cert.CheckSignatureFrom(root))
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+	cert, err = NewIntermediate(subject, key.Public(), root, rootKey, notAfter)
+	require.NoError(t, err)
+	assert.Equal(t, notAfter.UTC(), cert.NotAfter)
+
+	_, err = NewIntermediate(subject, key.Public(), root, key, notAfter)
+	assert.Error(t, err)
+}
+
+func TestCrossSign(t *testing.T) {
+	oldRoot, oldKey := newRoot(t, "Old", -1)
+	newRoot, newKey := newRoot(t, "New", 1)
+
+	cross, err := CrossSign(newRoot, oldRoot, oldKey)
+	require.NoError(t, err)
+	assert.Equal(t, newRoot.Subject.String(), cross.Subject.String())
+	assert.Equal(t, newRoot.SubjectKeyId, cross.SubjectKeyId)
+	assert.Equal(t, newRoot.RawSubjectPublicKeyInfo, cross.RawSubjectPublicKeyInfo)
+	assert.Equal(t, oldRoot.NotAfter, cross.NotAfter)
+	assert.Equal(t, 1, cross.MaxPathLen)
+	assert.NoError(t, cross.CheckSignatureFrom(oldRoot))
+
+	// A client trusting only the old root validates the chains of the new
+	// intermediate through the cross-signed certificate.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	intermediate, err := NewIntermediate(pkix.Name{CommonName: "New Intermediate CA"}, key.Public(), newRoot, newKey, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	leaf := newLeaf(t, intermediate, key)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(oldRoot)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+	intermediates.AddCert(cross)
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	assert.NoError(t, err)
+
+	// Errors
+	_, err = CrossSign(leaf, oldRoot, oldKey)
+	assert.Error(t, err)
+	_, err = CrossSign(oldRoot, oldRoot, oldKey)
+	assert.Error(t, err)
+}
+
+func TestCrossSign_constraints(t *testing.T) {
+	oldRoot, oldKey := newRoot(t, "Old", -1)
+	key, err := keyutil.GenerateDefaultSigner()
+	require.NoError(t, err)
+	policy, err := x509.ParseOID("1.3.6.1.4.1.37476.9000.64.100")
+	require.NoError(t, err)
+	now := time.Now()
+	cert, err := x509util.CreateCertificate(&x509.Certificate{
+		Subject:                     pkix.Name{CommonName: "Constrained CA"},
+		NotBefore:                   now,
+		NotAfter:                    now.Add(time.Hour),
+		KeyUsage:                    x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		ExtKeyUsage:                 []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		UnknownExtKeyUsage:          []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 37476, 9000, 64, 101}},
+		BasicConstraintsValid:       true,
+		IsCA:                        true,
+		MaxPathLen:                  -1,
+		Policies:                    []x509.OID{policy},
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         []string{"example.com"},
+		ExcludedDNSDomains:          []string{"internal.example.com"},
+		PermittedEmailAddresses:     []string{"example.com"},
+		ExcludedEmailAddresses:      []string{"admin@example.com"},
+		PermittedURIDomains:         []string{".example.com"},
+		ExcludedURIDomains:          []string{"internal.example.com"},
+	}, &x509.Certificate{Subject: pkix.Name{CommonName: "Constrained CA"}, PublicKey: key.Public()}, key.Public(), key)
+	require.NoError(t, err)
+
+	cross, err := CrossSign(cert, oldRoot, oldKey)
+	require.NoError(t, err)
+	assert.NoError(t, cross.CheckSignatureFrom(oldRoot))
+	assert.Equal(t, cert.ExtKeyUsage, cross.ExtKeyUsage)
+	assert.Equal(t, cert.UnknownExtKeyUsage, cross.UnknownExtKeyUsage)
+	assert.Equal(t, []x509.OID{policy}, cross.Policies)
+	assert.Equal(t, cert.PolicyIdentifiers, cross.PolicyIdentifiers)
+	assert.True(t, cross.PermittedDNSDomainsCritical)
+	assert.Equal(t, []string{"example.com"}, cross.PermittedDNSDomains)
+	assert.Equal(t, []string{"internal.example.com"}, cross.ExcludedDNSDomains)
+	assert.Equal(t, []string{"example.com"}, cross.PermittedEmailAddresses)
+	assert.Equal(t, []string{"admin@example.com"}, cross.ExcludedEmailAddresses)
+	assert.Equal(t, []string{".example.com"}, cross.PermittedURIDomains)
+	assert.Equal(t, []string{"internal.example.com"}, cross.ExcludedURIDomains)
+}
+
+func TestPathLenAllowsCrossSign(t *testing.T) {
+	unlimited, _ := newRoot(t, "Unlimited", -1)
+	one, _ := newRoot(t, "One", 1)
+	two, _ := newRoot(t, "Two", 2)
+
+	assert.True(t, PathLenAllowsCrossSign(one, unlimited))
+	assert.True(t, PathLenAllowsCrossSign(one, two))
+	assert.False(t, PathLenAllowsCrossSign(one, one))
+	assert.False(t, PathLenAllowsCrossSign(unlimited, two))
+}
+
+func TestBundle(t *testing.T) {
+	a, _ := newRoot(t, "A", 1)
+	b, _ := newRoot(t, "B", 1)
+
+	certs, err := pemutil.ParseCertificateBundle(Bundle(a, b, a))
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	assert.True(t, a.Equal(certs[0]))
+	assert.True(t, b.Equal(certs[1]))
+	assert.Empty(t, Bundle())
+}
+
+func TestLifetime(t *testing.T) {
+	now := time.Now()
+	cert := &x509.Certificate{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(3 * time.Hour)}
+
+	left, used := Lifetime(cert, now)
+	assert.Equal(t, 3*time.Hour, left)
+	assert.InDelta(t, 0.25, used, 0.001)
+
+	left, used = Lifetime(cert, now.Add(4*time.Hour))
+	assert.Equal(t, -time.Hour, left)
+	assert.InDelta(t, 1, used, 0.001)
+
+	_, used = Lifetime(cert, now.Add(-2*time.Hour))
+	assert.InDelta(t, 0, used, 0.001)
+}
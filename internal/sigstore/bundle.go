@@ -0,0 +1,249 @@
+// Package sigstore implements the creation and offline verification of
+// Sigstore bundles with message signatures, signed with a public key or a
+// certificate, and optionally timestamped by an RFC 3161 timestamp authority.
+// Transparency log entries are not supported.
+package sigstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Media types of Sigstore bundles.
+const (
+	MediaTypeV01 = "application/vnd.dev.sigstore.bundle+json;version=0.1"
+	MediaTypeV02 = "application/vnd.dev.sigstore.bundle+json;version=0.2"
+	MediaTypeV03 = "application/vnd.dev.sigstore.bundle.v0.3+json"
+)
+
+// Bundle is the JSON representation of a Sigstore bundle with a message
+// signature.
+type Bundle struct {
+	MediaType            string                `json:"mediaType"`
+	VerificationMaterial *VerificationMaterial `json:"verificationMaterial"`
+	MessageSignature     *MessageSignature     `json:"messageSignature"`
+}
+
+// VerificationMaterial contains the key or certificates and the timestamps
+// used to verify a bundle.
+type VerificationMaterial struct {
+	PublicKey                 *PublicKeyIdentifier       `json:"publicKey,omitempty"`
+	X509CertificateChain      *X509CertificateChain      `json:"x509CertificateChain,omitempty"`
+	Certificate               *X509Certificate           `json:"certificate,omitempty"`
+	TlogEntries               []json.RawMessage          `json:"tlogEntries,omitempty"`
+	TimestampVerificationData *TimestampVerificationData `json:"timestampVerificationData,omitempty"`
+}
+
+// PublicKeyIdentifier is a hint to identify the public key used to sign a
+// bundle. The public key is not part of the bundle.
+type PublicKeyIdentifier struct {
+	Hint string `json:"hint,omitempty"`
+}
+
+// X509CertificateChain is a certificate chain, the first certificate is the
+// signing certificate.
+type X509CertificateChain struct {
+	Certificates []X509Certificate `json:"certificates"`
+}
+
+// X509Certificate is a DER encoded certificate.
+type X509Certificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// TimestampVerificationData contains RFC 3161 timestamps.
+type TimestampVerificationData struct {
+	RFC3161Timestamps []RFC3161SignedTimestamp `json:"rfc3161Timestamps,omitempty"`
+}
+
+// RFC3161SignedTimestamp is a DER encoded RFC 3161 timestamp token.
+type RFC3161SignedTimestamp struct {
+	SignedTimestamp []byte `json:"signedTimestamp"`
+}
+
+// MessageSignature is the signature of an artifact.
+type MessageSignature struct {
+	MessageDigest *HashOutput `json:"messageDigest,omitempty"`
+	Signature     []byte      `json:"signature"`
+}
+
+// HashOutput is the digest of an artifact.
+type HashOutput struct {
+	Algorithm string `json:"algorithm"`
+	Digest    []byte `json:"digest"`
+}
+
+var hashAlgorithms = map[crypto.Hash]string{
+	crypto.SHA256: "SHA2_256",
+	crypto.SHA384: "SHA2_384",
+	crypto.SHA512: "SHA2_512",
+}
+
+// hashFor returns the hash function used to sign with the given key.
+func hashFor(pub crypto.PublicKey) (crypto.Hash, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return crypto.SHA256, nil
+		case elliptic.P384():
+			return crypto.SHA384, nil
+		case elliptic.P521():
+			return crypto.SHA512, nil
+		default:
+			return 0, errors.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return crypto.SHA256, nil
+	default:
+		return 0, errors.Errorf("unsupported key type %T", k)
+	}
+}
+
+// digestMessage returns the digest of the message in r. Ed25519 signs the
+// message, so in that case the message is also returned.
+func digestMessage(pub crypto.PublicKey, r io.Reader) (crypto.Hash, []byte, []byte, error) {
+	h, err := hashFor(pub)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	hh := h.New()
+	var message []byte
+	if _, ok := pub.(ed25519.PublicKey); ok {
+		if message, err = io.ReadAll(r); err != nil {
+			return 0, nil, nil, errors.Wrap(err, "error reading message")
+		}
+		hh.Write(message)
+	} else if _, err := io.Copy(hh, r); err != nil {
+		return 0, nil, nil, errors.Wrap(err, "error reading message")
+	}
+	return h, hh.Sum(nil), message, nil
+}
+
+// Hint returns the hint used to identify a public key: the base64 encoding of
+// the SHA-256 digest of the DER encoded public key.
+func Hint(pub crypto.PublicKey) (string, error) {
+	b, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshaling public key")
+	}
+	sum := sha256.Sum256(b)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// Sign signs the message read from r. ECDSA signatures are ASN.1 encoded, RSA
+// signatures use PKCS #1 v1.5, and Ed25519 signatures are computed over the
+// message instead of the digest.
+func Sign(signer crypto.Signer, r io.Reader) (*MessageSignature, error) {
+	h, digest, message, err := digestMessage(signer.Public(), r)
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	if message != nil {
+		sig, err = signer.Sign(rand.Reader, message, crypto.Hash(0))
+	} else {
+		sig, err = signer.Sign(rand.Reader, digest, h)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing message")
+	}
+	return &MessageSignature{
+		MessageDigest: &HashOutput{
+			Algorithm: hashAlgorithms[h],
+			Digest:    digest,
+		},
+		Signature: sig,
+	}, nil
+}
+
+// NewBundle creates a bundle with the given message signature. If the chain
+// is empty the bundle identifies the public key of the signer with a hint.
+// Bundles with a single certificate use the version 0.3 of the format, and
+// bundles with a certificate chain use the version 0.2.
+func NewBundle(sig *MessageSignature, pub crypto.PublicKey, chain []*x509.Certificate, timestamps [][]byte) (*Bundle, error) {
+	vm := &VerificationMaterial{}
+	mediaType := MediaTypeV03
+	switch len(chain) {
+	case 0:
+		hint, err := Hint(pub)
+		if err != nil {
+			return nil, err
+		}
+		vm.PublicKey = &PublicKeyIdentifier{Hint: hint}
+	case 1:
+		vm.Certificate = &X509Certificate{RawBytes: chain[0].Raw}
+	default:
+		mediaType = MediaTypeV02
+		vm.X509CertificateChain = &X509CertificateChain{}
+		for _, crt := range chain {
+			vm.X509CertificateChain.Certificates = append(vm.X509CertificateChain.Certificates, X509Certificate{RawBytes: crt.Raw})
+		}
+	}
+	if len(timestamps) > 0 {
+		vm.TimestampVerificationData = &TimestampVerificationData{}
+		for _, ts := range timestamps {
+			vm.TimestampVerificationData.RFC3161Timestamps = append(vm.TimestampVerificationData.RFC3161Timestamps, RFC3161SignedTimestamp{SignedTimestamp: ts})
+		}
+	}
+	return &Bundle{
+		MediaType:            mediaType,
+		VerificationMaterial: vm,
+		MessageSignature:     sig,
+	}, nil
+}
+
+// Parse parses a JSON encoded bundle.
+func Parse(b []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		return nil, errors.Wrap(err, "error parsing bundle")
+	}
+	switch bundle.MediaType {
+	case MediaTypeV01, MediaTypeV02, MediaTypeV03:
+	default:
+		return nil, errors.Errorf("error parsing bundle: unsupported media type %q", bundle.MediaType)
+	}
+	if bundle.VerificationMaterial == nil {
+		return nil, errors.New("error parsing bundle: verificationMaterial is missing")
+	}
+	if bundle.MessageSignature == nil {
+		return nil, errors.New("error parsing bundle: messageSignature is missing, only message signatures are supported")
+	}
+	return &bundle, nil
+}
+
+// Certificates returns the certificates in the bundle, the first one is the
+// signing certificate.
+func (b *Bundle) Certificates() ([]*x509.Certificate, error) {
+	var raw [][]byte
+	vm := b.VerificationMaterial
+	switch {
+	case vm.Certificate != nil:
+		raw = append(raw, vm.Certificate.RawBytes)
+	case vm.X509CertificateChain != nil:
+		for _, c := range vm.X509CertificateChain.Certificates {
+			raw = append(raw, c.RawBytes)
+		}
+	}
+	var certs []*x509.Certificate
+	for _, der := range raw {
+		crt, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing bundle certificate")
+		}
+		certs = append(certs, crt)
+	}
+	return certs, nil
+}
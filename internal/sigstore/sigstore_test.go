@@ -0,0 +1,280 @@
+package sigstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mozilla.org/pkcs7"
+	"go.step.sm/crypto/minica"
+)
+
+func mustKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+type testCA struct {
+	*minica.CA
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	ca, err := minica.New(minica.WithName(name))
+	require.NoError(t, err)
+	return &testCA{CA: ca}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Root)
+	return pool
+}
+
+func (ca *testCA) sign(t *testing.T, template *x509.Certificate, pub crypto.PublicKey) *x509.Certificate {
+	t.Helper()
+	template.PublicKey = pub
+	crt, err := ca.Sign(template)
+	require.NoError(t, err)
+	return crt
+}
+
+// newTestTSA returns a server implementing an RFC 3161 timestamp authority.
+func newTestTSA(t *testing.T, ca *testCA, genTime time.Time) *httptest.Server {
+	t.Helper()
+	key := mustKey(t)
+	crt := ca.sign(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "Test TSA"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		NotBefore:   genTime.Add(-time.Hour),
+		NotAfter:    genTime.Add(time.Hour),
+	}, key.Public())
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/timestamp-query" {
+			http.Error(w, "bad content type", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req timeStampReq
+		_, err = asn1.Unmarshal(body, &req)
+		require.NoError(t, err)
+
+		info, err := asn1.Marshal(tstInfo{
+			Version:        1,
+			Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+			MessageImprint: req.MessageImprint,
+			SerialNumber:   big.NewInt(1),
+			GenTime:        genTime.UTC().Truncate(time.Second),
+			Nonce:          req.Nonce,
+		})
+		require.NoError(t, err)
+		sd, err := pkcs7.NewSignedData(info)
+		require.NoError(t, err)
+		sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+		require.NoError(t, sd.AddSignerChain(crt, key, []*x509.Certificate{ca.Intermediate}, pkcs7.SignerInfoConfig{}))
+		token, err := sd.Finish()
+		require.NoError(t, err)
+
+		resp, err := asn1.Marshal(timeStampResp{
+			Status:         pkiStatusInfo{Status: 0},
+			TimeStampToken: asn1.RawValue{FullBytes: token},
+		})
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+}
+
+func TestSignVerify_publicKey(t *testing.T) {
+	ecKey := mustKey(t)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	message := []byte("the quick brown fox")
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+		alg    string
+	}{
+		{"P-256", ecKey, "SHA2_256"},
+		{"P-384", p384, "SHA2_384"},
+		{"RSA", rsaKey, "SHA2_256"},
+		{"Ed25519", edKey, "SHA2_256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := Sign(tt.signer, bytes.NewReader(message))
+			require.NoError(t, err)
+			assert.Equal(t, tt.alg, sig.MessageDigest.Algorithm)
+
+			bundle, err := NewBundle(sig, tt.signer.Public(), nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, MediaTypeV03, bundle.MediaType)
+			hint, err := Hint(tt.signer.Public())
+			require.NoError(t, err)
+			assert.Equal(t, hint, bundle.VerificationMaterial.PublicKey.Hint)
+
+			b, err := json.Marshal(bundle)
+			require.NoError(t, err)
+			bundle, err = Parse(b)
+			require.NoError(t, err)
+
+			res, err := bundle.Verify(bytes.NewReader(message), VerifyOptions{PublicKey: tt.signer.Public()})
+			require.NoError(t, err)
+			assert.Nil(t, res.Certificate)
+
+			_, err = bundle.Verify(bytes.NewReader([]byte("the quick brown fix")), VerifyOptions{PublicKey: tt.signer.Public()})
+			assert.Error(t, err)
+			_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{PublicKey: mustKey(t).Public()})
+			assert.EqualError(t, err, "validation failed: public key does not match the hint in the bundle")
+			_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{})
+			assert.EqualError(t, err, "bundle is signed with a public key: a public key is required")
+		})
+	}
+}
+
+func TestSignVerify_certificate(t *testing.T) {
+	ca := newTestCA(t, "Test")
+	key := mustKey(t)
+	leaf := ca.sign(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "signer"},
+		EmailAddresses: []string{"jane@example.com"},
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, key.Public())
+
+	message := []byte("the quick brown fox")
+	sig, err := Sign(key, bytes.NewReader(message))
+	require.NoError(t, err)
+
+	t.Run("chain", func(t *testing.T) {
+		bundle, err := NewBundle(sig, key.Public(), []*x509.Certificate{leaf, ca.Intermediate}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, MediaTypeV02, bundle.MediaType)
+		assert.Len(t, bundle.VerificationMaterial.X509CertificateChain.Certificates, 2)
+
+		res, err := bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool()})
+		require.NoError(t, err)
+		assert.Equal(t, leaf, res.Certificate)
+
+		_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: newTestCA(t, "Other").pool()})
+		assert.ErrorContains(t, err, "validation failed: invalid signing certificate")
+		_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{})
+		assert.EqualError(t, err, "bundle is signed with a certificate: root certificates are required")
+	})
+
+	t.Run("certificate", func(t *testing.T) {
+		bundle, err := NewBundle(sig, key.Public(), []*x509.Certificate{leaf}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, MediaTypeV03, bundle.MediaType)
+
+		_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool()})
+		assert.ErrorContains(t, err, "validation failed: invalid signing certificate")
+
+		intermediates := x509.NewCertPool()
+		intermediates.AddCert(ca.Intermediate)
+		_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool(), Intermediates: intermediates})
+		require.NoError(t, err)
+	})
+}
+
+func TestSignVerify_timestamp(t *testing.T) {
+	ca := newTestCA(t, "Test")
+	tsaCA := newTestCA(t, "TSA")
+	// The timestamp must be within the validity of the minica certificates,
+	// which starts when they are created.
+	genTime := time.Now().Add(30 * time.Second)
+	srv := newTestTSA(t, tsaCA, genTime)
+	defer srv.Close()
+
+	key := mustKey(t)
+	leaf := ca.sign(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "signer"},
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+		NotBefore: genTime.Add(-time.Minute),
+		NotAfter:  genTime.Add(time.Minute),
+	}, key.Public())
+
+	message := []byte("the quick brown fox")
+	sig, err := Sign(key, bytes.NewReader(message))
+	require.NoError(t, err)
+	token, err := RequestTimestamp(srv.URL, sig.Signature)
+	require.NoError(t, err)
+
+	ts, err := ParseTimestamp(token)
+	require.NoError(t, err)
+	assert.True(t, genTime.Truncate(time.Second).Equal(ts.Time))
+
+	bundle, err := NewBundle(sig, key.Public(), []*x509.Certificate{leaf, ca.Intermediate}, [][]byte{token})
+	require.NoError(t, err)
+	res, err := bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool(), TSARoots: tsaCA.pool()})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{ts.Time}, res.Timestamps)
+
+	// Timestamps signed by another authority.
+	_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool(), TSARoots: ca.pool()})
+	assert.ErrorContains(t, err, "validation failed: invalid timestamp signature")
+
+	// Timestamp over another signature.
+	other, err := Sign(key, bytes.NewReader(message))
+	require.NoError(t, err)
+	bundle, err = NewBundle(other, key.Public(), []*x509.Certificate{leaf, ca.Intermediate}, [][]byte{token})
+	require.NoError(t, err)
+	_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool(), TSARoots: tsaCA.pool()})
+	assert.EqualError(t, err, "validation failed: timestamp does not match the signature")
+
+	// No timestamps.
+	bundle, err = NewBundle(sig, key.Public(), []*x509.Certificate{leaf, ca.Intermediate}, nil)
+	require.NoError(t, err)
+	_, err = bundle.Verify(bytes.NewReader(message), VerifyOptions{Roots: ca.pool(), TSARoots: tsaCA.pool()})
+	assert.EqualError(t, err, "validation failed: bundle does not contain timestamps")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"ok", `{"mediaType":"` + MediaTypeV03 + `","verificationMaterial":{"publicKey":{"hint":"foo"}},"messageSignature":{"signature":"AQID"}}`, ""},
+		{"fail json", `{`, "error parsing bundle: unexpected end of JSON input"},
+		{"fail media type", `{"mediaType":"application/json"}`, `error parsing bundle: unsupported media type "application/json"`},
+		{"fail verification material", `{"mediaType":"` + MediaTypeV02 + `"}`, "error parsing bundle: verificationMaterial is missing"},
+		{"fail dsse", `{"mediaType":"` + MediaTypeV03 + `","verificationMaterial":{},"dsseEnvelope":{}}`, "error parsing bundle: messageSignature is missing, only message signatures are supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.json))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
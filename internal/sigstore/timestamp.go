@@ -0,0 +1,195 @@
+package sigstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mozilla.org/pkcs7"
+)
+
+var (
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// maxResponseSize is the maximum size of a timestamp response.
+const maxResponseSize = 1 << 20
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// timeStampReq is the RFC 3161 TimeStampReq.
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+// timeStampResp is the RFC 3161 TimeStampResp.
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// tstInfo is the RFC 3161 TSTInfo.
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional,default:false"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,explicit,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+// Timestamp is a parsed RFC 3161 timestamp token.
+type Timestamp struct {
+	Time         time.Time
+	SerialNumber *big.Int
+	Policy       asn1.ObjectIdentifier
+	Certificates []*x509.Certificate
+	nonce        *big.Int
+	hash         crypto.Hash
+	hashed       []byte
+	p7           *pkcs7.PKCS7
+}
+
+// RequestTimestamp requests a timestamp of the signature to the RFC 3161
+// timestamp authority at the given URL, and returns the DER encoded token.
+func RequestTimestamp(tsaURL string, signature []byte) ([]byte, error) {
+	sum := crypto.SHA256.New()
+	sum.Write(signature)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating nonce")
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: sum.Sum(nil),
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating timestamp request")
+	}
+
+	resp, err := http.Post(tsaURL, "application/timestamp-query", bytes.NewReader(req)) //nolint:gosec // the URL is provided by the user
+	if err != nil {
+		return nil, errors.Wrapf(err, "error requesting timestamp to %s", tsaURL)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading timestamp response from %s", tsaURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error requesting timestamp to %s: %s", tsaURL, resp.Status)
+	}
+
+	var tsr timeStampResp
+	if _, err := asn1.Unmarshal(body, &tsr); err != nil {
+		return nil, errors.Wrap(err, "error parsing timestamp response")
+	}
+	// Status 0 is granted and 1 is granted with modifications.
+	if tsr.Status.Status > 1 {
+		return nil, errors.Errorf("timestamp request was rejected with status %d %v", tsr.Status.Status, tsr.Status.StatusString)
+	}
+	token := tsr.TimeStampToken.FullBytes
+	ts, err := ParseTimestamp(token)
+	if err != nil {
+		return nil, err
+	}
+	if ts.nonce == nil || ts.nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("error validating timestamp response: nonce does not match")
+	}
+	if err := ts.verifyImprint(signature); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ParseTimestamp parses a DER encoded timestamp token.
+func ParseTimestamp(token []byte) (*Timestamp, error) {
+	p7, err := pkcs7.Parse(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing timestamp token")
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(p7.Content, &info); err != nil {
+		return nil, errors.Wrap(err, "error parsing timestamp token info")
+	}
+	ts := &Timestamp{
+		Time:         info.GenTime,
+		SerialNumber: info.SerialNumber,
+		Policy:       info.Policy,
+		Certificates: p7.Certificates,
+		nonce:        info.Nonce,
+		hashed:       info.MessageImprint.HashedMessage,
+		p7:           p7,
+	}
+	switch alg := info.MessageImprint.HashAlgorithm.Algorithm; {
+	case alg.Equal(oidSHA256):
+		ts.hash = crypto.SHA256
+	case alg.Equal(oidSHA384):
+		ts.hash = crypto.SHA384
+	case alg.Equal(oidSHA512):
+		ts.hash = crypto.SHA512
+	default:
+		return nil, errors.Errorf("error parsing timestamp token: unsupported hash algorithm %s", alg)
+	}
+	return ts, nil
+}
+
+func (t *Timestamp) verifyImprint(signature []byte) error {
+	h := t.hash.New()
+	h.Write(signature)
+	if subtle.ConstantTimeCompare(h.Sum(nil), t.hashed) != 1 {
+		return errors.New("validation failed: timestamp does not match the signature")
+	}
+	return nil
+}
+
+// Verify verifies that the timestamp is over the given signature, and that it
+// is signed by a timestamp authority that chains to the given roots.
+func (t *Timestamp) Verify(roots *x509.CertPool, signature []byte) error {
+	if err := t.verifyImprint(signature); err != nil {
+		return err
+	}
+	if err := t.p7.VerifyWithChainAtTime(roots, t.Time); err != nil {
+		return errors.Wrap(err, "validation failed: invalid timestamp signature")
+	}
+	return nil
+}
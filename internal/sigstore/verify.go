@@ -0,0 +1,133 @@
+package sigstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// VerifyOptions are the options used to verify a bundle.
+type VerifyOptions struct {
+	// PublicKey is the key used to verify bundles without certificates.
+	PublicKey crypto.PublicKey
+	// Roots are the root certificates used to verify the signing certificate.
+	Roots *x509.CertPool
+	// Intermediates are additional intermediate certificates used to verify
+	// the signing certificate.
+	Intermediates *x509.CertPool
+	// TSARoots are the root certificates of the timestamp authorities. If not
+	// set, the timestamps in the bundle are ignored.
+	TSARoots *x509.CertPool
+}
+
+// Result is the result of a successful verification.
+type Result struct {
+	// Certificate is the signing certificate, if the bundle has one.
+	Certificate *x509.Certificate
+	// Timestamps are the verified timestamps.
+	Timestamps []time.Time
+}
+
+// Verify verifies the bundle and the message read from r. If the bundle has
+// verified timestamps, the signing certificate must be valid at the time of
+// each timestamp; otherwise it must be valid at the current time.
+func (b *Bundle) Verify(r io.Reader, opts VerifyOptions) (*Result, error) {
+	sig := b.MessageSignature.Signature
+	res := &Result{}
+
+	// Verify timestamps
+	if opts.TSARoots != nil {
+		tvd := b.VerificationMaterial.TimestampVerificationData
+		if tvd == nil || len(tvd.RFC3161Timestamps) == 0 {
+			return nil, errors.New("validation failed: bundle does not contain timestamps")
+		}
+		for _, v := range tvd.RFC3161Timestamps {
+			ts, err := ParseTimestamp(v.SignedTimestamp)
+			if err != nil {
+				return nil, err
+			}
+			if err := ts.Verify(opts.TSARoots, sig); err != nil {
+				return nil, err
+			}
+			res.Timestamps = append(res.Timestamps, ts.Time)
+		}
+	}
+
+	// Get the verification key
+	certs, err := b.Certificates()
+	if err != nil {
+		return nil, err
+	}
+	pub := opts.PublicKey
+	switch {
+	case len(certs) > 0:
+		if opts.Roots == nil {
+			return nil, errors.New("bundle is signed with a certificate: root certificates are required")
+		}
+		intermediates := opts.Intermediates
+		if intermediates == nil {
+			intermediates = x509.NewCertPool()
+		}
+		for _, crt := range certs[1:] {
+			intermediates.AddCert(crt)
+		}
+		times := res.Timestamps
+		if len(times) == 0 {
+			times = []time.Time{time.Now()}
+		}
+		for _, t := range times {
+			if _, err := certs[0].Verify(x509.VerifyOptions{
+				Roots:         opts.Roots,
+				Intermediates: intermediates,
+				CurrentTime:   t,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			}); err != nil {
+				return nil, errors.Wrap(err, "validation failed: invalid signing certificate")
+			}
+		}
+		res.Certificate = certs[0]
+		pub = certs[0].PublicKey
+	case pub == nil:
+		return nil, errors.New("bundle is signed with a public key: a public key is required")
+	default:
+		if pk := b.VerificationMaterial.PublicKey; pk != nil && pk.Hint != "" {
+			if hint, err := Hint(pub); err == nil && hint != pk.Hint {
+				return nil, errors.New("validation failed: public key does not match the hint in the bundle")
+			}
+		}
+	}
+
+	// Verify the message signature
+	h, digest, message, err := digestMessage(pub, r)
+	if err != nil {
+		return nil, err
+	}
+	if md := b.MessageSignature.MessageDigest; md != nil {
+		if md.Algorithm != hashAlgorithms[h] {
+			return nil, errors.Errorf("validation failed: unexpected digest algorithm %s", md.Algorithm)
+		}
+		if subtle.ConstantTimeCompare(md.Digest, digest) != 1 {
+			return nil, errors.New("validation failed: message digest does not match")
+		}
+	}
+	var ok bool
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, digest, sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, h, digest, sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, message, sig)
+	}
+	if !ok {
+		return nil, errors.New("validation failed: invalid signature")
+	}
+	return res, nil
+}
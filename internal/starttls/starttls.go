@@ -0,0 +1,392 @@
+// Package starttls implements the protocol-specific negotiation required to
+// start a TLS handshake on a plaintext connection.
+package starttls
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// upgraders maps the supported protocols to the function that performs the
+// negotiation.
+var upgraders = map[string]func(conn net.Conn, serverName string) error{
+	"smtp":     smtp,
+	"imap":     imap,
+	"pop3":     pop3,
+	"ldap":     ldap,
+	"postgres": postgres,
+	"mysql":    mysql,
+	"xmpp":     xmpp,
+}
+
+// Protocols returns the list of supported protocols.
+func Protocols() []string {
+	protocols := make([]string, 0, len(upgraders))
+	for p := range upgraders {
+		protocols = append(protocols, p)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// IsSupported returns true if the given protocol is supported.
+func IsSupported(protocol string) bool {
+	_, ok := upgraders[protocol]
+	return ok
+}
+
+// Upgrade performs the negotiation of the given protocol on the connection.
+// After Upgrade returns without errors, the TLS handshake can start on the
+// connection.
+func Upgrade(conn net.Conn, protocol, serverName string) error {
+	fn, ok := upgraders[protocol]
+	if !ok {
+		return errors.Errorf("unsupported STARTTLS protocol %q", protocol)
+	}
+	if err := fn(conn, serverName); err != nil {
+		return errors.Wrapf(err, "error negotiating STARTTLS with %s", protocol)
+	}
+	return nil
+}
+
+// unbufferedReader reads one byte at a time, so no data that belongs to the
+// TLS handshake is consumed by a buffered reader.
+type unbufferedReader struct {
+	r io.Reader
+}
+
+func (u *unbufferedReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return u.r.Read(p)
+}
+
+func newTextConn(conn net.Conn) *textproto.Conn {
+	return textproto.NewConn(struct {
+		io.Reader
+		io.Writer
+		io.Closer
+	}{
+		Reader: &unbufferedReader{conn},
+		Writer: conn,
+		Closer: io.NopCloser(nil),
+	})
+}
+
+// smtp implements the STARTTLS extension of RFC 3207.
+func smtp(conn net.Conn, serverName string) error {
+	text := newTextConn(conn)
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return err
+	}
+	if serverName == "" {
+		serverName = "localhost"
+	}
+	if err := text.PrintfLine("EHLO %s", serverName); err != nil {
+		return err
+	}
+	_, msg, err := text.ReadResponse(250)
+	if err != nil {
+		return err
+	}
+	if !hasLine(msg, "STARTTLS") {
+		return errors.New("server does not support STARTTLS")
+	}
+	if err := text.PrintfLine("STARTTLS"); err != nil {
+		return err
+	}
+	_, _, err = text.ReadResponse(220)
+	return err
+}
+
+// imap implements the STARTTLS command of RFC 2595.
+func imap(conn net.Conn, _ string) error {
+	text := newTextConn(conn)
+	line, err := text.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "* OK") {
+		return errors.Errorf("unexpected greeting %q", line)
+	}
+	if err := text.PrintfLine("a001 STARTTLS"); err != nil {
+		return err
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return err
+		}
+		// Skip untagged responses.
+		if !strings.HasPrefix(line, "a001 ") {
+			continue
+		}
+		if !strings.HasPrefix(line, "a001 OK") {
+			return errors.Errorf("server returned %q", strings.TrimPrefix(line, "a001 "))
+		}
+		return nil
+	}
+}
+
+// pop3 implements the STLS command of RFC 2595.
+func pop3(conn net.Conn, _ string) error {
+	text := newTextConn(conn)
+	line, err := text.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return errors.Errorf("unexpected greeting %q", line)
+	}
+	if err := text.PrintfLine("STLS"); err != nil {
+		return err
+	}
+	if line, err = text.ReadLine(); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return errors.Errorf("server returned %q", line)
+	}
+	return nil
+}
+
+// hasLine returns true if one of the lines of a response starts with the given
+// keyword.
+func hasLine(msg, keyword string) bool {
+	for _, line := range strings.Split(msg, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && strings.EqualFold(fields[0], keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// ldapStartTLSOID is the object identifier of the LDAP StartTLS extended
+// operation defined in RFC 4511.
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// ldap implements the StartTLS extended operation of RFC 4511.
+func ldap(conn net.Conn, _ string) error {
+	// ExtendedRequest ::= [APPLICATION 23] SEQUENCE {
+	//      requestName      [0] LDAPOID }
+	op, err := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassApplication,
+		Tag:        23,
+		IsCompound: true,
+		Bytes:      append([]byte{0x80, byte(len(ldapStartTLSOID))}, ldapStartTLSOID...),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := asn1.Marshal(ldapMessage{
+		MessageID: 1,
+		Op:        asn1.RawValue{FullBytes: op},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	b, err := readTLV(conn)
+	if err != nil {
+		return err
+	}
+	var resp ldapMessage
+	if _, err := asn1.Unmarshal(b, &resp); err != nil {
+		return errors.Wrap(err, "error parsing response")
+	}
+	// ExtendedResponse ::= [APPLICATION 24] SEQUENCE {
+	//      COMPONENTS OF LDAPResult, ... }
+	if resp.Op.Class != asn1.ClassApplication || resp.Op.Tag != 24 {
+		return errors.Errorf("unexpected response with tag %d", resp.Op.Tag)
+	}
+	var resultCode asn1.Enumerated
+	if _, err := asn1.Unmarshal(resp.Op.Bytes, &resultCode); err != nil {
+		return errors.Wrap(err, "error parsing response")
+	}
+	if resultCode != 0 {
+		return errors.Errorf("server returned result code %d", resultCode)
+	}
+	return nil
+}
+
+type ldapMessage struct {
+	MessageID int
+	Op        asn1.RawValue
+}
+
+// readTLV reads a single DER encoded element from the reader.
+func readTLV(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 {
+			return nil, errors.New("unsupported length encoding")
+		}
+		lb := make([]byte, n)
+		if _, err := io.ReadFull(r, lb); err != nil {
+			return nil, err
+		}
+		header = append(header, lb...)
+		length = 0
+		for _, b := range lb {
+			length = length<<8 | int(b)
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+// postgresSSLRequestCode is the code of the SSLRequest message of the
+// PostgreSQL protocol.
+const postgresSSLRequestCode = 80877103
+
+// postgres implements the SSLRequest message of the PostgreSQL protocol.
+func postgres(conn net.Conn, _ string) error {
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	switch resp[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return errors.New("server does not support SSL")
+	default:
+		return errors.Errorf("unexpected response %q", resp[0])
+	}
+}
+
+// MySQL capability flags.
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientSecureConnection = 0x00008000
+)
+
+// mysql implements the SSLRequest packet of the MySQL client/server protocol.
+func mysql(conn net.Conn, _ string) error {
+	seq, payload, err := readMySQLPacket(conn)
+	if err != nil {
+		return err
+	}
+	if len(payload) > 0 && payload[0] == 0xff {
+		if len(payload) > 3 {
+			return errors.Errorf("server returned %q", payload[3:])
+		}
+		return errors.New("server returned an error")
+	}
+	if len(payload) == 0 || payload[0] != 10 {
+		return errors.New("unsupported protocol version")
+	}
+	// Initial handshake: protocol version, NUL terminated server version,
+	// connection id, auth plugin data, filler and the lower capability flags.
+	i := bytes.IndexByte(payload[1:], 0)
+	if i < 0 || len(payload) < 1+i+1+4+8+1+2 {
+		return errors.New("malformed handshake packet")
+	}
+	offset := 1 + i + 1 + 4 + 8 + 1
+	capabilities := binary.LittleEndian.Uint16(payload[offset:])
+	if capabilities&mysqlClientSSL == 0 {
+		return errors.New("server does not support SSL")
+	}
+
+	// SSLRequest: capability flags, max packet size, character set and 23
+	// bytes of filler.
+	req := make([]byte, 4+32)
+	req[0] = 32
+	req[3] = seq + 1
+	binary.LittleEndian.PutUint32(req[4:], mysqlClientLongPassword|mysqlClientProtocol41|mysqlClientSSL|mysqlClientSecureConnection)
+	binary.LittleEndian.PutUint32(req[8:], 1<<24-1)
+	req[12] = 45 // utf8mb4_general_ci
+	_, err = conn.Write(req)
+	return err
+}
+
+// readMySQLPacket reads a packet and returns its sequence id and payload.
+func readMySQLPacket(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[3], payload, nil
+}
+
+// xmppMaxStanza is the maximum size of the data read while negotiating
+// STARTTLS with an XMPP server.
+const xmppMaxStanza = 64 * 1024
+
+// xmpp implements the STARTTLS negotiation of RFC 6120.
+func xmpp(conn net.Conn, serverName string) error {
+	if _, err := fmt.Fprintf(conn, "<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", serverName); err != nil {
+		return err
+	}
+	features, err := readUntil(conn, "</stream:features>")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(features, "<starttls") {
+		return errors.New("server does not support STARTTLS")
+	}
+	if _, err := io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	resp, err := readUntil(conn, "/>", "</proceed>", "</failure>")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(resp, "<proceed") {
+		return errors.New("server refused STARTTLS")
+	}
+	return nil
+}
+
+// readUntil reads one byte at a time until the data read ends with one of the
+// given suffixes.
+func readUntil(r io.Reader, suffixes ...string) (string, error) {
+	var buf []byte
+	b := make([]byte, 1)
+	for len(buf) < xmppMaxStanza {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		buf = append(buf, b[0])
+		for _, suffix := range suffixes {
+			if bytes.HasSuffix(buf, []byte(suffix)) {
+				return string(buf), nil
+			}
+		}
+	}
+	return "", errors.New("response is too large")
+}
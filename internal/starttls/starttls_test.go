@@ -0,0 +1,217 @@
+package starttls
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipe returns the client side of a connection served by the given function.
+func pipe(t *testing.T, serve func(conn net.Conn)) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	go func() {
+		defer server.Close()
+		serve(server)
+	}()
+	return client
+}
+
+func TestUpgrade_smtp(t *testing.T) {
+	smtpServer := func(extensions ...string) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			r := bufio.NewReader(conn)
+			io.WriteString(conn, "220-mail.example.com ESMTP\r\n220 ready\r\n")
+			line, _ := r.ReadString('\n')
+			if !strings.HasPrefix(line, "EHLO ") {
+				io.WriteString(conn, "500 unexpected\r\n")
+				return
+			}
+			io.WriteString(conn, "250-mail.example.com\r\n")
+			for _, ext := range extensions {
+				io.WriteString(conn, "250-"+ext+"\r\n")
+			}
+			io.WriteString(conn, "250 8BITMIME\r\n")
+			if line, _ = r.ReadString('\n'); line == "STARTTLS\r\n" {
+				io.WriteString(conn, "220 go ahead\r\n")
+				// The first byte of the TLS handshake must not be consumed.
+				io.WriteString(conn, "X")
+			}
+		}
+	}
+
+	conn := pipe(t, smtpServer("PIPELINING", "STARTTLS"))
+	require.NoError(t, Upgrade(conn, "smtp", "example.com"))
+	b := make([]byte, 1)
+	_, err := io.ReadFull(conn, b)
+	require.NoError(t, err)
+	assert.Equal(t, "X", string(b))
+
+	conn = pipe(t, smtpServer("PIPELINING"))
+	assert.ErrorContains(t, Upgrade(conn, "smtp", ""), "server does not support STARTTLS")
+}
+
+func TestUpgrade_ldap(t *testing.T) {
+	ldapServer := func(resultCode byte) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			req, err := readTLV(conn)
+			if err != nil {
+				return
+			}
+			// LDAPMessage with messageID 1 and the StartTLS extended request.
+			if hex.EncodeToString(req) != "301d02010177188016"+hex.EncodeToString([]byte(ldapStartTLSOID)) {
+				return
+			}
+			conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, resultCode, 0x04, 0x00, 0x04, 0x00})
+		}
+	}
+
+	conn := pipe(t, ldapServer(0))
+	assert.NoError(t, Upgrade(conn, "ldap", "example.com"))
+
+	conn = pipe(t, ldapServer(2))
+	assert.ErrorContains(t, Upgrade(conn, "ldap", "example.com"), "server returned result code 2")
+}
+
+func TestUpgrade_imap(t *testing.T) {
+	imapServer := func(result string) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			r := bufio.NewReader(conn)
+			io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n")
+			if line, _ := r.ReadString('\n'); line == "a001 STARTTLS\r\n" {
+				io.WriteString(conn, "* NOTE untagged\r\na001 "+result+"\r\n")
+			}
+		}
+	}
+
+	conn := pipe(t, imapServer("OK Begin TLS negotiation now"))
+	assert.NoError(t, Upgrade(conn, "imap", "example.com"))
+
+	conn = pipe(t, imapServer("BAD not supported"))
+	assert.ErrorContains(t, Upgrade(conn, "imap", "example.com"), `server returned "BAD not supported"`)
+}
+
+func TestUpgrade_pop3(t *testing.T) {
+	pop3Server := func(result string) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			r := bufio.NewReader(conn)
+			io.WriteString(conn, "+OK POP3 ready\r\n")
+			if line, _ := r.ReadString('\n'); line == "STLS\r\n" {
+				io.WriteString(conn, result+"\r\n")
+			}
+		}
+	}
+
+	conn := pipe(t, pop3Server("+OK begin TLS"))
+	assert.NoError(t, Upgrade(conn, "pop3", "example.com"))
+
+	conn = pipe(t, pop3Server("-ERR command not supported"))
+	assert.ErrorContains(t, Upgrade(conn, "pop3", "example.com"), `server returned "-ERR command not supported"`)
+}
+
+func TestUpgrade_postgres(t *testing.T) {
+	postgresServer := func(resp byte) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			req := make([]byte, 8)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			if hex.EncodeToString(req) == "0000000804d2162f" {
+				conn.Write([]byte{resp})
+			}
+		}
+	}
+
+	conn := pipe(t, postgresServer('S'))
+	assert.NoError(t, Upgrade(conn, "postgres", "example.com"))
+
+	conn = pipe(t, postgresServer('N'))
+	assert.ErrorContains(t, Upgrade(conn, "postgres", "example.com"), "server does not support SSL")
+}
+
+func TestUpgrade_mysql(t *testing.T) {
+	mysqlServer := func(capabilities uint16) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			payload := []byte{10}
+			payload = append(payload, "8.0.36\x00"...)
+			payload = append(payload, 1, 0, 0, 0)        // connection id
+			payload = append(payload, "12345678\x00"...) // auth plugin data and filler
+			payload = append(payload, byte(capabilities), byte(capabilities>>8))
+			packet := append([]byte{byte(len(payload)), 0, 0, 0}, payload...)
+			conn.Write(packet)
+
+			header := make([]byte, 4)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			if header[0] != 32 || header[3] != 1 {
+				return
+			}
+			req := make([]byte, 32)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			if req[1]&0x08 != 0 {
+				// The TLS handshake would start here.
+				conn.Write([]byte("X"))
+			}
+		}
+	}
+
+	conn := pipe(t, mysqlServer(0xffff))
+	require.NoError(t, Upgrade(conn, "mysql", "example.com"))
+	b := make([]byte, 1)
+	_, err := io.ReadFull(conn, b)
+	require.NoError(t, err)
+	assert.Equal(t, "X", string(b))
+
+	conn = pipe(t, mysqlServer(0xf7ff))
+	assert.ErrorContains(t, Upgrade(conn, "mysql", "example.com"), "server does not support SSL")
+}
+
+func TestUpgrade_xmpp(t *testing.T) {
+	xmppServer := func(features, resp string) func(conn net.Conn) {
+		return func(conn net.Conn) {
+			header, err := readUntil(conn, "version='1.0'>")
+			if err != nil || !strings.Contains(header, "to='example.com'") {
+				return
+			}
+			io.WriteString(conn, "<?xml version='1.0'?><stream:stream from='example.com' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>")
+			io.WriteString(conn, "<stream:features>"+features+"</stream:features>")
+			if _, err := readUntil(conn, "/>"); err == nil {
+				io.WriteString(conn, resp)
+			}
+		}
+	}
+
+	starttls := "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls>"
+	conn := pipe(t, xmppServer(starttls, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"))
+	assert.NoError(t, Upgrade(conn, "xmpp", "example.com"))
+
+	conn = pipe(t, xmppServer(starttls, "<failure xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"))
+	assert.ErrorContains(t, Upgrade(conn, "xmpp", "example.com"), "server refused STARTTLS")
+
+	conn = pipe(t, xmppServer("<bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'/>", ""))
+	assert.ErrorContains(t, Upgrade(conn, "xmpp", "example.com"), "server does not support STARTTLS")
+}
+
+func TestUpgrade_unsupported(t *testing.T) {
+	conn := pipe(t, func(net.Conn) {})
+	assert.ErrorContains(t, Upgrade(conn, "foo", "example.com"), `unsupported STARTTLS protocol "foo"`)
+}
+
+func TestProtocols(t *testing.T) {
+	assert.Equal(t, []string{"imap", "ldap", "mysql", "pop3", "postgres", "smtp", "xmpp"}, Protocols())
+	assert.True(t, IsSupported("smtp"))
+	assert.False(t, IsSupported("smtps"))
+}
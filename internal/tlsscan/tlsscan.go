@@ -0,0 +1,454 @@
+// Package tlsscan implements a scanner that reports the TLS configuration of
+// a server: the supported protocol versions and cipher suites, ALPN protocols,
+// OCSP stapling, signed certificate timestamps, and the problems of the
+// certificate chain.
+package tlsscan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/smallstep/cli/internal/ctutil"
+	"github.com/smallstep/cli/internal/ocsputil"
+	"github.com/smallstep/cli/internal/starttls"
+)
+
+// DefaultALPN is the list of ALPN protocols probed by default.
+var DefaultALPN = []string{"h2", "http/1.1"}
+
+// versions is the list of protocol versions probed, from the newest.
+var versions = []uint16{
+	tls.VersionTLS13,
+	tls.VersionTLS12,
+	tls.VersionTLS11,
+	tls.VersionTLS10,
+}
+
+// Options are the options of a scan.
+type Options struct {
+	// Address is the host:port of the server.
+	Address string
+	// ServerName is the name sent in the SNI extension, and used to verify the
+	// certificate. Defaults to the host in the address.
+	ServerName string
+	// StartTLS is the protocol used to upgrade the connection before the TLS
+	// handshake, if any.
+	StartTLS string
+	// Roots is the pool used to verify the certificate chain. If nil, the
+	// system pool is used.
+	Roots *x509.CertPool
+	// ALPN is the list of ALPN protocols to probe. Defaults to DefaultALPN.
+	ALPN []string
+	// Timeout is the timeout of each connection. Defaults to 10 seconds.
+	Timeout time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Report is the result of a scan.
+type Report struct {
+	Address      string        `json:"address"`
+	ServerName   string        `json:"serverName,omitempty"`
+	StartTLS     string        `json:"startTLS,omitempty"`
+	Versions     []Version     `json:"versions"`
+	ALPN         []string      `json:"alpn"`
+	Certificates []Certificate `json:"certificates"`
+	Verified     bool          `json:"verified"`
+	VerifyError  string        `json:"verifyError,omitempty"`
+	ChainIssues  []string      `json:"chainIssues,omitempty"`
+	SNI          *SNI          `json:"sni,omitempty"`
+	OCSP         *OCSP         `json:"ocsp,omitempty"`
+	SCTs         []SCT         `json:"scts,omitempty"`
+}
+
+// Version is the result of probing a protocol version.
+type Version struct {
+	Name         string   `json:"name"`
+	Supported    bool     `json:"supported"`
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+}
+
+// Certificate contains the details of a certificate sent by the server.
+type Certificate struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Fingerprint string    `json:"fingerprint"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	DaysLeft    int       `json:"daysLeft"`
+	Expired     bool      `json:"expired"`
+}
+
+// SNI is the result of comparing the certificates sent with and without the
+// server name indication extension.
+type SNI struct {
+	Required    bool         `json:"required"`
+	Different   bool         `json:"different"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// OCSP contains the details of the stapled OCSP response.
+type OCSP struct {
+	Stapled    bool       `json:"stapled"`
+	Status     string     `json:"status,omitempty"`
+	ThisUpdate *time.Time `json:"thisUpdate,omitempty"`
+	NextUpdate *time.Time `json:"nextUpdate,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// SCT contains the details of a signed certificate timestamp.
+type SCT struct {
+	Source    string    `json:"source"`
+	LogID     string    `json:"logID"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type scanner struct {
+	Options
+	host string
+}
+
+// Scan connects to the server multiple times and returns the report of its
+// TLS configuration.
+func Scan(ctx context.Context, opts Options) (*Report, error) {
+	host, _, err := net.SplitHostPort(opts.Address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing address %s", opts.Address)
+	}
+	if opts.ServerName == "" {
+		opts.ServerName = host
+	}
+	if len(opts.ALPN) == 0 {
+		opts.ALPN = DefaultALPN
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	s := &scanner{Options: opts, host: host}
+
+	// Connect with the default configuration, to get the certificates.
+	state, err := s.handshake(ctx, &tls.Config{MinVersion: tls.VersionTLS10})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{
+		Address:    opts.Address,
+		ServerName: opts.ServerName,
+		StartTLS:   opts.StartTLS,
+		ALPN:       []string{},
+	}
+	now := opts.Now()
+	for _, crt := range state.PeerCertificates {
+		r.Certificates = append(r.Certificates, newCertificate(crt, now))
+	}
+	r.Verified, r.VerifyError = verify(state.PeerCertificates, opts.ServerName, opts.Roots, now)
+	r.ChainIssues = chainIssues(state.PeerCertificates, isAnchored(state.PeerCertificates, opts.Roots, now), now)
+	r.OCSP = newOCSP(state)
+	r.SCTs = scts(state)
+
+	for _, v := range versions {
+		r.Versions = append(r.Versions, s.probeVersion(ctx, v))
+	}
+	for _, proto := range opts.ALPN {
+		st, err := s.handshake(ctx, &tls.Config{
+			MinVersion: tls.VersionTLS10,
+			NextProtos: []string{proto},
+		})
+		if err == nil && st.NegotiatedProtocol == proto {
+			r.ALPN = append(r.ALPN, proto)
+		}
+	}
+	if net.ParseIP(opts.ServerName) == nil {
+		r.SNI = s.probeSNI(ctx, state.PeerCertificates[0], now)
+	}
+	return r, nil
+}
+
+// handshake connects to the server using the configured server name and
+// returns the connection state.
+func (s *scanner) handshake(ctx context.Context, config *tls.Config) (*tls.ConnectionState, error) {
+	config.ServerName = s.ServerName
+	return s.dial(ctx, config)
+}
+
+// dial connects to the server and returns the connection state. The
+// certificate is not verified.
+func (s *scanner) dial(ctx context.Context, config *tls.Config) (*tls.ConnectionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	d := &net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", s.Address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", s.Address)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.StartTLS != "" {
+		if err := starttls.Upgrade(conn, s.StartTLS, s.host); err != nil {
+			return nil, err
+		}
+	}
+
+	config.InsecureSkipVerify = true //nolint:gosec // the scanner verifies the chain later
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", s.Address)
+	}
+	state := tlsConn.ConnectionState()
+	return &state, nil
+}
+
+// probeVersion checks if a version is supported, and the cipher suites
+// supported with it. The cipher suites of TLS 1.3 cannot be configured, so
+// only the negotiated one is reported.
+func (s *scanner) probeVersion(ctx context.Context, version uint16) Version {
+	v := Version{Name: tls.VersionName(version)}
+	config := func(suites []uint16) *tls.Config {
+		return &tls.Config{
+			MinVersion:   version,
+			MaxVersion:   version,
+			CipherSuites: suites,
+		}
+	}
+
+	var suites []*tls.CipherSuite
+	if version != tls.VersionTLS13 {
+		for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			if supportsVersion(cs, version) {
+				suites = append(suites, cs)
+			}
+		}
+	}
+	ids := make([]uint16, len(suites))
+	for i, cs := range suites {
+		ids[i] = cs.ID
+	}
+
+	state, err := s.handshake(ctx, config(ids))
+	if err != nil {
+		return v
+	}
+	v.Supported = true
+	if version == tls.VersionTLS13 {
+		v.CipherSuites = []string{tls.CipherSuiteName(state.CipherSuite)}
+		return v
+	}
+	for _, cs := range suites {
+		if _, err := s.handshake(ctx, config([]uint16{cs.ID})); err == nil {
+			v.CipherSuites = append(v.CipherSuites, cs.Name)
+		}
+	}
+	return v
+}
+
+func supportsVersion(cs *tls.CipherSuite, version uint16) bool {
+	for _, v := range cs.SupportedVersions {
+		if v == version && v != tls.VersionTLS13 {
+			return true
+		}
+	}
+	return false
+}
+
+// probeSNI connects without the server name indication and compares the
+// certificate with the one sent with it.
+func (s *scanner) probeSNI(ctx context.Context, leaf *x509.Certificate, now time.Time) *SNI {
+	// Go does not send the SNI extension if the server name is empty.
+	state, err := s.dial(ctx, &tls.Config{MinVersion: tls.VersionTLS10})
+	if err != nil {
+		return &SNI{Required: true, Error: err.Error()}
+	}
+	crt := state.PeerCertificates[0]
+	sni := &SNI{Different: !bytes.Equal(crt.Raw, leaf.Raw)}
+	if sni.Different {
+		c := newCertificate(crt, now)
+		sni.Certificate = &c
+	}
+	return sni
+}
+
+func newCertificate(crt *x509.Certificate, now time.Time) Certificate {
+	sum := sha256.Sum256(crt.Raw)
+	return Certificate{
+		Subject:     crt.Subject.String(),
+		Issuer:      crt.Issuer.String(),
+		Fingerprint: hex.EncodeToString(sum[:]),
+		NotBefore:   crt.NotBefore,
+		NotAfter:    crt.NotAfter,
+		DaysLeft:    int(crt.NotAfter.Sub(now).Hours() / 24),
+		Expired:     now.After(crt.NotAfter),
+	}
+}
+
+func verify(chain []*x509.Certificate, serverName string, roots *x509.CertPool, now time.Time) (bool, string) {
+	intermediates := x509.NewCertPool()
+	for _, crt := range chain[1:] {
+		intermediates.AddCert(crt)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
+
+// isAnchored checks if the last certificate in the chain is a trusted root or
+// is issued by one. The server name and the expiration of the certificate are
+// ignored, those are reported separately.
+func isAnchored(chain []*x509.Certificate, roots *x509.CertPool, now time.Time) bool {
+	crt := chain[len(chain)-1]
+	if now.After(crt.NotAfter) || now.Before(crt.NotBefore) {
+		now = crt.NotBefore.Add(crt.NotAfter.Sub(crt.NotBefore) / 2)
+	}
+	_, err := crt.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+func isIssuedBy(crt, issuer *x509.Certificate) bool {
+	return bytes.Equal(crt.RawIssuer, issuer.RawSubject) && crt.CheckSignatureFrom(issuer) == nil
+}
+
+func isSelfSigned(crt *x509.Certificate) bool {
+	return bytes.Equal(crt.RawIssuer, crt.RawSubject) && crt.CheckSignatureFrom(crt) == nil
+}
+
+// chainIssues returns the problems found in the certificate chain sent by the
+// server. The anchored parameter indicates whether the last certificate is
+// issued by a trusted root.
+func chainIssues(chain []*x509.Certificate, anchored bool, now time.Time) []string {
+	var issues []string
+	if chain[0].IsCA {
+		issues = append(issues, "the first certificate is a CA certificate")
+	}
+
+	seen := make(map[string]bool)
+	for i, crt := range chain {
+		if seen[string(crt.Raw)] {
+			issues = append(issues, "certificate "+crt.Subject.String()+" is sent more than once")
+			continue
+		}
+		seen[string(crt.Raw)] = true
+
+		if now.After(crt.NotAfter) {
+			issues = append(issues, "certificate "+crt.Subject.String()+" has expired")
+		} else if now.Before(crt.NotBefore) {
+			issues = append(issues, "certificate "+crt.Subject.String()+" is not yet valid")
+		}
+
+		if isSelfSigned(crt) {
+			if i > 0 {
+				issues = append(issues, "the chain includes the root certificate "+crt.Subject.String())
+			}
+			continue
+		}
+		if i+1 < len(chain) && isIssuedBy(crt, chain[i+1]) {
+			continue
+		}
+		var found bool
+		for j, issuer := range chain {
+			if j != i && isIssuedBy(crt, issuer) {
+				found = true
+				break
+			}
+		}
+		switch {
+		case found:
+			issues = append(issues, "the chain is not in order: the issuer of "+crt.Subject.String()+" is not the next certificate")
+		case i+1 < len(chain):
+			issues = append(issues, "the next certificate after "+crt.Subject.String()+" is not its issuer")
+		case !anchored:
+			issues = append(issues, "the issuer of "+crt.Subject.String()+" ("+crt.Issuer.String()+") was not sent and is not a trusted root")
+		}
+	}
+	return issues
+}
+
+func newOCSP(state *tls.ConnectionState) *OCSP {
+	if len(state.OCSPResponse) == 0 {
+		return &OCSP{}
+	}
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	o := &OCSP{Stapled: true}
+	resp, err := ocsputil.ParseResponse(state.OCSPResponse, state.PeerCertificates[0], issuer)
+	if err != nil {
+		o.Error = err.Error()
+		return o
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		o.Status = "good"
+	case ocsp.Revoked:
+		o.Status = "revoked"
+	default:
+		o.Status = "unknown"
+	}
+	o.ThisUpdate = &resp.ThisUpdate
+	if !resp.NextUpdate.IsZero() {
+		o.NextUpdate = &resp.NextUpdate
+	}
+	return o
+}
+
+// scts returns the SCTs sent in the TLS extension, embedded in the
+// certificate, and in the stapled OCSP response.
+func scts(state *tls.ConnectionState) []SCT {
+	var ret []SCT
+	add := func(source string, list []*ctutil.SCT) {
+		for _, sct := range list {
+			ret = append(ret, SCT{
+				Source:    source,
+				LogID:     sct.LogIDString(),
+				Timestamp: sct.Timestamp,
+			})
+		}
+	}
+	for _, b := range state.SignedCertificateTimestamps {
+		if sct, err := ctutil.ParseSCT(b); err == nil {
+			add("tls", []*ctutil.SCT{sct})
+		}
+	}
+	if list, err := ctutil.EmbeddedSCTs(state.PeerCertificates[0]); err == nil {
+		add("certificate", list)
+	}
+	if len(state.OCSPResponse) > 0 {
+		if resp, err := ocsp.ParseResponse(state.OCSPResponse, nil); err == nil {
+			for _, ext := range resp.Extensions {
+				if ext.Id.Equal(ctutil.OIDOCSPSCTList) {
+					if list, err := ctutil.ParseExtension(ext.Value); err == nil {
+						add("ocsp", list)
+					}
+				}
+			}
+		}
+	}
+	return ret
+}
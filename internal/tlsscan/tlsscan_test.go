@@ -0,0 +1,233 @@
+package tlsscan
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+type testPKI struct {
+	*minica.CA
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	ca, err := minica.New()
+	require.NoError(t, err)
+	return &testPKI{CA: ca}
+}
+
+func (p *testPKI) leaf(t *testing.T, name string, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := p.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: name},
+		DNSNames:  []string{name},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  notAfter,
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+	return tls.Certificate{
+		Certificate: [][]byte{leaf.Raw, p.Intermediate.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+func (p *testPKI) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.Root)
+	return pool
+}
+
+// serve starts a TLS server that closes the connections after the handshake.
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	p := newTestPKI(t)
+	cert := p.leaf(t, "example.com", time.Now().Add(time.Hour))
+	defaultCert := p.leaf(t, "default.example.com", time.Now().Add(time.Hour))
+	addr := serve(t, &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		},
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "example.com" {
+				return &cert, nil
+			}
+			return &defaultCert, nil
+		},
+	})
+
+	r, err := Scan(context.Background(), Options{
+		Address:    addr,
+		ServerName: "example.com",
+		Roots:      p.roots(),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Version{
+		{Name: "TLS 1.3"},
+		{Name: "TLS 1.2", Supported: true, CipherSuites: []string{
+			"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+		}},
+		{Name: "TLS 1.1"},
+		{Name: "TLS 1.0"},
+	}, r.Versions)
+	assert.Equal(t, []string{"http/1.1"}, r.ALPN)
+	assert.True(t, r.Verified)
+	assert.Empty(t, r.VerifyError)
+	assert.Empty(t, r.ChainIssues)
+	if assert.Len(t, r.Certificates, 2) {
+		assert.Equal(t, "CN=example.com", r.Certificates[0].Subject)
+		assert.Equal(t, "CN=MiniCA Intermediate CA", r.Certificates[1].Subject)
+		assert.False(t, r.Certificates[0].Expired)
+		assert.Equal(t, 0, r.Certificates[0].DaysLeft)
+	}
+	if assert.NotNil(t, r.SNI) {
+		assert.True(t, r.SNI.Different)
+		assert.Equal(t, "CN=default.example.com", r.SNI.Certificate.Subject)
+	}
+	assert.Equal(t, &OCSP{}, r.OCSP)
+	assert.Empty(t, r.SCTs)
+
+	// Unknown root and the wrong host name.
+	r, err = Scan(context.Background(), Options{
+		Address:    addr,
+		ServerName: "foo.example.com",
+		Roots:      x509.NewCertPool(),
+		ALPN:       []string{"h2"},
+	})
+	require.NoError(t, err)
+	assert.False(t, r.Verified)
+	assert.NotEmpty(t, r.VerifyError)
+	assert.Equal(t, []string{}, r.ALPN)
+	assert.Equal(t, []string{
+		"the issuer of CN=MiniCA Intermediate CA (CN=MiniCA Root CA) was not sent and is not a trusted root",
+	}, r.ChainIssues)
+	assert.False(t, r.SNI.Different)
+
+	// The wrong host name does not affect the chain issues.
+	r, err = Scan(context.Background(), Options{
+		Address:    addr,
+		ServerName: "foo.example.com",
+		Roots:      p.roots(),
+	})
+	require.NoError(t, err)
+	assert.False(t, r.Verified)
+	assert.Empty(t, r.ChainIssues)
+
+	_, err = Scan(context.Background(), Options{Address: "127.0.0.1"})
+	assert.Error(t, err)
+}
+
+func TestScan_tls13(t *testing.T) {
+	p := newTestPKI(t)
+	cert := p.leaf(t, "example.com", time.Now().Add(time.Hour))
+	addr := serve(t, &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+	})
+
+	r, err := Scan(context.Background(), Options{
+		Address:    addr,
+		ServerName: "example.com",
+		Roots:      p.roots(),
+	})
+	require.NoError(t, err)
+	assert.True(t, r.Versions[0].Supported)
+	assert.Len(t, r.Versions[0].CipherSuites, 1)
+	for _, v := range r.Versions[1:] {
+		assert.False(t, v.Supported, v.Name)
+	}
+	assert.False(t, r.SNI.Different)
+}
+
+func TestScan_timeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = Scan(context.Background(), Options{
+		Address: l.Addr().String(),
+		Timeout: 100 * time.Millisecond,
+	})
+	assert.Error(t, err)
+}
+
+func Test_chainIssues(t *testing.T) {
+	p := newTestPKI(t)
+	cert := p.leaf(t, "example.com", time.Now().Add(-time.Minute))
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		chain    []*x509.Certificate
+		anchored bool
+		want     []string
+	}{
+		{"ok", []*x509.Certificate{cert.Leaf, p.Intermediate}, true, []string{
+			"certificate CN=example.com has expired",
+		}},
+		{"root", []*x509.Certificate{p.Intermediate, p.Root}, true, []string{
+			"the first certificate is a CA certificate",
+			"the chain includes the root certificate CN=MiniCA Root CA",
+		}},
+		{"order", []*x509.Certificate{cert.Leaf, p.Root, p.Intermediate}, true, []string{
+			"certificate CN=example.com has expired",
+			"the chain is not in order: the issuer of CN=example.com is not the next certificate",
+			"the chain includes the root certificate CN=MiniCA Root CA",
+			"the chain is not in order: the issuer of CN=MiniCA Intermediate CA is not the next certificate",
+		}},
+		{"duplicate", []*x509.Certificate{p.Intermediate, p.Intermediate}, false, []string{
+			"the first certificate is a CA certificate",
+			"the next certificate after CN=MiniCA Intermediate CA is not its issuer",
+			"certificate CN=MiniCA Intermediate CA is sent more than once",
+		}},
+		{"missing", []*x509.Certificate{cert.Leaf}, false, []string{
+			"certificate CN=example.com has expired",
+			"the issuer of CN=example.com (CN=MiniCA Intermediate CA) was not sent and is not a trusted root",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, chainIssues(tt.chain, tt.anchored, now))
+		})
+	}
+}
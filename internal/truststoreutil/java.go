@@ -0,0 +1,117 @@
+package truststoreutil
+
+import (
+	"bufio"
+	"crypto/x509"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/truststore"
+)
+
+// JavaStore is the cacerts key store of a Java runtime.
+type JavaStore struct {
+	KeytoolPath string
+	CacertsPath string
+}
+
+// NewJavaStore returns the store of the Java runtime in $JAVA_HOME.
+func NewJavaStore() (*JavaStore, error) {
+	home := os.Getenv("JAVA_HOME")
+	if home == "" {
+		return nil, errors.New("the Java trust store requires the JAVA_HOME environment variable")
+	}
+
+	keytoolPath := filepath.Join(home, "bin", "keytool")
+	if runtime.GOOS == "windows" {
+		keytoolPath += ".exe"
+	}
+	if _, err := os.Stat(keytoolPath); err != nil {
+		return nil, errors.Errorf("error finding keytool: %s does not exist", keytoolPath)
+	}
+
+	for _, p := range []string{
+		filepath.Join(home, "jre", "lib", "security", "cacerts"),
+		filepath.Join(home, "lib", "security", "cacerts"),
+	} {
+		if _, err := os.Stat(p); err == nil {
+			return &JavaStore{KeytoolPath: keytoolPath, CacertsPath: p}, nil
+		}
+	}
+	return nil, errors.Errorf("error finding the cacerts key store in %s", home)
+}
+
+// Name implements the Store interface.
+func (s *JavaStore) Name() string {
+	return "java:" + s.CacertsPath
+}
+
+func (s *JavaStore) keytool(args ...string) ([]byte, error) {
+	args = append(args, "-keystore", s.CacertsPath, "-storepass", truststore.JavaStorePass)
+	//nolint:gosec // keytool is run with the key store and aliases of the store
+	cmd := exec.Command(s.KeytoolPath, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, truststore.NewCmdError(err, cmd, out)
+	}
+	return out, nil
+}
+
+// List implements the Store interface.
+func (s *JavaStore) List() ([]Entry, error) {
+	out, err := s.keytool("-list", "-rfc")
+	if err != nil {
+		return nil, err
+	}
+	return parseKeytoolList(string(out)), nil
+}
+
+// Add implements the Store interface.
+func (s *JavaStore) Add(prefix string, cert *x509.Certificate) error {
+	filename, cleanup, err := writeTempCertificate(cert)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	_, err = s.keytool("-importcert", "-noprompt", "-file", filename, "-alias", EntryName(prefix, cert))
+	return err
+}
+
+// Remove implements the Store interface.
+func (s *JavaStore) Remove(e Entry) error {
+	_, err := s.keytool("-delete", "-alias", e.Name)
+	return err
+}
+
+// parseKeytoolList returns the entries in the output of keytool -list -rfc.
+func parseKeytoolList(out string) []Entry {
+	var (
+		entries []Entry
+		alias   string
+		block   strings.Builder
+	)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "Alias name:"):
+			alias = strings.TrimSpace(strings.TrimPrefix(line, "Alias name:"))
+		case strings.HasPrefix(line, "-----BEGIN CERTIFICATE-----"):
+			block.Reset()
+			block.WriteString(line + "\n")
+		case strings.HasPrefix(line, "-----END CERTIFICATE-----"):
+			block.WriteString(line + "\n")
+			if certs := parseCertificates([]byte(block.String())); len(certs) > 0 && alias != "" {
+				entries = append(entries, Entry{Name: alias, Certificate: certs[0]})
+			}
+			block.Reset()
+		case block.Len() > 0:
+			block.WriteString(line + "\n")
+		}
+	}
+	return entries
+}
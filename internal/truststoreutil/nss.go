@@ -0,0 +1,146 @@
+package truststoreutil
+
+import (
+	"bufio"
+	"crypto/x509"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/truststore"
+)
+
+// NSSStore is an NSS security database, used by Firefox and other NSS based
+// applications.
+type NSSStore struct {
+	// Path is the directory of the database, with an optional sql: or dbm:
+	// prefix.
+	Path         string
+	CertutilPath string
+}
+
+// DefaultNSSPaths returns the NSS security databases of the current user: the
+// shared database and the Firefox profiles.
+func DefaultNSSPaths() []string {
+	var paths []string
+	if truststore.NSSProfile != "" {
+		paths, _ = filepath.Glob(truststore.NSSProfile)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".pki", "nssdb"))
+	}
+	var dbs []string
+	for _, p := range paths {
+		if isNSSDatabase(p) {
+			dbs = append(dbs, p)
+		}
+	}
+	return dbs
+}
+
+func isNSSDatabase(dir string) bool {
+	for _, name := range []string{"cert9.db", "cert8.db"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// NewNSSStore returns the NSS store for the database in the given directory.
+// It requires the certutil command.
+func NewNSSStore(path string) (*NSSStore, error) {
+	certutilPath, err := exec.LookPath("certutil")
+	if err != nil {
+		return nil, errors.Errorf(`"certutil" is not available, install it with "%s" and try again`, truststore.CertutilInstallHelp)
+	}
+	return &NSSStore{Path: path, CertutilPath: certutilPath}, nil
+}
+
+// Name implements the Store interface.
+func (s *NSSStore) Name() string {
+	return "nss:" + strings.TrimPrefix(strings.TrimPrefix(s.Path, "sql:"), "dbm:")
+}
+
+// database returns the database argument of certutil.
+func (s *NSSStore) database() string {
+	if strings.HasPrefix(s.Path, "sql:") || strings.HasPrefix(s.Path, "dbm:") {
+		return s.Path
+	}
+	if _, err := os.Stat(filepath.Join(s.Path, "cert8.db")); err == nil {
+		if _, err := os.Stat(filepath.Join(s.Path, "cert9.db")); err != nil {
+			return "dbm:" + s.Path
+		}
+	}
+	return "sql:" + s.Path
+}
+
+// certutil runs a certutil command with the given options on the database.
+func (s *NSSStore) certutil(command string, options ...string) ([]byte, error) {
+	args := append([]string{command, "-d", s.database()}, options...)
+	//nolint:gosec // certutil is run with the database and nicknames of the store
+	cmd := exec.Command(s.CertutilPath, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, truststore.NewCmdError(err, cmd, out)
+	}
+	return out, nil
+}
+
+// List implements the Store interface.
+func (s *NSSStore) List() ([]Entry, error) {
+	out, err := s.certutil("-L")
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, nickname := range parseCertutilList(string(out)) {
+		b, err := s.certutil("-L", "-n", nickname, "-a")
+		if err != nil {
+			return nil, err
+		}
+		if certs := parseCertificates(b); len(certs) > 0 {
+			entries = append(entries, Entry{Name: nickname, Certificate: certs[0]})
+		}
+	}
+	return entries, nil
+}
+
+// Add implements the Store interface.
+func (s *NSSStore) Add(prefix string, cert *x509.Certificate) error {
+	filename, cleanup, err := writeTempCertificate(cert)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	_, err = s.certutil("-A", "-t", "C,,", "-n", EntryName(prefix, cert), "-i", filename)
+	return err
+}
+
+// Remove implements the Store interface.
+func (s *NSSStore) Remove(e Entry) error {
+	_, err := s.certutil("-D", "-n", e.Name)
+	return err
+}
+
+var certutilTrustRegexp = regexp.MustCompile(`^(.*\S)\s+([a-zA-Z]*,[a-zA-Z]*,[a-zA-Z]*)$`)
+
+// parseCertutilList returns the nicknames in the output of certutil -L.
+func parseCertutilList(out string) []string {
+	var nicknames []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip the header lines.
+		if strings.HasPrefix(line, "Certificate Nickname") || strings.HasPrefix(line, "SSL,S/MIME") {
+			continue
+		}
+		if m := certutilTrustRegexp.FindStringSubmatch(line); m != nil {
+			nicknames = append(nicknames, m[1])
+		}
+	}
+	return nicknames
+}
@@ -0,0 +1,35 @@
+//go:build !linux && !freebsd
+
+package truststoreutil
+
+import (
+	"crypto/x509"
+	"strings"
+
+	"github.com/smallstep/truststore"
+)
+
+// SystemStore is the trust store of the operating system. Roots can be added
+// and removed, but listing the trust store is not supported on this platform.
+type SystemStore struct{}
+
+// Name implements the Store interface.
+func (s *SystemStore) Name() string {
+	return "system"
+}
+
+// List implements the Store interface.
+func (s *SystemStore) List() ([]Entry, error) {
+	return nil, ErrNotSupported
+}
+
+// Add implements the Store interface.
+func (s *SystemStore) Add(prefix string, cert *x509.Certificate) error {
+	return truststore.Install(cert, truststore.WithPrefix(prefix))
+}
+
+// Remove implements the Store interface.
+func (s *SystemStore) Remove(e Entry) error {
+	prefix := strings.TrimSuffix(e.Name, e.Certificate.SerialNumber.String())
+	return truststore.Uninstall(e.Certificate, truststore.WithPrefix(prefix))
+}
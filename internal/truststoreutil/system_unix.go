@@ -0,0 +1,54 @@
+//go:build linux || freebsd
+
+package truststoreutil
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"strings"
+
+	"github.com/smallstep/truststore"
+)
+
+// SystemStore is the trust store of the operating system. Only the directory
+// with the roots added locally is listed.
+type SystemStore struct{}
+
+// Name implements the Store interface.
+func (s *SystemStore) Name() string {
+	return "system"
+}
+
+func (s *SystemStore) dir() (*DirStore, error) {
+	if truststore.SystemTrustFilename == "" {
+		return nil, ErrNotSupported
+	}
+	return &DirStore{
+		Path: filepath.Dir(truststore.SystemTrustFilename),
+		Ext:  filepath.Ext(truststore.SystemTrustFilename),
+	}, nil
+}
+
+// List implements the Store interface.
+func (s *SystemStore) List() ([]Entry, error) {
+	dir, err := s.dir()
+	if err != nil {
+		return nil, err
+	}
+	return dir.List()
+}
+
+// Add implements the Store interface. It uses sudo if available and updates
+// the trust store.
+func (s *SystemStore) Add(prefix string, cert *x509.Certificate) error {
+	return truststore.Install(cert, truststore.WithPrefix(prefix))
+}
+
+// Remove implements the Store interface. It uses sudo if available and
+// updates the trust store.
+func (s *SystemStore) Remove(e Entry) error {
+	// The file name is the prefix and serial number with spaces replaced by
+	// underscores.
+	prefix := strings.TrimSuffix(e.Name, e.Certificate.SerialNumber.String())
+	return truststore.Uninstall(e.Certificate, truststore.WithPrefix(prefix))
+}
@@ -0,0 +1,226 @@
+// Package truststoreutil implements the listing and the synchronization of the
+// root certificates installed by step in the system, NSS, and Java trust
+// stores, and in directories of PEM files.
+package truststoreutil
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/x509util"
+)
+
+// ErrNotSupported is the error returned when a trust store cannot be listed on
+// the current platform.
+var ErrNotSupported = errors.New("trust store is not supported on this platform")
+
+// Entry is a certificate in a trust store.
+type Entry struct {
+	// Name is the name of the entry in the trust store: the nickname, the
+	// alias, or the file name without extension.
+	Name        string
+	Certificate *x509.Certificate
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate.
+func (e *Entry) Fingerprint() string {
+	return x509util.Fingerprint(e.Certificate)
+}
+
+// IsManaged returns true if the entry was added by step with the given prefix.
+// The name of those entries is the one written by Add: the prefix and the
+// serial number of the certificate, with spaces replaced by underscores in
+// file names.
+func (e *Entry) IsManaged(prefix string) bool {
+	name := EntryName(prefix, e.Certificate)
+	return e.Name == name || e.Name == fileName(name)
+}
+
+// Store is a trust store.
+type Store interface {
+	// Name returns the name of the trust store.
+	Name() string
+	// List returns the certificates in the trust store.
+	List() ([]Entry, error)
+	// Add adds a root certificate to the trust store, with a name made of the
+	// prefix and the serial number.
+	Add(prefix string, cert *x509.Certificate) error
+	// Remove removes an entry from the trust store.
+	Remove(e Entry) error
+}
+
+// DefaultPrefix returns the default prefix of the name of a root certificate
+// in a trust store.
+func DefaultPrefix(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName + " "
+	}
+	return "Smallstep Development CA "
+}
+
+// EntryName returns the name of a root certificate added with the given
+// prefix.
+func EntryName(prefix string, cert *x509.Certificate) string {
+	return prefix + cert.SerialNumber.String()
+}
+
+// Plan returns the roots that must be added to a trust store with the given
+// entries, and the entries added by step that must be removed, to converge
+// the trust store to the given roots. The prefix function returns the prefix
+// used to add a certificate; only the entries with that name are removed.
+// Roots already in the trust store, managed or not, are not added.
+func Plan(entries []Entry, roots []*x509.Certificate, prefix func(*x509.Certificate) string) (add []*x509.Certificate, remove []Entry) {
+	want := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		want[x509util.Fingerprint(root)] = true
+	}
+	have := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		fp := e.Fingerprint()
+		have[fp] = true
+		if !want[fp] && e.IsManaged(prefix(e.Certificate)) {
+			remove = append(remove, e)
+		}
+	}
+	for _, root := range roots {
+		fp := x509util.Fingerprint(root)
+		if !have[fp] {
+			add = append(add, root)
+			have[fp] = true
+		}
+	}
+	return add, remove
+}
+
+// DirStore is a directory of PEM files, one per root certificate, like the
+// directories used to add roots to container images.
+type DirStore struct {
+	Path string
+	// Ext is the extension of the files written, .crt by default.
+	Ext string
+}
+
+// Name implements the Store interface.
+func (s *DirStore) Name() string {
+	return "dir:" + s.Path
+}
+
+// List implements the Store interface. It returns the first certificate of
+// each .crt and .pem file in the directory. A missing directory is empty.
+func (s *DirStore) List() ([]Entry, error) {
+	files, err := os.ReadDir(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error reading %s", s.Path)
+	}
+	var entries []Entry
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".crt" && ext != ".pem") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.Path, f.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", filepath.Join(s.Path, f.Name()))
+		}
+		certs := parseCertificates(b)
+		if len(certs) == 0 {
+			continue
+		}
+		entries = append(entries, Entry{
+			Name:        strings.TrimSuffix(f.Name(), ext),
+			Certificate: certs[0],
+		})
+	}
+	return entries, nil
+}
+
+// Add implements the Store interface. Spaces in the name of the file are
+// replaced by underscores.
+func (s *DirStore) Add(prefix string, cert *x509.Certificate) error {
+	if err := os.MkdirAll(s.Path, 0o755); err != nil {
+		return errors.Wrapf(err, "error creating %s", s.Path)
+	}
+	ext := s.Ext
+	if ext == "" {
+		ext = ".crt"
+	}
+	filename := filepath.Join(s.Path, fileName(EntryName(prefix, cert))+ext)
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return errors.Wrapf(err, "error writing %s", filename)
+	}
+	return nil
+}
+
+// Remove implements the Store interface.
+func (s *DirStore) Remove(e Entry) error {
+	for _, ext := range []string{".crt", ".pem"} {
+		filename := filepath.Join(s.Path, e.Name+ext)
+		if err := os.Remove(filename); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "error removing %s", filename)
+		}
+	}
+	return errors.Errorf("error removing %s: file not found", filepath.Join(s.Path, e.Name))
+}
+
+// fileName returns the name of the file of an entry.
+func fileName(name string) string {
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// parseCertificates returns the certificates in PEM data, skipping the blocks
+// that cannot be parsed.
+func parseCertificates(b []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// writeTempCertificate writes a certificate to a temporary file and returns
+// its name and a function that removes it.
+func writeTempCertificate(cert *x509.Certificate) (string, func(), error) {
+	f, err := os.CreateTemp("", "truststore.*.pem")
+	if err != nil {
+		return "", func() {}, errors.Wrap(err, "error creating temporary file")
+	}
+	name := f.Name()
+	cleanup := func() { os.Remove(name) }
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+		f.Close()
+		cleanup()
+		return "", func() {}, errors.Wrapf(err, "error writing %s", name)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", func() {}, errors.Wrapf(err, "error writing %s", name)
+	}
+	return name, cleanup, nil
+}
+
+// SortEntries sorts the entries by name.
+func SortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+}
@@ -0,0 +1,138 @@
+package truststoreutil
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+// rootTemplate is the minica root template with a fixed serial number and
+// common name.
+const rootTemplate = `{
+	"subject": {"commonName": %q},
+	"issuer": {"commonName": %[1]q},
+	"serialNumber": %d,
+	"keyUsage": ["certSign", "crlSign"],
+	"basicConstraints": {
+		"isCA": true,
+		"maxPathLen": 1
+	}
+}`
+
+func newRoot(t *testing.T, serial int64, cn string) *x509.Certificate {
+	t.Helper()
+	ca, err := minica.New(minica.WithRootTemplate(fmt.Sprintf(rootTemplate, cn, serial)))
+	require.NoError(t, err)
+	return ca.Root
+}
+
+func toPEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func TestEntry_IsManaged(t *testing.T) {
+	cert := newRoot(t, 1234, "Smallstep Root CA")
+	prefix := DefaultPrefix(cert)
+	assert.True(t, (&Entry{Name: "Smallstep Root CA 1234", Certificate: cert}).IsManaged(prefix))
+	assert.True(t, (&Entry{Name: "Smallstep_Root_CA_1234", Certificate: cert}).IsManaged(prefix))
+	assert.True(t, (&Entry{Name: "custom-1234", Certificate: cert}).IsManaged("custom-"))
+	assert.False(t, (&Entry{Name: "custom-1234", Certificate: cert}).IsManaged(prefix))
+	assert.False(t, (&Entry{Name: "Other Root CA 1234", Certificate: cert}).IsManaged(prefix))
+	assert.False(t, (&Entry{Name: "1234", Certificate: cert}).IsManaged(prefix))
+	assert.False(t, (&Entry{Name: "ISRG Root X1", Certificate: cert}).IsManaged(prefix))
+	assert.Equal(t, "Smallstep Root CA 1234", EntryName(DefaultPrefix(cert), cert))
+	assert.Equal(t, "Smallstep Development CA ", DefaultPrefix(&x509.Certificate{}))
+}
+
+func TestPlan(t *testing.T) {
+	keep := newRoot(t, 1, "Keep")
+	retired := newRoot(t, 2, "Retired")
+	unmanaged := newRoot(t, 3, "Unmanaged")
+	added := newRoot(t, 4, "Added")
+
+	// A vendor root saved as root-1.crt, its name ends with its serial.
+	vendor := newRoot(t, 1, "Vendor Root")
+
+	entries := []Entry{
+		{Name: "Keep 1", Certificate: keep},
+		{Name: "Retired 2", Certificate: retired},
+		{Name: "ISRG Root", Certificate: unmanaged},
+		{Name: "root-1", Certificate: vendor},
+	}
+	add, remove := Plan(entries, []*x509.Certificate{keep, added, unmanaged, added}, DefaultPrefix)
+	assert.Equal(t, []*x509.Certificate{added}, add)
+	assert.Equal(t, []Entry{entries[1]}, remove)
+
+	// With a custom prefix only the entries with that prefix are removed.
+	add, remove = Plan(entries, nil, func(*x509.Certificate) string { return "root-" })
+	assert.Empty(t, add)
+	assert.Equal(t, []Entry{entries[3]}, remove)
+
+	add, remove = Plan(nil, nil, DefaultPrefix)
+	assert.Empty(t, add)
+	assert.Empty(t, remove)
+}
+
+func TestDirStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "certs")
+	s := &DirStore{Path: dir}
+	assert.Equal(t, "dir:"+dir, s.Name())
+
+	entries, err := s.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	root := newRoot(t, 1234, "Smallstep Root CA")
+	require.NoError(t, s.Add(DefaultPrefix(root), root))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("foo"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("foo"), 0o600))
+
+	entries, err = s.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Smallstep_Root_CA_1234", entries[0].Name)
+	assert.True(t, entries[0].IsManaged(DefaultPrefix(root)))
+	assert.True(t, root.Equal(entries[0].Certificate))
+	assert.Len(t, entries[0].Fingerprint(), 64)
+
+	require.NoError(t, s.Remove(entries[0]))
+	_, err = os.Stat(filepath.Join(dir, "Smallstep_Root_CA_1234.crt"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, s.Remove(entries[0]))
+}
+
+func TestParseCertutilList(t *testing.T) {
+	out := `
+Certificate Nickname                                         Trust Attributes
+                                                             SSL,S/MIME,JAR/XPI
+
+Smallstep Root CA 1234                                       C,,
+My Personal Cert                                             u,u,u
+`
+	assert.Equal(t, []string{"Smallstep Root CA 1234", "My Personal Cert"}, parseCertutilList(out))
+	assert.Empty(t, parseCertutilList(""))
+}
+
+func TestParseKeytoolList(t *testing.T) {
+	a := newRoot(t, 1, "A")
+	b := newRoot(t, 2, "B")
+	out := "Keystore type: JKS\nKeystore provider: SUN\n\nYour keystore contains 2 entries\n\n" +
+		"Alias name: a 1\nCreation date: Jan 1, 2026\nEntry type: trustedCertEntry\n\n" + toPEM(a) +
+		"\n\n*******************************************\n\n\n" +
+		"Alias name: b 2\nCreation date: Jan 1, 2026\nEntry type: trustedCertEntry\n\n" + strings.ReplaceAll(toPEM(b), "\n", "\r\n")
+
+	entries := parseKeytoolList(out)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a 1", entries[0].Name)
+	assert.True(t, a.Equal(entries[0].Certificate))
+	assert.Equal(t, "b 2", entries[1].Name)
+	assert.True(t, b.Equal(entries[1].Certificate))
+}
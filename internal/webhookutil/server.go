@@ -0,0 +1,176 @@
+package webhookutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxBodySize is the maximum size of the requests accepted by the Handler.
+const maxBodySize = 1 << 20
+
+// Responder returns the body of the response to a verified webhook request.
+type Responder func(r *http.Request, body []byte) ([]byte, error)
+
+// StaticResponder returns a Responder that always replies with the given
+// body.
+func StaticResponder(body []byte) Responder {
+	return func(*http.Request, []byte) ([]byte, error) {
+		return body, nil
+	}
+}
+
+// CommandResponder returns a Responder that runs the given command for each
+// request. The command reads the request body from its standard input and
+// writes the response body to its standard output. The webhook ID and the
+// path of the request are available in the STEP_WEBHOOK_ID and
+// STEP_WEBHOOK_PATH environment variables.
+func CommandResponder(name string, args ...string) Responder {
+	return func(r *http.Request, body []byte) ([]byte, error) {
+		var stdout bytes.Buffer
+		cmd := exec.CommandContext(r.Context(), name, args...)
+		cmd.Env = append(os.Environ(),
+			"STEP_WEBHOOK_ID="+r.Header.Get(IDHeader),
+			"STEP_WEBHOOK_PATH="+r.URL.Path,
+		)
+		cmd.Stdin = bytes.NewReader(body)
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return nil, errors.Wrapf(err, "error running %s", name)
+		}
+		return stdout.Bytes(), nil
+	}
+}
+
+// Handler is an http.Handler that verifies the requests sent by step-ca to a
+// webhook, writes them to Out, and replies with the body returned by the
+// Responder.
+type Handler struct {
+	// Secret is the base64 encoded webhook secret.
+	Secret string
+	// ID, if set, is the required value of the X-Smallstep-Webhook-ID header.
+	ID string
+	// Responder returns the response body of valid requests.
+	Responder Responder
+	// Out is the writer used to print the requests and responses.
+	Out io.Writer
+
+	mu sync.Mutex
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The log of each request is written at once, before the response.
+	var log bytes.Buffer
+	fmt.Fprintf(&log, "%s %s %s from %s\n", time.Now().Format(time.RFC3339), r.Method, r.URL.Path, r.RemoteAddr)
+	reply := func(status int, body []byte) {
+		fmt.Fprintf(&log, "Response: %d %s\n", status, http.StatusText(status))
+		WriteJSON(&log, body)
+		h.mu.Lock()
+		_, _ = log.WriteTo(h.Out)
+		h.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}
+	fail := func(status int, err error) {
+		fmt.Fprintf(&log, "Error: %v\n", err)
+		b, _ := json.Marshal(map[string]string{"error": err.Error()})
+		reply(status, b)
+	}
+
+	if r.Method != http.MethodPost {
+		fail(http.StatusMethodNotAllowed, errors.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		fail(http.StatusBadRequest, errors.Wrap(err, "error reading request"))
+		return
+	}
+
+	id := r.Header.Get(IDHeader)
+	fmt.Fprintf(&log, "Webhook ID: %s\n", id)
+	if h.ID != "" && id != h.ID {
+		fmt.Fprintln(&log, "Signature: not verified")
+		WriteJSON(&log, body)
+		fail(http.StatusUnauthorized, errors.Errorf("unexpected webhook ID %q", id))
+		return
+	}
+	if err := Verify(h.Secret, body, r.Header.Get(SignatureHeader)); err != nil {
+		fmt.Fprintln(&log, "Signature: invalid")
+		WriteJSON(&log, body)
+		fail(http.StatusUnauthorized, err)
+		return
+	}
+	fmt.Fprintln(&log, "Signature: valid")
+	WriteJSON(&log, body)
+
+	resp, err := h.Responder(r, body)
+	if err != nil {
+		fail(http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := ParseResponse(resp); err != nil {
+		fail(http.StatusInternalServerError, err)
+		return
+	}
+	reply(http.StatusOK, resp)
+}
+
+// WriteJSON writes the indented JSON in b to w, or b as it is if it's not
+// valid JSON.
+func WriteJSON(w io.Writer, b []byte) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(b), "", "  "); err != nil {
+		buf.Reset()
+		buf.Write(bytes.TrimSpace(b))
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	_, _ = buf.WriteTo(w)
+}
+
+// Send sends a signed webhook request with the given body to url and returns
+// the status code and body of the response. The header contains additional
+// fields, like Authorization, to set in the request.
+func Send(ctx context.Context, client *http.Client, url, id, secret string, header http.Header, body []byte) (int, []byte, error) {
+	sig, err := Sign(secret, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error creating request")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, sig)
+	if id != "" {
+		req.Header.Set(IDHeader, id)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "error sending request to %s", url)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "error reading response from %s", url)
+	}
+	return resp.StatusCode, b, nil
+}
@@ -0,0 +1,185 @@
+// Package webhookutil implements the signatures of the requests sent by
+// step-ca to provisioner webhooks, and the sample requests and server used to
+// develop and test webhook servers.
+package webhookutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/smallstep/certificates/webhook"
+	"go.step.sm/crypto/sshutil"
+	"go.step.sm/crypto/x509util"
+	"golang.org/x/crypto/ssh"
+)
+
+// Header fields set by step-ca in webhook requests.
+const (
+	SignatureHeader = "X-Smallstep-Signature"
+	IDHeader        = "X-Smallstep-Webhook-ID"
+)
+
+// ErrInvalidSignature is the error returned when the signature of a request
+// does not match its body.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Sign returns the value of the X-Smallstep-Signature header for the given
+// body, the hex encoded HMAC-SHA256 of the body keyed with the base64 encoded
+// secret returned when the webhook was created.
+func Sign(secret string, body []byte) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum(key, body)), nil
+}
+
+// Verify verifies the value of the X-Smallstep-Signature header of a request
+// with the given body.
+func Verify(secret string, body []byte, signature string) error {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, sum(key, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding webhook secret")
+	}
+	if len(key) == 0 {
+		return nil, errors.New("webhook secret cannot be empty")
+	}
+	return key, nil
+}
+
+func sum(key, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// Kinds of webhooks.
+const (
+	KindEnriching   = "ENRICHING"
+	KindAuthorizing = "AUTHORIZING"
+)
+
+// ParseResponse parses and validates the body of a webhook response.
+func ParseResponse(b []byte) (*webhook.ResponseBody, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, errors.Wrap(err, "error parsing webhook response: response must be a JSON object")
+	}
+	if _, ok := raw["allow"]; !ok {
+		return nil, errors.New("error parsing webhook response: missing required property \"allow\"")
+	}
+	var resp webhook.ResponseBody
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, errors.Wrap(err, "error parsing webhook response")
+	}
+	if resp.Data != nil {
+		if _, ok := resp.Data.(map[string]any); !ok {
+			return nil, errors.New("error parsing webhook response: property \"data\" must be a JSON object")
+		}
+	}
+	return &resp, nil
+}
+
+// SampleRequest returns the body of a request like the ones step-ca sends to
+// webhooks of the given kind, ENRICHING or AUTHORIZING, when signing a
+// certificate of the given type, X509 or SSH, for subject. Requests to
+// enriching webhooks contain a new certificate request, and requests to
+// authorizing webhooks contain the certificate that is going to be signed.
+func SampleRequest(kind, certType, provisionerName, subject string) ([]byte, error) {
+	kind = strings.ToUpper(kind)
+	if kind != KindEnriching && kind != KindAuthorizing {
+		return nil, errors.Errorf("unsupported webhook kind %q", kind)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating key")
+	}
+
+	var opt webhook.RequestBodyOption
+	now := time.Now().UTC()
+	switch strings.ToUpper(certType) {
+	case "X509":
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: subject},
+			DNSNames: []string{subject},
+		}, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating certificate request")
+		}
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing certificate request")
+		}
+		if kind == KindEnriching {
+			opt = webhook.WithX509CertificateRequest(csr)
+			break
+		}
+		cert, err := x509util.NewCertificate(csr)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating certificate")
+		}
+		leaf := cert.GetCertificate()
+		leaf.NotBefore, leaf.NotAfter = now, now.Add(24*time.Hour)
+		opt = webhook.WithX509Certificate(cert, leaf)
+	case "SSH":
+		pub, err := ssh.NewPublicKey(key.Public())
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating public key")
+		}
+		cr := sshutil.CertificateRequest{
+			Key:        pub,
+			Type:       "user",
+			KeyID:      subject,
+			Principals: []string{subject},
+		}
+		if kind == KindEnriching {
+			opt = webhook.WithSSHCertificateRequest(cr)
+			break
+		}
+		data := sshutil.CreateTemplateData(sshutil.UserCert, subject, []string{subject})
+		cert, err := sshutil.NewCertificate(cr, sshutil.WithTemplate(sshutil.DefaultTemplate, data))
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating certificate")
+		}
+		tpl := cert.GetCertificate()
+		tpl.ValidAfter, tpl.ValidBefore = uint64(now.Unix()), uint64(now.Add(24*time.Hour).Unix())
+		opt = webhook.WithSSHCertificate(cert, tpl)
+	default:
+		return nil, errors.Errorf("unsupported certificate type %q", certType)
+	}
+
+	body, err := webhook.NewRequestBody(opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating request")
+	}
+	body.Timestamp = now
+	body.ProvisionerName = provisionerName
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling request")
+	}
+	return b, nil
+}
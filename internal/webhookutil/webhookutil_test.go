@@ -0,0 +1,191 @@
+package webhookutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/smallstep/certificates/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/x509util"
+	"golang.org/x/crypto/ssh"
+)
+
+// testSecret is the base64 encoding of "secret".
+const testSecret = "c2VjcmV0"
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"timestamp":"2024-01-01T00:00:00Z"}`)
+	sig, err := Sign(testSecret, body)
+	require.NoError(t, err)
+	// echo -n '{"timestamp":"2024-01-01T00:00:00Z"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "e007a04da193533bff2c99ca6d821373b5167b8e945b6178ae85ffd4467495eb", sig)
+
+	assert.NoError(t, Verify(testSecret, body, sig))
+	assert.ErrorIs(t, Verify(testSecret, append(body, ' '), sig), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("b3RoZXI=", body, sig), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, body, "not-hex"), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, body, ""), ErrInvalidSignature)
+
+	_, err = Sign("not base64!", body)
+	assert.Error(t, err)
+	_, err = Sign("", body)
+	assert.Error(t, err)
+}
+
+func TestParseResponse(t *testing.T) {
+	resp, err := ParseResponse([]byte(`{"allow": true, "data": {"role": "eng"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &webhook.ResponseBody{Allow: true, Data: map[string]any{"role": "eng"}}, resp)
+
+	resp, err = ParseResponse([]byte(`{"allow": false}`))
+	require.NoError(t, err)
+	assert.Equal(t, &webhook.ResponseBody{}, resp)
+
+	resp, err = ParseResponse([]byte(`{"allow": false, "error": {"code": "denied", "message": "not allowed"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &webhook.ResponseBody{Error: &webhook.Error{Code: "denied", Message: "not allowed"}}, resp)
+
+	for _, s := range []string{``, `[]`, `{}`, `{"allow": "yes"}`, `{"allow": true, "data": []}`} {
+		_, err := ParseResponse([]byte(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSampleRequest(t *testing.T) {
+	b, err := SampleRequest("ENRICHING", "X509", "my_provisioner", "test.example.com")
+	require.NoError(t, err)
+	var v struct {
+		ProvisionerName        string `json:"provisionerName"`
+		X509CertificateRequest struct {
+			DNSNames           []string `json:"dnsNames"`
+			PublicKey          []byte   `json:"publicKey"`
+			PublicKeyAlgorithm string   `json:"publicKeyAlgorithm"`
+			Raw                []byte   `json:"raw"`
+		} `json:"x509CertificateRequest"`
+		SSHCertificateRequest *struct{} `json:"sshCertificateRequest"`
+	}
+	require.NoError(t, json.Unmarshal(b, &v))
+	assert.Equal(t, "my_provisioner", v.ProvisionerName)
+	assert.Equal(t, []string{"test.example.com"}, v.X509CertificateRequest.DNSNames)
+	assert.Equal(t, "ECDSA", v.X509CertificateRequest.PublicKeyAlgorithm)
+	csr, err := x509.ParseCertificateRequest(v.X509CertificateRequest.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, "test.example.com", csr.Subject.CommonName)
+	pub, err := x509.ParsePKIXPublicKey(v.X509CertificateRequest.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, csr.PublicKey, pub)
+	assert.Nil(t, v.SSHCertificateRequest)
+
+	b, err = SampleRequest("enriching", "ssh", "", "jane")
+	require.NoError(t, err)
+	var s struct {
+		SSHCertificateRequest struct {
+			PublicKey  []byte   `json:"publicKey"`
+			Type       string   `json:"type"`
+			KeyID      string   `json:"keyID"`
+			Principals []string `json:"principals"`
+		} `json:"sshCertificateRequest"`
+	}
+	require.NoError(t, json.Unmarshal(b, &s))
+	assert.Equal(t, "user", s.SSHCertificateRequest.Type)
+	assert.Equal(t, "jane", s.SSHCertificateRequest.KeyID)
+	assert.Equal(t, []string{"jane"}, s.SSHCertificateRequest.Principals)
+	_, err = ssh.ParsePublicKey(s.SSHCertificateRequest.PublicKey)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "provisionerName")
+
+	_, err = SampleRequest("ENRICHING", "ALL", "", "jane")
+	assert.Error(t, err)
+	_, err = SampleRequest("NO_KIND", "X509", "", "jane")
+	assert.Error(t, err)
+}
+
+func TestSampleRequest_authorizing(t *testing.T) {
+	b, err := SampleRequest("AUTHORIZING", "X509", "my_provisioner", "test.example.com")
+	require.NoError(t, err)
+	var v webhook.RequestBody
+	require.NoError(t, json.Unmarshal(b, &v))
+	assert.Equal(t, "my_provisioner", v.ProvisionerName)
+	assert.Nil(t, v.X509CertificateRequest)
+	require.NotNil(t, v.X509Certificate)
+	assert.Equal(t, "test.example.com", v.X509Certificate.Subject.CommonName)
+	assert.Equal(t, x509util.MultiString{"test.example.com"}, v.X509Certificate.DNSNames)
+	assert.Equal(t, "ECDSA", v.X509Certificate.PublicKeyAlgorithm)
+	assert.Equal(t, 24*time.Hour, v.X509Certificate.NotAfter.Sub(v.X509Certificate.NotBefore))
+	_, err = x509.ParsePKIXPublicKey(v.X509Certificate.PublicKey)
+	assert.NoError(t, err)
+
+	b, err = SampleRequest("AUTHORIZING", "SSH", "", "jane")
+	require.NoError(t, err)
+	v = webhook.RequestBody{}
+	require.NoError(t, json.Unmarshal(b, &v))
+	assert.Nil(t, v.SSHCertificateRequest)
+	require.NotNil(t, v.SSHCertificate)
+	assert.Equal(t, "jane", v.SSHCertificate.KeyID)
+	assert.Equal(t, []string{"jane"}, v.SSHCertificate.Principals)
+	assert.Equal(t, uint64(24*60*60), v.SSHCertificate.ValidBefore-v.SSHCertificate.ValidAfter)
+	_, err = ssh.ParsePublicKey(v.SSHCertificate.PublicKey)
+	assert.NoError(t, err)
+}
+
+func TestHandler(t *testing.T) {
+	var out bytes.Buffer
+	h := &Handler{
+		Secret:    testSecret,
+		ID:        "c5f2a1d0",
+		Responder: StaticResponder([]byte(`{"allow": true, "data": {"role": "eng"}}`)),
+		Out:       &out,
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	body, err := SampleRequest("ENRICHING", "X509", "", "test.example.com")
+	require.NoError(t, err)
+	header := http.Header{"Authorization": {"Bearer token"}}
+
+	status, resp, err := Send(context.Background(), srv.Client(), srv.URL+"/enrich", "c5f2a1d0", testSecret, header, body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"allow": true, "data": {"role": "eng"}}`, string(resp))
+	assert.Contains(t, out.String(), " POST /enrich from ")
+	assert.Contains(t, out.String(), "Webhook ID: c5f2a1d0\nSignature: valid\n{\n  \"timestamp\": ")
+	assert.Contains(t, out.String(), "Response: 200 OK\n")
+
+	tests := map[string]struct {
+		id, secret string
+		status     int
+		log        string
+	}{
+		"bad secret": {"c5f2a1d0", "b3RoZXI=", http.StatusUnauthorized, "Signature: invalid\n"},
+		"bad id":     {"other", testSecret, http.StatusUnauthorized, "Error: unexpected webhook ID \"other\"\n"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			out.Reset()
+			status, _, err := Send(context.Background(), srv.Client(), srv.URL, tc.id, tc.secret, nil, body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, status)
+			assert.Contains(t, out.String(), tc.log)
+		})
+	}
+
+	out.Reset()
+	resp2, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	resp2.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp2.StatusCode)
+
+	// Invalid responses are not sent to step-ca.
+	h.Responder = StaticResponder([]byte(`{"data": {}}`))
+	status, resp, err = Send(context.Background(), srv.Client(), srv.URL, "c5f2a1d0", testSecret, nil, body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, string(resp), "missing required property")
+}
@@ -404,6 +404,53 @@ func (f *CertificateFlow) CreateSignRequest(ctx *cli.Context, tok, subject strin
 	}, pk, nil
 }
 
+// CreateTemplateSignRequest is a helper function that given an x509 OTT
+// returns a sign request with a certificate request rendered from the given
+// template and signed by the given signer. The template data has the subject
+// and SANs of the request and the values of the --set and --set-file flags.
+func (f *CertificateFlow) CreateTemplateSignRequest(ctx *cli.Context, tok, subject string, sans []string, template string, signer crypto.Signer) (*api.SignRequest, error) {
+	jwt, err := token.ParseInsecure(tok)
+	if err != nil {
+		return nil, err
+	}
+
+	switch jwt.Payload.Type() {
+	case token.OIDC, token.AWS, token.GCP, token.Azure, token.K8sSA:
+		// Use subject from command line, the server validates it.
+	default: // Use common name in the token
+		subject = jwt.Payload.Subject
+	}
+	if len(sans) == 0 {
+		sans = jwt.Payload.SANs
+	}
+	if len(sans) == 0 {
+		sans = []string{subject}
+	}
+
+	userData, err := flags.GetTemplateData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := x509util.CreateTemplateData(subject, sans)
+	data.SetUserData(userData)
+
+	csr, err := x509util.NewCertificateRequest(signer, x509util.WithTemplate(template, data))
+	if err != nil {
+		return nil, errors.Wrap(err, "error rendering certificate request template")
+	}
+	cr, err := csr.GetCertificateRequest()
+	if err != nil {
+		return nil, err
+	}
+	if err := cr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "error signing certificate request")
+	}
+	return &api.SignRequest{
+		CsrPEM: api.CertificateRequest{CertificateRequest: cr},
+		OTT:    tok,
+	}, nil
+}
+
 // splitSANs unifies the SAN collections passed as arguments and returns a list
 // of DNS names, a list of IP addresses, and a list of emails.
 func splitSANs(args ...[]string) (dnsNames []string, ipAddresses []net.IP, email []string, uris []*url.URL) {
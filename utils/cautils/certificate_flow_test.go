@@ -0,0 +1,129 @@
+package cautils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"flag"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/token"
+)
+
+const csrTemplate = `{
+	"subject": {
+		"commonName": {{ toJson .Subject.CommonName }},
+		"organizationalUnit": {{ toJson .Insecure.User.ou }}
+	},
+	"sans": {{ toJson .SANs }}
+}`
+
+func newTemplateContext(t *testing.T, set ...string) *cli.Context {
+	t.Helper()
+	fs := flag.NewFlagSet(t.Name(), 0)
+	fs.String("set-file", "", "")
+	setFlag := &cli.StringSlice{}
+	fs.Var(setFlag, "set", "")
+	for _, s := range set {
+		require.NoError(t, setFlag.Set(s))
+	}
+	return cli.NewContext(cli.NewApp(), fs, nil)
+}
+
+func newTestToken(t *testing.T, opts ...token.Options) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	now := time.Now()
+	claims, err := token.NewClaims(append([]token.Options{
+		token.WithIssuer("issuer"),
+		token.WithAudience("https://ca.smallstep.com/1.0/sign"),
+		token.WithValidity(now, now.Add(time.Minute)),
+	}, opts...)...)
+	require.NoError(t, err)
+	tok, err := claims.Sign(jose.ES256, key)
+	require.NoError(t, err)
+	return tok
+}
+
+func TestCertificateFlow_CreateTemplateSignRequest(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwkToken := newTestToken(t, token.WithSubject("foo.internal"), token.WithSHA("sha"),
+		token.WithSANS([]string{"foo.internal", "10.0.0.1"}))
+	oidcToken := newTestToken(t, token.WithSubject("1234567890"))
+
+	tests := []struct {
+		name        string
+		tok         string
+		subject     string
+		sans        []string
+		set         []string
+		template    string
+		wantCN      string
+		wantOU      []string
+		wantDNS     []string
+		wantIPs     []net.IP
+		wantEmails  []string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "ok/jwk-token-sans", tok: jwkToken, subject: "ignored", template: csrTemplate,
+			set:    []string{"ou=Engineering"},
+			wantCN: "foo.internal", wantOU: []string{"Engineering"},
+			wantDNS: []string{"foo.internal"}, wantIPs: []net.IP{net.ParseIP("10.0.0.1")},
+		},
+		{
+			name: "ok/command-line-sans", tok: jwkToken, subject: "ignored", template: csrTemplate,
+			sans:   []string{"bar.internal", "jane@example.com"},
+			set:    []string{`ou=["Engineering","Security"]`},
+			wantCN: "foo.internal", wantOU: []string{"Engineering", "Security"},
+			wantDNS: []string{"bar.internal"}, wantEmails: []string{"jane@example.com"},
+		},
+		{
+			name: "ok/oidc-subject", tok: oidcToken, subject: "jane@example.com", template: csrTemplate,
+			wantCN: "jane@example.com", wantEmails: []string{"jane@example.com"},
+		},
+		{
+			name: "fail/template", tok: jwkToken, template: `{"subject": {{ fail "bad template" }}}`,
+			wantErr: true, errContains: "error rendering certificate request template",
+		},
+		{
+			name: "fail/token", tok: "not-a-token", template: csrTemplate, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &CertificateFlow{}
+			got, err := f.CreateTemplateSignRequest(newTemplateContext(t, tt.set...), tt.tok, tt.subject, tt.sans, tt.template, signer)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+
+			cr := got.CsrPEM.CertificateRequest
+			assert.Equal(t, tt.tok, got.OTT)
+			assert.NoError(t, cr.CheckSignature())
+			assert.Equal(t, &signer.PublicKey, cr.PublicKey)
+			assert.Equal(t, tt.wantCN, cr.Subject.CommonName)
+			assert.ElementsMatch(t, tt.wantOU, cr.Subject.OrganizationalUnit)
+			assert.Equal(t, tt.wantDNS, cr.DNSNames)
+			assert.Equal(t, tt.wantEmails, cr.EmailAddresses)
+			assert.Len(t, cr.IPAddresses, len(tt.wantIPs))
+			for i, ip := range tt.wantIPs {
+				assert.True(t, ip.Equal(cr.IPAddresses[i]))
+			}
+		})
+	}
+}
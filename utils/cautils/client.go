@@ -97,18 +97,25 @@ func NewUnauthenticatedAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*
 
 // NewAdminClient returns a client for the mgmt API of the online CA.
 func NewAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient, error) {
+	client, _, err := NewAdminClientWithCertificate(ctx, opts...)
+	return client, err
+}
+
+// NewAdminClientWithCertificate is like NewAdminClient, but it also returns
+// the certificate used to authenticate the admin.
+func NewAdminClientWithCertificate(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient, *x509.Certificate, error) {
 	caURL, err := flags.ParseCaURLIfExists(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if caURL == "" {
-		return nil, errs.RequiredFlag(ctx, "ca-url")
+		return nil, nil, errs.RequiredFlag(ctx, "ca-url")
 	}
 	root := ctx.String("root")
 	if root == "" {
 		root = pki.GetRootCAPath()
 		if _, err := os.Stat(root); err != nil {
-			return nil, errs.RequiredFlag(ctx, "root")
+			return nil, nil, errs.RequiredFlag(ctx, "root")
 		}
 	}
 
@@ -120,37 +127,37 @@ func NewAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient,
 	)
 	if adminCertFile != "" || adminKeyFile != "" {
 		if adminCertFile == "" {
-			return nil, errs.RequiredWithFlag(ctx, "admin-key", "admin-cert")
+			return nil, nil, errs.RequiredWithFlag(ctx, "admin-key", "admin-cert")
 		}
 		if adminKeyFile == "" {
-			return nil, errs.RequiredWithFlag(ctx, "admin-cert", "admin-key")
+			return nil, nil, errs.RequiredWithFlag(ctx, "admin-cert", "admin-key")
 		}
 		adminCert, err = pemutil.ReadCertificateBundle(adminCertFile)
 		if err != nil {
-			return nil, errors.Wrap(err, "error reading admin certificate")
+			return nil, nil, errors.Wrap(err, "error reading admin certificate")
 		}
 		adminKey, err = pemutil.Read(adminKeyFile)
 		if err != nil {
-			return nil, errors.Wrap(err, "error reading admin key")
+			return nil, nil, errors.Wrap(err, "error reading admin key")
 		}
 	} else {
 		ui.Printf("No admin credentials found. You must login to execute admin commands.\n")
 		// Generate a new admin cert/key in memory.
 		client, err := ca.NewClient(caURL, ca.WithRootFile(root))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		subject := ctx.String("admin-subject")
 		if subject == "" {
 			subject, err = ui.Prompt("Please enter admin name/subject (e.g., name@example.com)", ui.WithValidateNotEmpty())
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
 		tok, err := NewTokenFlow(ctx, SignType, subject, []string{subject}, caURL, root, time.Time{}, time.Time{}, provisioner.TimeDuration{}, provisioner.TimeDuration{})
 
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		dnsNames, ips, emails, uris := splitSANs([]string{subject})
@@ -166,18 +173,18 @@ func NewAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient,
 
 		adminKey, err = keyutil.GenerateDefaultKey()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		csr, err := x509.CreateCertificateRequest(rand.Reader, template, adminKey)
 		if err != nil {
-			return nil, errors.Wrap(err, "error creating admin certificate request")
+			return nil, nil, errors.Wrap(err, "error creating admin certificate request")
 		}
 		cr, err := x509.ParseCertificateRequest(csr)
 		if err != nil {
-			return nil, errors.Wrap(err, "error parsing admin certificate request")
+			return nil, nil, errors.Wrap(err, "error parsing admin certificate request")
 		}
 		if err := cr.CheckSignature(); err != nil {
-			return nil, errors.Wrap(err, "error signing admin certificate request")
+			return nil, nil, errors.Wrap(err, "error signing admin certificate request")
 		}
 		signRequest := &api.SignRequest{
 			CsrPEM: api.CertificateRequest{CertificateRequest: cr},
@@ -185,7 +192,7 @@ func NewAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient,
 		}
 		signResponse, err := client.Sign(signRequest)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if len(signResponse.CertChainPEM) == 0 {
 			signResponse.CertChainPEM = []api.Certificate{signResponse.ServerPEM, signResponse.CaPEM}
@@ -200,5 +207,19 @@ func NewAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient,
 	opts = append([]ca.ClientOption{ca.WithRootFile(root),
 		ca.WithAdminX5C(adminCert, adminKey, ctx.String("password-file"))},
 		opts...)
-	return ca.NewAdminClient(caURL, opts...)
+	client, err := ca.NewAdminClient(caURL, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, adminCert[0], nil
+}
+
+// AdminIdentity returns the subjects and the provisioner name the CA uses to
+// find the admin authenticated with the given certificate.
+func AdminIdentity(cert *x509.Certificate) (subjects []string, provisionerName string) {
+	subjects = append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	if ext, ok := provisioner.GetProvisionerExtension(cert); ok {
+		provisionerName = ext.Name
+	}
+	return subjects, provisionerName
 }
@@ -535,6 +535,16 @@ func (c *OfflineCA) SSHBastion(req *api.SSHBastionRequest) (*api.SSHBastionRespo
 	}, nil
 }
 
+// RevokeTokenGenerator returns a generator of X.509 revocation tokens signed
+// by a JWK provisioner in the ca.json configuration.
+func (c *OfflineCA) RevokeTokenGenerator(ctx *cli.Context) (*TokenGenerator, error) {
+	return newJWKTokenGenerator(ctx, c.Provisioners(), tokenAttrs{
+		root:     c.Root(),
+		caURL:    c.CaURL(),
+		audience: c.Audience(RevokeType),
+	})
+}
+
 // GenerateToken creates the token used by the authority to authorize requests.
 func (c *OfflineCA) GenerateToken(ctx *cli.Context, tokType int, subject string, sans []string, notBefore, notAfter time.Time, certNotBefore, certNotAfter provisioner.TimeDuration) (string, error) {
 	// Use ca.json configuration for the root and audience
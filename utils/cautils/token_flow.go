@@ -204,6 +204,70 @@ func NewIdentityTokenFlow(ctx *cli.Context, caURL, root string) (string, error)
 	}
 }
 
+// NewRevokeTokenGenerator returns a generator of X.509 revocation tokens
+// signed by a JWK provisioner of the CA. The provisioner is selected and its
+// key decrypted once, so the generator can be used to revoke many
+// certificates.
+func NewRevokeTokenGenerator(ctx *cli.Context, caURL, root string) (*TokenGenerator, error) {
+	audience, err := parseAudience(ctx, RevokeType)
+	if err != nil {
+		return nil, err
+	}
+	provisioners, err := pki.GetProvisioners(caURL, root)
+	if err != nil {
+		return nil, err
+	}
+	return newJWKTokenGenerator(ctx, provisioners, tokenAttrs{
+		root:     root,
+		caURL:    caURL,
+		audience: audience,
+	})
+}
+
+// newJWKTokenGenerator selects a JWK provisioner, filtered by the kid flag,
+// and returns a token generator with its decrypted key.
+func newJWKTokenGenerator(ctx *cli.Context, provisioners provisioner.List, tokAttrs tokenAttrs) (*TokenGenerator, error) {
+	provisioners = provisionerFilter(provisioners, func(p provisioner.Interface) bool {
+		jwk, ok := p.(*provisioner.JWK)
+		return ok && (ctx.String("kid") == "" || jwk.Key.KeyID == ctx.String("kid"))
+	})
+	if len(provisioners) == 0 {
+		if kid := ctx.String("kid"); kid != "" {
+			return nil, errs.InvalidFlagValue(ctx, "kid", kid, "")
+		}
+		return nil, errors.New("cannot create a new token: the CA does not have any JWK provisioner configured")
+	}
+
+	var items []*provisionersSelect
+	for _, p := range provisioners {
+		p := p.(*provisioner.JWK)
+		items = append(items, &provisionersSelect{
+			Name:        fmt.Sprintf("%s (%s) [kid: %s]", p.Name, p.GetType(), p.Key.KeyID),
+			Provisioner: p,
+		})
+	}
+	i := 0
+	if len(items) == 1 {
+		if err := ui.PrintSelected("Provisioner", items[0].Name); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		i, _, err = ui.Select("What provisioner key do you want to use?", items, ui.WithSelectTemplates(ui.NamedSelectTemplates("Provisioner")))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := items[i].Provisioner.(*provisioner.JWK)
+	jwk, kid, err := loadJWK(ctx, p, tokAttrs)
+	if err != nil {
+		return nil, err
+	}
+	return NewTokenGenerator(kid, p.Name, tokAttrs.audience, tokAttrs.root,
+		time.Time{}, time.Time{}, jwk), nil
+}
+
 // OfflineTokenFlow generates a provisioning token using either
 //  1. static configuration from ca.json (created with `step ca init`)
 //  2. input from command line flags